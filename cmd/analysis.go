@@ -0,0 +1,410 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/shlex"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// analysisCommand is a static analysis command from --analysis-command.
+type analysisCommand struct {
+	// name is the name of the test used to report the problems found by the
+	// command in a package.
+	name    string
+	command []string
+}
+
+var analysisCommandNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// analysisCommandsValue is a flag.Value which adds an analysis command for
+// each value. The format of the value is: NAME=COMMAND.
+type analysisCommandsValue []analysisCommand
+
+func (v *analysisCommandsValue) String() string {
+	names := make([]string, 0, len(*v))
+	for _, c := range *v {
+		names = append(names, c.name)
+	}
+	return strings.Join(names, ",")
+}
+
+func (v *analysisCommandsValue) Set(raw string) error {
+	name, rawCommand := cutString(raw, "=")
+	name = strings.TrimSpace(name)
+	if !strings.Contains(raw, "=") || !analysisCommandNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid analysis command %q, must be NAME=COMMAND, "+
+			"and the name may only use letters, numbers, '-', and '_'", raw)
+	}
+	if name == vetTestName {
+		return fmt.Errorf("invalid analysis command %q, the name %v is used by --vet-mode=separate", raw, name)
+	}
+	for _, c := range *v {
+		if c.name == name {
+			return fmt.Errorf("duplicate analysis command name %v", name)
+		}
+	}
+	command, err := shlex.Split(rawCommand)
+	if err != nil {
+		return fmt.Errorf("invalid analysis command %q: %w", raw, err)
+	}
+	if len(command) == 0 {
+		return fmt.Errorf("invalid analysis command %q, the command is empty", raw)
+	}
+	*v = append(*v, analysisCommand{name: name, command: command})
+	return nil
+}
+
+func (v *analysisCommandsValue) Type() string {
+	return "name=command"
+}
+
+// analysisStageEnabled returns true when go vet, or an analysis command, runs
+// as a stage before go test.
+func analysisStageEnabled(opts *options) bool {
+	return opts.vetMode == vetModeSeparate || len(opts.analysisCommands) > 0
+}
+
+func validateAnalysisStage(opts *options) error {
+	if !analysisStageEnabled(opts) {
+		if opts.vetContinue {
+			return fmt.Errorf("--vet-continue requires --vet-mode=separate or --analysis-command")
+		}
+		return nil
+	}
+	flag := "--vet-mode=separate"
+	if opts.vetMode != vetModeSeparate {
+		flag = "--analysis-command"
+	}
+	var unsupported string
+	switch {
+	case opts.rawCommand:
+		unsupported = "--raw-command"
+	case opts.watch:
+		unsupported = "--watch"
+	case opts.untilFailure:
+		unsupported = "--until-failure"
+	case len(opts.pkgGroups) > 0:
+		unsupported = "--pkg-group"
+	case len(opts.args) > 0 && len(opts.packages) == 0:
+		return fmt.Errorf("when go test args are used with %v "+
+			"the list of packages to test must be specified by the --packages flag", flag)
+	default:
+		return nil
+	}
+	return fmt.Errorf("%v can not be used with %v", flag, unsupported)
+}
+
+// analysisCmdArgs returns the command for an --analysis-command. The packages
+// to test are added to the end of the command.
+func analysisCmdArgs(opts *options, c analysisCommand) []string {
+	args := append([]string{}, c.command...)
+	return append(args, cmdArgPackageList(opts, rerunOpts{}, "./...")...)
+}
+
+// analysisProblem is a problem found in a package by a static analysis
+// command.
+type analysisProblem struct {
+	pkg  string
+	line string
+}
+
+// analysisResult is the problems found by a static analysis command, which
+// are reported as a failure of the test in each package.
+type analysisResult struct {
+	test     string
+	elapsed  time.Duration
+	problems []analysisProblem
+}
+
+// shims for testing
+var (
+	// runAnalysisFn returns the combined output of the command.
+	runAnalysisFn = func(ctx context.Context, args []string) ([]byte, error) {
+		log.Debugf("exec: %s", args)
+		return exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	}
+
+	// goListDirsFn returns the import path of each package matched by args,
+	// keyed by the directory of the package.
+	goListDirsFn = func(args []string) (map[string]string, error) {
+		args = append([]string{"list", "-e", "-f", "{{.Dir}}\t{{.ImportPath}}"}, args...)
+		log.Debugf("exec: go %s", args)
+		cmd := exec.Command("go", args...)
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, err
+		}
+		dirs := make(map[string]string)
+		for _, line := range strings.Split(string(out), "\n") {
+			if dir, pkg := cutString(line, "\t"); dir != "" && pkg != "" {
+				dirs[dir] = pkg
+			}
+		}
+		return dirs, nil
+	}
+)
+
+// runAnalysisStage runs go vet, when --vet-mode=separate, and each
+// --analysis-command. The problems found in each package are reported as a
+// failed test in that package, with the name of the command. Any other output
+// from the commands is reported as an error. The returned Execution is nil
+// when there is no analysis stage.
+func runAnalysisStage(ctx context.Context, opts *options, handler testjson.EventHandler) (*testjson.Execution, error) {
+	if !analysisStageEnabled(opts) {
+		return nil, nil
+	}
+
+	// The output is scanned while the commands run, so that the elapsed time of
+	// the Execution includes the time to run the commands.
+	stdout, stdoutW := io.Pipe()
+	stderr, stderrW := io.Pipe()
+	errCh := make(chan error, 1)
+	go func() {
+		defer stdoutW.Close() // nolint: errcheck
+		defer stderrW.Close() // nolint: errcheck
+		results, other, err := runAnalysis(ctx, opts)
+		errCh <- err
+		if err != nil {
+			return
+		}
+		cwd, _ := os.Getwd()
+		stdoutW.Write(analysisEvents(results, cwd)) // nolint: errcheck
+		if len(other) > 0 {
+			stderrW.Write([]byte(strings.Join(other, "\n") + "\n")) // nolint: errcheck
+		}
+	}()
+
+	analysisExec, err := testjson.ScanTestOutputContext(ctx, testjson.ScanConfig{
+		Stdout:  stdout,
+		Stderr:  stderr,
+		Handler: handler,
+		Clock:   opts.clock.newClock(),
+	})
+	stdout.Close() // nolint: errcheck
+	stderr.Close() // nolint: errcheck
+	if runErr := <-errCh; runErr != nil {
+		return nil, runErr
+	}
+	return analysisExec, err
+}
+
+func runAnalysis(ctx context.Context, opts *options) ([]analysisResult, []string, error) {
+	var results []analysisResult
+	var other []string
+	if opts.vetMode == vetModeSeparate {
+		start := time.Now()
+		result, out, err := runVet(ctx, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.elapsed = time.Since(start)
+		results = append(results, result)
+		other = append(other, out...)
+	}
+	if len(opts.analysisCommands) == 0 {
+		return results, other, nil
+	}
+
+	listArgs := append(goListBuildFlags(opts.args), cmdArgPackageList(opts, rerunOpts{}, "./...")...)
+	dirs, err := goListDirsFn(listArgs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list packages for --analysis-command: %w", err)
+	}
+	cwd, _ := os.Getwd()
+	for _, c := range opts.analysisCommands {
+		start := time.Now()
+		out, err := runAnalysisFn(ctx, analysisCmdArgs(opts, c))
+		if err != nil && !IsExitCoder(err) {
+			return nil, nil, fmt.Errorf("failed to run --analysis-command %v: %w", c.name, err)
+		}
+		problems, unknown := parseAnalysisOutput(out, dirs, cwd)
+		for _, line := range unknown {
+			other = append(other, c.name+": "+line)
+		}
+		if err != nil && len(problems) == 0 && len(unknown) == 0 {
+			other = append(other, c.name+": "+err.Error())
+		}
+		results = append(results, analysisResult{
+			test:     c.name,
+			elapsed:  time.Since(start),
+			problems: problems,
+		})
+	}
+	return results, other, nil
+}
+
+// analysisLinePattern matches a line of output with the position of a
+// problem, ex: pkg/file.go:12:3: message.
+var analysisLinePattern = regexp.MustCompile(`^(.+\.go):\d+(:\d+)?: `)
+
+// parseAnalysisOutput returns the problems found by a static analysis
+// command, and the lines of output which are not a problem in one of the
+// packages. Each problem is attributed to a package from the directory of
+// the file. Indented lines after a problem, like the source of the problem,
+// are part of the problem.
+func parseAnalysisOutput(out []byte, dirs map[string]string, cwd string) ([]analysisProblem, []string) {
+	var problems []analysisProblem
+	var other []string
+	var pkg string
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		switch {
+		case line == "":
+		case pkg != "" && (line[0] == ' ' || line[0] == '\t'):
+			problems = append(problems, analysisProblem{pkg: pkg, line: line})
+		default:
+			pkg = ""
+			if match := analysisLinePattern.FindStringSubmatch(line); match != nil {
+				file := match[1]
+				if !filepath.IsAbs(file) {
+					file = filepath.Join(cwd, file)
+				}
+				pkg = dirs[filepath.Dir(file)]
+			}
+			if pkg == "" {
+				other = append(other, line)
+				continue
+			}
+			problems = append(problems, analysisProblem{pkg: pkg, line: line})
+		}
+	}
+	return problems, other
+}
+
+// analysisTestNames returns the names of the tests used to report the
+// problems found by the analysis stage.
+func analysisTestNames(opts *options) map[string]bool {
+	names := make(map[string]bool)
+	if opts.vetMode == vetModeSeparate {
+		names[vetTestName] = true
+	}
+	for _, c := range opts.analysisCommands {
+		names[c.name] = true
+	}
+	return names
+}
+
+// analysisFailed returns the number of test cases that failed because of
+// problems found by the analysis stage.
+func analysisFailed(opts *options, exec *testjson.Execution) int {
+	return len(exec.Failed()) - len(withoutAnalysisTestCases(opts)(exec.Failed()))
+}
+
+// withoutAnalysisTestCases returns a filter which removes the test cases of
+// the analysis stage, which can not be run again by --rerun-fails.
+func withoutAnalysisTestCases(opts *options) testCaseFilter {
+	names := analysisTestNames(opts)
+	return func(tcs []testjson.TestCase) []testjson.TestCase {
+		if len(names) == 0 {
+			return tcs
+		}
+		result := make([]testjson.TestCase, 0, len(tcs))
+		for _, tc := range tcs {
+			if !names[tc.Test.Name()] {
+				result = append(result, tc)
+			}
+		}
+		return result
+	}
+}
+
+// analysisExitErr returns an error when the analysis stage found a problem,
+// so that the run fails even when all the tests passed.
+func analysisExitErr(opts *options, exec *testjson.Execution, exitErr error) error {
+	if exitErr != nil || exec == nil || analysisFailed(opts, exec) == 0 {
+		return exitErr
+	}
+	return exitError{num: 1}
+}
+
+// analysisEvent is a test2json event.
+type analysisEvent struct {
+	Time    time.Time
+	Action  testjson.Action
+	Package string
+	Test    string  `json:",omitempty"`
+	Elapsed float64 `json:",omitempty"`
+	Output  string  `json:",omitempty"`
+}
+
+// analysisEvents returns the test2json events of a failed test for each
+// result with problems in a package. Absolute paths in the problems are made
+// relative to dir.
+func analysisEvents(results []analysisResult, dir string) []byte {
+	type key struct{ pkg, test string }
+	lines := make(map[key][]string)
+	var packages []string
+	seen := make(map[string]bool)
+	for _, result := range results {
+		for _, problem := range result.problems {
+			if !seen[problem.pkg] {
+				seen[problem.pkg] = true
+				packages = append(packages, problem.pkg)
+			}
+			line := problem.line
+			if dir != "" && filepath.IsAbs(line) {
+				if rel, err := filepath.Rel(dir, line); err == nil && !strings.HasPrefix(rel, "..") {
+					line = rel
+				}
+			}
+			k := key{pkg: problem.pkg, test: result.test}
+			lines[k] = append(lines[k], line)
+		}
+	}
+
+	now := time.Now()
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	write := func(event analysisEvent) {
+		event.Time = now
+		enc.Encode(event) // nolint: errcheck
+	}
+	for _, pkg := range packages {
+		var elapsed time.Duration
+		write(analysisEvent{Action: testjson.ActionStart, Package: pkg})
+		for _, result := range results {
+			problems, ok := lines[key{pkg: pkg, test: result.test}]
+			if !ok {
+				continue
+			}
+			if result.elapsed > elapsed {
+				elapsed = result.elapsed
+			}
+			test := result.test
+			output := func(text string) {
+				write(analysisEvent{Action: testjson.ActionOutput, Package: pkg, Test: test, Output: text})
+			}
+			write(analysisEvent{Action: testjson.ActionRun, Package: pkg, Test: test})
+			output("=== RUN   " + test + "\n")
+			for _, line := range problems {
+				output("    " + line + "\n")
+			}
+			output(fmt.Sprintf("--- FAIL: %s (%.2fs)\n", test, result.elapsed.Seconds()))
+			write(analysisEvent{
+				Action:  testjson.ActionFail,
+				Package: pkg,
+				Test:    test,
+				Elapsed: result.elapsed.Seconds(),
+			})
+		}
+		write(analysisEvent{Action: testjson.ActionOutput, Package: pkg, Output: "FAIL\n"})
+		write(analysisEvent{Action: testjson.ActionFail, Package: pkg, Elapsed: elapsed.Seconds()})
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestAnalysisCommandsValue_Set(t *testing.T) {
+	var v analysisCommandsValue
+	assert.NilError(t, v.Set("staticcheck=staticcheck -checks 'all,-ST1000'"))
+	assert.NilError(t, v.Set("lint = golangci-lint run --out-format=line-number"))
+	assert.Equal(t, v.String(), "staticcheck,lint")
+	assert.DeepEqual(t, v[0].command, []string{"staticcheck", "-checks", "all,-ST1000"})
+	assert.DeepEqual(t, v[1].command, []string{"golangci-lint", "run", "--out-format=line-number"})
+
+	assert.ErrorContains(t, v.Set("staticcheck=staticcheck"), "duplicate analysis command name staticcheck")
+	assert.ErrorContains(t, v.Set("GoVet=go vet"), "the name GoVet is used by --vet-mode=separate")
+	assert.ErrorContains(t, v.Set("staticcheck"), "must be NAME=COMMAND")
+	assert.ErrorContains(t, v.Set("static check=staticcheck"), "must be NAME=COMMAND")
+	assert.ErrorContains(t, v.Set("empty="), "the command is empty")
+}
+
+func TestParseAnalysisOutput(t *testing.T) {
+	cwd := filepath.FromSlash("/work/project")
+	dirs := map[string]string{
+		filepath.Join(cwd, "one"): "example.com/project/one",
+		filepath.Join(cwd, "two"): "example.com/project/two",
+	}
+	out := `one/a.go:12:3: should use strings.ReplaceAll (S1020)
+two/b.go:4:1: exported function Two should have comment (ST1000)
+	func Two() {}
+	^
+` + filepath.Join(cwd, "one", "c.go") + `:7: unused variable x
+other/d.go:1:1: not a package that was tested
+level=warning msg="something"
+`
+	problems, other := parseAnalysisOutput([]byte(out), dirs, cwd)
+	expected := []analysisProblem{
+		{pkg: "example.com/project/one", line: "one/a.go:12:3: should use strings.ReplaceAll (S1020)"},
+		{pkg: "example.com/project/two", line: "two/b.go:4:1: exported function Two should have comment (ST1000)"},
+		{pkg: "example.com/project/two", line: "\tfunc Two() {}"},
+		{pkg: "example.com/project/two", line: "\t^"},
+		{pkg: "example.com/project/one", line: filepath.Join(cwd, "one", "c.go") + ":7: unused variable x"},
+	}
+	assert.DeepEqual(t, problems, expected, cmpAnalysisProblem)
+	assert.DeepEqual(t, other, []string{
+		"other/d.go:1:1: not a package that was tested",
+		`level=warning msg="something"`,
+	})
+}
+
+func TestRunAnalysisStage_WithAnalysisCommands(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NilError(t, err)
+
+	var commands [][]string
+	defer patchRunAnalysisFn(func(args []string) ([]byte, error) {
+		commands = append(commands, args)
+		switch args[0] {
+		case "go":
+			return []byte(`{
+	"example.com/pkg/b": {
+		"printf": [{"posn": "b/b.go:4:2", "message": "bad format"}]
+	}
+}
+`), nil
+		case "staticcheck":
+			return []byte("a/a.go:3:1: should omit comparison to bool constant (S1002)\n" +
+				"b/b.go:9:6: func unused is unused (U1000)\n"), nil
+		}
+		return []byte("lint: warning: no go files\n"), nil
+	})()
+	var listArgs []string
+	orig := goListDirsFn
+	goListDirsFn = func(args []string) (map[string]string, error) {
+		listArgs = args
+		return map[string]string{
+			filepath.Join(cwd, "a"): "example.com/pkg/a",
+			filepath.Join(cwd, "b"): "example.com/pkg/b",
+		}, nil
+	}
+	defer func() { goListDirsFn = orig }()
+
+	opts := &options{
+		vetMode:  vetModeSeparate,
+		packages: []string{"./..."},
+		args:     []string{"-tags=integration"},
+	}
+	assert.NilError(t, opts.analysisCommands.Set("staticcheck=staticcheck -checks all"))
+	assert.NilError(t, opts.analysisCommands.Set("lint=lint"))
+
+	exec, err := runAnalysisStage(context.Background(), opts, noopHandler{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, commands, [][]string{
+		{"go", "vet", "-json", "-tags=integration", "./..."},
+		{"staticcheck", "-checks", "all", "./..."},
+		{"lint", "./..."},
+	})
+	assert.DeepEqual(t, listArgs, []string{"-tags=integration", "./..."})
+
+	var failed []string
+	for _, tc := range exec.Failed() {
+		failed = append(failed, tc.Package+" "+tc.Test.Name())
+	}
+	assert.DeepEqual(t, failed, []string{
+		"example.com/pkg/a staticcheck",
+		"example.com/pkg/b GoVet",
+		"example.com/pkg/b staticcheck",
+	})
+	assert.Equal(t, analysisFailed(opts, exec), 3)
+	assert.DeepEqual(t, exec.Errors(), []string{"lint: lint: warning: no go files"})
+
+	tc := exec.Failed()[2]
+	output := strings.Join(exec.OutputLines(tc), "")
+	assert.Assert(t, is.Contains(output, "    b/b.go:9:6: func unused is unused (U1000)\n"))
+
+	assert.Equal(t, len(withoutAnalysisTestCases(opts)(exec.Failed())), 0)
+}
+
+func TestRun_AnalysisCommandContinue(t *testing.T) {
+	defer patchRunAnalysisFn(func([]string) ([]byte, error) {
+		return []byte("a.go:3:1: should omit comparison to bool constant (S1002)\n"),
+			newExitCode("staticcheck failed", 1)
+	})()
+	cwd, err := os.Getwd()
+	assert.NilError(t, err)
+	orig := goListDirsFn
+	goListDirsFn = func([]string) (map[string]string, error) {
+		return map[string]string{cwd: "example.com/pkg"}, nil
+	}
+	defer func() { goListDirsFn = orig }()
+
+	jsonPassed := `{"Package": "example.com/pkg", "Action": "run"}
+{"Package": "example.com/pkg", "Test": "TestOne", "Action": "run"}
+{"Package": "example.com/pkg", "Test": "TestOne", "Action": "pass"}
+{"Package": "example.com/pkg", "Action": "pass"}
+`
+	var goTestArgs []string
+	defer patchStartGoTestFn(func(args []string) *proc {
+		goTestArgs = args
+		return &proc{
+			cmd:    fakeWaiter{},
+			stdout: strings.NewReader(jsonPassed),
+			stderr: bytes.NewReader(nil),
+		}
+	})()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		format:      "testname",
+		vetContinue: true,
+		stdout:      out,
+		stderr:      out,
+		hideSummary: newHideSummaryValue(),
+	}
+	assert.NilError(t, opts.analysisCommands.Set("staticcheck=staticcheck"))
+	err = run(opts)
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+	assert.DeepEqual(t, goTestArgs, []string{"go", "test", "-json", "./..."})
+	assert.Assert(t, is.Contains(out.String(), "FAIL example.com/pkg.staticcheck"))
+	assert.Assert(t, is.Contains(out.String(), "DONE 2 tests, 1 failure"))
+}
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/analytics"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// analyticsUploadTimeout is the maximum time to upload the results, including
+// retries.
+const analyticsUploadTimeout = 2 * time.Minute
+
+// headersValue is a flag.Value which adds a header for each value. The format
+// of the value is: NAME: VALUE. Environment variables in the value are
+// expanded, so that a secret does not need to be in the command line.
+type headersValue map[string]string
+
+func (v *headersValue) String() string {
+	names := make([]string, 0, len(*v))
+	for name := range *v {
+		names = append(names, name)
+	}
+	return strings.Join(names, ",")
+}
+
+func (v *headersValue) Set(raw string) error {
+	name, value := cutString(raw, ":")
+	name = strings.TrimSpace(name)
+	if name == "" || !strings.Contains(raw, ":") {
+		return fmt.Errorf("invalid header %q, must be NAME: VALUE", raw)
+	}
+	if *v == nil {
+		*v = make(map[string]string)
+	}
+	(*v)[name] = os.ExpandEnv(strings.TrimSpace(value))
+	return nil
+}
+
+func (v *headersValue) Type() string {
+	return "header"
+}
+
+func validateAnalyticsUpload(opts *options) error {
+	if opts.analyticsUpload == "" {
+		return nil
+	}
+	if opts.analyticsUploadRetries < 0 {
+		return fmt.Errorf("--analytics-upload-retries must not be negative")
+	}
+	if _, err := analytics.New(opts.analyticsUpload, nil, 0); err != nil {
+		return fmt.Errorf("invalid --analytics-upload: %w", err)
+	}
+	return nil
+}
+
+// newAnalyticsUploader is a shim for testing.
+var newAnalyticsUploader = analytics.New
+
+// uploadAnalytics uploads the results of the run to opts.analyticsUpload. A
+// failed upload is printed as a warning, because the upload must not change
+// the result of the run.
+func uploadAnalytics(opts *options, execs ...*testjson.Execution) error {
+	if opts.analyticsUpload == "" {
+		return nil
+	}
+	uploader, err := newAnalyticsUploader(
+		opts.analyticsUpload, opts.analyticsUploadHeaders, opts.analyticsUploadRetries)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), analyticsUploadTimeout)
+	defer cancel()
+	if err := uploader.Upload(ctx, newAnalyticsResults(opts, execs)); err != nil {
+		log.Warnf("Failed to upload results to --analytics-upload: %v", err)
+	}
+	return nil
+}
+
+func newAnalyticsResults(opts *options, execs []*testjson.Execution) analytics.Results {
+	results := analytics.Results{
+		Version: analytics.Version,
+		RunID:   opts.runID,
+		Git:     analyticsGitMetadata(),
+		Tests:   []analytics.Test{},
+	}
+	ci := detectCI()
+	results.CI = ci.CI
+	if ci.Branch != "" {
+		results.Git.Branch = ci.Branch
+	}
+
+	var elapsed time.Duration
+	for _, exec := range execs {
+		if results.Started.IsZero() || exec.Started().Before(results.Started) {
+			results.Started = exec.Started()
+		}
+		elapsed += exec.Elapsed()
+		results.Errors = append(results.Errors, exec.Errors()...)
+
+		for _, name := range exec.Packages() {
+			pkg := exec.Package(name)
+			for _, result := range []struct {
+				action testjson.Action
+				tcs    []testjson.TestCase
+				count  *int
+			}{
+				{action: testjson.ActionPass, tcs: pkg.Passed, count: &results.Summary.Passed},
+				{action: testjson.ActionFail, tcs: pkg.Failed, count: &results.Summary.Failed},
+				{action: testjson.ActionSkip, tcs: pkg.Skipped, count: &results.Summary.Skipped},
+			} {
+				*result.count += len(result.tcs)
+				for _, tc := range result.tcs {
+					test := analytics.Test{
+						Package: tc.Package,
+						Name:    tc.Test.Name(),
+						Result:  string(result.action),
+						Elapsed: tc.Elapsed.Seconds(),
+						Attempt: tc.RunID,
+					}
+					if result.action == testjson.ActionFail {
+						test.Output = strings.Join(pkg.OutputLines(tc), "")
+					}
+					results.Tests = append(results.Tests, test)
+				}
+			}
+		}
+	}
+	results.Started = results.Started.UTC()
+	results.Elapsed = elapsed.Seconds()
+	results.Summary.Total = len(results.Tests)
+	results.Summary.Errors = len(results.Errors)
+	return results
+}
+
+// analyticsGitMetadata is a shim for testing.
+var analyticsGitMetadata = func() analytics.Git {
+	return analytics.Git{
+		SHA:        gitOutput("rev-parse", "HEAD"),
+		Branch:     gitOutput("rev-parse", "--abbrev-ref", "HEAD"),
+		Repository: gitOutput("config", "--get", "remote.origin.url"),
+	}
+}
+
+func gitOutput(args ...string) string {
+	log.Debugf("exec: git %s", args)
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		log.Debugf("git %s: %v", args, err)
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// ciMetadata is the CI job that is running gotestsum, and the branch that is
+// being tested. In CI the git checkout is often a detached HEAD, so the branch
+// is read from the environment.
+type ciMetadata struct {
+	analytics.CI
+	Branch string
+}
+
+// detectCI returns the metadata of the CI job from the variables each CI
+// system sets in the environment.
+func detectCI() ciMetadata {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		branch := os.Getenv("GITHUB_HEAD_REF")
+		if branch == "" {
+			branch = os.Getenv("GITHUB_REF_NAME")
+		}
+		return ciMetadata{
+			CI: analytics.CI{
+				Provider: "github-actions",
+				BuildID:  os.Getenv("GITHUB_RUN_ID"),
+				BuildURL: fmt.Sprintf("%s/%s/actions/runs/%s",
+					os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID")),
+				Job: os.Getenv("GITHUB_JOB"),
+			},
+			Branch: branch,
+		}
+	case os.Getenv("GITLAB_CI") == "true":
+		return ciMetadata{
+			CI: analytics.CI{
+				Provider: "gitlab",
+				BuildID:  os.Getenv("CI_PIPELINE_ID"),
+				BuildURL: os.Getenv("CI_JOB_URL"),
+				Job:      os.Getenv("CI_JOB_NAME"),
+			},
+			Branch: os.Getenv("CI_COMMIT_REF_NAME"),
+		}
+	case os.Getenv("BUILDKITE") == "true":
+		return ciMetadata{
+			CI: analytics.CI{
+				Provider: "buildkite",
+				BuildID:  os.Getenv("BUILDKITE_BUILD_ID"),
+				BuildURL: os.Getenv("BUILDKITE_BUILD_URL"),
+				Job:      os.Getenv("BUILDKITE_LABEL"),
+			},
+			Branch: os.Getenv("BUILDKITE_BRANCH"),
+		}
+	case os.Getenv("CIRCLECI") == "true":
+		return ciMetadata{
+			CI: analytics.CI{
+				Provider: "circleci",
+				BuildID:  os.Getenv("CIRCLE_WORKFLOW_ID"),
+				BuildURL: os.Getenv("CIRCLE_BUILD_URL"),
+				Job:      os.Getenv("CIRCLE_JOB"),
+			},
+			Branch: os.Getenv("CIRCLE_BRANCH"),
+		}
+	}
+	return ciMetadata{}
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/analytics"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestUploadAnalytics(t *testing.T) {
+	defer env.PatchAll(t, map[string]string{
+		"GITHUB_ACTIONS":    "true",
+		"GITHUB_SERVER_URL": "https://github.com",
+		"GITHUB_REPOSITORY": "example/repo",
+		"GITHUB_RUN_ID":     "1234",
+		"GITHUB_JOB":        "test",
+		"GITHUB_HEAD_REF":   "",
+		"GITHUB_REF_NAME":   "main",
+		"API_TOKEN":         "secret",
+	})()
+	origGit := analyticsGitMetadata
+	analyticsGitMetadata = func() analytics.Git {
+		return analytics.Git{SHA: "abc123", Branch: "HEAD", Repository: "git@github.com:example/repo.git"}
+	}
+	defer func() {
+		analyticsGitMetadata = origGit
+	}()
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestFlaky","Output":"=== RUN   TestFlaky\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestFlaky","Output":"    flaky_test.go:10: timeout\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestFlaky","Output":"--- FAIL: TestFlaky (0.50s)\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFlaky","Elapsed":0.5}
+{"Action":"run","Package":"example.com/pkg","Test":"TestSkipped"}
+{"Action":"skip","Package":"example.com/pkg","Test":"TestSkipped","Elapsed":0}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.6}
+`),
+	})
+	assert.NilError(t, err)
+	_, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		RunID:     1,
+		Execution: exec,
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestFlaky","Elapsed":0.25}
+{"Action":"pass","Package":"example.com/pkg","Elapsed":0.3}
+`),
+	})
+	assert.NilError(t, err)
+
+	var got analytics.Results
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Check(t, r.Header.Get("Authorization") == "Bearer secret")
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Check(t, json.Unmarshal(body, &got))
+	}))
+	defer srv.Close()
+
+	flags, opts := setupFlags("gotestsum")
+	assert.NilError(t, flags.Parse([]string{
+		"--analytics-upload", srv.URL,
+		"--analytics-upload-header", "Authorization: Bearer $API_TOKEN",
+	}))
+	opts.runID = "run-1"
+	assert.NilError(t, uploadAnalytics(opts, exec))
+
+	assert.Equal(t, got.Version, 1)
+	assert.Equal(t, got.RunID, "run-1")
+	assert.DeepEqual(t, got.Git, analytics.Git{
+		SHA:        "abc123",
+		Branch:     "main",
+		Repository: "git@github.com:example/repo.git",
+	})
+	assert.DeepEqual(t, got.CI, analytics.CI{
+		Provider: "github-actions",
+		BuildID:  "1234",
+		BuildURL: "https://github.com/example/repo/actions/runs/1234",
+		Job:      "test",
+	})
+	assert.DeepEqual(t, got.Summary, analytics.Summary{Total: 3, Passed: 1, Failed: 1, Skipped: 1})
+	assert.DeepEqual(t, got.Tests, []analytics.Test{
+		{Package: "example.com/pkg", Name: "TestFlaky", Result: "pass", Elapsed: 0.25, Attempt: 1},
+		{
+			Package: "example.com/pkg",
+			Name:    "TestFlaky",
+			Result:  "fail",
+			Elapsed: 0.5,
+			Output:  "=== RUN   TestFlaky\n    flaky_test.go:10: timeout\n--- FAIL: TestFlaky (0.50s)\n",
+		},
+		{Package: "example.com/pkg", Name: "TestSkipped", Result: "skip"},
+	})
+}
+
+func TestUploadAnalytics_FailureIsNotAnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader("")})
+	assert.NilError(t, err)
+	assert.NilError(t, uploadAnalytics(&options{analyticsUpload: srv.URL}, exec))
+}
+
+func TestHeadersValue_Set(t *testing.T) {
+	var v headersValue
+	assert.NilError(t, v.Set("X-Api-Key:  key"))
+	assert.NilError(t, v.Set("Authorization: Bearer a:b"))
+	assert.DeepEqual(t, v, headersValue{"X-Api-Key": "key", "Authorization": "Bearer a:b"})
+
+	assert.Error(t, v.Set("X-Api-Key"), `invalid header "X-Api-Key", must be NAME: VALUE`)
+	assert.Error(t, v.Set(": value"), `invalid header ": value", must be NAME: VALUE`)
+}
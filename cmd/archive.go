@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/history"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Names of the files written to each run directory in the archive.
+const (
+	archiveEventsFile  = "events.json"
+	archiveSummaryFile = "summary.json"
+	archiveJUnitFile   = "junit.xml"
+)
+
+// openHistory is a shim for testing
+var openHistory = history.Open
+
+// maxArchiveRunsPerSecond is the number of runs that can be archived with the
+// same timestamp in a directory.
+const maxArchiveRunsPerSecond = 100
+
+// setupArchive creates a new directory for the run in opts.archiveDir. The
+// path of the directory is stored in opts.archivePath. When opts.archiveDir is
+// the URL of a remote history store, the files of the run are written to a
+// temporary directory, and copied to the store by writeArchive.
+//
+// The name of the run is the timestamp of now. A run in a directory that has
+// the same timestamp as an existing run is given the next unused counter as a
+// suffix. A run in a remote store has the run ID as a suffix, because the
+// store can not create a run only if it does not exist.
+func setupArchive(opts *options, now time.Time) error {
+	if opts.archiveDir == "" {
+		return nil
+	}
+	timestamp := now.Format(outputPathTimestampFormat)
+	if history.IsRemote(opts.archiveDir) {
+		opts.archiveRun = timestamp
+		if opts.runID != "" {
+			opts.archiveRun += "-" + opts.runID
+		}
+		dir, err := ioutil.TempDir("", "gotestsum-archive-")
+		if err != nil {
+			return fmt.Errorf("failed to create archive directory: %w", err)
+		}
+		opts.archivePath = dir
+		return nil
+	}
+	if err := os.MkdirAll(opts.archiveDir, 0755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	for i := 0; i < maxArchiveRunsPerSecond; i++ {
+		name := timestamp
+		if i > 0 {
+			name = fmt.Sprintf("%v-%d", timestamp, i)
+		}
+		path := filepath.Join(opts.archiveDir, name)
+		switch err := os.Mkdir(path, 0755); {
+		case os.IsExist(err):
+			continue
+		case err != nil:
+			return fmt.Errorf("failed to create archive directory: %w", err)
+		}
+		opts.archiveRun, opts.archivePath = name, path
+		return nil
+	}
+	return fmt.Errorf("failed to create archive directory: more than %d runs started at %v",
+		maxArchiveRunsPerSecond, timestamp)
+}
+
+// archiveSummary is the structure of the summary.json file written to each
+// run directory in the archive.
+type archiveSummary struct {
+	// ID is the ID of the run, from --run-id or a random ID.
+	ID      string `json:",omitempty"`
+	Command []string
+	Started time.Time
+	Elapsed time.Duration
+	Total   int
+	Failed  []archiveTestCase
+	Skipped int
+	Errors  []string
+	// AutoParallel is the result of --auto-parallel.
+	AutoParallel *autoParallel `json:",omitempty"`
+	// FirstFailure is the first test or package that failed, and the time from
+	// the start of the run to the failure.
+	FirstFailure *testjson.FirstFailure `json:",omitempty"`
+	// Traceparent is the W3C trace context of the run, from --trace-context.
+	Traceparent string `json:",omitempty"`
+	// Packages is the elapsed time of each package that ran tests, used as the
+	// baseline of --format-slow-package-percent.
+	Packages map[string]time.Duration `json:",omitempty"`
+}
+
+type archiveTestCase struct {
+	Package string
+	Test    string
+	// OriginalTest is the name of the test before it was changed by
+	// --rewrite-test-name. It is empty if the name was not changed.
+	OriginalTest string `json:",omitempty"`
+	Elapsed      time.Duration
+	RunID        int
+}
+
+func newArchiveSummary(opts *options, exec *testjson.Execution) archiveSummary {
+	summary := archiveSummary{
+		ID:      opts.runID,
+		Command: goTestCmdArgs(opts, rerunOpts{}),
+		Started: exec.Started(),
+		Elapsed: exec.Elapsed(),
+		Total:   exec.Total(),
+		Skipped: len(exec.Skipped()),
+		Errors:  exec.Errors(),
+
+		AutoParallel: opts.autoParallelValues,
+		FirstFailure: exec.FirstFailure(),
+	}
+	if opts.traceContext {
+		summary.Traceparent = opts.traceSpan.Traceparent()
+	}
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		if pkg.Total == 0 || pkg.Elapsed() <= 0 {
+			continue
+		}
+		if summary.Packages == nil {
+			summary.Packages = make(map[string]time.Duration)
+		}
+		summary.Packages[name] = pkg.Elapsed()
+	}
+	for _, tc := range exec.Failed() {
+		atc := archiveTestCase{
+			Package: tc.Package,
+			Test:    tc.Test.Name(),
+			Elapsed: tc.Elapsed,
+			RunID:   tc.RunID,
+		}
+		if original := tc.OriginalName(); original != tc.Test {
+			atc.OriginalTest = original.Name()
+		}
+		summary.Failed = append(summary.Failed, atc)
+	}
+	return summary
+}
+
+// writeArchive writes the summary and JUnit XML files for the run to the
+// archive, and removes old runs from the archive. The events file is written
+// by the eventHandler.
+func writeArchive(opts *options, exec *testjson.Execution) error {
+	if opts.archivePath == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(newArchiveSummary(opts, exec), "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(opts.archivePath, archiveSummaryFile)
+	if err := ioutil.WriteFile(path, raw, 0644); err != nil {
+		return err
+	}
+
+	junitOpts := *opts
+	junitOpts.junitFile = filepath.Join(opts.archivePath, archiveJUnitFile)
+	if err := writeJUnitFile(&junitOpts, exec); err != nil {
+		return err
+	}
+
+	store, err := openHistory(opts.archiveDir)
+	if err != nil {
+		return err
+	}
+	if history.IsRemote(opts.archiveDir) {
+		if err := copyArchiveRun(store, opts.archivePath, opts.archiveRun); err != nil {
+			return err
+		}
+	}
+	return removeOldArchiveRuns(store, opts.archiveKeep, opts.archiveKeepDays, exec.Started())
+}
+
+// copyArchiveRun copies the files of the run in dir to the store, and removes
+// dir.
+func copyArchiveRun(store history.Store, dir string, run string) error {
+	ctx := context.Background()
+	for _, name := range []string{archiveEventsFile, archiveSummaryFile, archiveJUnitFile} {
+		raw, err := ioutil.ReadFile(filepath.Join(dir, name))
+		switch {
+		case os.IsNotExist(err):
+			continue
+		case err != nil:
+			return err
+		}
+		if err := store.WriteFile(ctx, run, name, raw); err != nil {
+			return fmt.Errorf("failed to write %v to %v: %w", name, run, err)
+		}
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		log.Debugf("failed to remove %v: %v", dir, err)
+	}
+	return nil
+}
+
+// archiveRun is a run directory in the archive.
+type archiveRun struct {
+	name    string
+	started time.Time
+}
+
+// archiveRuns returns the runs in the archive store, sorted with the most
+// recent run first.
+func archiveRuns(store history.Store) ([]archiveRun, error) {
+	names, err := store.Runs(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []archiveRun
+	for _, name := range names {
+		started, err := archiveRunStarted(name)
+		if err != nil {
+			continue
+		}
+		runs = append(runs, archiveRun{name: name, started: started})
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		a, b := runs[i], runs[j]
+		switch {
+		case !a.started.Equal(b.started):
+			return a.started.After(b.started)
+		case len(a.name) != len(b.name):
+			return len(a.name) > len(b.name)
+		default:
+			return a.name > b.name
+		}
+	})
+	return runs, nil
+}
+
+// archiveRunStarted returns the time from the name of a run directory, which
+// is the timestamp, optionally followed by a dash and a suffix added by
+// setupArchive.
+func archiveRunStarted(name string) (time.Time, error) {
+	timestamp := name
+	if i := strings.Index(name, "-"); i >= 0 {
+		timestamp = name[:i]
+	}
+	return time.ParseInLocation(outputPathTimestampFormat, timestamp, time.Local)
+}
+
+// removeOldArchiveRuns removes all except the most recent keep runs from the
+// archive store, and any runs older than keepDays. A value of 0 for keep or
+// keepDays disables that limit.
+func removeOldArchiveRuns(store history.Store, keep int, keepDays int, now time.Time) error {
+	runs, err := archiveRuns(store)
+	if err != nil {
+		return err
+	}
+
+	cutoff := now.AddDate(0, 0, -keepDays)
+	for i, r := range runs {
+		if (keep <= 0 || i < keep) && (keepDays <= 0 || !r.started.Before(cutoff)) {
+			continue
+		}
+		log.Debugf("removing archived run %v", r.name)
+		if err := store.RemoveRun(context.Background(), r.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// multiWriteCloser writes to and closes all of the wrapped WriteClosers.
+type multiWriteCloser []io.WriteCloser
+
+func (m multiWriteCloser) Write(p []byte) (int, error) {
+	for _, w := range m {
+		if _, err := w.Write(p); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (m multiWriteCloser) Close() error {
+	var firstErr error
+	for _, w := range m {
+		if err := w.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/internal/history"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+)
+
+func TestWriteArchive(t *testing.T) {
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+
+	opts := &options{archiveDir: dir.Path()}
+	assert.NilError(t, setupArchive(opts, time.Now()))
+
+	exec := newExecFromTestData(t)
+	assert.NilError(t, writeArchive(opts, exec))
+
+	_, err := os.Stat(filepath.Join(opts.archivePath, archiveJUnitFile))
+	assert.NilError(t, err)
+
+	raw, err := ioutil.ReadFile(filepath.Join(opts.archivePath, archiveSummaryFile))
+	assert.NilError(t, err)
+	var summary archiveSummary
+	assert.NilError(t, json.Unmarshal(raw, &summary))
+	assert.Equal(t, summary.Total, 46)
+	assert.Equal(t, len(summary.Failed), 5)
+	assert.DeepEqual(t, summary.Command, []string{"go", "test", "-json", "./..."})
+}
+
+func TestSetupArchive_RunsInTheSameSecond(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+
+	now := time.Date(2021, 6, 10, 12, 0, 0, 0, time.Local)
+	var names []string
+	for i := 0; i < 3; i++ {
+		opts := &options{archiveDir: dir.Path()}
+		assert.NilError(t, setupArchive(opts, now))
+		assert.Equal(t, opts.archivePath, dir.Join(opts.archiveRun))
+		names = append(names, opts.archiveRun)
+	}
+	assert.DeepEqual(t, names, []string{"20210610T120000", "20210610T120000-1", "20210610T120000-2"})
+
+	runs, err := archiveRuns(history.Dir(dir.Path()))
+	assert.NilError(t, err)
+	assert.Equal(t, len(runs), 3)
+	assert.Equal(t, runs[0].name, "20210610T120000-2")
+	assert.Assert(t, runs[0].started.Equal(now))
+	assert.Equal(t, runs[2].name, "20210610T120000")
+}
+
+func TestRemoveOldArchiveRuns(t *testing.T) {
+	now := time.Date(2021, 6, 10, 12, 0, 0, 0, time.Local)
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithDir("20210601T120000"),
+		fs.WithDir("20210605T120000"),
+		fs.WithDir("20210608T120000"),
+		fs.WithDir("20210610T110000"),
+		fs.WithDir("not-a-run"))
+	defer dir.Remove()
+
+	t.Run("keep days", func(t *testing.T) {
+		assert.NilError(t, removeOldArchiveRuns(history.Dir(dir.Path()), 0, 7, now))
+		assert.Assert(t, fs.Equal(dir.Path(), fs.Expected(t,
+			fs.WithDir("20210605T120000"),
+			fs.WithDir("20210608T120000"),
+			fs.WithDir("20210610T110000"),
+			fs.WithDir("not-a-run"))))
+	})
+	t.Run("keep runs", func(t *testing.T) {
+		assert.NilError(t, removeOldArchiveRuns(history.Dir(dir.Path()), 1, 0, now))
+		assert.Assert(t, fs.Equal(dir.Path(), fs.Expected(t,
+			fs.WithDir("20210610T110000"),
+			fs.WithDir("not-a-run"))))
+	})
+}
+
+func TestWriteArchive_RemoteStore(t *testing.T) {
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+	dir := fs.NewDir(t, t.Name())
+	orig := openHistory
+	openHistory = func(location string) (history.Store, error) {
+		assert.Equal(t, location, "https://ci.example.com/history")
+		return history.Dir(dir.Path()), nil
+	}
+	defer func() { openHistory = orig }()
+
+	now := time.Date(2021, 6, 10, 12, 0, 0, 0, time.Local)
+	opts := &options{archiveDir: "https://ci.example.com/history", runID: "ci-1234"}
+	assert.NilError(t, setupArchive(opts, now))
+	staging := opts.archivePath
+	assert.Assert(t, !strings.HasPrefix(staging, dir.Path()))
+
+	exec := newExecFromTestData(t)
+	assert.NilError(t, writeArchive(opts, exec))
+
+	run := now.Format(outputPathTimestampFormat) + "-ci-1234"
+	assert.Equal(t, opts.archiveRun, run)
+	_, err := os.Stat(dir.Join(run, archiveJUnitFile))
+	assert.NilError(t, err)
+	_, err = os.Stat(dir.Join(run, archiveSummaryFile))
+	assert.NilError(t, err)
+	_, err = os.Stat(staging)
+	assert.Assert(t, os.IsNotExist(err), "staging directory was not removed")
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"gotest.tools/gotestsum/log"
+)
+
+// autoParallel is the result of --auto-parallel. It is recorded in the
+// summary.json of the archive, so that the values used for a run can be found
+// later.
+type autoParallel struct {
+	// CPUs is the number of CPUs available to the process, after applying the
+	// cgroup CPU quota.
+	CPUs int
+	// MemoryLimit is the cgroup memory limit in bytes, or 0 if there is no limit.
+	MemoryLimit int64 `json:",omitempty"`
+	// P is the value used for 'go test -p'.
+	P int
+	// Parallel is the value used for 'go test -parallel'.
+	Parallel int
+}
+
+// cgroupRoot is the path where the cgroup filesystem is mounted.
+var cgroupRoot = "/sys/fs/cgroup"
+
+// memoryPerPackage is the amount of memory to reserve for each test binary
+// that 'go test' runs in parallel, when the memory of the process is limited.
+const memoryPerPackage = 1 << 30
+
+// newAutoParallel returns the -p and -parallel values to use for a machine
+// with numCPU CPUs, and the cgroup limits read from root.
+func newAutoParallel(root string, numCPU int) autoParallel {
+	result := autoParallel{CPUs: numCPU}
+	if quota, ok := cgroupCPUQuota(root); ok && quota < float64(numCPU) {
+		result.CPUs = int(math.Ceil(quota))
+	}
+	if result.CPUs < 1 {
+		result.CPUs = 1
+	}
+	result.P = result.CPUs
+	result.Parallel = result.CPUs
+
+	if limit, ok := cgroupMemoryLimit(root); ok {
+		result.MemoryLimit = limit
+		if byMemory := int(limit / memoryPerPackage); byMemory < result.P {
+			result.P = byMemory
+		}
+		if result.P < 1 {
+			result.P = 1
+		}
+	}
+	return result
+}
+
+// cgroupCPUQuota returns the number of CPUs allowed by the cgroup CPU quota.
+// Returns false if there is no quota.
+func cgroupCPUQuota(root string) (float64, bool) {
+	// cgroup v2
+	if raw, err := readCgroupFile(root, "cpu.max"); err == nil {
+		fields := strings.Fields(raw)
+		if len(fields) != 2 || fields[0] == "max" {
+			return 0, false
+		}
+		return cpuQuota(fields[0], fields[1])
+	}
+	// cgroup v1
+	quota, err := readCgroupFile(root, "cpu", "cpu.cfs_quota_us")
+	if err != nil {
+		return 0, false
+	}
+	period, err := readCgroupFile(root, "cpu", "cpu.cfs_period_us")
+	if err != nil {
+		return 0, false
+	}
+	return cpuQuota(quota, period)
+}
+
+func cpuQuota(rawQuota, rawPeriod string) (float64, bool) {
+	quota, err := strconv.ParseInt(rawQuota, 10, 64)
+	if err != nil || quota <= 0 {
+		return 0, false
+	}
+	period, err := strconv.ParseInt(rawPeriod, 10, 64)
+	if err != nil || period <= 0 {
+		return 0, false
+	}
+	return float64(quota) / float64(period), true
+}
+
+// cgroupMemoryLimit returns the cgroup memory limit in bytes. Returns false if
+// there is no limit.
+func cgroupMemoryLimit(root string) (int64, bool) {
+	raw, err := readCgroupFile(root, "memory.max")
+	if err != nil {
+		raw, err = readCgroupFile(root, "memory", "memory.limit_in_bytes")
+	}
+	if err != nil || raw == "max" {
+		return 0, false
+	}
+	limit, err := strconv.ParseInt(raw, 10, 64)
+	// cgroup v1 uses a very large number, rounded to the page size, when there
+	// is no limit.
+	if err != nil || limit <= 0 || limit >= math.MaxInt64/2 {
+		return 0, false
+	}
+	return limit, true
+}
+
+func readCgroupFile(root string, path ...string) (string, error) {
+	raw, err := ioutil.ReadFile(filepath.Join(append([]string{root}, path...)...))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(raw)), nil
+}
+
+// setupAutoParallel sets opts.autoParallelValues when --auto-parallel is
+// enabled.
+func setupAutoParallel(opts *options) {
+	if !opts.autoParallel {
+		return
+	}
+	values := newAutoParallel(cgroupRoot, runtime.NumCPU())
+	log.Debugf("auto-parallel: cpus=%d memory-limit=%d -p=%d -parallel=%d",
+		values.CPUs, values.MemoryLimit, values.P, values.Parallel)
+	opts.autoParallelValues = &values
+}
+
+// autoParallelArgs returns the 'go test' flags set by --auto-parallel. Flags
+// which are already set in args are not changed.
+func autoParallelArgs(opts *options, args []string) []string {
+	values := opts.autoParallelValues
+	if values == nil {
+		return nil
+	}
+	var result []string
+	if start, _ := argIndex("p", args); start < 0 {
+		result = append(result, fmt.Sprintf("-p=%d", values.P))
+	}
+	if start, _ := argIndex("parallel", args); start < 0 {
+		result = append(result, fmt.Sprintf("-parallel=%d", values.Parallel))
+	}
+	return result
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestNewAutoParallel(t *testing.T) {
+	type testCase struct {
+		name     string
+		files    []fs.PathOp
+		numCPU   int
+		expected autoParallel
+	}
+
+	fn := func(t *testing.T, tc testCase) {
+		dir := fs.NewDir(t, "cgroup", tc.files...)
+		actual := newAutoParallel(dir.Path(), tc.numCPU)
+		assert.Equal(t, actual, tc.expected)
+	}
+
+	var testCases = []testCase{
+		{
+			name:     "no cgroup limits",
+			numCPU:   8,
+			expected: autoParallel{CPUs: 8, P: 8, Parallel: 8},
+		},
+		{
+			name: "cgroup v2 unlimited",
+			files: []fs.PathOp{
+				fs.WithFile("cpu.max", "max 100000\n"),
+				fs.WithFile("memory.max", "max\n"),
+			},
+			numCPU:   8,
+			expected: autoParallel{CPUs: 8, P: 8, Parallel: 8},
+		},
+		{
+			name: "cgroup v2 cpu quota rounds up",
+			files: []fs.PathOp{
+				fs.WithFile("cpu.max", "250000 100000\n"),
+			},
+			numCPU:   16,
+			expected: autoParallel{CPUs: 3, P: 3, Parallel: 3},
+		},
+		{
+			name: "cgroup v2 quota larger than cpu count",
+			files: []fs.PathOp{
+				fs.WithFile("cpu.max", "1600000 100000\n"),
+			},
+			numCPU:   4,
+			expected: autoParallel{CPUs: 4, P: 4, Parallel: 4},
+		},
+		{
+			name: "cgroup v2 memory limit",
+			files: []fs.PathOp{
+				fs.WithFile("cpu.max", "400000 100000\n"),
+				fs.WithFile("memory.max", "2147483648\n"),
+			},
+			numCPU:   16,
+			expected: autoParallel{CPUs: 4, MemoryLimit: 2 << 30, P: 2, Parallel: 4},
+		},
+		{
+			name: "memory limit smaller than one package",
+			files: []fs.PathOp{
+				fs.WithFile("memory.max", "536870912\n"),
+			},
+			numCPU:   2,
+			expected: autoParallel{CPUs: 2, MemoryLimit: 512 << 20, P: 1, Parallel: 2},
+		},
+		{
+			name: "cgroup v1",
+			files: []fs.PathOp{
+				fs.WithDir("cpu",
+					fs.WithFile("cpu.cfs_quota_us", "200000\n"),
+					fs.WithFile("cpu.cfs_period_us", "100000\n")),
+				fs.WithDir("memory",
+					fs.WithFile("memory.limit_in_bytes", "9223372036854771712\n")),
+			},
+			numCPU:   8,
+			expected: autoParallel{CPUs: 2, P: 2, Parallel: 2},
+		},
+		{
+			name: "cgroup v1 no quota",
+			files: []fs.PathOp{
+				fs.WithDir("cpu",
+					fs.WithFile("cpu.cfs_quota_us", "-1\n"),
+					fs.WithFile("cpu.cfs_period_us", "100000\n")),
+			},
+			numCPU:   8,
+			expected: autoParallel{CPUs: 8, P: 8, Parallel: 8},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fn(t, tc)
+		})
+	}
+}
+
+func TestGoTestCmdArgs_AutoParallel(t *testing.T) {
+	values := &autoParallel{CPUs: 4, P: 2, Parallel: 4}
+
+	t.Run("no args", func(t *testing.T) {
+		opts := &options{autoParallelValues: values}
+		assert.DeepEqual(t, goTestCmdArgs(opts, rerunOpts{}),
+			[]string{"go", "test", "-json", "-p=2", "-parallel=4", "./..."})
+	})
+
+	t.Run("does not override args", func(t *testing.T) {
+		opts := &options{
+			autoParallelValues: values,
+			args:               []string{"-parallel", "1", "./pkg"},
+		}
+		assert.DeepEqual(t, goTestCmdArgs(opts, rerunOpts{}),
+			[]string{"go", "test", "-json", "-p=2", "-parallel", "1", "./pkg"})
+	})
+}
+
+func TestOptions_Validate_AutoParallelWithRawCommand(t *testing.T) {
+	opts := &options{autoParallel: true, rawCommand: true}
+	assert.ErrorContains(t, opts.Validate(), "--auto-parallel can not be used with --raw-command")
+}
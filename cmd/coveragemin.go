@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// coverageMinRule sets the minimum coverage of the packages which match
+// pattern.
+type coverageMinRule struct {
+	pattern string
+	percent float64
+}
+
+// readCoverageMinFile reads the --coverage-min-file. Each line is a package
+// pattern followed by the minimum coverage percent of the packages which
+// match the pattern. Blank lines and lines that start with # are ignored.
+func readCoverageMinFile(filename string) ([]coverageMinRule, error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close() // nolint: errcheck
+
+	var rules []coverageMinRule
+	scanner := bufio.NewScanner(fh)
+	var lineNum int
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%v:%d: expected a package pattern followed by a percent", filename, lineNum)
+		}
+		if _, err := path.Match(fields[0], ""); err != nil {
+			return nil, fmt.Errorf("%v:%d: invalid package pattern %v: %w", filename, lineNum, fields[0], err)
+		}
+		percent, err := parseCoveragePercent(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%v:%d: %w", filename, lineNum, err)
+		}
+		rules = append(rules, coverageMinRule{pattern: fields[0], percent: percent})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %v: %w", filename, err)
+	}
+	return rules, nil
+}
+
+func parseCoveragePercent(value string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil || percent < 0 || percent > 100 {
+		return 0, fmt.Errorf("invalid percent %v, expected a number from 0 to 100", value)
+	}
+	return percent, nil
+}
+
+// coverageMinimum returns the minimum coverage for pkg. The last rule that
+// matches pkg overrides the rules before it, and the global --coverage-min is
+// used when no rule matches.
+func coverageMinimum(global float64, rules []coverageMinRule, pkg string) float64 {
+	min := global
+	for _, rule := range rules {
+		if matchPackagePattern(rule.pattern, pkg) {
+			min = rule.percent
+		}
+	}
+	return min
+}
+
+// lowCoverage is a package with coverage below its minimum.
+type lowCoverage struct {
+	pkg     string
+	percent float64
+	minimum float64
+}
+
+// findLowCoverage returns the packages with coverage below the --coverage-min,
+// or the minimum from the --coverage-min-file. Packages which did not print
+// their coverage, for example because they have no test files, are ignored.
+func findLowCoverage(opts *options, exec *testjson.Execution) ([]lowCoverage, error) {
+	if exec == nil || (opts.coverageMin == 0 && opts.coverageMinFile == "") {
+		return nil, nil
+	}
+	var rules []coverageMinRule
+	if opts.coverageMinFile != "" {
+		var err error
+		if rules, err = readCoverageMinFile(opts.coverageMinFile); err != nil {
+			return nil, fmt.Errorf("failed to read --coverage-min-file: %w", err)
+		}
+	}
+
+	var result []lowCoverage
+	for _, name := range exec.Packages() {
+		percent, ok := exec.Package(name).CoveragePercent()
+		if !ok {
+			continue
+		}
+		minimum := coverageMinimum(opts.coverageMin, rules, name)
+		if percent < minimum {
+			result = append(result, lowCoverage{pkg: name, percent: percent, minimum: minimum})
+		}
+	}
+	return result, nil
+}
+
+// coverageMinExitErr returns an error when any package has coverage below its
+// minimum.
+func coverageMinExitErr(low []lowCoverage, exitErr error) error {
+	if exitErr != nil || len(low) == 0 {
+		return exitErr
+	}
+	log.Errorf("%d %s below the minimum coverage",
+		len(low), pluralize(len(low), "package is", "packages are"))
+	return exitError{num: 1}
+}
+
+func printLowCoverage(out io.Writer, low []lowCoverage) {
+	if len(low) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n=== Coverage below minimum (%d %s)\n", len(low),
+		pluralize(len(low), "package", "packages"))
+	for _, c := range low {
+		fmt.Fprintf(out, "%s %.1f%% (minimum %.1f%%)\n",
+			testjson.RelativePackagePath(c.pkg), c.percent, c.minimum)
+	}
+}
+
+func validateCoverageMin(opts *options) error {
+	if opts.coverageMin < 0 || opts.coverageMin > 100 {
+		return fmt.Errorf("--coverage-min must be a percent from 0 to 100")
+	}
+	if opts.coverageMinFile == "" {
+		return nil
+	}
+	// Read the file before the tests run, so that a mistake in the file does
+	// not fail the run after all the tests have finished.
+	if _, err := readCoverageMinFile(opts.coverageMinFile); err != nil {
+		return fmt.Errorf("failed to read --coverage-min-file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+const coverageEvents = `{"Action":"run","Package":"example.com/app/api","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/app/api","Test":"TestOne","Elapsed":0.1}
+{"Action":"output","Package":"example.com/app/api","Output":"coverage: 62.5% of statements\n"}
+{"Action":"pass","Package":"example.com/app/api","Elapsed":0.1}
+{"Action":"run","Package":"example.com/app/store","Test":"TestTwo"}
+{"Action":"pass","Package":"example.com/app/store","Test":"TestTwo","Elapsed":0.1}
+{"Action":"output","Package":"example.com/app/store","Output":"coverage: 91.1% of statements\n"}
+{"Action":"pass","Package":"example.com/app/store","Elapsed":0.1}
+{"Action":"output","Package":"example.com/app/tools","Output":"?   \texample.com/app/tools\t[no test files]\n"}
+{"Action":"skip","Package":"example.com/app/tools","Elapsed":0}
+`
+
+func TestFindLowCoverage(t *testing.T) {
+	exec := scanEvents(t, coverageEvents)
+
+	t.Run("global minimum", func(t *testing.T) {
+		opts := &options{coverageMin: 80}
+		low, err := findLowCoverage(opts, exec)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, low, []lowCoverage{
+			{pkg: "example.com/app/api", percent: 62.5, minimum: 80},
+		}, cmpLowCoverage)
+
+		out := new(bytes.Buffer)
+		printLowCoverage(out, low)
+		assert.Equal(t, out.String(),
+			"\n=== Coverage below minimum (1 package)\nexample.com/app/api 62.5% (minimum 80.0%)\n")
+		assert.Equal(t, ExitCodeWithDefault(coverageMinExitErr(low, nil)), 1)
+	})
+
+	t.Run("per package minimum", func(t *testing.T) {
+		file := fs.NewFile(t, t.Name(), fs.WithContent(`
+# the api is new
+example.com/app/api 50
+example.com/app/... 95%
+example.com/app/api 60
+`))
+		defer file.Remove()
+
+		opts := &options{coverageMin: 80, coverageMinFile: file.Path()}
+		low, err := findLowCoverage(opts, exec)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, low, []lowCoverage{
+			{pkg: "example.com/app/store", percent: 91.1, minimum: 95},
+		}, cmpLowCoverage)
+	})
+
+	t.Run("no minimum", func(t *testing.T) {
+		low, err := findLowCoverage(&options{}, exec)
+		assert.NilError(t, err)
+		assert.Equal(t, len(low), 0)
+		assert.NilError(t, coverageMinExitErr(low, nil))
+	})
+}
+
+var cmpLowCoverage = gocmp.AllowUnexported(lowCoverage{})
+
+func TestValidateCoverageMin(t *testing.T) {
+	opts := &options{coverageMin: 120}
+	assert.ErrorContains(t, opts.Validate(), "--coverage-min must be a percent from 0 to 100")
+
+	file := fs.NewFile(t, t.Name(), fs.WithContent("example.com/app/... high\n"))
+	defer file.Remove()
+	opts = &options{coverageMinFile: file.Path()}
+	assert.ErrorContains(t, opts.Validate(), ":1: invalid percent high")
+
+	opts = &options{coverageMin: 75}
+	assert.NilError(t, opts.Validate())
+}
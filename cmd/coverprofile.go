@@ -0,0 +1,30 @@
+package cmd
+
+import "strings"
+
+// coverProfileArg returns the value of the -coverprofile flag in args, or an
+// empty string if the flag is not set. The -test.coverprofile flag of a test
+// binary, used with --raw-command, is also accepted.
+func coverProfileArg(args []string) string {
+	for i, arg := range args {
+		if arg == "-args" || arg == "--args" {
+			break
+		}
+		name, value := cutString(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || (name != "coverprofile" && name != "test.coverprofile") {
+			continue
+		}
+		if !strings.Contains(arg, "=") && i+1 < len(args) {
+			value = args[i+1]
+		}
+		return value
+	}
+	return ""
+}
+
+// setupCoverProfile sets the CoverProfile of the format options, so that the
+// total coverage in the summary is weighted by the number of statements in
+// each package.
+func setupCoverProfile(opts *options) {
+	opts.formatOptions.CoverProfile = coverProfileArg(opts.args)
+}
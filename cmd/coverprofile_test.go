@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestCoverProfileArg(t *testing.T) {
+	var testCases = map[string]struct {
+		args     []string
+		expected string
+	}{
+		"no args":         {},
+		"no flag":         {args: []string{"-cover", "./..."}},
+		"equals":          {args: []string{"-coverprofile=cover.out", "./..."}, expected: "cover.out"},
+		"separate value":  {args: []string{"--coverprofile", "cover.out", "./..."}, expected: "cover.out"},
+		"test binary":     {args: []string{"./pkg.test", "-test.coverprofile=c.out"}, expected: "c.out"},
+		"after args":      {args: []string{"./...", "-args", "-coverprofile=cover.out"}},
+		"similar flag":    {args: []string{"-coverpkg=./..."}},
+		"missing a value": {args: []string{"-coverprofile"}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, coverProfileArg(tc.args), tc.expected)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// printDryRun prints the commands that would be run by gotestsum with opts,
+// without running any of them.
+func printDryRun(out io.Writer, opts *options) {
+	if cmd := opts.preRunHookCmd.Value(); len(cmd) > 0 {
+		fmt.Fprintf(out, "pre run command:\n    %s\n", formatCommand(cmd))
+	}
+	if opts.vetMode == vetModeSeparate {
+		fmt.Fprintf(out, "go vet command:\n    %s\n", formatCommand(vetCmdArgs(opts)))
+	}
+	for _, c := range opts.analysisCommands {
+		fmt.Fprintf(out, "analysis command (%s):\n    %s\n", c.name, formatCommand(analysisCmdArgs(opts, c)))
+	}
+	if len(opts.pkgGroups) > 0 {
+		for _, group := range opts.pkgGroups {
+			groupOpts := *opts
+			groupOpts.packages = group.packages
+			groupOpts.args = append(append([]string{}, group.args...), opts.args...)
+			fmt.Fprintf(out, "go test command (group %s):\n    %s\n",
+				group.name, formatCommand(goTestCmdArgs(&groupOpts, rerunOpts{})))
+		}
+	} else {
+		fmt.Fprintf(out, "go test command:\n    %s\n",
+			formatCommand(goTestCmdArgs(opts, rerunOpts{})))
+	}
+
+	if v := opts.autoParallelValues; v != nil {
+		fmt.Fprintf(out, "auto-parallel: -p=%d -parallel=%d (cpus: %d, memory limit: %d bytes)\n",
+			v.P, v.Parallel, v.CPUs, v.MemoryLimit)
+	}
+	if maxRerunAttempts(opts) > 0 {
+		example := rerunOpts{
+			runFlag: goTestRunFlagForTestCase("TestName/SubTest"),
+			pkg:     "PACKAGE",
+		}
+		target := "failed test"
+		if opts.rerunFailsScope == rerunScopePackage {
+			example.runFlag = ""
+			target = "package with a failed test"
+		}
+		fmt.Fprintf(out, "rerun command (up to %d times for each %s):\n    %s\n",
+			defaultRerunAttempts(opts), target, formatCommand(goTestCmdArgs(opts, example)))
+		for _, o := range opts.rerunFailsPackages {
+			fmt.Fprintf(out, "    up to %d times for packages matching %s\n", o.attempts, o.pattern)
+		}
+		if opts.rerunFailsDelay > 0 {
+			fmt.Fprintf(out, "    %v before each rerun%s\n", opts.rerunFailsDelay, rerunBackoffDescription(opts))
+		}
+	}
+	if cmd := opts.onFailCmd.Value(); len(cmd) > 0 {
+		fmt.Fprintf(out, "on fail command:\n    %s\n", formatCommand(cmd))
+	}
+	if cmd := opts.postRunHookCmd.Value(); len(cmd) > 0 {
+		fmt.Fprintf(out, "post run command:\n    %s\n", formatCommand(cmd))
+	}
+
+	files := outputFileFlags(opts)
+	files = append(files,
+		outputFileFlag{name: "archive-dir", path: &opts.archiveDir},
+		outputFileFlag{name: "upload", path: &opts.upload})
+	for _, file := range files {
+		if *file.path != "" {
+			fmt.Fprintf(out, "%s: %s\n", file.name, *file.path)
+		}
+	}
+}
+
+// formatCommand joins args into a string, quoting any args which contain
+// whitespace or shell meta characters, so that the command can be copied into
+// a shell.
+func formatCommand(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	return strings.Join(quoted, " ")
+}
+
+func shellQuote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(arg, " \t\n'\"\\$`|&;<>()*?[]{}!#~") {
+		return arg
+	}
+	return "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+}
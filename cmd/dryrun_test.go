@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestPrintDryRun(t *testing.T) {
+	defer env.PatchAll(t, nil)()
+	flags, opts := setupFlags("gotestsum")
+	args := []string{
+		"--rerun-fails",
+		"--packages", "./pkg/... ./cmd",
+		"--junitfile", "out/junit.xml",
+		"--pre-run-command", "docker compose up -d --wait",
+		"--post-run-command", "notify --title 'tests done'",
+		"--", "-tags=integration", "-count=1",
+	}
+	assert.NilError(t, flags.Parse(args))
+	opts.args = flags.Args()
+
+	buf := new(bytes.Buffer)
+	printDryRun(buf, opts)
+	expected := `pre run command:
+    docker compose up -d --wait
+go test command:
+    go test -json -tags=integration -count=1 ./pkg/... ./cmd
+rerun command (up to 2 times for each failed test):
+    go test -json '-test.run=^TestName$/^SubTest$' -tags=integration -count=1 PACKAGE
+post run command:
+    notify --title 'tests done'
+junitfile: out/junit.xml
+`
+	assert.Equal(t, buf.String(), expected)
+}
+
+func TestPrintDryRun_PkgGroups(t *testing.T) {
+	defer env.PatchAll(t, nil)()
+	flags, opts := setupFlags("gotestsum")
+	args := []string{
+		"--pkg-group", "unit=./... : -short",
+		"--pkg-group", "integration=./it/... : -tags=integration -p 1",
+	}
+	assert.NilError(t, flags.Parse(args))
+	opts.args = flags.Args()
+
+	buf := new(bytes.Buffer)
+	printDryRun(buf, opts)
+	expected := `go test command (group unit):
+    go test -json -short ./...
+go test command (group integration):
+    go test -json -tags=integration -p 1 ./it/...
+`
+	assert.Equal(t, buf.String(), expected)
+}
+
+func TestPrintDryRun_RerunPackageScope(t *testing.T) {
+	defer env.PatchAll(t, nil)()
+	flags, opts := setupFlags("gotestsum")
+	args := []string{
+		"--rerun-fails=3",
+		"--rerun-fails-scope", "package",
+		"--rerun-fails-delay", "5s",
+		"--rerun-fails-backoff",
+	}
+	assert.NilError(t, flags.Parse(args))
+	opts.args = flags.Args()
+
+	buf := new(bytes.Buffer)
+	printDryRun(buf, opts)
+	expected := `go test command:
+    go test -json ./...
+rerun command (up to 3 times for each package with a failed test):
+    go test -json PACKAGE
+    5s before each rerun, doubled after each attempt
+`
+	assert.Equal(t, buf.String(), expected)
+}
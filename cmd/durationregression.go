@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"gotest.tools/gotestsum/internal/aggregate"
+	"gotest.tools/gotestsum/internal/history"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// durationBaselineMinRuns is the number of passed runs of a test required in
+// the --history-db before the test is checked by --duration-regression.
+const durationBaselineMinRuns = 3
+
+// durationRegression is a test which was slower than its baseline by more than
+// --duration-regression percent.
+type durationRegression struct {
+	testjson.TestCase
+	baseline time.Duration
+}
+
+func (r durationRegression) percent() int {
+	return int((r.Elapsed - r.baseline) * 100 / r.baseline)
+}
+
+// findDurationRegressions compares the elapsed time of each root test which
+// passed to the median elapsed time of the test in the passed runs in the
+// --history-db. The regressions are sorted by package and test name.
+//
+// The history is read before the run is added to it by writeHistoryDB.
+func findDurationRegressions(opts *options, exec *testjson.Execution) []durationRegression {
+	if opts.durationRegression <= 0 || exec == nil {
+		return nil
+	}
+	db, err := history.OpenDB(opts.historyDB)
+	if err != nil {
+		log.Warnf("failed to read test durations from %v: %v", opts.historyDB, err)
+		return nil
+	}
+
+	var regressions []durationRegression
+	for _, name := range exec.Packages() {
+		var passed []testjson.TestCase
+		for _, tc := range exec.Package(name).Passed {
+			if !tc.Test.IsSubTest() {
+				passed = append(passed, tc)
+			}
+		}
+		for _, tc := range aggregate.ByElapsed(passed, aggregate.Median) {
+			if tc.Elapsed < opts.durationRegressionMin {
+				continue
+			}
+			baseline, ok := durationBaseline(db.Results(tc.Package, tc.Test.Name()))
+			if !ok || baseline <= 0 {
+				continue
+			}
+			r := durationRegression{TestCase: tc, baseline: baseline}
+			if r.percent() > opts.durationRegression {
+				regressions = append(regressions, r)
+			}
+		}
+	}
+	sort.Slice(regressions, func(i, j int) bool {
+		a, b := regressions[i], regressions[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Test < b.Test
+	})
+	return regressions
+}
+
+// durationBaseline returns the median elapsed time of the passed results, or
+// false when there are not enough passed results.
+func durationBaseline(results []history.Result) (time.Duration, bool) {
+	var times []time.Duration
+	for _, result := range results {
+		if result.Action == testjson.ActionPass && result.Elapsed >= 0 {
+			times = append(times, result.Elapsed)
+		}
+	}
+	if len(times) < durationBaselineMinRuns {
+		return 0, false
+	}
+	return aggregate.Median(times), true
+}
+
+// durationRegressionExitErr returns an error when tests were slower than their
+// baseline, and --duration-regression-fail is set.
+func durationRegressionExitErr(opts *options, regressions []durationRegression, exitErr error) error {
+	if exitErr != nil || !opts.durationRegressionFail || len(regressions) == 0 {
+		return exitErr
+	}
+	log.Errorf("%d %s slower than --duration-regression, failing the run because of --duration-regression-fail",
+		len(regressions), pluralize(len(regressions), "test was", "tests were"))
+	return exitError{num: 1}
+}
+
+func printDurationRegressions(out io.Writer, regressions []durationRegression) {
+	if len(regressions) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n=== Slower (%d %s)\n", len(regressions),
+		pluralize(len(regressions), "test", "tests"))
+	for _, r := range regressions {
+		fmt.Fprintf(out, "%s %s %s (+%d%% vs %s median)\n",
+			testjson.RelativePackagePath(r.Package), r.Test,
+			testjson.FormatDurationAsSeconds(r.Elapsed, 2), r.percent(),
+			testjson.FormatDurationAsSeconds(r.baseline, 2))
+	}
+}
+
+func validateDurationRegression(opts *options) error {
+	switch {
+	case opts.durationRegression < 0:
+		return fmt.Errorf("--duration-regression must be a positive percent")
+	case opts.durationRegression > 0 && opts.historyDB == "":
+		return fmt.Errorf("--duration-regression requires --history-db")
+	case opts.durationRegressionFail && opts.durationRegression == 0:
+		return fmt.Errorf("--duration-regression-fail requires --duration-regression")
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/internal/history"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func durationEvents(one, two float64) string {
+	return fmt.Sprintf(`{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestOne","Elapsed":%[1]v}
+{"Action":"run","Package":"example.com/pkg","Test":"TestTwo"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestTwo","Elapsed":%[2]v}
+{"Action":"pass","Package":"example.com/pkg","Elapsed":%[3]v}
+`, one, two, one+two)
+}
+
+func scanEvents(t *testing.T, events string) *testjson.Execution {
+	t.Helper()
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(events)})
+	assert.NilError(t, err)
+	return exec
+}
+
+func TestFindDurationRegressions(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+
+	opts := &options{
+		historyDB:             filepath.Join(dir.Path(), "history.json"),
+		durationRegression:    50,
+		durationRegressionMin: 100 * time.Millisecond,
+	}
+	db, err := history.OpenDB(opts.historyDB)
+	assert.NilError(t, err)
+	for _, elapsed := range []float64{0.4, 0.5, 0.6} {
+		db.Add("run", scanEvents(t, durationEvents(elapsed, 0.05)))
+	}
+	assert.NilError(t, db.Save())
+
+	t.Run("no history", func(t *testing.T) {
+		opts := *opts
+		opts.historyDB = filepath.Join(dir.Path(), "missing.json")
+		exec := scanEvents(t, durationEvents(2, 0.5))
+		assert.Equal(t, len(findDurationRegressions(&opts, exec)), 0)
+	})
+
+	t.Run("slower test", func(t *testing.T) {
+		// TestTwo is 10 times slower, but faster than durationRegressionMin.
+		exec := scanEvents(t, durationEvents(0.8, 0.09))
+		regressions := findDurationRegressions(opts, exec)
+		assert.Equal(t, len(regressions), 1)
+		assert.Equal(t, regressions[0].Test, testjson.TestName("TestOne"))
+		assert.Equal(t, regressions[0].baseline, 500*time.Millisecond)
+		assert.Equal(t, regressions[0].percent(), 60)
+
+		out := new(bytes.Buffer)
+		printDurationRegressions(out, regressions)
+		assert.Equal(t, out.String(),
+			"\n=== Slower (1 test)\nexample.com/pkg TestOne 0.80s (+60% vs 0.50s median)\n")
+
+		assert.NilError(t, durationRegressionExitErr(opts, regressions, nil))
+		failOpts := *opts
+		failOpts.durationRegressionFail = true
+		assert.Equal(t, ExitCodeWithDefault(durationRegressionExitErr(&failOpts, regressions, nil)), 1)
+	})
+
+	t.Run("within the percent", func(t *testing.T) {
+		exec := scanEvents(t, durationEvents(0.7, 0.05))
+		assert.Equal(t, len(findDurationRegressions(opts, exec)), 0)
+	})
+}
+
+func TestValidateDurationRegression(t *testing.T) {
+	opts := &options{durationRegression: 20}
+	assert.ErrorContains(t, opts.Validate(), "--duration-regression requires --history-db")
+	opts = &options{durationRegressionFail: true}
+	assert.ErrorContains(t, opts.Validate(), "--duration-regression-fail requires --duration-regression")
+	opts = &options{durationRegression: 20, historyDB: "history.json", durationRegressionFail: true}
+	assert.NilError(t, opts.Validate())
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// expectedFailures are the tests read from the --expected-failures file, by
+// package and test name.
+type expectedFailures map[string]map[string]bool
+
+// readExpectedFailuresFile reads the --expected-failures file. Each line of the
+// file is the import path of a package followed by the name of a test in the
+// package. Blank lines and lines that start with # are ignored.
+func readExpectedFailuresFile(filename string) (expectedFailures, error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close() // nolint: errcheck
+
+	result := make(expectedFailures)
+	scanner := bufio.NewScanner(fh)
+	var lineNum int
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%v:%d: expected a package followed by a test name", filename, lineNum)
+		}
+		if result[fields[0]] == nil {
+			result[fields[0]] = make(map[string]bool)
+		}
+		result[fields[0]][fields[1]] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %v: %w", filename, err)
+	}
+	return result, nil
+}
+
+// setupExpectedFailures reads the --expected-failures file.
+func setupExpectedFailures(opts *options) error {
+	if opts.expectedFailuresFile == "" {
+		return nil
+	}
+	expected, err := readExpectedFailuresFile(opts.expectedFailuresFile)
+	if err != nil {
+		return fmt.Errorf("failed to read --expected-failures: %w", err)
+	}
+	opts.expectedFailures = expected
+	return nil
+}
+
+// isExpected returns true if tc, or the root test of tc, is expected to fail.
+func (e expectedFailures) isExpected(tc testjson.TestCase) bool {
+	tests := e[tc.Package]
+	root, _ := tc.Test.Split()
+	return tests[tc.Test.Name()] || tests[root]
+}
+
+// unexpectedPasses returns the tests in the --expected-failures file which
+// passed, and did not fail in any run, sorted by package and test name.
+func unexpectedPasses(opts *options, exec *testjson.Execution) []testjson.TestCase {
+	if len(opts.expectedFailures) == 0 {
+		return nil
+	}
+	var result []testjson.TestCase
+	for _, name := range exec.Packages() {
+		tests := opts.expectedFailures[name]
+		if len(tests) == 0 {
+			continue
+		}
+		pkg := exec.Package(name)
+		failed := make(map[testjson.TestName]bool, len(pkg.Failed))
+		for _, tc := range pkg.Failed {
+			failed[tc.Test] = true
+		}
+		seen := make(map[testjson.TestName]bool)
+		for _, tc := range pkg.Passed {
+			if !tests[tc.Test.Name()] || failed[tc.Test] || seen[tc.Test] {
+				continue
+			}
+			seen[tc.Test] = true
+			result = append(result, tc)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Package != result[j].Package {
+			return result[i].Package < result[j].Package
+		}
+		return result[i].Test < result[j].Test
+	})
+	return result
+}
+
+func printUnexpectedPasses(out io.Writer, passed []testjson.TestCase) {
+	if len(passed) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n=== Unexpected passes (%d %s)\n", len(passed), pluralize(len(passed), "test", "tests"))
+	for _, tc := range passed {
+		fmt.Fprintf(out, "%s %s\n", tc.Package, tc.Test)
+	}
+	fmt.Fprintf(out, "Remove %s from the --expected-failures file.\n",
+		pluralize(len(passed), "this test", "these tests"))
+}
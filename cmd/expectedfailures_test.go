@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestReadExpectedFailuresFile(t *testing.T) {
+	file := fs.NewFile(t, t.Name(), fs.WithContent(`
+# package          test
+example.com/pkg    TestLegacy
+example.com/pkg    TestOld/case_one
+example.com/other  TestBroken
+`))
+	defer file.Remove()
+
+	expected, err := readExpectedFailuresFile(file.Path())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, expected, expectedFailures{
+		"example.com/pkg":   {"TestLegacy": true, "TestOld/case_one": true},
+		"example.com/other": {"TestBroken": true},
+	})
+	assert.Assert(t, expected.isExpected(testjson.TestCase{Package: "example.com/pkg", Test: "TestLegacy/sub"}))
+	assert.Assert(t, expected.isExpected(testjson.TestCase{Package: "example.com/pkg", Test: "TestOld/case_one"}))
+	assert.Assert(t, !expected.isExpected(testjson.TestCase{Package: "example.com/pkg", Test: "TestOld"}))
+	assert.Assert(t, !expected.isExpected(testjson.TestCase{Package: "example.com/other", Test: "TestLegacy"}))
+}
+
+func TestReadExpectedFailuresFile_Invalid(t *testing.T) {
+	file := fs.NewFile(t, t.Name(), fs.WithContent("example.com/pkg TestOne TestTwo\n"))
+	defer file.Remove()
+
+	_, err := readExpectedFailuresFile(file.Path())
+	assert.ErrorContains(t, err, ":1: expected a package followed by a test name")
+}
+
+const expectedFailuresInput = `{"Action":"run","Package":"example.com/pkg","Test":"TestLegacy"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestLegacy","Elapsed":0.1}
+{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFlaky","Elapsed":0.1}
+{"Action":"run","Package":"example.com/pkg","Test":"TestFixed"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestFixed","Elapsed":0.1}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.3}
+{"Action":"run","Package":"example.com/other","Test":"TestAlsoFixed"}
+{"Action":"pass","Package":"example.com/other","Test":"TestAlsoFixed","Elapsed":0.1}
+{"Action":"pass","Package":"example.com/other","Elapsed":0.1}
+`
+
+func TestAllowedFailuresExitErr_WithExpectedFailures(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(expectedFailuresInput),
+	})
+	assert.NilError(t, err)
+
+	opts := &options{expectedFailures: expectedFailures{
+		"example.com/pkg": {"TestLegacy": true},
+	}}
+	err = allowedFailuresExitErr(opts, exec, exitError{num: 1})
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+
+	opts.formatOptions.Quarantine = testjson.NewQuarantine([]testjson.QuarantineRule{
+		{Package: "example.com/pkg", Test: "TestFlaky"},
+	})
+	err = allowedFailuresExitErr(opts, exec, exitError{num: 1})
+	assert.NilError(t, err)
+}
+
+func TestUnexpectedPasses(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(expectedFailuresInput),
+	})
+	assert.NilError(t, err)
+
+	opts := &options{expectedFailures: expectedFailures{
+		"example.com/pkg":   {"TestLegacy": true, "TestFixed": true, "TestMissing": true},
+		"example.com/other": {"TestAlsoFixed": true},
+	}}
+	passed := unexpectedPasses(opts, exec)
+
+	out := new(bytes.Buffer)
+	printUnexpectedPasses(out, passed)
+	expected := `
+=== Unexpected passes (2 tests)
+example.com/other TestAlsoFixed
+example.com/pkg TestFixed
+Remove these tests from the --expected-failures file.
+`
+	assert.Equal(t, out.String(), expected)
+}
@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gotest.tools/gotestsum/log"
+)
+
+// goListFn is a shim for testing. It returns the import path of each package
+// matched by args, which are the go list flags followed by the patterns.
+var goListFn = func(dir string, args []string) ([]string, error) {
+	args = append([]string{"list", "-e", "-f", "{{.ImportPath}}"}, args...)
+	log.Debugf("exec: go %s", args)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// fastListCacheDir is a shim for testing.
+var fastListCacheDir = func() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gotestsum", "fast-list"), nil
+}
+
+// setupFastList replaces the package patterns with the packages they match
+// when --fast-list is enabled. The packages are cached, so that go list only
+// runs again when a go file, directory, go.mod, or go.sum changes. Only
+// patterns with a wildcard are expanded, because go test can quickly find
+// any other package. When the packages can not be listed the patterns are
+// used.
+func setupFastList(opts *options) {
+	if !opts.fastList || opts.rawCommand || len(opts.pkgGroups) > 0 {
+		return
+	}
+	patterns := opts.packages
+	if len(patterns) == 0 {
+		if len(opts.args) > 0 {
+			log.Debugf("--fast-list: the packages are not set by --packages")
+			return
+		}
+		patterns = cmdArgPackageList(opts, rerunOpts{}, "./...")
+	}
+	if !hasWildcard(patterns) {
+		return
+	}
+
+	cacheDir, err := fastListCacheDir()
+	if err != nil {
+		log.Warnf("Failed to find the cache directory for --fast-list: %v", err)
+		return
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		log.Warnf("Failed to list packages for --fast-list: %v", err)
+		return
+	}
+	args := append(goListBuildFlags(opts.args), patterns...)
+	pkgs, err := expandPackages(cacheDir, dir, args)
+	switch {
+	case err != nil:
+		log.Warnf("Failed to list packages for --fast-list: %v", err)
+		return
+	case len(pkgs) == 0:
+		return
+	}
+	opts.packages = pkgs
+}
+
+func hasWildcard(patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.Contains(pattern, "...") {
+			return true
+		}
+	}
+	return false
+}
+
+// goListBuildFlags returns the flags from the go test args which change the
+// packages matched by a pattern.
+func goListBuildFlags(args []string) []string {
+	var result []string
+	for _, flag := range []string{"tags", "mod", "modfile"} {
+		start, end := argIndex(flag, args)
+		if start >= 0 && end < len(args) {
+			result = append(result, args[start:end+1]...)
+		}
+	}
+	return result
+}
+
+// expandPackages returns the packages matched by the go list args in dir,
+// from the cache when the source tree of the module has not changed since
+// the last time the same args were listed in dir.
+func expandPackages(cacheDir, dir string, args []string) ([]string, error) {
+	key := sha256.New()
+	fmt.Fprintln(key, dir)
+	fmt.Fprintln(key, strings.Join(args, "\x00"))
+	for _, name := range []string{"GOFLAGS", "GOOS", "GOARCH", "CGO_ENABLED", "GOWORK"} {
+		fmt.Fprintf(key, "%s=%s\n", name, os.Getenv(name))
+	}
+	cacheFile := filepath.Join(cacheDir, hex.EncodeToString(key.Sum(nil)))
+
+	tree, err := sourceTreeHash(findModuleRoot(dir))
+	if err != nil {
+		return nil, err
+	}
+	if pkgs, ok := readFastListCache(cacheFile, tree); ok {
+		log.Debugf("--fast-list: using %d cached packages", len(pkgs))
+		return pkgs, nil
+	}
+
+	pkgs, err := goListFn(dir, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFastListCache(cacheFile, tree, pkgs); err != nil {
+		log.Warnf("Failed to write the --fast-list cache: %v", err)
+	}
+	return pkgs, nil
+}
+
+// The first line of a cache file is the hash of the source tree, followed by
+// one package on each line.
+func readFastListCache(path string, tree string) ([]string, bool) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	lines := strings.Split(strings.TrimSuffix(string(raw), "\n"), "\n")
+	if len(lines) < 2 || lines[0] != tree {
+		return nil, false
+	}
+	return lines[1:], true
+}
+
+func writeFastListCache(path string, tree string, pkgs []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteString(tree + "\n")
+	for _, pkg := range pkgs {
+		buf.WriteString(pkg + "\n")
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// findModuleRoot returns the first directory, starting from dir, which has a
+// go.mod. If there is no go.mod, dir is returned.
+func findModuleRoot(dir string) string {
+	for current := dir; ; {
+		if _, err := os.Stat(filepath.Join(current, "go.mod")); err == nil {
+			return current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return dir
+		}
+		current = parent
+	}
+}
+
+// sourceTreeHash returns a hash of the contents of go.mod, go.sum, and
+// go.work, and of the path, size, and modification time of every directory
+// and go file under root. The hash changes when a change to the tree could
+// change the packages matched by a pattern. Directories which are ignored by
+// go are skipped.
+func sourceTreeHash(root string) (string, error) {
+	h := sha256.New()
+	for _, name := range []string{"go.mod", "go.sum", "go.work"} {
+		fh, err := os.Open(filepath.Join(root, name))
+		if err != nil {
+			continue
+		}
+		fmt.Fprintln(h, name)
+		_, err = io.Copy(h, fh)
+		fh.Close() // nolint: errcheck
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		name := info.Name()
+		switch {
+		case info.IsDir() && path != root && isIgnoredDir(name):
+			return filepath.SkipDir
+		case info.IsDir():
+			fmt.Fprintf(h, "d %s\n", rel)
+		case strings.HasSuffix(name, ".go"):
+			fmt.Fprintf(h, "f %s %d %d\n", rel, info.Size(), info.ModTime().UnixNano())
+		}
+		return nil
+	})
+	return hex.EncodeToString(h.Sum(nil)), err
+}
+
+// isIgnoredDir returns true for the directories which are never matched by a
+// package pattern.
+func isIgnoredDir(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata"
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestExpandPackages(t *testing.T) {
+	src := fs.NewDir(t, t.Name(),
+		fs.WithFile("go.mod", "module example.com/mod\n"),
+		fs.WithDir("one", fs.WithFile("one.go", "package one\n")),
+		fs.WithDir("testdata", fs.WithFile("data.go", "package data\n")))
+	defer src.Remove()
+	cache := fs.NewDir(t, t.Name())
+	defer cache.Remove()
+
+	var calls [][]string
+	expected := []string{"example.com/mod/one"}
+	defer patchGoListFn(func(dir string, args []string) ([]string, error) {
+		assert.Equal(t, dir, src.Path())
+		calls = append(calls, args)
+		return expected, nil
+	})()
+
+	args := []string{"-tags", "integration", "./..."}
+	pkgs, err := expandPackages(cache.Path(), src.Path(), args)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, pkgs, expected)
+	assert.DeepEqual(t, calls, [][]string{args})
+
+	t.Run("cached", func(t *testing.T) {
+		pkgs, err := expandPackages(cache.Path(), src.Path(), args)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, pkgs, expected)
+		assert.Equal(t, len(calls), 1)
+	})
+
+	t.Run("different args are not cached", func(t *testing.T) {
+		_, err := expandPackages(cache.Path(), src.Path(), []string{"./..."})
+		assert.NilError(t, err)
+		assert.Equal(t, len(calls), 2)
+	})
+
+	t.Run("changes to ignored dirs do not change the tree", func(t *testing.T) {
+		assert.NilError(t, os.Remove(src.Join("testdata", "data.go")))
+		_, err := expandPackages(cache.Path(), src.Path(), args)
+		assert.NilError(t, err)
+		assert.Equal(t, len(calls), 2)
+	})
+
+	t.Run("new package", func(t *testing.T) {
+		fs.Apply(t, src, fs.WithDir("two", fs.WithFile("two.go", "package two\n")))
+		expected = []string{"example.com/mod/one", "example.com/mod/two"}
+		pkgs, err := expandPackages(cache.Path(), src.Path(), args)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, pkgs, expected)
+		assert.Equal(t, len(calls), 3)
+	})
+
+	t.Run("changed go file", func(t *testing.T) {
+		later := time.Now().Add(time.Minute)
+		assert.NilError(t, os.Chtimes(src.Join("two", "two.go"), later, later))
+		_, err := expandPackages(cache.Path(), src.Path(), args)
+		assert.NilError(t, err)
+		assert.Equal(t, len(calls), 4)
+	})
+}
+
+func patchGoListFn(fn func(dir string, args []string) ([]string, error)) func() {
+	orig := goListFn
+	goListFn = fn
+	return func() {
+		goListFn = orig
+	}
+}
+
+func TestSetupFastList(t *testing.T) {
+	cache := fs.NewDir(t, t.Name())
+	defer cache.Remove()
+	origCacheDir := fastListCacheDir
+	fastListCacheDir = func() (string, error) {
+		return cache.Path(), nil
+	}
+	defer func() {
+		fastListCacheDir = origCacheDir
+	}()
+
+	var listed []string
+	defer patchGoListFn(func(dir string, args []string) ([]string, error) {
+		listed = args
+		return []string{"example.com/mod/one", "example.com/mod/two"}, nil
+	})()
+
+	type testCase struct {
+		name     string
+		opts     *options
+		listed   []string
+		expected []string
+	}
+	for _, tc := range []testCase{
+		{
+			name:     "default package pattern",
+			opts:     &options{fastList: true},
+			listed:   []string{"./..."},
+			expected: []string{"example.com/mod/one", "example.com/mod/two"},
+		},
+		{
+			name:     "packages and build flags",
+			opts:     &options{fastList: true, packages: []string{"./one/...", "./two"}, args: []string{"-tags=e2e", "-race"}},
+			listed:   []string{"-tags=e2e", "./one/...", "./two"},
+			expected: []string{"example.com/mod/one", "example.com/mod/two"},
+		},
+		{
+			name:     "no wildcard",
+			opts:     &options{fastList: true, packages: []string{"./one"}},
+			expected: []string{"./one"},
+		},
+		{
+			name: "packages in args",
+			opts: &options{fastList: true, args: []string{"-race", "./..."}},
+		},
+		{
+			name:     "disabled",
+			opts:     &options{packages: []string{"./..."}},
+			expected: []string{"./..."},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			listed = nil
+			setupFastList(tc.opts)
+			assert.DeepEqual(t, listed, tc.listed)
+			assert.DeepEqual(t, tc.opts.packages, tc.expected)
+		})
+	}
+}
@@ -52,6 +52,161 @@ func (s *hideSummaryValue) String() string {
 	return (testjson.SummarizeAll ^ s.value).String()
 }
 
+var durationFormatValues = "default, seconds, units"
+
+// durationFormatValue is a flag.Value which sets a testjson.DurationFormat.
+type durationFormatValue struct {
+	value *testjson.DurationFormat
+}
+
+func (d durationFormatValue) Set(val string) error {
+	format, ok := testjson.NewDurationFormat(val)
+	if !ok {
+		return errors.Errorf("invalid value: %v, must be one of: "+durationFormatValues, val)
+	}
+	*d.value = format
+	return nil
+}
+
+func (d durationFormatValue) Type() string {
+	return "format"
+}
+
+func (d durationFormatValue) String() string {
+	if d.value == nil {
+		return testjson.DurationDefault.String()
+	}
+	return d.value.String()
+}
+
+var showOutputValues = "fail, pass, all, none"
+
+// showOutputValue is a flag.Value which sets a testjson.ShowOutput.
+type showOutputValue struct {
+	value *testjson.ShowOutput
+}
+
+func (s showOutputValue) Set(val string) error {
+	show, ok := testjson.NewShowOutput(val)
+	if !ok {
+		return errors.Errorf("invalid value: %v, must be one of: "+showOutputValues, val)
+	}
+	*s.value = show
+	return nil
+}
+
+func (s showOutputValue) Type() string {
+	return "results"
+}
+
+func (s showOutputValue) String() string {
+	if s.value == nil {
+		return testjson.ShowOutputFail.String()
+	}
+	return s.value.String()
+}
+
+var logFoldingValues = "auto, none, github, gitlab, buildkite"
+
+// logFoldingValue is a flag.Value which sets a testjson.LogFolding. The value
+// auto detects the CI system from the environment.
+type logFoldingValue struct {
+	value *testjson.LogFolding
+}
+
+func (f logFoldingValue) Set(val string) error {
+	if val == "auto" {
+		*f.value = detectLogFolding()
+		return nil
+	}
+	folding, ok := testjson.NewLogFolding(val)
+	if !ok {
+		return errors.Errorf("invalid value: %v, must be one of: "+logFoldingValues, val)
+	}
+	*f.value = folding
+	return nil
+}
+
+func (f logFoldingValue) Type() string {
+	return "ci"
+}
+
+func (f logFoldingValue) String() string {
+	if f.value == nil {
+		return testjson.LogFoldingNone.String()
+	}
+	return f.value.String()
+}
+
+// clockValue is a flag.Value which selects the testjson.Clock of a run.
+type clockValue struct {
+	value string
+}
+
+func (c *clockValue) Set(val string) error {
+	switch val {
+	case "wall", "event":
+		c.value = val
+		return nil
+	}
+	return errors.Errorf("invalid value: %v, must be one of: wall, event", val)
+}
+
+func (c *clockValue) Type() string {
+	return "clock"
+}
+
+func (c *clockValue) String() string {
+	if c.value == "" {
+		return "wall"
+	}
+	return c.value
+}
+
+// newClock returns the Clock for the ScanConfig of a run. A nil Clock is the
+// wall clock.
+func (c *clockValue) newClock() testjson.Clock {
+	if c.value == "event" {
+		return testjson.NewEventClock()
+	}
+	return nil
+}
+
+var stderrMergeValues = map[string]testjson.StderrMerge{
+	"concurrent": testjson.StderrMergeConcurrent,
+	"separate":   testjson.StderrMergeSeparate,
+	"package":    testjson.StderrMergePackage,
+}
+
+// stderrMergeValue is the --stderr-merge flag.
+type stderrMergeValue struct {
+	value string
+}
+
+func (v *stderrMergeValue) Set(val string) error {
+	if _, ok := stderrMergeValues[val]; !ok {
+		return errors.Errorf("invalid value: %v, must be one of: concurrent, separate, package", val)
+	}
+	v.value = val
+	return nil
+}
+
+func (v *stderrMergeValue) Type() string {
+	return "mode"
+}
+
+func (v *stderrMergeValue) String() string {
+	if v.value == "" {
+		return "concurrent"
+	}
+	return v.value
+}
+
+// merge returns the StderrMerge for the ScanConfig of a run.
+func (v *stderrMergeValue) merge() testjson.StderrMerge {
+	return stderrMergeValues[v.String()]
+}
+
 var junitFieldFormatValues = "full, relative, short"
 
 type junitFieldFormatValue struct {
@@ -3,7 +3,9 @@ package cmd
 import (
 	"testing"
 
+	"gotest.tools/gotestsum/testjson"
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
 )
 
 func TestNoSummaryValue_SetAndString(t *testing.T) {
@@ -33,3 +35,50 @@ func TestStringSlice(t *testing.T) {
 	assert.NilError(t, ss.Set(value))
 	assert.DeepEqual(t, v, []string{"one", "two", "three", "four", "five"})
 }
+
+func TestShowOutputValue(t *testing.T) {
+	var show testjson.ShowOutput
+	value := showOutputValue{value: &show}
+	assert.Equal(t, value.String(), "fail")
+	assert.NilError(t, value.Set("all"))
+	assert.Equal(t, show, testjson.ShowOutputAll)
+	assert.Equal(t, value.String(), "all")
+	assert.ErrorContains(t, value.Set("bogus"), "must be one of: fail, pass, all, none")
+}
+
+func TestLogFoldingValue(t *testing.T) {
+	var folding testjson.LogFolding
+	value := logFoldingValue{value: &folding}
+	assert.Equal(t, value.String(), "none")
+	assert.NilError(t, value.Set("gitlab"))
+	assert.Equal(t, folding, testjson.LogFoldingGitLab)
+	assert.Equal(t, value.String(), "gitlab")
+	assert.ErrorContains(t, value.Set("bogus"), "must be one of: auto, none, github, gitlab, buildkite")
+
+	defer env.PatchAll(t, map[string]string{"BUILDKITE": "true"})()
+	assert.NilError(t, value.Set("auto"))
+	assert.Equal(t, folding, testjson.LogFoldingBuildkite)
+}
+
+func TestClockValue(t *testing.T) {
+	var value clockValue
+	assert.Equal(t, value.String(), "wall")
+	assert.Assert(t, value.newClock() == nil)
+	assert.NilError(t, value.Set("event"))
+	assert.Equal(t, value.String(), "event")
+	_, ok := value.newClock().(*testjson.EventClock)
+	assert.Assert(t, ok)
+	assert.ErrorContains(t, value.Set("bogus"), "must be one of: wall, event")
+}
+
+func TestStderrMergeValue(t *testing.T) {
+	var value stderrMergeValue
+	assert.Equal(t, value.String(), "concurrent")
+	assert.Equal(t, value.merge(), testjson.StderrMergeConcurrent)
+	assert.NilError(t, value.Set("package"))
+	assert.Equal(t, value.String(), "package")
+	assert.Equal(t, value.merge(), testjson.StderrMergePackage)
+	assert.NilError(t, value.Set("separate"))
+	assert.Equal(t, value.merge(), testjson.StderrMergeSeparate)
+	assert.ErrorContains(t, value.Set("ordered"), "must be one of: concurrent, separate, package")
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"os"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// detectLogFolding returns the LogFolding of the CI system that is running
+// gotestsum, from the variables each CI system sets in the environment.
+func detectLogFolding() testjson.LogFolding {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return testjson.LogFoldingGitHub
+	case os.Getenv("GITLAB_CI") == "true":
+		return testjson.LogFoldingGitLab
+	case os.Getenv("BUILDKITE") == "true":
+		return testjson.LogFoldingBuildkite
+	}
+	return testjson.LogFoldingNone
+}
+
+// printSummary prints the summary of the run. With --format-fold the summary
+// is printed in an expanded group, so that it is not hidden in the group of
+// the last package.
+func printSummary(opts *options, exec *testjson.Execution) {
+	folding := opts.formatOptions.LogFolding
+	folding.Start(opts.stdout, "gotestsum-summary", "Summary", false)
+	testjson.PrintSummaryWithOptions(opts.stdout, exec, opts.hideSummary.value, opts.formatOptions)
+	folding.End(opts.stdout, "gotestsum-summary")
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// formatPluginPrefix is the prefix of a --format which runs a command to
+// format the events.
+const formatPluginPrefix = "exec:"
+
+// formatPlugin is an EventFormatter which writes a formatPluginMessage for
+// each event, as a line of JSON, to the stdin of a command. The stdout and
+// stderr of the command are the stdout and stderr of gotestsum, so the
+// command prints the output of the format.
+type formatPlugin struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	enc    *json.Encoder
+	closed bool
+}
+
+// formatPluginMessage is the line of JSON written to the stdin of a format
+// plugin for each event. Fields may be added in later versions, so plugins
+// should ignore any fields they do not use.
+type formatPluginMessage struct {
+	// Event is the test2json event, with the RunID of the run from
+	// --rerun-fails.
+	Event testjson.TestEvent
+	// Package is the state of the package of the event, including the event.
+	Package formatPluginPackage
+	// Run is the state of the whole run, including the event.
+	Run formatPluginRun
+}
+
+type formatPluginPackage struct {
+	Total   int
+	Passed  int
+	Failed  int
+	Skipped int
+	// Result is the action of the package once it has ended, otherwise it is
+	// empty.
+	Result   testjson.Action `json:",omitempty"`
+	Coverage string          `json:",omitempty"`
+}
+
+type formatPluginRun struct {
+	Total    int
+	Failed   int
+	Skipped  int
+	Errors   int
+	Packages int
+}
+
+// newFormatPlugin starts the command of a --format with formatPluginPrefix.
+// The command is split on spaces, so that it can include arguments.
+func newFormatPlugin(opts *options) (*formatPlugin, error) {
+	args := strings.Fields(strings.TrimPrefix(opts.format, formatPluginPrefix))
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = opts.stdout
+	cmd.Stderr = opts.stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("exec: %s", cmd.Args)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start format %v: %w", args[0], err)
+	}
+	return &formatPlugin{cmd: cmd, stdin: stdin, enc: json.NewEncoder(stdin)}, nil
+}
+
+func (p *formatPlugin) Format(event testjson.TestEvent, exec *testjson.Execution) error {
+	msg := formatPluginMessage{
+		Event: event,
+		Run: formatPluginRun{
+			Total:    exec.Total(),
+			Failed:   len(exec.Failed()),
+			Skipped:  len(exec.Skipped()),
+			Errors:   len(exec.Errors()),
+			Packages: len(exec.Packages()),
+		},
+	}
+	if pkg := exec.Package(event.Package); pkg != nil {
+		msg.Package = formatPluginPackage{
+			Total:    pkg.Total,
+			Passed:   len(pkg.Passed),
+			Failed:   len(pkg.Failed),
+			Skipped:  len(pkg.Skipped),
+			Result:   pkg.Result(),
+			Coverage: pkg.Coverage(),
+		}
+	}
+	if err := p.enc.Encode(msg); err != nil {
+		return fmt.Errorf("failed to send event to format %v: %w", p.cmd.Path, err)
+	}
+	return nil
+}
+
+// Close closes the stdin of the command, and waits for the command to exit.
+// It is safe to call Close more than once, and on a nil formatPlugin.
+func (p *formatPlugin) Close() error {
+	if p == nil || p.closed {
+		return nil
+	}
+	p.closed = true
+	if err := p.stdin.Close(); err != nil {
+		log.Debugf("failed to close stdin of format %v: %v", p.cmd.Path, err)
+	}
+	if err := p.cmd.Wait(); err != nil {
+		return fmt.Errorf("format %v failed: %w", p.cmd.Path, err)
+	}
+	return nil
+}
+
+func validateFormatPlugin(opts *options) error {
+	if !strings.HasPrefix(opts.format, formatPluginPrefix) {
+		return nil
+	}
+	if strings.TrimSpace(strings.TrimPrefix(opts.format, formatPluginPrefix)) == "" {
+		return fmt.Errorf("--format %v requires a command", formatPluginPrefix)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestFormatPlugin(t *testing.T) {
+	out := new(bytes.Buffer)
+	opts := &options{format: "exec:cat", stdout: out, stderr: new(bytes.Buffer)}
+	assert.NilError(t, opts.Validate())
+	handler, err := newEventHandler(opts)
+	assert.NilError(t, err)
+
+	input := `{"Package":"example.com/pkg","Test":"TestOne","Action":"run"}
+{"Package":"example.com/pkg","Test":"TestOne","Action":"fail","Elapsed":0.5}
+{"Package":"example.com/pkg","Action":"output","Output":"coverage: 20.0% of statements\n"}
+{"Package":"example.com/pkg","Action":"fail","Elapsed":1}
+`
+	_, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  strings.NewReader(input),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, opts.formatPlugin.Close())
+	assert.NilError(t, handler.Close())
+
+	var messages []formatPluginMessage
+	dec := json.NewDecoder(out)
+	for dec.More() {
+		var msg formatPluginMessage
+		assert.NilError(t, dec.Decode(&msg))
+		messages = append(messages, msg)
+	}
+	assert.Equal(t, len(messages), 4)
+
+	last := messages[3]
+	assert.Equal(t, last.Event.Action, testjson.ActionFail)
+	assert.Equal(t, last.Event.Package, "example.com/pkg")
+	expectedPkg := formatPluginPackage{
+		Total:    1,
+		Failed:   1,
+		Result:   testjson.ActionFail,
+		Coverage: "coverage: 20.0% of statements",
+	}
+	assert.DeepEqual(t, last.Package, expectedPkg)
+	assert.DeepEqual(t, last.Run, formatPluginRun{Total: 1, Failed: 1, Packages: 1})
+
+	first := messages[0]
+	assert.Equal(t, first.Event.Test, "TestOne")
+	assert.DeepEqual(t, first.Package, formatPluginPackage{Total: 1})
+}
+
+func TestOptions_Validate_FormatPlugin(t *testing.T) {
+	opts := &options{format: "exec: "}
+	assert.ErrorContains(t, opts.Validate(), "--format exec: requires a command")
+}
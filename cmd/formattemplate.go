@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"text/template"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// parseFormatTemplate parses the --format-template file. The template is
+// executed with a testjson.TemplateData for each event, and may use the
+// testjson.TemplateFuncs, and the runID function to get the ID of the run.
+func parseFormatTemplate(opts *options) (*template.Template, error) {
+	raw, err := ioutil.ReadFile(opts.formatTemplateFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --format-template: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(opts.formatTemplateFile)).
+		Funcs(testjson.TemplateFuncs()).
+		Funcs(template.FuncMap{"runID": func() string { return opts.runID }}).
+		Parse(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse --format-template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// setupFormatTemplate sets the Template of the format options from the
+// --format-template file.
+func setupFormatTemplate(opts *options) error {
+	if opts.formatTemplateFile == "" {
+		return nil
+	}
+	tmpl, err := parseFormatTemplate(opts)
+	if err != nil {
+		return err
+	}
+	opts.formatOptions.Template = tmpl
+	return nil
+}
+
+func validateFormatTemplate(opts *options) error {
+	switch {
+	case opts.format == "template" && opts.formatTemplateFile == "":
+		return fmt.Errorf("--format template requires --format-template")
+	case opts.formatTemplateFile == "":
+		return nil
+	case opts.format != "template":
+		return fmt.Errorf("--format-template requires --format template")
+	}
+	_, err := parseFormatTemplate(opts)
+	return err
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestSetupFormatTemplate(t *testing.T) {
+	file := fs.NewFile(t, t.Name(),
+		fs.WithContent("{{if .Event.Action.IsTerminal}}{{runID}} {{.Event.Action}} {{.RelativePackage}}\n{{end}}"))
+	defer file.Remove()
+
+	opts := &options{format: "template", formatTemplateFile: file.Path(), runID: "ci-1234"}
+	assert.NilError(t, opts.Validate())
+	assert.NilError(t, setupFormatTemplate(opts))
+
+	out := new(bytes.Buffer)
+	formatter := testjson.NewEventFormatterWithOptions(out, "template", opts.formatOptions)
+	event := testjson.TestEvent{Package: "example.com/pkg", Action: testjson.ActionPass}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: bytes.NewReader(nil)})
+	assert.NilError(t, err)
+	assert.NilError(t, formatter.Format(event, exec))
+	assert.Equal(t, out.String(), "ci-1234 pass example.com/pkg\n")
+}
+
+func TestOptions_Validate_FormatTemplate(t *testing.T) {
+	file := fs.NewFile(t, t.Name(), fs.WithContent("{{.Event.Action"))
+	defer file.Remove()
+
+	opts := &options{format: "template", formatTemplateFile: file.Path()}
+	assert.ErrorContains(t, opts.Validate(), "failed to parse --format-template")
+
+	opts = &options{format: "template"}
+	assert.ErrorContains(t, opts.Validate(), "--format template requires --format-template")
+
+	opts = &options{format: "pkgname", formatTemplateFile: file.Path()}
+	assert.ErrorContains(t, opts.Validate(), "--format-template requires --format template")
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// fullpathGoVersion is a shim for testing
+var fullpathGoVersion = func() string {
+	log.Debugf("exec: go env GOVERSION")
+	out, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		log.Warnf("Failed to lookup go version for --fullpath: %v", err)
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// goVersionAtLeast returns true if version, in the format of 'go env
+// GOVERSION' (ex: go1.21.3), is at least go1.minor.
+func goVersionAtLeast(version string, minor int) bool {
+	major, rest := cutString(strings.TrimPrefix(version, "go"), ".")
+	if end := strings.IndexFunc(rest, isNotDigit); end >= 0 {
+		rest = rest[:end]
+	}
+	majorN, err := strconv.Atoi(major)
+	if err != nil {
+		return false
+	}
+	minorN, err := strconv.Atoi(rest)
+	return majorN > 1 || (majorN == 1 && err == nil && minorN >= minor)
+}
+
+func isNotDigit(r rune) bool {
+	return r < '0' || r > '9'
+}
+
+// setupFullpath checks that the go version supports the -fullpath flag added
+// by --fullpath, and sets the directory that paths in the output are made
+// relative to.
+func setupFullpath(opts *options) {
+	if !opts.fullpath {
+		return
+	}
+	if opts.pathRoot == "" {
+		opts.pathRoot = moduleRoot()
+	}
+	if opts.rawCommand {
+		return
+	}
+	if version := fullpathGoVersion(); goVersionAtLeast(version, 21) {
+		opts.fullpathSupported = true
+		return
+	}
+	log.Warnf("--fullpath requires go1.21 or later, go test will not use -fullpath")
+}
+
+// fullpathArgs returns the -fullpath flag when --fullpath is set, unless the
+// go test args already set -fullpath.
+func fullpathArgs(opts *options, args []string) []string {
+	if !opts.fullpathSupported || boolArgIndex("fullpath", args) >= 0 {
+		return nil
+	}
+	return []string{"-fullpath"}
+}
+
+// moduleRoot returns the directory of the go.mod file for the current
+// directory, or the current directory when there is no go.mod file.
+func moduleRoot() string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for dir := cwd; ; {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return cwd
+		}
+		dir = parent
+	}
+}
+
+// newOutputRewriter returns the function used for
+// testjson.ScanConfig.RewriteOutput. The paths in the output are first
+// rewritten by the --path-map mappings, and then, when --fullpath is set, made
+// relative to the --path-root.
+func newOutputRewriter(opts *options) func(string) string {
+	stages := [][]testjson.PathMapping{opts.pathMaps}
+	if opts.fullpath {
+		stages = append(stages, []testjson.PathMapping{{From: opts.pathRoot}})
+	}
+	var rewriters []func(string) string
+	for _, mappings := range stages {
+		if rewrite := testjson.NewPathRewriter(mappings); rewrite != nil {
+			rewriters = append(rewriters, rewrite)
+		}
+	}
+	switch len(rewriters) {
+	case 0:
+		return nil
+	case 1:
+		return rewriters[0]
+	}
+	return func(output string) string {
+		for _, rewrite := range rewriters {
+			output = rewrite(output)
+		}
+		return output
+	}
+}
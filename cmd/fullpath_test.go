@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+)
+
+func TestGoVersionAtLeast(t *testing.T) {
+	var testCases = []struct {
+		version  string
+		expected bool
+	}{
+		{version: "go1.21.3", expected: true},
+		{version: "go1.21rc2", expected: true},
+		{version: "go1.22", expected: true},
+		{version: "go2.0", expected: true},
+		{version: "go1.20.7", expected: false},
+		{version: "go1.9", expected: false},
+		{version: "devel go1.22-abc", expected: false},
+		{version: "", expected: false},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, goVersionAtLeast(tc.version, 21), tc.expected, tc.version)
+	}
+}
+
+func patchFullpathGoVersion(version string) func() {
+	orig := fullpathGoVersion
+	fullpathGoVersion = func() string {
+		return version
+	}
+	return func() { fullpathGoVersion = orig }
+}
+
+func TestSetupFullpath(t *testing.T) {
+	t.Run("supported", func(t *testing.T) {
+		defer patchFullpathGoVersion("go1.21.0")()
+		opts := &options{fullpath: true, pathRoot: "/src"}
+		setupFullpath(opts)
+		assert.Assert(t, opts.fullpathSupported)
+		assert.Equal(t, opts.pathRoot, "/src")
+
+		args := goTestCmdArgs(opts, rerunOpts{})
+		assert.DeepEqual(t, args, []string{"go", "test", "-json", "-fullpath", "./..."})
+
+		opts.args = []string{"-fullpath", "./pkg"}
+		args = goTestCmdArgs(opts, rerunOpts{})
+		assert.DeepEqual(t, args, []string{"go", "test", "-json", "-fullpath", "./pkg"})
+	})
+
+	t.Run("not supported", func(t *testing.T) {
+		defer patchFullpathGoVersion("go1.20.1")()
+		opts := &options{fullpath: true, pathRoot: "/src"}
+		setupFullpath(opts)
+		assert.Assert(t, !opts.fullpathSupported)
+
+		args := goTestCmdArgs(opts, rerunOpts{})
+		assert.DeepEqual(t, args, []string{"go", "test", "-json", "./..."})
+	})
+
+	t.Run("path root defaults to module root", func(t *testing.T) {
+		defer patchFullpathGoVersion("go1.21.0")()
+		dir := fs.NewDir(t, "module",
+			fs.WithFile("go.mod", "module example.com/pkg\n"),
+			fs.WithDir("sub"))
+		defer env.ChangeWorkingDir(t, dir.Join("sub"))()
+
+		opts := &options{fullpath: true}
+		setupFullpath(opts)
+		root, err := filepath.EvalSymlinks(dir.Path())
+		assert.NilError(t, err)
+		actual, err := filepath.EvalSymlinks(opts.pathRoot)
+		assert.NilError(t, err)
+		assert.Equal(t, actual, root)
+	})
+}
+
+func TestNewOutputRewriter(t *testing.T) {
+	assert.Assert(t, newOutputRewriter(&options{}) == nil)
+
+	rewrite := newOutputRewriter(&options{fullpath: true, pathRoot: "/src"})
+	assert.Equal(t, rewrite("    /src/pkg/pkg_test.go:10: failed\n"), "    pkg/pkg_test.go:10: failed\n")
+}
+
+func TestNewOutputRewriter_WithPathMaps(t *testing.T) {
+	var maps pathMapValue
+	assert.NilError(t, maps.Set("/src=>/home/ci/repo"))
+	assert.NilError(t, maps.Set("/go/pkg/mod => /home/ci/go/pkg/mod"))
+	assert.Equal(t, maps.String(), "/src=>/home/ci/repo,/go/pkg/mod=>/home/ci/go/pkg/mod")
+
+	rewrite := newOutputRewriter(&options{pathMaps: maps})
+	assert.Equal(t, rewrite("\t/src/pkg/store.go:40 +0x1d\n"), "\t/home/ci/repo/pkg/store.go:40 +0x1d\n")
+	assert.Equal(t, rewrite("\t/go/pkg/mod/example.com/x@v1.0.0/x.go:3\n"),
+		"\t/home/ci/go/pkg/mod/example.com/x@v1.0.0/x.go:3\n")
+
+	opts := &options{pathMaps: maps, fullpath: true, pathRoot: "/home/ci/repo"}
+	rewrite = newOutputRewriter(opts)
+	assert.Equal(t, rewrite("    /src/pkg/pkg_test.go:10: failed\n"), "    pkg/pkg_test.go:10: failed\n")
+}
+
+func TestPathMapValue_Set_Invalid(t *testing.T) {
+	var maps pathMapValue
+	assert.Error(t, maps.Set("/src"), `invalid value "/src", must be FROM=>TO`)
+	assert.Error(t, maps.Set("=>/src"), `invalid value "=>/src", must be FROM=>TO`)
+}
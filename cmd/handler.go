@@ -1,12 +1,18 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/internal/compress"
 	"gotest.tools/gotestsum/internal/junitxml"
 	"gotest.tools/gotestsum/log"
 	"gotest.tools/gotestsum/testjson"
@@ -16,7 +22,18 @@ type eventHandler struct {
 	formatter testjson.EventFormatter
 	err       io.Writer
 	jsonFile  io.WriteCloser
-	maxFails  int
+	// runID is the --run-id written to the jsonFile with the start of each
+	// --rerun-fails attempt.
+	runID string
+	// attempt is the RunID of the most recent event written to the jsonFile.
+	attempt  int
+	maxFails int
+	onFail   *onFailHook
+	snapshot *snapshotHook
+	memory   *memoryGuard
+	duration *testDurationWatch
+	otlpLogs *otlpLogsHook
+	plugin   *formatPlugin
 }
 
 func (h *eventHandler) Err(text string) error {
@@ -28,6 +45,9 @@ func (h *eventHandler) Err(text string) error {
 func (h *eventHandler) Event(event testjson.TestEvent, execution *testjson.Execution) error {
 	// ignore artificial events with no raw Bytes()
 	if h.jsonFile != nil && len(event.Bytes()) > 0 {
+		if err := h.writeRunStart(event); err != nil {
+			return errors.Wrap(err, "failed to write JSON file")
+		}
 		_, err := h.jsonFile.Write(append(event.Bytes(), '\n'))
 		if err != nil {
 			return errors.Wrap(err, "failed to write JSON file")
@@ -38,6 +58,11 @@ func (h *eventHandler) Event(event testjson.TestEvent, execution *testjson.Execu
 	if err != nil {
 		return errors.Wrap(err, "failed to format event")
 	}
+	h.onFail.Event(event, execution)
+	h.snapshot.Event(event, execution)
+	h.otlpLogs.Event(event)
+	h.memory.check(execution)
+	h.duration.Event(event)
 
 	if h.maxFails > 0 && len(execution.Failed()) >= h.maxFails {
 		return fmt.Errorf("ending test run because max failures was reached")
@@ -45,42 +70,165 @@ func (h *eventHandler) Event(event testjson.TestEvent, execution *testjson.Execu
 	return nil
 }
 
+// writeRunStart writes an ActionRunStart event to the jsonFile before the
+// first event of each --rerun-fails attempt, so that the attempts can be
+// split into runs when the file is read by testjson.ScanConfig.SplitRuns.
+func (h *eventHandler) writeRunStart(event testjson.TestEvent) error {
+	if event.RunID == h.attempt || h.runID == "" {
+		return nil
+	}
+	h.attempt = event.RunID
+	_, err := h.jsonFile.Write(append(testjson.RunStartEvent(h.runID, event.RunID, time.Now()), '\n'))
+	return err
+}
+
+// Flush the formatter, if it buffers output.
+func (h *eventHandler) Flush() error {
+	if f, ok := h.formatter.(testjson.Flusher); ok {
+		return errors.Wrap(f.Flush(), "failed to write output")
+	}
+	return nil
+}
+
 func (h *eventHandler) Close() error {
 	if h.jsonFile != nil {
 		if err := h.jsonFile.Close(); err != nil {
 			log.Errorf("Failed to close JSON file: %v", err)
 		}
 	}
-	return nil
+	h.onFail.Close()
+	h.otlpLogs.Close()
+	h.duration.Close()
+	if err := h.plugin.Close(); err != nil {
+		log.Errorf("%v", err)
+	}
+	return h.memory.Close()
 }
 
 var _ testjson.EventHandler = &eventHandler{}
+var _ testjson.Flusher = &eventHandler{}
 
 func newEventHandler(opts *options) (*eventHandler, error) {
-	formatter := testjson.NewEventFormatter(opts.stdout, opts.format)
+	var formatter testjson.EventFormatter
+	if strings.HasPrefix(opts.format, formatPluginPrefix) {
+		plugin, err := newFormatPlugin(opts)
+		if err != nil {
+			return nil, err
+		}
+		opts.formatPlugin = plugin
+		formatter = plugin
+	} else {
+		formatter = testjson.NewEventFormatterWithOptions(opts.stdout, opts.format, opts.formatOptions)
+	}
 	if formatter == nil {
 		return nil, errors.Errorf("unknown format %s", opts.format)
 	}
+	if opts.scriptOutput {
+		var err error
+		if formatter, err = newScriptFormatter(opts.stdout); err != nil {
+			return nil, errors.Wrap(err, "failed to write output")
+		}
+	}
 	handler := &eventHandler{
 		formatter: formatter,
+		plugin:    opts.formatPlugin,
 		err:       opts.stderr,
 		maxFails:  opts.maxFails,
+		runID:     opts.runID,
+		snapshot:  newSnapshotHook(opts),
+		memory:    newMemoryGuard(opts),
 	}
 	var err error
-	if opts.jsonFile != "" {
-		handler.jsonFile, err = os.Create(opts.jsonFile)
+	handler.onFail, err = newOnFailHook(opts)
+	if err != nil {
+		return handler, err
+	}
+	handler.otlpLogs, err = newOTLPLogsHook(opts)
+	if err != nil {
+		return handler, err
+	}
+	jsonFile, err := openJSONFile(opts)
+	if jsonFile != nil {
+		opts.jsonFileWriter = &closeOnceWriter{WriteCloser: jsonFile}
+		handler.jsonFile = opts.jsonFileWriter
+	}
+	if err != nil {
+		return handler, err
+	}
+	handler.duration = newTestDurationWatch(opts)
+	opts.testDurationWatch = handler.duration
+	return handler, nil
+}
+
+// closeOnceWriter closes the wrapped WriteCloser the first time Close is
+// called. It is used for the --jsonfile, which is closed by finishRun before
+// the file is read, and again when the eventHandler is closed.
+type closeOnceWriter struct {
+	io.WriteCloser
+	closed bool
+}
+
+func (w *closeOnceWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	return w.WriteCloser.Write(p)
+}
+
+func (w *closeOnceWriter) Close() error {
+	if w == nil || w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.WriteCloser.Close()
+}
+
+// openJSONFile opens the --jsonfile, and the events file in the archive, for
+// writing the test2json events. It returns nil if neither file is used.
+func openJSONFile(opts *options) (io.WriteCloser, error) {
+	var jsonFile io.WriteCloser
+	if opts.jsonFile != "" && opts.jsonFileMaxSize > 0 {
+		rotating, err := newRotatingJSONFile(opts.jsonFile, int64(opts.jsonFileMaxSize))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open JSON file")
+		}
+		opts.jsonFileRotation = rotating
+		jsonFile = rotating
+	} else if opts.jsonFile != "" {
+		fh, err := createOutputFile(opts.jsonFile)
 		if err != nil {
-			return handler, errors.Wrap(err, "failed to open JSON file")
+			return nil, errors.Wrap(err, "failed to open JSON file")
 		}
+		jsonFile = compress.NewWriter(opts.jsonFile, fh)
 	}
-	return handler, nil
+	if opts.archivePath != "" {
+		archive, err := createOutputFile(filepath.Join(opts.archivePath, archiveEventsFile))
+		if err != nil {
+			return jsonFile, errors.Wrap(err, "failed to open archive events file")
+		}
+		if jsonFile == nil {
+			jsonFile = archive
+		} else {
+			jsonFile = multiWriteCloser{jsonFile, archive}
+		}
+	}
+	if jsonFile != nil && opts.runID != "" {
+		event := testjson.RunStartEvent(opts.runID, 0, time.Now())
+		if _, err := jsonFile.Write(append(event, '\n')); err != nil {
+			return jsonFile, errors.Wrap(err, "failed to write JSON file")
+		}
+	}
+	return jsonFile, nil
 }
 
 func writeJUnitFile(opts *options, execution *testjson.Execution) error {
 	if opts.junitFile == "" {
 		return nil
 	}
-	junitFile, err := os.Create(opts.junitFile)
+	if len(opts.pkgGroupResults) > 0 {
+		return writePkgGroupsJUnitFile(opts, opts.pkgGroupResults)
+	}
+	junitFile, err := createOutputFile(opts.junitFile)
 	if err != nil {
 		return fmt.Errorf("failed to open JUnit file: %v", err)
 	}
@@ -90,30 +238,117 @@ func writeJUnitFile(opts *options, execution *testjson.Execution) error {
 		}
 	}()
 
-	return junitxml.Write(junitFile, execution, junitxml.Config{
+	return junitxml.Write(junitFile, execution, newJUnitConfig(opts))
+}
+
+// newJUnitConfig returns the junitxml.Config for the --junitfile of the run.
+func newJUnitConfig(opts *options) junitxml.Config {
+	return junitxml.Config{
 		FormatTestSuiteName:     opts.junitTestSuiteNameFormat.Value(),
 		FormatTestCaseClassname: opts.junitTestCaseClassnameFormat.Value(),
-	})
+		Properties:              append(runIDJUnitProperties(opts), traceJUnitProperties(opts)...),
+		Labels:                  testjson.SplitLabels(opts.formatOptions.Labels),
+		KnownIssues:             opts.formatOptions.KnownIssues,
+		Quarantine:              opts.formatOptions.Quarantine,
+		FileAttribute:           opts.fullpath,
+		PassedOutput:            opts.formatOptions.ShowOutput.Passed(),
+		FlakyFailures:           opts.junitFlakyFailures,
+		TestSuitePerTest:        opts.junitTestSuitePerTest,
+	}
 }
 
-func postRunHook(opts *options, execution *testjson.Execution) error {
+func postRunHook(opts *options, execution *testjson.Execution, exitErr error) error {
 	command := opts.postRunHookCmd.Value()
 	if len(command) == 0 {
 		return nil
 	}
+	payload, err := json.Marshal(newPostRunPayload(opts, execution, exitErr))
+	if err != nil {
+		return err
+	}
 
 	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = bytes.NewReader(payload)
 	cmd.Stdout = opts.stdout
 	cmd.Stderr = opts.stderr
 	cmd.Env = append(
 		os.Environ(),
 		"GOTESTSUM_JSONFILE="+opts.jsonFile,
 		"GOTESTSUM_JUNITFILE="+opts.junitFile,
+		"GOTESTSUM_RUN_ID="+opts.runID,
+		fmt.Sprintf("GOTESTSUM_EXIT_CODE=%d", runExitCode(exitErr)),
 		fmt.Sprintf("TESTS_TOTAL=%d", execution.Total()),
 		fmt.Sprintf("TESTS_FAILED=%d", len(execution.Failed())),
 		fmt.Sprintf("TESTS_SKIPPED=%d", len(execution.Skipped())),
 		fmt.Sprintf("TESTS_ERRORS=%d", len(execution.Errors())),
 	)
-	// TODO: send a more detailed report to stdin?
+	if opts.pkgGroupName != "" {
+		cmd.Env = append(cmd.Env, "GOTESTSUM_PKG_GROUP="+opts.pkgGroupName)
+	}
 	return cmd.Run()
 }
+
+// postRunPayload is the summary of the run sent as JSON to the stdin of the
+// --post-run-command.
+type postRunPayload struct {
+	RunID    string `json:"runID,omitempty"`
+	PkgGroup string `json:"pkgGroup,omitempty"`
+	// ExitCode is the exit code of gotestsum, before the post run command.
+	ExitCode int `json:"exitCode"`
+	// Elapsed is the elapsed time of the run in seconds.
+	Elapsed     float64       `json:"elapsed"`
+	Total       int           `json:"total"`
+	Failed      int           `json:"failed"`
+	Skipped     int           `json:"skipped"`
+	Errors      []string      `json:"errors"`
+	FailedTests []postRunTest `json:"failedTests"`
+	Files       postRunFiles  `json:"files"`
+}
+
+type postRunTest struct {
+	Package string `json:"package"`
+	Name    string `json:"name"`
+	// Elapsed is the elapsed time of the test in seconds.
+	Elapsed float64 `json:"elapsed"`
+}
+
+// postRunFiles are the paths of the output files, empty when the file was not
+// written.
+type postRunFiles struct {
+	JSONFile  string `json:"jsonfile,omitempty"`
+	JUnitFile string `json:"junitfile,omitempty"`
+}
+
+func newPostRunPayload(opts *options, execution *testjson.Execution, exitErr error) postRunPayload {
+	payload := postRunPayload{
+		RunID:       opts.runID,
+		PkgGroup:    opts.pkgGroupName,
+		ExitCode:    runExitCode(exitErr),
+		Elapsed:     execution.Elapsed().Seconds(),
+		Total:       execution.Total(),
+		Failed:      len(execution.Failed()),
+		Skipped:     len(execution.Skipped()),
+		Errors:      append([]string{}, execution.Errors()...),
+		FailedTests: []postRunTest{},
+		Files:       postRunFiles{JSONFile: opts.jsonFile, JUnitFile: opts.junitFile},
+	}
+	for _, tc := range execution.Failed() {
+		payload.FailedTests = append(payload.FailedTests, postRunTest{
+			Package: tc.Package,
+			Name:    tc.Test.Name(),
+			Elapsed: tc.Elapsed.Seconds(),
+		})
+	}
+	return payload
+}
+
+// runExitCode returns the code gotestsum exits with when run returns err.
+func runExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case IsExitCoder(err):
+		return ExitCodeWithDefault(err)
+	}
+	return 3
+}
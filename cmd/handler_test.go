@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"strings"
@@ -9,6 +11,7 @@ import (
 
 	"gotest.tools/gotestsum/testjson"
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
 	"gotest.tools/v3/env"
 	"gotest.tools/v3/golden"
 )
@@ -23,17 +26,64 @@ func TestPostRunHook(t *testing.T) {
 		postRunHookCmd: command,
 		jsonFile:       "events.json",
 		junitFile:      "junit.xml",
+		runID:          "ci-1234",
 		stdout:         buf,
 	}
 
 	defer env.Patch(t, "GOTESTSUM_FORMAT", "short")()
 
 	exec := newExecFromTestData(t)
-	err = postRunHook(opts, exec)
+	err = postRunHook(opts, exec, nil)
 	assert.NilError(t, err)
 	golden.Assert(t, buf.String(), "post-run-hook-expected")
 }
 
+func TestPostRunHook_Payload(t *testing.T) {
+	command := &commandValue{}
+	err := command.Set("go run ./testdata/postrunhook/main.go")
+	assert.NilError(t, err)
+
+	buf := new(bytes.Buffer)
+	opts := &options{
+		postRunHookCmd: command,
+		junitFile:      "junit.xml",
+		runID:          "ci-1234",
+		pkgGroupName:   "unit",
+		stdout:         buf,
+	}
+	defer env.Patch(t, "TEST_STUB_STDIN", "1")()
+
+	exec := newExecFromTestData(t)
+	err = postRunHook(opts, exec, exitError{num: 2})
+	assert.NilError(t, err)
+
+	out := buf.String()
+	assert.Assert(t, cmp.Contains(out, "GOTESTSUM_EXIT_CODE=2\n"))
+	assert.Assert(t, cmp.Contains(out, "GOTESTSUM_PKG_GROUP=unit\n"))
+
+	var payload postRunPayload
+	assert.NilError(t, json.Unmarshal([]byte(out[strings.Index(out, "{"):]), &payload))
+	assert.Equal(t, payload.ExitCode, 2)
+	assert.Equal(t, payload.RunID, "ci-1234")
+	assert.Equal(t, payload.PkgGroup, "unit")
+	assert.Equal(t, payload.Total, 46)
+	assert.Equal(t, payload.Failed, 5)
+	assert.Equal(t, payload.Skipped, 4)
+	assert.DeepEqual(t, payload.Files, postRunFiles{JUnitFile: "junit.xml"})
+	assert.Equal(t, len(payload.FailedTests), 5)
+	assert.DeepEqual(t, payload.FailedTests[0], postRunTest{
+		Package: exec.Failed()[0].Package,
+		Name:    exec.Failed()[0].Test.Name(),
+		Elapsed: exec.Failed()[0].Elapsed.Seconds(),
+	})
+}
+
+func TestRunExitCode(t *testing.T) {
+	assert.Equal(t, runExitCode(nil), 0)
+	assert.Equal(t, runExitCode(exitError{num: 4}), 4)
+	assert.Equal(t, runExitCode(fmt.Errorf("failed to write junit file")), 3)
+}
+
 func newExecFromTestData(t *testing.T) *testjson.Execution {
 	t.Helper()
 	f, err := os.Open("../testjson/testdata/go-test-json.out")
@@ -57,7 +107,7 @@ func (bufferCloser) Close() error { return nil }
 func TestEventHandler_Event_WithMissingActionFail(t *testing.T) {
 	buf := new(bufferCloser)
 	errBuf := new(bytes.Buffer)
-	format := testjson.NewEventFormatter(errBuf, "testname")
+	format := testjson.NewEventFormatterWithOptions(errBuf, "testname", testjson.FormatOptions{})
 
 	source := golden.Get(t, "../../testjson/testdata/go-test-json-missing-test-fail.out")
 	cfg := testjson.ScanConfig{
@@ -74,7 +124,7 @@ func TestEventHandler_Event_WithMissingActionFail(t *testing.T) {
 }
 
 func TestEventHandler_Event_MaxFails(t *testing.T) {
-	format := testjson.NewEventFormatter(ioutil.Discard, "testname")
+	format := testjson.NewEventFormatterWithOptions(ioutil.Discard, "testname", testjson.FormatOptions{})
 
 	source := golden.Get(t, "../../testjson/testdata/go-test-json.out")
 	cfg := testjson.ScanConfig{
@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"gotest.tools/gotestsum/internal/history"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// writeHistoryDB adds the results of the run to the --history-db file.
+func writeHistoryDB(opts *options, exec *testjson.Execution) error {
+	if opts.historyDB == "" {
+		return nil
+	}
+	db, err := history.OpenDB(opts.historyDB)
+	if err != nil {
+		return err
+	}
+	db.Add(opts.runID, exec)
+	return db.Save()
+}
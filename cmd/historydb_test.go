@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/history"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestWriteHistoryDB(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+
+	opts := &options{historyDB: filepath.Join(dir.Path(), "history.json"), runID: "ci-1"}
+	exec := newExecFromTestData(t)
+	assert.NilError(t, writeHistoryDB(opts, exec))
+
+	db, err := history.OpenDB(opts.historyDB)
+	assert.NilError(t, err)
+	for _, tc := range exec.Failed() {
+		if tc.Test == "" {
+			continue
+		}
+		results := db.Results(tc.Package, tc.Test.Name())
+		assert.Assert(t, len(results) > 0, tc.Test)
+		assert.Equal(t, results[len(results)-1].Run, "ci-1")
+	}
+}
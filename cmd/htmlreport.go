@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"fmt"
+
+	"gotest.tools/gotestsum/internal/htmlreport"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func writeHTMLReport(opts *options, execution *testjson.Execution) error {
+	if opts.htmlReportFile == "" {
+		return nil
+	}
+	file, err := createOutputFile(opts.htmlReportFile)
+	if err != nil {
+		return fmt.Errorf("failed to open HTML report: %v", err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Errorf("Failed to close HTML report: %v", err)
+		}
+	}()
+
+	return htmlreport.Write(file, execution, htmlreport.Config{
+		KnownIssues:  opts.formatOptions.KnownIssues,
+		PassedOutput: opts.formatOptions.ShowOutput.Passed(),
+	})
+}
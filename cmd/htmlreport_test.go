@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestWriteHTMLReport(t *testing.T) {
+	input := `{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestOne","Elapsed":0.1}
+{"Action":"pass","Package":"example.com/pkg","Elapsed":0.2}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+
+	dir := fs.NewDir(t, t.Name())
+	opts := &options{htmlReportFile: dir.Join("reports", "report.html")}
+	assert.NilError(t, writeHTMLReport(opts, exec))
+
+	raw, err := os.ReadFile(opts.htmlReportFile)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(raw), "<span class=\"label\">PASS</span> TestOne"), string(raw))
+}
@@ -0,0 +1,324 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/cover"
+	"gotest.tools/gotestsum/internal/history"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func validateImpact(opts *options) error {
+	name := "--impacted-by"
+	switch {
+	case opts.impactRecord && len(opts.impactedBy) > 0:
+		return fmt.Errorf("--impact-record can not be used with --impacted-by")
+	case opts.impactRecord:
+		name = "--impact-record"
+	case len(opts.impactedBy) == 0:
+		return nil
+	}
+
+	runFlag, _ := argIndex("run", opts.args)
+	var unsupported string
+	switch {
+	case opts.historyDB == "":
+		return fmt.Errorf("%v requires --history-db", name)
+	case opts.rawCommand:
+		unsupported = "--raw-command"
+	case len(opts.pkgGroups) > 0:
+		unsupported = "--pkg-group"
+	case len(opts.noCacheTests) > 0:
+		unsupported = "--no-cache-tests"
+	case opts.watch:
+		unsupported = "--watch"
+	case opts.untilFailure:
+		unsupported = "--until-failure"
+	case opts.rerunLastFailed:
+		unsupported = "--rerun-last-failed"
+	case !opts.impactRecord && runFlag >= 0:
+		unsupported = "the go test -run flag"
+	case opts.impactRecord && coverProfileArg(opts.args) != "":
+		unsupported = "the go test -coverprofile flag"
+	case len(opts.args) > 0 && len(opts.packages) == 0:
+		return fmt.Errorf("when go test args are used with %v "+
+			"the list of packages to test must be specified by the --packages flag", name)
+	default:
+		return nil
+	}
+	return fmt.Errorf("%v can not be used with %v", name, unsupported)
+}
+
+// setupImpactedBy sets opts.packages and opts.selectRunFlag to run only the
+// tests that are impacted by the --impacted-by files. A test is impacted by a
+// file when the --history-db records that the test ran code in the file, or
+// when the test is declared in the file. Returns false if there are no tests
+// to run.
+func setupImpactedBy(opts *options) (bool, error) {
+	if len(opts.impactedBy) == 0 {
+		return true, nil
+	}
+	db, err := history.OpenDB(opts.historyDB)
+	if err != nil {
+		return false, err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return false, err
+	}
+	listArgs := append(goListBuildFlags(opts.args), cmdArgPackageList(opts, rerunOpts{}, "./...")...)
+	dirs, err := goListDirsFn(listArgs)
+	if err != nil {
+		return false, fmt.Errorf("failed to list packages for --impacted-by: %w", err)
+	}
+
+	files := impactFilePaths(cwd, opts.impactedBy)
+	impacted := db.ImpactedTests(files)
+	for _, file := range files {
+		switch {
+		case strings.HasSuffix(file, "_test.go"):
+			pkg, ok := dirs[filepath.Join(cwd, filepath.Dir(filepath.FromSlash(file)))]
+			if !ok {
+				continue
+			}
+			names, err := testFuncNames(filepath.Join(cwd, filepath.FromSlash(file)))
+			switch {
+			case os.IsNotExist(err):
+			case err != nil:
+				log.Warnf("failed to read the tests in %v: %v", file, err)
+			default:
+				impacted[pkg] = append(impacted[pkg], names...)
+			}
+		case !strings.HasSuffix(file, ".go"):
+			log.Warnf("--impacted-by only uses Go files, ignoring %v", file)
+		}
+	}
+
+	tested := make(map[string]bool, len(dirs))
+	for _, pkg := range dirs {
+		tested[pkg] = true
+	}
+	var packages, patterns []string
+	seen := make(map[string]bool)
+	for pkg, names := range impacted {
+		if !tested[pkg] || len(names) == 0 {
+			continue
+		}
+		packages = append(packages, pkg)
+		for _, name := range names {
+			// Tests in different packages may have the same name.
+			if pattern := goTestRunPattern(testjson.TestName(name)); !seen[pattern] {
+				seen[pattern] = true
+				patterns = append(patterns, pattern)
+			}
+		}
+	}
+	if len(packages) == 0 {
+		fmt.Fprintf(opts.stdout, "No tests are impacted by %v\n", strings.Join(files, ", "))
+		return false, nil
+	}
+	sort.Strings(packages)
+	sort.Strings(patterns)
+	log.Debugf("impacted by %v: %d tests in %d packages", files, len(patterns), len(packages))
+
+	opts.packages = packages
+	opts.selectRunFlag = "-test.run=" + strings.Join(patterns, "|")
+	return true, nil
+}
+
+// impactFilePaths returns the paths of files relative to cwd, with forward
+// slashes, which is the format of the paths in the impact map of a DB.
+func impactFilePaths(cwd string, files []string) []string {
+	result := make([]string, 0, len(files))
+	for _, file := range files {
+		if filepath.IsAbs(file) {
+			if rel, err := filepath.Rel(cwd, file); err == nil {
+				file = rel
+			}
+		}
+		result = append(result, filepath.ToSlash(filepath.Clean(file)))
+	}
+	return result
+}
+
+// testFuncNames returns the names of the tests, examples, and fuzz tests
+// declared in the Go file at path.
+func testFuncNames(path string) ([]string, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || fn.Name.Name == "TestMain" {
+			continue
+		}
+		for _, prefix := range []string{"Test", "Example", "Fuzz"} {
+			if isTestFuncName(fn.Name.Name, prefix) {
+				names = append(names, fn.Name.Name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// isTestFuncName returns true if name is prefix followed by nothing, or by a
+// character which is not lower case, the same as go test.
+func isTestFuncName(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	if len(name) == len(prefix) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(name[len(prefix):])
+	return !unicode.IsLower(r)
+}
+
+// runCoverTestFn is a shim for testing. It returns the combined output of the
+// command.
+var runCoverTestFn = func(ctx context.Context, args []string) ([]byte, error) {
+	log.Debugf("exec: %s", args)
+	return exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+}
+
+// recordImpact runs each test in exec again on its own, with coverage of all
+// the packages being tested, and replaces the source files run by each test in
+// the impact map of the --history-db.
+func recordImpact(ctx context.Context, opts *options, exec *testjson.Execution) error {
+	if !opts.impactRecord {
+		return nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	patterns := cmdArgPackageList(opts, rerunOpts{}, "./...")
+	dirs, err := goListDirsFn(append(goListBuildFlags(opts.args), patterns...))
+	if err != nil {
+		return fmt.Errorf("failed to list packages: %w", err)
+	}
+	pkgDirs := make(map[string]string, len(dirs))
+	for dir, pkg := range dirs {
+		pkgDirs[pkg] = dir
+	}
+	tmpDir, err := ioutil.TempDir("", "gotestsum-impact")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir) // nolint: errcheck
+
+	db, err := history.OpenDB(opts.historyDB)
+	if err != nil {
+		return err
+	}
+	var recorded int
+	for i, tc := range impactTestCases(opts, exec) {
+		profile := filepath.Join(tmpDir, fmt.Sprintf("%d.out", i))
+		out, err := runCoverTestFn(ctx, impactCmdArgs(opts, tc, patterns, profile))
+		if err != nil && !IsExitCoder(err) {
+			return fmt.Errorf("failed to run go test: %w", err)
+		}
+		files, err := coveredFiles(profile, pkgDirs, cwd)
+		if err != nil {
+			log.Warnf("failed to record the files run by %v %v: %v\n%s",
+				testjson.RelativePackagePath(tc.Package), tc.Test, err, out)
+			continue
+		}
+		db.SetImpact(tc.Package, tc.Test.Name(), files)
+		recorded++
+	}
+	fmt.Fprintf(opts.stdout, "\nRecorded the files run by %d %s in %v\n",
+		recorded, pluralize(recorded, "test", "tests"), opts.historyDB)
+	return db.Save()
+}
+
+// impactTestCases returns the top-level tests that passed or failed in exec,
+// without the tests that report static analysis problems.
+func impactTestCases(opts *options, exec *testjson.Execution) []testjson.TestCase {
+	var result []testjson.TestCase
+	seen := make(map[string]bool)
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		tcs := withoutAnalysisTestCases(opts)(append(append([]testjson.TestCase(nil), pkg.Passed...), pkg.Failed...))
+		for _, tc := range tcs {
+			key := tc.Package + "." + tc.Test.Name()
+			if tc.Test.IsSubTest() || seen[key] {
+				continue
+			}
+			seen[key] = true
+			result = append(result, tc)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Package != result[j].Package {
+			return result[i].Package < result[j].Package
+		}
+		return result[i].Test < result[j].Test
+	})
+	return result
+}
+
+// impactCmdArgs returns the go test command that runs only tc, and writes the
+// coverage of all the packages in patterns to profile.
+func impactCmdArgs(opts *options, tc testjson.TestCase, patterns []string, profile string) []string {
+	args := []string{
+		"go", "test", "-count=1",
+		"-run=" + goTestRunPattern(tc.Test),
+		"-coverpkg=" + strings.Join(patterns, ","),
+		"-coverprofile=" + profile,
+	}
+	args = append(args, goListBuildFlags(opts.args)...)
+	return append(args, tc.Package)
+}
+
+// coveredFiles returns the source files with at least one statement that was
+// run, from the cover profile. The files are relative to cwd, when the
+// directory of their package is in pkgDirs.
+func coveredFiles(profile string, pkgDirs map[string]string, cwd string) ([]string, error) {
+	profiles, err := cover.ParseProfiles(profile)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, p := range profiles {
+		if !profileHasCoverage(p) {
+			continue
+		}
+		file := p.FileName
+		if dir, ok := pkgDirs[path.Dir(file)]; ok {
+			if rel, err := filepath.Rel(cwd, filepath.Join(dir, path.Base(file))); err == nil {
+				file = filepath.ToSlash(rel)
+			}
+		}
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func profileHasCoverage(p *cover.Profile) bool {
+	for _, block := range p.Blocks {
+		if block.Count > 0 {
+			return true
+		}
+	}
+	return false
+}
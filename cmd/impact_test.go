@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/history"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func patchGoListDirsFn(dirs map[string]string) func() {
+	orig := goListDirsFn
+	goListDirsFn = func([]string) (map[string]string, error) {
+		return dirs, nil
+	}
+	return func() {
+		goListDirsFn = orig
+	}
+}
+
+func TestSetupImpactedBy(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NilError(t, err)
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("history.json", `{"packages": {}, "impact": {
+  "example.com/proj/api": {"TestCreate": ["store/db.go", "api/api.go"], "TestList": ["api/api.go"]},
+  "example.com/proj/store": {"TestGet": ["store/db.go"]},
+  "example.com/proj/other": {"TestGet": ["store/db.go"]}
+}}`),
+		fs.WithFile("cache_test.go", `package store
+
+func TestMain(m *testing.M) {}
+func TestCache(t *testing.T) {}
+func Testing(t *testing.T) {}
+func ExampleCache() {}
+func helper() {}
+`))
+	defer dir.Remove()
+	defer patchGoListDirsFn(map[string]string{
+		filepath.Join(cwd, "api"): "example.com/proj/api",
+		dir.Path():                "example.com/proj/store",
+	})()
+
+	opts := &options{
+		historyDB:  dir.Join("history.json"),
+		impactedBy: []string{"./store/db.go", dir.Join("cache_test.go")},
+	}
+	ok, err := setupImpactedBy(opts)
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, opts.packages, []string{"example.com/proj/api", "example.com/proj/store"})
+	assert.Equal(t, opts.selectRunFlag, "-test.run=^ExampleCache$|^TestCache$|^TestCreate$|^TestGet$")
+}
+
+func TestSetupImpactedBy_NoImpactedTests(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("history.json", `{"packages": {}, "impact": {
+  "example.com/proj/api": {"TestList": ["api/api.go"]}
+}}`))
+	defer dir.Remove()
+	defer patchGoListDirsFn(map[string]string{})()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		historyDB:  dir.Join("history.json"),
+		impactedBy: []string{"store/db.go"},
+		stdout:     out,
+	}
+	ok, err := setupImpactedBy(opts)
+	assert.NilError(t, err)
+	assert.Assert(t, !ok)
+	assert.Equal(t, out.String(), "No tests are impacted by store/db.go\n")
+}
+
+func TestRecordImpact(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NilError(t, err)
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	defer patchGoListDirsFn(map[string]string{
+		filepath.Join(cwd, "api"):   "example.com/proj/api",
+		filepath.Join(cwd, "store"): "example.com/proj/store",
+	})()
+
+	var commands [][]string
+	orig := runCoverTestFn
+	runCoverTestFn = func(_ context.Context, args []string) ([]byte, error) {
+		commands = append(commands, args)
+		profile := strings.TrimPrefix(args[5], "-coverprofile=")
+		return nil, ioutil.WriteFile(profile, []byte(`mode: set
+example.com/proj/api/api.go:3.10,5.2 1 0
+example.com/proj/store/db.go:3.10,5.2 1 1
+example.com/proj/store/db.go:7.10,9.2 1 0
+`), 0644)
+	}
+	defer func() { runCoverTestFn = orig }()
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Package": "example.com/proj/api", "Test": "TestCreate", "Action": "run"}
+{"Package": "example.com/proj/api", "Test": "TestCreate/sub", "Action": "run"}
+{"Package": "example.com/proj/api", "Test": "TestCreate/sub", "Action": "pass"}
+{"Package": "example.com/proj/api", "Test": "TestCreate", "Action": "pass"}
+{"Package": "example.com/proj/api", "Action": "pass"}
+`),
+	})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		impactRecord: true,
+		historyDB:    dir.Join("history.json"),
+		packages:     []string{"./..."},
+		args:         []string{"-tags=it"},
+		stdout:       out,
+	}
+	assert.NilError(t, recordImpact(context.Background(), opts, exec))
+	assert.Equal(t, len(commands), 1)
+	assert.DeepEqual(t, commands[0][:5], []string{
+		"go", "test", "-count=1", "-run=^TestCreate$", "-coverpkg=./...",
+	})
+	assert.DeepEqual(t, commands[0][6:], []string{"-tags=it", "example.com/proj/api"})
+	assert.Assert(t, cmp.Contains(out.String(), "Recorded the files run by 1 test in"))
+
+	db, err := history.OpenDB(opts.historyDB)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, db.Impact, map[string]map[string][]string{
+		"example.com/proj/api": {"TestCreate": {"store/db.go"}},
+	})
+}
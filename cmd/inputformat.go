@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// validateInputFormat checks that --input-format is one of
+// testjson.InputFormats, and that a format other than go is only used with
+// the --raw-command which runs the tests.
+func validateInputFormat(opts *options) error {
+	if _, ok := testjson.NewInputAdapter(opts.inputFormat); !ok {
+		return fmt.Errorf("invalid --input-format %q, must be one of: %v",
+			opts.inputFormat, strings.Join(testjson.InputFormats, ", "))
+	}
+	if opts.inputFormat == "go" || opts.inputFormat == "" {
+		return nil
+	}
+	switch {
+	case !opts.rawCommand:
+		return fmt.Errorf("--input-format=%v requires --raw-command", opts.inputFormat)
+	case maxRerunAttempts(opts) > 0:
+		return fmt.Errorf("--input-format=%v can not be used with --rerun-fails", opts.inputFormat)
+	case opts.watch:
+		return fmt.Errorf("--input-format=%v can not be used with --watch", opts.inputFormat)
+	}
+	return nil
+}
+
+// newInputAdapter returns the InputAdapter for a scan of the output of the
+// tests. A new adapter is used for each scan, because an adapter keeps the
+// state of the running tests.
+func newInputAdapter(opts *options) testjson.InputAdapter {
+	adapter, _ := testjson.NewInputAdapter(opts.inputFormat)
+	return adapter
+}
@@ -0,0 +1,297 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+	"gotest.tools/gotestsum/internal/tui"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// failure is a failed test shown by --interactive-summary.
+type failure struct {
+	tc     testjson.TestCase
+	output []string
+	// rerun is the result of the most recent rerun of the test, or nil if the
+	// test has not been rerun.
+	rerun *rerunResult
+}
+
+// rerunResult is the result of rerunning a single test from the
+// --interactive-summary.
+type rerunResult struct {
+	result  testjson.Action
+	elapsed time.Duration
+	output  []string
+}
+
+// failureBrowser is the tui.Model for --interactive-summary.
+type failureBrowser struct {
+	failures []*failure
+	list     tui.List
+	// detail is true when the output of the selected failure is shown.
+	detail bool
+	pager  tui.Pager
+	// status is a message about the last action, shown below the title.
+	status string
+	// height is the number of rows shown by the last call to View.
+	height int
+	rerun  func(tc testjson.TestCase) (rerunResult, error)
+}
+
+func newFailureBrowser(opts *options, exec *testjson.Execution) *failureBrowser {
+	b := &failureBrowser{
+		rerun: func(tc testjson.TestCase) (rerunResult, error) {
+			return rerunTestCase(opts, tc)
+		},
+	}
+	for _, tc := range testjson.FilterFailedUnique(exec.Failed()) {
+		b.failures = append(b.failures, &failure{tc: tc, output: exec.OutputLines(tc)})
+	}
+	return b
+}
+
+func (b *failureBrowser) Update(key tui.Key) bool {
+	if b.detail {
+		if b.pager.Update(key, b.height) {
+			return false
+		}
+	} else if b.list.Update(key, b.height) {
+		return false
+	}
+
+	switch key {
+	case "q", tui.KeyCtrlC:
+		return true
+	case tui.KeyEnter, tui.KeyRight, "l":
+		if len(b.failures) > 0 {
+			b.detail = true
+			b.pager = tui.Pager{}
+		}
+	case tui.KeyEscape, tui.KeyBackspace, tui.KeyLeft, "h":
+		b.detail = false
+	case "r":
+		b.rerunSelected()
+	}
+	return false
+}
+
+func (b *failureBrowser) rerunSelected() {
+	if len(b.failures) == 0 {
+		return
+	}
+	f := b.failures[b.list.Cursor]
+	result, err := b.rerun(f.tc)
+	if err != nil {
+		b.status = fmt.Sprintf("rerun of %s failed: %v", f.tc.Test.Name(), err)
+		return
+	}
+	f.rerun = &result
+	b.status = fmt.Sprintf("rerun of %s: %s in %s",
+		f.tc.Test.Name(), formatAction(result.result), result.elapsed.Round(time.Millisecond))
+	if b.detail {
+		// scroll to the start of the output from the rerun
+		b.pager.Offset = len(b.outputLines(f)) - len(result.output)
+	}
+}
+
+// Number of lines used by the header and footer of each view.
+const (
+	browserHeaderLines = 3
+	browserFooterLines = 1
+)
+
+func (b *failureBrowser) View(width, height int) []string {
+	b.height = height - browserHeaderLines - browserFooterLines
+	if b.detail {
+		return b.detailView(width, height)
+	}
+
+	lines := []string{
+		tui.Bold + tui.Line(fmt.Sprintf("gotestsum · %d failed tests", len(b.failures)), width, false) + tui.Reset,
+		tui.Line(b.status, width, false),
+		tui.Bold + tui.Line(fmt.Sprintf("%10s  %-5s  %s", "ELAPSED", "RERUN", "TEST"), width, false) + tui.Reset,
+	}
+	b.list.SetLen(len(b.failures))
+	start, end := b.list.Visible(b.height)
+	for i := start; i < end; i++ {
+		f := b.failures[i]
+		rerun := ""
+		if f.rerun != nil {
+			rerun = formatAction(f.rerun.result)
+		}
+		row := fmt.Sprintf("%10s  %-5s  %s.%s",
+			f.tc.Elapsed.Round(time.Millisecond), rerun,
+			testjson.RelativePackagePath(f.tc.Package), f.tc.Test.Name())
+		lines = append(lines, tui.Line(row, width, i == b.list.Cursor))
+	}
+	if len(b.failures) == 0 {
+		lines = append(lines, "no failed tests")
+	}
+	return withBrowserFooter(lines, width, height,
+		"↑/↓ move  enter output  r rerun  q quit")
+}
+
+func (b *failureBrowser) detailView(width, height int) []string {
+	f := b.failures[b.list.Cursor]
+	lines := []string{
+		tui.Bold + tui.Line(fmt.Sprintf("gotestsum · %s.%s",
+			testjson.RelativePackagePath(f.tc.Package), f.tc.Test.Name()), width, false) + tui.Reset,
+		tui.Line(b.status, width, false),
+		"",
+	}
+	output := b.outputLines(f)
+	b.pager.Len = len(output)
+	start, end := b.pager.Visible(b.height)
+	for _, line := range output[start:end] {
+		lines = append(lines, tui.Line(line, width, false))
+	}
+	return withBrowserFooter(lines, width, height,
+		"↑/↓ scroll  r rerun  esc back  q quit")
+}
+
+// outputLines returns the lines of output shown in the detail view for f. The
+// output of the most recent rerun follows the output of the failure.
+func (b *failureBrowser) outputLines(f *failure) []string {
+	lines := formatOutputLines(f.output)
+	if f.rerun != nil {
+		lines = append(lines, "",
+			fmt.Sprintf("=== Rerun %s in %s", formatAction(f.rerun.result),
+				f.rerun.elapsed.Round(time.Millisecond)))
+		lines = append(lines, formatOutputLines(f.rerun.output)...)
+	}
+	return lines
+}
+
+// formatOutputLines removes the trailing newline from each line, and expands
+// tabs so that the width of each line can be measured.
+func formatOutputLines(output []string) []string {
+	lines := make([]string, 0, len(output))
+	for _, line := range output {
+		line = strings.TrimSuffix(line, "\n")
+		lines = append(lines, strings.ReplaceAll(line, "\t", "    "))
+	}
+	return lines
+}
+
+// withBrowserFooter pads lines to fill the screen, and adds the help text on
+// the last line.
+func withBrowserFooter(lines []string, width, height int, help string) []string {
+	for len(lines) < height-browserFooterLines {
+		lines = append(lines, "")
+	}
+	if len(lines) > height-browserFooterLines && height > browserFooterLines {
+		lines = lines[:height-browserFooterLines]
+	}
+	return append(lines, tui.Line(help, width, false))
+}
+
+func formatAction(action testjson.Action) string {
+	return strings.ToUpper(string(action))
+}
+
+// rerunTestCase runs 'go test' for a single test case, and returns the result
+// and output of the test.
+func rerunTestCase(opts *options, tc testjson.TestCase) (rerunResult, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	goTestProc, err := startGoTestFn(ctx, goTestCmdArgs(opts, newRerunOptsFromTestCase(tc)))
+	if err != nil {
+		return rerunResult{}, err
+	}
+	handler := &stderrRecorder{}
+	cfg := testjson.ScanConfig{
+		Stdout:          goTestProc.stdout,
+		Stderr:          goTestProc.stderr,
+		Handler:         handler,
+		Stop:            cancel,
+		RewriteTestName: newTestNameRewriter(opts),
+		RewriteOutput:   newOutputRewriter(opts),
+	}
+	exec, err := testjson.ScanTestOutputContext(ctx, cfg)
+	if err != nil {
+		return rerunResult{}, err
+	}
+	exitErr := goTestProc.cmd.Wait()
+
+	result := rerunResult{result: testjson.ActionFail, elapsed: time.Since(start)}
+	if pkg := exec.Package(tc.Package); pkg != nil {
+		if other, action, ok := findTestCase(pkg, tc.Test); ok {
+			result.result = action
+			result.elapsed = other.Elapsed
+			result.output = pkg.OutputLines(other)
+			return result, nil
+		}
+		result.output = pkg.OutputLines(testjson.TestCase{})
+	}
+	result.output = append(result.output, handler.lines...)
+	if exitErr != nil {
+		result.output = append(result.output, fmt.Sprintf("go test failed: %v", exitErr))
+	}
+	return result, nil
+}
+
+// findTestCase returns the most recent run of the test with name in pkg, and
+// the result of the test.
+func findTestCase(pkg *testjson.Package, name testjson.TestName) (testjson.TestCase, testjson.Action, bool) {
+	for _, group := range []struct {
+		action testjson.Action
+		tcs    []testjson.TestCase
+	}{
+		{action: testjson.ActionFail, tcs: pkg.Failed},
+		{action: testjson.ActionSkip, tcs: pkg.Skipped},
+		{action: testjson.ActionPass, tcs: pkg.Passed},
+	} {
+		for i := len(group.tcs) - 1; i >= 0; i-- {
+			if group.tcs[i].Test == name {
+				return group.tcs[i], group.action, true
+			}
+		}
+	}
+	return testjson.TestCase{}, "", false
+}
+
+// stderrRecorder is a testjson.EventHandler that stores lines written to
+// stderr by 'go test'.
+type stderrRecorder struct {
+	lines []string
+}
+
+func (r *stderrRecorder) Event(testjson.TestEvent, *testjson.Execution) error {
+	return nil
+}
+
+func (r *stderrRecorder) Err(text string) error {
+	r.lines = append(r.lines, text)
+	return nil
+}
+
+// runInteractiveSummary opens the --interactive-summary when there are failed
+// tests and stdout is a terminal.
+func runInteractiveSummary(opts *options, exec *testjson.Execution) error {
+	if !opts.interactiveSummary || len(exec.Failed()) == 0 {
+		return nil
+	}
+	if !stdoutIsTerminal() {
+		log.Debugf("--interactive-summary is ignored because stdout is not a terminal")
+		return nil
+	}
+	return runTUIFn(newFailureBrowser(opts, exec))
+}
+
+// shims for testing
+var (
+	stdoutIsTerminal = func() bool {
+		return term.IsTerminal(int(os.Stdout.Fd()))
+	}
+	runTUIFn = func(model tui.Model) error {
+		return tui.Run(os.Stdout, model)
+	}
+)
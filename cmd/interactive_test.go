@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/tui"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+const interactiveSummaryRun = `{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestOne","Output":"=== RUN   TestOne\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestOne","Output":"\tone_test.go:10: broken\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestOne","Elapsed":0.4}
+{"Action":"run","Package":"example.com/pkg","Test":"TestTwo"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestTwo","Output":"=== RUN   TestTwo\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestTwo","Elapsed":1.2}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":2}
+`
+
+func newTestFailureBrowser(t *testing.T) *failureBrowser {
+	t.Helper()
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(interactiveSummaryRun),
+	})
+	assert.NilError(t, err)
+	return newFailureBrowser(&options{}, exec)
+}
+
+// browserLines returns the lines of the view between the header and footer,
+// without escape sequences.
+func browserLines(b *failureBrowser) []string {
+	lines := b.View(80, 10)
+	var result []string
+	for _, line := range lines[browserHeaderLines : len(lines)-browserFooterLines] {
+		line = strings.NewReplacer(tui.Reverse, "", tui.Reset, "", tui.Bold, "").Replace(line)
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+func TestFailureBrowser(t *testing.T) {
+	b := newTestFailureBrowser(t)
+	var reruns []string
+	b.rerun = func(tc testjson.TestCase) (rerunResult, error) {
+		reruns = append(reruns, tc.Test.Name())
+		return rerunResult{
+			result:  testjson.ActionPass,
+			elapsed: 300e6,
+			output:  []string{"=== RUN   TestTwo\n", "--- PASS: TestTwo (0.30s)\n"},
+		}, nil
+	}
+
+	expected := []string{
+		"400ms         example.com/pkg.TestOne",
+		"1.2s         example.com/pkg.TestTwo",
+	}
+	assert.DeepEqual(t, browserLines(b), expected)
+
+	b.Update(tui.KeyEnter)
+	expected = []string{
+		"=== RUN   TestOne",
+		"one_test.go:10: broken",
+	}
+	assert.DeepEqual(t, browserLines(b), expected)
+
+	b.Update(tui.KeyEscape)
+	b.Update(tui.KeyDown)
+	b.Update(tui.KeyEnter)
+	b.Update("r")
+	assert.DeepEqual(t, reruns, []string{"TestTwo"})
+	assert.Equal(t, b.status, "rerun of TestTwo: PASS in 300ms")
+	expected = []string{
+		"=== RUN   TestTwo",
+		"=== Rerun PASS in 300ms",
+		"=== RUN   TestTwo",
+		"--- PASS: TestTwo (0.30s)",
+	}
+	assert.DeepEqual(t, browserLines(b), expected)
+
+	b.Update(tui.KeyEscape)
+	expected = []string{
+		"400ms         example.com/pkg.TestOne",
+		"1.2s  PASS   example.com/pkg.TestTwo",
+	}
+	assert.DeepEqual(t, browserLines(b), expected)
+
+	assert.Assert(t, b.Update("q"))
+}
+
+func TestRerunTestCase(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(interactiveSummaryRun),
+	})
+	assert.NilError(t, err)
+	tc := exec.Failed()[1]
+
+	var args []string
+	fn := func(a []string) *proc {
+		args = a
+		out := `{"Action":"run","Package":"example.com/pkg","Test":"TestTwo"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestTwo","Output":"--- SKIP: TestTwo\n"}
+{"Action":"skip","Package":"example.com/pkg","Test":"TestTwo","Elapsed":0.1}
+{"Action":"pass","Package":"example.com/pkg","Elapsed":0.2}
+`
+		return &proc{
+			cmd:    fakeWaiter{},
+			stdout: strings.NewReader(out),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	defer patchStartGoTestFn(fn)()
+
+	result, err := rerunTestCase(&options{}, tc)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, args,
+		[]string{"go", "test", "-json", "-test.run=^TestTwo$", "example.com/pkg"})
+	assert.Equal(t, result.result, testjson.ActionSkip)
+	assert.Equal(t, result.elapsed.String(), "100ms")
+	assert.DeepEqual(t, result.output, []string{"--- SKIP: TestTwo\n"})
+}
+
+func TestRunInteractiveSummary(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(interactiveSummaryRun),
+	})
+	assert.NilError(t, err)
+
+	origTerminal, origRun := stdoutIsTerminal, runTUIFn
+	defer func() {
+		stdoutIsTerminal, runTUIFn = origTerminal, origRun
+	}()
+	var ran bool
+	runTUIFn = func(tui.Model) error {
+		ran = true
+		return nil
+	}
+
+	stdoutIsTerminal = func() bool { return false }
+	assert.NilError(t, runInteractiveSummary(&options{interactiveSummary: true}, exec))
+	assert.Assert(t, !ran)
+
+	stdoutIsTerminal = func() bool { return true }
+	assert.NilError(t, runInteractiveSummary(&options{interactiveSummary: true}, exec))
+	assert.Assert(t, ran)
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/compress"
+)
+
+// rotatingJSONFile writes the --jsonfile, and moves it to a rotated file when
+// it reaches --jsonfile-max-size. Only one rotated file is kept, so the events
+// of a long run use at most twice the max size on disk, and the most recent
+// events are always kept.
+//
+// Each call to Write must be a whole event, so that a file never starts or
+// ends with part of an event.
+type rotatingJSONFile struct {
+	path        string
+	rotatedPath string
+	maxSize     int64
+
+	out *countingWriteCloser
+	w   io.WriteCloser
+	// events is the number of events in the current file, and rotatedEvents is
+	// the number of events in the rotated file.
+	events        int
+	rotatedEvents int
+	// rotations is the number of times the file was rotated, and removed is the
+	// number of events that were removed with a rotated file.
+	rotations int
+	removed   int
+}
+
+func newRotatingJSONFile(path string, maxSize int64) (*rotatingJSONFile, error) {
+	f := &rotatingJSONFile{path: path, rotatedPath: rotatedPath(path), maxSize: maxSize}
+	// A rotated file from a previous run would be mistaken for part of this run.
+	if err := removeRotatedFile(f.rotatedPath); err != nil {
+		return nil, err
+	}
+	if err := f.create(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingJSONFile) create() error {
+	fh, err := createOutputFile(f.path)
+	if err != nil {
+		return err
+	}
+	f.out = &countingWriteCloser{WriteCloser: fh}
+	f.w = compress.NewWriter(f.path, f.out)
+	return nil
+}
+
+func (f *rotatingJSONFile) Write(p []byte) (int, error) {
+	if f.out.size >= f.maxSize && f.events > 0 {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.w.Write(p)
+	if err == nil {
+		f.events++
+	}
+	return n, err
+}
+
+// rotate replaces the rotated file with the current file, and creates a new
+// current file.
+func (f *rotatingJSONFile) rotate() error {
+	if err := f.w.Close(); err != nil {
+		return err
+	}
+	// Rename does not replace an existing file on Windows.
+	if err := removeRotatedFile(f.rotatedPath); err != nil {
+		return err
+	}
+	if err := os.Rename(f.path, f.rotatedPath); err != nil {
+		return fmt.Errorf("failed to rotate JSON file: %w", err)
+	}
+	f.rotations++
+	f.removed += f.rotatedEvents
+	f.rotatedEvents, f.events = f.events, 0
+	return f.create()
+}
+
+func (f *rotatingJSONFile) Close() error {
+	return f.w.Close()
+}
+
+func removeRotatedFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove rotated JSON file: %w", err)
+	}
+	return nil
+}
+
+// rotatedPath returns path with .1 added before the extensions of the file
+// name, ex: events.json.gz is rotated to events.1.json.gz.
+func rotatedPath(path string) string {
+	dir, name := filepath.Split(path)
+	i := strings.Index(name, ".")
+	if i <= 0 {
+		return path + ".1"
+	}
+	return dir + name[:i] + ".1" + name[i:]
+}
+
+// countingWriteCloser counts the number of bytes written to the file.
+type countingWriteCloser struct {
+	io.WriteCloser
+	size int64
+}
+
+func (w *countingWriteCloser) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// printJSONFileRotated prints a note when the --jsonfile was rotated, because
+// the file no longer has all the events of the run.
+func printJSONFileRotated(out io.Writer, f *rotatingJSONFile) {
+	if f == nil || f.rotations == 0 {
+		return
+	}
+	if f.removed == 0 {
+		fmt.Fprintf(out, "\n=== JSON file rotated\n")
+		fmt.Fprintf(out, "The events reached --jsonfile-max-size %v, and are split between %v and %v.\n",
+			formatBytes(uint64(f.maxSize)), f.rotatedPath, f.path)
+		return
+	}
+	fmt.Fprintf(out, "\n=== JSON file truncated (%d %s removed)\n", f.removed,
+		pluralize(f.removed, "event", "events"))
+	fmt.Fprintf(out, "The events reached --jsonfile-max-size %v. The oldest events were removed, "+
+		"the most recent events are in %v and %v.\n",
+		formatBytes(uint64(f.maxSize)), f.rotatedPath, f.path)
+}
+
+func validateJSONFileMaxSize(opts *options) error {
+	if opts.jsonFileMaxSize > 0 && opts.jsonFile == "" {
+		return fmt.Errorf("--jsonfile-max-size requires --jsonfile")
+	}
+	return nil
+}
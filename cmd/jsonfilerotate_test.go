@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestRotatingJSONFile(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(), fs.WithFile("events.1.json", "stale\n"))
+	defer dir.Remove()
+
+	path := filepath.Join(dir.Path(), "events.json")
+	f, err := newRotatingJSONFile(path, 20)
+	assert.NilError(t, err)
+	for _, event := range []string{"event-1", "event-2", "event-3", "event-4", "event-5", "event-6", "event-7"} {
+		_, err := f.Write([]byte(event + "\n"))
+		assert.NilError(t, err)
+	}
+	assert.NilError(t, f.Close())
+
+	readLines := func(path string) string {
+		raw, err := ioutil.ReadFile(path)
+		assert.NilError(t, err)
+		return strings.Join(strings.Fields(string(raw)), " ")
+	}
+	assert.Equal(t, readLines(filepath.Join(dir.Path(), "events.1.json")), "event-4 event-5 event-6")
+	assert.Equal(t, readLines(path), "event-7")
+	assert.Equal(t, f.rotations, 2)
+	assert.Equal(t, f.removed, 3)
+
+	out := new(bytes.Buffer)
+	printJSONFileRotated(out, f)
+	expected := `
+=== JSON file truncated (3 events removed)
+The events reached --jsonfile-max-size 20B. The oldest events were removed, the most recent events are in ` +
+		filepath.Join(dir.Path(), "events.1.json") + " and " + path + ".\n"
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestRotatedPath(t *testing.T) {
+	assert.Equal(t, rotatedPath("out/events.json.gz"), "out/events.1.json.gz")
+	assert.Equal(t, rotatedPath("events.log"), "events.1.log")
+	assert.Equal(t, rotatedPath("out/events"), "out/events.1")
+	assert.Equal(t, rotatedPath(".events"), ".events.1")
+}
+
+func TestValidateJSONFileMaxSize(t *testing.T) {
+	opts := &options{jsonFileMaxSize: 1 << 20}
+	assert.ErrorContains(t, opts.Validate(), "--jsonfile-max-size requires --jsonfile")
+	opts.jsonFile = "events.json"
+	assert.NilError(t, opts.Validate())
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// readKnownIssuesFile reads the --known-issues file. Each line of the file is
+// the fingerprint of a failure followed by the URL of the issue that tracks
+// the failure. Blank lines and lines that start with # are ignored.
+func readKnownIssuesFile(filename string) (*testjson.KnownIssues, error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close() // nolint: errcheck
+
+	issues := make(map[string]string)
+	scanner := bufio.NewScanner(fh)
+	var lineNum int
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%v:%d: expected a fingerprint followed by an issue URL", filename, lineNum)
+		}
+		issues[fields[0]] = fields[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %v: %w", filename, err)
+	}
+	return testjson.NewKnownIssues(issues), nil
+}
+
+// setupKnownIssues sets the KnownIssues of the format options from the
+// --known-issues file.
+func setupKnownIssues(opts *options) error {
+	if opts.knownIssuesFile == "" {
+		return nil
+	}
+	issues, err := readKnownIssuesFile(opts.knownIssuesFile)
+	if err != nil {
+		return fmt.Errorf("failed to read --known-issues: %w", err)
+	}
+	opts.formatOptions.KnownIssues = issues
+	return nil
+}
+
+func validateKnownIssues(opts *options) error {
+	if opts.knownIssuesNonFatal && opts.knownIssuesFile == "" {
+		return fmt.Errorf("--known-issues-non-fatal requires --known-issues")
+	}
+	return nil
+}
+
+// knownIssuesExitErr returns nil when the run failed only because of tests
+// that failed with a known issue, and --known-issues-non-fatal is set.
+// Otherwise it returns exitErr.
+func knownIssuesExitErr(opts *options, exec *testjson.Execution, exitErr error) error {
+	if !opts.knownIssuesNonFatal || ExitCodeWithDefault(exitErr) != 1 {
+		return exitErr
+	}
+	if len(exec.Errors()) > 0 {
+		return exitErr
+	}
+	for _, name := range exec.Packages() {
+		if exec.Package(name).TestMainFailed() {
+			return exitErr
+		}
+	}
+	failed := exec.Failed()
+	if len(failed) == 0 || !opts.formatOptions.KnownIssues.IsKnown(exec, failed) {
+		return exitErr
+	}
+	log.Warnf("all %d %s matched a known issue, not failing the run because of --known-issues-non-fatal",
+		len(failed), pluralize(len(failed), "failure", "failures"))
+	return nil
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestReadKnownIssuesFile(t *testing.T) {
+	file := fs.NewFile(t, t.Name(), fs.WithContent(`
+# fingerprint  issue
+1a2b3c4d5e6f   https://issues.example.com/12
+`))
+	defer file.Remove()
+
+	issues, err := readKnownIssuesFile(file.Path())
+	assert.NilError(t, err)
+	url, ok := issues.Lookup("1a2b3c4d5e6f")
+	assert.Assert(t, ok)
+	assert.Equal(t, url, "https://issues.example.com/12")
+	_, ok = issues.Lookup("000000000000")
+	assert.Assert(t, !ok)
+}
+
+func TestReadKnownIssuesFile_Invalid(t *testing.T) {
+	file := fs.NewFile(t, t.Name(), fs.WithContent("1a2b3c4d5e6f\n"))
+	defer file.Remove()
+
+	_, err := readKnownIssuesFile(file.Path())
+	assert.ErrorContains(t, err, ":1: expected a fingerprint followed by an issue URL")
+}
+
+func TestKnownIssuesExitErr(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestKnown"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestKnown","Output":"    pkg_test.go:10: connection reset\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestKnown","Elapsed":0.1}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.1}
+`),
+	})
+	assert.NilError(t, err)
+	fingerprint := exec.Fingerprint(exec.Failed()[0])
+
+	var testCases = []struct {
+		name     string
+		nonFatal bool
+		issues   map[string]string
+		exitErr  error
+		expected int
+	}{
+		{
+			name:     "known issue, non-fatal",
+			nonFatal: true,
+			issues:   map[string]string{fingerprint: "https://issues.example.com/1"},
+			exitErr:  exitError{num: 1},
+		},
+		{
+			name:     "known issue, fatal",
+			issues:   map[string]string{fingerprint: "https://issues.example.com/1"},
+			exitErr:  exitError{num: 1},
+			expected: 1,
+		},
+		{
+			name:     "unknown failure",
+			nonFatal: true,
+			issues:   map[string]string{},
+			exitErr:  exitError{num: 1},
+			expected: 1,
+		},
+		{
+			name:     "unexpected exit code",
+			nonFatal: true,
+			issues:   map[string]string{fingerprint: "https://issues.example.com/1"},
+			exitErr:  exitError{num: 2},
+			expected: 2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := &options{knownIssuesNonFatal: tc.nonFatal}
+			opts.formatOptions.KnownIssues = testjson.NewKnownIssues(tc.issues)
+			err := knownIssuesExitErr(opts, exec, tc.exitErr)
+			assert.Equal(t, ExitCodeWithDefault(err), tc.expected)
+		})
+	}
+}
+
+func TestOptions_Validate_KnownIssues(t *testing.T) {
+	opts := &options{knownIssuesNonFatal: true}
+	assert.ErrorContains(t, opts.Validate(), "--known-issues-non-fatal requires --known-issues")
+}
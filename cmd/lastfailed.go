@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// lastFailedTests returns the tests that failed in the most recent run in the
+// archive in dir, grouped by package. Runs that did not write a summary, for
+// example because they were interrupted, are ignored.
+func lastFailedTests(dir string) (string, map[string][]testjson.TestName, error) {
+	store, err := openHistory(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	runs, err := archiveRuns(store)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	for _, run := range runs {
+		raw, err := store.ReadFile(context.Background(), run.name, archiveSummaryFile)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			continue
+		case err != nil:
+			return "", nil, err
+		}
+		var summary archiveSummary
+		if err := json.Unmarshal(raw, &summary); err != nil {
+			return "", nil, fmt.Errorf("failed to read %v of %v: %w", archiveSummaryFile, run.name, err)
+		}
+		return run.name, failedTestsByPackage(summary.Failed), nil
+	}
+	return "", nil, fmt.Errorf("no previous runs found in %v", dir)
+}
+
+// failedTestsByPackage returns the unique names of the failed tests in each
+// package. A root test is removed when one of its subtests failed, so that
+// only the failed subtests are run.
+func failedTestsByPackage(failed []archiveTestCase) map[string][]testjson.TestName {
+	seen := make(map[string]map[testjson.TestName]bool)
+	for _, tc := range failed {
+		name := testjson.TestName(tc.Test)
+		if tc.OriginalTest != "" {
+			name = testjson.TestName(tc.OriginalTest)
+		}
+		if seen[tc.Package] == nil {
+			seen[tc.Package] = make(map[testjson.TestName]bool)
+		}
+		seen[tc.Package][name] = true
+	}
+
+	result := make(map[string][]testjson.TestName, len(seen))
+	for pkg, names := range seen {
+		hasFailedSubTest := make(map[string]bool)
+		for name := range names {
+			if name.IsSubTest() {
+				root, _ := name.Split()
+				hasFailedSubTest[root] = true
+			}
+		}
+		for name := range names {
+			if !name.IsSubTest() && hasFailedSubTest[name.Name()] {
+				continue
+			}
+			result[pkg] = append(result[pkg], name)
+		}
+		sort.Slice(result[pkg], func(i, j int) bool {
+			return result[pkg][i] < result[pkg][j]
+		})
+	}
+	return result
+}
+
+// setupRerunLastFailed sets opts.packages and opts.selectRunFlag to run
+// only the tests that failed in the most recent run in the archive. Returns
+// false if there are no tests to run.
+func setupRerunLastFailed(opts *options) (bool, error) {
+	if !opts.rerunLastFailed {
+		return true, nil
+	}
+	run, failed, err := lastFailedTests(opts.archiveDir)
+	if err != nil {
+		return false, err
+	}
+	if len(failed) == 0 {
+		fmt.Fprintf(opts.stdout, "No failed tests in the last run (%v)\n", run)
+		return false, nil
+	}
+
+	var packages, patterns []string
+	seen := make(map[string]bool)
+	for pkg, names := range failed {
+		packages = append(packages, pkg)
+		for _, name := range names {
+			// The same test may have failed in more than one package.
+			if pattern := goTestRunPattern(name); !seen[pattern] {
+				seen[pattern] = true
+				patterns = append(patterns, pattern)
+			}
+		}
+	}
+	sort.Strings(packages)
+	sort.Strings(patterns)
+	log.Debugf("rerun last failed from %v: %d tests in %d packages",
+		run, len(patterns), len(packages))
+
+	opts.packages = packages
+	opts.selectRunFlag = "-test.run=" + strings.Join(patterns, "|")
+	return true, nil
+}
+
+func validateRerunLastFailed(opts *options) error {
+	switch {
+	case !opts.rerunLastFailed:
+		return nil
+	case opts.archiveDir == "":
+		return fmt.Errorf("--rerun-last-failed requires --archive-dir")
+	case opts.rawCommand:
+		return fmt.Errorf("--rerun-last-failed can not be used with --raw-command")
+	case opts.watch:
+		return fmt.Errorf("--rerun-last-failed can not be used with --watch")
+	case len(opts.pkgGroups) > 0:
+		return fmt.Errorf("--rerun-last-failed can not be used with --pkg-group")
+	case len(opts.args) > 0 && len(opts.packages) == 0:
+		return fmt.Errorf(
+			"when go test args are used with --rerun-last-failed " +
+				"the list of packages to test must be specified by the --packages flag")
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestSetupRerunLastFailed(t *testing.T) {
+	summary := `{
+  "Failed": [
+    {"Package": "example.com/one", "Test": "TestBroken"},
+    {"Package": "example.com/one", "Test": "TestBroken", "RunID": 1},
+    {"Package": "example.com/one", "Test": "TestNested/case_one"},
+    {"Package": "example.com/one", "Test": "TestNested"},
+    {"Package": "example.com/two", "Test": "pretty name", "OriginalTest": "TestRenamed"}
+  ]
+}`
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithDir("20210601T120000",
+			fs.WithFile(archiveSummaryFile, `{"Failed": [{"Package": "example.com/old", "Test": "TestOld"}]}`)),
+		fs.WithDir("20210608T120000",
+			fs.WithFile(archiveSummaryFile, summary)),
+		// a run that was interrupted before it wrote the summary
+		fs.WithDir("20210610T110000"))
+	defer dir.Remove()
+
+	opts := &options{rerunLastFailed: true, archiveDir: dir.Path()}
+	ok, err := setupRerunLastFailed(opts)
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, opts.packages, []string{"example.com/one", "example.com/two"})
+
+	expected := []string{
+		"go", "test", "-json",
+		"-test.run=^TestBroken$|^TestNested$/^case_one$|^TestRenamed$",
+		"example.com/one", "example.com/two",
+	}
+	assert.DeepEqual(t, goTestCmdArgs(opts, rerunOpts{}), expected)
+}
+
+func TestSetupRerunLastFailed_NoFailures(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithDir("20210608T120000", fs.WithFile(archiveSummaryFile, `{"Total": 3}`)))
+	defer dir.Remove()
+
+	out := new(bytes.Buffer)
+	opts := &options{rerunLastFailed: true, archiveDir: dir.Path(), stdout: out}
+	ok, err := setupRerunLastFailed(opts)
+	assert.NilError(t, err)
+	assert.Assert(t, !ok)
+	assert.Equal(t, out.String(), "No failed tests in the last run (20210608T120000)\n")
+}
+
+func TestSetupRerunLastFailed_NoRuns(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+
+	opts := &options{rerunLastFailed: true, archiveDir: dir.Path()}
+	_, err := setupRerunLastFailed(opts)
+	assert.ErrorContains(t, err, "no previous runs found in")
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// leakCheckEnv is the environment variable which enables the leakcheck
+// package in the tests. It must match leakcheck.EnvVar.
+const leakCheckEnv = "GOTESTSUM_LEAK_CHECK"
+
+// setupLeakCheck sets leakCheckEnv when --leak-check is enabled, so that it is
+// inherited by go test and the test binaries.
+func setupLeakCheck(opts *options) {
+	if !opts.leakCheck {
+		return
+	}
+	if err := os.Setenv(leakCheckEnv, "1"); err != nil {
+		log.Warnf("Failed to set %v: %v", leakCheckEnv, err)
+	}
+}
+
+// printLeaks lists the tests which reported a leak with testjson.LeakMarker.
+func printLeaks(out io.Writer, exec *testjson.Execution) {
+	leaked := exec.Leaked()
+	if len(leaked) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n=== Leaks (%d %s)\n", len(leaked), pluralize(len(leaked), "test", "tests"))
+	for _, tc := range leaked {
+		leaks := make([]string, 0, len(tc.Leaks))
+		for _, leak := range tc.Leaks {
+			leaks = append(leaks, fmt.Sprintf("%d %s", leak.Count, leak.Kind))
+		}
+		var rerun string
+		if tc.RunID > 0 {
+			rerun = fmt.Sprintf(" (re-run %d)", tc.RunID)
+		}
+		fmt.Fprintf(out, "%s %s%s leaked %s\n", testjson.RelativePackagePath(tc.Package),
+			tc.Test, rerun, strings.Join(leaks, ", "))
+	}
+}
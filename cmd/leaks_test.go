@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestPrintLeaks(t *testing.T) {
+	in := `{"Action":"run","Package":"example.com/pkg","Test":"TestServer"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestServer","Output":"    server_test.go:40: gotestsum-leak: goroutines=2,fds=1\n"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestServer","Elapsed":1}
+{"Action":"run","Package":"example.com/pkg","Test":"TestClient"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestClient","Elapsed":1}
+{"Action":"pass","Package":"example.com/pkg","Elapsed":2}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(in)})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	printLeaks(out, exec)
+	expected := `
+=== Leaks (1 test)
+example.com/pkg TestServer leaked 2 goroutines, 1 fds
+`
+	assert.Equal(t, out.String(), expected)
+
+	out.Reset()
+	printLeaks(out, nil)
+	assert.Equal(t, out.String(), "")
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"text/template"
+)
+
+// parseLinkTemplate parses the --link-template. The template is executed with
+// a testjson.TestCase, and may also use the runID function to get the ID of
+// the run.
+func parseLinkTemplate(opts *options) (*template.Template, error) {
+	tmpl, err := template.New("link").
+		Funcs(template.FuncMap{"runID": func() string { return opts.runID }}).
+		Parse(opts.linkTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse --link-template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// setupLinkTemplate sets the FailureLink of the format options from the
+// --link-template.
+func setupLinkTemplate(opts *options) error {
+	if opts.linkTemplate == "" {
+		return nil
+	}
+	tmpl, err := parseLinkTemplate(opts)
+	if err != nil {
+		return err
+	}
+	opts.formatOptions.FailureLink = tmpl
+	return nil
+}
+
+func validateLinkTemplate(opts *options) error {
+	if opts.linkTemplate == "" {
+		return nil
+	}
+	_, err := parseLinkTemplate(opts)
+	return err
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestSetupLinkTemplate(t *testing.T) {
+	opts := &options{
+		linkTemplate: "https://ci.example.com/{{runID}}/{{.Package}}/{{urlquery .Test.Name}}",
+		runID:        "ci-1234",
+	}
+	assert.NilError(t, setupLinkTemplate(opts))
+
+	tc := testjson.TestCase{Package: "example.com/pkg", Test: "TestOne/with space"}
+	assert.Equal(t, opts.formatOptions.Link(tc),
+		"https://ci.example.com/ci-1234/example.com/pkg/TestOne%2Fwith+space")
+}
+
+func TestOptions_Validate_LinkTemplate(t *testing.T) {
+	opts := &options{linkTemplate: "https://ci/{{.Package"}
+	assert.ErrorContains(t, opts.Validate(), "failed to parse --link-template")
+}
+
+func TestWriteTeamMarkdown_WithLinks(t *testing.T) {
+	opts := &options{linkTemplate: "https://ci/{{.Test}}"}
+	assert.NilError(t, setupLinkTemplate(opts))
+
+	tc := testjson.TestCase{Package: "example.com/db", Test: "TestBroken"}
+	report := teamReport{
+		team:     "storage",
+		packages: 1,
+		failed:   []testjson.TestCase{tc},
+		slowest:  []testjson.TestCase{tc},
+	}
+	out := new(bytes.Buffer)
+	writeTeamMarkdown(out, report, opts.formatOptions)
+	assert.Assert(t, strings.Contains(out.String(),
+		"| example.com/db | [TestBroken](https://ci/TestBroken) | 0.00s |\n\n## Slowest"), out.String())
+	assert.Assert(t, strings.HasSuffix(out.String(), "| example.com/db | TestBroken | 0.00s |\n"), out.String())
+}
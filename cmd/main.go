@@ -10,10 +10,13 @@ import (
 	"strings"
 	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/dnephin/pflag"
 	"github.com/fatih/color"
 	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/internal/dotwriter"
+	"gotest.tools/gotestsum/internal/otlp"
 	"gotest.tools/gotestsum/log"
 	"gotest.tools/gotestsum/testjson"
 )
@@ -30,7 +33,9 @@ func Run(name string, args []string) error {
 		return err
 	}
 	opts.args = flags.Args()
+	opts.gotestsumArgs = args
 	setupLogging(opts)
+	setupAutoParallel(opts)
 
 	switch {
 	case opts.version:
@@ -38,6 +43,8 @@ func Run(name string, args []string) error {
 		return nil
 	case opts.watch:
 		return runWatcher(opts)
+	case opts.untilFailure:
+		return runUntilFailure(opts)
 	}
 	return run(opts)
 }
@@ -47,7 +54,10 @@ func setupFlags(name string) (*pflag.FlagSet, *options) {
 		hideSummary:                  newHideSummaryValue(),
 		junitTestCaseClassnameFormat: &junitFieldFormatValue{},
 		junitTestSuiteNameFormat:     &junitFieldFormatValue{},
+		preRunHookCmd:                &commandValue{},
 		postRunHookCmd:               &commandValue{},
+		onFailCmd:                    &commandValue{},
+		failureSnapshotCmd:           &commandValue{},
 		stdout:                       color.Output,
 		stderr:                       color.Error,
 	}
@@ -59,14 +69,147 @@ func setupFlags(name string) (*pflag.FlagSet, *options) {
 	flags.StringVarP(&opts.format, "format", "f",
 		lookEnvWithDefault("GOTESTSUM_FORMAT", "short"),
 		"print format of test input")
+	flags.StringVar(&opts.formatTemplateFile, "format-template", "",
+		"file of a Go template executed for each event by --format template")
+	flags.Var(logFoldingValue{value: &opts.formatOptions.LogFolding}, "format-fold",
+		"print the output of each package in a collapsible group of a CI log viewer, one of: "+logFoldingValues)
+	flags.Lookup("format-fold").NoOptDefVal = "auto"
+	flags.BoolVar(&opts.formatOptions.ShowBuildTime, "format-show-build-time", false,
+		"show the time spent building each package in the pkgname formats")
+	flags.BoolVar(&opts.formatOptions.BufferTestOutput, "format-buffer-test-output", false,
+		"print the output of each test as one block when it ends in the standard-verbose and github-actions formats")
+	flags.BoolVar(&opts.formatOptions.ShowPackageStart, "format-package-start", false,
+		"print a line when each package starts in the testname and pkgname formats")
+	flags.BoolVar(&opts.packageStartTests, "format-package-start-tests", false,
+		"add the number of tests in each package to --format-package-start, using go test -list")
+	flags.DurationVar(&opts.formatOptions.SlowPackageThreshold, "format-slow-package", 0,
+		"mark packages that take longer than this duration as slow in the pkgname formats")
+	flags.IntVar(&opts.formatOptions.SlowPackagePercent, "format-slow-package-percent", 0,
+		"mark packages that take this percent longer than their average in --archive-dir as slow in the pkgname formats")
+	flags.Var(durationFormatValue{value: &opts.formatOptions.DurationFormat}, "format-duration",
+		"format of elapsed times in the output and summary, one of: "+durationFormatValues)
+	flags.StringVar(&opts.formatOptions.DecimalSeparator, "format-decimal-separator", "",
+		"separator to use in place of '.' in elapsed times")
+	flags.StringVar(&opts.formatOptions.ThousandsSeparator, "format-thousands-separator", "",
+		"separator to print between groups of three digits in counts of tests")
+	flags.BoolVar(&opts.formatOptions.AlignDurations, "format-align-durations", false,
+		"pad elapsed times to a fixed width so they line up")
+	flags.BoolVar(&opts.formatOptions.HighlightLogLevels, "format-highlight-log-levels", false,
+		"color lines in the summary that look like error or warning logs, panics, or data races")
+	flags.BoolVar(&opts.formatOptions.FirstErrorFirst, "format-first-error", false,
+		"print the first line that looks like an error at the top of the output of each test in the summary")
+	flags.BoolVar(&opts.formatOptions.Accessible, "accessible", false,
+		"print a different symbol or word for each result, so that no result is shown only by color")
+	flags.CountVarP(&opts.verbose, "verbose", "v",
+		"print more detail in the testname and pkgname formats, repeat for the output of passed tests")
+	flags.BoolVarP(&opts.quiet, "quiet", "q", false,
+		"print only failures in the testname and pkgname formats")
+	flags.Var(showOutputValue{value: &opts.formatOptions.ShowOutput}, "show-output",
+		"print the output of tests with these results, and include passed output in reports, one of: "+showOutputValues)
+	flags.BoolVar(&opts.formatOptions.Deterministic, "deterministic", false,
+		"print the same output for every run of the same tests, by sorting packages and tests, and hiding elapsed times")
+	flags.StringVar(&opts.knownIssuesFile, "known-issues", "",
+		"file of failure fingerprints and the URL of the known issue for each, used to annotate failures")
+	flags.BoolVar(&opts.knownIssuesNonFatal, "known-issues-non-fatal", false,
+		"do not fail the run when all the failures match a known issue")
+	flags.StringVar(&opts.quarantineFile, "quarantine-file", "",
+		"file of known-flaky tests, by package and test name, whose failures are reported but do not fail the run")
+	flags.StringVar(&opts.expectedFailuresFile, "expected-failures", "",
+		"file of tests, by package and test name, which are expected to fail and do not fail the run")
+	flags.StringVar(&opts.linkTemplate, "link-template", "",
+		"template of a link printed with each failed test in the summary, may use {{.Package}}, {{.Test}}, and {{runID}}")
+	flags.StringVar(&opts.formatOptions.Labels, "label", "",
+		"list only tests with one of these comma separated labels in the summary and JUnit XML")
+	flags.StringVar(&opts.ownersFile, "owners", "",
+		"print the failed, flaky, and slowest tests of each team, using this file of team names and package patterns")
+	flags.StringVar(&opts.ownersMarkdownDir, "owners-markdown-dir", "",
+		"write a Markdown file for each team in --owners to this directory")
+	flags.Var(&opts.rewriteTestName, "rewrite-test-name",
+		"rewrite test names in all output, format: REGEX=REPLACEMENT. May be repeated")
+	flags.Var(&opts.rewriteTestNameTemplate, "rewrite-test-name-template",
+		"rewrite test names in all output with a template, may use {{.Package}} and {{.Name}}")
 	flags.BoolVar(&opts.rawCommand, "raw-command", false,
 		"don't prepend 'go test -json' to the 'go test' command")
+	flags.StringVar(&opts.inputFormat, "input-format", "go",
+		"format of the output of a --raw-command, one of: "+strings.Join(testjson.InputFormats, ", "))
+	flags.Var(&opts.clock, "clock",
+		"measure the elapsed time of the run with the wall clock, or with the time of the events, one of: wall, event")
+	flags.Var(&opts.stderrMerge, "stderr-merge",
+		"when to print the stderr of go test, like build errors, one of: concurrent (as it is read), "+
+			"separate (after all the test output), package (next to the result of the package)")
+	flags.BoolVar(&opts.fullpath, "fullpath", false,
+		"run go test with -fullpath (go1.21+), and make the file paths in the output relative to --path-root")
+	flags.StringVar(&opts.pathRoot, "path-root", "",
+		"directory that --fullpath makes file paths relative to, defaults to the directory of go.mod")
+	flags.Var(&opts.pathMaps, "path-map",
+		"replace the directory FROM at the start of file paths in test output with TO, format: FROM=>TO")
 	flags.BoolVar(&opts.ignoreNonJSONOutputLines, "ignore-non-json-output-lines", false,
 		"write non-JSON 'go test' output lines to stderr instead of failing")
 	flags.Lookup("ignore-non-json-output-lines").Hidden = true
 	flags.StringVar(&opts.jsonFile, "jsonfile",
 		lookEnvWithDefault("GOTESTSUM_JSONFILE", ""),
-		"write all TestEvents to file")
+		"write all TestEvents to file, compressed with gzip when the file name ends with .gz")
+	flags.Var(&opts.jsonFileMaxSize, "jsonfile-max-size",
+		"rotate --jsonfile when it reaches this size, keeping only the most recent events, ex: 500MiB")
+	flags.StringVar(&opts.archiveDir, "archive-dir", "",
+		"write the events, summary, and JUnit XML of each run to a new directory in this directory, "+
+			"or to an s3:// or http(s):// history store")
+	flags.IntVar(&opts.archiveKeep, "archive-keep", 0,
+		"keep only this number of the most recent runs in --archive-dir")
+	flags.IntVar(&opts.archiveKeepDays, "archive-keep-days", 0,
+		"remove runs older than this number of days from --archive-dir")
+	flags.StringVar(&opts.historyDB, "history-db", "",
+		"add the result and elapsed time of each test to this history database file")
+	flags.BoolVar(&opts.impactRecord, "impact-record", false,
+		"run each test again on its own with coverage, and record the source files run by each test in --history-db")
+	flags.StringSliceVar(&opts.impactedBy, "impacted-by", nil,
+		"run only the tests that --history-db records as running code in these comma separated files, or that are declared in them")
+	flags.IntVar(&opts.durationRegression, "duration-regression", 0,
+		"warn in the summary when a test is this percent slower than its median elapsed time in --history-db")
+	flags.DurationVar(&opts.durationRegressionMin, "duration-regression-min", 100*time.Millisecond,
+		"ignore tests faster than this duration for --duration-regression")
+	flags.BoolVar(&opts.durationRegressionFail, "duration-regression-fail", false,
+		"fail the run when a test is slower than --duration-regression")
+	flags.Float64Var(&opts.coverageMin, "coverage-min", 0,
+		"fail the run when the coverage of a package is below this percent")
+	flags.StringVar(&opts.coverageMinFile, "coverage-min-file", "",
+		"file of package patterns and the minimum coverage percent of the packages which match them")
+	flags.IntVar(&opts.outputKeep, "output-keep", 0,
+		"keep only this number of the most recent files for file flags with a {{.Timestamp}}, {{.GitSHA}}, or {{.RunID}} template")
+	flags.StringVar(&opts.provenanceFile, "provenance", "",
+		"write a provenance record of the inputs, results, and output file hashes of the run")
+	flags.StringVar(&opts.provenanceKey, "provenance-key", "",
+		"sign the --provenance record with this PEM encoded ed25519, ECDSA, or RSA private key")
+	flags.StringVar(&opts.upload, "upload",
+		lookEnvWithDefault("GOTESTSUM_UPLOAD", ""),
+		"upload the output files to s3://BUCKET/PREFIX or gs://BUCKET/PREFIX at the end of the run")
+	flags.StringVar(&opts.analyticsUpload, "analytics-upload",
+		lookEnvWithDefault("GOTESTSUM_ANALYTICS_UPLOAD", ""),
+		"POST the results of the run as JSON, with git and CI metadata, to this URL at the end of the run")
+	flags.Var(&opts.analyticsUploadHeaders, "analytics-upload-header",
+		"send this header with the --analytics-upload request, format: NAME: VALUE, may use $ENV_VAR")
+	flags.IntVar(&opts.analyticsUploadRetries, "analytics-upload-retries", 3,
+		"number of times to retry a failed --analytics-upload")
+	flags.StringVar(&opts.notifyWebhook, "notify-webhook",
+		lookEnvWithDefault("GOTESTSUM_NOTIFY_WEBHOOK", ""),
+		"POST a notification with the result of the run to this URL, ex: a Slack incoming webhook")
+	flags.StringVar(&opts.notifyWebhookTemplate, "notify-webhook-template", "",
+		"file with a text/template used to create the body of the --notify-webhook request")
+	flags.BoolVar(&opts.notifyOnFailure, "notify-on-failure", false,
+		"only send the --notify-webhook notification when the run fails")
+	flags.StringVar(&opts.otlpLogsEndpoint, "otlp-logs-endpoint",
+		lookEnvWithDefault("GOTESTSUM_OTLP_LOGS_ENDPOINT", ""),
+		"send the output of each test as a log record to this OTLP/HTTP endpoint, ex: http://localhost:4318")
+	flags.StringVar(&opts.metricsPush, "metrics-push",
+		lookEnvWithDefault("GOTESTSUM_METRICS_PUSH", ""),
+		"push metrics of the run to this Prometheus Pushgateway URL, ex: http://localhost:9091/metrics/job/gotestsum")
+	flags.StringVar(&opts.metricsFile, "metrics-file", "",
+		"write metrics of the run to this file in the OpenMetrics text format")
+	flags.BoolVar(&opts.traceContext, "trace-context", false,
+		"start a W3C trace context for the run, and set TRACEPARENT in the environment of the tests")
+	flags.StringVar(&opts.runID, "run-id",
+		lookEnvWithDefault("GOTESTSUM_RUN_ID", ""),
+		"ID of the run added to the output files, defaults to a random ID")
 	flags.BoolVar(&opts.noColor, "no-color", color.NoColor, "disable color output")
 
 	flags.Var(opts.hideSummary, "no-summary",
@@ -74,12 +217,49 @@ func setupFlags(name string) (*pflag.FlagSet, *options) {
 	flags.Lookup("no-summary").Hidden = true
 	flags.Var(opts.hideSummary, "hide-summary",
 		"hide sections of the summary: "+testjson.SummarizeAll.String())
+	flags.BoolVar(&opts.formatOptions.ShowFirstFailure, "summary-first-failure", false,
+		"print the time to the first failure, and the time go test -failfast would have saved")
+	flags.BoolVar(&opts.formatOptions.GroupFailures, "summary-group-failures", false,
+		"group the failed tests in the summary which failed with the same panic or error message")
+	flags.BoolVar(&opts.scriptOutput, "script-output", false,
+		"print a stable, tab separated, line for each test and package result instead of --format and the summary")
+	flags.BoolVar(&opts.interactiveSummary, "interactive-summary", false,
+		"open a terminal UI after the run to browse the output of failed tests, and rerun them")
+	flags.Var(opts.preRunHookCmd, "pre-run-command",
+		"command to run before go test starts, the tests are not run when the command fails")
 	flags.Var(opts.postRunHookCmd, "post-run-command",
-		"command to run after the tests have completed")
+		"command to run after the tests have completed, with a JSON summary of the run on stdin")
+	flags.Var(opts.onFailCmd, "on-fail-command",
+		"command to run when a test fails, args may use {{.Package}} and {{.Test}}")
+	flags.DurationVar(&opts.onFailInterval, "on-fail-command-interval", time.Second,
+		"minimum time between runs of --on-fail-command")
+	flags.StringVar(&opts.failureSnapshotEnv, "failure-snapshot-env", "",
+		"comma separated list of environment variables to include with each failed test")
+	flags.Var(opts.failureSnapshotCmd, "failure-snapshot-command",
+		"command to run when a test fails, the output is included with the failed test")
+	flags.BoolVar(&opts.autoParallel, "auto-parallel", false,
+		"set go test -p and -parallel from the CPU count, and the cgroup CPU and memory limits")
+	flags.Var(&opts.maxMemory, "max-memory",
+		"store test output on disk, and then truncate it, when gotestsum uses more than this memory, ex: 2GiB")
 	flags.BoolVar(&opts.watch, "watch", false,
 		"watch go files, and run tests when a file is modified")
 	flags.IntVar(&opts.maxFails, "max-fails", 0,
 		"end the test run after this number of failures")
+	flags.DurationVar(&opts.warnTestDuration, "warn-test-duration", 0,
+		"warn while a top-level test has been running for longer than this duration, and list the tests in the summary")
+	flags.BoolVar(&opts.leakCheck, "leak-check", false,
+		"enable the leakcheck package in tests, to report tests which leak goroutines or file descriptors")
+	flags.Var(&opts.teardownFailures, "teardown-failures",
+		"how to report packages that fail after all tests passed, one of: "+teardownFailuresValues)
+	flags.Var(&opts.vetMode, "vet-mode",
+		"run go vet as part of go test, or as a separate stage before go test, one of: "+vetModeValues)
+	flags.Var(&opts.analysisCommands, "analysis-command",
+		"run a static analysis command before the tests, and report its problems as a NAME test in each package, format: NAME=COMMAND. May be repeated")
+	flags.BoolVar(&opts.vetContinue, "vet-continue", false,
+		"run the tests when go vet, or an --analysis-command, finds a problem")
+	flags.Var(&opts.strictStderr, "strict-stderr",
+		"fail the run when go test writes a line to stderr that does not match the allow pattern")
+	flags.Lookup("strict-stderr").NoOptDefVal = strictStderrDefault
 
 	flags.StringVar(&opts.junitFile, "junitfile",
 		lookEnvWithDefault("GOTESTSUM_JUNITFILE", ""),
@@ -88,20 +268,65 @@ func setupFlags(name string) (*pflag.FlagSet, *options) {
 		"format the testsuite name field as: "+junitFieldFormatValues)
 	flags.Var(opts.junitTestCaseClassnameFormat, "junitfile-testcase-classname",
 		"format the testcase classname field as: "+junitFieldFormatValues)
+	flags.BoolVar(&opts.junitFlakyFailures, "junitfile-flaky-failures", false,
+		"report the failures of a test that passed when it was rerun as flakyFailure elements of the passed testcase")
+	flags.BoolVar(&opts.junitTestSuitePerTest, "junitfile-testsuite-per-test", false,
+		"write a testsuite for each top-level test, with a testcase for the test and each of its subtests")
+	flags.StringVar(&opts.markdownSummaryFile, "markdown-summary",
+		lookEnvWithDefault("GOTESTSUM_MARKDOWN_SUMMARY", ""),
+		"append a Markdown summary of failed, skipped, and slow tests, and package totals, to this file")
+	flags.StringVar(&opts.htmlReportFile, "html-report",
+		lookEnvWithDefault("GOTESTSUM_HTML_REPORT", ""),
+		"write a self-contained HTML report of the run")
 
 	flags.IntVar(&opts.rerunFailsMaxAttempts, "rerun-fails", 0,
 		"rerun failed tests until they all pass, or attempts exceeds maximum. Defaults to max 2 reruns when enabled.")
 	flags.Lookup("rerun-fails").NoOptDefVal = "2"
 	flags.IntVar(&opts.rerunFailsMaxInitialFailures, "rerun-fails-max-failures", 10,
 		"do not rerun any tests if the initial run has more than this number of failures")
+	flags.Var(&opts.rerunFailsPackages, "rerun-fails-package",
+		"rerun failed tests in packages matching PATTERN up to ATTEMPTS times, format: PATTERN=ATTEMPTS")
+	flags.DurationVar(&opts.rerunFailsMaxTime, "rerun-fails-max-time", 0,
+		"stop rerunning failed tests when the reruns have taken longer than this duration")
+	flags.Var(&opts.rerunFailsScope, "rerun-fails-scope",
+		"rerun each failed test, or all the tests in a package with a failed test, one of: "+rerunFailsScopeValues)
+	flags.IntVar(&opts.rerunFailsMaxAttemptsPerTest, "rerun-fails-max-attempts-per-test", 0,
+		"rerun each failed test until it passes, or it has been rerun this many times, before rerunning the next failed test")
+	flags.DurationVar(&opts.rerunFailsDelay, "rerun-fails-delay", 0,
+		"wait this long before each rerun of a failed test")
+	flags.BoolVar(&opts.rerunFailsBackoff, "rerun-fails-backoff", false,
+		"double the --rerun-fails-delay after each attempt to rerun a test")
 	flags.Var((*stringSlice)(&opts.packages), "packages",
 		"space separated list of package to test")
+	flags.Var((*stringSlice)(&opts.noCacheTests), "no-cache-tests",
+		"run the packages that match these space separated package patterns with -count=1, so that the results are not cached")
+	flags.BoolVar(&opts.fastList, "fast-list", false,
+		"cache the packages matched by the package patterns, and only run go list again when the source tree changes")
+	flags.Var(&opts.pkgGroups, "pkg-group",
+		"run a group of packages with extra go test args, format: NAME=PACKAGES [: ARGS]")
+	flags.BoolVar(&opts.rerunLastFailed, "rerun-last-failed", false,
+		"run only the tests that failed in the most recent run in --archive-dir")
 	flags.StringVar(&opts.rerunFailsReportFile, "rerun-fails-report", "",
 		"write a report to the file, of the tests that were rerun")
+	flags.Var(&opts.rerunFailsReportFormat, "rerun-fails-report-format",
+		"format of the --rerun-fails-report file, one of: "+rerunFailsReportFormatValues)
 	flags.BoolVar(&opts.rerunFailsOnlyRootCases, "rerun-fails-only-root-testcases", false,
 		"rerun only root testcaes, instead of only subtests")
 	flags.Lookup("rerun-fails-only-root-testcases").Hidden = true
 
+	flags.BoolVar(&opts.untilFailure, "until-failure", false,
+		"run the tests repeatedly until a run fails")
+	flags.IntVar(&opts.untilFailureMaxRuns, "until-failure-max-runs", 0,
+		"stop --until-failure after this number of runs")
+	flags.DurationVar(&opts.untilFailureMaxTime, "until-failure-max-time", 0,
+		"stop --until-failure when the runs have taken longer than this duration")
+	flags.BoolVar(&opts.untilFailureShuffle, "until-failure-shuffle", false,
+		"run the tests in a different random order for each --until-failure run")
+	flags.IntVar(&opts.stressParallel, "stress-parallel", 1,
+		"run this number of go test commands at the same time for each --until-failure run")
+
+	flags.BoolVar(&opts.dryRun, "dry-run", false,
+		"print the commands that would be run, without running them")
 	flags.BoolVar(&opts.debug, "debug", false, "enabled debug logging")
 	flags.BoolVar(&opts.version, "version", false, "show version and exit")
 	return flags, opts
@@ -125,7 +350,18 @@ Formats:
     testname                print a line for each test and package
     standard-quiet          standard go test format
     standard-verbose        standard go test -v format
-
+    tap                     Test Anything Protocol version 13
+    github-actions          a group for each package, and an annotation for each failure
+    teamcity                TeamCity service messages to report each test as it runs
+    template                a line for each event from the --format-template
+    exec:COMMAND            send each event as JSON to the stdin of COMMAND, which prints the output
+    screen-reader           a sentence for each test and package, for screen readers
+    screen-reader-failures  a sentence for each failed test and package, for screen readers
+`)
+	for _, format := range testjson.RegisteredFormats() {
+		fmt.Fprintf(out, "    %-23s custom format\n", format)
+	}
+	fmt.Fprint(out, `
 Commands:
     tool                    tools for working with test2json output
     help                    print this help next
@@ -142,37 +378,257 @@ func lookEnvWithDefault(key, defValue string) string {
 type options struct {
 	args                         []string
 	format                       string
+	formatOptions                testjson.FormatOptions
 	debug                        bool
+	dryRun                       bool
 	rawCommand                   bool
+	inputFormat                  string
+	fullpath                     bool
+	fullpathSupported            bool
+	pathRoot                     string
+	pathMaps                     pathMapValue
 	ignoreNonJSONOutputLines     bool
 	jsonFile                     string
+	jsonFileMaxSize              memoryValue
 	junitFile                    string
+	htmlReportFile               string
+	markdownSummaryFile          string
+	outputKeep                   int
+	archiveDir                   string
+	archiveKeep                  int
+	archiveKeepDays              int
+	archivePath                  string
+	archiveRun                   string
+	historyDB                    string
+	impactRecord                 bool
+	impactedBy                   []string
+	durationRegression           int
+	durationRegressionMin        time.Duration
+	durationRegressionFail       bool
+	coverageMin                  float64
+	coverageMinFile              string
+	outputPathTemplates          []string
+	preRunHookCmd                *commandValue
 	postRunHookCmd               *commandValue
+	onFailCmd                    *commandValue
+	failureSnapshotEnv           string
+	failureSnapshotCmd           *commandValue
+	onFailInterval               time.Duration
+	warnTestDuration             time.Duration
+	leakCheck                    bool
 	noColor                      bool
 	hideSummary                  *hideSummaryValue
+	interactiveSummary           bool
+	scriptOutput                 bool
+	linkTemplate                 string
+	knownIssuesFile              string
+	knownIssuesNonFatal          bool
+	quarantineFile               string
+	expectedFailuresFile         string
+	ownersFile                   string
+	ownersMarkdownDir            string
 	junitTestSuiteNameFormat     *junitFieldFormatValue
 	junitTestCaseClassnameFormat *junitFieldFormatValue
+	junitFlakyFailures           bool
+	junitTestSuitePerTest        bool
 	rerunFailsMaxAttempts        int
 	rerunFailsMaxInitialFailures int
+	rerunFailsMaxTime            time.Duration
+	rerunFailsPackages           rerunPackageAttemptsValue
+	rerunFailsMaxAttemptsPerTest int
+	rerunFailsScope              rerunFailsScopeValue
+	rerunFailsDelay              time.Duration
+	rerunFailsBackoff            bool
+	untilFailure                 bool
+	untilFailureMaxRuns          int
+	untilFailureMaxTime          time.Duration
+	untilFailureShuffle          bool
+	stressParallel               int
+	shuffleSeed                  string
 	rerunFailsReportFile         string
+	rerunFailsReportFormat       rerunFailsReportFormatValue
 	rerunFailsOnlyRootCases      bool
+	rerunLastFailed              bool
+	selectRunFlag                string
 	packages                     []string
+	noCacheTests                 []string
+	noCachePolicy                *noCachePolicy
+	fastList                     bool
+	pkgGroups                    pkgGroupsValue
+	pkgGroupName                 string
+	pkgGroupResults              []pkgGroupResult
 	watch                        bool
 	maxFails                     int
+	teardownFailures             teardownFailuresValue
+	vetMode                      vetModeValue
+	vetContinue                  bool
+	analysisCommands             analysisCommandsValue
+	strictStderr                 strictStderrValue
+	rewriteTestName              rewriteRulesValue
+	rewriteTestNameTemplate      rewriteTemplateValue
+	autoParallel                 bool
+	autoParallelValues           *autoParallel
+	maxMemory                    memoryValue
+	upload                       string
+	analyticsUpload              string
+	analyticsUploadHeaders       headersValue
+	analyticsUploadRetries       int
+	notifyWebhook                string
+	notifyWebhookTemplate        string
+	notifyOnFailure              bool
+	runID                        string
+	provenanceFile               string
+	provenanceKey                string
+	otlpLogsEndpoint             string
+	metricsPush                  string
+	metricsFile                  string
+	traceContext                 bool
+	gotestsumArgs                []string
 	version                      bool
 
+	// rerunCommands are the commands run by --rerun-fails, for the
+	// --rerun-fails-report.
+	rerunCommands []rerunCommand
+
+	// traceParent is the span from the TRACEPARENT environment variable, and
+	// traceSpan is the span of the run, from --trace-context.
+	traceParent *otlp.SpanContext
+	traceSpan   otlp.SpanContext
+
+	// verbose is the number of times -v was used, and quiet is -q. They set
+	// the verbosity level of the format.
+	verbose int
+	quiet   bool
+
+	// packageStartTests is --format-package-start-tests.
+	packageStartTests bool
+	// formatTemplateFile is --format-template.
+	formatTemplateFile string
+	// formatPlugin is the command of a --format exec:, started by
+	// newEventHandler.
+	formatPlugin *formatPlugin
+	// clock is --clock.
+	clock clockValue
+	// stderrMerge is --stderr-merge.
+	stderrMerge stderrMergeValue
+	// testDurationWatch checks the running tests for --warn-test-duration,
+	// created by newEventHandler.
+	testDurationWatch *testDurationWatch
+	// jsonFileRotation is the --jsonfile when it is rotated because of
+	// --jsonfile-max-size, created by newEventHandler.
+	jsonFileRotation *rotatingJSONFile
+	// jsonFileWriter writes the --jsonfile, and the events file in the
+	// archive, created by newEventHandler. It is closed by finishRun, so that
+	// the files are complete before they are read.
+	jsonFileWriter *closeOnceWriter
+	// expectedFailures are the tests read from --expected-failures.
+	expectedFailures expectedFailures
+
 	// shims for testing
 	stdout io.Writer
 	stderr io.Writer
 }
 
 func (o options) Validate() error {
-	if o.rerunFailsMaxAttempts > 0 && len(o.args) > 0 && !o.rawCommand && len(o.packages) == 0 {
+	if maxRerunAttempts(&o) > 0 && len(o.args) > 0 && !o.rawCommand && len(o.packages) == 0 {
 		return fmt.Errorf(
 			"when go test args are used with --rerun-fails-max-attempts " +
 				"the list of packages to test must be specified by the --packages flag")
 	}
-	return nil
+	if o.interactiveSummary && o.watch {
+		return fmt.Errorf("--interactive-summary can not be used with --watch")
+	}
+	if o.dryRun && o.watch {
+		return fmt.Errorf("--dry-run can not be used with --watch")
+	}
+	if o.autoParallel && o.rawCommand {
+		return fmt.Errorf("--auto-parallel can not be used with --raw-command")
+	}
+	if err := validateInputFormat(&o); err != nil {
+		return err
+	}
+	if o.pathRoot != "" && !o.fullpath {
+		return fmt.Errorf("--path-root requires --fullpath")
+	}
+	if o.verbose > 0 && o.quiet {
+		return fmt.Errorf("--verbose can not be used with --quiet")
+	}
+	if o.formatOptions.SlowPackagePercent > 0 && o.archiveDir == "" {
+		return fmt.Errorf("--format-slow-package-percent requires --archive-dir")
+	}
+	if err := validateRerunFailsPerTest(&o); err != nil {
+		return err
+	}
+	if err := validateRerunFailsScope(&o); err != nil {
+		return err
+	}
+	if err := validateImpact(&o); err != nil {
+		return err
+	}
+	if err := validateRerunLastFailed(&o); err != nil {
+		return err
+	}
+	if err := validatePackageStart(&o); err != nil {
+		return err
+	}
+	if err := validateUpload(&o); err != nil {
+		return err
+	}
+	if err := validateAnalyticsUpload(&o); err != nil {
+		return err
+	}
+	if err := validateNotifyWebhook(&o); err != nil {
+		return err
+	}
+	if err := validateRunID(&o); err != nil {
+		return err
+	}
+	if err := validateUntilFailure(&o); err != nil {
+		return err
+	}
+	if err := validateOwners(&o); err != nil {
+		return err
+	}
+	if err := validateLinkTemplate(&o); err != nil {
+		return err
+	}
+	if err := validateFormatTemplate(&o); err != nil {
+		return err
+	}
+	if err := validateFormatPlugin(&o); err != nil {
+		return err
+	}
+	if err := validateKnownIssues(&o); err != nil {
+		return err
+	}
+	if err := validateProvenance(&o); err != nil {
+		return err
+	}
+	if err := validateOTLPLogs(&o); err != nil {
+		return err
+	}
+	if err := validateMetrics(&o); err != nil {
+		return err
+	}
+	if err := validateDurationRegression(&o); err != nil {
+		return err
+	}
+	if err := validateCoverageMin(&o); err != nil {
+		return err
+	}
+	if err := validateJSONFileMaxSize(&o); err != nil {
+		return err
+	}
+	if err := validateAnalysisStage(&o); err != nil {
+		return err
+	}
+	if err := validateNoCacheTests(&o); err != nil {
+		return err
+	}
+	if o.warnTestDuration < 0 {
+		return fmt.Errorf("--warn-test-duration must be a positive duration")
+	}
+	return validatePkgGroups(&o)
 }
 
 func setupLogging(opts *options) {
@@ -180,19 +636,26 @@ func setupLogging(opts *options) {
 		log.SetLevel(log.DebugLevel)
 	}
 	color.NoColor = opts.noColor
+	// Windows consoles require virtual terminal processing to be enabled for
+	// color, and for the dots format to update lines.
+	dotwriter.EnableVirtualTerminal(os.Stdout.Fd())
 }
 
 func run(opts *options) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	if err := opts.Validate(); err != nil {
+	if ok, err := setupRun(ctx, opts); err != nil || !ok {
 		return err
 	}
 
-	goTestProc, err := startGoTestFn(ctx, goTestCmdArgs(opts, rerunOpts{}))
-	if err != nil {
-		return err
+	if len(opts.pkgGroups) > 0 {
+		handler, err := newEventHandler(opts)
+		if err != nil {
+			return err
+		}
+		defer handler.Close() // nolint: errcheck
+		return runPkgGroups(ctx, opts, handler)
 	}
 
 	handler, err := newEventHandler(opts)
@@ -200,14 +663,39 @@ func run(opts *options) error {
 		return err
 	}
 	defer handler.Close() // nolint: errcheck
+	analysisExec, err := runAnalysisStage(ctx, opts, handler)
+	if err != nil {
+		return err
+	}
+	if analysisExec != nil && analysisFailed(opts, analysisExec) > 0 && !opts.vetContinue {
+		log.Errorf("static analysis failed, not running tests. Use --vet-continue to run the tests when static analysis fails.")
+		return finishRun(opts, analysisExec, exitError{num: 1})
+	}
+
+	goTestProc, err := startGoTestFn(ctx, goTestCmdArgs(opts, rerunOpts{}))
+	if err != nil {
+		return err
+	}
+
 	cfg := testjson.ScanConfig{
+		Execution:                analysisExec,
 		Stdout:                   goTestProc.stdout,
 		Stderr:                   goTestProc.stderr,
 		Handler:                  handler,
 		Stop:                     cancel,
 		IgnoreNonJSONOutputLines: opts.ignoreNonJSONOutputLines,
+		DemoteTeardownFailures:   opts.teardownFailures.demote(),
+		KeepPassedOutput:         opts.formatOptions.ShowOutput.Passed(),
+		RewriteTestName:          newTestNameRewriter(opts),
+		RewriteOutput:            newOutputRewriter(opts),
+		Clock:                    opts.clock.newClock(),
+		InputAdapter:             newInputAdapter(opts),
+		StderrMerge:              opts.stderrMerge.merge(),
+		// A --raw-command may print a --jsonfile from earlier runs, which can
+		// include the reruns from --rerun-fails.
+		SplitRuns: opts.rawCommand && maxRerunAttempts(opts) == 0,
 	}
-	exec, err := testjson.ScanTestOutput(cfg)
+	exec, err := testjson.ScanTestOutputContext(ctx, cfg)
 	if err != nil {
 		return finishRun(opts, exec, err)
 	}
@@ -215,7 +703,7 @@ func run(opts *options) error {
 	if signum := atomic.LoadInt32(&goTestProc.signal); signum != 0 {
 		return finishRun(opts, exec, exitError{num: signalExitCode + int(signum)})
 	}
-	if exitErr == nil || opts.rerunFailsMaxAttempts == 0 {
+	if exitErr == nil || maxRerunAttempts(opts) == 0 {
 		return finishRun(opts, exec, exitErr)
 	}
 	if err := hasErrors(exitErr, exec); err != nil {
@@ -230,7 +718,12 @@ func run(opts *options) error {
 		return finishRun(opts, exec, err)
 	}
 
-	cfg = testjson.ScanConfig{Execution: exec, Handler: handler}
+	cfg = testjson.ScanConfig{
+		Execution:       exec,
+		Handler:         handler,
+		RewriteTestName: newTestNameRewriter(opts),
+		RewriteOutput:   newOutputRewriter(opts),
+	}
 	exitErr = rerunFailed(ctx, opts, cfg)
 	if err := writeRerunFailsReport(opts, exec); err != nil {
 		return err
@@ -238,15 +731,149 @@ func run(opts *options) error {
 	return finishRun(opts, exec, exitErr)
 }
 
+// setupRun validates the options, and sets up the state shared by run,
+// runUntilFailure, and runSingle. Returns false if there are no tests to run.
+func setupRun(ctx context.Context, opts *options) (bool, error) {
+	if err := opts.Validate(); err != nil {
+		return false, err
+	}
+	warnVerbosityConflicts(opts)
+	setupFormatVerbosity(opts)
+	setupCoverProfile(opts)
+	setupRunID(opts)
+	setupTraceContext(opts)
+	setupLeakCheck(opts)
+	if err := setupLinkTemplate(opts); err != nil {
+		return false, err
+	}
+	if err := setupFormatTemplate(opts); err != nil {
+		return false, err
+	}
+	if err := setupKnownIssues(opts); err != nil {
+		return false, err
+	}
+	if err := setupQuarantine(opts); err != nil {
+		return false, err
+	}
+	if err := setupExpectedFailures(opts); err != nil {
+		return false, err
+	}
+	setupFullpath(opts)
+	now := time.Now()
+	if err := resolveOutputPaths(opts, now); err != nil {
+		return false, err
+	}
+	if err := preflight(opts); err != nil {
+		return false, err
+	}
+	if ok, err := setupRerunLastFailed(opts); err != nil || !ok {
+		return false, err
+	}
+	if ok, err := setupImpactedBy(opts); err != nil || !ok {
+		return false, err
+	}
+	if opts.dryRun {
+		printDryRun(opts.stdout, opts)
+		return false, nil
+	}
+	setupFastList(opts)
+	if err := setupNoCacheTests(opts); err != nil {
+		return false, err
+	}
+	setupSlowPackageBaseline(opts)
+	setupPackageTestCounts(ctx, opts)
+	if err := setupArchive(opts, now); err != nil {
+		return false, err
+	}
+	if err := preRunHook(ctx, opts); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func finishRun(opts *options, exec *testjson.Execution, exitErr error) error {
-	testjson.PrintSummary(opts.stdout, exec, opts.hideSummary.value)
+	exitErr = analysisExitErr(opts, exec, exitErr)
+	exitErr = teardownFailuresExitErr(opts, exec, exitErr)
+	exitErr = knownIssuesExitErr(opts, exec, exitErr)
+	exitErr = allowedFailuresExitErr(opts, exec, exitErr)
+	exitErr = strictStderrExitErr(opts, exec, exitErr)
+	regressions := findDurationRegressions(opts, exec)
+	exitErr = durationRegressionExitErr(opts, regressions, exitErr)
+	belowMin, err := findLowCoverage(opts, exec)
+	if err != nil {
+		return err
+	}
+	exitErr = coverageMinExitErr(belowMin, exitErr)
+	// The format plugin must print all of its output before the summary.
+	if err := opts.formatPlugin.Close(); err != nil {
+		log.Errorf("%v", err)
+	}
+	// A compressed --jsonfile is only complete once it is closed, and it is
+	// read by the provenance, the uploads, and the archive.
+	if err := opts.jsonFileWriter.Close(); err != nil {
+		log.Errorf("Failed to close JSON file: %v", err)
+	}
+	execs := pkgGroupExecs(opts, exec)
+	if opts.scriptOutput {
+		if err := printScriptRunSummary(opts, exec); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	} else {
+		printRunSummary(opts, exec)
+		printDurationRegressions(opts.stdout, regressions)
+		printSlowTests(opts.stdout, opts.testDurationWatch)
+		printNoCachePolicy(opts.stdout, opts.noCachePolicy)
+		printLeaks(opts.stdout, exec)
+		printUnexpectedPasses(opts.stdout, unexpectedPasses(opts, exec))
+		printLowCoverage(opts.stdout, belowMin)
+		printJSONFileRotated(opts.stdout, opts.jsonFileRotation)
+	}
+	if err := finishOwners(opts, execs...); err != nil {
+		return err
+	}
 
 	if err := writeJUnitFile(opts, exec); err != nil {
 		return fmt.Errorf("failed to write junit file: %w", err)
 	}
-	if err := postRunHook(opts, exec); err != nil {
+	if err := writeHTMLReport(opts, exec); err != nil {
+		return fmt.Errorf("failed to write HTML report: %w", err)
+	}
+	if err := writeMarkdownSummary(opts, exec); err != nil {
+		return fmt.Errorf("failed to write Markdown summary: %w", err)
+	}
+	if err := writeMetrics(opts, exec); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if err := writeProvenance(opts, execs...); err != nil {
+		return fmt.Errorf("failed to write provenance: %w", err)
+	}
+	if err := removeOldOutputFiles(opts); err != nil {
+		return fmt.Errorf("failed to remove old output files: %w", err)
+	}
+	if err := writeArchive(opts, exec); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := recordImpact(context.Background(), opts, exec); err != nil {
+		return fmt.Errorf("failed to record test impact: %w", err)
+	}
+	if err := writeHistoryDB(opts, exec); err != nil {
+		return fmt.Errorf("failed to write history database: %w", err)
+	}
+	if err := uploadFiles(opts); err != nil {
+		return fmt.Errorf("failed to upload test results: %w", err)
+	}
+	if err := uploadAnalytics(opts, execs...); err != nil {
+		return fmt.Errorf("failed to upload analytics: %w", err)
+	}
+	if err := notifyWebhook(opts, exitErr, execs...); err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	if err := postRunHooks(opts, exec, exitErr); err != nil {
 		return fmt.Errorf("post run command failed: %w", err)
 	}
+	if err := runInteractiveSummary(opts, exec); err != nil {
+		return fmt.Errorf("interactive summary failed: %w", err)
+	}
 	return exitErr
 }
 
@@ -254,15 +881,23 @@ func goTestCmdArgs(opts *options, rerunOpts rerunOpts) []string {
 	if opts.rawCommand {
 		var result []string
 		result = append(result, opts.args...)
+		result = append(result, rawCommandVerbosityArgs(opts)...)
 		result = append(result, rerunOpts.Args()...)
 		return result
 	}
 
+	if rerunOpts.runFlag == "" {
+		rerunOpts.runFlag = opts.selectRunFlag
+	}
 	args := opts.args
 	result := []string{"go", "test"}
 
 	if len(args) == 0 {
 		result = append(result, "-json")
+		result = append(result, autoParallelArgs(opts, args)...)
+		result = append(result, shuffleArgs(opts, args)...)
+		result = append(result, fullpathArgs(opts, args)...)
+		result = append(result, vetArgs(opts, args)...)
 		if rerunOpts.runFlag != "" {
 			result = append(result, rerunOpts.runFlag)
 		}
@@ -272,6 +907,10 @@ func goTestCmdArgs(opts *options, rerunOpts rerunOpts) []string {
 	if boolArgIndex("json", args) < 0 {
 		result = append(result, "-json")
 	}
+	result = append(result, autoParallelArgs(opts, args)...)
+	result = append(result, shuffleArgs(opts, args)...)
+	result = append(result, fullpathArgs(opts, args)...)
+	result = append(result, vetArgs(opts, args)...)
 
 	if rerunOpts.runFlag != "" {
 		// Remove any existing run arg, it needs to be replaced with our new one
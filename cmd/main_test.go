@@ -67,6 +67,179 @@ func TestOptions_Validate_FromFlags(t *testing.T) {
 			name: "rerun flag, no go-test args, with packages flag",
 			args: []string{"--rerun-fails", "--packages", "./..."},
 		},
+		{
+			name:     "rerun max attempts per test, go-test args, no packages flag",
+			args:     []string{"--rerun-fails-max-attempts-per-test", "3", "--", "./..."},
+			expected: "the list of packages to test must be specified by the --packages flag",
+		},
+		{
+			name: "rerun max attempts per test, with delay and backoff",
+			args: []string{"--rerun-fails-max-attempts-per-test", "3", "--rerun-fails-delay", "5s", "--rerun-fails-backoff"},
+		},
+		{
+			name:     "rerun max attempts per test, negative",
+			args:     []string{"--rerun-fails-max-attempts-per-test", "-1"},
+			expected: "--rerun-fails-max-attempts-per-test must not be negative",
+		},
+		{
+			name:     "rerun delay, without rerun fails",
+			args:     []string{"--rerun-fails-delay", "5s"},
+			expected: "--rerun-fails-delay requires --rerun-fails or --rerun-fails-max-attempts-per-test",
+		},
+		{
+			name: "impacted by, with history db",
+			args: []string{"--impacted-by", "store/db.go,api/api.go", "--history-db", "history.json"},
+		},
+		{
+			name:     "impacted by, without history db",
+			args:     []string{"--impacted-by", "store/db.go"},
+			expected: "--impacted-by requires --history-db",
+		},
+		{
+			name:     "impacted by, with run flag",
+			args:     []string{"--impacted-by", "store/db.go", "--history-db", "h.json", "--packages", "./...", "--", "-run", "TestOne"},
+			expected: "--impacted-by can not be used with the go test -run flag",
+		},
+		{
+			name:     "impact record, with impacted by",
+			args:     []string{"--impact-record", "--impacted-by", "store/db.go", "--history-db", "h.json"},
+			expected: "--impact-record can not be used with --impacted-by",
+		},
+		{
+			name:     "impact record, with coverprofile",
+			args:     []string{"--impact-record", "--history-db", "h.json", "--packages", "./...", "--", "-coverprofile=c.out"},
+			expected: "--impact-record can not be used with the go test -coverprofile flag",
+		},
+		{
+			name: "rerun scope package",
+			args: []string{"--rerun-fails", "--rerun-fails-scope", "package"},
+		},
+		{
+			name:     "rerun scope package, without rerun fails",
+			args:     []string{"--rerun-fails-scope", "package"},
+			expected: "--rerun-fails-scope=package requires --rerun-fails or --rerun-fails-max-attempts-per-test",
+		},
+		{
+			name:     "rerun backoff, without delay",
+			args:     []string{"--rerun-fails", "--rerun-fails-backoff"},
+			expected: "--rerun-fails-backoff requires --rerun-fails-delay",
+		},
+		{
+			name:     "verbose and quiet",
+			args:     []string{"-v", "-q"},
+			expected: "--verbose can not be used with --quiet",
+		},
+		{
+			name:     "slow package percent without archive dir",
+			args:     []string{"--format-slow-package-percent", "20"},
+			expected: "--format-slow-package-percent requires --archive-dir",
+		},
+		{
+			name:     "package start tests without package start",
+			args:     []string{"--format-package-start-tests"},
+			expected: "--format-package-start-tests requires --format-package-start",
+		},
+		{
+			name: "input format with raw command",
+			args: []string{"--input-format", "pytest-reportlog", "--raw-command", "--", "pytest"},
+		},
+		{
+			name:     "input format without raw command",
+			args:     []string{"--input-format", "pytest-reportlog"},
+			expected: "--input-format=pytest-reportlog requires --raw-command",
+		},
+		{
+			name:     "input format with rerun fails",
+			args:     []string{"--input-format", "pytest-reportlog", "--raw-command", "--rerun-fails", "--", "pytest"},
+			expected: "--input-format=pytest-reportlog can not be used with --rerun-fails",
+		},
+		{
+			name:     "unknown input format",
+			args:     []string{"--input-format", "ctest"},
+			expected: `invalid --input-format "ctest", must be one of: go, pytest-reportlog`,
+		},
+		{
+			name: "metrics push",
+			args: []string{"--metrics-push", "http://localhost:9091"},
+		},
+		{
+			name:     "metrics push without scheme",
+			args:     []string{"--metrics-push", "localhost:9091"},
+			expected: "invalid --metrics-push: invalid Pushgateway URL localhost:9091, must start with http:// or https://",
+		},
+		{
+			name:     "analytics upload with invalid scheme",
+			args:     []string{"--analytics-upload", "s3://bucket/results"},
+			expected: "invalid --analytics-upload: invalid upload URL s3://bucket/results, must start with http:// or https://",
+		},
+		{
+			name:     "analytics upload with negative retries",
+			args:     []string{"--analytics-upload", "https://example.com", "--analytics-upload-retries=-1"},
+			expected: "--analytics-upload-retries must not be negative",
+		},
+		{
+			name:     "notify on failure without webhook",
+			args:     []string{"--notify-on-failure"},
+			expected: "--notify-on-failure requires --notify-webhook",
+		},
+		{
+			name:     "notify webhook without scheme",
+			args:     []string{"--notify-webhook", "hooks.slack.com/services/T0/B0/X"},
+			expected: "invalid --notify-webhook hooks.slack.com/services/T0/B0/X, must start with http:// or https://",
+		},
+		{
+			name:     "notify webhook with missing template",
+			args:     []string{"--notify-webhook", "https://example.com", "--notify-webhook-template", "missing.tmpl"},
+			expected: "failed to read --notify-webhook-template: open missing.tmpl",
+		},
+		{
+			name:     "vet continue without vet mode separate",
+			args:     []string{"--vet-continue"},
+			expected: "--vet-continue requires --vet-mode=separate",
+		},
+		{
+			name:     "vet mode separate with raw command",
+			args:     []string{"--vet-mode=separate", "--raw-command", "--", "./test-all"},
+			expected: "--vet-mode=separate can not be used with --raw-command",
+		},
+		{
+			name:     "vet mode separate, go-test args, no packages flag",
+			args:     []string{"--vet-mode=separate", "--", "-race"},
+			expected: "the list of packages to test must be specified by the --packages flag",
+		},
+		{
+			name: "vet mode separate, go-test args, with packages flag",
+			args: []string{"--vet-mode=separate", "--vet-continue", "--packages", "./...", "--", "-race"},
+		},
+		{
+			name:     "analysis command with watch",
+			args:     []string{"--analysis-command", "staticcheck=staticcheck", "--watch"},
+			expected: "--analysis-command can not be used with --watch",
+		},
+		{
+			name:     "dry run with watch",
+			args:     []string{"--dry-run", "--watch"},
+			expected: "--dry-run can not be used with --watch",
+		},
+		{
+			name:     "no cache tests with rerun fails",
+			args:     []string{"--no-cache-tests", "./integration/...", "--rerun-fails"},
+			expected: "--no-cache-tests can not be used with --rerun-fails",
+		},
+		{
+			name:     "no cache tests with count flag",
+			args:     []string{"--no-cache-tests", "*/integration/...", "--packages", "./...", "--", "-count=2"},
+			expected: "--no-cache-tests can not be used with the go test -count flag",
+		},
+		{
+			name:     "no cache tests, go-test args, no packages flag",
+			args:     []string{"--no-cache-tests", "*/integration/...", "--", "-race"},
+			expected: "the list of packages to test must be specified by the --packages flag",
+		},
+		{
+			name: "analysis command with vet continue",
+			args: []string{"--analysis-command", "staticcheck=staticcheck -checks all", "--vet-continue"},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -99,6 +272,30 @@ func TestGoTestCmdArgs(t *testing.T) {
 		},
 		expected: []string{"./script", "-test.timeout=20m"},
 	})
+	run(t, "raw command with test2json", testCase{
+		opts: &options{
+			rawCommand: true,
+			format:     "testname",
+			args:       []string{"go", "tool", "test2json", "-t", "./pkg.test"},
+		},
+		expected: []string{"go", "tool", "test2json", "-t", "./pkg.test", "-test.v=test2json"},
+	})
+	run(t, "raw command with test2json, with -test.v", testCase{
+		opts: &options{
+			rawCommand: true,
+			format:     "testname",
+			args:       []string{"go", "tool", "test2json", "./pkg.test", "-test.v"},
+		},
+		expected: []string{"go", "tool", "test2json", "./pkg.test", "-test.v"},
+	})
+	run(t, "raw command with test2json, standard-quiet format", testCase{
+		opts: &options{
+			rawCommand: true,
+			format:     "standard-quiet",
+			args:       []string{"go", "tool", "test2json", "./pkg.test"},
+		},
+		expected: []string{"go", "tool", "test2json", "./pkg.test"},
+	})
 	run(t, "no args", testCase{
 		opts:     &options{},
 		expected: []string{"go", "test", "-json", "./..."},
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// markdownSlowestCount is the number of the slowest tests listed in the
+// --markdown-summary.
+const markdownSlowestCount = 10
+
+// writeMarkdownSummary appends the Markdown summary of the run to the
+// --markdown-summary file. The file is appended to, instead of replaced, so
+// that it can be a file shared by many commands, like $GITHUB_STEP_SUMMARY.
+func writeMarkdownSummary(opts *options, exec *testjson.Execution) error {
+	if opts.markdownSummaryFile == "" {
+		return nil
+	}
+	buf := new(bytes.Buffer)
+	writeMarkdownSummaryTo(buf, exec, opts.formatOptions)
+
+	if dir := filepath.Dir(opts.markdownSummaryFile); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory %v: %w", dir, err)
+		}
+	}
+	fh, err := os.OpenFile(opts.markdownSummaryFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := fh.Close(); err != nil {
+			log.Errorf("Failed to close Markdown summary: %v", err)
+		}
+	}()
+	_, err = fh.Write(buf.Bytes())
+	return err
+}
+
+func writeMarkdownSummaryTo(out io.Writer, exec *testjson.Execution, formatOpts testjson.FormatOptions) {
+	failed := testjson.FilterFailedUnique(exec.Failed())
+	result := "PASS"
+	if len(failed) > 0 || len(exec.Errors()) > 0 {
+		result = "FAIL"
+	}
+	fmt.Fprintf(out, "## Test results\n\n")
+	fmt.Fprintf(out, "**%s** %d %s, %d failed, %d skipped, in %d %s\n", result,
+		exec.Total(), pluralize(exec.Total(), "test", "tests"), len(failed), len(exec.Skipped()),
+		len(exec.Packages()), pluralize(len(exec.Packages()), "package", "packages"))
+
+	if len(failed) > 0 {
+		fmt.Fprintf(out, "\n### Failed\n\n")
+		fmt.Fprintln(out, "| Package | Test | Elapsed |")
+		fmt.Fprintln(out, "|---------|------|---------|")
+		for _, tc := range failed {
+			name := markdownCell(string(tc.Test))
+			if link := formatOpts.Link(tc); link != "" {
+				name = "[" + name + "](" + link + ")"
+			}
+			if url, ok := formatOpts.KnownIssues.Lookup(exec.Fingerprint(tc)); ok {
+				name += " ([known issue](" + url + "))"
+			}
+			fmt.Fprintf(out, "| %s | %s | %s |\n", markdownCell(tc.Package), name,
+				testjson.FormatDurationAsSeconds(tc.Elapsed, 2))
+		}
+	}
+
+	if skipped := exec.Skipped(); len(skipped) > 0 {
+		fmt.Fprintf(out, "\n### Skipped\n\n")
+		fmt.Fprintln(out, "| Package | Test | Reason |")
+		fmt.Fprintln(out, "|---------|------|--------|")
+		for _, tc := range skipped {
+			fmt.Fprintf(out, "| %s | %s | %s |\n", markdownCell(tc.Package),
+				markdownCell(string(tc.Test)), markdownCell(testjson.SkipReason(exec.OutputLines(tc))))
+		}
+	}
+
+	if slowest := slowestTests(exec, markdownSlowestCount); len(slowest) > 0 {
+		fmt.Fprintf(out, "\n### Slowest\n\n")
+		fmt.Fprintln(out, "| Package | Test | Elapsed |")
+		fmt.Fprintln(out, "|---------|------|---------|")
+		for _, tc := range slowest {
+			fmt.Fprintf(out, "| %s | %s | %s |\n", markdownCell(tc.Package),
+				markdownCell(string(tc.Test)), testjson.FormatDurationAsSeconds(tc.Elapsed, 2))
+		}
+	}
+
+	fmt.Fprintf(out, "\n### Packages\n\n")
+	fmt.Fprintln(out, "| Package | Result | Passed | Failed | Skipped | Elapsed |")
+	fmt.Fprintln(out, "|---------|--------|--------|--------|---------|---------|")
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		fmt.Fprintf(out, "| %s | %s | %d | %d | %d | %s |\n", markdownCell(name),
+			markdownPackageResult(pkg), len(pkg.Passed), len(pkg.Failed), len(pkg.Skipped),
+			testjson.FormatDurationAsSeconds(pkg.Elapsed(), 2))
+	}
+	fmt.Fprintln(out)
+}
+
+func markdownPackageResult(pkg *testjson.Package) string {
+	switch {
+	case pkg.Result() == testjson.ActionFail:
+		return "FAIL"
+	case pkg.Result() == testjson.ActionSkip, pkg.Total == 0:
+		return "EMPTY"
+	}
+	return "PASS"
+}
+
+// slowestTests returns the n slowest root tests that passed or failed, sorted
+// by elapsed time.
+func slowestTests(exec *testjson.Execution, n int) []testjson.TestCase {
+	var tcs []testjson.TestCase
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		for _, tc := range append(append([]testjson.TestCase{}, pkg.Passed...), pkg.Failed...) {
+			if !tc.Test.IsSubTest() && tc.Elapsed > 0 {
+				tcs = append(tcs, tc)
+			}
+		}
+	}
+	sort.SliceStable(tcs, func(i, j int) bool {
+		return tcs[i].Elapsed > tcs[j].Elapsed
+	})
+	if len(tcs) > n {
+		tcs = tcs[:n]
+	}
+	return tcs
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+var markdownSummaryInput = `{"Action":"run","Package":"example.com/db","Test":"TestBroken"}
+{"Action":"output","Package":"example.com/db","Test":"TestBroken","Output":"    db_test.go:9: broken | pipe\n"}
+{"Action":"fail","Package":"example.com/db","Test":"TestBroken","Elapsed":0.5}
+{"Action":"run","Package":"example.com/db","Test":"TestSkipped"}
+{"Action":"output","Package":"example.com/db","Test":"TestSkipped","Output":"    db_test.go:20: needs a database\n"}
+{"Action":"skip","Package":"example.com/db","Test":"TestSkipped","Elapsed":0}
+{"Action":"run","Package":"example.com/db","Test":"TestSlow"}
+{"Action":"pass","Package":"example.com/db","Test":"TestSlow","Elapsed":3}
+{"Action":"fail","Package":"example.com/db","Elapsed":3.6}
+{"Action":"run","Package":"example.com/tools","Test":"TestTool"}
+{"Action":"pass","Package":"example.com/tools","Test":"TestTool","Elapsed":0.1}
+{"Action":"pass","Package":"example.com/tools","Elapsed":0.2}
+`
+
+func TestWriteMarkdownSummary(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(markdownSummaryInput),
+	})
+	assert.NilError(t, err)
+
+	file := fs.NewFile(t, t.Name(), fs.WithContent("# Earlier step\n\n"))
+	defer file.Remove()
+	opts := &options{markdownSummaryFile: file.Path()}
+	assert.NilError(t, writeMarkdownSummary(opts, exec))
+
+	raw, err := ioutil.ReadFile(file.Path())
+	assert.NilError(t, err)
+	expected := `# Earlier step
+
+## Test results
+
+**FAIL** 4 tests, 1 failed, 1 skipped, in 2 packages
+
+### Failed
+
+| Package | Test | Elapsed |
+|---------|------|---------|
+| example.com/db | TestBroken | 0.50s |
+
+### Skipped
+
+| Package | Test | Reason |
+|---------|------|--------|
+| example.com/db | TestSkipped | needs a database |
+
+### Slowest
+
+| Package | Test | Elapsed |
+|---------|------|---------|
+| example.com/db | TestSlow | 3.00s |
+| example.com/db | TestBroken | 0.50s |
+| example.com/tools | TestTool | 0.10s |
+
+### Packages
+
+| Package | Result | Passed | Failed | Skipped | Elapsed |
+|---------|--------|--------|--------|---------|---------|
+| example.com/db | FAIL | 1 | 1 | 1 | 3.60s |
+| example.com/tools | PASS | 1 | 0 | 0 | 0.20s |
+
+`
+	assert.Equal(t, string(raw), expected)
+}
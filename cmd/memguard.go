@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// memoryValue is the flag.Value for --max-memory. The value is a number of
+// bytes with an optional unit suffix, ex: 512MiB, 2GB. The number may be a
+// decimal, ex: 1.5GiB, as long as the value is a whole number of bytes.
+type memoryValue uint64
+
+var memoryUnits = []struct {
+	suffix string
+	size   uint64
+}{
+	// longer suffixes first, so that KiB is not matched by B
+	{suffix: "kib", size: 1 << 10},
+	{suffix: "mib", size: 1 << 20},
+	{suffix: "gib", size: 1 << 30},
+	{suffix: "kb", size: 1e3},
+	{suffix: "mb", size: 1e6},
+	{suffix: "gb", size: 1e9},
+	{suffix: "b", size: 1},
+}
+
+var memoryNumber = regexp.MustCompile(`^([0-9]+(\.[0-9]*)?|\.[0-9]+)$`)
+
+func (v *memoryValue) Set(raw string) error {
+	value, size := strings.ToLower(strings.TrimSpace(raw)), uint64(1)
+	for _, unit := range memoryUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value, size = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix)), unit.size
+			break
+		}
+	}
+	n, ok := new(big.Rat).SetString(value)
+	if !ok || !memoryNumber.MatchString(value) {
+		return errors.Errorf("invalid value: %v, must be a number of bytes with "+
+			"an optional unit suffix B, KB, MB, GB, KiB, MiB, or GiB, ex: 512MiB, 1.5GB", raw)
+	}
+	n.Mul(n, new(big.Rat).SetInt(new(big.Int).SetUint64(size)))
+	switch {
+	case !n.IsInt():
+		return errors.Errorf("invalid value: %v, must be a whole number of bytes", raw)
+	case !n.Num().IsUint64():
+		return errors.Errorf("invalid value: %v, must be at most %d bytes", raw, uint64(math.MaxUint64))
+	}
+	*v = memoryValue(n.Num().Uint64())
+	return nil
+}
+
+func (v *memoryValue) Type() string {
+	return "bytes"
+}
+
+func (v *memoryValue) String() string {
+	if *v == 0 {
+		return ""
+	}
+	return formatBytes(uint64(*v))
+}
+
+func formatBytes(n uint64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fGiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMiB", float64(n)/(1<<20))
+	default:
+		return fmt.Sprintf("%dB", n)
+	}
+}
+
+// maxOutputLinesWhenTruncated is the number of lines of output kept for each
+// test once memory use is still over --max-memory after the output was moved
+// to disk.
+const maxOutputLinesWhenTruncated = 200
+
+// memoryGuard checks the memory used by gotestsum while tests are running.
+// The first time memory use is over the limit the test output is moved to a
+// file on disk. If memory use is still over the limit the test output is
+// truncated.
+type memoryGuard struct {
+	limit    uint64
+	interval time.Duration
+	// rss returns the memory used by the process.
+	rss       func() (uint64, error)
+	lastCheck time.Time
+
+	// execution is the Execution that is being checked, and degraded is the
+	// number of steps taken to reduce the memory used by execution.
+	execution *testjson.Execution
+	degraded  int
+	// files used to store test output, one for each Execution.
+	files []*os.File
+}
+
+func newMemoryGuard(opts *options) *memoryGuard {
+	if opts.maxMemory == 0 {
+		return nil
+	}
+	return &memoryGuard{
+		limit:    uint64(opts.maxMemory),
+		interval: time.Second,
+		rss:      processRSS,
+	}
+}
+
+// check the memory used by the process, and reduce the memory used by exec
+// if it is over the limit. check must be called from EventHandler.Event.
+func (g *memoryGuard) check(exec *testjson.Execution) {
+	if g == nil {
+		return
+	}
+	if exec != g.execution {
+		g.execution, g.degraded = exec, 0
+	}
+	now := time.Now()
+	if g.degraded >= 2 || now.Sub(g.lastCheck) < g.interval {
+		return
+	}
+	g.lastCheck = now
+
+	rss, err := g.rss()
+	if err != nil {
+		log.Debugf("failed to read memory usage: %v", err)
+		return
+	}
+	if rss < g.limit {
+		return
+	}
+
+	if g.degraded == 0 {
+		g.degraded++
+		err := g.spill(exec)
+		if err == nil {
+			log.Warnf("memory usage %v is over --max-memory %v, test output will be stored on disk",
+				formatBytes(rss), formatBytes(g.limit))
+			debug.FreeOSMemory()
+			return
+		}
+		log.Warnf("failed to store test output on disk: %v", err)
+	}
+	g.degraded++
+	log.Warnf("memory usage %v is over --max-memory %v, test output will be truncated to the last %d lines of each test",
+		formatBytes(rss), formatBytes(g.limit), maxOutputLinesWhenTruncated)
+	exec.TruncateOutput(maxOutputLinesWhenTruncated)
+	debug.FreeOSMemory()
+}
+
+func (g *memoryGuard) spill(exec *testjson.Execution) error {
+	file, err := ioutil.TempFile("", "gotestsum-output-")
+	if err != nil {
+		return err
+	}
+	g.files = append(g.files, file)
+	return exec.SpillOutput(file)
+}
+
+// Close and remove the files used to store test output.
+func (g *memoryGuard) Close() error {
+	if g == nil {
+		return nil
+	}
+	for _, file := range g.files {
+		if err := file.Close(); err != nil {
+			log.Errorf("Failed to close file %v: %v", file.Name(), err)
+		}
+		if err := os.Remove(file.Name()); err != nil {
+			log.Errorf("Failed to remove file %v: %v", file.Name(), err)
+		}
+	}
+	g.files = nil
+	return nil
+}
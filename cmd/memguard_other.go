@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package cmd
+
+import "runtime"
+
+// processRSS returns an estimate of the resident set size of the process,
+// using the memory obtained from the OS by the Go runtime.
+func processRSS() (uint64, error) {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.Sys - stats.HeapReleased, nil
+}
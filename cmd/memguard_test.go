@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestMemoryValue_Set(t *testing.T) {
+	type testCase struct {
+		raw      string
+		expected uint64
+	}
+	for _, tc := range []testCase{
+		{raw: "1024", expected: 1024},
+		{raw: "512MiB", expected: 512 << 20},
+		{raw: "2GiB", expected: 2 << 30},
+		{raw: "2 gb", expected: 2e9},
+		{raw: "300kB", expected: 300e3},
+		{raw: "100b", expected: 100},
+		{raw: "1KiB", expected: 1024},
+		{raw: " 10MB  ", expected: 10e6},
+		{raw: "1.5GB", expected: 1.5e9},
+		{raw: "1.5GiB", expected: 3 << 29},
+		{raw: ".5KiB", expected: 512},
+		{raw: "2.MB", expected: 2e6},
+		{raw: "18446744073709551615", expected: math.MaxUint64},
+		{raw: "17179869183GiB", expected: 17179869183 << 30},
+	} {
+		var value memoryValue
+		assert.NilError(t, value.Set(tc.raw), tc.raw)
+		assert.Equal(t, uint64(value), tc.expected, tc.raw)
+	}
+}
+
+func TestMemoryValue_SetInvalid(t *testing.T) {
+	type testCase struct {
+		raw      string
+		expected string
+	}
+	for _, tc := range []testCase{
+		{raw: "lots", expected: "invalid value: lots, must be a number of bytes"},
+		{raw: "-1GB", expected: "invalid value: -1GB, must be a number of bytes"},
+		{raw: "1e3MB", expected: "invalid value: 1e3MB, must be a number of bytes"},
+		{raw: "1/2GB", expected: "invalid value: 1/2GB, must be a number of bytes"},
+		{raw: "1.5G", expected: "invalid value: 1.5G, must be a number of bytes"},
+		{raw: ".", expected: "invalid value: ., must be a number of bytes"},
+		{raw: "1.5", expected: "invalid value: 1.5, must be a whole number of bytes"},
+		{raw: "0.0001KB", expected: "invalid value: 0.0001KB, must be a whole number of bytes"},
+		{raw: "18446744073709551616", expected: "invalid value: 18446744073709551616, must be at most"},
+		{raw: "17179869184GiB", expected: "invalid value: 17179869184GiB, must be at most"},
+		{raw: "20000000000GB", expected: "invalid value: 20000000000GB, must be at most"},
+	} {
+		var value memoryValue
+		assert.ErrorContains(t, value.Set(tc.raw), tc.expected, tc.raw)
+		assert.Equal(t, uint64(value), uint64(0), tc.raw)
+	}
+}
+
+func TestMemoryGuard_Check(t *testing.T) {
+	var source strings.Builder
+	source.WriteString(`{"Action":"run","Package":"pkg","Test":"TestOne"}` + "\n")
+	for i := 0; i < 300; i++ {
+		fmt.Fprintf(&source, `{"Action":"output","Package":"pkg","Test":"TestOne","Output":"line %d\n"}`+"\n", i)
+	}
+	source.WriteString(`{"Action":"fail","Package":"pkg","Test":"TestOne"}` + "\n")
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(source.String())})
+	assert.NilError(t, err)
+	tc := exec.Failed()[0]
+	expected := exec.Package("pkg").OutputLines(tc)
+
+	rss := uint64(100)
+	guard := &memoryGuard{
+		limit: 200,
+		rss:   func() (uint64, error) { return rss, nil },
+	}
+	defer guard.Close() // nolint: errcheck
+
+	guard.check(exec)
+	assert.Equal(t, guard.degraded, 0)
+
+	rss = 300
+	guard.check(exec)
+	assert.Equal(t, guard.degraded, 1)
+	assert.Equal(t, len(guard.files), 1)
+	assert.DeepEqual(t, exec.Package("pkg").OutputLines(tc), expected)
+
+	guard.check(exec)
+	assert.Equal(t, guard.degraded, 2)
+	lines := exec.Package("pkg").OutputLines(tc)
+	assert.Equal(t, len(lines), maxOutputLinesWhenTruncated+1)
+	assert.Equal(t, lines[0], "... 100 lines of output were truncated to reduce memory use\n")
+	assert.Equal(t, lines[1], "line 100\n")
+
+	name := guard.files[0].Name()
+	assert.NilError(t, guard.Close())
+	_, err = os.Stat(name)
+	assert.Assert(t, os.IsNotExist(err))
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"gotest.tools/gotestsum/internal/metrics"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// metricsPushTimeout is the maximum time to wait for the --metrics-push
+// Pushgateway to accept the metrics.
+const metricsPushTimeout = 30 * time.Second
+
+// durationBuckets are the upper bounds, in seconds, of the buckets of the
+// test and package duration histograms.
+var durationBuckets = []float64{0.01, 0.1, 0.5, 1, 5, 10, 30, 60, 300, 600}
+
+func validateMetrics(opts *options) error {
+	if opts.metricsPush == "" {
+		return nil
+	}
+	if _, err := metrics.NewPusher(opts.metricsPush); err != nil {
+		return fmt.Errorf("invalid --metrics-push: %w", err)
+	}
+	return nil
+}
+
+// newMetricFamilies returns the metrics of the run. The test counts include
+// every run of a test, and the re-runs are also counted separately.
+func newMetricFamilies(exec *testjson.Execution) []metrics.Family {
+	tests := metrics.Family{
+		Name: "gotestsum_tests_total",
+		Help: "Number of tests run, by package and result.",
+		Type: metrics.Counter,
+	}
+	reruns := metrics.Family{
+		Name: "gotestsum_test_reruns_total",
+		Help: "Number of tests re-run by --rerun-fails, by package.",
+		Type: metrics.Counter,
+	}
+	testDuration := metrics.Sample{}
+	packageDuration := metrics.Sample{}
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		pkgLabel := metrics.Label{Name: "package", Value: name}
+		var rerun int
+		for _, result := range []struct {
+			action testjson.Action
+			tcs    []testjson.TestCase
+		}{
+			{action: testjson.ActionPass, tcs: pkg.Passed},
+			{action: testjson.ActionFail, tcs: pkg.Failed},
+			{action: testjson.ActionSkip, tcs: pkg.Skipped},
+		} {
+			tests.Samples = append(tests.Samples, metrics.Sample{
+				Labels: []metrics.Label{pkgLabel, {Name: "result", Value: string(result.action)}},
+				Value:  float64(len(result.tcs)),
+			})
+			for _, tc := range result.tcs {
+				if tc.RunID > 0 {
+					rerun++
+				}
+				testDuration.Observations = append(testDuration.Observations, tc.Elapsed.Seconds())
+			}
+		}
+		reruns.Samples = append(reruns.Samples, metrics.Sample{
+			Labels: []metrics.Label{pkgLabel},
+			Value:  float64(rerun),
+		})
+		packageDuration.Observations = append(packageDuration.Observations, pkg.Elapsed().Seconds())
+	}
+
+	return []metrics.Family{
+		tests,
+		reruns,
+		{
+			Name:    "gotestsum_test_duration_seconds",
+			Help:    "Elapsed time of each test.",
+			Type:    metrics.Histogram,
+			Samples: []metrics.Sample{testDuration},
+			Buckets: durationBuckets,
+		},
+		{
+			Name:    "gotestsum_package_duration_seconds",
+			Help:    "Elapsed time of each package.",
+			Type:    metrics.Histogram,
+			Samples: []metrics.Sample{packageDuration},
+			Buckets: durationBuckets,
+		},
+		{
+			Name:    "gotestsum_run_duration_seconds",
+			Help:    "Elapsed time of the run.",
+			Type:    metrics.Gauge,
+			Samples: []metrics.Sample{{Value: exec.Elapsed().Seconds()}},
+		},
+		{
+			Name:    "gotestsum_errors",
+			Help:    "Number of errors from go test, like build failures, in the run.",
+			Type:    metrics.Gauge,
+			Samples: []metrics.Sample{{Value: float64(len(exec.Errors()))}},
+		},
+	}
+}
+
+// writeMetrics writes the metrics of the run to --metrics-file, and pushes them
+// to --metrics-push. A failed push is printed as a warning, because the
+// metrics must not change the result of the run.
+func writeMetrics(opts *options, exec *testjson.Execution) error {
+	if opts.metricsFile == "" && opts.metricsPush == "" {
+		return nil
+	}
+	families := newMetricFamilies(exec)
+	if opts.metricsFile != "" {
+		fh, err := os.Create(opts.metricsFile)
+		if err != nil {
+			return err
+		}
+		if err := metrics.Encode(fh, families, metrics.FormatOpenMetrics); err != nil {
+			fh.Close() // nolint: errcheck
+			return err
+		}
+		if err := fh.Close(); err != nil {
+			return err
+		}
+	}
+	if opts.metricsPush != "" {
+		pusher, err := metrics.NewPusher(opts.metricsPush)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), metricsPushTimeout)
+		defer cancel()
+		if err := pusher.Push(ctx, families); err != nil {
+			log.Warnf("Failed to push metrics to --metrics-push: %v", err)
+		}
+	}
+	return nil
+}
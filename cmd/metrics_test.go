@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFlaky","Elapsed":0.2}
+{"Action":"run","Package":"example.com/pkg","Test":"TestSkipped"}
+{"Action":"skip","Package":"example.com/pkg","Test":"TestSkipped","Elapsed":0}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.3}
+{"Action":"run","Package":"example.com/other","Test":"TestPasses"}
+{"Action":"pass","Package":"example.com/other","Test":"TestPasses","Elapsed":2}
+{"Action":"pass","Package":"example.com/other","Elapsed":2.5}
+`),
+	})
+	assert.NilError(t, err)
+	_, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		RunID:     1,
+		Execution: exec,
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestFlaky","Elapsed":0.2}
+{"Action":"pass","Package":"example.com/pkg","Elapsed":0.3}
+`),
+	})
+	assert.NilError(t, err)
+
+	var pushed string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		pushed = string(body)
+	}))
+	defer srv.Close()
+
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	opts := &options{metricsFile: dir.Join("metrics.txt"), metricsPush: srv.URL}
+	assert.NilError(t, writeMetrics(opts, exec))
+
+	raw, err := ioutil.ReadFile(opts.metricsFile)
+	assert.NilError(t, err)
+	file := string(raw)
+	for _, line := range []string{
+		"# TYPE gotestsum_tests counter\n",
+		`gotestsum_tests_total{package="example.com/other",result="pass"} 1` + "\n",
+		`gotestsum_tests_total{package="example.com/pkg",result="pass"} 1` + "\n",
+		`gotestsum_tests_total{package="example.com/pkg",result="fail"} 1` + "\n",
+		`gotestsum_tests_total{package="example.com/pkg",result="skip"} 1` + "\n",
+		`gotestsum_test_reruns_total{package="example.com/pkg"} 1` + "\n",
+		`gotestsum_test_reruns_total{package="example.com/other"} 0` + "\n",
+		`gotestsum_test_duration_seconds_bucket{le="0.5"} 3` + "\n",
+		"gotestsum_test_duration_seconds_count 4\n",
+		`gotestsum_package_duration_seconds_bucket{le="1"} 1` + "\n",
+		"gotestsum_package_duration_seconds_count 2\n",
+		"gotestsum_errors 0\n",
+		"# EOF\n",
+	} {
+		assert.Check(t, strings.Contains(file, line), "missing %q in:\n%s", line, file)
+	}
+
+	assert.Check(t, strings.Contains(pushed, "# TYPE gotestsum_tests_total counter\n"), pushed)
+	assert.Check(t, !strings.Contains(pushed, "# EOF"), pushed)
+}
+
+func TestWriteMetrics_PushFailureIsNotAnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader("")})
+	assert.NilError(t, err)
+	assert.NilError(t, writeMetrics(&options{metricsPush: srv.URL}, exec))
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Names of the package groups created by --no-cache-tests.
+const (
+	cachedGroupName  = "cached"
+	noCacheGroupName = "no-cache"
+)
+
+// noCachePolicy is the packages that run with -count=1 because they match a
+// --no-cache-tests pattern, and the packages that may use cached results.
+type noCachePolicy struct {
+	patterns []string
+	noCache  []string
+	cached   []string
+}
+
+func validateNoCacheTests(opts *options) error {
+	if len(opts.noCacheTests) == 0 {
+		return nil
+	}
+	countFlag, _ := argIndex("count", opts.args)
+	var unsupported string
+	switch {
+	case opts.rawCommand:
+		unsupported = "--raw-command"
+	case len(opts.pkgGroups) > 0:
+		unsupported = "--pkg-group"
+	case maxRerunAttempts(opts) > 0:
+		unsupported = "--rerun-fails"
+	case opts.watch:
+		unsupported = "--watch"
+	case opts.untilFailure:
+		unsupported = "--until-failure"
+	case opts.archiveDir != "":
+		unsupported = "--archive-dir"
+	case analysisStageEnabled(opts):
+		unsupported = "--vet-mode=separate or --analysis-command"
+	case countFlag >= 0:
+		unsupported = "the go test -count flag"
+	case len(opts.args) > 0 && len(opts.packages) == 0:
+		return fmt.Errorf("when go test args are used with --no-cache-tests " +
+			"the list of packages to test must be specified by the --packages flag")
+	default:
+		return nil
+	}
+	return fmt.Errorf("--no-cache-tests can not be used with %v", unsupported)
+}
+
+// setupNoCacheTests lists the packages to test, and runs the packages that
+// match a --no-cache-tests pattern with -count=1, so that go test does not
+// use a cached result for those packages. When only some of the packages
+// match, the packages are run as two package groups.
+func setupNoCacheTests(opts *options) error {
+	if len(opts.noCacheTests) == 0 {
+		return nil
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	args := append(goListBuildFlags(opts.args), cmdArgPackageList(opts, rerunOpts{}, "./...")...)
+	pkgs, err := goListFn(dir, args)
+	if err != nil {
+		return fmt.Errorf("failed to list packages for --no-cache-tests: %w", err)
+	}
+
+	policy := &noCachePolicy{patterns: opts.noCacheTests}
+	for _, pkg := range pkgs {
+		if matchesAnyPackagePattern(opts.noCacheTests, pkg) {
+			policy.noCache = append(policy.noCache, pkg)
+			continue
+		}
+		policy.cached = append(policy.cached, pkg)
+	}
+	opts.noCachePolicy = policy
+
+	switch {
+	case len(policy.noCache) == 0:
+	case len(policy.cached) == 0:
+		opts.packages = policy.noCache
+		opts.args = append([]string{"-count=1"}, opts.args...)
+	default:
+		opts.packages = nil
+		opts.pkgGroups = pkgGroupsValue{
+			{name: cachedGroupName, packages: policy.cached},
+			{name: noCacheGroupName, packages: policy.noCache, args: []string{"-count=1"}},
+		}
+	}
+	return nil
+}
+
+func matchesAnyPackagePattern(patterns []string, pkg string) bool {
+	for _, pattern := range patterns {
+		if matchPackagePattern(pattern, pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// printNoCachePolicy prints the packages that were run with -count=1 because
+// of --no-cache-tests.
+func printNoCachePolicy(out io.Writer, policy *noCachePolicy) {
+	if policy == nil {
+		return
+	}
+	fmt.Fprintf(out, "\n=== Cache policy (--no-cache-tests %v)\n", strings.Join(policy.patterns, " "))
+	fmt.Fprintf(out, "-count=1: %d %s\n", len(policy.noCache), pluralize(len(policy.noCache), "package", "packages"))
+	for _, pkg := range policy.noCache {
+		fmt.Fprintf(out, "    %v\n", testjson.RelativePackagePath(pkg))
+	}
+	fmt.Fprintf(out, "cacheable: %d %s\n", len(policy.cached), pluralize(len(policy.cached), "package", "packages"))
+}
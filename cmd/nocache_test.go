@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestSetupNoCacheTests(t *testing.T) {
+	var listArgs []string
+	defer patchGoListFn(func(dir string, args []string) ([]string, error) {
+		listArgs = args
+		return []string{
+			"example.com/proj/api",
+			"example.com/proj/integration/db",
+			"example.com/proj/integration/http",
+			"example.com/proj/store",
+		}, nil
+	})()
+
+	t.Run("some packages match", func(t *testing.T) {
+		opts := &options{
+			noCacheTests: []string{"example.com/proj/integration/...", "*/*/store"},
+			packages:     []string{"./..."},
+			args:         []string{"-tags=it", "-race"},
+		}
+		assert.NilError(t, setupNoCacheTests(opts))
+		assert.DeepEqual(t, listArgs, []string{"-tags=it", "./..."})
+		assert.Assert(t, opts.packages == nil)
+		assert.DeepEqual(t, opts.pkgGroups, pkgGroupsValue{
+			{name: "cached", packages: []string{"example.com/proj/api"}},
+			{
+				name: "no-cache",
+				packages: []string{
+					"example.com/proj/integration/db",
+					"example.com/proj/integration/http",
+					"example.com/proj/store",
+				},
+				args: []string{"-count=1"},
+			},
+		}, cmpPkgGroup)
+	})
+
+	t.Run("all packages match", func(t *testing.T) {
+		opts := &options{noCacheTests: []string{"example.com/proj/..."}}
+		assert.NilError(t, setupNoCacheTests(opts))
+		assert.Equal(t, len(opts.pkgGroups), 0)
+		assert.Equal(t, len(opts.packages), 4)
+		assert.DeepEqual(t, goTestCmdArgs(opts, rerunOpts{}), []string{
+			"go", "test", "-json", "-count=1",
+			"example.com/proj/api",
+			"example.com/proj/integration/db",
+			"example.com/proj/integration/http",
+			"example.com/proj/store",
+		})
+	})
+
+	t.Run("no packages match", func(t *testing.T) {
+		opts := &options{noCacheTests: []string{"example.com/other/..."}}
+		assert.NilError(t, setupNoCacheTests(opts))
+		assert.Equal(t, len(opts.pkgGroups), 0)
+		assert.DeepEqual(t, goTestCmdArgs(opts, rerunOpts{}), []string{"go", "test", "-json", "./..."})
+		assert.Equal(t, len(opts.noCachePolicy.cached), 4)
+	})
+}
+
+func TestPrintNoCachePolicy(t *testing.T) {
+	buf := new(bytes.Buffer)
+	printNoCachePolicy(buf, &noCachePolicy{
+		patterns: []string{"example.com/proj/integration/...", "*/*/store"},
+		noCache:  []string{"example.com/proj/integration/db", "example.com/proj/store"},
+		cached:   []string{"example.com/proj/api"},
+	})
+	expected := `
+=== Cache policy (--no-cache-tests example.com/proj/integration/... */*/store)
+-count=1: 2 packages
+    example.com/proj/integration/db
+    example.com/proj/store
+cacheable: 1 package
+`
+	assert.Equal(t, buf.String(), expected)
+
+	buf.Reset()
+	printNoCachePolicy(buf, nil)
+	assert.Equal(t, buf.String(), "")
+}
+
+func TestRun_NoCacheTests(t *testing.T) {
+	defer patchGoListFn(func(dir string, args []string) ([]string, error) {
+		return []string{"example.com/proj/api", "example.com/proj/integration"}, nil
+	})()
+
+	var commands [][]string
+	defer patchStartGoTestFn(func(args []string) *proc {
+		commands = append(commands, args)
+		pkg := args[len(args)-1]
+		return &proc{
+			cmd: fakeWaiter{},
+			stdout: strings.NewReader(`{"Package": "` + pkg + `", "Action": "run"}
+{"Package": "` + pkg + `", "Test": "TestOne", "Action": "run"}
+{"Package": "` + pkg + `", "Test": "TestOne", "Action": "pass"}
+{"Package": "` + pkg + `", "Action": "pass"}
+`),
+			stderr: bytes.NewReader(nil),
+		}
+	})()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		format:       "testname",
+		noCacheTests: []string{"example.com/proj/integration"},
+		stdout:       out,
+		stderr:       out,
+		hideSummary:  newHideSummaryValue(),
+	}
+	assert.NilError(t, run(opts))
+	assert.DeepEqual(t, commands, [][]string{
+		{"go", "test", "-json", "example.com/proj/api"},
+		{"go", "test", "-json", "-count=1", "example.com/proj/integration"},
+	})
+	assert.Assert(t, cmp.Contains(out.String(), "=== Group no-cache\n"))
+	assert.Assert(t, cmp.Contains(out.String(), `
+=== Cache policy (--no-cache-tests example.com/proj/integration)
+-count=1: 1 package
+    example.com/proj/integration
+cacheable: 1 package
+`))
+}
+
+func TestRun_NoCacheTests_WithCoverageMin(t *testing.T) {
+	defer patchGoListFn(func(dir string, args []string) ([]string, error) {
+		return []string{"example.com/proj/api", "example.com/proj/integration"}, nil
+	})()
+
+	defer patchStartGoTestFn(func(args []string) *proc {
+		pkg := args[len(args)-1]
+		coverage := "90.0"
+		if pkg == "example.com/proj/api" {
+			coverage = "40.0"
+		}
+		return &proc{
+			cmd: fakeWaiter{},
+			stdout: strings.NewReader(`{"Package": "` + pkg + `", "Test": "TestOne", "Action": "run"}
+{"Package": "` + pkg + `", "Test": "TestOne", "Action": "pass"}
+{"Package": "` + pkg + `", "Action": "output", "Output": "coverage: ` + coverage + `% of statements\n"}
+{"Package": "` + pkg + `", "Action": "pass"}
+`),
+			stderr: bytes.NewReader(nil),
+		}
+	})()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		format:       "testname",
+		noCacheTests: []string{"example.com/proj/integration"},
+		coverageMin:  80,
+		stdout:       out,
+		stderr:       out,
+		hideSummary:  newHideSummaryValue(),
+	}
+	err := run(opts)
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+	assert.Assert(t, cmp.Contains(out.String(), `
+=== Coverage below minimum (1 package)
+example.com/proj/api 40.0% (minimum 80.0%)
+`))
+}
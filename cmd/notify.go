@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// notifyTimeout is the maximum time to wait for the --notify-webhook request.
+const notifyTimeout = 30 * time.Second
+
+// notifyMaxFailedTests is the number of failed tests listed in the Text of
+// the notification.
+const notifyMaxFailedTests = 10
+
+// defaultNotifyTemplate is the payload sent when there is no
+// --notify-webhook-template. The format is accepted by Slack incoming
+// webhooks, and by most chat services with a Slack compatible webhook.
+const defaultNotifyTemplate = `{"text": {{ json .Text }}}
+`
+
+// notifyData is the data used to execute the --notify-webhook-template.
+type notifyData struct {
+	// Status is passed or failed.
+	Status  string
+	Total   int
+	Failed  int
+	Skipped int
+	Errors  int
+	Elapsed time.Duration
+	// FailedTests are the names of the failed tests, with the package name
+	// as a prefix.
+	FailedTests []string
+	RunID       string
+	// JobURL is the URL of the CI job, or empty when the tests did not run in
+	// a known CI system.
+	JobURL string
+	// Text is a message with all the other fields, for the body of a chat
+	// message.
+	Text string
+}
+
+func parseNotifyTemplate(opts *options) (*template.Template, error) {
+	name, raw := "default", defaultNotifyTemplate
+	if opts.notifyWebhookTemplate != "" {
+		content, err := ioutil.ReadFile(opts.notifyWebhookTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --notify-webhook-template: %w", err)
+		}
+		name, raw = filepath.Base(opts.notifyWebhookTemplate), string(content)
+	}
+	tmpl, err := template.New(name).Funcs(template.FuncMap{"json": templateJSON}).Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse --notify-webhook-template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// templateJSON returns v encoded as JSON, so that a template can include any
+// value in a JSON payload.
+func templateJSON(v interface{}) (string, error) {
+	raw, err := json.Marshal(v)
+	return string(raw), err
+}
+
+func validateNotifyWebhook(opts *options) error {
+	if opts.notifyWebhook == "" {
+		switch {
+		case opts.notifyWebhookTemplate != "":
+			return fmt.Errorf("--notify-webhook-template requires --notify-webhook")
+		case opts.notifyOnFailure:
+			return fmt.Errorf("--notify-on-failure requires --notify-webhook")
+		}
+		return nil
+	}
+	u, err := url.Parse(opts.notifyWebhook)
+	if err != nil {
+		return fmt.Errorf("invalid --notify-webhook: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid --notify-webhook %v, must start with http:// or https://", opts.notifyWebhook)
+	}
+	_, err = parseNotifyTemplate(opts)
+	return err
+}
+
+// notifyWebhook posts the result of the run to opts.notifyWebhook. A failed
+// request is printed as a warning, because the notification must not change
+// the result of the run.
+func notifyWebhook(opts *options, exitErr error, execs ...*testjson.Execution) error {
+	if opts.notifyWebhook == "" {
+		return nil
+	}
+	data := newNotifyData(opts, exitErr, execs)
+	if opts.notifyOnFailure && data.Status != "failed" {
+		return nil
+	}
+	tmpl, err := parseNotifyTemplate(opts)
+	if err != nil {
+		return err
+	}
+	body := new(bytes.Buffer)
+	if err := tmpl.Execute(body, data); err != nil {
+		return fmt.Errorf("failed to execute --notify-webhook-template: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if err := postWebhook(ctx, opts.notifyWebhook, body.Bytes()); err != nil {
+		log.Warnf("Failed to send notification to --notify-webhook: %v", err)
+	}
+	return nil
+}
+
+func newNotifyData(opts *options, exitErr error, execs []*testjson.Execution) notifyData {
+	data := notifyData{
+		Status: "passed",
+		RunID:  opts.runID,
+		JobURL: detectCI().BuildURL,
+	}
+	for _, exec := range execs {
+		data.Total += exec.Total()
+		data.Failed += len(exec.Failed())
+		data.Skipped += len(exec.Skipped())
+		data.Errors += len(exec.Errors())
+		data.Elapsed += exec.Elapsed()
+		for _, tc := range exec.Failed() {
+			data.FailedTests = append(data.FailedTests,
+				testjson.RelativePackagePath(tc.Package)+" "+tc.Test.Name())
+		}
+	}
+	if exitErr != nil || data.Failed > 0 || data.Errors > 0 {
+		data.Status = "failed"
+	}
+	data.Elapsed = data.Elapsed.Round(time.Millisecond)
+	data.Text = notifyText(data)
+	return data
+}
+
+func notifyText(data notifyData) string {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "gotestsum: tests %s. %d %s", data.Status, data.Total, pluralize(data.Total, "test", "tests"))
+	if data.Skipped > 0 {
+		fmt.Fprintf(buf, ", %d skipped", data.Skipped)
+	}
+	if data.Failed > 0 {
+		fmt.Fprintf(buf, ", %d %s", data.Failed, pluralize(data.Failed, "failure", "failures"))
+	}
+	if data.Errors > 0 {
+		fmt.Fprintf(buf, ", %d %s", data.Errors, pluralize(data.Errors, "error", "errors"))
+	}
+	fmt.Fprintf(buf, " in %v", data.Elapsed)
+	if data.RunID != "" {
+		fmt.Fprintf(buf, " (run %v)", data.RunID)
+	}
+	buf.WriteString("\n")
+
+	if len(data.FailedTests) > 0 {
+		buf.WriteString("Failed tests:\n")
+		for i, name := range data.FailedTests {
+			if i == notifyMaxFailedTests {
+				fmt.Fprintf(buf, "  and %d more\n", len(data.FailedTests)-i)
+				break
+			}
+			fmt.Fprintf(buf, "  %v\n", name)
+		}
+	}
+	if data.JobURL != "" {
+		fmt.Fprintf(buf, "%v\n", data.JobURL)
+	}
+	return buf.String()
+}
+
+func postWebhook(ctx context.Context, rawURL string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected response %v: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
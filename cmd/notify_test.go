@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+)
+
+func TestNotifyText(t *testing.T) {
+	data := notifyData{
+		Status:  "failed",
+		Total:   40,
+		Failed:  12,
+		Skipped: 2,
+		Errors:  1,
+		Elapsed: 90 * time.Second,
+		RunID:   "run-1",
+		JobURL:  "https://ci.example.com/jobs/1",
+	}
+	for i := 0; i < 12; i++ {
+		data.FailedTests = append(data.FailedTests, fmt.Sprintf("pkg TestFails%d", i))
+	}
+	expected := `gotestsum: tests failed. 40 tests, 2 skipped, 12 failures, 1 error in 1m30s (run run-1)
+Failed tests:
+  pkg TestFails0
+  pkg TestFails1
+  pkg TestFails2
+  pkg TestFails3
+  pkg TestFails4
+  pkg TestFails5
+  pkg TestFails6
+  pkg TestFails7
+  pkg TestFails8
+  pkg TestFails9
+  and 2 more
+https://ci.example.com/jobs/1
+`
+	assert.Equal(t, notifyText(data), expected)
+
+	passed := notifyData{Status: "passed", Total: 1, Elapsed: time.Second}
+	assert.Equal(t, notifyText(passed), "gotestsum: tests passed. 1 test in 1s\n")
+}
+
+func TestNotifyWebhook(t *testing.T) {
+	defer env.PatchAll(t, map[string]string{
+		"GITHUB_ACTIONS": "", "GITLAB_CI": "", "BUILDKITE": "", "CIRCLECI": "",
+	})()
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Check(t, r.Header.Get("Content-Type") == "application/json")
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, string(body))
+	}))
+	defer srv.Close()
+
+	failed, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestFails"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFails","Elapsed":0.1}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.2}
+`),
+	})
+	assert.NilError(t, err)
+	passed, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestPasses"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestPasses","Elapsed":0.1}
+{"Action":"pass","Package":"example.com/pkg","Elapsed":0.2}
+`),
+	})
+	assert.NilError(t, err)
+
+	t.Run("default template", func(t *testing.T) {
+		requests = nil
+		opts := &options{notifyWebhook: srv.URL}
+		assert.NilError(t, notifyWebhook(opts, nil, failed))
+		assert.Equal(t, len(requests), 1)
+
+		var payload struct{ Text string }
+		assert.NilError(t, json.Unmarshal([]byte(requests[0]), &payload))
+		assert.Assert(t, strings.HasPrefix(payload.Text, "gotestsum: tests failed. 1 test, 1 failure in "), payload.Text)
+		assert.Assert(t, strings.HasSuffix(payload.Text, "\nFailed tests:\n  example.com/pkg TestFails\n"), payload.Text)
+	})
+
+	t.Run("only on failure", func(t *testing.T) {
+		requests = nil
+		opts := &options{notifyWebhook: srv.URL, notifyOnFailure: true}
+		assert.NilError(t, notifyWebhook(opts, nil, passed))
+		assert.Equal(t, len(requests), 0)
+
+		assert.NilError(t, notifyWebhook(opts, fmt.Errorf("exit status 1"), passed))
+		assert.Equal(t, len(requests), 1)
+	})
+
+	t.Run("custom template", func(t *testing.T) {
+		requests = nil
+		tmpl := fs.NewFile(t, t.Name(), fs.WithContent(
+			`{"status": {{ json .Status }}, "failed": {{ .Failed }}, "tests": {{ json .FailedTests }}}`))
+		defer tmpl.Remove()
+
+		opts := &options{notifyWebhook: srv.URL, notifyWebhookTemplate: tmpl.Path()}
+		assert.NilError(t, notifyWebhook(opts, nil, failed))
+		assert.DeepEqual(t, requests, []string{
+			`{"status": "failed", "failed": 1, "tests": ["example.com/pkg TestFails"]}`,
+		})
+	})
+}
+
+func TestNotifyWebhook_FailureIsNotAnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader("")})
+	assert.NilError(t, err)
+	assert.NilError(t, notifyWebhook(&options{notifyWebhook: srv.URL}, nil, exec))
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// onFailCommandTimeout is the maximum time each run of the --on-fail-command
+// may take.
+const onFailCommandTimeout = 5 * time.Minute
+
+// onFailHook runs a command each time a test failure is observed. The
+// command is rate limited, failures observed less than interval after the
+// previous command started do not run the command.
+//
+// The command runs in the background so that it does not delay the test run.
+// Commands run one at a time, in the order the failures were observed, and
+// the output of each command is written to stderr once the command exits.
+type onFailHook struct {
+	args     []*template.Template
+	interval time.Duration
+	timeout  time.Duration
+	stderr   io.Writer
+	clock    clockwork.Clock
+	last     time.Time
+	runID    string
+
+	wg sync.WaitGroup
+	// done is closed when the most recent command exits.
+	done chan struct{}
+}
+
+// onFailData is the data used to execute the --on-fail-command templates.
+type onFailData struct {
+	Package string
+	Test    string
+	RunID   int
+	Elapsed time.Duration
+}
+
+func newOnFailHook(opts *options) (*onFailHook, error) {
+	command := opts.onFailCmd.Value()
+	if len(command) == 0 {
+		return nil, nil
+	}
+	hook := &onFailHook{
+		interval: opts.onFailInterval,
+		timeout:  onFailCommandTimeout,
+		stderr:   opts.stderr,
+		clock:    clockwork.NewRealClock(),
+		runID:    opts.runID,
+	}
+	for _, arg := range command {
+		tmpl, err := template.New("on-fail-command").Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse --on-fail-command: %w", err)
+		}
+		hook.args = append(hook.args, tmpl)
+	}
+	return hook, nil
+}
+
+// isTestFailure returns true if the event is the fail event for a test, or for
+// a package which failed without any failed tests.
+func isTestFailure(event testjson.TestEvent, exec *testjson.Execution) bool {
+	if event.Action != testjson.ActionFail {
+		return false
+	}
+	if !event.PackageEvent() {
+		return true
+	}
+	pkg := exec.Package(event.Package)
+	return pkg != nil && pkg.TestMainFailed()
+}
+
+func (h *onFailHook) Event(event testjson.TestEvent, exec *testjson.Execution) {
+	if h == nil || !isTestFailure(event, exec) {
+		return
+	}
+	now := h.clock.Now()
+	if !h.last.IsZero() && now.Sub(h.last) < h.interval {
+		log.Debugf("on-fail-command skipped for %s %s, rate limited",
+			event.Package, event.Test)
+		return
+	}
+	h.last = now
+
+	data := onFailData{
+		Package: event.Package,
+		Test:    event.Test,
+		RunID:   event.RunID,
+		Elapsed: time.Duration(event.Elapsed * float64(time.Second)),
+	}
+	args, err := h.commandArgs(data)
+	if err != nil {
+		log.Warnf("on-fail-command failed: %v", err)
+		return
+	}
+	failed := len(exec.Failed())
+
+	prev, done := h.done, make(chan struct{})
+	h.done = done
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		defer close(done)
+		if prev != nil {
+			<-prev
+		}
+		if err := h.run(args, data, failed); err != nil {
+			log.Warnf("on-fail-command failed: %v", err)
+		}
+	}()
+}
+
+func (h *onFailHook) commandArgs(data onFailData) ([]string, error) {
+	args := make([]string, 0, len(h.args))
+	for _, tmpl := range h.args {
+		buf := new(bytes.Buffer)
+		if err := tmpl.Execute(buf, data); err != nil {
+			return nil, err
+		}
+		args = append(args, buf.String())
+	}
+	return args, nil
+}
+
+func (h *onFailHook) run(args []string, data onFailData, failed int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	out := new(bytes.Buffer)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.Env = append(
+		os.Environ(),
+		"TEST_PACKAGE="+data.Package,
+		"TEST_NAME="+data.Test,
+		fmt.Sprintf("TESTS_FAILED=%d", failed),
+		"GOTESTSUM_RUN_ID="+h.runID,
+	)
+	log.Debugf("exec: %s", args)
+	err := cmd.Run()
+	_, _ = h.stderr.Write(out.Bytes())
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("stopped after %v", h.timeout)
+	}
+	return err
+}
+
+// Close waits for the commands that are still running.
+func (h *onFailHook) Close() {
+	if h == nil {
+		return
+	}
+	h.wg.Wait()
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestOnFailHook(t *testing.T) {
+	command := &commandValue{}
+	assert.NilError(t, command.Set("echo {{.Package}}.{{.Test}}"))
+
+	buf := new(bytes.Buffer)
+	hook, err := newOnFailHook(&options{onFailCmd: command, stderr: buf})
+	assert.NilError(t, err)
+
+	source := golden.Get(t, "../../testjson/testdata/go-test-json.out")
+	cfg := testjson.ScanConfig{
+		Stdout:  bytes.NewReader(source),
+		Handler: &eventHandler{formatter: discardFormatter(), onFail: hook},
+	}
+	_, err = testjson.ScanTestOutput(cfg)
+	assert.NilError(t, err)
+	hook.Close()
+
+	expected := `github.com/gotestyourself/gotestyourself/testjson/internal/badmain.
+github.com/gotestyourself/gotestyourself/testjson/internal/stub.TestFailed
+github.com/gotestyourself/gotestyourself/testjson/internal/stub.TestFailedWithStderr
+github.com/gotestyourself/gotestyourself/testjson/internal/stub.TestNestedWithFailure/c
+github.com/gotestyourself/gotestyourself/testjson/internal/stub.TestNestedWithFailure
+`
+	assert.Equal(t, buf.String(), expected)
+}
+
+func TestOnFailHook_RateLimited(t *testing.T) {
+	command := &commandValue{}
+	assert.NilError(t, command.Set("echo {{.Test}}"))
+
+	buf := new(bytes.Buffer)
+	hook, err := newOnFailHook(&options{
+		onFailCmd:      command,
+		onFailInterval: time.Minute,
+		stderr:         buf,
+	})
+	assert.NilError(t, err)
+	fakeClock := clockwork.NewFakeClock()
+	hook.clock = fakeClock
+
+	exec := newExecFromTestData(t)
+	event := func(name string) testjson.TestEvent {
+		return testjson.TestEvent{Action: testjson.ActionFail, Package: "pkg", Test: name}
+	}
+	hook.Event(event("TestOne"), exec)
+	fakeClock.Advance(30 * time.Second)
+	hook.Event(event("TestTwo"), exec)
+	fakeClock.Advance(31 * time.Second)
+	hook.Event(event("TestThree"), exec)
+	hook.Close()
+
+	assert.Equal(t, buf.String(), "TestOne\nTestThree\n")
+}
+
+func TestOnFailHook_RunsInBackground(t *testing.T) {
+	command := &commandValue{}
+	assert.NilError(t, command.Set("sleep 10"))
+
+	hook, err := newOnFailHook(&options{onFailCmd: command, stderr: ioutil.Discard})
+	assert.NilError(t, err)
+	hook.timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	exec := newExecFromTestData(t)
+	hook.Event(testjson.TestEvent{Action: testjson.ActionFail, Package: "pkg", Test: "TestOne"}, exec)
+	assert.Assert(t, time.Since(start) < time.Second, "Event must not wait for the command")
+
+	hook.Close()
+	assert.Assert(t, time.Since(start) < 5*time.Second, "the command must be stopped by the timeout")
+}
+
+func TestNewOnFailHook_InvalidTemplate(t *testing.T) {
+	command := &commandValue{}
+	assert.NilError(t, command.Set("echo {{.Test"))
+
+	_, err := newOnFailHook(&options{onFailCmd: command})
+	assert.ErrorContains(t, err, "failed to parse --on-fail-command")
+}
+
+func discardFormatter() testjson.EventFormatter {
+	return testjson.NewEventFormatterWithOptions(ioutil.Discard, "testname", testjson.FormatOptions{})
+}
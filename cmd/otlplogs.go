@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/otlp"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+const (
+	// otlpLogsBatchSize is the number of log records sent to the
+	// --otlp-logs-endpoint in each request.
+	otlpLogsBatchSize = 512
+	// otlpLogsExportTimeout is the maximum time to wait for the
+	// --otlp-logs-endpoint to accept a batch of log records.
+	otlpLogsExportTimeout = 30 * time.Second
+)
+
+// otlpLogsHook exports the output of each test as an OpenTelemetry log record.
+// The output of a test is collected as it is received, because the output of
+// passed tests is removed from the Execution when the test ends.
+type otlpLogsHook struct {
+	exporter *otlp.Exporter
+	runID    string
+	// span is the trace context of the run, used for the output of tests
+	// which did not print a traceparent.
+	span    otlp.SpanContext
+	output  map[otlpLogKey]*otlpLog
+	records []otlp.LogRecord
+}
+
+type otlpLogKey struct {
+	pkg  string
+	test string
+}
+
+type otlpLog struct {
+	output  strings.Builder
+	traceID string
+	spanID  string
+}
+
+func validateOTLPLogs(opts *options) error {
+	if opts.otlpLogsEndpoint == "" {
+		return nil
+	}
+	if _, err := newOTLPExporter(opts.otlpLogsEndpoint); err != nil {
+		return fmt.Errorf("invalid --otlp-logs-endpoint: %w", err)
+	}
+	return nil
+}
+
+// newOTLPExporter returns an exporter for endpoint, using the headers and
+// service name from the standard OpenTelemetry environment variables.
+func newOTLPExporter(endpoint string) (*otlp.Exporter, error) {
+	headers, err := otlp.ParseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+	if err != nil {
+		return nil, fmt.Errorf("OTEL_EXPORTER_OTLP_HEADERS: %w", err)
+	}
+	logHeaders, err := otlp.ParseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_LOGS_HEADERS"))
+	if err != nil {
+		return nil, fmt.Errorf("OTEL_EXPORTER_OTLP_LOGS_HEADERS: %w", err)
+	}
+	for k, v := range logHeaders {
+		headers[k] = v
+	}
+	resource := []otlp.Attribute{
+		{Key: "service.name", Value: lookEnvWithDefault("OTEL_SERVICE_NAME", "gotestsum")},
+	}
+	return otlp.NewExporter(endpoint, headers, resource)
+}
+
+func newOTLPLogsHook(opts *options) (*otlpLogsHook, error) {
+	if opts.otlpLogsEndpoint == "" {
+		return nil, nil
+	}
+	exporter, err := newOTLPExporter(opts.otlpLogsEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --otlp-logs-endpoint: %w", err)
+	}
+	return &otlpLogsHook{
+		exporter: exporter,
+		runID:    opts.runID,
+		span:     opts.traceSpan,
+		output:   make(map[otlpLogKey]*otlpLog),
+	}, nil
+}
+
+func (h *otlpLogsHook) Event(event testjson.TestEvent) {
+	if h == nil {
+		return
+	}
+	key := otlpLogKey{pkg: event.Package, test: event.Test}
+	switch event.Action {
+	case testjson.ActionOutput:
+		l, ok := h.output[key]
+		if !ok {
+			l = &otlpLog{}
+			h.output[key] = l
+		}
+		l.output.WriteString(event.Output)
+		if l.traceID == "" {
+			l.traceID, l.spanID, _ = otlp.FindTraceparent(event.Output)
+		}
+	case testjson.ActionPass, testjson.ActionFail, testjson.ActionSkip:
+		l := h.output[key]
+		delete(h.output, key)
+		// The output of a package is only useful when it failed, for example
+		// with a build error, or a panic in TestMain.
+		if event.PackageEvent() && event.Action != testjson.ActionFail {
+			return
+		}
+		h.records = append(h.records, h.newRecord(event, l))
+		if len(h.records) >= otlpLogsBatchSize {
+			h.flush()
+		}
+	}
+}
+
+func (h *otlpLogsHook) newRecord(event testjson.TestEvent, l *otlpLog) otlp.LogRecord {
+	record := otlp.LogRecord{
+		Time:     event.Time,
+		Severity: otlpSeverity(event.Action),
+		Attributes: []otlp.Attribute{
+			{Key: "test.package", Value: event.Package},
+		},
+	}
+	if event.Test != "" {
+		record.Attributes = append(record.Attributes,
+			otlp.Attribute{Key: "test.name", Value: event.Test})
+	}
+	record.Attributes = append(record.Attributes,
+		otlp.Attribute{Key: "test.result", Value: string(event.Action)},
+		otlp.Attribute{Key: "test.elapsed", Value: fmt.Sprintf("%.3fs", event.Elapsed)})
+	if h.runID != "" {
+		record.Attributes = append(record.Attributes,
+			otlp.Attribute{Key: "gotestsum.run_id", Value: h.runID})
+	}
+	record.TraceID, record.SpanID = h.span.TraceID, h.span.SpanID
+	if l != nil {
+		record.Body = l.output.String()
+		if l.traceID != "" {
+			record.TraceID, record.SpanID = l.traceID, l.spanID
+		}
+	}
+	return record
+}
+
+func otlpSeverity(action testjson.Action) otlp.Severity {
+	switch action {
+	case testjson.ActionFail:
+		return otlp.SeverityError
+	case testjson.ActionSkip:
+		return otlp.SeverityWarn
+	default:
+		return otlp.SeverityInfo
+	}
+}
+
+// flush sends the pending log records. Errors are printed as a warning,
+// because the export must not change the result of the run.
+func (h *otlpLogsHook) flush() {
+	if len(h.records) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), otlpLogsExportTimeout)
+	defer cancel()
+	if err := h.exporter.Export(ctx, h.records); err != nil {
+		log.Warnf("Failed to export %d log records to --otlp-logs-endpoint: %v", len(h.records), err)
+	}
+	h.records = nil
+}
+
+// Close sends any pending log records.
+func (h *otlpLogsHook) Close() {
+	if h == nil {
+		return
+	}
+	h.flush()
+}
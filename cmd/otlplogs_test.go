@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestOTLPLogsHook(t *testing.T) {
+	defer env.PatchAll(t, map[string]string{
+		"OTEL_EXPORTER_OTLP_HEADERS": "x-api-key=secret",
+		"OTEL_SERVICE_NAME":          "",
+	})()
+	var requests []otlpRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Check(t, r.Header.Get("x-api-key") == "secret")
+		var req otlpRequest
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Check(t, json.Unmarshal(body, &req))
+		requests = append(requests, req)
+	}))
+	defer srv.Close()
+
+	hook, err := newOTLPLogsHook(&options{otlpLogsEndpoint: srv.URL, runID: "run-1"})
+	assert.NilError(t, err)
+	_, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestFails"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestFails","Output":"=== RUN   TestFails\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestFails","Output":"    client.go:10: traceparent: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestFails","Output":"--- FAIL: TestFails (0.10s)\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFails","Elapsed":0.1}
+{"Action":"run","Package":"example.com/pkg","Test":"TestPasses"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestPasses","Output":"--- PASS: TestPasses (0.00s)\n"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestPasses","Elapsed":0}
+{"Action":"output","Package":"example.com/pkg","Output":"FAIL\n"}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.2}
+{"Action":"output","Package":"example.com/other","Output":"ok\n"}
+{"Action":"pass","Package":"example.com/other","Elapsed":0.2}
+`),
+		Handler: &otlpLogsHandler{hook: hook},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(requests), 0, "records are sent when the hook is closed")
+	hook.Close()
+
+	assert.Equal(t, len(requests), 1)
+	rl := requests[0].ResourceLogs[0]
+	assert.DeepEqual(t, rl.Resource.Attributes,
+		[]otlpKeyValue{{Key: "service.name", Value: otlpValue{StringValue: "gotestsum"}}})
+
+	records := rl.ScopeLogs[0].LogRecords
+	assert.Equal(t, len(records), 3)
+	assert.Equal(t, records[0].SeverityText, "ERROR")
+	assert.Equal(t, records[0].Body.StringValue, `=== RUN   TestFails
+    client.go:10: traceparent: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+--- FAIL: TestFails (0.10s)
+`)
+	assert.Equal(t, records[0].TraceID, "4bf92f3577b34da6a3ce929d0e0e4736")
+	assert.Equal(t, records[0].SpanID, "00f067aa0ba902b7")
+	assert.DeepEqual(t, records[0].Attributes, []otlpKeyValue{
+		{Key: "test.package", Value: otlpValue{StringValue: "example.com/pkg"}},
+		{Key: "test.name", Value: otlpValue{StringValue: "TestFails"}},
+		{Key: "test.result", Value: otlpValue{StringValue: "fail"}},
+		{Key: "test.elapsed", Value: otlpValue{StringValue: "0.100s"}},
+		{Key: "gotestsum.run_id", Value: otlpValue{StringValue: "run-1"}},
+	})
+
+	assert.Equal(t, records[1].SeverityText, "INFO")
+	assert.Equal(t, records[1].Body.StringValue, "--- PASS: TestPasses (0.00s)\n")
+	assert.Equal(t, records[1].TraceID, "")
+
+	assert.Equal(t, records[2].SeverityText, "ERROR")
+	assert.Equal(t, records[2].Body.StringValue, "FAIL\n")
+	assert.Equal(t, records[2].Attributes[1].Key, "test.result")
+}
+
+func TestNewOTLPLogsHook_NotEnabled(t *testing.T) {
+	hook, err := newOTLPLogsHook(&options{})
+	assert.NilError(t, err)
+	assert.Assert(t, hook == nil)
+}
+
+func TestOptions_Validate_OTLPLogsEndpoint(t *testing.T) {
+	opts := &options{otlpLogsEndpoint: "localhost:4318"}
+	err := opts.Validate()
+	assert.ErrorContains(t, err, "invalid --otlp-logs-endpoint")
+}
+
+type otlpLogsHandler struct {
+	hook *otlpLogsHook
+}
+
+func (h *otlpLogsHandler) Event(event testjson.TestEvent, _ *testjson.Execution) error {
+	h.hook.Event(event)
+	return nil
+}
+
+func (h *otlpLogsHandler) Err(string) error {
+	return nil
+}
+
+type otlpRequest struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []otlpKeyValue
+		}
+		ScopeLogs []struct {
+			LogRecords []struct {
+				SeverityText string
+				Body         otlpValue
+				Attributes   []otlpKeyValue
+				TraceID      string
+				SpanID       string
+			}
+		}
+	}
+}
+
+type otlpKeyValue struct {
+	Key   string
+	Value otlpValue
+}
+
+type otlpValue struct {
+	StringValue string
+}
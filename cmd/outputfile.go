@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"gotest.tools/gotestsum/log"
+)
+
+// outputPathTimestampFormat is the format used for {{.Timestamp}} in the path
+// of output files. It sorts lexically, and is safe to use in a filename.
+const outputPathTimestampFormat = "20060102T150405"
+
+// outputPathData is the data used to execute the template in the path of an
+// output file.
+type outputPathData struct {
+	Timestamp string
+	RunID     string
+	gitSHA    func() string
+}
+
+// GitSHA returns the short git commit SHA of HEAD in the working directory.
+func (d outputPathData) GitSHA() string {
+	return d.gitSHA()
+}
+
+func newOutputPathData(now time.Time, runID string) outputPathData {
+	var sha string
+	return outputPathData{
+		Timestamp: now.Format(outputPathTimestampFormat),
+		RunID:     runID,
+		gitSHA: func() string {
+			if sha == "" {
+				sha = gitShortSHA()
+			}
+			return sha
+		},
+	}
+}
+
+func gitShortSHA() string {
+	log.Debugf("exec: git rev-parse --short HEAD")
+	out, err := exec.Command("git", "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		log.Warnf("Failed to lookup git SHA for output file path: %v", err)
+		return "unknown"
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// isOutputPathTemplate returns true if the path contains a template action.
+func isOutputPathTemplate(path string) bool {
+	return strings.Contains(path, "{{")
+}
+
+func executeOutputPath(path string, data interface{}) (string, error) {
+	tmpl, err := template.New("output-path").Parse(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse output file path %v: %w", path, err)
+	}
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute output file path %v: %w", path, err)
+	}
+	return buf.String(), nil
+}
+
+// outputFileFlag is the name of a flag, and a pointer to the option which is
+// the path of a file written by gotestsum.
+type outputFileFlag struct {
+	name string
+	path *string
+}
+
+// outputFileFlags returns the flags which are the paths of files written by
+// gotestsum.
+func outputFileFlags(opts *options) []outputFileFlag {
+	return []outputFileFlag{
+		{name: "jsonfile", path: &opts.jsonFile},
+		{name: "junitfile", path: &opts.junitFile},
+		{name: "html-report", path: &opts.htmlReportFile},
+		{name: "markdown-summary", path: &opts.markdownSummaryFile},
+		{name: "rerun-fails-report", path: &opts.rerunFailsReportFile},
+		{name: "metrics-file", path: &opts.metricsFile},
+		{name: "provenance", path: &opts.provenanceFile},
+	}
+}
+
+// outputFilePaths returns pointers to the options which are the paths of files
+// written by gotestsum.
+func outputFilePaths(opts *options) []*string {
+	flags := outputFileFlags(opts)
+	paths := make([]*string, 0, len(flags))
+	for _, flag := range flags {
+		paths = append(paths, flag.path)
+	}
+	return paths
+}
+
+// resolveOutputPaths executes the template in the path of every output file.
+// The original template is saved so that old files can be removed by
+// removeOldOutputFiles.
+func resolveOutputPaths(opts *options, now time.Time) error {
+	data := newOutputPathData(now, opts.runID)
+	for _, path := range outputFilePaths(opts) {
+		if !isOutputPathTemplate(*path) {
+			continue
+		}
+		resolved, err := executeOutputPath(*path, data)
+		if err != nil {
+			return err
+		}
+		opts.outputPathTemplates = append(opts.outputPathTemplates, *path)
+		*path = resolved
+	}
+	if isOutputPathTemplate(opts.upload) {
+		resolved, err := executeOutputPath(opts.upload, data)
+		if err != nil {
+			return err
+		}
+		opts.upload = resolved
+	}
+	return nil
+}
+
+// createOutputFile creates the file at path, and any missing parent directories.
+func createOutputFile(path string) (*os.File, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory %v: %w", dir, err)
+		}
+	}
+	return os.Create(path)
+}
+
+// removeOldOutputFiles removes all except the most recent opts.outputKeep files
+// which match one of the output path templates.
+func removeOldOutputFiles(opts *options) error {
+	if opts.outputKeep <= 0 {
+		return nil
+	}
+	glob := outputPathData{
+		Timestamp: "*",
+		RunID:     "*",
+		gitSHA:    func() string { return "*" },
+	}
+	for _, path := range opts.outputPathTemplates {
+		pattern, err := executeOutputPath(path, glob)
+		if err != nil {
+			return err
+		}
+		if err := removeAllExceptRecent(pattern, opts.outputKeep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeAllExceptRecent(pattern string, keep int) error {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	if len(matches) <= keep {
+		return nil
+	}
+
+	modTimes := make(map[string]time.Time, len(matches))
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			return err
+		}
+		modTimes[match] = info.ModTime()
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if modTimes[matches[i]].Equal(modTimes[matches[j]]) {
+			return matches[i] > matches[j]
+		}
+		return modTimes[matches[i]].After(modTimes[matches[j]])
+	})
+	for _, match := range matches[keep:] {
+		log.Debugf("removing old output file %v", match)
+		if err := os.RemoveAll(match); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestResolveOutputPaths(t *testing.T) {
+	opts := &options{
+		jsonFile:  "out/{{.Timestamp}}/events.json",
+		junitFile: "junit.xml",
+	}
+	now := time.Date(2021, 6, 1, 10, 11, 12, 0, time.UTC)
+	assert.NilError(t, resolveOutputPaths(opts, now))
+
+	assert.Equal(t, opts.jsonFile, "out/20210601T101112/events.json")
+	assert.Equal(t, opts.junitFile, "junit.xml")
+	assert.DeepEqual(t, opts.outputPathTemplates, []string{"out/{{.Timestamp}}/events.json"})
+}
+
+func TestResolveOutputPaths_InvalidTemplate(t *testing.T) {
+	opts := &options{junitFile: "{{.Bogus}}.xml"}
+	err := resolveOutputPaths(opts, time.Now())
+	assert.ErrorContains(t, err, "failed to execute output file path {{.Bogus}}.xml")
+}
+
+func TestCreateOutputFile_CreatesParentDirectories(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+
+	fh, err := createOutputFile(dir.Join("a", "b", "junit.xml"))
+	assert.NilError(t, err)
+	assert.NilError(t, fh.Close())
+
+	_, err = os.Stat(dir.Join("a", "b", "junit.xml"))
+	assert.NilError(t, err)
+}
+
+func TestRemoveOldOutputFiles(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("events-20210601T100000.json", ""),
+		fs.WithFile("events-20210602T100000.json", ""),
+		fs.WithFile("events-20210603T100000.json", ""),
+		fs.WithFile("other.json", ""))
+	defer dir.Remove()
+
+	for i, name := range []string{
+		"events-20210601T100000.json",
+		"events-20210602T100000.json",
+		"events-20210603T100000.json",
+	} {
+		mtime := time.Now().Add(time.Duration(i-3) * time.Hour)
+		assert.NilError(t, os.Chtimes(dir.Join(name), mtime, mtime))
+	}
+
+	opts := &options{
+		outputKeep:          2,
+		outputPathTemplates: []string{filepath.Join(dir.Path(), "events-{{.Timestamp}}.json")},
+	}
+	assert.NilError(t, removeOldOutputFiles(opts))
+
+	expected := fs.Expected(t,
+		fs.WithFile("events-20210602T100000.json", ""),
+		fs.WithFile("events-20210603T100000.json", ""),
+		fs.WithFile("other.json", ""))
+	assert.Assert(t, fs.Equal(dir.Path(), expected))
+}
@@ -0,0 +1,304 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// unownedTeam is the heading used for packages which do not match any of the
+// patterns in the --owners file.
+const unownedTeam = "unowned"
+
+// ownersSlowestCount is the number of the slowest tests listed for each team.
+const ownersSlowestCount = 3
+
+// ownerRule assigns the packages which match any of patterns to team.
+type ownerRule struct {
+	team     string
+	patterns []string
+}
+
+// owners is the mapping of teams to packages read from the --owners file.
+type owners []ownerRule
+
+// readOwnersFile reads the --owners file. Each line is the name of a team
+// followed by one or more package patterns, separated by whitespace. Blank
+// lines and lines that start with # are ignored.
+func readOwnersFile(filename string) (owners, error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close() // nolint: errcheck
+
+	var result owners
+	scanner := bufio.NewScanner(fh)
+	var lineNum int
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%v:%d: expected a team followed by package patterns", filename, lineNum)
+		}
+		for _, pattern := range fields[1:] {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("%v:%d: invalid package pattern %v: %w", filename, lineNum, pattern, err)
+			}
+		}
+		result = append(result, ownerRule{team: fields[0], patterns: fields[1:]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %v: %w", filename, err)
+	}
+	return result, nil
+}
+
+// teamOf returns the team of the first rule with a pattern that matches pkg,
+// or unownedTeam if no pattern matches.
+func (o owners) teamOf(pkg string) string {
+	for _, rule := range o {
+		for _, pattern := range rule.patterns {
+			if matchPackagePattern(pattern, pkg) {
+				return rule.team
+			}
+		}
+	}
+	return unownedTeam
+}
+
+// matchPackagePattern returns true if pkg matches pattern. The pattern uses
+// the syntax of path.Match. A pattern that ends with /... also matches all the
+// packages in sub-directories, the same as a go package pattern.
+func matchPackagePattern(pattern, pkg string) bool {
+	if !strings.HasSuffix(pattern, "/...") {
+		ok, _ := path.Match(pattern, pkg)
+		return ok
+	}
+	prefix := strings.TrimSuffix(pattern, "/...")
+	n := strings.Count(prefix, "/") + 1
+	segments := strings.Split(pkg, "/")
+	if len(segments) < n {
+		return false
+	}
+	ok, _ := path.Match(prefix, strings.Join(segments[:n], "/"))
+	return ok
+}
+
+// teamReport is the results of the tests in the packages owned by a team.
+type teamReport struct {
+	team     string
+	packages int
+	// failed are the tests which failed in every run.
+	failed []testjson.TestCase
+	// flaky are the tests which failed, and then passed when they were re-run.
+	flaky []testjson.TestCase
+	// slowest are the slowest root tests, sorted by elapsed time.
+	slowest []testjson.TestCase
+}
+
+// newTeamReports returns a report for each team which owns at least one of the
+// packages in execs, sorted by the name of the team. Packages that are not
+// owned by a team are reported last, under unownedTeam.
+func newTeamReports(o owners, execs ...*testjson.Execution) []teamReport {
+	byTeam := make(map[string]*teamReport)
+	for _, exec := range execs {
+		for _, name := range exec.Packages() {
+			team := o.teamOf(name)
+			report, ok := byTeam[team]
+			if !ok {
+				report = &teamReport{team: team}
+				byTeam[team] = report
+			}
+			report.addPackage(exec.Package(name))
+		}
+	}
+
+	reports := make([]teamReport, 0, len(byTeam))
+	for _, report := range byTeam {
+		sort.SliceStable(report.slowest, func(i, j int) bool {
+			return report.slowest[i].Elapsed > report.slowest[j].Elapsed
+		})
+		if len(report.slowest) > ownersSlowestCount {
+			report.slowest = report.slowest[:ownersSlowestCount]
+		}
+		reports = append(reports, *report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if (reports[i].team == unownedTeam) != (reports[j].team == unownedTeam) {
+			return reports[j].team == unownedTeam
+		}
+		return reports[i].team < reports[j].team
+	})
+	return reports
+}
+
+func (r *teamReport) addPackage(pkg *testjson.Package) {
+	r.packages++
+	passed := make(map[testjson.TestName]bool, len(pkg.Passed))
+	for _, tc := range pkg.Passed {
+		passed[tc.Test] = true
+	}
+	seen := make(map[testjson.TestName]bool)
+	for _, tc := range testjson.FilterFailedUnique(pkg.Failed) {
+		if seen[tc.Test] {
+			continue
+		}
+		seen[tc.Test] = true
+		if passed[tc.Test] {
+			r.flaky = append(r.flaky, tc)
+			continue
+		}
+		r.failed = append(r.failed, tc)
+	}
+	for _, tcs := range [][]testjson.TestCase{pkg.Passed, pkg.Failed} {
+		for _, tc := range tcs {
+			if !tc.Test.IsSubTest() {
+				r.slowest = append(r.slowest, tc)
+			}
+		}
+	}
+}
+
+// teamSection is a list of tests in a teamReport.
+type teamSection struct {
+	heading string
+	prefix  string
+	tcs     []testjson.TestCase
+	// withLink adds the --link-template link to each test in the Markdown.
+	withLink bool
+}
+
+func (r teamReport) sections() []teamSection {
+	return []teamSection{
+		{heading: "Failed", prefix: "FAIL ", tcs: r.failed, withLink: true},
+		{heading: "Flaky", prefix: "FLAKY", tcs: r.flaky, withLink: true},
+		{heading: "Slowest", prefix: "SLOW ", tcs: r.slowest},
+	}
+}
+
+// printOwnersSummary prints a section for each team with the failed, flaky,
+// and slowest tests in the packages owned by the team.
+func printOwnersSummary(out io.Writer, reports []teamReport) {
+	for _, report := range reports {
+		fmt.Fprintf(out, "\n=== Team %s (%d %s)\n", report.team, report.packages,
+			pluralize(report.packages, "package", "packages"))
+		for _, section := range report.sections() {
+			for _, tc := range section.tcs {
+				fmt.Fprintf(out, "%s %s %s (%s)\n", section.prefix,
+					testjson.RelativePackagePath(tc.Package), tc.Test,
+					testjson.FormatDurationAsSeconds(tc.Elapsed, 2))
+			}
+		}
+	}
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
+
+// writeOwnersMarkdown writes a Markdown file for each team to dir, so that the
+// results can be sent to a different channel for each team.
+func writeOwnersMarkdown(dir string, reports []teamReport, formatOpts testjson.FormatOptions) error {
+	for _, report := range reports {
+		filename := filepath.Join(dir, teamFilename(report.team)+".md")
+		fh, err := createOutputFile(filename)
+		if err != nil {
+			return err
+		}
+		writeTeamMarkdown(fh, report, formatOpts)
+		if err := fh.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTeamMarkdown(out io.Writer, report teamReport, formatOpts testjson.FormatOptions) {
+	fmt.Fprintf(out, "# Test results for %s\n\n", report.team)
+	fmt.Fprintf(out, "%d failed, %d flaky, in %d %s\n", len(report.failed), len(report.flaky),
+		report.packages, pluralize(report.packages, "package", "packages"))
+
+	for _, section := range report.sections() {
+		if len(section.tcs) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "\n## %s\n\n", section.heading)
+		fmt.Fprintln(out, "| Package | Test | Elapsed |")
+		fmt.Fprintln(out, "|---------|------|---------|")
+		for _, tc := range section.tcs {
+			name := markdownCell(string(tc.Test))
+			if section.withLink {
+				if link := formatOpts.Link(tc); link != "" {
+					name = "[" + name + "](" + link + ")"
+				}
+			}
+			fmt.Fprintf(out, "| %s | %s | %s |\n",
+				markdownCell(tc.Package), name,
+				testjson.FormatDurationAsSeconds(tc.Elapsed, 2))
+		}
+	}
+}
+
+var markdownCellEscaper = strings.NewReplacer("|", `\|`, "\n", " ")
+
+func markdownCell(s string) string {
+	return markdownCellEscaper.Replace(s)
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// teamFilename returns the name of the team with any characters which are not
+// safe to use in a filename replaced by '-'.
+func teamFilename(team string) string {
+	return unsafeFilenameChars.ReplaceAllString(team, "-")
+}
+
+// finishOwners prints the owners summary and writes the Markdown files for the
+// teams, when --owners is set.
+func finishOwners(opts *options, execs ...*testjson.Execution) error {
+	if opts.ownersFile == "" {
+		return nil
+	}
+	o, err := readOwnersFile(opts.ownersFile)
+	if err != nil {
+		return fmt.Errorf("failed to read owners file: %w", err)
+	}
+	reports := newTeamReports(o, execs...)
+	if !opts.scriptOutput {
+		printOwnersSummary(opts.stdout, reports)
+	}
+	if opts.ownersMarkdownDir == "" {
+		return nil
+	}
+	if err := writeOwnersMarkdown(opts.ownersMarkdownDir, reports, opts.formatOptions); err != nil {
+		return fmt.Errorf("failed to write owners markdown: %w", err)
+	}
+	return nil
+}
+
+func validateOwners(opts *options) error {
+	if opts.ownersFile == "" {
+		if opts.ownersMarkdownDir != "" {
+			return fmt.Errorf("--owners-markdown-dir requires --owners")
+		}
+		return nil
+	}
+	_, err := readOwnersFile(opts.ownersFile)
+	return err
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestMatchPackagePattern(t *testing.T) {
+	type testCase struct {
+		pattern  string
+		pkg      string
+		expected bool
+	}
+	for _, tc := range []testCase{
+		{pattern: "example.com/db", pkg: "example.com/db", expected: true},
+		{pattern: "example.com/db", pkg: "example.com/db/sql"},
+		{pattern: "example.com/db/...", pkg: "example.com/db", expected: true},
+		{pattern: "example.com/db/...", pkg: "example.com/db/sql/driver", expected: true},
+		{pattern: "example.com/db/...", pkg: "example.com/dbx"},
+		{pattern: "example.com/*/api", pkg: "example.com/users/api", expected: true},
+		{pattern: "example.com/*/api", pkg: "example.com/users/api/v2"},
+		{pattern: "example.com/*/api/...", pkg: "example.com/users/api/v2", expected: true},
+	} {
+		t.Run(tc.pattern+" "+tc.pkg, func(t *testing.T) {
+			assert.Equal(t, matchPackagePattern(tc.pattern, tc.pkg), tc.expected)
+		})
+	}
+}
+
+func TestReadOwnersFile(t *testing.T) {
+	file := fs.NewFile(t, t.Name(), fs.WithContent(`
+# team   packages
+storage  example.com/db/...  example.com/cache
+web      example.com/*/api
+`))
+	defer file.Remove()
+
+	o, err := readOwnersFile(file.Path())
+	assert.NilError(t, err)
+	assert.Equal(t, o.teamOf("example.com/db/sql"), "storage")
+	assert.Equal(t, o.teamOf("example.com/cache"), "storage")
+	assert.Equal(t, o.teamOf("example.com/users/api"), "web")
+	assert.Equal(t, o.teamOf("example.com/tools"), unownedTeam)
+}
+
+func TestReadOwnersFile_Invalid(t *testing.T) {
+	file := fs.NewFile(t, t.Name(), fs.WithContent("storage\n"))
+	defer file.Remove()
+
+	_, err := readOwnersFile(file.Path())
+	assert.ErrorContains(t, err, ":1: expected a team followed by package patterns")
+}
+
+const ownersInput = `{"Action":"run","Package":"example.com/db","Test":"TestBroken"}
+{"Action":"fail","Package":"example.com/db","Test":"TestBroken","Elapsed":0.5}
+{"Action":"run","Package":"example.com/db","Test":"TestFlaky"}
+{"Action":"fail","Package":"example.com/db","Test":"TestFlaky","Elapsed":0.25}
+{"Action":"run","Package":"example.com/db","Test":"TestSlow"}
+{"Action":"pass","Package":"example.com/db","Test":"TestSlow","Elapsed":3}
+{"Action":"fail","Package":"example.com/db","Elapsed":4}
+{"Action":"run","Package":"example.com/db","Test":"TestFlaky"}
+{"Action":"pass","Package":"example.com/db","Test":"TestFlaky","Elapsed":0.25}
+{"Action":"pass","Package":"example.com/db","Elapsed":1}
+{"Action":"run","Package":"example.com/tools","Test":"TestTool"}
+{"Action":"pass","Package":"example.com/tools","Test":"TestTool","Elapsed":0.1}
+{"Action":"pass","Package":"example.com/tools","Elapsed":0.1}
+`
+
+func TestFinishOwners(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(ownersInput),
+	})
+	assert.NilError(t, err)
+
+	ownersFile := fs.NewFile(t, t.Name(), fs.WithContent("storage example.com/db/...\n"))
+	defer ownersFile.Remove()
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		ownersFile:        ownersFile.Path(),
+		ownersMarkdownDir: dir.Path(),
+		stdout:            out,
+	}
+	assert.NilError(t, finishOwners(opts, exec))
+
+	expected := `
+=== Team storage (1 package)
+FAIL  example.com/db TestBroken (0.50s)
+FLAKY example.com/db TestFlaky (0.25s)
+SLOW  example.com/db TestSlow (3.00s)
+SLOW  example.com/db TestBroken (0.50s)
+SLOW  example.com/db TestFlaky (0.25s)
+
+=== Team unowned (1 package)
+SLOW  example.com/tools TestTool (0.10s)
+`
+	assert.Equal(t, out.String(), expected)
+
+	raw, err := ioutil.ReadFile(dir.Join("storage.md"))
+	assert.NilError(t, err)
+	expectedMarkdown := `# Test results for storage
+
+1 failed, 1 flaky, in 1 package
+
+## Failed
+
+| Package | Test | Elapsed |
+|---------|------|---------|
+| example.com/db | TestBroken | 0.50s |
+
+## Flaky
+
+| Package | Test | Elapsed |
+|---------|------|---------|
+| example.com/db | TestFlaky | 0.25s |
+
+## Slowest
+
+| Package | Test | Elapsed |
+|---------|------|---------|
+| example.com/db | TestSlow | 3.00s |
+| example.com/db | TestBroken | 0.50s |
+| example.com/db | TestFlaky | 0.25s |
+`
+	assert.Equal(t, string(raw), expectedMarkdown)
+
+	_, err = ioutil.ReadFile(dir.Join("unowned.md"))
+	assert.NilError(t, err)
+}
+
+func TestOptions_Validate_Owners(t *testing.T) {
+	opts := &options{ownersMarkdownDir: "owners"}
+	assert.ErrorContains(t, opts.Validate(), "--owners-markdown-dir requires --owners")
+}
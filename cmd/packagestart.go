@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// setupPackageTestCounts runs 'go test -list' with the same packages and flags
+// as the test run, to count the number of tests in each package for the lines
+// printed by --format-package-start. The counts are only used for display, so
+// a failure to list the tests is logged instead of stopping the run. A package
+// which fails to build is reported by the test run.
+func setupPackageTestCounts(ctx context.Context, opts *options) {
+	if !opts.packageStartTests {
+		return
+	}
+	counts, err := listPackageTests(ctx, opts)
+	if err != nil {
+		log.Warnf("failed to count the tests in each package: %v", err)
+		return
+	}
+	opts.formatOptions.PackageTestCounts = testjson.NewPackageTestCounts(counts)
+}
+
+// listPackageTests returns the number of tests, examples, and fuzz tests in
+// each package. The -run flag does not apply to -list, so the count includes
+// tests which are not selected by -run.
+func listPackageTests(ctx context.Context, opts *options) (map[string]int, error) {
+	args := goTestCmdArgs(opts, rerunOpts{})
+	args = append([]string{args[0], args[1], "-list=."}, args[2:]...)
+	goTestProc, err := startGoTestFn(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	exec, err := testjson.ScanTestOutputContext(ctx, testjson.ScanConfig{
+		Stdout: goTestProc.stdout,
+		Stderr: goTestProc.stderr,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := goTestProc.cmd.Wait(); err != nil {
+		log.Debugf("go test -list: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		if pkg.Result() == testjson.ActionFail {
+			continue
+		}
+		counts[name] = countListedTests(pkg.Output(0))
+	}
+	return counts, nil
+}
+
+// countListedTests returns the number of test names in the output of
+// 'go test -list'. Benchmarks are not counted because they are not run
+// without -bench.
+func countListedTests(output string) int {
+	var count int
+	for _, line := range strings.Split(output, "\n") {
+		for _, prefix := range []string{"Test", "Example", "Fuzz"} {
+			if strings.HasPrefix(line, prefix) && !strings.Contains(line, " ") {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+func validatePackageStart(opts *options) error {
+	switch {
+	case !opts.packageStartTests:
+		return nil
+	case !opts.formatOptions.ShowPackageStart:
+		return fmt.Errorf("--format-package-start-tests requires --format-package-start")
+	case opts.rawCommand:
+		return fmt.Errorf("--format-package-start-tests can not be used with --raw-command")
+	case len(opts.pkgGroups) > 0:
+		return fmt.Errorf("--format-package-start-tests can not be used with --pkg-group")
+	}
+	return nil
+}
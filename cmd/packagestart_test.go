@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestSetupPackageTestCounts(t *testing.T) {
+	var args []string
+	fn := func(a []string) *proc {
+		args = a
+		out := `{"Action":"start","Package":"example.com/one"}
+{"Action":"output","Package":"example.com/one","Output":"TestOne\n"}
+{"Action":"output","Package":"example.com/one","Output":"TestTwo\n"}
+{"Action":"output","Package":"example.com/one","Output":"BenchmarkOne\n"}
+{"Action":"output","Package":"example.com/one","Output":"ExampleOne\n"}
+{"Action":"output","Package":"example.com/one","Output":"FuzzOne\n"}
+{"Action":"output","Package":"example.com/one","Output":"ok  \texample.com/one\t0.003s\n"}
+{"Action":"pass","Package":"example.com/one","Elapsed":0.003}
+{"Action":"start","Package":"example.com/empty"}
+{"Action":"output","Package":"example.com/empty","Output":"?   \texample.com/empty\t[no test files]\n"}
+{"Action":"skip","Package":"example.com/empty","Elapsed":0}
+{"Action":"start","Package":"example.com/broken"}
+{"Action":"output","Package":"example.com/broken","Output":"FAIL\texample.com/broken [build failed]\n"}
+{"Action":"fail","Package":"example.com/broken","Elapsed":0}
+`
+		return &proc{
+			cmd:    fakeWaiter{},
+			stdout: strings.NewReader(out),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	defer patchStartGoTestFn(fn)()
+
+	opts := &options{
+		packageStartTests: true,
+		args:              []string{"-tags=integration"},
+		packages:          []string{"./..."},
+	}
+	setupPackageTestCounts(context.Background(), opts)
+	assert.DeepEqual(t, args,
+		[]string{"go", "test", "-list=.", "-json", "-tags=integration", "./..."})
+
+	counts := opts.formatOptions.PackageTestCounts
+	count, ok := counts.Lookup("example.com/one")
+	assert.Assert(t, ok)
+	assert.Equal(t, count, 4)
+	count, ok = counts.Lookup("example.com/empty")
+	assert.Assert(t, ok)
+	assert.Equal(t, count, 0)
+	_, ok = counts.Lookup("example.com/broken")
+	assert.Assert(t, !ok)
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"strings"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// pathMapValue is a flag.Value which appends a testjson.PathMapping for each
+// value. The format of the value is: FROM=>TO.
+type pathMapValue []testjson.PathMapping
+
+func (v *pathMapValue) String() string {
+	values := make([]string, 0, len(*v))
+	for _, m := range *v {
+		values = append(values, m.From+"=>"+m.To)
+	}
+	return strings.Join(values, ",")
+}
+
+func (v *pathMapValue) Set(raw string) error {
+	m, err := testjson.ParsePathMapping(raw)
+	if err != nil {
+		return err
+	}
+	*v = append(*v, m)
+	return nil
+}
+
+func (v *pathMapValue) Type() string {
+	return "from=>to"
+}
@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// pkgGroup is a list of packages to test with 'go test', and the args used to
+// test those packages.
+type pkgGroup struct {
+	name     string
+	packages []string
+	args     []string
+}
+
+// pkgGroupsValue is a flag.Value which appends a pkgGroup for each value. The
+// format of the value is: NAME=PACKAGES [: ARGS].
+type pkgGroupsValue []pkgGroup
+
+func (v *pkgGroupsValue) String() string {
+	groups := make([]string, 0, len(*v))
+	for _, g := range *v {
+		groups = append(groups, g.name)
+	}
+	return strings.Join(groups, ",")
+}
+
+func (v *pkgGroupsValue) Set(raw string) error {
+	name, rest := cutString(raw, "=")
+	name = strings.TrimSpace(name)
+	if name == "" || rest == "" {
+		return errors.Errorf("invalid value %q, must be NAME=PACKAGES [: ARGS]", raw)
+	}
+	for _, g := range *v {
+		if g.name == name {
+			return errors.Errorf("duplicate package group name %v", name)
+		}
+	}
+	pkgs, args := cutString(rest, ":")
+	group := pkgGroup{
+		name:     name,
+		packages: strings.Fields(pkgs),
+		args:     strings.Fields(args),
+	}
+	if len(group.packages) == 0 {
+		return errors.Errorf("package group %v must have at least one package", name)
+	}
+	*v = append(*v, group)
+	return nil
+}
+
+func (v *pkgGroupsValue) Type() string {
+	return "group"
+}
+
+func cutString(s, sep string) (string, string) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):]
+	}
+	return s, ""
+}
+
+func validatePkgGroups(opts *options) error {
+	if len(opts.pkgGroups) == 0 {
+		return nil
+	}
+	var unsupported string
+	switch {
+	case opts.rawCommand:
+		unsupported = "--raw-command"
+	case maxRerunAttempts(opts) > 0:
+		unsupported = "--rerun-fails"
+	case opts.watch:
+		unsupported = "--watch"
+	case opts.archiveDir != "":
+		unsupported = "--archive-dir"
+	case len(opts.packages) > 0:
+		unsupported = "--packages"
+	}
+	if unsupported != "" {
+		return fmt.Errorf("%v can not be used with --pkg-group", unsupported)
+	}
+	return nil
+}
+
+type pkgGroupResult struct {
+	group pkgGroup
+	exec  *testjson.Execution
+	err   error
+}
+
+// runPkgGroups runs 'go test' once for each package group. The events from
+// every group are sent to the same handler, and a summary is printed for each
+// group once all the groups have run.
+func runPkgGroups(ctx context.Context, opts *options, handler *eventHandler) error {
+	var results []pkgGroupResult
+	for _, group := range opts.pkgGroups {
+		groupOpts := *opts
+		groupOpts.packages = group.packages
+		groupOpts.args = append(append([]string{}, group.args...), opts.args...)
+
+		if err := printPkgGroupHeader(opts, group.name); err != nil {
+			return err
+		}
+		exec, err := runPkgGroup(ctx, &groupOpts, handler, group.name)
+		if exec == nil {
+			return err
+		}
+		results = append(results, pkgGroupResult{group: group, exec: exec, err: err})
+		// Stop running groups if the error was not a test failure, ex: --max-fails
+		if err != nil && !IsExitCoder(err) {
+			break
+		}
+	}
+	return finishPkgGroups(opts, results)
+}
+
+func runPkgGroup(ctx context.Context, opts *options, handler *eventHandler, name string) (*testjson.Execution, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	goTestProc, err := startGoTestFn(ctx, goTestCmdArgs(opts, rerunOpts{}))
+	if err != nil {
+		return nil, err
+	}
+	cfg := testjson.ScanConfig{
+		Stdout:                   goTestProc.stdout,
+		Stderr:                   goTestProc.stderr,
+		Handler:                  handler,
+		Stop:                     cancel,
+		IgnoreNonJSONOutputLines: opts.ignoreNonJSONOutputLines,
+		DemoteTeardownFailures:   opts.teardownFailures.demote(),
+		KeepPassedOutput:         opts.formatOptions.ShowOutput.Passed(),
+		RewriteTestName:          newTestNameRewriter(opts),
+		RewriteOutput:            newOutputRewriter(opts),
+		Clock:                    opts.clock.newClock(),
+		InputAdapter:             newInputAdapter(opts),
+		StderrMerge:              opts.stderrMerge.merge(),
+	}
+	exec, err := testjson.ScanTestOutputContext(ctx, cfg)
+	if err != nil {
+		return exec, err
+	}
+	if err := goTestProc.cmd.Wait(); err != nil {
+		log.Debugf("package group %v failed: %v", name, err)
+		return exec, err
+	}
+	return exec, nil
+}
+
+// finishPkgGroups reports the results of all the package groups as a single
+// run. The summary, JUnit file, and post run command are produced for each
+// group, and everything else uses the merged Execution of all the groups.
+func finishPkgGroups(opts *options, results []pkgGroupResult) error {
+	var exitErr error
+	execs := make([]*testjson.Execution, 0, len(results))
+	for _, result := range results {
+		execs = append(execs, result.exec)
+		err := teardownFailuresExitErr(opts, result.exec, result.err)
+		err = knownIssuesExitErr(opts, result.exec, err)
+		err = allowedFailuresExitErr(opts, result.exec, err)
+		err = strictStderrExitErr(opts, result.exec, err)
+		if exitErr == nil {
+			exitErr = err
+		}
+	}
+	opts.pkgGroupResults = results
+	return finishRun(opts, testjson.MergeExecutions(execs...), exitErr)
+}
+
+// pkgGroupExecs returns the Execution of each package group, or exec when
+// the run did not use package groups.
+func pkgGroupExecs(opts *options, exec *testjson.Execution) []*testjson.Execution {
+	if len(opts.pkgGroupResults) == 0 {
+		return []*testjson.Execution{exec}
+	}
+	execs := make([]*testjson.Execution, 0, len(opts.pkgGroupResults))
+	for _, result := range opts.pkgGroupResults {
+		execs = append(execs, result.exec)
+	}
+	return execs
+}
+
+// printRunSummary prints the summary of exec, or a summary for each package
+// group when the run used package groups.
+func printRunSummary(opts *options, exec *testjson.Execution) {
+	if len(opts.pkgGroupResults) == 0 {
+		printSummary(opts, exec)
+		return
+	}
+	for _, result := range opts.pkgGroupResults {
+		fmt.Fprintf(opts.stdout, "\n=== Group %s\n", result.group.name)
+		printSummary(opts, result.exec)
+	}
+}
+
+// printScriptRunSummary writes the --script-output summary of exec. When the
+// run used package groups, the ERROR lines of each group follow the GROUP line
+// of the group, and the DONE line has the totals of every group.
+func printScriptRunSummary(opts *options, exec *testjson.Execution) error {
+	if len(opts.pkgGroupResults) == 0 {
+		return printScriptSummary(opts.stdout, exec)
+	}
+	for _, result := range opts.pkgGroupResults {
+		if err := writeScriptGroup(opts.stdout, result.group.name); err != nil {
+			return err
+		}
+		if err := writeScriptErrors(opts.stdout, result.exec); err != nil {
+			return err
+		}
+	}
+	return writeScriptDone(opts.stdout, pkgGroupExecs(opts, exec)...)
+}
+
+// printPkgGroupHeader prints the line that starts the events of a package
+// group, as a GROUP line when the output is --script-output.
+func printPkgGroupHeader(opts *options, name string) error {
+	if opts.scriptOutput {
+		if err := writeScriptGroup(opts.stdout, name); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		return nil
+	}
+	fmt.Fprintf(opts.stdout, "=== Group %s\n", name)
+	return nil
+}
+
+// postRunHooks runs the --post-run-command once for exec, or once for each
+// package group when the run used package groups.
+func postRunHooks(opts *options, exec *testjson.Execution, exitErr error) error {
+	if len(opts.pkgGroupResults) == 0 {
+		return postRunHook(opts, exec, exitErr)
+	}
+	for _, result := range opts.pkgGroupResults {
+		groupOpts := *opts
+		groupOpts.pkgGroupName = result.group.name
+		if err := postRunHook(&groupOpts, result.exec, exitErr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writePkgGroupsJUnitFile(opts *options, results []pkgGroupResult) error {
+	if opts.junitFile == "" {
+		return nil
+	}
+	junitFile, err := createOutputFile(opts.junitFile)
+	if err != nil {
+		return fmt.Errorf("failed to open JUnit file: %v", err)
+	}
+	defer func() {
+		if err := junitFile.Close(); err != nil {
+			log.Errorf("Failed to close JUnit file: %v", err)
+		}
+	}()
+
+	execs := make([]junitxml.LabeledExecution, 0, len(results))
+	for _, result := range results {
+		execs = append(execs, junitxml.LabeledExecution{
+			Label:     result.group.name,
+			Execution: result.exec,
+		})
+	}
+	return junitxml.WriteLabeled(junitFile, execs, newJUnitConfig(opts))
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestPkgGroupsValue_Set(t *testing.T) {
+	var value pkgGroupsValue
+	assert.NilError(t, value.Set("unit=./... : -short"))
+	assert.NilError(t, value.Set("integration = ./it/... ./e2e : -tags=integration -p 1"))
+	assert.NilError(t, value.Set("plain=./pkg"))
+
+	expected := pkgGroupsValue{
+		{name: "unit", packages: []string{"./..."}, args: []string{"-short"}},
+		{
+			name:     "integration",
+			packages: []string{"./it/...", "./e2e"},
+			args:     []string{"-tags=integration", "-p", "1"},
+		},
+		{name: "plain", packages: []string{"./pkg"}},
+	}
+	assert.DeepEqual(t, value, expected, cmpPkgGroup)
+	assert.Equal(t, value.String(), "unit,integration,plain")
+
+	assert.ErrorContains(t, value.Set("./..."), "must be NAME=PACKAGES")
+	assert.ErrorContains(t, value.Set("unit=./other"), "duplicate package group name unit")
+	assert.ErrorContains(t, value.Set("empty= : -v"), "must have at least one package")
+}
+
+func TestRunPkgGroups(t *testing.T) {
+	jsonPassed := `{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+{"Package": "pkg", "Test": "TestOne", "Action": "pass"}
+{"Package": "pkg", "Action": "pass"}
+`
+	var cmds [][]string
+	fn := func(args []string) *proc {
+		cmds = append(cmds, args)
+		return &proc{
+			cmd:    fakeWaiter{},
+			stdout: strings.NewReader(jsonPassed),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		format:      "pkgname",
+		stdout:      out,
+		stderr:      out,
+		hideSummary: newHideSummaryValue(),
+	}
+	assert.NilError(t, opts.pkgGroups.Set("unit=./... : -short"))
+	assert.NilError(t, opts.pkgGroups.Set("integration=./it/... : -tags=integration"))
+
+	handler, err := newEventHandler(opts)
+	assert.NilError(t, err)
+	assert.NilError(t, runPkgGroups(context.Background(), opts, handler))
+
+	expected := [][]string{
+		{"go", "test", "-json", "-short", "./..."},
+		{"go", "test", "-json", "-tags=integration", "./it/..."},
+	}
+	assert.DeepEqual(t, cmds, expected)
+	assert.Assert(t, strings.Contains(out.String(), "\n=== Group unit\n\nDONE 1 tests"), out.String())
+	assert.Assert(t, strings.Contains(out.String(), "\n=== Group integration\n\nDONE 1 tests"), out.String())
+}
+
+func TestRunPkgGroups_ScriptOutput(t *testing.T) {
+	fn := func(args []string) *proc {
+		pkg := args[len(args)-1]
+		stderr := ""
+		if pkg == "./it/..." {
+			stderr = "example.com/it: build failed\n"
+		}
+		return &proc{
+			cmd: fakeWaiter{},
+			stdout: strings.NewReader(`{"Package": "` + pkg + `", "Test": "TestOne", "Action": "run"}
+{"Package": "` + pkg + `", "Test": "TestOne", "Action": "pass"}
+{"Package": "` + pkg + `", "Action": "pass"}
+`),
+			stderr: strings.NewReader(stderr),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		format:       "pkgname",
+		scriptOutput: true,
+		stdout:       out,
+		stderr:       new(bytes.Buffer),
+		hideSummary:  newHideSummaryValue(),
+	}
+	assert.NilError(t, opts.pkgGroups.Set("unit=./... : -short"))
+	assert.NilError(t, opts.pkgGroups.Set("integration=./it/..."))
+
+	handler, err := newEventHandler(opts)
+	assert.NilError(t, err)
+	assert.NilError(t, runPkgGroups(context.Background(), opts, handler))
+
+	// remove the elapsed time from the DONE line, it uses the current time
+	lines := strings.Split(out.String(), "\n")
+	last := strings.Split(lines[len(lines)-2], "\t")
+	lines[len(lines)-2] = strings.Join(last[:len(last)-1], "\t")
+
+	expected := `VERSION	1
+GROUP	unit
+RESULT	./...	TestOne	pass	0.000
+PACKAGE	./...	pass	0.000
+GROUP	integration
+RESULT	./it/...	TestOne	pass	0.000
+PACKAGE	./it/...	pass	0.000
+GROUP	unit
+GROUP	integration
+ERROR	example.com/it: build failed
+DONE	2	0	0	1
+`
+	assert.Equal(t, strings.Join(lines, "\n"), expected)
+}
+
+func TestRunPkgGroups_CoverageMin(t *testing.T) {
+	fn := func(args []string) *proc {
+		pkg := args[len(args)-1]
+		return &proc{
+			cmd: fakeWaiter{},
+			stdout: strings.NewReader(`{"Package": "` + pkg + `", "Test": "TestOne", "Action": "run"}
+{"Package": "` + pkg + `", "Test": "TestOne", "Action": "pass"}
+{"Package": "` + pkg + `", "Action": "output", "Output": "coverage: 40.0% of statements\n"}
+{"Package": "` + pkg + `", "Action": "pass"}
+`),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		format:      "pkgname",
+		stdout:      out,
+		stderr:      out,
+		hideSummary: newHideSummaryValue(),
+		coverageMin: 80,
+	}
+	assert.NilError(t, opts.pkgGroups.Set("unit=example.com/unit"))
+	assert.NilError(t, opts.pkgGroups.Set("integration=example.com/integration : -tags=integration"))
+
+	handler, err := newEventHandler(opts)
+	assert.NilError(t, err)
+	err = runPkgGroups(context.Background(), opts, handler)
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+	assert.Assert(t, strings.Contains(out.String(), "\n=== Group unit\n\nDONE 1 tests"), out.String())
+	assert.Assert(t, strings.Contains(out.String(), `
+=== Coverage below minimum (2 packages)
+example.com/integration 40.0% (minimum 80.0%)
+example.com/unit 40.0% (minimum 80.0%)
+`), out.String())
+}
+
+func TestOptions_Validate_PkgGroups(t *testing.T) {
+	opts := &options{rawCommand: true}
+	assert.NilError(t, opts.pkgGroups.Set("unit=./..."))
+	assert.ErrorContains(t, opts.Validate(), "--raw-command can not be used with --pkg-group")
+}
+
+var cmpPkgGroup = gocmp.Options{gocmp.AllowUnexported(pkgGroup{}), cmpopts.EquateEmpty()}
+
+func TestRunPkgGroups_SameReportsAsRun(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+
+	fn := func(args []string) *proc {
+		pkg := args[len(args)-1]
+		return &proc{
+			cmd: fakeWaiter{},
+			stdout: strings.NewReader(`{"Package": "` + pkg + `", "Test": "TestOne", "Action": "run"}
+{"Package": "` + pkg + `", "Test": "TestOne", "Action": "output", "Output": "passed output of ` + pkg + `\n"}
+{"Package": "` + pkg + `", "Test": "TestOne", "Action": "pass"}
+{"Package": "` + pkg + `", "Action": "pass"}
+`),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		format:      "pkgname",
+		junitFile:   dir.Join("junit.xml"),
+		stdout:      out,
+		stderr:      out,
+		hideSummary: newHideSummaryValue(),
+	}
+	opts.formatOptions.ShowOutput = testjson.ShowOutputPass
+	opts.formatOptions.LogFolding = testjson.LogFoldingGitHub
+	assert.NilError(t, opts.pkgGroups.Set("unit=./unit"))
+	assert.NilError(t, opts.pkgGroups.Set("integration=./it"))
+
+	handler, err := newEventHandler(opts)
+	assert.NilError(t, err)
+	assert.NilError(t, runPkgGroups(context.Background(), opts, handler))
+
+	raw, err := ioutil.ReadFile(opts.junitFile)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(string(raw), "passed output of ./unit"))
+	assert.Assert(t, cmp.Contains(string(raw), "passed output of ./it"))
+
+	assert.Assert(t, cmp.Contains(out.String(), "\n=== Group unit\n::group::Summary\n"), out.String())
+	assert.Assert(t, cmp.Contains(out.String(), "\n=== Group integration\n::group::Summary\n"), out.String())
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gotest.tools/gotestsum/log"
+)
+
+// goTestFlagVersions are the go test flags which require a minimum go1.N
+// version.
+var goTestFlagVersions = []struct {
+	flag  string
+	minor int
+}{
+	{flag: "shuffle", minor: 17},
+	{flag: "fuzz", minor: 18},
+	{flag: "skip", minor: 20},
+	{flag: "fullpath", minor: 21},
+}
+
+// shims for testing
+var (
+	preflightGoVersion = func() string {
+		log.Debugf("exec: go env GOVERSION")
+		out, err := exec.Command("go", "env", "GOVERSION").Output()
+		if err != nil {
+			log.Debugf("go env GOVERSION: %v", err)
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+)
+
+// preflight checks the options against the environment before any tests run,
+// so that a misconfiguration is reported at the start of the run instead of
+// after the tests have run. Options which can be checked without the
+// environment are checked by options.Validate. Every problem is included in
+// the error.
+func preflight(opts *options) error {
+	var problems []string
+	problems = append(problems, checkOutputFiles(opts)...)
+	problems = append(problems, checkGoTestFlags(opts)...)
+	switch len(problems) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("preflight check failed: %v", problems[0])
+	}
+	return fmt.Errorf("preflight checks failed:\n  %v", strings.Join(problems, "\n  "))
+}
+
+// checkOutputFiles returns a problem for each output file which can not be
+// written, and for each file which is the path of more than one flag.
+func checkOutputFiles(opts *options) []string {
+	var problems []string
+	seen := make(map[string]string)
+	for _, flag := range outputFileFlags(opts) {
+		path := *flag.path
+		if path == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			if other, ok := seen[abs]; ok {
+				problems = append(problems, fmt.Sprintf(
+					"--%v and --%v are the same file %v, use a different file for each flag",
+					other, flag.name, path))
+				continue
+			}
+			seen[abs] = flag.name
+		}
+		if err := checkWritable(path); err != nil {
+			problems = append(problems, fmt.Sprintf("--%v %v can not be written: %v", flag.name, path, err))
+		}
+	}
+	return problems
+}
+
+// checkWritable returns an error if a file can not be created at path. The
+// directories that would be created for the file are not created. The nearest
+// existing directory must be writable.
+func checkWritable(path string) error {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return fmt.Errorf("the path is a directory")
+	}
+	dir := filepath.Dir(path)
+	info, err := os.Stat(dir)
+	for os.IsNotExist(err) && filepath.Dir(dir) != dir {
+		dir = filepath.Dir(dir)
+		info, err = os.Stat(dir)
+	}
+	switch {
+	case err != nil:
+		return err
+	case !info.IsDir():
+		return fmt.Errorf("%v is not a directory", dir)
+	}
+	fh, err := ioutil.TempFile(dir, ".gotestsum-preflight-")
+	if err != nil {
+		return fmt.Errorf("directory %v is not writable: %w", dir, err)
+	}
+	fh.Close()           // nolint: errcheck
+	os.Remove(fh.Name()) // nolint: errcheck
+	return nil
+}
+
+// checkGoTestFlags returns a problem for each go test flag which is not supported
+// by the version of go.
+func checkGoTestFlags(opts *options) []string {
+	if opts.rawCommand {
+		return nil
+	}
+	args := goTestCmdArgs(opts, rerunOpts{})
+
+	var version string
+	var problems []string
+	for _, v := range goTestFlagVersions {
+		if start, _ := argIndex(v.flag, args[:findPkgArgPosition(args)]); start < 0 {
+			continue
+		}
+		if version == "" {
+			if version = preflightGoVersion(); version == "" {
+				return nil
+			}
+		}
+		if !goVersionAtLeast(version, v.minor) {
+			problems = append(problems, fmt.Sprintf(
+				"the -%v flag requires go1.%d or later, the go version is %v",
+				v.flag, v.minor, version))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestPreflight_OutputFiles(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("file", ""),
+		fs.WithDir("out"))
+	defer dir.Remove()
+
+	opts := &options{
+		jsonFile:       dir.Join("new", "dir", "events.json"),
+		htmlReportFile: dir.Join("file", "report.html"),
+		metricsFile:    dir.Join("out"),
+		provenanceFile: dir.Join("new", "..", "new", "dir", "events.json"),
+	}
+	err := preflight(opts)
+	expected := `preflight checks failed:
+  --html-report ` + dir.Join("file", "report.html") + ` can not be written: ` + dir.Join("file") + ` is not a directory
+  --metrics-file ` + dir.Join("out") + ` can not be written: the path is a directory
+  --jsonfile and --provenance are the same file ` + opts.provenanceFile + `, use a different file for each flag`
+	assert.Error(t, err, expected)
+
+	_, err = os.Stat(dir.Join("new"))
+	assert.Assert(t, os.IsNotExist(err), "preflight must not create directories")
+}
+
+func TestPreflight_OutputFileInReadOnlyDir(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("root can write to a read-only directory")
+	}
+	dir := fs.NewDir(t, t.Name(), fs.WithDir("readonly", fs.WithMode(0555)))
+	defer dir.Remove()
+
+	err := preflight(&options{junitFile: dir.Join("readonly", "junit.xml")})
+	assert.ErrorContains(t, err, "preflight check failed: --junitfile "+dir.Join("readonly", "junit.xml")+
+		" can not be written: directory "+dir.Join("readonly")+" is not writable: ")
+}
+
+func TestCheckWritable(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(), fs.WithFile("file", ""))
+	defer dir.Remove()
+
+	assert.NilError(t, checkWritable(dir.Join("file")))
+	assert.NilError(t, checkWritable(dir.Join("a", "b", "c.xml")))
+	assert.NilError(t, checkWritable("relative.xml"))
+	assert.ErrorContains(t, checkWritable(dir.Join("file", "c.xml")), "is not a directory")
+
+	entries, err := filepath.Glob(dir.Join(".gotestsum-preflight-*"))
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 0, "temporary files must be removed")
+}
+
+func TestPreflight_GoTestFlags(t *testing.T) {
+	var lookups int
+	version := "go1.16.15"
+	orig := preflightGoVersion
+	preflightGoVersion = func() string {
+		lookups++
+		return version
+	}
+	defer func() {
+		preflightGoVersion = orig
+	}()
+
+	opts := &options{args: []string{"-shuffle=on", "-skip", "TestSlow", "./...", "-args", "-fuzz"}}
+	err := preflight(opts)
+	assert.Error(t, err, `preflight checks failed:
+  the -shuffle flag requires go1.17 or later, the go version is go1.16.15
+  the -skip flag requires go1.20 or later, the go version is go1.16.15`)
+	assert.Equal(t, lookups, 1)
+
+	version = "go1.22.1"
+	assert.NilError(t, preflight(opts))
+
+	t.Run("no flags which require a version", func(t *testing.T) {
+		lookups = 0
+		assert.NilError(t, preflight(&options{args: []string{"-race", "./..."}}))
+		assert.Equal(t, lookups, 0)
+	})
+
+	t.Run("raw command", func(t *testing.T) {
+		lookups = 0
+		assert.NilError(t, preflight(&options{rawCommand: true, args: []string{"./test.test", "-shuffle=on"}}))
+		assert.Equal(t, lookups, 0)
+	})
+
+	t.Run("shuffle from until-failure", func(t *testing.T) {
+		version = "go1.16"
+		err := preflight(&options{shuffleSeed: "123"})
+		assert.Error(t, err, "preflight check failed: the -shuffle flag requires go1.17 or later, the go version is go1.16")
+	})
+}
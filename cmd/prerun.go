@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"gotest.tools/gotestsum/log"
+)
+
+// preRunHook runs the --pre-run-command, and waits for it to exit, before any
+// tests run. The output of the command is written to the same writers as the
+// output of the tests. When the command fails the tests are not run.
+func preRunHook(ctx context.Context, opts *options) error {
+	command := opts.preRunHookCmd.Value()
+	if len(command) == 0 {
+		return nil
+	}
+	log.Debugf("exec: %s", command)
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdout = opts.stdout
+	cmd.Stderr = opts.stderr
+	cmd.Env = append(
+		os.Environ(),
+		"GOTESTSUM_JSONFILE="+opts.jsonFile,
+		"GOTESTSUM_JUNITFILE="+opts.junitFile,
+		"GOTESTSUM_RUN_ID="+opts.runID,
+	)
+	if err := cmd.Run(); err != nil {
+		// The error is wrapped so that it is not an exitCoder, which would
+		// exit without printing the error.
+		return fmt.Errorf("pre run command failed, not running tests: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/env"
+)
+
+func TestPreRunHook(t *testing.T) {
+	command := &commandValue{}
+	err := command.Set("go run ./testdata/postrunhook/main.go")
+	assert.NilError(t, err)
+
+	buf := new(bytes.Buffer)
+	opts := &options{
+		preRunHookCmd: command,
+		junitFile:     "junit.xml",
+		runID:         "ci-1234",
+		stdout:        buf,
+		stderr:        buf,
+	}
+	err = preRunHook(context.Background(), opts)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(buf.String(), "GOTESTSUM_JUNITFILE=junit.xml\n"))
+	assert.Assert(t, cmp.Contains(buf.String(), "GOTESTSUM_RUN_ID=ci-1234\n"))
+}
+
+func TestRun_PreRunCommandFails(t *testing.T) {
+	command := &commandValue{}
+	err := command.Set("go run ./testdata/postrunhook/main.go")
+	assert.NilError(t, err)
+	defer env.Patch(t, "TEST_STUB_ERROR", "database is not ready")()
+
+	var started bool
+	defer patchStartGoTestFn(func(args []string) *proc {
+		started = true
+		return &proc{
+			cmd:    fakeWaiter{},
+			stdout: strings.NewReader(""),
+			stderr: bytes.NewReader(nil),
+		}
+	})()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		preRunHookCmd: command,
+		format:        "testname",
+		stdout:        out,
+		stderr:        out,
+		hideSummary:   newHideSummaryValue(),
+	}
+	err = run(opts)
+	assert.ErrorContains(t, err, "pre run command failed, not running tests: exit status 1")
+	assert.Assert(t, !IsExitCoder(err))
+	assert.Assert(t, !started, "go test should not run")
+	assert.Assert(t, cmp.Contains(out.String(), "database is not ready\n"))
+}
@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// provenanceStatement is the record of a test run written by --provenance. The
+// format follows the in-toto attestation statement, with the artifacts written
+// by gotestsum as the subject.
+type provenanceStatement struct {
+	Type          string              `json:"_type"`
+	Subject       []provenanceSubject `json:"subject"`
+	PredicateType string              `json:"predicateType"`
+	Predicate     provenancePredicate `json:"predicate"`
+}
+
+type provenanceSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type provenancePredicate struct {
+	Inputs  provenanceInputs  `json:"inputs"`
+	Outputs provenanceOutputs `json:"outputs"`
+}
+
+type provenanceInputs struct {
+	RunID            string   `json:"runID,omitempty"`
+	GitSHA           string   `json:"gitSHA"`
+	GoVersion        string   `json:"goVersion"`
+	GotestsumVersion string   `json:"gotestsumVersion"`
+	Args             []string `json:"args"`
+	Command          []string `json:"command"`
+}
+
+type provenanceOutputs struct {
+	Started time.Time `json:"started"`
+	Elapsed string    `json:"elapsed"`
+	Total   int       `json:"total"`
+	Failed  int       `json:"failed"`
+	Skipped int       `json:"skipped"`
+	Errors  int       `json:"errors"`
+}
+
+const (
+	provenanceStatementType = "https://in-toto.io/Statement/v0.1"
+	provenancePredicateType = "https://gotest.tools/gotestsum/provenance/v1"
+	provenancePayloadType   = "application/vnd.in-toto+json"
+)
+
+// dsseEnvelope is the envelope used to sign the statement when
+// --provenance-key is set. See https://github.com/secure-systems-lab/dsse.
+type dsseEnvelope struct {
+	PayloadType string          `json:"payloadType"`
+	Payload     string          `json:"payload"`
+	Signatures  []dsseSignature `json:"signatures"`
+}
+
+type dsseSignature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// shims for testing
+var (
+	provenanceGitSHA    = gitSHA
+	provenanceGoVersion = goVersion
+)
+
+func newProvenanceStatement(opts *options, execs []*testjson.Execution) (provenanceStatement, error) {
+	statement := provenanceStatement{
+		Type:          provenanceStatementType,
+		Subject:       []provenanceSubject{},
+		PredicateType: provenancePredicateType,
+		Predicate: provenancePredicate{
+			Inputs: provenanceInputs{
+				RunID:            opts.runID,
+				GitSHA:           provenanceGitSHA(),
+				GoVersion:        provenanceGoVersion(),
+				GotestsumVersion: version,
+				Args:             opts.gotestsumArgs,
+				Command:          goTestCmdArgs(opts, rerunOpts{}),
+			},
+		},
+	}
+
+	outputs := &statement.Predicate.Outputs
+	var elapsed time.Duration
+	for _, exec := range execs {
+		if outputs.Started.IsZero() || exec.Started().Before(outputs.Started) {
+			outputs.Started = exec.Started()
+		}
+		elapsed += exec.Elapsed()
+		outputs.Total += exec.Total()
+		outputs.Failed += len(exec.Failed())
+		outputs.Skipped += len(exec.Skipped())
+		outputs.Errors += len(exec.Errors())
+	}
+	outputs.Elapsed = elapsed.String()
+
+	for _, path := range outputFilePaths(opts) {
+		if *path == "" || *path == opts.provenanceFile {
+			continue
+		}
+		digest, err := fileSHA256(*path)
+		switch {
+		case os.IsNotExist(err):
+			continue
+		case err != nil:
+			return statement, err
+		}
+		statement.Subject = append(statement.Subject, provenanceSubject{
+			Name:   filepath.Base(*path),
+			Digest: map[string]string{"sha256": digest},
+		})
+	}
+	return statement, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fh.Close() // nolint: errcheck
+	h := sha256.New()
+	if _, err := io.Copy(h, fh); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func gitSHA() string {
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		log.Warnf("Failed to lookup git SHA for provenance: %v", err)
+		return "unknown"
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func goVersion() string {
+	out, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		log.Warnf("Failed to lookup go version for provenance: %v", err)
+		return "unknown"
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// writeProvenance writes the provenance record of the run to
+// opts.provenanceFile. When opts.provenanceKey is set the record is signed,
+// and written in a DSSE envelope.
+func writeProvenance(opts *options, execs ...*testjson.Execution) error {
+	if opts.provenanceFile == "" {
+		return nil
+	}
+	statement, err := newProvenanceStatement(opts, execs)
+	if err != nil {
+		return err
+	}
+	payload, err := json.MarshalIndent(statement, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if opts.provenanceKey != "" {
+		signer, err := readSigningKey(opts.provenanceKey)
+		if err != nil {
+			return err
+		}
+		envelope, err := newDSSEEnvelope(payload, signer)
+		if err != nil {
+			return err
+		}
+		if payload, err = json.MarshalIndent(envelope, "", "  "); err != nil {
+			return err
+		}
+	}
+
+	fh, err := createOutputFile(opts.provenanceFile)
+	if err != nil {
+		return err
+	}
+	if _, err := fh.Write(append(payload, '\n')); err != nil {
+		fh.Close() // nolint: errcheck
+		return err
+	}
+	return fh.Close()
+}
+
+// readSigningKey reads a PEM encoded ed25519, ECDSA, or RSA private key.
+func readSigningKey(path string) (crypto.Signer, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provenance key: %w", err)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("provenance key %v is not PEM encoded", path)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse provenance key %v: %w", path, err)
+	}
+	switch key := key.(type) {
+	case ed25519.PrivateKey:
+		return key, nil
+	case *ecdsa.PrivateKey:
+		return key, nil
+	case *rsa.PrivateKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported provenance key type %T", key)
+	}
+}
+
+func newDSSEEnvelope(payload []byte, signer crypto.Signer) (dsseEnvelope, error) {
+	sig, err := signPayload(signer, dssePAE(provenancePayloadType, payload))
+	if err != nil {
+		return dsseEnvelope{}, fmt.Errorf("failed to sign provenance: %w", err)
+	}
+	keyID, err := publicKeyID(signer.Public())
+	if err != nil {
+		return dsseEnvelope{}, err
+	}
+	return dsseEnvelope{
+		PayloadType: provenancePayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []dsseSignature{
+			{KeyID: keyID, Sig: base64.StdEncoding.EncodeToString(sig)},
+		},
+	}, nil
+}
+
+// dssePAE returns the pre-authentication encoding of the payload, which is the
+// message that is signed.
+func dssePAE(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s",
+		len(payloadType), payloadType, len(payload), payload))
+}
+
+func signPayload(signer crypto.Signer, message []byte) ([]byte, error) {
+	if _, ok := signer.(ed25519.PrivateKey); ok {
+		return signer.Sign(rand.Reader, message, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(message)
+	return signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+// publicKeyID returns the hex encoded SHA256 of the DER encoded public key.
+func publicKeyID(key crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func validateProvenance(opts *options) error {
+	if opts.provenanceKey == "" {
+		return nil
+	}
+	if opts.provenanceFile == "" {
+		return fmt.Errorf("--provenance-key requires --provenance")
+	}
+	_, err := readSigningKey(opts.provenanceKey)
+	return err
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/compress"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+func patchProvenanceShims(t *testing.T) {
+	origGitSHA, origGoVersion := provenanceGitSHA, provenanceGoVersion
+	provenanceGitSHA = func() string { return "0123456789abcdef" }
+	provenanceGoVersion = func() string { return "go1.16.2" }
+	t.Cleanup(func() {
+		provenanceGitSHA, provenanceGoVersion = origGitSHA, origGoVersion
+	})
+}
+
+func scanProvenanceExecution(t *testing.T) *testjson.Execution {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: bytes.NewReader(golden.Get(t, "../../testjson/testdata/go-test-json.out")),
+	})
+	assert.NilError(t, err)
+	return exec
+}
+
+func TestWriteProvenance(t *testing.T) {
+	patchProvenanceShims(t)
+	dir := fs.NewDir(t, "provenance", fs.WithFile("junit.xml", "<testsuites/>"))
+	opts := &options{
+		junitFile:      dir.Join("junit.xml"),
+		jsonFile:       dir.Join("missing.json"),
+		provenanceFile: dir.Join("provenance.json"),
+		gotestsumArgs:  []string{"--junitfile", "junit.xml", "--", "./..."},
+		args:           []string{"./..."},
+	}
+	exec := scanProvenanceExecution(t)
+	assert.NilError(t, writeProvenance(opts, exec))
+
+	raw, err := ioutil.ReadFile(opts.provenanceFile)
+	assert.NilError(t, err)
+	var statement provenanceStatement
+	assert.NilError(t, json.Unmarshal(raw, &statement))
+
+	assert.Equal(t, statement.Type, provenanceStatementType)
+	assert.Equal(t, statement.PredicateType, provenancePredicateType)
+	sum := sha256.Sum256([]byte("<testsuites/>"))
+	assert.DeepEqual(t, statement.Subject, []provenanceSubject{
+		{Name: "junit.xml", Digest: map[string]string{"sha256": hex.EncodeToString(sum[:])}},
+	})
+
+	inputs := statement.Predicate.Inputs
+	assert.Equal(t, inputs.GitSHA, "0123456789abcdef")
+	assert.Equal(t, inputs.GoVersion, "go1.16.2")
+	assert.DeepEqual(t, inputs.Args, opts.gotestsumArgs)
+	assert.DeepEqual(t, inputs.Command, []string{"go", "test", "-json", "./..."})
+
+	outputs := statement.Predicate.Outputs
+	assert.Equal(t, outputs.Total, exec.Total())
+	assert.Equal(t, outputs.Failed, len(exec.Failed()))
+	assert.Equal(t, outputs.Skipped, len(exec.Skipped()))
+	assert.Equal(t, outputs.Errors, len(exec.Errors()))
+}
+
+func TestWriteProvenance_Signed(t *testing.T) {
+	patchProvenanceShims(t)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	assert.NilError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NilError(t, err)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	assert.NilError(t, err)
+
+	dir := fs.NewDir(t, "provenance",
+		fs.WithFile("ec.pem", string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER}))),
+		fs.WithFile("ed25519.pem", string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edDER}))))
+
+	verify := map[string]func(message, sig []byte) bool{
+		"ec.pem": func(message, sig []byte) bool {
+			digest := sha256.Sum256(message)
+			return ecdsa.VerifyASN1(&ecKey.PublicKey, digest[:], sig)
+		},
+		"ed25519.pem": func(message, sig []byte) bool {
+			return ed25519.Verify(edKey.Public().(ed25519.PublicKey), message, sig)
+		},
+	}
+	for name, verify := range verify {
+		t.Run(name, func(t *testing.T) {
+			opts := &options{
+				provenanceFile: dir.Join("provenance.json"),
+				provenanceKey:  dir.Join(name),
+			}
+			assert.NilError(t, validateProvenance(opts))
+			assert.NilError(t, writeProvenance(opts, scanProvenanceExecution(t)))
+
+			raw, err := ioutil.ReadFile(opts.provenanceFile)
+			assert.NilError(t, err)
+			var envelope dsseEnvelope
+			assert.NilError(t, json.Unmarshal(raw, &envelope))
+			assert.Equal(t, envelope.PayloadType, provenancePayloadType)
+			assert.Equal(t, len(envelope.Signatures), 1)
+
+			signer, err := readSigningKey(opts.provenanceKey)
+			assert.NilError(t, err)
+			keyID, err := publicKeyID(signer.Public())
+			assert.NilError(t, err)
+			assert.Equal(t, envelope.Signatures[0].KeyID, keyID)
+
+			payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+			assert.NilError(t, err)
+			sig, err := base64.StdEncoding.DecodeString(envelope.Signatures[0].Sig)
+			assert.NilError(t, err)
+			assert.Assert(t, verify(dssePAE(envelope.PayloadType, payload), sig))
+
+			var statement provenanceStatement
+			assert.NilError(t, json.Unmarshal(payload, &statement))
+			assert.Equal(t, statement.Predicate.Inputs.GitSHA, "0123456789abcdef")
+		})
+	}
+}
+
+func TestValidateProvenance(t *testing.T) {
+	dir := fs.NewDir(t, "provenance", fs.WithFile("key.pem", "not a key"))
+
+	opts := &options{provenanceKey: dir.Join("key.pem")}
+	assert.Error(t, validateProvenance(opts), "--provenance-key requires --provenance")
+
+	opts.provenanceFile = "provenance.json"
+	assert.ErrorContains(t, validateProvenance(opts), "is not PEM encoded")
+}
+
+func TestDSSEPAE(t *testing.T) {
+	actual := dssePAE("http://example.com/HelloWorld", []byte("hello world"))
+	assert.Equal(t, string(actual), "DSSEv1 29 http://example.com/HelloWorld 11 hello world")
+}
+
+func TestRun_ProvenanceOfCompressedJSONFile(t *testing.T) {
+	patchProvenanceShims(t)
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+
+	defer patchStartGoTestFn(func(args []string) *proc {
+		return &proc{
+			cmd: fakeWaiter{},
+			stdout: strings.NewReader(`{"Package": "example.com/pkg", "Test": "TestOne", "Action": "run"}
+{"Package": "example.com/pkg", "Test": "TestOne", "Action": "pass"}
+{"Package": "example.com/pkg", "Action": "pass"}
+`),
+			stderr: bytes.NewReader(nil),
+		}
+	})()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		format:         "testname",
+		jsonFile:       dir.Join("events.json.gz"),
+		provenanceFile: dir.Join("provenance.json"),
+		stdout:         out,
+		stderr:         out,
+		hideSummary:    newHideSummaryValue(),
+	}
+	assert.NilError(t, run(opts))
+
+	raw, err := ioutil.ReadFile(opts.provenanceFile)
+	assert.NilError(t, err)
+	var statement provenanceStatement
+	assert.NilError(t, json.Unmarshal(raw, &statement))
+
+	events, err := ioutil.ReadFile(opts.jsonFile)
+	assert.NilError(t, err)
+	sum := sha256.Sum256(events)
+	assert.DeepEqual(t, statement.Subject, []provenanceSubject{
+		{Name: "events.json.gz", Digest: map[string]string{"sha256": hex.EncodeToString(sum[:])}},
+	})
+
+	r, err := compress.Open(opts.jsonFile)
+	assert.NilError(t, err)
+	defer r.Close() // nolint: errcheck
+	decompressed, err := ioutil.ReadAll(r)
+	assert.NilError(t, err)
+	assert.Assert(t, bytes.Contains(decompressed, []byte(`"Action": "pass"}`)))
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// readQuarantineFile reads the --quarantine-file. Each line of the file is the
+// import path of a package, followed by the name of a test in the package,
+// optionally followed by the reason the test is quarantined. Blank lines and
+// lines that start with # are ignored.
+func readQuarantineFile(filename string) (*testjson.Quarantine, error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close() // nolint: errcheck
+
+	var rules []testjson.QuarantineRule
+	scanner := bufio.NewScanner(fh)
+	var lineNum int
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%v:%d: expected a package followed by a test name", filename, lineNum)
+		}
+		rules = append(rules, testjson.QuarantineRule{
+			Package: fields[0],
+			Test:    fields[1],
+			Reason:  strings.Join(fields[2:], " "),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %v: %w", filename, err)
+	}
+	return testjson.NewQuarantine(rules), nil
+}
+
+// setupQuarantine sets the Quarantine of the format options from the
+// --quarantine-file.
+func setupQuarantine(opts *options) error {
+	if opts.quarantineFile == "" {
+		return nil
+	}
+	quarantine, err := readQuarantineFile(opts.quarantineFile)
+	if err != nil {
+		return fmt.Errorf("failed to read --quarantine-file: %w", err)
+	}
+	opts.formatOptions.Quarantine = quarantine
+	return nil
+}
+
+// allowedFailuresExitErr returns nil when the run failed only because of
+// tests in the --quarantine-file, or tests in the --expected-failures file.
+// Otherwise it returns exitErr.
+func allowedFailuresExitErr(opts *options, exec *testjson.Execution, exitErr error) error {
+	quarantine := opts.formatOptions.Quarantine
+	if quarantine == nil && opts.expectedFailures == nil {
+		return exitErr
+	}
+	if ExitCodeWithDefault(exitErr) != 1 || len(exec.Errors()) > 0 {
+		return exitErr
+	}
+	for _, name := range exec.Packages() {
+		if exec.Package(name).TestMainFailed() {
+			return exitErr
+		}
+	}
+	failed := stillFailing(exec)
+	if len(failed) == 0 {
+		return exitErr
+	}
+	for _, tc := range failed {
+		if _, ok := quarantine.Lookup(tc); !ok && !opts.expectedFailures.isExpected(tc) {
+			return exitErr
+		}
+	}
+	log.Warnf("all %d failed %s listed in %s, not failing the run",
+		len(failed), pluralize(len(failed), "test is", "tests are"), allowedFailuresFlags(opts))
+	return nil
+}
+
+func allowedFailuresFlags(opts *options) string {
+	switch {
+	case opts.formatOptions.Quarantine == nil:
+		return "--expected-failures"
+	case opts.expectedFailures == nil:
+		return "--quarantine-file"
+	default:
+		return "--quarantine-file or --expected-failures"
+	}
+}
+
+// stillFailing returns the failed tests which did not pass when they were
+// re-run, without the root tests of failed subtests.
+func stillFailing(exec *testjson.Execution) []testjson.TestCase {
+	var result []testjson.TestCase
+	for _, tc := range testjson.FilterFailedUnique(exec.Failed()) {
+		if !passedInLaterRun(exec.Package(tc.Package), tc) {
+			result = append(result, tc)
+		}
+	}
+	return result
+}
+
+func passedInLaterRun(pkg *testjson.Package, failed testjson.TestCase) bool {
+	for _, tc := range pkg.Passed {
+		if tc.Test == failed.Test && tc.RunID > failed.RunID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestReadQuarantineFile(t *testing.T) {
+	file := fs.NewFile(t, t.Name(), fs.WithContent(`
+# package          test          reason
+example.com/pkg    TestFlaky     https://issues.example.com/7 times out
+example.com/other  TestRace
+`))
+	defer file.Remove()
+
+	quarantine, err := readQuarantineFile(file.Path())
+	assert.NilError(t, err)
+	rule, ok := quarantine.Lookup(testjson.TestCase{Package: "example.com/pkg", Test: "TestFlaky"})
+	assert.Assert(t, ok)
+	assert.Equal(t, rule.Reason, "https://issues.example.com/7 times out")
+	rule, ok = quarantine.Lookup(testjson.TestCase{Package: "example.com/other", Test: "TestRace/sub"})
+	assert.Assert(t, ok)
+	assert.Equal(t, rule.Reason, "")
+}
+
+func TestReadQuarantineFile_Invalid(t *testing.T) {
+	file := fs.NewFile(t, t.Name(), fs.WithContent("example.com/pkg\n"))
+	defer file.Remove()
+
+	_, err := readQuarantineFile(file.Path())
+	assert.ErrorContains(t, err, ":1: expected a package followed by a test name")
+}
+
+func TestQuarantineExitErr(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky/sub"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFlaky/sub","Elapsed":0.1}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFlaky","Elapsed":0.1}
+{"Action":"run","Package":"example.com/pkg","Test":"TestRerun"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestRerun","Elapsed":0.1}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.2}
+`),
+	})
+	assert.NilError(t, err)
+	_, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		RunID:     1,
+		Execution: exec,
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestRerun"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestRerun","Elapsed":0.1}
+{"Action":"pass","Package":"example.com/pkg","Elapsed":0.1}
+`),
+	})
+	assert.NilError(t, err)
+
+	var testCases = []struct {
+		name     string
+		rules    []testjson.QuarantineRule
+		exitErr  error
+		expected int
+	}{
+		{
+			name:    "failed subtest is quarantined",
+			rules:   []testjson.QuarantineRule{{Package: "example.com/pkg", Test: "TestFlaky/sub"}},
+			exitErr: exitError{num: 1},
+		},
+		{
+			name:     "failure is not quarantined",
+			rules:    []testjson.QuarantineRule{{Package: "example.com/pkg", Test: "TestRerun"}},
+			exitErr:  exitError{num: 1},
+			expected: 1,
+		},
+		{
+			name:     "unexpected exit code",
+			rules:    []testjson.QuarantineRule{{Package: "example.com/pkg", Test: "TestFlaky"}},
+			exitErr:  exitError{num: 2},
+			expected: 2,
+		},
+		{
+			name:     "no quarantine file",
+			exitErr:  exitError{num: 1},
+			expected: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := &options{}
+			if tc.rules != nil {
+				opts.formatOptions.Quarantine = testjson.NewQuarantine(tc.rules)
+			}
+			err := allowedFailuresExitErr(opts, exec, tc.exitErr)
+			assert.Equal(t, ExitCodeWithDefault(err), tc.expected)
+		})
+	}
+}
@@ -3,9 +3,10 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"os"
 	"sort"
 
+	"github.com/jonboulle/clockwork"
+	"gotest.tools/gotestsum/log"
 	"gotest.tools/gotestsum/testjson"
 )
 
@@ -27,7 +28,7 @@ func (o rerunOpts) Args() []string {
 
 func newRerunOptsFromTestCase(tc testjson.TestCase) rerunOpts {
 	return rerunOpts{
-		runFlag: goTestRunFlagForTestCase(tc.Test),
+		runFlag: goTestRunFlagForTestCase(tc.OriginalName()),
 		pkg:     tc.Package,
 	}
 }
@@ -35,58 +36,142 @@ func newRerunOptsFromTestCase(tc testjson.TestCase) rerunOpts {
 type testCaseFilter func([]testjson.TestCase) []testjson.TestCase
 
 func rerunFailsFilter(o *options) testCaseFilter {
+	withoutAnalysis := withoutAnalysisTestCases(o)
 	if o.rerunFailsOnlyRootCases {
-		return func(tcs []testjson.TestCase) []testjson.TestCase {
-			return tcs
-		}
+		return withoutAnalysis
+	}
+	return func(tcs []testjson.TestCase) []testjson.TestCase {
+		return testjson.FilterFailedUnique(withoutAnalysis(tcs))
 	}
-	return testjson.FilterFailedUnique
 }
 
 func rerunFailed(ctx context.Context, opts *options, scanConfig testjson.ScanConfig) error {
+	if opts.rerunFailsMaxAttemptsPerTest > 0 {
+		return rerunFailedPerTest(ctx, opts, scanConfig)
+	}
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
+	defer endRerunSpans(opts)
 	tcFilter := rerunFailsFilter(opts)
+	outOfTime := newRerunDeadline(opts)
 
 	rec := newFailureRecorderFromExecution(scanConfig.Execution)
-	for attempts := 0; rec.count() > 0 && attempts < opts.rerunFailsMaxAttempts; attempts++ {
-		testjson.PrintSummary(opts.stdout, scanConfig.Execution, testjson.SummarizeNone)
-		opts.stdout.Write([]byte("\n")) // nolint: errcheck
-
-		nextRec := newFailureRecorder(scanConfig.Handler)
-		for _, tc := range tcFilter(rec.failures) {
-			goTestProc, err := startGoTestFn(ctx, goTestCmdArgs(opts, newRerunOptsFromTestCase(tc)))
-			if err != nil {
-				return err
+	// notRerun is the number of failures that were not rerun because their
+	// package has fewer attempts, from --rerun-fails-package.
+	var notRerun int
+	for attempts := 0; rec.count() > 0 && attempts < maxRerunAttempts(opts); attempts++ {
+		var failures []testjson.TestCase
+		for _, tc := range rerunTargets(opts, tcFilter(rec.failures)) {
+			if attempts >= rerunAttemptsForPackage(opts, tc.Package) {
+				notRerun++
+				continue
 			}
+			failures = append(failures, tc)
+		}
+		if len(failures) == 0 {
+			break
+		}
+		if outOfTime(len(failures)) {
+			return exitError{num: 1}
+		}
+		if !opts.scriptOutput {
+			testjson.PrintSummaryWithOptions(opts.stdout, scanConfig.Execution, testjson.SummarizeNone, opts.formatOptions)
+			opts.stdout.Write([]byte("\n")) // nolint: errcheck
+		}
 
-			cfg := testjson.ScanConfig{
-				RunID:     attempts + 1,
-				Stdout:    goTestProc.stdout,
-				Stderr:    goTestProc.stderr,
-				Handler:   nextRec,
-				Execution: scanConfig.Execution,
-				Stop:      cancel,
+		nextRec := newFailureRecorder(scanConfig.Handler)
+		for i, tc := range failures {
+			if outOfTime(len(failures) - i) {
+				return exitError{num: 1}
 			}
-			if _, err := testjson.ScanTestOutput(cfg); err != nil {
+			exitErr, err := runRerunAttempt(ctx, opts, scanConfig, nextRec, cancel, tc, attempts+1)
+			if err != nil {
 				return err
 			}
-			exitErr := goTestProc.cmd.Wait()
 			if exitErr != nil {
 				nextRec.lastErr = exitErr
 			}
-			if err := hasErrors(exitErr, scanConfig.Execution); err != nil {
-				return err
-			}
 		}
 		rec = nextRec
 	}
+	if rec.lastErr == nil && notRerun > 0 {
+		return exitError{num: 1}
+	}
 	return rec.lastErr
 }
 
+// newRerunDeadline returns a function which returns true, and logs a warning
+// about the remaining failures that will not be rerun, when the reruns have
+// taken longer than --rerun-fails-max-time.
+func newRerunDeadline(opts *options) func(remaining int) bool {
+	start := rerunClock.Now()
+	return func(remaining int) bool {
+		if opts.rerunFailsMaxTime <= 0 || rerunClock.Since(start) < opts.rerunFailsMaxTime {
+			return false
+		}
+		log.Warnf("not rerunning %d failed %s, reruns exceeded --rerun-fails-max-time=%v",
+			remaining, pluralize(remaining, "test", "tests"), opts.rerunFailsMaxTime)
+		return true
+	}
+}
+
+// runRerunAttempt waits for the --rerun-fails-delay, then runs go test for the
+// failed test case tc, or for its package with --rerun-fails-scope=package. The events are scanned into the execution, and sent to
+// handler. runRerunAttempt returns the exit error from go test, and an error if
+// the reruns should stop.
+func runRerunAttempt(
+	ctx context.Context,
+	opts *options,
+	scanConfig testjson.ScanConfig,
+	handler testjson.EventHandler,
+	stop func(),
+	tc testjson.TestCase,
+	attempt int,
+) (error, error) {
+	if err := rerunSleep(ctx, rerunDelay(opts, attempt)); err != nil {
+		return nil, err
+	}
+	args := goTestCmdArgs(opts, rerunOptsForTestCase(opts, tc))
+	command := rerunCommand{
+		Attempt:     attempt,
+		Package:     tc.Package,
+		Command:     args,
+		Traceparent: newRerunSpan(opts),
+	}
+	if opts.rerunFailsScope != rerunScopePackage {
+		command.Test = tc.Test.Name()
+		command.RunPattern = goTestRunPattern(tc.OriginalName())
+	}
+	opts.rerunCommands = append(opts.rerunCommands, command)
+	goTestProc, err := startGoTestFn(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := testjson.ScanConfig{
+		RunID:           attempt,
+		Stdout:          goTestProc.stdout,
+		Stderr:          goTestProc.stderr,
+		Handler:         handler,
+		Execution:       scanConfig.Execution,
+		Stop:            stop,
+		RewriteTestName: scanConfig.RewriteTestName,
+		RewriteOutput:   scanConfig.RewriteOutput,
+		StderrMerge:     scanConfig.StderrMerge,
+	}
+	if _, err := testjson.ScanTestOutputContext(ctx, cfg); err != nil {
+		return nil, err
+	}
+	exitErr := goTestProc.cmd.Wait()
+	return exitErr, hasErrors(exitErr, scanConfig.Execution)
+}
+
 // startGoTestFn is a shim for testing
 var startGoTestFn = startGoTest
 
+// rerunClock is a shim for testing
+var rerunClock = clockwork.NewRealClock()
+
 func hasErrors(err error, exec *testjson.Execution) error {
 	switch {
 	case len(exec.Errors()) > 0:
@@ -124,22 +209,38 @@ func (r *failureRecorder) Event(event testjson.TestEvent, execution *testjson.Ex
 	return r.EventHandler.Event(event, execution)
 }
 
+// Flush the wrapped handler, if it buffers output.
+func (r *failureRecorder) Flush() error {
+	if f, ok := r.EventHandler.(testjson.Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
 func (r *failureRecorder) count() int {
 	return len(r.failures)
 }
 
 func goTestRunFlagForTestCase(test testjson.TestName) string {
+	return "-test.run=" + goTestRunPattern(test)
+}
+
+// goTestRunPattern returns the 'go test -run' pattern that matches only test.
+func goTestRunPattern(test testjson.TestName) string {
 	if test.IsSubTest() {
 		root, sub := test.Split()
-		return "-test.run=^" + root + "$/^" + sub + "$"
+		return "^" + root + "$/^" + sub + "$"
 	}
-	return "-test.run=^" + test.Name() + "$"
+	return "^" + test.Name() + "$"
 }
 
 func writeRerunFailsReport(opts *options, exec *testjson.Execution) error {
-	if opts.rerunFailsMaxAttempts == 0 || opts.rerunFailsReportFile == "" {
+	if maxRerunAttempts(opts) == 0 || opts.rerunFailsReportFile == "" {
 		return nil
 	}
+	if opts.rerunFailsReportFormat == rerunFailsReportJSON {
+		return writeRerunFailsReportJSON(opts, exec)
+	}
 
 	type testCaseCounts struct {
 		total  int
@@ -173,10 +274,11 @@ func writeRerunFailsReport(opts *options, exec *testjson.Execution) error {
 		results[name] = counts
 	}
 
-	fh, err := os.Create(opts.rerunFailsReportFile)
+	fh, err := createOutputFile(opts.rerunFailsReportFile)
 	if err != nil {
 		return err
 	}
+	defer fh.Close() // nolint: errcheck
 
 	sort.Strings(names)
 	for _, name := range names {
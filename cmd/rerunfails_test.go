@@ -7,7 +7,9 @@ import (
 	"io/ioutil"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/jonboulle/clockwork"
 	"gotest.tools/gotestsum/testjson"
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/fs"
@@ -36,6 +38,40 @@ func TestWriteRerunFailsReport(t *testing.T) {
 	golden.Assert(t, string(raw), t.Name()+"-expected")
 }
 
+func TestWriteRerunFailsReport_JSON(t *testing.T) {
+	reportFile := fs.NewFile(t, t.Name())
+	defer reportFile.Remove()
+
+	opts := &options{
+		rerunFailsReportFile:   reportFile.Path(),
+		rerunFailsReportFormat: rerunFailsReportJSON,
+		rerunFailsMaxAttempts:  4,
+		runID:                  "ci-1234",
+		rerunCommands: []rerunCommand{
+			{
+				Attempt:    1,
+				Package:    "gotest.tools/gotestsum/testdata/e2e/flaky",
+				Test:       "TestFailsRarely",
+				RunPattern: "^TestFailsRarely$",
+				Command: []string{"go", "test", "-json", "-test.run=^TestFailsRarely$",
+					"gotest.tools/gotestsum/testdata/e2e/flaky"},
+			},
+		},
+	}
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: bytes.NewReader(golden.Get(t, "go-test-json-flaky-rerun.out")),
+	})
+	assert.NilError(t, err)
+
+	err = writeRerunFailsReport(opts, exec)
+	assert.NilError(t, err)
+
+	raw, err := ioutil.ReadFile(reportFile.Path())
+	assert.NilError(t, err)
+	golden.Assert(t, string(raw), t.Name()+"-expected")
+}
+
 func TestWriteRerunFailsReport_HandlesMissingActionRunEvents(t *testing.T) {
 	reportFile := fs.NewFile(t, t.Name())
 	defer reportFile.Remove()
@@ -136,6 +172,46 @@ func TestRerunFailed_ReturnsAnErrorWhenTheLastTestIsSuccessful(t *testing.T) {
 	assert.Error(t, err, "run-failed-3")
 }
 
+func TestRerunFailed_StopsWhenMaxTimeIsExceeded(t *testing.T) {
+	jsonFailed := `{"Package": "pkg", "Action": "run"}
+{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+{"Package": "pkg", "Test": "TestOne", "Action": "fail"}
+{"Package": "pkg", "Action": "fail"}
+`
+	clock := clockwork.NewFakeClock()
+	origClock := rerunClock
+	rerunClock = clock
+	defer func() { rerunClock = origClock }()
+
+	var started [][]string
+	fn := func(args []string) *proc {
+		started = append(started, args)
+		clock.Advance(time.Minute)
+		return &proc{
+			cmd:    fakeWaiter{result: newExitCode("run-failed", 1)},
+			stdout: strings.NewReader(jsonFailed),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	opts := &options{
+		rerunFailsMaxInitialFailures: 10,
+		rerunFailsMaxAttempts:        3,
+		rerunFailsMaxTime:            90 * time.Second,
+		stdout:                       new(bytes.Buffer),
+	}
+	cfg := testjson.ScanConfig{
+		Execution: newExecutionWithTwoFailures(t),
+		Handler:   noopHandler{},
+	}
+	err := rerunFailed(context.Background(), opts, cfg)
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+	assert.Equal(t, len(started), 2)
+	assert.Equal(t, len(opts.rerunCommands), 2)
+}
+
 func patchStartGoTestFn(f func(args []string) *proc) func() {
 	orig := startGoTestFn
 	startGoTestFn = func(ctx context.Context, args []string) (*proc, error) {
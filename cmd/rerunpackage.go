@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// rerunPackageAttempts sets the number of times the failed tests in packages
+// that match pattern are rerun.
+type rerunPackageAttempts struct {
+	pattern  string
+	attempts int
+}
+
+// rerunPackageAttemptsValue is a flag.Value which appends a
+// rerunPackageAttempts for each value. The format of the value is:
+// PATTERN=ATTEMPTS.
+type rerunPackageAttemptsValue []rerunPackageAttempts
+
+func (v *rerunPackageAttemptsValue) String() string {
+	values := make([]string, 0, len(*v))
+	for _, o := range *v {
+		values = append(values, o.pattern+"="+strconv.Itoa(o.attempts))
+	}
+	return strings.Join(values, ",")
+}
+
+func (v *rerunPackageAttemptsValue) Set(raw string) error {
+	pattern, value := cutString(raw, "=")
+	pattern = strings.TrimSpace(pattern)
+	attempts, err := strconv.Atoi(strings.TrimSpace(value))
+	if pattern == "" || err != nil || attempts < 0 {
+		return errors.Errorf("invalid value %q, must be PATTERN=ATTEMPTS", raw)
+	}
+	*v = append(*v, rerunPackageAttempts{pattern: pattern, attempts: attempts})
+	return nil
+}
+
+func (v *rerunPackageAttemptsValue) Type() string {
+	return "pattern=attempts"
+}
+
+// rerunAttemptsForPackage returns the maximum number of times the failed tests
+// in pkg are rerun. The first --rerun-fails-package that matches pkg is used,
+// otherwise the default from defaultRerunAttempts.
+func rerunAttemptsForPackage(opts *options, pkg string) int {
+	for _, o := range opts.rerunFailsPackages {
+		if matchPackagePattern(o.pattern, pkg) {
+			return o.attempts
+		}
+	}
+	return defaultRerunAttempts(opts)
+}
+
+// defaultRerunAttempts returns the maximum number of times a failed test is
+// rerun when its package does not match a --rerun-fails-package. The value of
+// --rerun-fails-max-attempts-per-test is used when it is set, otherwise the
+// value of --rerun-fails.
+func defaultRerunAttempts(opts *options) int {
+	if opts.rerunFailsMaxAttemptsPerTest > 0 {
+		return opts.rerunFailsMaxAttemptsPerTest
+	}
+	return opts.rerunFailsMaxAttempts
+}
+
+// maxRerunAttempts returns the maximum number of times any failed test may be
+// rerun.
+func maxRerunAttempts(opts *options) int {
+	max := defaultRerunAttempts(opts)
+	for _, o := range opts.rerunFailsPackages {
+		if o.attempts > max {
+			max = o.attempts
+		}
+	}
+	return max
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestRerunPackageAttemptsValue_Set(t *testing.T) {
+	var v rerunPackageAttemptsValue
+	assert.NilError(t, v.Set("example.com/integration/...=3"))
+	assert.NilError(t, v.Set("example.com/unit=0"))
+	assert.Equal(t, v.String(), "example.com/integration/...=3,example.com/unit=0")
+
+	for _, value := range []string{"example.com/unit", "=2", "example.com/unit=-1", "example.com/unit=two"} {
+		assert.ErrorContains(t, v.Set(value), "must be PATTERN=ATTEMPTS", value)
+	}
+}
+
+func TestRerunAttemptsForPackage(t *testing.T) {
+	opts := &options{rerunFailsMaxAttempts: 2}
+	assert.NilError(t, opts.rerunFailsPackages.Set("example.com/integration/...=4"))
+	assert.NilError(t, opts.rerunFailsPackages.Set("example.com/...=0"))
+
+	assert.Equal(t, rerunAttemptsForPackage(opts, "example.com/integration/db"), 4)
+	assert.Equal(t, rerunAttemptsForPackage(opts, "example.com/unit"), 0)
+	assert.Equal(t, rerunAttemptsForPackage(opts, "other.com/unit"), 2)
+	assert.Equal(t, maxRerunAttempts(opts), 4)
+}
+
+func TestRerunFailed_WithPackageAttempts(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Package": "example.com/unit", "Test": "TestOne", "Action": "run"}
+{"Package": "example.com/unit", "Test": "TestOne", "Action": "fail"}
+{"Package": "example.com/unit", "Action": "fail"}
+{"Package": "example.com/integration", "Test": "TestTwo", "Action": "run"}
+{"Package": "example.com/integration", "Test": "TestTwo", "Action": "fail"}
+{"Package": "example.com/integration", "Action": "fail"}
+`),
+	})
+	assert.NilError(t, err)
+
+	outputs := []string{
+		`{"Package": "example.com/integration", "Test": "TestTwo", "Action": "run"}
+{"Package": "example.com/integration", "Test": "TestTwo", "Action": "fail"}
+{"Package": "example.com/integration", "Action": "fail"}
+`,
+		`{"Package": "example.com/integration", "Test": "TestTwo", "Action": "run"}
+{"Package": "example.com/integration", "Test": "TestTwo", "Action": "pass"}
+{"Package": "example.com/integration", "Action": "pass"}
+`,
+	}
+	var started [][]string
+	fn := func(args []string) *proc {
+		started = append(started, args)
+		out := outputs[0]
+		outputs = outputs[1:]
+		var err error
+		if strings.Contains(out, `"fail"`) {
+			err = newExitCode("run-failed", 1)
+		}
+		return &proc{
+			cmd:    fakeWaiter{result: err},
+			stdout: strings.NewReader(out),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	opts := &options{
+		rerunFailsMaxInitialFailures: 10,
+		stdout:                       new(bytes.Buffer),
+	}
+	assert.NilError(t, opts.rerunFailsPackages.Set("example.com/integration=3"))
+	cfg := testjson.ScanConfig{Execution: exec, Handler: noopHandler{}}
+
+	err = rerunFailed(context.Background(), opts, cfg)
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+	assert.Equal(t, len(started), 2)
+	for _, args := range started {
+		assert.Equal(t, args[len(args)-1], "example.com/integration")
+	}
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+var rerunFailsReportFormatValues = "text, json"
+
+// rerunFailsReportFormatValue is the flag.Value for --rerun-fails-report-format.
+type rerunFailsReportFormatValue string
+
+const (
+	// rerunFailsReportText writes a line for each test that was rerun, with
+	// the number of runs and failures.
+	rerunFailsReportText rerunFailsReportFormatValue = "text"
+	// rerunFailsReportJSON writes a rerunReport, with the result of every
+	// attempt of each test, and the commands used to rerun them.
+	rerunFailsReportJSON rerunFailsReportFormatValue = "json"
+)
+
+func (v *rerunFailsReportFormatValue) Set(val string) error {
+	switch rerunFailsReportFormatValue(val) {
+	case rerunFailsReportText, rerunFailsReportJSON:
+		*v = rerunFailsReportFormatValue(val)
+		return nil
+	}
+	return errors.Errorf("invalid value: %v, must be one of: "+rerunFailsReportFormatValues, val)
+}
+
+func (v *rerunFailsReportFormatValue) Type() string {
+	return "format"
+}
+
+func (v *rerunFailsReportFormatValue) String() string {
+	if *v == "" {
+		return string(rerunFailsReportText)
+	}
+	return string(*v)
+}
+
+// rerunCommand is a go test command run by --rerun-fails.
+type rerunCommand struct {
+	Attempt int
+	Package string
+	// Test is empty when the whole package is rerun, from
+	// --rerun-fails-scope=package.
+	Test string
+	// RunPattern is the value of the -test.run flag used to select the test.
+	RunPattern string
+	Command    []string
+	// Traceparent is the W3C trace context of the command, from
+	// --trace-context.
+	Traceparent string `json:",omitempty"`
+}
+
+// rerunReport is the structure of the --rerun-fails-report file when
+// --rerun-fails-report-format=json.
+type rerunReport struct {
+	// RunID is the ID of the run, from --run-id or a random ID.
+	RunID       string `json:",omitempty"`
+	MaxAttempts int
+	Tests       []rerunReportTest
+	Commands    []rerunCommand
+}
+
+type rerunReportTest struct {
+	Package string
+	Test    string
+	// Status is the result of the last attempt, pass or fail.
+	Status   testjson.Action
+	Attempts []rerunReportAttempt
+}
+
+type rerunReportAttempt struct {
+	// Attempt is 0 for the first run, and the number of the rerun for any
+	// other attempt.
+	Attempt int
+	Status  testjson.Action
+	Elapsed time.Duration
+}
+
+func newRerunReport(opts *options, exec *testjson.Execution) rerunReport {
+	report := rerunReport{
+		RunID:       opts.runID,
+		MaxAttempts: maxRerunAttempts(opts),
+		Tests:       []rerunReportTest{},
+		Commands:    opts.rerunCommands,
+	}
+	if report.Commands == nil {
+		report.Commands = []rerunCommand{}
+	}
+
+	seen := map[string]bool{}
+	for _, failure := range exec.Failed() {
+		key := failure.Package + "." + failure.Test.Name()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		type result struct {
+			tc     testjson.TestCase
+			status testjson.Action
+		}
+		var results []result
+		pkg := exec.Package(failure.Package)
+		for _, tc := range pkg.Failed {
+			if tc.Test == failure.Test {
+				results = append(results, result{tc: tc, status: testjson.ActionFail})
+			}
+		}
+		for _, tc := range pkg.Passed {
+			if tc.Test == failure.Test {
+				results = append(results, result{tc: tc, status: testjson.ActionPass})
+			}
+		}
+		// TestCase.ID increases in the order the tests were run.
+		sort.Slice(results, func(i, j int) bool {
+			return results[i].tc.ID < results[j].tc.ID
+		})
+
+		test := rerunReportTest{Package: failure.Package, Test: failure.Test.Name()}
+		for _, r := range results {
+			test.Attempts = append(test.Attempts, rerunReportAttempt{
+				Attempt: r.tc.RunID,
+				Status:  r.status,
+				Elapsed: r.tc.Elapsed,
+			})
+		}
+		test.Status = test.Attempts[len(test.Attempts)-1].Status
+		report.Tests = append(report.Tests, test)
+	}
+
+	sort.Slice(report.Tests, func(i, j int) bool {
+		a, b := report.Tests[i], report.Tests[j]
+		return a.Package+"."+a.Test < b.Package+"."+b.Test
+	})
+	return report
+}
+
+func writeRerunFailsReportJSON(opts *options, exec *testjson.Execution) error {
+	fh, err := createOutputFile(opts.rerunFailsReportFile)
+	if err != nil {
+		return err
+	}
+	defer fh.Close() // nolint: errcheck
+
+	enc := json.NewEncoder(fh)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(newRerunReport(opts, exec)); err != nil {
+		return fmt.Errorf("failed to write rerun report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+var rerunFailsScopeValues = "test, package"
+
+// rerunFailsScopeValue is the flag.Value for --rerun-fails-scope, which sets
+// what is rerun when a test fails.
+type rerunFailsScopeValue string
+
+const (
+	// rerunScopeTest reruns each failed test, using -test.run to select the
+	// test.
+	rerunScopeTest rerunFailsScopeValue = "test"
+	// rerunScopePackage reruns all the tests in a package that has a failed
+	// test.
+	rerunScopePackage rerunFailsScopeValue = "package"
+)
+
+func (v *rerunFailsScopeValue) Set(val string) error {
+	switch rerunFailsScopeValue(val) {
+	case rerunScopeTest, rerunScopePackage:
+		*v = rerunFailsScopeValue(val)
+		return nil
+	}
+	return errors.Errorf("invalid value: %v, must be one of: "+rerunFailsScopeValues, val)
+}
+
+func (v *rerunFailsScopeValue) Type() string {
+	return "scope"
+}
+
+func (v *rerunFailsScopeValue) String() string {
+	if *v == "" {
+		return string(rerunScopeTest)
+	}
+	return string(*v)
+}
+
+func validateRerunFailsScope(opts *options) error {
+	if opts.rerunFailsScope == rerunScopePackage && maxRerunAttempts(opts) == 0 {
+		return fmt.Errorf("--rerun-fails-scope=package requires --rerun-fails or --rerun-fails-max-attempts-per-test")
+	}
+	return nil
+}
+
+// rerunTargets returns the failed test cases to rerun. With
+// --rerun-fails-scope=package only the first failure in each package is
+// returned, because the rerun of that failure runs the whole package.
+func rerunTargets(opts *options, failures []testjson.TestCase) []testjson.TestCase {
+	if opts.rerunFailsScope != rerunScopePackage {
+		return failures
+	}
+	seen := make(map[string]bool)
+	var result []testjson.TestCase
+	for _, tc := range failures {
+		if seen[tc.Package] {
+			continue
+		}
+		seen[tc.Package] = true
+		result = append(result, tc)
+	}
+	return result
+}
+
+// rerunOptsForTestCase returns the rerunOpts used to rerun the failed test
+// case tc.
+func rerunOptsForTestCase(opts *options, tc testjson.TestCase) rerunOpts {
+	if opts.rerunFailsScope == rerunScopePackage {
+		return rerunOpts{pkg: tc.Package}
+	}
+	return newRerunOptsFromTestCase(tc)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestRerunFailed_WithPackageScope(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Package": "example.com/db", "Test": "TestOne", "Action": "run"}
+{"Package": "example.com/db", "Test": "TestOne", "Action": "fail"}
+{"Package": "example.com/db", "Test": "TestTwo", "Action": "run"}
+{"Package": "example.com/db", "Test": "TestTwo", "Action": "fail"}
+{"Package": "example.com/db", "Action": "fail"}
+{"Package": "example.com/api", "Test": "TestThree", "Action": "run"}
+{"Package": "example.com/api", "Test": "TestThree", "Action": "fail"}
+{"Package": "example.com/api", "Action": "fail"}
+`),
+	})
+	assert.NilError(t, err)
+
+	var started [][]string
+	defer patchStartGoTestFn(func(args []string) *proc {
+		started = append(started, args)
+		pkg := args[len(args)-1]
+		return &proc{
+			cmd: fakeWaiter{},
+			stdout: strings.NewReader(`{"Package": "` + pkg + `", "Test": "TestOne", "Action": "run"}
+{"Package": "` + pkg + `", "Test": "TestOne", "Action": "pass"}
+{"Package": "` + pkg + `", "Action": "pass"}
+`),
+			stderr: bytes.NewReader(nil),
+		}
+	})()
+
+	opts := &options{
+		rerunFailsMaxAttempts:        2,
+		rerunFailsScope:              rerunScopePackage,
+		rerunFailsMaxInitialFailures: 10,
+		stdout:                       new(bytes.Buffer),
+	}
+	cfg := testjson.ScanConfig{Execution: exec, Handler: noopHandler{}}
+
+	err = rerunFailed(context.Background(), opts, cfg)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, started, [][]string{
+		{"go", "test", "-json", "example.com/api"},
+		{"go", "test", "-json", "example.com/db"},
+	})
+	assert.Equal(t, opts.rerunCommands[0].Test, "")
+	assert.Equal(t, opts.rerunCommands[0].RunPattern, "")
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+func validateRerunFailsPerTest(opts *options) error {
+	switch {
+	case opts.rerunFailsMaxAttemptsPerTest < 0:
+		return fmt.Errorf("--rerun-fails-max-attempts-per-test must not be negative")
+	case opts.rerunFailsDelay < 0:
+		return fmt.Errorf("--rerun-fails-delay must not be negative")
+	case opts.rerunFailsBackoff && opts.rerunFailsDelay == 0:
+		return fmt.Errorf("--rerun-fails-backoff requires --rerun-fails-delay")
+	case opts.rerunFailsDelay > 0 && maxRerunAttempts(opts) == 0:
+		return fmt.Errorf("--rerun-fails-delay requires --rerun-fails or --rerun-fails-max-attempts-per-test")
+	}
+	return nil
+}
+
+// rerunFailedPerTest reruns each failed test until it passes, or until it has
+// been rerun the maximum number of attempts for the test. Unlike the rounds
+// used by rerunFailed, a test that fails again is rerun right away, so that
+// each test uses its own attempts, and the delay between attempts applies to
+// each test.
+func rerunFailedPerTest(ctx context.Context, opts *options, scanConfig testjson.ScanConfig) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer endRerunSpans(opts)
+	outOfTime := newRerunDeadline(opts)
+
+	failures := rerunTargets(opts, rerunFailsFilter(opts)(scanConfig.Execution.Failed()))
+	if len(failures) == 0 {
+		return nil
+	}
+	if !opts.scriptOutput {
+		testjson.PrintSummaryWithOptions(opts.stdout, scanConfig.Execution, testjson.SummarizeNone, opts.formatOptions)
+		opts.stdout.Write([]byte("\n")) // nolint: errcheck
+	}
+
+	var lastErr error
+	for i, tc := range failures {
+		// exitErr is the error from the last attempt of the test, or from the
+		// initial run when the test is not rerun.
+		var exitErr error = exitError{num: 1}
+		for attempt := 1; attempt <= rerunAttemptsForPackage(opts, tc.Package); attempt++ {
+			if outOfTime(len(failures) - i) {
+				return exitError{num: 1}
+			}
+			var err error
+			exitErr, err = runRerunAttempt(ctx, opts, scanConfig, scanConfig.Handler, cancel, tc, attempt)
+			if err != nil {
+				return err
+			}
+			if exitErr == nil {
+				break
+			}
+		}
+		if exitErr != nil {
+			lastErr = exitErr
+		}
+	}
+	return lastErr
+}
+
+// rerunDelay returns the time to wait before the attempt to rerun a test. With
+// --rerun-fails-backoff the delay is doubled for each attempt after the first.
+func rerunDelay(opts *options, attempt int) time.Duration {
+	delay := opts.rerunFailsDelay
+	if delay <= 0 {
+		return 0
+	}
+	if opts.rerunFailsBackoff {
+		for i := 1; i < attempt; i++ {
+			delay *= 2
+		}
+	}
+	return delay
+}
+
+func rerunBackoffDescription(opts *options) string {
+	if !opts.rerunFailsBackoff {
+		return ""
+	}
+	return ", doubled after each attempt"
+}
+
+// rerunSleep is a shim for testing
+var rerunSleep = func(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
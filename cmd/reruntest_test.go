@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func patchRerunSleep(f func(ctx context.Context, d time.Duration) error) func() {
+	orig := rerunSleep
+	rerunSleep = f
+	return func() {
+		rerunSleep = orig
+	}
+}
+
+func TestRerunDelay(t *testing.T) {
+	opts := &options{rerunFailsDelay: time.Second}
+	assert.Equal(t, rerunDelay(opts, 1), time.Second)
+	assert.Equal(t, rerunDelay(opts, 3), time.Second)
+
+	opts.rerunFailsBackoff = true
+	assert.Equal(t, rerunDelay(opts, 1), time.Second)
+	assert.Equal(t, rerunDelay(opts, 3), 4*time.Second)
+
+	assert.Equal(t, rerunDelay(&options{rerunFailsBackoff: true}, 3), time.Duration(0))
+}
+
+func TestRerunFailed_WithMaxAttemptsPerTest(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Package": "example.com/api", "Test": "TestLimited", "Action": "run"}
+{"Package": "example.com/api", "Test": "TestLimited", "Action": "fail"}
+{"Package": "example.com/api", "Test": "TestOther", "Action": "run"}
+{"Package": "example.com/api", "Test": "TestOther", "Action": "fail"}
+{"Package": "example.com/api", "Action": "fail"}
+`),
+	})
+	assert.NilError(t, err)
+
+	// TestLimited fails again on the first two reruns, and TestOther passes
+	// on the first rerun.
+	results := map[string][]string{
+		"TestLimited": {"fail", "fail", "pass"},
+		"TestOther":   {"pass"},
+	}
+	var started []string
+	defer patchStartGoTestFn(func(args []string) *proc {
+		name := strings.TrimSuffix(strings.TrimPrefix(args[3], "-test.run=^"), "$")
+		started = append(started, name)
+		action := results[name][0]
+		results[name] = results[name][1:]
+		var err error
+		if action == "fail" {
+			err = newExitCode("run-failed", 1)
+		}
+		return &proc{
+			cmd: fakeWaiter{result: err},
+			stdout: strings.NewReader(`{"Package": "example.com/api", "Test": "` + name + `", "Action": "run"}
+{"Package": "example.com/api", "Test": "` + name + `", "Action": "` + action + `"}
+{"Package": "example.com/api", "Action": "` + action + `"}
+`),
+			stderr: bytes.NewReader(nil),
+		}
+	})()
+
+	var delays []time.Duration
+	defer patchRerunSleep(func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	})()
+
+	opts := &options{
+		rerunFailsMaxAttemptsPerTest: 3,
+		rerunFailsDelay:              time.Second,
+		rerunFailsBackoff:            true,
+		rerunFailsMaxInitialFailures: 10,
+		stdout:                       new(bytes.Buffer),
+	}
+	cfg := testjson.ScanConfig{Execution: exec, Handler: noopHandler{}}
+
+	err = rerunFailed(context.Background(), opts, cfg)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, started, []string{"TestLimited", "TestLimited", "TestLimited", "TestOther"})
+	assert.DeepEqual(t, delays, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, time.Second})
+
+	var attempts []int
+	for _, cmd := range opts.rerunCommands {
+		attempts = append(attempts, cmd.Attempt)
+	}
+	assert.DeepEqual(t, attempts, []int{1, 2, 3, 1})
+}
+
+func TestRerunFailed_WithMaxAttemptsPerTest_ExhaustsAttempts(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Package": "example.com/api", "Test": "TestLimited", "Action": "run"}
+{"Package": "example.com/api", "Test": "TestLimited", "Action": "fail"}
+{"Package": "example.com/api", "Action": "fail"}
+`),
+	})
+	assert.NilError(t, err)
+
+	var started int
+	defer patchStartGoTestFn(func(args []string) *proc {
+		started++
+		return &proc{
+			cmd: fakeWaiter{result: newExitCode("run-failed", 1)},
+			stdout: strings.NewReader(`{"Package": "example.com/api", "Test": "TestLimited", "Action": "run"}
+{"Package": "example.com/api", "Test": "TestLimited", "Action": "fail"}
+{"Package": "example.com/api", "Action": "fail"}
+`),
+			stderr: bytes.NewReader(nil),
+		}
+	})()
+
+	opts := &options{
+		rerunFailsMaxAttemptsPerTest: 2,
+		rerunFailsMaxInitialFailures: 10,
+		stdout:                       new(bytes.Buffer),
+	}
+	cfg := testjson.ScanConfig{Execution: exec, Handler: noopHandler{}}
+
+	err = rerunFailed(context.Background(), opts, cfg)
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+	assert.Equal(t, started, 2)
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/log"
+)
+
+// rewriteRule replaces every match of pattern in a test name with replacement.
+type rewriteRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// rewriteRulesValue is the flag.Value for --rewrite-test-name. Each value
+// appends a rule in the format: REGEX=REPLACEMENT.
+type rewriteRulesValue []rewriteRule
+
+func (v *rewriteRulesValue) Set(raw string) error {
+	i := strings.LastIndex(raw, "=")
+	if i <= 0 {
+		return errors.Errorf("invalid value %q, must be REGEX=REPLACEMENT", raw)
+	}
+	pattern, err := regexp.Compile(raw[:i])
+	if err != nil {
+		return errors.Wrapf(err, "invalid regex in %q", raw)
+	}
+	*v = append(*v, rewriteRule{pattern: pattern, replacement: raw[i+1:]})
+	return nil
+}
+
+func (v *rewriteRulesValue) Type() string {
+	return "rule"
+}
+
+func (v *rewriteRulesValue) String() string {
+	rules := make([]string, 0, len(*v))
+	for _, rule := range *v {
+		rules = append(rules, rule.pattern.String()+"="+rule.replacement)
+	}
+	return strings.Join(rules, ",")
+}
+
+// rewriteTemplateValue is the flag.Value for --rewrite-test-name-template.
+type rewriteTemplateValue struct {
+	raw  string
+	tmpl *template.Template
+}
+
+func (v *rewriteTemplateValue) Set(raw string) error {
+	tmpl, err := template.New("rewrite-test-name").Option("missingkey=error").Parse(raw)
+	if err != nil {
+		return errors.Wrap(err, "invalid template")
+	}
+	v.raw, v.tmpl = raw, tmpl
+	return nil
+}
+
+func (v *rewriteTemplateValue) Type() string {
+	return "template"
+}
+
+func (v *rewriteTemplateValue) String() string {
+	return v.raw
+}
+
+// rewriteTemplateData is the data used to execute the
+// --rewrite-test-name-template.
+type rewriteTemplateData struct {
+	Package string
+	Name    string
+}
+
+// newTestNameRewriter returns the function used for
+// testjson.ScanConfig.RewriteTestName. The rules from --rewrite-test-name are
+// applied in order, followed by --rewrite-test-name-template. Returns nil if
+// neither flag was set.
+func newTestNameRewriter(opts *options) func(pkg, name string) string {
+	rules, tmpl := opts.rewriteTestName, opts.rewriteTestNameTemplate.tmpl
+	if len(rules) == 0 && tmpl == nil {
+		return nil
+	}
+	return func(pkg, name string) string {
+		for _, rule := range rules {
+			name = rule.pattern.ReplaceAllString(name, rule.replacement)
+		}
+		if tmpl == nil {
+			return name
+		}
+		buf := new(strings.Builder)
+		if err := tmpl.Execute(buf, rewriteTemplateData{Package: pkg, Name: name}); err != nil {
+			log.Warnf("failed to rewrite test name %v: %v", name, err)
+			return name
+		}
+		return buf.String()
+	}
+}
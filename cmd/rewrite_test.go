@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestNewTestNameRewriter(t *testing.T) {
+	type testCase struct {
+		name     string
+		args     []string
+		pkg      string
+		test     string
+		expected string
+	}
+
+	fn := func(t *testing.T, tc testCase) {
+		flags, opts := setupFlags("gotestsum")
+		assert.NilError(t, flags.Parse(tc.args))
+
+		rewrite := newTestNameRewriter(opts)
+		assert.Assert(t, rewrite != nil)
+		assert.Equal(t, rewrite(tc.pkg, tc.test), tc.expected)
+	}
+
+	var testCases = []testCase{
+		{
+			name:     "regex",
+			args:     []string{"--rewrite-test-name", `_[0-9a-f]{8}$=`},
+			test:     "TestGenerated_deadbeef",
+			expected: "TestGenerated",
+		},
+		{
+			name: "regex with capture groups, applied in order",
+			args: []string{
+				"--rewrite-test-name", `^(Test\w+)/case_(\d+)$=$1/JIRA-$2`,
+				"--rewrite-test-name", `^TestIssues/=`,
+			},
+			test:     "TestIssues/case_42",
+			expected: "JIRA-42",
+		},
+		{
+			name:     "template",
+			args:     []string{"--rewrite-test-name-template", "{{.Name}} [{{.Package}}]"},
+			pkg:      "example.com/pkg",
+			test:     "TestOne",
+			expected: "TestOne [example.com/pkg]",
+		},
+		{
+			name: "template after regex",
+			args: []string{
+				"--rewrite-test-name-template", "x/{{.Name}}",
+				"--rewrite-test-name", "One=Two",
+			},
+			test:     "TestOne",
+			expected: "x/TestTwo",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fn(t, tc)
+		})
+	}
+}
+
+func TestNewTestNameRewriter_NotSet(t *testing.T) {
+	assert.Assert(t, newTestNameRewriter(&options{}) == nil)
+}
+
+func TestRewriteRulesValue_Set_Invalid(t *testing.T) {
+	value := &rewriteRulesValue{}
+	assert.ErrorContains(t, value.Set("no-separator"), "must be REGEX=REPLACEMENT")
+	assert.ErrorContains(t, value.Set("=foo"), "must be REGEX=REPLACEMENT")
+	assert.ErrorContains(t, value.Set("(=foo"), "invalid regex")
+}
+
+func TestRewriteTemplateValue_Set_Invalid(t *testing.T) {
+	value := &rewriteTemplateValue{}
+	assert.ErrorContains(t, value.Set("{{.Name"), "invalid template")
+}
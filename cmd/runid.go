@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"fmt"
+	"regexp"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+)
+
+const (
+	// runIDJUnitProperty is the name of the property of each JUnit testsuite
+	// which is set to the run ID.
+	runIDJUnitProperty = "gotestsum.run.id"
+	// uploadRunIDMetadataKey is the key of the metadata of each uploaded
+	// object which is set to the run ID.
+	uploadRunIDMetadataKey = "gotestsum-run-id"
+)
+
+// newRunID is a shim for testing. It returns a random ID in the format of a
+// version 4 UUID.
+var newRunID = func() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %v", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// setupRunID sets opts.runID to a new run ID if one was not set by --run-id.
+func setupRunID(opts *options) {
+	if opts.runID == "" {
+		opts.runID = newRunID()
+	}
+}
+
+// runIDPattern matches the characters which are safe to use in a file path, an
+// HTTP header, and a JUnit property.
+var runIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+func validateRunID(opts *options) error {
+	if opts.runID == "" || runIDPattern.MatchString(opts.runID) {
+		return nil
+	}
+	return fmt.Errorf("invalid --run-id %q, must contain only letters, digits, '.', '_', or '-'", opts.runID)
+}
+
+func runIDJUnitProperties(opts *options) []junitxml.JUnitProperty {
+	if opts.runID == "" {
+		return nil
+	}
+	return []junitxml.JUnitProperty{{Name: runIDJUnitProperty, Value: opts.runID}}
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestNewRunID(t *testing.T) {
+	id := newRunID()
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	assert.Assert(t, uuid.MatchString(id), id)
+	assert.Assert(t, id != newRunID())
+}
+
+func TestSetupRunID(t *testing.T) {
+	opts := &options{runID: "ci-1234"}
+	setupRunID(opts)
+	assert.Equal(t, opts.runID, "ci-1234")
+
+	orig := newRunID
+	newRunID = func() string { return "random" }
+	defer func() { newRunID = orig }()
+	opts = &options{}
+	setupRunID(opts)
+	assert.Equal(t, opts.runID, "random")
+}
+
+func TestOptions_Validate_RunID(t *testing.T) {
+	opts := &options{runID: "build.42_attempt-1"}
+	assert.NilError(t, opts.Validate())
+
+	opts = &options{runID: "../escape"}
+	assert.ErrorContains(t, opts.Validate(), `invalid --run-id "../escape"`)
+}
+
+func TestNewEventHandler_WritesRunStartEvent(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+
+	opts := &options{
+		format:   "testname",
+		jsonFile: dir.Join("events.json"),
+		runID:    "ci-1234",
+		stdout:   new(bytes.Buffer),
+		stderr:   new(bytes.Buffer),
+	}
+	handler, err := newEventHandler(opts)
+	assert.NilError(t, err)
+	assert.NilError(t, handler.Close())
+
+	fh, err := os.Open(opts.jsonFile)
+	assert.NilError(t, err)
+	defer fh.Close() // nolint: errcheck
+	scanner := bufio.NewScanner(fh)
+	assert.Assert(t, scanner.Scan())
+
+	var event testjson.TestEvent
+	assert.NilError(t, json.Unmarshal(scanner.Bytes(), &event))
+	assert.Equal(t, event.Action, testjson.ActionRunStart)
+	assert.Equal(t, event.Output, "ci-1234")
+}
+
+func TestEventHandler_WritesRunStartEventForRerunAttempts(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+
+	opts := &options{
+		format:   "testname",
+		jsonFile: dir.Join("events.json"),
+		runID:    "ci-1234",
+		stdout:   new(bytes.Buffer),
+		stderr:   new(bytes.Buffer),
+	}
+	handler, err := newEventHandler(opts)
+	assert.NilError(t, err)
+
+	attempt := func(runID int, result string) {
+		_, err := testjson.ScanTestOutput(testjson.ScanConfig{
+			Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"` + result + `","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"` + result + `","Package":"example.com/pkg"}
+`),
+			Handler: handler,
+			RunID:   runID,
+		})
+		assert.NilError(t, err)
+	}
+	attempt(0, "fail")
+	attempt(1, "fail")
+	attempt(1, "pass")
+	assert.NilError(t, handler.Close())
+
+	fh, err := os.Open(opts.jsonFile)
+	assert.NilError(t, err)
+	defer fh.Close() // nolint: errcheck
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: fh, SplitRuns: true})
+	assert.NilError(t, err)
+
+	pkg := exec.Package("example.com/pkg")
+	assert.Equal(t, len(pkg.Failed), 2)
+	assert.Equal(t, pkg.Failed[0].RunID, 0)
+	assert.Equal(t, pkg.Failed[1].RunID, 1)
+	assert.Equal(t, len(pkg.Passed), 1)
+	assert.Equal(t, pkg.Passed[0].RunID, 1)
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// scriptOutputVersion is the version of the --script-output protocol. The
+// version must be incremented when a change to the protocol could break an
+// existing script. Adding a new field to the end of a line, or a new type of
+// line, does not require a new version.
+const scriptOutputVersion = 1
+
+// scriptFormatter is the testjson.EventFormatter for --script-output. See
+// the "Script output" section of the README for the documentation of the
+// protocol.
+type scriptFormatter struct {
+	out io.Writer
+}
+
+// newScriptFormatter returns a new scriptFormatter, after writing the VERSION
+// line to out.
+func newScriptFormatter(out io.Writer) (*scriptFormatter, error) {
+	err := writeScriptLine(out, "VERSION", fmt.Sprint(scriptOutputVersion))
+	return &scriptFormatter{out: out}, err
+}
+
+func (f *scriptFormatter) Format(event testjson.TestEvent, _ *testjson.Execution) error {
+	switch {
+	case !event.Action.IsTerminal():
+		return nil
+	case event.PackageEvent():
+		return writeScriptLine(f.out, "PACKAGE", event.Package,
+			string(event.Action), scriptDuration(event.Elapsed))
+	default:
+		return writeScriptLine(f.out, "RESULT", event.Package, event.Test,
+			string(event.Action), scriptDuration(event.Elapsed))
+	}
+}
+
+// printScriptSummary writes an ERROR line for each error, and the DONE line
+// with the totals from all of execs.
+func printScriptSummary(out io.Writer, execs ...*testjson.Execution) error {
+	for _, exec := range execs {
+		if err := writeScriptErrors(out, exec); err != nil {
+			return err
+		}
+	}
+	return writeScriptDone(out, execs...)
+}
+
+// writeScriptGroup writes the GROUP line for the --pkg-group with name.
+func writeScriptGroup(out io.Writer, name string) error {
+	return writeScriptLine(out, "GROUP", name)
+}
+
+// writeScriptErrors writes an ERROR line for each error in exec.
+func writeScriptErrors(out io.Writer, exec *testjson.Execution) error {
+	for _, msg := range exec.Errors() {
+		if err := writeScriptLine(out, "ERROR", msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeScriptDone writes the DONE line with the totals from all of execs.
+func writeScriptDone(out io.Writer, execs ...*testjson.Execution) error {
+	var total, failed, skipped, errors int
+	var elapsed time.Duration
+	for _, exec := range execs {
+		total += exec.Total()
+		failed += len(exec.Failed())
+		skipped += len(exec.Skipped())
+		errors += len(exec.Errors())
+		elapsed += exec.Elapsed()
+	}
+	return writeScriptLine(out, "DONE", fmt.Sprint(total), fmt.Sprint(failed),
+		fmt.Sprint(skipped), fmt.Sprint(errors), scriptDuration(elapsed.Seconds()))
+}
+
+// scriptDuration formats a number of seconds with millisecond precision.
+func scriptDuration(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
+
+var scriptFieldEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// writeScriptLine writes the fields separated by tabs. Backslash, tab, and
+// line break characters in a field are escaped, so that each line always has the
+// same number of fields.
+func writeScriptLine(out io.Writer, fields ...string) error {
+	escaped := make([]string, len(fields))
+	for i, field := range fields {
+		escaped[i] = scriptFieldEscaper.Replace(field)
+	}
+	_, err := io.WriteString(out, strings.Join(escaped, "\t")+"\n")
+	return err
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+type formatterHandler struct {
+	testjson.EventFormatter
+}
+
+func (h formatterHandler) Event(event testjson.TestEvent, exec *testjson.Execution) error {
+	return h.Format(event, exec)
+}
+
+func (h formatterHandler) Err(string) error {
+	return nil
+}
+
+func TestScriptFormatter(t *testing.T) {
+	in := `{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestOne","Output":"=== RUN   TestOne\n"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestOne","Elapsed":0.012}
+{"Action":"run","Package":"example.com/pkg","Test":"TestTwo_tab\tand\\slash"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestTwo_tab\tand\\slash","Elapsed":1.5}
+{"Action":"run","Package":"example.com/pkg","Test":"TestThree"}
+{"Action":"skip","Package":"example.com/pkg","Test":"TestThree"}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":2.25}
+{"Action":"output","Package":"example.com/empty","Output":"?   \texample.com/empty\t[no test files]\n"}
+{"Action":"skip","Package":"example.com/empty","Elapsed":0}
+`
+	out := new(bytes.Buffer)
+	formatter, err := newScriptFormatter(out)
+	assert.NilError(t, err)
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  strings.NewReader(in),
+		Stderr:  strings.NewReader("example.com/broken: build failed\n"),
+		Handler: formatterHandler{formatter},
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, printScriptSummary(out, exec))
+
+	// remove the elapsed time from the DONE line, it uses the current time
+	lines := strings.Split(out.String(), "\n")
+	last := strings.Split(lines[len(lines)-2], "\t")
+	lines[len(lines)-2] = strings.Join(last[:len(last)-1], "\t")
+
+	expected := `VERSION	1
+RESULT	example.com/pkg	TestOne	pass	0.012
+RESULT	example.com/pkg	TestTwo_tab\tand\\slash	fail	1.500
+RESULT	example.com/pkg	TestThree	skip	0.000
+PACKAGE	example.com/pkg	fail	2.250
+PACKAGE	example.com/empty	skip	0.000
+ERROR	example.com/broken: build failed
+DONE	3	1	1	1
+`
+	assert.Equal(t, strings.Join(lines, "\n"), expected)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// slowPackageBaselineRuns is the maximum number of runs from the archive used
+// to compute the average elapsed time of each package for
+// --format-slow-package-percent.
+const slowPackageBaselineRuns = 10
+
+// setupSlowPackageBaseline sets the baseline of --format-slow-package-percent
+// to the average elapsed time of each package in the most recent runs in the
+// archive. The baseline is only used to mark packages in the output, so errors
+// reading the archive are logged instead of stopping the run.
+func setupSlowPackageBaseline(opts *options) {
+	if opts.formatOptions.SlowPackagePercent <= 0 {
+		return
+	}
+	baseline, err := slowPackageBaseline(opts.archiveDir, slowPackageBaselineRuns)
+	if err != nil {
+		log.Warnf("failed to read package elapsed times from %v: %v", opts.archiveDir, err)
+		return
+	}
+	opts.formatOptions.SlowPackageBaseline = testjson.NewPackageBaseline(baseline)
+}
+
+// slowPackageBaseline returns the average elapsed time of each package in the
+// most recent runs, up to maxRuns, from the archive in dir. Runs without
+// package elapsed times, for example because they were interrupted, or were
+// written by an older version, are ignored.
+func slowPackageBaseline(dir string, maxRuns int) (map[string]time.Duration, error) {
+	store, err := openHistory(dir)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := archiveRuns(store)
+	if err != nil {
+		return nil, err
+	}
+
+	total := make(map[string]time.Duration)
+	count := make(map[string]int)
+	var used int
+	for _, run := range runs {
+		if used >= maxRuns {
+			break
+		}
+		raw, err := store.ReadFile(context.Background(), run.name, archiveSummaryFile)
+		if err != nil {
+			log.Debugf("skipping run %v for package baseline: %v", run.name, err)
+			continue
+		}
+		var summary archiveSummary
+		if err := json.Unmarshal(raw, &summary); err != nil {
+			log.Debugf("skipping run %v for package baseline: %v", run.name, err)
+			continue
+		}
+		if len(summary.Packages) == 0 {
+			continue
+		}
+		used++
+		for pkg, elapsed := range summary.Packages {
+			total[pkg] += elapsed
+			count[pkg]++
+		}
+	}
+
+	baseline := make(map[string]time.Duration, len(total))
+	for pkg, elapsed := range total {
+		baseline[pkg] = elapsed / time.Duration(count[pkg])
+	}
+	return baseline, nil
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestSetupSlowPackageBaseline(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithDir("20210601T120000",
+			fs.WithFile(archiveSummaryFile, `{"Packages": {"example.com/one": 9000000000}}`)),
+		fs.WithDir("20210607T120000",
+			fs.WithFile(archiveSummaryFile, `{"Packages": {"example.com/one": 1000000000}}`)),
+		fs.WithDir("20210608T120000",
+			fs.WithFile(archiveSummaryFile, `{"Packages": {"example.com/one": 3000000000, "example.com/two": 500000000}}`)),
+		// a run written before package elapsed times were archived
+		fs.WithDir("20210609T120000", fs.WithFile(archiveSummaryFile, `{"Total": 3}`)),
+		// a run that was interrupted before it wrote the summary
+		fs.WithDir("20210610T110000"))
+	defer dir.Remove()
+
+	baseline, err := slowPackageBaseline(dir.Path(), 2)
+	assert.NilError(t, err)
+	expected := map[string]time.Duration{
+		"example.com/one": 2 * time.Second,
+		"example.com/two": 500 * time.Millisecond,
+	}
+	assert.DeepEqual(t, baseline, expected)
+
+	opts := &options{archiveDir: dir.Path()}
+	opts.formatOptions.SlowPackagePercent = 20
+	setupSlowPackageBaseline(opts)
+	elapsed, ok := opts.formatOptions.SlowPackageBaseline.Lookup("example.com/one")
+	assert.Assert(t, ok)
+	assert.Equal(t, elapsed, 13*time.Second/3)
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+const (
+	// snapshotCommandTimeout is the maximum time a --failure-snapshot-command
+	// may run for each failed test.
+	snapshotCommandTimeout = 10 * time.Second
+	// maxSnapshotSize is the maximum number of bytes of output kept from each
+	// run of the --failure-snapshot-command.
+	maxSnapshotSize = 16 * 1024
+)
+
+// snapshotHook takes a snapshot of the environment each time a test fails,
+// and stores it with the failed test, so that it is printed in the summary and
+// written to the JUnit XML file.
+type snapshotHook struct {
+	envVars []string
+	command []string
+	stderr  io.Writer
+	runID   string
+}
+
+func newSnapshotHook(opts *options) *snapshotHook {
+	var envVars []string
+	for _, name := range strings.Split(opts.failureSnapshotEnv, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			envVars = append(envVars, name)
+		}
+	}
+	command := opts.failureSnapshotCmd.Value()
+	if len(envVars) == 0 && len(command) == 0 {
+		return nil
+	}
+	return &snapshotHook{
+		envVars: envVars,
+		command: command,
+		stderr:  opts.stderr,
+		runID:   opts.runID,
+	}
+}
+
+func (h *snapshotHook) Event(event testjson.TestEvent, exec *testjson.Execution) {
+	if h == nil || event.PackageEvent() || event.Action != testjson.ActionFail {
+		return
+	}
+	pkg := exec.Package(event.Package)
+	tc := pkg.LastFailedByName(event.Test)
+	pkg.SetSnapshot(tc, h.snapshot(event))
+}
+
+// snapshot returns the values of the environment variables, followed by the
+// output of the command.
+func (h *snapshotHook) snapshot(event testjson.TestEvent) string {
+	buf := new(bytes.Buffer)
+	for _, name := range h.envVars {
+		if value, ok := os.LookupEnv(name); ok {
+			fmt.Fprintf(buf, "%s=%s\n", name, value)
+		} else {
+			fmt.Fprintf(buf, "%s is not set\n", name)
+		}
+	}
+	if len(h.command) == 0 {
+		return buf.String()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotCommandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, h.command[0], h.command[1:]...)
+	out := &limitedBuffer{max: maxSnapshotSize}
+	cmd.Stdout = out
+	cmd.Stderr = h.stderr
+	cmd.Env = append(
+		os.Environ(),
+		"TEST_PACKAGE="+event.Package,
+		"TEST_NAME="+event.Test,
+		"GOTESTSUM_RUN_ID="+h.runID,
+	)
+	log.Debugf("exec: %s", h.command)
+	err := cmd.Run()
+	buf.Write(out.Bytes())
+	if out.truncated {
+		fmt.Fprintf(buf, "\n(output truncated to %d bytes)\n", maxSnapshotSize)
+	}
+	if err != nil {
+		log.Warnf("failure-snapshot-command failed: %v", err)
+		fmt.Fprintf(buf, "\n(failure-snapshot-command failed: %v)\n", err)
+	}
+	return buf.String()
+}
+
+// limitedBuffer is a bytes.Buffer which discards everything written after the
+// first max bytes.
+type limitedBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.max - b.Len(); len(p) > remaining {
+		b.truncated = true
+		b.Buffer.Write(p[:remaining])
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
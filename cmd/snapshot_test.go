@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestSnapshotHook(t *testing.T) {
+	defer env.Patch(t, "GOTESTSUM_SNAPSHOT_VAR", "value")()
+
+	command := &commandValue{}
+	assert.NilError(t, command.Set("echo disk: ok"))
+	opts := &options{
+		failureSnapshotEnv: "GOTESTSUM_SNAPSHOT_VAR, GOTESTSUM_SNAPSHOT_UNSET",
+		failureSnapshotCmd: command,
+		stderr:             new(bytes.Buffer),
+	}
+	hook := newSnapshotHook(opts)
+	assert.Assert(t, hook != nil)
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestFails"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFails","Elapsed":0.1}
+{"Action":"run","Package":"example.com/pkg","Test":"TestPasses"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestPasses","Elapsed":0.1}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.2}
+`),
+		Handler: &snapshotHandler{hook: hook},
+	})
+	assert.NilError(t, err)
+
+	pkg := exec.Package("example.com/pkg")
+	expected := `GOTESTSUM_SNAPSHOT_VAR=value
+GOTESTSUM_SNAPSHOT_UNSET is not set
+disk: ok
+`
+	assert.Equal(t, pkg.Snapshot(pkg.Failed[0]), expected)
+	assert.Equal(t, pkg.Snapshot(pkg.Passed[0]), "")
+
+	out := new(bytes.Buffer)
+	testjson.PrintSummaryWithOptions(out, exec, testjson.SummarizeFailed|testjson.SummarizeOutput,
+		testjson.FormatOptions{})
+	assert.Assert(t, strings.Contains(out.String(), `
+=== FAIL: example.com/pkg TestFails (0.10s)
+    environment:
+        GOTESTSUM_SNAPSHOT_VAR=value
+        GOTESTSUM_SNAPSHOT_UNSET is not set
+        disk: ok
+`), out.String())
+}
+
+func TestNewSnapshotHook_NotEnabled(t *testing.T) {
+	assert.Assert(t, newSnapshotHook(&options{failureSnapshotEnv: " , "}) == nil)
+}
+
+type snapshotHandler struct {
+	hook *snapshotHook
+}
+
+func (h *snapshotHandler) Event(event testjson.TestEvent, exec *testjson.Execution) error {
+	h.hook.Event(event, exec)
+	return nil
+}
+
+func (h *snapshotHandler) Err(string) error {
+	return nil
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	buf := &limitedBuffer{max: 5}
+	n, err := buf.Write([]byte("abc"))
+	assert.NilError(t, err)
+	assert.Equal(t, n, 3)
+	n, err = buf.Write([]byte("defgh"))
+	assert.NilError(t, err)
+	assert.Equal(t, n, 5)
+	assert.Equal(t, buf.String(), "abcde")
+	assert.Assert(t, buf.truncated)
+}
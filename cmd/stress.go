@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// runInstance is one of the go test commands of an --until-failure run. There
+// is more than one instance of each run when --stress-parallel is used.
+type runInstance struct {
+	num  int
+	opts options
+	exec *testjson.Execution
+	err  error
+
+	// buffered is true when the output and events of the instance are kept in
+	// memory, instead of being written to stdout and the --jsonfile, so that
+	// the output of concurrent instances is not mixed together.
+	buffered bool
+	output   bytes.Buffer
+	events   [][]byte
+}
+
+func newRunInstance(opts *options, num int, seed int64) *runInstance {
+	inst := &runInstance{num: num, opts: *opts}
+	if opts.untilFailureShuffle {
+		inst.opts.shuffleSeed = strconv.FormatInt(seed+int64(num-1), 10)
+	}
+	return inst
+}
+
+func (i *runInstance) failed() bool {
+	return i.err != nil || len(i.exec.Failed()) > 0 || len(i.exec.Errors()) > 0
+}
+
+// describeFailure returns the names of the failed tests of the instance, or
+// the reason the instance failed when no tests failed.
+func (i *runInstance) describeFailure() string {
+	var names []string
+	for _, tc := range testjson.FilterFailedUnique(i.exec.Failed()) {
+		names = append(names, tc.Package+"."+tc.Test.Name())
+	}
+	switch {
+	case len(names) > 0:
+		return strings.Join(names, ", ")
+	case len(i.exec.Errors()) > 0:
+		return i.exec.Errors()[0]
+	default:
+		return i.err.Error()
+	}
+}
+
+// replay writes the buffered output of the instance to stdout, and the
+// buffered events to the --jsonfile.
+func (i *runInstance) replay(opts *options) error {
+	if !i.buffered {
+		return nil
+	}
+	fmt.Fprintf(opts.stdout, "\n=== instance %d of %d%s\n",
+		i.num, opts.stressParallel, seedSuffix(&i.opts))
+	if _, err := opts.stdout.Write(i.output.Bytes()); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	jsonFile, err := openJSONFile(opts)
+	if err != nil || jsonFile == nil {
+		return err
+	}
+	defer jsonFile.Close() // nolint: errcheck
+	for _, event := range i.events {
+		if _, err := jsonFile.Write(append(event, '\n')); err != nil {
+			return fmt.Errorf("failed to write JSON file: %w", err)
+		}
+	}
+	return nil
+}
+
+func failedInstances(instances []*runInstance) []*runInstance {
+	var result []*runInstance
+	for _, inst := range instances {
+		if inst.failed() {
+			result = append(result, inst)
+		}
+	}
+	return result
+}
+
+// runInstances runs the instances of one --until-failure run. Each instance
+// of the run uses a different shuffle seed.
+func runInstances(ctx context.Context, opts *options) ([]*runInstance, error) {
+	seed := newShuffleSeed()
+	if opts.stressParallel <= 1 {
+		inst := newRunInstance(opts, 1, seed)
+		handler, err := newEventHandler(&inst.opts)
+		if err != nil {
+			return nil, err
+		}
+		inst.exec, inst.err = runOnce(ctx, &inst.opts, handler)
+		handler.Close() // nolint: errcheck
+		if inst.exec == nil {
+			return nil, inst.err
+		}
+		return []*runInstance{inst}, nil
+	}
+
+	instances := make([]*runInstance, opts.stressParallel)
+	errs := make([]error, opts.stressParallel)
+	var wg sync.WaitGroup
+	for n := range instances {
+		inst := newRunInstance(opts, n+1, seed)
+		inst.buffered = true
+		inst.opts.stdout = &inst.output
+		inst.opts.jsonFile = ""
+		inst.opts.archivePath = ""
+		instances[n] = inst
+
+		handler, err := newEventHandler(&inst.opts)
+		if err != nil {
+			wg.Wait()
+			return nil, err
+		}
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			defer handler.Close() // nolint: errcheck
+			recorder := &eventRecorder{EventHandler: handler, events: &inst.events}
+			inst.exec, inst.err = runOnce(ctx, &inst.opts, recorder)
+			if inst.exec == nil {
+				errs[n] = inst.err
+			}
+		}(n)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return instances, nil
+}
+
+// eventRecorder keeps the raw bytes of each event, and passes the event to
+// the wrapped handler.
+type eventRecorder struct {
+	testjson.EventHandler
+	events *[][]byte
+}
+
+func (r *eventRecorder) Event(event testjson.TestEvent, execution *testjson.Execution) error {
+	// ignore artificial events with no raw Bytes(). The bytes are copied
+	// because the scanner reuses its buffer for the next line.
+	if raw := event.Bytes(); len(raw) > 0 {
+		*r.events = append(*r.events, append([]byte(nil), raw...))
+	}
+	return r.EventHandler.Event(event, execution)
+}
+
+// Flush the wrapped handler, if it buffers output.
+func (r *eventRecorder) Flush() error {
+	if f, ok := r.EventHandler.(testjson.Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
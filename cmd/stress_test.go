@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestRunUntilFailure_StressParallel(t *testing.T) {
+	jsonPassed := `{"Package": "example.com/pkg", "Test": "TestFlaky", "Action": "run"}
+{"Package": "example.com/pkg", "Test": "TestFlaky", "Action": "pass"}
+{"Package": "example.com/pkg", "Action": "pass"}
+`
+	jsonFailed := `{"Package": "example.com/pkg", "Test": "TestFlaky", "Action": "run"}
+{"Package": "example.com/pkg", "Test": "TestFlaky", "Action": "output", "Output": "flaky_test.go:10: contention\n"}
+{"Package": "example.com/pkg", "Test": "TestFlaky", "Action": "fail"}
+{"Package": "example.com/pkg", "Action": "fail"}
+`
+	var mu sync.Mutex
+	var started int
+	fn := func(args []string) *proc {
+		mu.Lock()
+		started++
+		mu.Unlock()
+		out, err := jsonPassed, error(nil)
+		if args[3] == "-shuffle=21" {
+			out, err = jsonFailed, newExitCode("failed", 1)
+		}
+		return &proc{
+			cmd:    fakeWaiter{result: err},
+			stdout: strings.NewReader(out),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	defer patchStartGoTestFn(fn)()
+
+	seed := int64(0)
+	origSeed := newShuffleSeed
+	newShuffleSeed = func() int64 {
+		seed += 10
+		return seed
+	}
+	defer func() { newShuffleSeed = origSeed }()
+
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		format:              "testname",
+		untilFailure:        true,
+		untilFailureShuffle: true,
+		stressParallel:      3,
+		jsonFile:            dir.Join("events.json"),
+		stdout:              out,
+		stderr:              os.Stderr,
+		hideSummary:         newHideSummaryValue(),
+	}
+	err := runUntilFailure(opts)
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+	assert.Equal(t, started, 6)
+
+	assert.Assert(t, strings.Contains(out.String(), "\nrun 1 passed (3 instances)\n"), out.String())
+	assert.Assert(t, strings.Contains(out.String(), "\n=== instance 2 of 3 (shuffle seed 21)\n"), out.String())
+	assert.Assert(t, strings.Contains(out.String(),
+		"\nFailed on run 2 after"), out.String())
+	assert.Assert(t, strings.Contains(out.String(),
+		"\n    instance 2 of 3 (shuffle seed 21): example.com/pkg.TestFlaky\n"), out.String())
+
+	raw, err := ioutil.ReadFile(opts.jsonFile)
+	assert.NilError(t, err)
+	// the first line is the run-start event with the run ID
+	_, events := cutString(string(raw), "\n")
+	assert.Equal(t, events, jsonFailed)
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// strictStderrDefault is the value of --strict-stderr when the flag is used
+// without a value. It allows only blank lines.
+const strictStderrDefault = "^$"
+
+// strictStderrValue is the value of the --strict-stderr flag. When the flag is
+// set, any line written to stderr by 'go test' which does not match allow fails
+// the run.
+type strictStderrValue struct {
+	allow *regexp.Regexp
+}
+
+func (v *strictStderrValue) String() string {
+	if v.allow == nil {
+		return ""
+	}
+	return v.allow.String()
+}
+
+func (v *strictStderrValue) Set(raw string) error {
+	allow, err := regexp.Compile(raw)
+	if err != nil {
+		return fmt.Errorf("invalid allow pattern: %w", err)
+	}
+	v.allow = allow
+	return nil
+}
+
+func (v *strictStderrValue) Type() string {
+	return "allow-pattern"
+}
+
+// unexpectedStderr returns the lines from stderr which are not allowed by
+// --strict-stderr.
+func unexpectedStderr(opts *options, exec *testjson.Execution) []string {
+	if opts.strictStderr.allow == nil || exec == nil {
+		return nil
+	}
+	var lines []string
+	for _, line := range exec.Errors() {
+		if !opts.strictStderr.allow.MatchString(line) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// strictStderrExitErr returns an error when the run passed, but 'go test'
+// wrote unexpected lines to stderr. The lines are printed in the Errors
+// section of the summary.
+func strictStderrExitErr(opts *options, exec *testjson.Execution, exitErr error) error {
+	if exitErr != nil {
+		return exitErr
+	}
+	lines := unexpectedStderr(opts, exec)
+	if len(lines) == 0 {
+		return nil
+	}
+	log.Errorf("go test wrote %d unexpected %s to stderr, failing the run because of --strict-stderr",
+		len(lines), pluralize(len(lines), "line", "lines"))
+	return exitError{num: 1}
+}
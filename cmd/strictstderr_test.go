@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestStrictStderrExitErr(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"pass","Package":"example.com/pkg","Elapsed":0.1}` + "\n"),
+		Stderr: strings.NewReader("go: downloading example.com/dep v1.0.0\n" +
+			"\n" +
+			"warning: GOPATH set to GOROOT has no effect\n"),
+	})
+	assert.NilError(t, err)
+
+	var testCases = []struct {
+		name     string
+		flag     []string
+		exitErr  error
+		expected int
+	}{
+		{name: "not set", expected: 0},
+		{name: "set without a value", flag: []string{strictStderrDefault}, expected: 1},
+		{name: "allow pattern matches", flag: []string{"^(warning: |$)"}, expected: 0},
+		{name: "allow pattern does not match", flag: []string{"^deprecated"}, expected: 1},
+		{name: "run already failed", flag: []string{strictStderrDefault}, exitErr: exitError{num: 2}, expected: 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := &options{}
+			for _, value := range tc.flag {
+				assert.NilError(t, opts.strictStderr.Set(value))
+			}
+			err := strictStderrExitErr(opts, exec, tc.exitErr)
+			assert.Equal(t, ExitCodeWithDefault(err), tc.expected)
+		})
+	}
+}
+
+func TestStrictStderrValue_Set(t *testing.T) {
+	var v strictStderrValue
+	assert.Equal(t, v.String(), "")
+	assert.NilError(t, v.Set("^warning: "))
+	assert.Equal(t, v.String(), "^warning: ")
+	assert.ErrorContains(t, v.Set("("), "invalid allow pattern")
+}
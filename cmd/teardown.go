@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+var teardownFailuresValues = "fail, report, warn"
+
+// teardownFailuresValue is the flag.Value for --teardown-failures, which sets
+// how packages that fail after all of their tests passed are reported.
+type teardownFailuresValue string
+
+const (
+	// teardownFailuresFail reports the package as failed.
+	teardownFailuresFail teardownFailuresValue = "fail"
+	// teardownFailuresReport reports the package as passed with a teardown
+	// error, and the run still fails.
+	teardownFailuresReport teardownFailuresValue = "report"
+	// teardownFailuresWarn reports the package as passed with a teardown error,
+	// and the run does not fail because of the package.
+	teardownFailuresWarn teardownFailuresValue = "warn"
+)
+
+func (v *teardownFailuresValue) Set(val string) error {
+	switch teardownFailuresValue(val) {
+	case teardownFailuresFail, teardownFailuresReport, teardownFailuresWarn:
+		*v = teardownFailuresValue(val)
+		return nil
+	}
+	return errors.Errorf("invalid value: %v, must be one of: "+teardownFailuresValues, val)
+}
+
+func (v *teardownFailuresValue) Type() string {
+	return "mode"
+}
+
+func (v *teardownFailuresValue) String() string {
+	if *v == "" {
+		return string(teardownFailuresFail)
+	}
+	return string(*v)
+}
+
+// demote returns true if packages that failed after all of their tests passed
+// should be reported as passed with a teardown error.
+func (v teardownFailuresValue) demote() bool {
+	return v == teardownFailuresReport || v == teardownFailuresWarn
+}
+
+// teardownFailuresExitErr returns the error to use as the exit code of the
+// run. With --teardown-failures=warn a run where the only failures were
+// teardown errors does not fail. With --teardown-failures=report the run fails
+// even when the failed tests passed when they were rerun.
+func teardownFailuresExitErr(opts *options, exec *testjson.Execution, exitErr error) error {
+	if exec == nil || len(exec.TeardownFailed()) == 0 {
+		return exitErr
+	}
+	switch opts.teardownFailures {
+	case teardownFailuresWarn:
+		if ExitCodeWithDefault(exitErr) == 1 && len(exec.Failed()) == 0 && len(exec.Errors()) == 0 {
+			return nil
+		}
+	case teardownFailuresReport:
+		if exitErr == nil {
+			return exitError{num: 1}
+		}
+	}
+	return exitErr
+}
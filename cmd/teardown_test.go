@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestTeardownFailuresExitErr(t *testing.T) {
+	scan := func(t *testing.T, mode teardownFailuresValue) *testjson.Execution {
+		t.Helper()
+		exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+			Stdout:                 bytes.NewReader(golden.Get(t, "../../testjson/testdata/go-test-json-teardown-failure.out")),
+			DemoteTeardownFailures: mode.demote(),
+		})
+		assert.NilError(t, err)
+		return exec
+	}
+
+	var testCases = []struct {
+		name     string
+		mode     teardownFailuresValue
+		exitErr  error
+		expected int
+	}{
+		{name: "default", exitErr: exitError{num: 1}, expected: 1},
+		{name: "fail", mode: teardownFailuresFail, exitErr: exitError{num: 1}, expected: 1},
+		{name: "report", mode: teardownFailuresReport, exitErr: exitError{num: 1}, expected: 1},
+		{name: "report after rerun passed", mode: teardownFailuresReport, expected: 1},
+		{name: "warn", mode: teardownFailuresWarn, exitErr: exitError{num: 1}, expected: 0},
+		{name: "warn with other exit code", mode: teardownFailuresWarn, exitErr: exitError{num: 2}, expected: 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := &options{teardownFailures: tc.mode}
+			err := teardownFailuresExitErr(opts, scan(t, tc.mode), tc.exitErr)
+			assert.Equal(t, ExitCodeWithDefault(err), tc.expected)
+		})
+	}
+}
+
+func TestTeardownFailuresValue_Set(t *testing.T) {
+	var v teardownFailuresValue
+	assert.Equal(t, v.String(), "fail")
+	assert.NilError(t, v.Set("warn"))
+	assert.Equal(t, v, teardownFailuresWarn)
+	assert.Error(t, v.Set("bogus"), "invalid value: bogus, must be one of: fail, report, warn")
+}
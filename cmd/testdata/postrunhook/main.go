@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strings"
@@ -26,6 +27,12 @@ func run() error {
 		}
 	}
 
+	if os.Getenv("TEST_STUB_STDIN") != "" {
+		if _, err := io.Copy(os.Stdout, os.Stdin); err != nil {
+			return err
+		}
+	}
+
 	err := os.Getenv("TEST_STUB_ERROR")
 	if err != "" {
 		return errors.New(err)
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// testDurationWatch warns when a top-level test runs for longer than
+// --warn-test-duration. The running tests are checked on an interval, so that
+// the warning is printed while the test is still running, before it reaches
+// the go test -timeout. Subtests are not checked, because the root test of a
+// slow subtest is always slow as well.
+type testDurationWatch struct {
+	limit time.Duration
+	clock clockwork.Clock
+	stop  chan struct{}
+
+	mu sync.Mutex
+	// running is the time each running top-level test started.
+	running map[testDurationKey]time.Time
+	// slow are the tests which ran for longer than limit, in the order they
+	// were found.
+	slow []slowTest
+}
+
+type testDurationKey struct {
+	pkg   string
+	test  string
+	runID int
+}
+
+// slowTest is a test which ran for longer than --warn-test-duration.
+type slowTest struct {
+	testDurationKey
+	elapsed time.Duration
+	// action is the result of the test, or empty if the test never finished.
+	action testjson.Action
+}
+
+// testDurationCheckInterval is the longest time between checks of the running
+// tests.
+const testDurationCheckInterval = time.Second
+
+func newTestDurationWatch(opts *options) *testDurationWatch {
+	if opts.warnTestDuration <= 0 {
+		return nil
+	}
+	w := &testDurationWatch{
+		limit:   opts.warnTestDuration,
+		clock:   clockwork.NewRealClock(),
+		stop:    make(chan struct{}),
+		running: make(map[testDurationKey]time.Time),
+	}
+	interval := testDurationCheckInterval
+	if w.limit < interval {
+		interval = w.limit
+	}
+	go w.watch(interval)
+	return w
+}
+
+func (w *testDurationWatch) watch(interval time.Duration) {
+	ticker := w.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case now := <-ticker.Chan():
+			w.check(now)
+		}
+	}
+}
+
+// check warns about each running test which started more than limit before
+// now, and has not already been reported.
+func (w *testDurationWatch) check(now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var keys []testDurationKey
+	for key, started := range w.running {
+		if now.Sub(started) > w.limit && w.indexOf(key) < 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := w.running[keys[i]], w.running[keys[j]]
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		if keys[i].pkg != keys[j].pkg {
+			return keys[i].pkg < keys[j].pkg
+		}
+		return keys[i].test < keys[j].test
+	})
+	for _, key := range keys {
+		elapsed := now.Sub(w.running[key]).Truncate(time.Second)
+		log.Warnf("%s %s is still running after %v, longer than --warn-test-duration %v",
+			testjson.RelativePackagePath(key.pkg), key.test, elapsed, w.limit)
+		w.slow = append(w.slow, slowTest{testDurationKey: key, elapsed: elapsed})
+	}
+}
+
+// Event records the start and end of each top-level test. It must be called
+// from EventHandler.Event.
+func (w *testDurationWatch) Event(event testjson.TestEvent) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if event.PackageEvent() {
+		if event.Action.IsTerminal() {
+			for key := range w.running {
+				if key.pkg == event.Package && key.runID == event.RunID {
+					delete(w.running, key)
+				}
+			}
+		}
+		return
+	}
+	if testjson.TestName(event.Test).IsSubTest() {
+		return
+	}
+
+	key := testDurationKey{pkg: event.Package, test: event.Test, runID: event.RunID}
+	switch {
+	case event.Action == testjson.ActionRun:
+		w.running[key] = w.clock.Now()
+	case event.Action.IsTerminal():
+		delete(w.running, key)
+		elapsed := time.Duration(event.Elapsed * float64(time.Second))
+		if i := w.indexOf(key); i >= 0 {
+			w.slow[i].elapsed, w.slow[i].action = elapsed, event.Action
+			return
+		}
+		if elapsed > w.limit {
+			log.Warnf("%s %s took %v, longer than --warn-test-duration %v",
+				testjson.RelativePackagePath(key.pkg), key.test, elapsed, w.limit)
+			w.slow = append(w.slow, slowTest{testDurationKey: key, elapsed: elapsed, action: event.Action})
+		}
+	}
+}
+
+func (w *testDurationWatch) indexOf(key testDurationKey) int {
+	for i, s := range w.slow {
+		if s.testDurationKey == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Close stops checking the running tests.
+func (w *testDurationWatch) Close() {
+	if w == nil {
+		return
+	}
+	close(w.stop)
+}
+
+// slowTests returns the tests which ran for longer than the limit.
+func (w *testDurationWatch) slowTests() []slowTest {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]slowTest(nil), w.slow...)
+}
+
+func printSlowTests(out io.Writer, w *testDurationWatch) {
+	slow := w.slowTests()
+	if len(slow) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n=== Longer than --warn-test-duration %v (%d %s)\n", w.limit, len(slow),
+		pluralize(len(slow), "test", "tests"))
+	for _, s := range slow {
+		var result string
+		switch s.action {
+		case "":
+			result = ", did not finish"
+		case testjson.ActionFail:
+			result = ", failed"
+		}
+		var rerun string
+		if s.runID > 0 {
+			rerun = fmt.Sprintf(" (re-run %d)", s.runID)
+		}
+		fmt.Fprintf(out, "%s %s%s %s%s\n", testjson.RelativePackagePath(s.pkg), s.test, rerun,
+			testjson.FormatDurationAsSeconds(s.elapsed, 2), result)
+	}
+}
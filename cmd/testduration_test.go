@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestTestDurationWatch(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	w := &testDurationWatch{
+		limit:   10 * time.Second,
+		clock:   clock,
+		running: make(map[testDurationKey]time.Time),
+	}
+	event := func(action testjson.Action, test string, elapsed float64) {
+		w.Event(testjson.TestEvent{Action: action, Package: "example.com/pkg", Test: test, Elapsed: elapsed})
+	}
+
+	event(testjson.ActionRun, "TestSlow", 0)
+	event(testjson.ActionRun, "TestHangs", 0)
+	event(testjson.ActionRun, "TestFast", 0)
+	event(testjson.ActionRun, "TestSlow/sub", 0)
+	clock.Advance(5 * time.Second)
+	event(testjson.ActionPass, "TestFast", 5)
+	w.check(clock.Now())
+	assert.Equal(t, len(w.slowTests()), 0)
+
+	clock.Advance(7 * time.Second)
+	w.check(clock.Now())
+	w.check(clock.Now())
+	assert.Equal(t, len(w.slowTests()), 2)
+
+	event(testjson.ActionPass, "TestSlow/sub", 11)
+	event(testjson.ActionFail, "TestSlow", 12.5)
+	event(testjson.ActionRun, "TestDone", 0)
+	event(testjson.ActionPass, "TestDone", 11)
+	w.Event(testjson.TestEvent{Action: testjson.ActionFail, Package: "example.com/pkg", Elapsed: 20})
+	assert.Equal(t, len(w.running), 0)
+
+	out := new(bytes.Buffer)
+	printSlowTests(out, w)
+	expected := `
+=== Longer than --warn-test-duration 10s (3 tests)
+example.com/pkg TestHangs 12.00s, did not finish
+example.com/pkg TestSlow 12.50s, failed
+example.com/pkg TestDone 11.00s
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestTestDurationWatch_Nil(t *testing.T) {
+	var w *testDurationWatch
+	w.Event(testjson.TestEvent{Action: testjson.ActionRun, Package: "example.com/pkg", Test: "TestOne"})
+	w.Close()
+	out := new(bytes.Buffer)
+	printSlowTests(out, w)
+	assert.Equal(t, out.String(), "")
+}
@@ -5,7 +5,12 @@ import (
 	"os"
 
 	"gotest.tools/gotestsum/cmd"
+	"gotest.tools/gotestsum/cmd/tool/coverage"
+	"gotest.tools/gotestsum/cmd/tool/flaky"
+	"gotest.tools/gotestsum/cmd/tool/gantt"
+	"gotest.tools/gotestsum/cmd/tool/junit"
 	"gotest.tools/gotestsum/cmd/tool/slowest"
+	"gotest.tools/gotestsum/cmd/tool/trend"
 )
 
 // Run one of the tool commands.
@@ -15,8 +20,18 @@ func Run(name string, args []string) error {
 	case "":
 		fmt.Println(usage(name))
 		return nil
+	case "coverage":
+		return coverage.Run(name+" "+next, rest)
+	case "flaky":
+		return flaky.Run(name+" "+next, rest)
+	case "gantt":
+		return gantt.Run(name+" "+next, rest)
+	case "junit":
+		return junit.Run(name+" "+next, rest)
 	case "slowest":
 		return slowest.Run(name+" "+next, rest)
+	case "trend":
+		return trend.Run(name+" "+next, rest)
 	default:
 		fmt.Fprintln(os.Stderr, usage(name))
 		return fmt.Errorf("invalid command: %v %v", name, next)
@@ -26,7 +41,7 @@ func Run(name string, args []string) error {
 func usage(name string) string {
 	return fmt.Sprintf(`Usage: %s COMMAND [flags]
 
-Commands: slowest
+Commands: coverage, flaky, gantt, junit, slowest, trend
 
 Use '%s COMMAND --help' for command specific help.
 `, name, name)
@@ -0,0 +1,122 @@
+package coverage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// coberturaDocType is the DTD of the report, which some CI systems use to
+// detect the format.
+const coberturaDocType = `<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">`
+
+type coberturaCoverage struct {
+	XMLName         xml.Name           `xml:"coverage"`
+	LineRate        string             `xml:"line-rate,attr"`
+	BranchRate      string             `xml:"branch-rate,attr"`
+	LinesCovered    int                `xml:"lines-covered,attr"`
+	LinesValid      int                `xml:"lines-valid,attr"`
+	BranchesCovered int                `xml:"branches-covered,attr"`
+	BranchesValid   int                `xml:"branches-valid,attr"`
+	Complexity      int                `xml:"complexity,attr"`
+	Version         string             `xml:"version,attr"`
+	Timestamp       int64              `xml:"timestamp,attr"`
+	Sources         []string           `xml:"sources>source"`
+	Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   string           `xml:"line-rate,attr"`
+	BranchRate string           `xml:"branch-rate,attr"`
+	Complexity int              `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+}
+
+// coberturaClass is a file. Cobertura was created for Java, where each file is
+// a class.
+type coberturaClass struct {
+	Name       string          `xml:"name,attr"`
+	Filename   string          `xml:"filename,attr"`
+	LineRate   string          `xml:"line-rate,attr"`
+	BranchRate string          `xml:"branch-rate,attr"`
+	Complexity int             `xml:"complexity,attr"`
+	Methods    struct{}        `xml:"methods"`
+	Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number int `xml:"number,attr"`
+	Hits   int `xml:"hits,attr"`
+}
+
+// writeCobertura writes the coverage as a Cobertura XML report, with a package
+// for each Go package, and a class for each file. Branch coverage is not
+// recorded by go test, so the branch rates are always 0.
+func writeCobertura(out io.Writer, files []fileCoverage, now time.Time) error {
+	report := coberturaCoverage{
+		BranchRate: "0",
+		Version:    "gotestsum",
+		Timestamp:  now.UnixNano() / int64(time.Millisecond),
+		Sources:    []string{"."},
+	}
+	type packageLines struct {
+		coberturaPackage
+		valid, covered int
+	}
+	var packages []*packageLines
+	byName := make(map[string]*packageLines)
+	for _, f := range files {
+		pkg, ok := byName[f.pkg]
+		if !ok {
+			pkg = &packageLines{coberturaPackage: coberturaPackage{Name: f.pkg, BranchRate: "0"}}
+			byName[f.pkg] = pkg
+			packages = append(packages, pkg)
+		}
+		class := coberturaClass{
+			Name:       path.Base(f.path),
+			Filename:   f.path,
+			LineRate:   lineRate(f.linesHit(), len(f.lines)),
+			BranchRate: "0",
+		}
+		for _, line := range f.lines {
+			class.Lines = append(class.Lines, coberturaLine{Number: line.number, Hits: line.count})
+		}
+		pkg.Classes = append(pkg.Classes, class)
+
+		pkg.valid += len(f.lines)
+		pkg.covered += f.linesHit()
+		report.LinesValid += len(f.lines)
+		report.LinesCovered += f.linesHit()
+	}
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].Name < packages[j].Name
+	})
+	for _, pkg := range packages {
+		pkg.LineRate = lineRate(pkg.covered, pkg.valid)
+		report.Packages = append(report.Packages, pkg.coberturaPackage)
+	}
+	report.LineRate = lineRate(report.LinesCovered, report.LinesValid)
+
+	if _, err := io.WriteString(out, xml.Header+coberturaDocType+"\n"); err != nil {
+		return fmt.Errorf("failed to write Cobertura report: %v", err)
+	}
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("failed to write Cobertura report: %v", err)
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
+
+func lineRate(covered, valid int) string {
+	if valid == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(float64(covered)/float64(valid), 'f', 4, 64)
+}
@@ -0,0 +1,266 @@
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dnephin/pflag"
+	"golang.org/x/tools/cover"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func runConvert(name string, args []string) error {
+	flags, opts := setupConvertFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		convertUsage(os.Stderr, name, flags)
+		return err
+	}
+	if flags.NArg() > 0 {
+		convertUsage(os.Stderr, name, flags)
+		return fmt.Errorf("too many arguments: %v", strings.Join(flags.Args(), " "))
+	}
+	return convert(opts)
+}
+
+func setupConvertFlags(name string) (*pflag.FlagSet, *convertOptions) {
+	opts := &convertOptions{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		convertUsage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.profile, "profile", "",
+		"read the cover profile from this file, created by 'go test -coverprofile'")
+	flags.StringVar(&opts.format, "format", "",
+		"format of the coverage report, one of: lcov, cobertura. Defaults to the extension of --output")
+	flags.StringVarP(&opts.output, "output", "o", "",
+		"write the coverage report to this file, defaults to stdout")
+	flags.Var(&opts.pathMaps, "path-map",
+		"replace the FROM prefix of the import path of each file with the TO directory, ex: example.com/app=>backend")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging.")
+	return flags, opts
+}
+
+func convertUsage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags]
+
+Read a cover profile created by 'go test -coverprofile', and write it as an
+LCOV tracefile, or a Cobertura XML report, for coverage services and CI
+systems like Coveralls, Codecov, and GitLab.
+
+The files in a cover profile are Go import paths. By default the import path of
+each file is made relative to the module in the go.mod file of the current
+directory, so the paths are relative to the root of the module. Use --path-map
+when the module is not at the root of the repository.
+
+    %[1]s --profile cover.out --output coverage.info
+    %[1]s --profile cover.out --output coverage.xml --path-map example.com/app=>backend
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+type convertOptions struct {
+	profile  string
+	format   string
+	output   string
+	pathMaps pathMapsValue
+	debug    bool
+
+	// now is used for the timestamp of the Cobertura report, so that tests
+	// can use a fixed time.
+	now func() time.Time
+}
+
+// pathMapsValue is a flag.Value which appends a testjson.PathMapping for each
+// value.
+type pathMapsValue []testjson.PathMapping
+
+func (v *pathMapsValue) String() string {
+	values := make([]string, 0, len(*v))
+	for _, m := range *v {
+		values = append(values, m.From+"=>"+m.To)
+	}
+	return strings.Join(values, ",")
+}
+
+func (v *pathMapsValue) Set(raw string) error {
+	m, err := testjson.ParsePathMapping(raw)
+	if err != nil {
+		return err
+	}
+	*v = append(*v, m)
+	return nil
+}
+
+func (v *pathMapsValue) Type() string {
+	return "from=>to"
+}
+
+func convert(opts *convertOptions) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	if opts.profile == "" {
+		return fmt.Errorf("--profile is required")
+	}
+	format, err := reportFormat(opts)
+	if err != nil {
+		return err
+	}
+
+	profiles, err := cover.ParseProfiles(opts.profile)
+	if err != nil {
+		return fmt.Errorf("failed to read cover profile: %v", err)
+	}
+	files := newFileCoverage(profiles, newFilePathFunc(opts.pathMaps))
+
+	out, err := outputWriter(opts.output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	defer func() {
+		if err := out.Close(); err != nil {
+			log.Errorf("Failed to close file %v: %v", opts.output, err)
+		}
+	}()
+
+	switch format {
+	case "cobertura":
+		now := time.Now
+		if opts.now != nil {
+			now = opts.now
+		}
+		return writeCobertura(out, files, now())
+	default:
+		return writeLCOV(out, files)
+	}
+}
+
+func reportFormat(opts *convertOptions) (string, error) {
+	format := opts.format
+	if format == "" {
+		switch filepath.Ext(opts.output) {
+		case ".info", ".lcov":
+			format = "lcov"
+		case ".xml":
+			format = "cobertura"
+		default:
+			return "", fmt.Errorf("--format is required when the extension of --output is not .info, .lcov, or .xml")
+		}
+	}
+	switch format {
+	case "lcov", "cobertura":
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q, must be one of: lcov, cobertura", format)
+	}
+}
+
+// fileCoverage is the number of times each line of a file was run.
+type fileCoverage struct {
+	// pkg is the import path of the package of the file.
+	pkg string
+	// path is the path of the file, relative to the root of the repository.
+	path string
+	// lines are sorted by number.
+	lines []lineCoverage
+}
+
+type lineCoverage struct {
+	number int
+	count  int
+}
+
+func (f fileCoverage) linesHit() int {
+	var hit int
+	for _, line := range f.lines {
+		if line.count > 0 {
+			hit++
+		}
+	}
+	return hit
+}
+
+// newFileCoverage returns the coverage of each file in profiles, sorted by
+// path. A line that is part of more than one block uses the largest count of
+// the blocks, so that a line is covered when any of its statements ran.
+func newFileCoverage(profiles []*cover.Profile, filePath func(string) string) []fileCoverage {
+	files := make([]fileCoverage, 0, len(profiles))
+	for _, profile := range profiles {
+		counts := make(map[int]int)
+		for _, block := range profile.Blocks {
+			if block.NumStmt == 0 {
+				continue
+			}
+			for line := block.StartLine; line <= block.EndLine; line++ {
+				if count, ok := counts[line]; !ok || block.Count > count {
+					counts[line] = block.Count
+				}
+			}
+		}
+		f := fileCoverage{
+			pkg:  path.Dir(profile.FileName),
+			path: filePath(profile.FileName),
+		}
+		for number, count := range counts {
+			f.lines = append(f.lines, lineCoverage{number: number, count: count})
+		}
+		sort.Slice(f.lines, func(i, j int) bool {
+			return f.lines[i].number < f.lines[j].number
+		})
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].path < files[j].path
+	})
+	return files
+}
+
+// newFilePathFunc returns a function which returns the path of a file from the
+// import path in a cover profile. The first mapping with a From prefix that
+// matches the import path is used, otherwise the path is relative to the
+// module of the current directory.
+func newFilePathFunc(mappings []testjson.PathMapping) func(string) string {
+	rewrite := testjson.NewPathRewriter(mappings)
+	return func(name string) string {
+		if rewrite != nil {
+			if mapped := rewrite(name); mapped != name {
+				return mapped
+			}
+		}
+		dir, file := path.Split(name)
+		return path.Join(testjson.RelativePackagePath(strings.TrimSuffix(dir, "/")), file)
+	}
+}
+
+func outputWriter(v string) (io.WriteCloser, error) {
+	switch v {
+	case "", "-":
+		return nopWriteCloser{Writer: os.Stdout}, nil
+	default:
+		return os.Create(v)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,75 @@
+package coverage
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+func TestUsage_WithFlagsFromSetupFlags(t *testing.T) {
+	defer env.PatchAll(t, nil)()
+
+	name := "gotestsum tool coverage convert"
+	flags, _ := setupConvertFlags(name)
+	buf := new(bytes.Buffer)
+	convertUsage(buf, name, flags)
+
+	golden.Assert(t, buf.String(), "cmd-flags-help-text")
+}
+
+func TestConvert(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+
+	for _, name := range []string{"coverage.info", "coverage.xml"} {
+		t.Run(name, func(t *testing.T) {
+			opts := &convertOptions{
+				profile:  "testdata/cover.out",
+				output:   filepath.Join(dir.Path(), name),
+				pathMaps: []testjson.PathMapping{{From: "example.com/app", To: "backend"}},
+				now: func() time.Time {
+					return time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+				},
+			}
+			assert.NilError(t, convert(opts))
+
+			raw, err := ioutil.ReadFile(opts.output)
+			assert.NilError(t, err)
+			golden.Assert(t, string(raw), name+".golden")
+		})
+	}
+}
+
+func TestNewFilePathFunc(t *testing.T) {
+	filePath := newFilePathFunc(nil)
+	assert.Equal(t, filePath("gotest.tools/gotestsum/testjson/format.go"), "testjson/format.go")
+	assert.Equal(t, filePath("gotest.tools/gotestsum/main.go"), "main.go")
+	assert.Equal(t, filePath("example.com/other/file.go"), "example.com/other/file.go")
+
+	filePath = newFilePathFunc([]testjson.PathMapping{{From: "example.com/other"}})
+	assert.Equal(t, filePath("example.com/other/pkg/file.go"), "pkg/file.go")
+}
+
+func TestReportFormat(t *testing.T) {
+	format, err := reportFormat(&convertOptions{output: "coverage.lcov"})
+	assert.NilError(t, err)
+	assert.Equal(t, format, "lcov")
+
+	format, err = reportFormat(&convertOptions{format: "cobertura"})
+	assert.NilError(t, err)
+	assert.Equal(t, format, "cobertura")
+
+	_, err = reportFormat(&convertOptions{output: "coverage.txt"})
+	assert.ErrorContains(t, err, "--format is required")
+
+	_, err = reportFormat(&convertOptions{format: "html"})
+	assert.ErrorContains(t, err, `unsupported format "html"`)
+}
@@ -0,0 +1,34 @@
+package coverage
+
+import (
+	"fmt"
+	"os"
+
+	"gotest.tools/gotestsum/cmd"
+)
+
+// Run one of the coverage commands.
+func Run(name string, args []string) error {
+	next, rest := cmd.Next(args)
+	switch next {
+	case "":
+		fmt.Println(usage(name))
+		return nil
+	case "convert":
+		return runConvert(name+" "+next, rest)
+	case "diff":
+		return runDiff(name+" "+next, rest)
+	default:
+		fmt.Fprintln(os.Stderr, usage(name))
+		return fmt.Errorf("invalid command: %v %v", name, next)
+	}
+}
+
+func usage(name string) string {
+	return fmt.Sprintf(`Usage: %s COMMAND [flags]
+
+Commands: convert, diff
+
+Use '%s COMMAND --help' for command specific help.
+`, name, name)
+}
@@ -0,0 +1,257 @@
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/dnephin/pflag"
+	"golang.org/x/tools/cover"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func runDiff(name string, args []string) error {
+	flags, opts := setupDiffFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		diffUsage(os.Stderr, name, flags)
+		return err
+	}
+	if flags.NArg() > 0 {
+		diffUsage(os.Stderr, name, flags)
+		return fmt.Errorf("too many arguments: %v", strings.Join(flags.Args(), " "))
+	}
+	return diff(os.Stdout, opts)
+}
+
+func setupDiffFlags(name string) (*pflag.FlagSet, *diffOptions) {
+	opts := &diffOptions{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		diffUsage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.base, "base", "",
+		"cover profile of the base of the change, ex: the main branch")
+	flags.StringVar(&opts.head, "head", "",
+		"cover profile of the change")
+	flags.BoolVar(&opts.githubAnnotations, "github-annotations", false,
+		"print a GitHub Actions warning annotation for each range of newly uncovered lines")
+	flags.Var(&opts.pathMaps, "path-map",
+		"replace the FROM prefix of the import path of each file with the TO directory, ex: example.com/app=>backend")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging.")
+	return flags, opts
+}
+
+func diffUsage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags]
+
+Compare two cover profiles created by 'go test -coverprofile', and print the
+change in coverage of each package, and the lines which are newly uncovered.
+
+A line is newly uncovered when it is not covered in the --head profile, and it
+was covered in the --base profile, or it is not in the --base profile, for
+example because it is new code. Lines are compared by file and line number, so
+a line that moved in the change may be reported.
+
+    %[1]s --base main.out --head cover.out --github-annotations
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+type diffOptions struct {
+	base              string
+	head              string
+	githubAnnotations bool
+	pathMaps          pathMapsValue
+	debug             bool
+}
+
+func diff(out io.Writer, opts *diffOptions) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	if opts.base == "" || opts.head == "" {
+		return fmt.Errorf("--base and --head are required")
+	}
+	base, err := cover.ParseProfiles(opts.base)
+	if err != nil {
+		return fmt.Errorf("failed to read --base cover profile: %v", err)
+	}
+	head, err := cover.ParseProfiles(opts.head)
+	if err != nil {
+		return fmt.Errorf("failed to read --head cover profile: %v", err)
+	}
+
+	filePath := newFilePathFunc(opts.pathMaps)
+	writePackageDiff(out, packageStatements(base), packageStatements(head))
+	ranges := newlyUncovered(newFileCoverage(base, filePath), newFileCoverage(head, filePath))
+	writeUncovered(out, ranges)
+	if opts.githubAnnotations {
+		writeAnnotations(out, ranges)
+	}
+	return nil
+}
+
+// statements is the number of statements, and covered statements, in a
+// package.
+type statements struct {
+	total   int
+	covered int
+}
+
+func (s statements) percent() float64 {
+	if s.total == 0 {
+		return 0
+	}
+	return float64(s.covered) * 100 / float64(s.total)
+}
+
+// packageStatements returns the statements of each package in profiles, by the
+// import path of the package.
+func packageStatements(profiles []*cover.Profile) map[string]statements {
+	result := make(map[string]statements)
+	for _, profile := range profiles {
+		pkg := path.Dir(profile.FileName)
+		s := result[pkg]
+		for _, block := range profile.Blocks {
+			s.total += block.NumStmt
+			if block.Count > 0 {
+				s.covered += block.NumStmt
+			}
+		}
+		result[pkg] = s
+	}
+	return result
+}
+
+func writePackageDiff(out io.Writer, base, head map[string]statements) {
+	names := make([]string, 0, len(head))
+	for name := range base {
+		names = append(names, name)
+	}
+	for name := range head {
+		if _, ok := base[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var baseTotal, headTotal statements
+	fmt.Fprintln(out, "Coverage by package:")
+	for _, name := range names {
+		b, inBase := base[name]
+		h, inHead := head[name]
+		baseTotal.total, baseTotal.covered = baseTotal.total+b.total, baseTotal.covered+b.covered
+		headTotal.total, headTotal.covered = headTotal.total+h.total, headTotal.covered+h.covered
+		switch {
+		case !inBase:
+			fmt.Fprintf(out, "%s %.1f%% (new)\n", testjson.RelativePackagePath(name), h.percent())
+		case !inHead:
+			fmt.Fprintf(out, "%s removed\n", testjson.RelativePackagePath(name))
+		default:
+			fmt.Fprintf(out, "%s %s\n", testjson.RelativePackagePath(name), formatChange(b, h))
+		}
+	}
+	fmt.Fprintf(out, "\nTotal %s\n", formatChange(baseTotal, headTotal))
+}
+
+func formatChange(base, head statements) string {
+	return fmt.Sprintf("%.1f%% -> %.1f%% (%+.1f%%)", base.percent(), head.percent(),
+		head.percent()-base.percent())
+}
+
+// lineRange is a range of lines in a file, from start to end inclusive.
+type lineRange struct {
+	path  string
+	start int
+	end   int
+}
+
+func (r lineRange) String() string {
+	if r.start == r.end {
+		return fmt.Sprintf("%s:%d", r.path, r.start)
+	}
+	return fmt.Sprintf("%s:%d-%d", r.path, r.start, r.end)
+}
+
+// newlyUncovered returns the ranges of consecutive lines which are uncovered
+// in head, and were covered, or did not exist, in base.
+func newlyUncovered(base, head []fileCoverage) []lineRange {
+	baseLines := make(map[string]map[int]int, len(base))
+	for _, f := range base {
+		lines := make(map[int]int, len(f.lines))
+		for _, line := range f.lines {
+			lines[line.number] = line.count
+		}
+		baseLines[f.path] = lines
+	}
+
+	var ranges []lineRange
+	for _, f := range head {
+		var current *lineRange
+		for _, line := range f.lines {
+			if line.count > 0 {
+				current = nil
+				continue
+			}
+			if count, ok := baseLines[f.path][line.number]; ok && count == 0 {
+				current = nil
+				continue
+			}
+			if current != nil && current.end == line.number-1 {
+				current.end = line.number
+				continue
+			}
+			ranges = append(ranges, lineRange{path: f.path, start: line.number, end: line.number})
+			current = &ranges[len(ranges)-1]
+		}
+	}
+	return ranges
+}
+
+func writeUncovered(out io.Writer, ranges []lineRange) {
+	if len(ranges) == 0 {
+		fmt.Fprintln(out, "\nNo newly uncovered lines")
+		return
+	}
+	fmt.Fprintf(out, "\nNewly uncovered lines (%d %s):\n", len(ranges), pluralize(len(ranges), "range", "ranges"))
+	for _, r := range ranges {
+		fmt.Fprintln(out, r.String())
+	}
+}
+
+// writeAnnotations prints a GitHub Actions workflow command for each range, so
+// that the lines are annotated on the diff of the pull request.
+func writeAnnotations(out io.Writer, ranges []lineRange) {
+	for _, r := range ranges {
+		fmt.Fprintf(out, "::warning file=%s,line=%d,endLine=%d,title=Not covered::%s\n",
+			escapeWorkflowProperty(r.path), r.start, r.end, pluralize(r.end-r.start+1,
+				"This line is not covered by tests", "These lines are not covered by tests"))
+	}
+}
+
+// escapeWorkflowProperty escapes the value of a property of a workflow
+// command.
+func escapeWorkflowProperty(s string) string {
+	return strings.NewReplacer(
+		"%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
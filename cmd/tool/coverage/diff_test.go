@@ -0,0 +1,39 @@
+package coverage
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/golden"
+)
+
+func TestDiffUsage_WithFlagsFromSetupFlags(t *testing.T) {
+	defer env.PatchAll(t, nil)()
+
+	name := "gotestsum tool coverage diff"
+	flags, _ := setupDiffFlags(name)
+	buf := new(bytes.Buffer)
+	diffUsage(buf, name, flags)
+
+	golden.Assert(t, buf.String(), "diff-flags-help-text")
+}
+
+func TestDiff(t *testing.T) {
+	opts := &diffOptions{
+		base:              "testdata/base.out",
+		head:              "testdata/head.out",
+		githubAnnotations: true,
+		pathMaps:          []testjson.PathMapping{{From: "example.com/app", To: "backend"}},
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, diff(out, opts))
+	golden.Assert(t, out.String(), "diff.golden")
+}
+
+func TestDiff_MissingProfile(t *testing.T) {
+	err := diff(new(bytes.Buffer), &diffOptions{head: "testdata/head.out"})
+	assert.ErrorContains(t, err, "--base and --head are required")
+}
@@ -0,0 +1,24 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// writeLCOV writes the coverage as an LCOV tracefile, with a record for each
+// file. See https://github.com/linux-test-project/lcov/blob/master/man/geninfo.1.
+func writeLCOV(out io.Writer, files []fileCoverage) error {
+	w := bufio.NewWriter(out)
+	for _, f := range files {
+		fmt.Fprintf(w, "TN:\nSF:%s\n", f.path)
+		for _, line := range f.lines {
+			fmt.Fprintf(w, "DA:%d,%d\n", line.number, line.count)
+		}
+		fmt.Fprintf(w, "LF:%d\nLH:%d\nend_of_record\n", len(f.lines), f.linesHit())
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write LCOV report: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package flaky
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/compress"
+	"gotest.tools/gotestsum/internal/history"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	opts.jsonfiles = flags.Args()
+	return run(opts, os.Stdout)
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.historyDB, "history-db", "",
+		"read the results of tests from a history database created by 'gotestsum --history-db', instead of json files")
+	flags.Float64Var(&opts.minScore, "min-score", 0,
+		"only report tests with a flakiness score of at least this value, from 0 to 1")
+	flags.IntVar(&opts.outputLines, "output-lines", 10,
+		"number of lines from the end of the output of the last failure to print for each test, 0 for none")
+	flags.DurationVar(&opts.runGap, "run-gap", 0,
+		"start a new run when the time between two events in a json file is more than this duration")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging.")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] JSONFILE...
+
+Read the results of many runs, and report the flaky tests: the tests which
+passed in some runs and failed in others. The runs are read from json files
+created with 'gotestsum --jsonfile' or 'go test -json', or from the history
+database created by 'gotestsum --history-db'. The json files should be in the
+order the runs happened, and may be compressed with gzip.
+
+Each flaky test is printed with the number of runs, the number of failures, and
+a flakiness score, followed by the end of the output of its last failure. The
+score is the fraction of consecutive runs of the test which changed its result,
+from 0 (never changed) to 1 (changed on every run). A test which fails once in
+a while has a low score, and a test which fails every other run has a score of 1.
+The tests are sorted by score, from the most flaky.
+
+    %[1]s ci-*.json
+    %[1]s --history-db ~/.cache/gotestsum/history.json --min-score 0.2
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+type options struct {
+	jsonfiles   []string
+	historyDB   string
+	minScore    float64
+	outputLines int
+	runGap      time.Duration
+	debug       bool
+}
+
+// result is the result of one run of a test.
+type result struct {
+	action testjson.Action
+	// output is the output of a failed test.
+	output string
+}
+
+// test is the results of every run of a test, from the oldest to the most
+// recent.
+type test struct {
+	pkg     string
+	name    string
+	results []result
+}
+
+func run(opts *options, out io.Writer) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	var tests []*test
+	var err error
+	switch {
+	case opts.historyDB != "" && len(opts.jsonfiles) > 0:
+		return fmt.Errorf("json files can not be used with --history-db")
+	case opts.historyDB != "":
+		tests, err = readHistoryDB(opts.historyDB)
+	case len(opts.jsonfiles) > 0:
+		tests, err = readJSONFiles(opts.jsonfiles, opts.runGap)
+	default:
+		return fmt.Errorf("at least one json file, or --history-db, is required")
+	}
+	if err != nil {
+		return err
+	}
+	writeReport(out, flakyTests(tests, opts.minScore), opts.outputLines)
+	return nil
+}
+
+// readJSONFiles reads the results of tests from the json files, in the order
+// of the files.
+func readJSONFiles(paths []string, runGap time.Duration) ([]*test, error) {
+	tests := newTestSet()
+	for _, path := range paths {
+		if err := readJSONFile(tests, path, runGap); err != nil {
+			return nil, err
+		}
+	}
+	return tests.list, nil
+}
+
+func readJSONFile(tests *testSet, path string, runGap time.Duration) error {
+	fh, err := compress.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read jsonfile: %v", err)
+	}
+	defer func() {
+		if err := fh.Close(); err != nil {
+			log.Errorf("Failed to close file %v: %v", path, err)
+		}
+	}()
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:    fh,
+		SplitRuns: true,
+		RunGap:    runGap,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan testjson from %v: %v", path, err)
+	}
+
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		var results []testResult
+		for _, tc := range pkg.Passed {
+			results = append(results, testResult{TestCase: tc, action: testjson.ActionPass})
+		}
+		for _, tc := range pkg.Failed {
+			results = append(results, testResult{TestCase: tc, action: testjson.ActionFail})
+		}
+		// The ID of a test case is the order it was started in.
+		sort.Slice(results, func(i, j int) bool {
+			return results[i].ID < results[j].ID
+		})
+		for _, tr := range results {
+			r := result{action: tr.action}
+			if tr.action == testjson.ActionFail {
+				r.output = strings.Join(pkg.OutputLines(tr.TestCase), "")
+			}
+			t := tests.get(name, tr.Test.Name())
+			t.results = append(t.results, r)
+		}
+	}
+	return nil
+}
+
+// testResult is a test case with its result.
+type testResult struct {
+	testjson.TestCase
+	action testjson.Action
+}
+
+// readHistoryDB reads the results of tests from the history database.
+func readHistoryDB(path string) ([]*test, error) {
+	db, err := history.OpenDB(path)
+	if err != nil {
+		return nil, err
+	}
+	tests := newTestSet()
+	for pkg, dbTests := range db.Packages {
+		for name, results := range dbTests {
+			t := tests.get(pkg, name)
+			for _, r := range results {
+				if r.Action == testjson.ActionSkip {
+					continue
+				}
+				t.results = append(t.results, result{action: r.Action, output: r.Output})
+			}
+		}
+	}
+	return tests.list, nil
+}
+
+// testSet is the tests read from the runs, in the order they were first seen.
+type testSet struct {
+	list  []*test
+	byKey map[string]*test
+}
+
+func newTestSet() *testSet {
+	return &testSet{byKey: make(map[string]*test)}
+}
+
+// get returns the test, and adds it to the set if it is not in the set.
+func (s *testSet) get(pkg, name string) *test {
+	key := pkg + "\x00" + name
+	t, ok := s.byKey[key]
+	if !ok {
+		t = &test{pkg: pkg, name: name}
+		s.byKey[key] = t
+		s.list = append(s.list, t)
+	}
+	return t
+}
+
+// flakyTest is a test which both passed and failed.
+type flakyTest struct {
+	*test
+	failed int
+	score  float64
+	// lastFailure is the output of the most recent failure.
+	lastFailure string
+}
+
+// flakyTests returns the tests which both passed and failed, with a score of
+// at least minScore, sorted by score from the most flaky, then by name.
+func flakyTests(tests []*test, minScore float64) []flakyTest {
+	var flaky []flakyTest
+	for _, t := range tests {
+		ft := flakyTest{test: t}
+		var changes int
+		for i, r := range t.results {
+			if r.action == testjson.ActionFail {
+				ft.failed++
+				ft.lastFailure = r.output
+			}
+			if i > 0 && r.action != t.results[i-1].action {
+				changes++
+			}
+		}
+		if ft.failed == 0 || ft.failed == len(t.results) {
+			continue
+		}
+		ft.score = float64(changes) / float64(len(t.results)-1)
+		if ft.score < minScore {
+			continue
+		}
+		flaky = append(flaky, ft)
+	}
+	sort.SliceStable(flaky, func(i, j int) bool {
+		a, b := flaky[i], flaky[j]
+		switch {
+		case a.score != b.score:
+			return a.score > b.score
+		case a.pkg != b.pkg:
+			return a.pkg < b.pkg
+		}
+		return a.name < b.name
+	})
+	return flaky
+}
+
+func writeReport(out io.Writer, flaky []flakyTest, outputLines int) {
+	if len(flaky) == 0 {
+		fmt.Fprintln(out, "No flaky tests found.")
+		return
+	}
+	for _, ft := range flaky {
+		fmt.Fprintf(out, "%s %s: score %.2f, failed %d of %d runs\n",
+			ft.pkg, ft.name, ft.score, ft.failed, len(ft.results))
+		if outputLines <= 0 {
+			continue
+		}
+		lines := strings.SplitAfter(strings.TrimSuffix(ft.lastFailure, "\n"), "\n")
+		if len(lines) > outputLines {
+			lines = lines[len(lines)-outputLines:]
+		}
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			fmt.Fprintf(out, "    %s\n", strings.TrimSuffix(line, "\n"))
+		}
+	}
+	fmt.Fprintf(out, "\n%d flaky %s\n", len(flaky), pluralize(len(flaky), "test", "tests"))
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
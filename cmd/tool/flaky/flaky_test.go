@@ -0,0 +1,95 @@
+package flaky
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/history"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+func TestUsage_WithFlagsFromSetupFlags(t *testing.T) {
+	defer env.PatchAll(t, nil)()
+
+	name := "gotestsum tool flaky"
+	flags, _ := setupFlags(name)
+	buf := new(bytes.Buffer)
+	usage(buf, name, flags)
+
+	golden.Assert(t, buf.String(), "cmd-flags-help-text")
+}
+
+const passingEvents = `{"Action":"run","Package":"example.com/one","Test":"TestFirst"}
+{"Action":"pass","Package":"example.com/one","Test":"TestFirst","Elapsed":1}
+{"Action":"run","Package":"example.com/one","Test":"TestSecond"}
+{"Action":"pass","Package":"example.com/one","Test":"TestSecond","Elapsed":1}
+{"Action":"pass","Package":"example.com/one","Elapsed":2}
+`
+
+// failingEvents has a failure of TestFirst which passed when it was re-run,
+// and a failure of TestSecond.
+const failingEvents = `{"Action":"run","Package":"example.com/one","Test":"TestFirst"}
+{"Action":"output","Package":"example.com/one","Test":"TestFirst","Output":"=== RUN   TestFirst\n"}
+{"Action":"output","Package":"example.com/one","Test":"TestFirst","Output":"    one_test.go:10: timeout waiting for server\n"}
+{"Action":"output","Package":"example.com/one","Test":"TestFirst","Output":"--- FAIL: TestFirst (1.00s)\n"}
+{"Action":"fail","Package":"example.com/one","Test":"TestFirst","Elapsed":1}
+{"Action":"run","Package":"example.com/one","Test":"TestSecond"}
+{"Action":"output","Package":"example.com/one","Test":"TestSecond","Output":"    one_test.go:20: wrong answer\n"}
+{"Action":"fail","Package":"example.com/one","Test":"TestSecond","Elapsed":1}
+{"Action":"fail","Package":"example.com/one","Elapsed":2}
+{"Action":"run","Package":"example.com/one","Test":"TestFirst"}
+{"Action":"pass","Package":"example.com/one","Test":"TestFirst","Elapsed":1}
+{"Action":"fail","Package":"example.com/one","Elapsed":1}
+`
+
+func TestRun_JSONFiles(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("1.json", passingEvents),
+		fs.WithFile("2.json", failingEvents),
+		fs.WithFile("3.json", passingEvents))
+	defer dir.Remove()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		jsonfiles:   []string{dir.Join("1.json"), dir.Join("2.json"), dir.Join("3.json")},
+		outputLines: 10,
+	}
+	assert.NilError(t, run(opts, out))
+	golden.Assert(t, out.String(), "report.out")
+
+	out.Reset()
+	opts.minScore = 0.9
+	opts.outputLines = 0
+	assert.NilError(t, run(opts, out))
+	assert.Equal(t, out.String(), "example.com/one TestSecond: score 1.00, failed 1 of 3 runs\n\n1 flaky test\n")
+}
+
+func TestRun_HistoryDB(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+
+	path := filepath.Join(dir.Path(), "history.json")
+	db, err := history.OpenDB(path)
+	assert.NilError(t, err)
+	for i, events := range []string{passingEvents, failingEvents, passingEvents} {
+		exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(events)})
+		assert.NilError(t, err)
+		db.Add(string(rune('a'+i)), exec)
+	}
+	assert.NilError(t, db.Save())
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, run(&options{historyDB: path, outputLines: 10}, out))
+	golden.Assert(t, out.String(), "report.out")
+}
+
+func TestRun_NoInput(t *testing.T) {
+	err := run(&options{}, new(bytes.Buffer))
+	assert.ErrorContains(t, err, "at least one json file, or --history-db, is required")
+}
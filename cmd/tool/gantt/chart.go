@@ -0,0 +1,193 @@
+package gantt
+
+import (
+	"html"
+	"io"
+	"sort"
+	"time"
+
+	"gotest.tools/gotestsum/internal/errwriter"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// row is a single bar in the chart.
+type row struct {
+	label  string
+	start  time.Time
+	end    time.Time
+	result testjson.Action
+	// isTest is true when the row is a test, and false when it is a package.
+	isTest bool
+}
+
+// newRows returns a row for each package in exec, ordered by the time the
+// package started. Unless packagesOnly is true, each package row is followed
+// by a row for each of the top-level tests in the package, ordered by the time
+// the test started.
+func newRows(exec *testjson.Execution, packagesOnly bool) []row {
+	pkgs := exec.Packages()
+	sort.SliceStable(pkgs, func(i, j int) bool {
+		return exec.Package(pkgs[i]).StartTime().Before(exec.Package(pkgs[j]).StartTime())
+	})
+
+	var rows []row // nolint: prealloc
+	for _, name := range pkgs {
+		pkg := exec.Package(name)
+		rows = append(rows, row{
+			label:  name,
+			start:  pkg.StartTime(),
+			end:    pkg.EndTime(),
+			result: pkg.Result(),
+		})
+		if packagesOnly {
+			continue
+		}
+		rows = append(rows, testRows(pkg)...)
+	}
+	return rows
+}
+
+func testRows(pkg *testjson.Package) []row {
+	var rows []row
+	add := func(tcs []testjson.TestCase, result testjson.Action) {
+		for _, tc := range tcs {
+			if tc.Test.IsSubTest() {
+				continue
+			}
+			rows = append(rows, row{
+				label:  tc.Test.Name(),
+				start:  tc.Time,
+				end:    tc.Time.Add(tc.Elapsed),
+				result: result,
+				isTest: true,
+			})
+		}
+	}
+	add(pkg.Passed, testjson.ActionPass)
+	add(pkg.Failed, testjson.ActionFail)
+	add(pkg.Skipped, testjson.ActionSkip)
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].start.Before(rows[j].start)
+	})
+	return rows
+}
+
+// Dimensions of the chart, in pixels.
+const (
+	labelWidth = 360
+	chartWidth = 900
+	rowHeight  = 20
+	barHeight  = 14
+	axisHeight = 24
+	ticks      = 10
+)
+
+func resultColor(result testjson.Action) string {
+	switch result {
+	case testjson.ActionPass:
+		return "#2da44e"
+	case testjson.ActionFail:
+		return "#cf222e"
+	case testjson.ActionSkip:
+		return "#bf8700"
+	default:
+		return "#8c959f"
+	}
+}
+
+// timeRange returns the earliest start and latest end of all the rows.
+func timeRange(rows []row) (time.Time, time.Time) {
+	var start, end time.Time
+	for _, r := range rows {
+		if start.IsZero() || r.start.Before(start) {
+			start = r.start
+		}
+		if r.end.After(end) {
+			end = r.end
+		}
+	}
+	return start, end
+}
+
+func writeSVG(out io.Writer, rows []row) error {
+	start, end := timeRange(rows)
+	total := end.Sub(start)
+	scale := func(t time.Time) float64 {
+		if total <= 0 {
+			return labelWidth
+		}
+		return labelWidth + float64(t.Sub(start))/float64(total)*chartWidth
+	}
+
+	width := labelWidth + chartWidth + 10
+	height := axisHeight + len(rows)*rowHeight
+	w := errwriter.New(out)
+	w.Printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="monospace" font-size="12">`+"\n",
+		width, height)
+
+	for i := 0; i <= ticks; i++ {
+		x := labelWidth + float64(i)*chartWidth/ticks
+		w.Printf(`<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#d0d7de"/>`+"\n",
+			x, axisHeight-4, x, height)
+		w.Printf(`<text x="%.1f" y="%d" text-anchor="middle">%s</text>`+"\n",
+			x, axisHeight-8, formatDuration(total*time.Duration(i)/ticks))
+	}
+
+	for i, r := range rows {
+		y := axisHeight + i*rowHeight
+		label, indent := r.label, 4
+		if r.isTest {
+			indent = 20
+		}
+		x1, x2 := scale(r.start), scale(r.end)
+		if x2-x1 < 1 {
+			x2 = x1 + 1
+		}
+		w.Printf(`<text x="%d" y="%d">%s</text>`+"\n",
+			indent, y+barHeight-2, html.EscapeString(truncate(label, indent)))
+		w.Printf(`<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s">`,
+			x1, y+(rowHeight-barHeight)/2, x2-x1, barHeight, resultColor(r.result))
+		w.Printf(`<title>%s (%s)</title></rect>`+"\n",
+			html.EscapeString(label), formatDuration(r.end.Sub(r.start)))
+	}
+	w.Printf("</svg>\n")
+	return w.Err()
+}
+
+// truncate shortens label so that it fits in the label column of the chart.
+func truncate(label string, indent int) string {
+	const charWidth = 7
+	max := (labelWidth - indent - 8) / charWidth
+	runes := []rune(label)
+	if len(runes) <= max {
+		return label
+	}
+	return "…" + string(runes[len(runes)-max+1:])
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	default:
+		return d.Round(10 * time.Millisecond).String()
+	}
+}
+
+func writeHTML(out io.Writer, rows []row) error {
+	if _, err := io.WriteString(out, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gotestsum gantt chart</title>
+</head>
+<body>
+`); err != nil {
+		return err
+	}
+	if err := writeSVG(out, rows); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "</body>\n</html>\n")
+	return err
+}
@@ -0,0 +1,164 @@
+package gantt
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/compress"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	if flags.NArg() > 1 {
+		usage(os.Stderr, name, flags)
+		return fmt.Errorf("too many arguments: %v", strings.Join(flags.Args(), " "))
+	}
+	opts.jsonfile = flags.Arg(0)
+	return run(opts)
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVarP(&opts.output, "output", "o", "",
+		"write the chart to this file, defaults to stdout")
+	flags.StringVar(&opts.format, "format", "",
+		"format of the chart, one of: html, svg. Defaults to the extension of --output, or svg")
+	flags.BoolVar(&opts.packagesOnly, "packages-only", false,
+		"only show packages in the chart, not the tests in each package")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging.")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] [JSONFILE]
+
+Read a json file and render a Gantt chart of the run. Each package, and each
+top-level test in the package, is drawn as a bar from the time it started to
+the time it finished. Bars are colored by the outcome of the package or test.
+The chart shows how much of the run was spent running packages in parallel,
+and which packages were the bottleneck.
+
+The json file may be created with 'gotestsum --jsonfile' or 'go test -json',
+and may be compressed with gzip.
+If JSONFILE is not set, or is '-', the json is read from stdin.
+
+    %[1]s --output chart.html events.json
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+type options struct {
+	jsonfile     string
+	output       string
+	format       string
+	packagesOnly bool
+	debug        bool
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	format, err := chartFormat(opts)
+	if err != nil {
+		return err
+	}
+
+	in, err := jsonfileReader(opts.jsonfile)
+	if err != nil {
+		return fmt.Errorf("failed to read jsonfile: %v", err)
+	}
+	defer func() {
+		if err := in.Close(); err != nil {
+			log.Errorf("Failed to close file %v: %v", opts.jsonfile, err)
+		}
+	}()
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: in, SplitRuns: true})
+	if err != nil {
+		return fmt.Errorf("failed to scan testjson: %v", err)
+	}
+
+	out, err := outputWriter(opts.output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	defer func() {
+		if err := out.Close(); err != nil {
+			log.Errorf("Failed to close file %v: %v", opts.output, err)
+		}
+	}()
+
+	rows := newRows(exec, opts.packagesOnly)
+	switch format {
+	case "html":
+		return writeHTML(out, rows)
+	default:
+		return writeSVG(out, rows)
+	}
+}
+
+func chartFormat(opts *options) (string, error) {
+	format := opts.format
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(opts.output), ".")
+	}
+	switch format {
+	case "":
+		return "svg", nil
+	case "html", "svg":
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported chart format %q, must be one of: html, svg", format)
+	}
+}
+
+func jsonfileReader(v string) (io.ReadCloser, error) {
+	switch v {
+	case "", "-":
+		return compress.NewReader(os.Stdin)
+	default:
+		return compress.Open(v)
+	}
+}
+
+func outputWriter(v string) (io.WriteCloser, error) {
+	switch v {
+	case "", "-":
+		return nopWriteCloser{Writer: os.Stdout}, nil
+	default:
+		return os.Create(v)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
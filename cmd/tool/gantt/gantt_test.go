@@ -0,0 +1,99 @@
+package gantt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/golden"
+)
+
+func TestUsage_WithFlagsFromSetupFlags(t *testing.T) {
+	defer env.PatchAll(t, nil)()
+
+	name := "gotestsum tool gantt"
+	flags, _ := setupFlags(name)
+	buf := new(bytes.Buffer)
+	usage(buf, name, flags)
+
+	golden.Assert(t, buf.String(), "cmd-flags-help-text")
+}
+
+const events = `{"Time":"2021-01-02T03:04:00Z","Action":"run","Package":"example.com/one","Test":"TestFirst"}
+{"Time":"2021-01-02T03:04:00.5Z","Action":"run","Package":"example.com/two","Test":"TestSecond"}
+{"Time":"2021-01-02T03:04:01Z","Action":"run","Package":"example.com/one","Test":"TestFirst/sub"}
+{"Time":"2021-01-02T03:04:01.5Z","Action":"pass","Package":"example.com/one","Test":"TestFirst/sub","Elapsed":0.5}
+{"Time":"2021-01-02T03:04:02Z","Action":"pass","Package":"example.com/one","Test":"TestFirst","Elapsed":2}
+{"Time":"2021-01-02T03:04:02Z","Action":"run","Package":"example.com/one","Test":"TestSkipped"}
+{"Time":"2021-01-02T03:04:02.2Z","Action":"skip","Package":"example.com/one","Test":"TestSkipped","Elapsed":0.2}
+{"Time":"2021-01-02T03:04:02.5Z","Action":"pass","Package":"example.com/one","Elapsed":2.5}
+{"Time":"2021-01-02T03:04:04Z","Action":"fail","Package":"example.com/two","Test":"TestSecond","Elapsed":3.5}
+{"Time":"2021-01-02T03:04:05Z","Action":"fail","Package":"example.com/two","Elapsed":4.5}
+`
+
+func scanEvents(t *testing.T) *testjson.Execution {
+	t.Helper()
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(events),
+	})
+	assert.NilError(t, err)
+	return exec
+}
+
+func TestNewRows(t *testing.T) {
+	rows := newRows(scanEvents(t), false)
+	var labels []string
+	for _, r := range rows {
+		labels = append(labels, r.label)
+	}
+	expected := []string{"example.com/one", "TestFirst", "TestSkipped", "example.com/two", "TestSecond"}
+	assert.DeepEqual(t, labels, expected)
+	assert.Equal(t, rows[2].result, testjson.ActionSkip)
+	assert.Equal(t, rows[3].result, testjson.ActionFail)
+
+	rows = newRows(scanEvents(t), true)
+	assert.Equal(t, len(rows), 2)
+}
+
+func TestWriteSVG(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := writeSVG(buf, newRows(scanEvents(t), false))
+	assert.NilError(t, err)
+	golden.Assert(t, buf.String(), "chart.svg")
+}
+
+func TestChartFormat(t *testing.T) {
+	var testCases = []struct {
+		opts     options
+		expected string
+		err      string
+	}{
+		{expected: "svg"},
+		{opts: options{output: "chart.html"}, expected: "html"},
+		{opts: options{output: "chart.svg"}, expected: "svg"},
+		{opts: options{output: "chart.out", format: "html"}, expected: "html"},
+		{opts: options{output: "chart.png"}, err: `unsupported chart format "png", must be one of: html, svg`},
+	}
+	for _, tc := range testCases {
+		format, err := chartFormat(&tc.opts)
+		if tc.err != "" {
+			assert.Error(t, err, tc.err)
+			continue
+		}
+		assert.NilError(t, err)
+		assert.Equal(t, format, tc.expected)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, truncate("example.com/pkg", 0), "example.com/pkg")
+
+	label := strings.Repeat("é", 60)
+	actual := truncate(label, 0)
+	assert.Assert(t, utf8.ValidString(actual), actual)
+	assert.Equal(t, actual, "…"+strings.Repeat("é", 49))
+}
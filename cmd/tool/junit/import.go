@@ -0,0 +1,361 @@
+package junit
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/compress"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func runImport(name string, args []string) error {
+	flags, opts := setupImportFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		importUsage(os.Stderr, name, flags)
+		return err
+	}
+	opts.reports = flags.Args()
+	return importReports(opts)
+}
+
+func setupImportFlags(name string) (*pflag.FlagSet, *importOptions) {
+	opts := &importOptions{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.Usage = func() {
+		importUsage(os.Stdout, name, flags)
+	}
+	flags.StringVarP(&opts.output, "output", "o", "",
+		"write the events to this file instead of stdout, compressed with gzip when the name ends with .gz")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging.")
+	return flags, opts
+}
+
+func importUsage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] REPORT...
+
+Convert JUnit XML reports to test2json events, in the format of the json file
+written by 'gotestsum --jsonfile', so that the results of old CI runs can be
+read by the other 'gotestsum tool' commands.
+
+The events are an approximation of the original run. The classname of each
+testcase is used as the package, or the name of the testsuite when the
+classname is empty. Each test is started when the test before it in the
+testsuite ended, starting from the timestamp of the testsuite. The output of a
+test is the contents of its failure, error, skipped, system-out, and
+system-err elements.
+
+Each report is written as a separate run, in the order of the arguments.
+
+    %[1]s report.xml --output events.json
+    %[1]s ci-*.xml | gotestsum tool slowest
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+type importOptions struct {
+	reports []string
+	output  string
+	debug   bool
+}
+
+func importReports(opts *importOptions) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	if len(opts.reports) == 0 {
+		return fmt.Errorf("at least one JUnit XML report is required")
+	}
+	out, err := outputWriter(opts.output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	for _, path := range opts.reports {
+		if err := importReport(out, path); err != nil {
+			out.Close() // nolint: errcheck
+			return err
+		}
+	}
+	return out.Close()
+}
+
+func outputWriter(path string) (io.WriteCloser, error) {
+	switch path {
+	case "", "-":
+		return nopWriteCloser{Writer: os.Stdout}, nil
+	}
+	fh, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return compress.NewWriter(path, fh), nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func importReport(out io.Writer, path string) error {
+	log.Debugf("reading JUnit XML report %v", path)
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read JUnit XML report: %v", err)
+	}
+	suites, err := parseReport(raw)
+	if err != nil {
+		return fmt.Errorf("failed to parse JUnit XML report %v: %v", path, err)
+	}
+
+	var started time.Time
+	if len(suites) > 0 {
+		started = suites[0].timestamp()
+	}
+	if _, err := fmt.Fprintf(out, "%s\n", testjson.RunStartEvent(filepath.Base(path), 0, started)); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(out)
+	for _, suite := range suites {
+		for _, event := range suiteEvents(suite) {
+			if err := enc.Encode(event); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// testSuites is the root element of a JUnit XML report. It is either a
+// testsuites element, or a single testsuite.
+type testSuites struct {
+	XMLName xml.Name
+	testSuite
+}
+
+type testSuite struct {
+	Name      string      `xml:"name,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Suites    []testSuite `xml:"testsuite"`
+	TestCases []testCase  `xml:"testcase"`
+}
+
+type testCase struct {
+	Classname string  `xml:"classname,attr"`
+	Name      string  `xml:"name,attr"`
+	Time      string  `xml:"time,attr"`
+	Failure   *result `xml:"failure"`
+	Error     *result `xml:"error"`
+	Skipped   *result `xml:"skipped"`
+	SystemOut string  `xml:"system-out"`
+	SystemErr string  `xml:"system-err"`
+}
+
+type result struct {
+	Message  string `xml:"message,attr"`
+	Contents string `xml:",chardata"`
+}
+
+// parseReport returns every testsuite in the report, including the testsuites
+// nested in other testsuites.
+func parseReport(raw []byte) ([]testSuite, error) {
+	var root testSuites
+	if err := xml.Unmarshal(raw, &root); err != nil {
+		return nil, err
+	}
+	switch root.XMLName.Local {
+	case "testsuites":
+		return flattenSuites(root.Suites, ""), nil
+	case "testsuite":
+		return flattenSuites([]testSuite{root.testSuite}, ""), nil
+	}
+	return nil, fmt.Errorf("unexpected root element %v", root.XMLName.Local)
+}
+
+// flattenSuites returns suites, and the suites nested in them. A nested suite
+// without a timestamp uses the timestamp of its parent.
+func flattenSuites(suites []testSuite, timestamp string) []testSuite {
+	var result []testSuite
+	for _, suite := range suites {
+		if suite.Timestamp == "" {
+			suite.Timestamp = timestamp
+		}
+		result = append(result, suite)
+		result = append(result, flattenSuites(suite.Suites, suite.Timestamp)...)
+	}
+	return result
+}
+
+func (s testSuite) timestamp() time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, s.Timestamp); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// event is a test2json event, with the fields in the same order, and omitted
+// when empty in the same way.
+type event struct {
+	Time    *time.Time      `json:",omitempty"`
+	Action  testjson.Action `json:"Action"`
+	Package string          `json:",omitempty"`
+	Test    string          `json:",omitempty"`
+	Elapsed float64         `json:",omitempty"`
+	Output  string          `json:",omitempty"`
+}
+
+// suiteEvents returns the events for the tests in a testsuite, grouped by
+// package. The packages are started in the order of their first test, and end
+// after the last test in the testsuite.
+func suiteEvents(suite testSuite) []event {
+	if len(suite.TestCases) == 0 {
+		return nil
+	}
+	now := suite.timestamp()
+	at := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+		return &t
+	}
+
+	var events []event
+	var packages []string
+	failed := make(map[string]bool)
+	elapsed := make(map[string]float64)
+	for _, tc := range suite.TestCases {
+		pkg := tc.Classname
+		if pkg == "" {
+			pkg = suite.Name
+		}
+		if _, ok := elapsed[pkg]; !ok {
+			packages = append(packages, pkg)
+			elapsed[pkg] = 0
+			events = append(events, event{Time: at(now), Action: testjson.ActionStart, Package: pkg})
+		}
+
+		seconds := parseSeconds(tc.Time)
+		action := tc.action()
+		if action == testjson.ActionFail {
+			failed[pkg] = true
+		}
+		output := func(text string) event {
+			return event{Time: at(now), Action: testjson.ActionOutput, Package: pkg, Test: tc.Name, Output: text}
+		}
+		events = append(events, event{Time: at(now), Action: testjson.ActionRun, Package: pkg, Test: tc.Name})
+
+		// The output in a report written by gotestsum already includes the
+		// framing lines printed by go test.
+		lines := tc.outputLines()
+		if !hasLinePrefix(lines, "=== RUN   "+tc.Name) {
+			events = append(events, output("=== RUN   "+tc.Name+"\n"))
+		}
+		for _, line := range lines {
+			events = append(events, output(line))
+		}
+		if !now.IsZero() {
+			now = now.Add(time.Duration(seconds * float64(time.Second)))
+		}
+		framing := fmt.Sprintf("--- %s: %s ", strings.ToUpper(string(action)), tc.Name)
+		if !hasLinePrefix(lines, framing) {
+			events = append(events, output(fmt.Sprintf("%s(%.2fs)\n", framing, seconds)))
+		}
+		events = append(events, event{Time: at(now), Action: action, Package: pkg, Test: tc.Name, Elapsed: seconds})
+		elapsed[pkg] += seconds
+	}
+
+	// When the testsuite is a single package the time of the testsuite
+	// includes the time spent outside of tests.
+	if len(packages) == 1 {
+		if seconds := parseSeconds(suite.Time); seconds > elapsed[packages[0]] {
+			elapsed[packages[0]] = seconds
+		}
+	}
+	for _, pkg := range packages {
+		action := testjson.ActionPass
+		if failed[pkg] {
+			action = testjson.ActionFail
+		}
+		events = append(events, event{Time: at(now), Action: action, Package: pkg, Elapsed: elapsed[pkg]})
+	}
+	return events
+}
+
+func (tc testCase) action() testjson.Action {
+	switch {
+	case tc.Failure != nil || tc.Error != nil:
+		return testjson.ActionFail
+	case tc.Skipped != nil:
+		return testjson.ActionSkip
+	}
+	return testjson.ActionPass
+}
+
+// outputLines returns the output of the test, as lines ending with a newline.
+func (tc testCase) outputLines() []string {
+	var lines []string
+	add := func(text string) {
+		text = strings.Trim(text, "\n")
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, line+"\n")
+		}
+	}
+	for _, r := range []*result{tc.Failure, tc.Error, tc.Skipped} {
+		if r == nil {
+			continue
+		}
+		if strings.TrimSpace(r.Contents) == "" {
+			add(r.Message)
+			continue
+		}
+		add(r.Contents)
+	}
+	add(tc.SystemOut)
+	add(tc.SystemErr)
+	return lines
+}
+
+func hasLinePrefix(lines []string, prefix string) bool {
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), strings.TrimSpace(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSeconds returns the number of seconds in a time attribute, or 0 if the
+// attribute is not a number. Some reports format the time with a thousands
+// separator, ex: 1,024.5.
+func parseSeconds(value string) float64 {
+	seconds, err := strconv.ParseFloat(strings.Replace(value, ",", "", -1), 64)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return seconds
+}
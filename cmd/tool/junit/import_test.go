@@ -0,0 +1,77 @@
+package junit
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/compress"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+func TestUsage_WithFlagsFromSetupFlags(t *testing.T) {
+	defer env.PatchAll(t, nil)()
+
+	name := "gotestsum tool junit import"
+	flags, _ := setupImportFlags(name)
+	buf := new(bytes.Buffer)
+	importUsage(buf, name, flags)
+
+	golden.Assert(t, buf.String(), "cmd-flags-help-text")
+}
+
+func TestImportReports(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+
+	opts := &importOptions{
+		reports: []string{"testdata/report.xml"},
+		output:  filepath.Join(dir.Path(), "events.json"),
+	}
+	assert.NilError(t, importReports(opts))
+
+	raw, err := ioutil.ReadFile(opts.output)
+	assert.NilError(t, err)
+	golden.Assert(t, string(raw), "events.json.golden")
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: bytes.NewReader(raw)})
+	assert.NilError(t, err)
+	assert.Equal(t, exec.Total(), 5)
+	assert.Equal(t, len(exec.Failed()), 2)
+	assert.Equal(t, len(exec.Skipped()), 1)
+	assert.DeepEqual(t, exec.Packages(), []string{"com.example.StoreTest", "example.com/app/api"})
+}
+
+func TestImportReports_Gzip(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+
+	opts := &importOptions{
+		reports: []string{"testdata/report.xml", "testdata/report.xml"},
+		output:  filepath.Join(dir.Path(), "events.json.gz"),
+	}
+	assert.NilError(t, importReports(opts))
+
+	reader, err := compress.Open(opts.output)
+	assert.NilError(t, err)
+	defer reader.Close() // nolint: errcheck
+	raw, err := ioutil.ReadAll(reader)
+	assert.NilError(t, err)
+	assert.Equal(t, strings.Count(string(raw), `"Action":"run-start"`), 2)
+}
+
+func TestParseReport_SingleTestSuite(t *testing.T) {
+	suites, err := parseReport([]byte(`<testsuite name="pkg"><testcase name="TestOne" time="1"/></testsuite>`))
+	assert.NilError(t, err)
+	assert.Equal(t, len(suites), 1)
+	assert.Equal(t, suites[0].TestCases[0].Name, "TestOne")
+
+	_, err = parseReport([]byte(`<coverage/>`))
+	assert.ErrorContains(t, err, "unexpected root element coverage")
+}
@@ -0,0 +1,426 @@
+package slowest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/tui"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// testRow is one run of a test case.
+type testRow struct {
+	pkg     string
+	test    testjson.TestName
+	elapsed time.Duration
+	result  testjson.Action
+	// attempt is 1 for the first run of the test, and incremented for each
+	// rerun.
+	attempt int
+}
+
+type pkgRow struct {
+	name    string
+	elapsed time.Duration
+	tests   int
+	failed  int
+	result  testjson.Action
+}
+
+type view int
+
+const (
+	viewPackages view = iota
+	viewTests
+	viewDetail
+)
+
+type sortKey int
+
+const (
+	sortElapsed sortKey = iota
+	sortName
+	sortOutcome
+)
+
+var sortKeyNames = []string{"elapsed", "name", "outcome"}
+
+// outcomes is the order of the values for the outcome filter. An empty
+// Action shows all outcomes.
+var outcomes = []testjson.Action{"", testjson.ActionFail, testjson.ActionPass, testjson.ActionSkip}
+
+// browser is the tui.Model for 'tool slowest --interactive'.
+type browser struct {
+	tests      []testRow
+	packages   []pkgRow
+	maxAttempt int
+
+	view view
+	// pkg limits the tests view to a single package when it is not empty.
+	pkg string
+	// detail is the test shown by the detail view.
+	detail testRow
+
+	sort    sortKey
+	reverse bool
+	outcome int
+	attempt int
+	filter  string
+	editing bool
+
+	pkgList  tui.List
+	testList tui.List
+	// height is the number of rows shown by the last call to View.
+	height int
+}
+
+func newBrowser(exec *testjson.Execution) *browser {
+	b := &browser{maxAttempt: 1}
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		b.packages = append(b.packages, pkgRow{
+			name:    name,
+			elapsed: pkg.Elapsed(),
+			tests:   pkg.Total,
+			failed:  len(pkg.Failed),
+			result:  pkg.Result(),
+		})
+		add := func(tcs []testjson.TestCase, result testjson.Action) {
+			for _, tc := range tcs {
+				row := testRow{
+					pkg:     name,
+					test:    tc.Test,
+					elapsed: tc.Elapsed,
+					result:  result,
+					attempt: tc.RunID + 1,
+				}
+				if row.attempt > b.maxAttempt {
+					b.maxAttempt = row.attempt
+				}
+				b.tests = append(b.tests, row)
+			}
+		}
+		add(pkg.Passed, testjson.ActionPass)
+		add(pkg.Failed, testjson.ActionFail)
+		add(pkg.Skipped, testjson.ActionSkip)
+	}
+	return b
+}
+
+func (b *browser) Update(key tui.Key) bool {
+	if b.editing {
+		b.updateFilter(key)
+		return false
+	}
+
+	list := b.list()
+	if list != nil && list.Update(key, b.height) {
+		return false
+	}
+	switch key {
+	case "q", tui.KeyCtrlC:
+		return true
+	case tui.KeyEnter, tui.KeyRight, "l":
+		b.open()
+	case tui.KeyEscape, tui.KeyBackspace, tui.KeyLeft, "h":
+		b.back()
+	case tui.KeyTab:
+		b.toggleView()
+	case "s":
+		b.sort = (b.sort + 1) % sortKey(len(sortKeyNames))
+	case "r":
+		b.reverse = !b.reverse
+	case "o":
+		b.outcome = (b.outcome + 1) % len(outcomes)
+	case "a":
+		b.attempt = (b.attempt + 1) % (b.maxAttempt + 1)
+	case "/":
+		b.editing = true
+	}
+	return false
+}
+
+func (b *browser) updateFilter(key tui.Key) {
+	switch key {
+	case tui.KeyEnter:
+		b.editing = false
+	case tui.KeyEscape, tui.KeyCtrlC:
+		b.editing = false
+		b.filter = ""
+	case tui.KeyBackspace:
+		if r := []rune(b.filter); len(r) > 0 {
+			b.filter = string(r[:len(r)-1])
+		}
+	default:
+		if len([]rune(string(key))) == 1 {
+			b.filter += string(key)
+		}
+	}
+}
+
+func (b *browser) list() *tui.List {
+	switch b.view {
+	case viewPackages:
+		return &b.pkgList
+	case viewTests:
+		return &b.testList
+	}
+	return nil
+}
+
+func (b *browser) open() {
+	switch b.view {
+	case viewPackages:
+		pkgs := b.packageRows()
+		if len(pkgs) == 0 {
+			return
+		}
+		b.pkg = pkgs[b.pkgList.Cursor].name
+		b.view = viewTests
+		b.testList = tui.List{}
+	case viewTests:
+		tests := b.testRows()
+		if len(tests) == 0 {
+			return
+		}
+		b.detail = tests[b.testList.Cursor]
+		b.view = viewDetail
+	}
+}
+
+func (b *browser) back() {
+	switch b.view {
+	case viewDetail:
+		b.view = viewTests
+	case viewTests:
+		if b.pkg != "" {
+			b.pkg = ""
+			b.view = viewPackages
+		}
+	}
+}
+
+func (b *browser) toggleView() {
+	switch b.view {
+	case viewPackages:
+		b.pkg = ""
+		b.view = viewTests
+		b.testList = tui.List{}
+	case viewTests:
+		b.view = viewPackages
+	}
+}
+
+func (b *browser) packageRows() []pkgRow {
+	outcome := outcomes[b.outcome]
+	var rows []pkgRow
+	for _, row := range b.packages {
+		if outcome != "" && row.result != outcome {
+			continue
+		}
+		if !strings.Contains(row.name, b.filter) {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return b.compare(rows[i].elapsed, rows[j].elapsed,
+			rows[i].name, rows[j].name, rows[i].result, rows[j].result) < 0
+	})
+	return rows
+}
+
+func (b *browser) testRows() []testRow {
+	outcome := outcomes[b.outcome]
+	var rows []testRow
+	for _, row := range b.tests {
+		switch {
+		case b.pkg != "" && row.pkg != b.pkg:
+			continue
+		case outcome != "" && row.result != outcome:
+			continue
+		case b.attempt != 0 && row.attempt != b.attempt:
+			continue
+		case !strings.Contains(row.pkg+"."+row.test.Name(), b.filter):
+			continue
+		}
+		rows = append(rows, row)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return b.compare(rows[i].elapsed, rows[j].elapsed,
+			rows[i].pkg+"."+rows[i].test.Name(), rows[j].pkg+"."+rows[j].test.Name(),
+			rows[i].result, rows[j].result) < 0
+	})
+	return rows
+}
+
+// compare two rows using the sort key. Elapsed sorts the slowest first, name
+// sorts alphabetically, and outcome sorts failures first. Rows that are equal
+// by name or outcome are sorted by elapsed.
+func (b *browser) compare(
+	elapsedI, elapsedJ time.Duration,
+	nameI, nameJ string,
+	resultI, resultJ testjson.Action,
+) int {
+	var c int
+	switch b.sort {
+	case sortName:
+		c = strings.Compare(nameI, nameJ)
+	case sortOutcome:
+		c = outcomeRank(resultI) - outcomeRank(resultJ)
+	}
+	if c == 0 {
+		switch {
+		case elapsedI > elapsedJ:
+			c = -1
+		case elapsedI < elapsedJ:
+			c = 1
+		}
+	}
+	if b.reverse {
+		return -c
+	}
+	return c
+}
+
+func outcomeRank(result testjson.Action) int {
+	switch result {
+	case testjson.ActionFail:
+		return 0
+	case testjson.ActionSkip:
+		return 2
+	}
+	return 1
+}
+
+// Number of lines used by the header and footer of each view.
+const (
+	headerLines = 3
+	footerLines = 1
+)
+
+func (b *browser) View(width, height int) []string {
+	b.height = height - headerLines - footerLines
+	var title, columns string
+	var rows []string
+	var list *tui.List
+
+	switch b.view {
+	case viewPackages:
+		title = "Packages"
+		columns = fmt.Sprintf("%10s  %-6s  %6s  %6s  %s", "ELAPSED", "RESULT", "TESTS", "FAILED", "PACKAGE")
+		for _, row := range b.packageRows() {
+			rows = append(rows, fmt.Sprintf("%10s  %-6s  %6d  %6d  %s",
+				formatElapsed(row.elapsed), formatResult(row.result), row.tests, row.failed, row.name))
+		}
+		list = &b.pkgList
+	case viewTests:
+		title = "Tests"
+		if b.pkg != "" {
+			title = "Tests in " + b.pkg
+		}
+		columns = fmt.Sprintf("%10s  %-6s  %7s  %s", "ELAPSED", "RESULT", "ATTEMPT", "TEST")
+		for _, row := range b.testRows() {
+			name := row.test.Name()
+			if b.pkg == "" {
+				name = testjson.RelativePackagePath(row.pkg) + "." + name
+			}
+			rows = append(rows, fmt.Sprintf("%10s  %-6s  %7d  %s",
+				formatElapsed(row.elapsed), formatResult(row.result), row.attempt, name))
+		}
+		list = &b.testList
+	case viewDetail:
+		return b.detailView(width, height)
+	}
+
+	lines := []string{
+		tui.Bold + tui.Line("gotestsum tool slowest · "+title, width, false) + tui.Reset,
+		tui.Line(b.status(), width, false),
+		tui.Bold + tui.Line(columns, width, false) + tui.Reset,
+	}
+	list.SetLen(len(rows))
+	start, end := list.Visible(b.height)
+	for i := start; i < end; i++ {
+		lines = append(lines, tui.Line(rows[i], width, i == list.Cursor))
+	}
+	if len(rows) == 0 {
+		lines = append(lines, "no results")
+	}
+	return b.withFooter(lines, width, height,
+		"↑/↓ move  enter open  esc back  tab packages/tests  s sort  r reverse  o outcome  a attempt  / filter  q quit")
+}
+
+func (b *browser) status() string {
+	arrow := "↓"
+	if b.reverse {
+		arrow = "↑"
+	}
+	outcome := string(outcomes[b.outcome])
+	if outcome == "" {
+		outcome = "all"
+	}
+	attempt := "all"
+	if b.attempt != 0 {
+		attempt = fmt.Sprint(b.attempt)
+	}
+	filter := b.filter
+	if b.editing {
+		filter += "█"
+	}
+	return fmt.Sprintf("sort: %s %s  outcome: %s  attempt: %s  filter: %s",
+		sortKeyNames[b.sort], arrow, outcome, attempt, filter)
+}
+
+func (b *browser) detailView(width, height int) []string {
+	row := b.detail
+	lines := []string{
+		tui.Bold + tui.Line("gotestsum tool slowest · "+row.test.Name(), width, false) + tui.Reset,
+		tui.Line("package: "+row.pkg, width, false),
+		tui.Bold + tui.Line(fmt.Sprintf("%10s  %-6s  %7s", "ELAPSED", "RESULT", "ATTEMPT"), width, false) + tui.Reset,
+	}
+	var attempts []testRow
+	var total time.Duration
+	for _, other := range b.tests {
+		if other.pkg == row.pkg && other.test == row.test {
+			attempts = append(attempts, other)
+			total += other.elapsed
+		}
+	}
+	sort.SliceStable(attempts, func(i, j int) bool {
+		return attempts[i].attempt < attempts[j].attempt
+	})
+	for _, other := range attempts {
+		lines = append(lines, tui.Line(fmt.Sprintf("%10s  %-6s  %7d",
+			formatElapsed(other.elapsed), formatResult(other.result), other.attempt), width, other == row))
+	}
+	if count := len(attempts); count > 1 {
+		mean := total / time.Duration(count)
+		lines = append(lines, tui.Line(fmt.Sprintf("%10s  mean of %d runs", formatElapsed(mean), count), width, false))
+	}
+	return b.withFooter(lines, width, height, "esc back  q quit")
+}
+
+// withFooter pads lines to fill the screen, and adds the help text on the last
+// line.
+func (b *browser) withFooter(lines []string, width, height int, help string) []string {
+	for len(lines) < height-footerLines {
+		lines = append(lines, "")
+	}
+	if len(lines) > height-footerLines && height > footerLines {
+		lines = lines[:height-footerLines]
+	}
+	return append(lines, tui.Line(help, width, false))
+}
+
+func formatElapsed(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+func formatResult(result testjson.Action) string {
+	if result == testjson.ActionFail {
+		return "FAIL"
+	}
+	return string(result)
+}
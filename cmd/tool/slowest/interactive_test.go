@@ -0,0 +1,143 @@
+package slowest
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/tui"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+const interactiveRun = `{"Action":"run","Package":"example.com/fast","Test":"TestFast"}
+{"Action":"pass","Package":"example.com/fast","Test":"TestFast","Elapsed":0.01}
+{"Action":"pass","Package":"example.com/fast","Elapsed":0.2}
+{"Action":"run","Package":"example.com/slow","Test":"TestSlow"}
+{"Action":"pass","Package":"example.com/slow","Test":"TestSlow","Elapsed":2.5}
+{"Action":"run","Package":"example.com/slow","Test":"TestFlaky"}
+{"Action":"fail","Package":"example.com/slow","Test":"TestFlaky","Elapsed":1.2}
+{"Action":"run","Package":"example.com/slow","Test":"TestSkipped"}
+{"Action":"skip","Package":"example.com/slow","Test":"TestSkipped"}
+{"Action":"fail","Package":"example.com/slow","Elapsed":4}
+`
+
+const interactiveRerun = `{"Action":"run","Package":"example.com/slow","Test":"TestFlaky"}
+{"Action":"pass","Package":"example.com/slow","Test":"TestFlaky","Elapsed":0.8}
+{"Action":"pass","Package":"example.com/slow","Elapsed":1}
+`
+
+func newTestBrowser(t *testing.T) *browser {
+	t.Helper()
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(interactiveRun),
+	})
+	assert.NilError(t, err)
+	_, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		RunID:     1,
+		Stdout:    strings.NewReader(interactiveRerun),
+		Execution: exec,
+	})
+	assert.NilError(t, err)
+	return newBrowser(exec)
+}
+
+// rows returns the rows of the list in the view, without the header, footer,
+// or escape sequences.
+func rows(b *browser) []string {
+	lines := b.View(120, 12)
+	var result []string
+	for _, line := range lines[headerLines : len(lines)-footerLines] {
+		line = strings.NewReplacer(tui.Reverse, "", tui.Reset, "", tui.Bold, "").Replace(line)
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+func press(b *browser, keys ...tui.Key) {
+	for _, key := range keys {
+		b.Update(key)
+	}
+}
+
+func TestBrowser_Packages(t *testing.T) {
+	b := newTestBrowser(t)
+	expected := []string{
+		"1s  pass         4       1  example.com/slow",
+		"200ms  pass         1       0  example.com/fast",
+	}
+	assert.DeepEqual(t, rows(b), expected)
+
+	press(b, "s")
+	expected = []string{
+		"200ms  pass         1       0  example.com/fast",
+		"1s  pass         4       1  example.com/slow",
+	}
+	assert.DeepEqual(t, rows(b), expected)
+	assert.Assert(t, strings.Contains(b.View(120, 12)[1], "sort: name ↓"))
+}
+
+func TestBrowser_TestsInPackage(t *testing.T) {
+	b := newTestBrowser(t)
+	press(b, tui.KeyEnter)
+	assert.Assert(t, strings.Contains(b.View(120, 12)[0], "Tests in example.com/slow"))
+	expected := []string{
+		"2.5s  pass          1  TestSlow",
+		"1.2s  FAIL          1  TestFlaky",
+		"800ms  pass          2  TestFlaky",
+		"0s  skip          1  TestSkipped",
+	}
+	assert.DeepEqual(t, rows(b), expected)
+
+	t.Run("filter by outcome", func(t *testing.T) {
+		press(b, "o")
+		assert.DeepEqual(t, rows(b), []string{"1.2s  FAIL          1  TestFlaky"})
+		press(b, "o", "o", "o")
+	})
+
+	t.Run("filter by attempt", func(t *testing.T) {
+		press(b, "a", "a")
+		assert.DeepEqual(t, rows(b), []string{"800ms  pass          2  TestFlaky"})
+		press(b, "a")
+	})
+
+	t.Run("filter by name", func(t *testing.T) {
+		press(b, "/", "S", "l", "o", "x", tui.KeyBackspace, tui.KeyEnter)
+		assert.DeepEqual(t, rows(b), []string{"2.5s  pass          1  TestSlow"})
+		press(b, "/", tui.KeyEscape)
+		assert.Equal(t, len(rows(b)), 4)
+	})
+
+	t.Run("reverse", func(t *testing.T) {
+		press(b, "r")
+		assert.Equal(t, rows(b)[0], "0s  skip          1  TestSkipped")
+		press(b, "r")
+	})
+
+	t.Run("detail", func(t *testing.T) {
+		press(b, tui.KeyDown, tui.KeyEnter)
+		expected := []string{
+			"1.2s  FAIL          1",
+			"800ms  pass          2",
+			"1s  mean of 2 runs",
+		}
+		assert.DeepEqual(t, rows(b), expected)
+		assert.Assert(t, strings.Contains(b.View(120, 12)[0], "TestFlaky"))
+	})
+
+	t.Run("back to packages", func(t *testing.T) {
+		press(b, tui.KeyEscape, tui.KeyEscape)
+		assert.Equal(t, b.view, viewPackages)
+		assert.Assert(t, b.Update("q"))
+	})
+}
+
+func TestBrowser_AllTests(t *testing.T) {
+	b := newTestBrowser(t)
+	press(b, tui.KeyTab)
+	lines := rows(b)
+	assert.Equal(t, len(lines), 5)
+	assert.Equal(t, lines[0], "2.5s  pass          1  example.com/slow.TestSlow")
+	assert.Equal(t, lines[4], "0s  skip          1  example.com/slow.TestSkipped")
+}
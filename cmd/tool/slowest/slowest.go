@@ -3,12 +3,14 @@ package slowest
 import (
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"time"
 
 	"github.com/dnephin/pflag"
 	"gotest.tools/gotestsum/internal/aggregate"
+	"gotest.tools/gotestsum/internal/compress"
+	"gotest.tools/gotestsum/internal/history"
+	"gotest.tools/gotestsum/internal/tui"
 	"gotest.tools/gotestsum/log"
 	"gotest.tools/gotestsum/testjson"
 )
@@ -35,10 +37,18 @@ func setupFlags(name string) (*pflag.FlagSet, *options) {
 	}
 	flags.StringVar(&opts.jsonfile, "jsonfile", os.Getenv("GOTESTSUM_JSONFILE"),
 		"path to test2json output, defaults to stdin")
+	flags.StringVar(&opts.historyDB, "history-db", "",
+		"read the elapsed times of tests from a history database created by 'gotestsum --history-db', instead of a json file")
 	flags.DurationVar(&opts.threshold, "threshold", 100*time.Millisecond,
 		"test cases with elapsed time greater than threshold are slow tests")
 	flags.StringVar(&opts.skipStatement, "skip-stmt", "",
 		"add this go statement to slow tests, instead of printing the list of slow tests")
+	flags.BoolVar(&opts.interactive, "interactive", false,
+		"open a terminal UI to sort, filter, and explore the timing of every package and test")
+	flags.DurationVar(&opts.runGap, "run-gap", 0,
+		"start a new run when the time between two events in the json file is more than this duration")
+	flags.BoolVar(&opts.excludeWarmUp, "exclude-warmup", false,
+		"ignore the first run of each package that has more than one run, because it runs with cold caches")
 	flags.BoolVar(&opts.debug, "debug", false,
 		"enable debug logging.")
 	return flags, opts
@@ -49,7 +59,8 @@ func usage(out io.Writer, name string, flags *pflag.FlagSet) {
     %[1]s [flags]
 
 Read a json file and print or update tests which are slower than threshold.
-The json file may be created with 'gotestsum --jsonfile' or 'go test -json'.
+The json file may be created with 'gotestsum --jsonfile' or 'go test -json',
+and may be compressed with gzip.
 If a TestCase appears more than once in the json file, it will only appear once
 in the output, and the median value of all the elapsed times will be used.
 
@@ -79,6 +90,26 @@ Alternatively, a custom --skip-stmt may be provided as a string:
     '
     go test -json -short ./... | %[1]s --skip-stmt "$skip_stmt"
 
+If --interactive is set, a terminal UI is opened instead of printing the list.
+The UI shows every package and every run of each test, not only the tests slower
+than threshold. Packages and tests may be sorted by elapsed time, name, or
+outcome, and filtered by outcome, attempt, and name. Select a package to see
+its tests, and select a test to see the elapsed time of each attempt.
+
+The json file may contain more than one run, for example when the output of
+several runs was appended to the same file. A new run starts when a package that
+already finished starts again, or when the time between two events is more than
+--run-gap. Each run is shown as a separate attempt by --interactive.
+
+If --history-db is set, the elapsed times of tests are read from the history
+database file created by 'gotestsum --history-db', instead of a json file. The
+median of the elapsed times of the recent runs of each test is used.
+
+If --exclude-warmup is set, the first run of each package is ignored when the
+json file or history database has more than one run of the package. The first
+run is often slower because the build cache and other caches are cold, which
+can make a test look slow when it is not.
+
 Note that this tool does not add imports, so using a custom statement may require
 you to add imports to the file.
 
@@ -95,7 +126,11 @@ Flags:
 type options struct {
 	threshold     time.Duration
 	jsonfile      string
+	historyDB     string
 	skipStatement string
+	interactive   bool
+	runGap        time.Duration
+	excludeWarmUp bool
 	debug         bool
 }
 
@@ -103,6 +138,19 @@ func run(opts *options) error {
 	if opts.debug {
 		log.SetLevel(log.DebugLevel)
 	}
+	if opts.interactive && opts.skipStatement != "" {
+		return fmt.Errorf("--interactive can not be used with --skip-stmt")
+	}
+	if opts.historyDB != "" {
+		if opts.interactive {
+			return fmt.Errorf("--interactive can not be used with --history-db")
+		}
+		db, err := history.OpenDB(opts.historyDB)
+		if err != nil {
+			return err
+		}
+		return printOrSkip(opts, aggregate.SlowestTestCases(testCases(opts, db.TestCases()), opts.threshold))
+	}
 	in, err := jsonfileReader(opts.jsonfile)
 	if err != nil {
 		return fmt.Errorf("failed to read jsonfile: %v", err)
@@ -113,12 +161,37 @@ func run(opts *options) error {
 		}
 	}()
 
-	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: in})
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:    in,
+		SplitRuns: true,
+		RunGap:    opts.runGap,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to scan testjson: %v", err)
 	}
 
-	tcs := aggregate.Slowest(exec, opts.threshold)
+	if opts.interactive {
+		return tui.Run(os.Stdout, newBrowser(exec))
+	}
+
+	var tcs []testjson.TestCase
+	for _, pkg := range exec.Packages() {
+		tcs = append(tcs, exec.Package(pkg).TestCases()...)
+	}
+	return printOrSkip(opts, aggregate.SlowestTestCases(testCases(opts, tcs), opts.threshold))
+}
+
+// testCases returns the test cases used to find the slow tests, without the
+// first run of each package when --exclude-warmup is set.
+func testCases(opts *options, tcs []testjson.TestCase) []testjson.TestCase {
+	if opts.excludeWarmUp {
+		return aggregate.ExcludeWarmUp(tcs)
+	}
+	return tcs
+}
+
+// printOrSkip prints the slow tests, or adds the --skip-stmt to them.
+func printOrSkip(opts *options, tcs []testjson.TestCase) error {
 	if opts.skipStatement != "" {
 		skipStmt, err := parseSkipStatement(opts.skipStatement)
 		if err != nil {
@@ -136,8 +209,8 @@ func run(opts *options) error {
 func jsonfileReader(v string) (io.ReadCloser, error) {
 	switch v {
 	case "", "-":
-		return ioutil.NopCloser(os.Stdin), nil
+		return compress.NewReader(os.Stdin)
 	default:
-		return os.Open(v)
+		return compress.Open(v)
 	}
 }
@@ -0,0 +1,286 @@
+package trend
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/errwriter"
+)
+
+// metric is one of the values charted in the report.
+type metric struct {
+	name   string
+	value  func(r runStats) (float64, bool)
+	format func(v float64) string
+	// duration is true for the metric of the duration of the run, which is
+	// marked when the run was build dominated.
+	duration bool
+}
+
+var metrics = []metric{
+	{
+		name: "Pass rate",
+		value: func(r runStats) (float64, bool) {
+			return r.passRate(), true
+		},
+		format: formatPercent,
+	},
+	{
+		name: "Duration",
+		value: func(r runStats) (float64, bool) {
+			return float64(r.elapsed), true
+		},
+		format: func(v float64) string {
+			return formatDuration(time.Duration(v))
+		},
+		duration: true,
+	},
+	{
+		name: "Flaky tests",
+		value: func(r runStats) (float64, bool) {
+			return float64(r.flaky), true
+		},
+		format: func(v float64) string {
+			return fmt.Sprintf("%d", int(v))
+		},
+	},
+	{
+		name: "Coverage",
+		value: func(r runStats) (float64, bool) {
+			return r.coverage, r.coverage >= 0
+		},
+		format: formatPercent,
+	},
+}
+
+func formatPercent(v float64) string {
+	return fmt.Sprintf("%.1f%%", v)
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	default:
+		return d.Round(100 * time.Millisecond).String()
+	}
+}
+
+// series is the values of a metric for each run. ok is false for the runs
+// which have no value for the metric.
+type series struct {
+	values []float64
+	ok     []bool
+}
+
+func newSeries(m metric, runs []runStats) series {
+	s := series{values: make([]float64, len(runs)), ok: make([]bool, len(runs))}
+	for i, r := range runs {
+		s.values[i], s.ok[i] = m.value(r)
+		if m.duration && r.excludeDuration {
+			s.ok[i] = false
+		}
+	}
+	return s
+}
+
+// bounds returns the minimum and maximum value in the series, and false if
+// no run has a value.
+func (s series) bounds() (float64, float64, bool) {
+	var min, max float64
+	var found bool
+	for i, v := range s.values {
+		if !s.ok[i] {
+			continue
+		}
+		if !found || v < min {
+			min = v
+		}
+		if !found || v > max {
+			max = v
+		}
+		found = true
+	}
+	return min, max, found
+}
+
+// latest returns the value of the most recent run with a value.
+func (s series) latest() (float64, bool) {
+	for i := len(s.values) - 1; i >= 0; i-- {
+		if s.ok[i] {
+			return s.values[i], true
+		}
+	}
+	return 0, false
+}
+
+// scale returns the position of v between min and max as a fraction from 0 to
+// 1. All values are in the middle when every run has the same value.
+func scale(v, min, max float64) float64 {
+	if max == min {
+		return 0.5
+	}
+	return (v - min) / (max - min)
+}
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline returns a character for each run, with a height proportional to
+// the value of the run. Runs with no value are a space.
+func sparkline(s series) string {
+	min, max, _ := s.bounds()
+	var b strings.Builder
+	for i, v := range s.values {
+		if !s.ok[i] {
+			b.WriteRune(' ')
+			continue
+		}
+		idx := int(scale(v, min, max)*float64(len(sparkBlocks)-1) + 0.5)
+		b.WriteRune(sparkBlocks[idx])
+	}
+	return b.String()
+}
+
+func formatValue(m metric, v float64, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return m.format(v)
+}
+
+// formatRunValue formats the value of the metric for the run, and the percent
+// of the duration spent building when the run was build dominated.
+func formatRunValue(m metric, r runStats) string {
+	v, ok := m.value(r)
+	value := formatValue(m, v, ok)
+	if m.duration && r.buildDominated() {
+		value += fmt.Sprintf(" (%d%% build)", int(r.build*100/r.elapsed))
+	}
+	return value
+}
+
+const timeLayout = "2006-01-02 15:04"
+
+func writeMarkdown(out io.Writer, runs []runStats) error {
+	w := errwriter.New(out)
+	w.Printf("# Test trend\n\n")
+	w.Printf("%d %s, from %s to %s.\n\n", len(runs), pluralize(len(runs), "run", "runs"),
+		runs[0].started.Format(timeLayout), runs[len(runs)-1].started.Format(timeLayout))
+
+	w.Printf("| Metric | Trend | Latest | Min | Max |\n")
+	w.Printf("|--------|-------|--------|-----|-----|\n")
+	for _, m := range metrics {
+		s := newSeries(m, runs)
+		min, max, ok := s.bounds()
+		latest, _ := s.latest()
+		w.Printf("| %s | `%s` | %s | %s | %s |\n", m.name, sparkline(s),
+			formatValue(m, latest, ok), formatValue(m, min, ok), formatValue(m, max, ok))
+	}
+
+	w.Printf("\n## Runs\n\n")
+	w.Printf("| Run | Started | Passed | Failed | Pass rate | Duration | Flaky tests | Coverage |\n")
+	w.Printf("|-----|---------|--------|--------|-----------|----------|-------------|----------|\n")
+	for i := len(runs) - 1; i >= 0; i-- {
+		r := runs[i]
+		w.Printf("| %s | %s | %d | %d |", r.name, r.started.Format(timeLayout), r.passed, r.failed)
+		for _, m := range metrics {
+			w.Printf(" %s |", formatRunValue(m, r))
+		}
+		w.Printf("\n")
+	}
+	return w.Err()
+}
+
+// Dimensions of the charts in the HTML report, in pixels.
+const (
+	chartWidth  = 240
+	chartHeight = 40
+	chartMargin = 3
+)
+
+// writeSVGChart writes a line chart of the series. Runs with no value are
+// skipped.
+func writeSVGChart(w *errwriter.Writer, s series) {
+	min, max, _ := s.bounds()
+	step := float64(chartWidth - 2*chartMargin)
+	if len(s.values) > 1 {
+		step /= float64(len(s.values) - 1)
+	}
+	var points []string
+	var lastX, lastY float64
+	for i, v := range s.values {
+		if !s.ok[i] {
+			continue
+		}
+		x := chartMargin + float64(i)*step
+		y := chartMargin + (1-scale(v, min, max))*(chartHeight-2*chartMargin)
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+		lastX, lastY = x, y
+	}
+	w.Printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`, chartWidth, chartHeight)
+	if len(points) > 0 {
+		w.Printf(`<polyline fill="none" stroke="#0969da" stroke-width="2" points="%s"/>`,
+			strings.Join(points, " "))
+		w.Printf(`<circle cx="%.1f" cy="%.1f" r="3" fill="#0969da"/>`, lastX, lastY)
+	}
+	w.Printf("</svg>")
+}
+
+func writeHTML(out io.Writer, runs []runStats) error {
+	w := errwriter.New(out)
+	w.Printf(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gotestsum trend report</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { padding: 4px 12px; text-align: left; border-bottom: 1px solid #d0d7de; }
+</style>
+</head>
+<body>
+<h1>Test trend</h1>
+`)
+	w.Printf("<p>%d %s, from %s to %s.</p>\n", len(runs), pluralize(len(runs), "run", "runs"),
+		runs[0].started.Format(timeLayout), runs[len(runs)-1].started.Format(timeLayout))
+
+	w.Printf("<table>\n<tr><th>Metric</th><th>Trend</th><th>Latest</th><th>Min</th><th>Max</th></tr>\n")
+	for _, m := range metrics {
+		s := newSeries(m, runs)
+		min, max, ok := s.bounds()
+		latest, _ := s.latest()
+		w.Printf("<tr><td>%s</td><td>", m.name)
+		writeSVGChart(w, s)
+		w.Printf("</td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+			formatValue(m, latest, ok), formatValue(m, min, ok), formatValue(m, max, ok))
+	}
+	w.Printf("</table>\n")
+
+	w.Printf("<h2>Runs</h2>\n<table>\n<tr><th>Run</th><th>Started</th><th>Passed</th><th>Failed</th>")
+	for _, m := range metrics {
+		w.Printf("<th>%s</th>", m.name)
+	}
+	w.Printf("</tr>\n")
+	for i := len(runs) - 1; i >= 0; i-- {
+		r := runs[i]
+		w.Printf("<tr><td>%s</td><td>%s</td><td>%d</td><td>%d</td>",
+			html.EscapeString(r.name), r.started.Format(timeLayout), r.passed, r.failed)
+		for _, m := range metrics {
+			w.Printf("<td>%s</td>", html.EscapeString(formatRunValue(m, r)))
+		}
+		w.Printf("</tr>\n")
+	}
+	w.Printf("</table>\n</body>\n</html>\n")
+	return w.Err()
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
@@ -0,0 +1,335 @@
+package trend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/history"
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	if flags.NArg() > 0 {
+		usage(os.Stderr, name, flags)
+		return fmt.Errorf("too many arguments: %v", strings.Join(flags.Args(), " "))
+	}
+	return run(opts)
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.archiveDir, "archive-dir", "",
+		"directory, or s3:// or http(s):// history store, of archived runs created by 'gotestsum --archive-dir'")
+	flags.IntVar(&opts.runs, "runs", 20,
+		"number of the most recent runs to include in the report")
+	flags.StringVarP(&opts.output, "output", "o", "",
+		"write the report to this file, defaults to stdout")
+	flags.StringVar(&opts.format, "format", "",
+		"format of the report, one of: markdown, html. Defaults to the extension of --output, or markdown")
+	flags.BoolVar(&opts.excludeWarmUp, "exclude-warmup", false,
+		"exclude runs where building the tests took most of the time from the duration trend")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging.")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags]
+
+Read the runs archived by 'gotestsum --archive-dir' and print a report of the
+pass rate, duration, number of flaky tests, and code coverage of each run. A
+chart of each value over the runs shows the trend of the health of the suite.
+
+A flaky test is a test that failed, and then passed when it was re-run with
+--rerun-fails. Coverage is the average coverage of the packages in the run,
+and is only reported for runs with -cover.
+
+The duration of a run includes the time to build the tests. A run where more
+than half of the duration passed before the first test event, for example a run
+with a cold build cache, is marked with the percent of the duration spent
+building. Use --exclude-warmup to exclude these runs from the duration trend,
+so that they do not skew the minimum and maximum duration.
+
+    %[1]s --archive-dir ./test-runs --output trend.html
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+type options struct {
+	archiveDir    string
+	runs          int
+	output        string
+	format        string
+	excludeWarmUp bool
+	debug         bool
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	if opts.archiveDir == "" {
+		return fmt.Errorf("--archive-dir is required")
+	}
+	if opts.runs < 1 {
+		return fmt.Errorf("--runs must be at least 1")
+	}
+	format, err := reportFormat(opts)
+	if err != nil {
+		return err
+	}
+
+	runs, err := readRuns(opts.archiveDir, opts.runs)
+	if err != nil {
+		return err
+	}
+	if opts.excludeWarmUp {
+		for i := range runs {
+			runs[i].excludeDuration = runs[i].buildDominated()
+		}
+	}
+	if len(runs) == 0 {
+		return fmt.Errorf("no archived runs found in %v", opts.archiveDir)
+	}
+
+	out, err := outputWriter(opts.output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	defer func() {
+		if err := out.Close(); err != nil {
+			log.Errorf("Failed to close file %v: %v", opts.output, err)
+		}
+	}()
+
+	switch format {
+	case "html":
+		return writeHTML(out, runs)
+	default:
+		return writeMarkdown(out, runs)
+	}
+}
+
+func reportFormat(opts *options) (string, error) {
+	format := opts.format
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(opts.output), ".")
+	}
+	switch format {
+	case "", "md":
+		return "markdown", nil
+	case "markdown", "html":
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported report format %q, must be one of: markdown, html", format)
+	}
+}
+
+// Names of the files in each run directory of the archive.
+const (
+	summaryFile = "summary.json"
+	eventsFile  = "events.json"
+)
+
+// runStats are the values in the report for one archived run.
+type runStats struct {
+	name    string
+	started time.Time
+	elapsed time.Duration
+	passed  int
+	failed  int
+	flaky   int
+	// coverage is the average coverage of the packages in the run, or -1 when
+	// the run did not report coverage.
+	coverage float64
+	// build is the time from the start of the run to the first test event,
+	// which is mostly the time to build the first test binaries.
+	build time.Duration
+	// excludeDuration is true when the run is excluded from the duration
+	// trend by --exclude-warmup.
+	excludeDuration bool
+}
+
+// buildDominated returns true when more than half of the elapsed time of the
+// run passed before the first test event.
+func (r runStats) buildDominated() bool {
+	return r.elapsed > 0 && r.build*2 > r.elapsed
+}
+
+func (r runStats) passRate() float64 {
+	if r.passed+r.failed == 0 {
+		return 100
+	}
+	return 100 * float64(r.passed) / float64(r.passed+r.failed)
+}
+
+// readRuns reads the most recent max runs in the archive at location, and
+// returns them ordered from the oldest to the most recent run. Runs without a
+// summary are ignored.
+func readRuns(location string, max int) ([]runStats, error) {
+	store, err := history.Open(location)
+	if err != nil {
+		return nil, err
+	}
+	names, err := store.Runs(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	var runs []runStats // nolint: prealloc
+	for _, name := range names {
+		r, err := readRun(store, name)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			log.Debugf("skipping %v: not an archived run", name)
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("failed to read archived run %v: %w", name, err)
+		}
+		r.name = name
+		runs = append(runs, r)
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].started.Before(runs[j].started)
+	})
+	if len(runs) > max {
+		runs = runs[len(runs)-max:]
+	}
+	return runs, nil
+}
+
+// summary is the subset of the fields in the summary.json file of an archived
+// run that are used by the report.
+type summary struct {
+	Started time.Time
+	Elapsed time.Duration
+}
+
+func readRun(store history.Store, name string) (runStats, error) {
+	ctx := context.Background()
+	raw, err := store.ReadFile(ctx, name, summaryFile)
+	if err != nil {
+		return runStats{}, err
+	}
+	var s summary
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return runStats{}, fmt.Errorf("failed to parse %v: %w", summaryFile, err)
+	}
+
+	events, err := store.ReadFile(ctx, name, eventsFile)
+	if err != nil {
+		return runStats{}, err
+	}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:    bytes.NewReader(events),
+		Clock:     testjson.NewEventClock(),
+		SplitRuns: true,
+	})
+	if err != nil {
+		return runStats{}, fmt.Errorf("failed to scan %v: %w", eventsFile, err)
+	}
+
+	r := newRunStats(exec)
+	r.started, r.elapsed = s.Started, s.Elapsed
+	if first := exec.Started(); !first.IsZero() && !s.Started.IsZero() && first.After(s.Started) {
+		r.build = first.Sub(s.Started)
+	}
+	return r, nil
+}
+
+// newRunStats counts the passed, failed, and flaky tests in exec. A test which
+// failed and then passed when it was re-run is counted as passed and flaky.
+func newRunStats(exec *testjson.Execution) runStats {
+	r := runStats{coverage: -1}
+	var coverageTotal float64
+	var coveragePkgs int
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		passed := make(map[testjson.TestName]bool, len(pkg.Passed))
+		for _, tc := range pkg.Passed {
+			passed[tc.Test] = true
+		}
+		r.passed += len(pkg.Passed)
+
+		seen := make(map[testjson.TestName]bool)
+		for _, tc := range testjson.FilterFailedUnique(pkg.Failed) {
+			if seen[tc.Test] {
+				continue
+			}
+			seen[tc.Test] = true
+			if passed[tc.Test] {
+				r.flaky++
+				continue
+			}
+			r.failed++
+		}
+
+		if pct, ok := parseCoverage(pkg.Coverage()); ok {
+			coverageTotal += pct
+			coveragePkgs++
+		}
+	}
+	if coveragePkgs > 0 {
+		r.coverage = coverageTotal / float64(coveragePkgs)
+	}
+	return r
+}
+
+var coveragePattern = regexp.MustCompile(`coverage: (\d+(\.\d+)?)% of statements`)
+
+// parseCoverage returns the percentage from the coverage output of a package.
+func parseCoverage(output string) (float64, bool) {
+	match := coveragePattern.FindStringSubmatch(output)
+	if match == nil {
+		return 0, false
+	}
+	pct, err := strconv.ParseFloat(match[1], 64)
+	return pct, err == nil
+}
+
+func outputWriter(v string) (io.WriteCloser, error) {
+	switch v {
+	case "", "-":
+		return nopWriteCloser{Writer: os.Stdout}, nil
+	default:
+		return os.Create(v)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,156 @@
+package trend
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+func TestUsage_WithFlagsFromSetupFlags(t *testing.T) {
+	defer env.PatchAll(t, nil)()
+
+	name := "gotestsum tool trend"
+	flags, _ := setupFlags(name)
+	buf := new(bytes.Buffer)
+	usage(buf, name, flags)
+
+	golden.Assert(t, buf.String(), "cmd-flags-help-text")
+}
+
+const passingEvents = `{"Time":"2021-01-02T03:04:00Z","Action":"run","Package":"example.com/one","Test":"TestFirst"}
+{"Time":"2021-01-02T03:04:01Z","Action":"pass","Package":"example.com/one","Test":"TestFirst","Elapsed":1}
+{"Time":"2021-01-02T03:04:01Z","Action":"run","Package":"example.com/one","Test":"TestSecond"}
+{"Time":"2021-01-02T03:04:02Z","Action":"pass","Package":"example.com/one","Test":"TestSecond","Elapsed":1}
+{"Time":"2021-01-02T03:04:02Z","Action":"output","Package":"example.com/one","Output":"coverage: 80.0% of statements\n"}
+{"Time":"2021-01-02T03:04:02Z","Action":"pass","Package":"example.com/one","Elapsed":2}
+`
+
+const flakyEvents = `{"Time":"2021-01-03T03:04:00Z","Action":"run","Package":"example.com/one","Test":"TestFirst"}
+{"Time":"2021-01-03T03:04:01Z","Action":"fail","Package":"example.com/one","Test":"TestFirst","Elapsed":1}
+{"Time":"2021-01-03T03:04:01Z","Action":"run","Package":"example.com/one","Test":"TestSecond"}
+{"Time":"2021-01-03T03:04:02Z","Action":"fail","Package":"example.com/one","Test":"TestSecond","Elapsed":1}
+{"Time":"2021-01-03T03:04:02Z","Action":"output","Package":"example.com/one","Output":"coverage: 70.0% of statements\n"}
+{"Time":"2021-01-03T03:04:02Z","Action":"fail","Package":"example.com/one","Elapsed":2}
+{"Time":"2021-01-03T03:04:03Z","Action":"run","Package":"example.com/one","Test":"TestFirst"}
+{"Time":"2021-01-03T03:04:04Z","Action":"pass","Package":"example.com/one","Test":"TestFirst","Elapsed":1}
+{"Time":"2021-01-03T03:04:04Z","Action":"fail","Package":"example.com/one","Elapsed":1}
+`
+
+const noCoverageEvents = `{"Time":"2021-01-04T03:04:00Z","Action":"run","Package":"example.com/one","Test":"TestFirst"}
+{"Time":"2021-01-04T03:04:01Z","Action":"pass","Package":"example.com/one","Test":"TestFirst","Elapsed":1}
+{"Time":"2021-01-04T03:04:01Z","Action":"pass","Package":"example.com/one","Elapsed":1}
+`
+
+func setupArchive(t *testing.T) *fs.Dir {
+	t.Helper()
+	return fs.NewDir(t, "archive",
+		fs.WithDir("2021-01-02T03-04-00",
+			fs.WithFile(summaryFile, `{"Started":"2021-01-02T03:04:00Z","Elapsed":2500000000}`),
+			fs.WithFile(eventsFile, passingEvents)),
+		fs.WithDir("2021-01-04T03-04-00",
+			fs.WithFile(summaryFile, `{"Started":"2021-01-04T03:04:00Z","Elapsed":1200000000}`),
+			fs.WithFile(eventsFile, noCoverageEvents)),
+		fs.WithDir("2021-01-03T03-04-00",
+			fs.WithFile(summaryFile, `{"Started":"2021-01-03T03:04:00Z","Elapsed":4100000000}`),
+			fs.WithFile(eventsFile, flakyEvents)),
+		fs.WithDir("not-a-run"),
+		fs.WithFile("README", "not a run"))
+}
+
+func TestReadRuns(t *testing.T) {
+	dir := setupArchive(t)
+
+	runs, err := readRuns(dir.Path(), 20)
+	assert.NilError(t, err)
+	assert.Equal(t, len(runs), 3)
+
+	var names []string
+	for _, r := range runs {
+		names = append(names, r.name)
+	}
+	expected := []string{"2021-01-02T03-04-00", "2021-01-03T03-04-00", "2021-01-04T03-04-00"}
+	assert.DeepEqual(t, names, expected)
+
+	flaky := runs[1]
+	assert.Equal(t, flaky.passed, 1)
+	assert.Equal(t, flaky.failed, 1)
+	assert.Equal(t, flaky.flaky, 1)
+	assert.Equal(t, flaky.coverage, 70.0)
+	assert.Equal(t, runs[2].coverage, -1.0)
+
+	runs, err = readRuns(dir.Path(), 2)
+	assert.NilError(t, err)
+	assert.Equal(t, runs[0].name, "2021-01-03T03-04-00")
+}
+
+func TestReadRuns_BuildDominated(t *testing.T) {
+	dir := fs.NewDir(t, "archive",
+		fs.WithDir("2021-01-02T03-04-00",
+			fs.WithFile(summaryFile, `{"Started":"2021-01-02T03:03:57Z","Elapsed":5000000000}`),
+			fs.WithFile(eventsFile, passingEvents)),
+		fs.WithDir("2021-01-03T03-04-00",
+			fs.WithFile(summaryFile, `{"Started":"2021-01-03T03:03:59Z","Elapsed":4000000000}`),
+			fs.WithFile(eventsFile, flakyEvents)))
+	defer dir.Remove()
+
+	runs, err := readRuns(dir.Path(), 20)
+	assert.NilError(t, err)
+	assert.Equal(t, len(runs), 2)
+	assert.Equal(t, runs[0].build, 3*time.Second)
+	assert.Assert(t, runs[0].buildDominated())
+	assert.Equal(t, runs[1].build, time.Second)
+	assert.Assert(t, !runs[1].buildDominated())
+
+	runs[0].excludeDuration = true
+	buf := new(bytes.Buffer)
+	assert.NilError(t, writeMarkdown(buf, runs))
+	assert.Assert(t, cmp.Contains(buf.String(), "| 5s (60% build) |"))
+	assert.Assert(t, cmp.Contains(buf.String(), "| Duration | ` ▅` | 4s | 4s | 4s |"))
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	runs, err := readRuns(setupArchive(t).Path(), 20)
+	assert.NilError(t, err)
+
+	buf := new(bytes.Buffer)
+	assert.NilError(t, writeMarkdown(buf, runs))
+	golden.Assert(t, buf.String(), "report.md")
+}
+
+func TestWriteHTML(t *testing.T) {
+	runs, err := readRuns(setupArchive(t).Path(), 20)
+	assert.NilError(t, err)
+
+	buf := new(bytes.Buffer)
+	assert.NilError(t, writeHTML(buf, runs))
+	golden.Assert(t, buf.String(), "report.html")
+}
+
+func TestReportFormat(t *testing.T) {
+	var testCases = []struct {
+		opts     options
+		expected string
+		err      string
+	}{
+		{expected: "markdown"},
+		{opts: options{output: "trend.md"}, expected: "markdown"},
+		{opts: options{output: "trend.html"}, expected: "html"},
+		{opts: options{output: "trend.out", format: "html"}, expected: "html"},
+		{opts: options{output: "trend.pdf"}, err: `unsupported report format "pdf", must be one of: markdown, html`},
+	}
+	for _, tc := range testCases {
+		format, err := reportFormat(&tc.opts)
+		if tc.err != "" {
+			assert.Error(t, err, tc.err)
+			continue
+		}
+		assert.NilError(t, err)
+		assert.Equal(t, format, tc.expected)
+	}
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"os"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/internal/otlp"
+	"gotest.tools/gotestsum/log"
+)
+
+const (
+	// traceparentEnv is the environment variable used to propagate the W3C
+	// trace context to a child process.
+	traceparentEnv = "TRACEPARENT"
+	// traceIDJUnitProperty and spanIDJUnitProperty are the names of the
+	// properties of each JUnit testsuite which are set to the trace context of
+	// the run.
+	traceIDJUnitProperty = "gotestsum.trace.id"
+	spanIDJUnitProperty  = "gotestsum.span.id"
+)
+
+// setupTraceContext starts a new span for the run when --trace-context is
+// enabled. The span is a child of the TRACEPARENT from the environment of
+// gotestsum, or the start of a new trace. The TRACEPARENT of the span is set in
+// the environment so that it is inherited by go test, the tests, and any
+// commands run by gotestsum.
+func setupTraceContext(opts *options) {
+	if !opts.traceContext {
+		return
+	}
+	if opts.traceParent == nil {
+		parent, _ := otlp.ParseTraceparent(os.Getenv(traceparentEnv))
+		opts.traceParent = &parent
+	}
+	opts.traceSpan = opts.traceParent.NewSpan()
+	setTraceparentEnv(opts.traceSpan)
+}
+
+// newRerunSpan starts a new span for a command run by --rerun-fails, so that
+// the traces from each attempt can be found separately. It returns the
+// traceparent of the span, or an empty string when --trace-context is not
+// enabled.
+func newRerunSpan(opts *options) string {
+	if !opts.traceContext {
+		return ""
+	}
+	span := opts.traceSpan.NewSpan()
+	setTraceparentEnv(span)
+	return span.Traceparent()
+}
+
+// endRerunSpans sets the TRACEPARENT back to the span of the run, after the
+// --rerun-fails commands.
+func endRerunSpans(opts *options) {
+	if opts.traceContext {
+		setTraceparentEnv(opts.traceSpan)
+	}
+}
+
+func setTraceparentEnv(span otlp.SpanContext) {
+	if err := os.Setenv(traceparentEnv, span.Traceparent()); err != nil {
+		log.Warnf("Failed to set %v: %v", traceparentEnv, err)
+	}
+}
+
+func traceJUnitProperties(opts *options) []junitxml.JUnitProperty {
+	if !opts.traceContext {
+		return nil
+	}
+	return []junitxml.JUnitProperty{
+		{Name: traceIDJUnitProperty, Value: opts.traceSpan.TraceID},
+		{Name: spanIDJUnitProperty, Value: opts.traceSpan.SpanID},
+	}
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestSetupTraceContext(t *testing.T) {
+	defer env.Patch(t, traceparentEnv, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")()
+
+	opts := &options{traceContext: true}
+	setupTraceContext(opts)
+	assert.Equal(t, opts.traceSpan.TraceID, "4bf92f3577b34da6a3ce929d0e0e4736")
+	assert.Assert(t, opts.traceSpan.SpanID != "00f067aa0ba902b7")
+	assert.Equal(t, os.Getenv(traceparentEnv), opts.traceSpan.Traceparent())
+
+	assert.DeepEqual(t, traceJUnitProperties(opts), []junitxml.JUnitProperty{
+		{Name: traceIDJUnitProperty, Value: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{Name: spanIDJUnitProperty, Value: opts.traceSpan.SpanID},
+	})
+
+	t.Run("a new span for each rerun", func(t *testing.T) {
+		runSpan := opts.traceSpan.Traceparent()
+		rerun := newRerunSpan(opts)
+		assert.Assert(t, rerun != runSpan)
+		assert.Equal(t, rerun[:35], runSpan[:35], "same trace ID")
+		assert.Equal(t, os.Getenv(traceparentEnv), rerun)
+
+		endRerunSpans(opts)
+		assert.Equal(t, os.Getenv(traceparentEnv), runSpan)
+	})
+
+	t.Run("a new span for each run in watch mode", func(t *testing.T) {
+		first := opts.traceSpan
+		setupTraceContext(opts)
+		assert.Equal(t, opts.traceSpan.TraceID, first.TraceID)
+		assert.Assert(t, opts.traceSpan.SpanID != first.SpanID)
+	})
+}
+
+func TestSetupTraceContext_NewTrace(t *testing.T) {
+	defer env.Patch(t, traceparentEnv, "invalid")()
+
+	opts := &options{traceContext: true}
+	setupTraceContext(opts)
+	assert.Equal(t, len(opts.traceSpan.TraceID), 32)
+	assert.Assert(t, opts.traceSpan.Sampled)
+	assert.Equal(t, os.Getenv(traceparentEnv), opts.traceSpan.Traceparent())
+}
+
+func TestSetupTraceContext_NotEnabled(t *testing.T) {
+	defer env.Patch(t, traceparentEnv, "unchanged")()
+
+	opts := &options{}
+	setupTraceContext(opts)
+	assert.Equal(t, os.Getenv(traceparentEnv), "unchanged")
+	assert.Equal(t, newRerunSpan(opts), "")
+	assert.Assert(t, traceJUnitProperties(opts) == nil)
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func validateUntilFailure(opts *options) error {
+	if !opts.untilFailure {
+		switch {
+		case opts.untilFailureShuffle:
+			return fmt.Errorf("--until-failure-shuffle requires --until-failure")
+		case opts.untilFailureMaxRuns > 0:
+			return fmt.Errorf("--until-failure-max-runs requires --until-failure")
+		case opts.untilFailureMaxTime > 0:
+			return fmt.Errorf("--until-failure-max-time requires --until-failure")
+		case opts.stressParallel > 1:
+			return fmt.Errorf("--stress-parallel requires --until-failure")
+		}
+		return nil
+	}
+	var unsupported string
+	switch {
+	case opts.watch:
+		unsupported = "--watch"
+	case maxRerunAttempts(opts) > 0:
+		unsupported = "--rerun-fails"
+	case len(opts.pkgGroups) > 0:
+		unsupported = "--pkg-group"
+	case opts.interactiveSummary:
+		unsupported = "--interactive-summary"
+	case opts.untilFailureShuffle && opts.rawCommand:
+		return fmt.Errorf("--until-failure-shuffle can not be used with --raw-command")
+	default:
+		return nil
+	}
+	return fmt.Errorf("--until-failure can not be used with %v", unsupported)
+}
+
+// untilFailureClock is a shim for testing
+var untilFailureClock = clockwork.NewRealClock()
+
+// newShuffleSeed is a shim for testing
+var newShuffleSeed = func() int64 {
+	return time.Now().UnixNano()
+}
+
+// shuffleArgs returns the -shuffle flag for the seed set by
+// --until-failure-shuffle, unless the go test args already set -shuffle.
+func shuffleArgs(opts *options, args []string) []string {
+	if opts.shuffleSeed == "" {
+		return nil
+	}
+	if start, _ := argIndex("shuffle", args); start >= 0 {
+		return nil
+	}
+	return []string{"-shuffle=" + opts.shuffleSeed}
+}
+
+// runUntilFailure runs the tests repeatedly, until a run fails, or the limit
+// set by --until-failure-max-runs or --until-failure-max-time is reached.
+// Only the summary of the last run is printed.
+func runUntilFailure(opts *options) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if ok, err := setupRun(ctx, opts); err != nil || !ok {
+		return err
+	}
+
+	start := untilFailureClock.Now()
+	for count := 1; ; count++ {
+		instances, err := runInstances(ctx, opts)
+		if err != nil {
+			return err
+		}
+		elapsed := untilFailureClock.Since(start)
+
+		if failed := failedInstances(instances); len(failed) > 0 {
+			inst := failed[0]
+			if err := inst.replay(opts); err != nil {
+				return err
+			}
+			err := finishRun(&inst.opts, inst.exec, inst.err)
+			printUntilFailureReport(opts.stdout, instances, count, elapsed)
+			return err
+		}
+
+		fmt.Fprintf(opts.stdout, "\nrun %d passed%s\n", count, runSuffix(instances))
+		reachedMaxRuns := opts.untilFailureMaxRuns > 0 && count >= opts.untilFailureMaxRuns
+		reachedMaxTime := opts.untilFailureMaxTime > 0 && elapsed >= opts.untilFailureMaxTime
+		if reachedMaxRuns || reachedMaxTime {
+			inst := instances[0]
+			if err := inst.replay(opts); err != nil {
+				return err
+			}
+			err := finishRun(&inst.opts, inst.exec, nil)
+			fmt.Fprintf(opts.stdout, "\nNo failures in %d %s (%v)\n",
+				count, pluralize(count, "run", "runs"), elapsed.Round(time.Millisecond))
+			return err
+		}
+	}
+}
+
+// runOnce runs go test once, and returns the execution and the exit error of
+// the command.
+func runOnce(ctx context.Context, opts *options, handler testjson.EventHandler) (*testjson.Execution, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	goTestProc, err := startGoTestFn(ctx, goTestCmdArgs(opts, rerunOpts{}))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := testjson.ScanConfig{
+		Stdout:                   goTestProc.stdout,
+		Stderr:                   goTestProc.stderr,
+		Handler:                  handler,
+		Stop:                     cancel,
+		IgnoreNonJSONOutputLines: opts.ignoreNonJSONOutputLines,
+		DemoteTeardownFailures:   opts.teardownFailures.demote(),
+		KeepPassedOutput:         opts.formatOptions.ShowOutput.Passed(),
+		RewriteTestName:          newTestNameRewriter(opts),
+		RewriteOutput:            newOutputRewriter(opts),
+		Clock:                    opts.clock.newClock(),
+		InputAdapter:             newInputAdapter(opts),
+		StderrMerge:              opts.stderrMerge.merge(),
+	}
+	exec, err := testjson.ScanTestOutputContext(ctx, cfg)
+	if err != nil {
+		return exec, err
+	}
+	exitErr := goTestProc.cmd.Wait()
+	if signum := atomic.LoadInt32(&goTestProc.signal); signum != 0 {
+		return exec, exitError{num: signalExitCode + int(signum)}
+	}
+	return exec, exitErr
+}
+
+func seedSuffix(opts *options) string {
+	if opts.shuffleSeed == "" {
+		return ""
+	}
+	return " (shuffle seed " + opts.shuffleSeed + ")"
+}
+
+// runSuffix describes the instances of a run, or the shuffle seed of the run
+// when there is only one instance.
+func runSuffix(instances []*runInstance) string {
+	if len(instances) == 1 {
+		return seedSuffix(&instances[0].opts)
+	}
+	return fmt.Sprintf(" (%d instances)", len(instances))
+}
+
+// printUntilFailureReport prints the run that failed, which instances of the
+// run failed, and the commands that run each of the failed tests of the first
+// failed instance again with the same shuffle seed.
+func printUntilFailureReport(out io.Writer, instances []*runInstance, count int, elapsed time.Duration) {
+	fmt.Fprintf(out, "\nFailed on run %d after %v%s\n",
+		count, elapsed.Round(time.Millisecond), runSuffix(instances))
+	failed := failedInstances(instances)
+	if len(instances) > 1 {
+		for _, inst := range failed {
+			fmt.Fprintf(out, "    instance %d of %d%s: %s\n",
+				inst.num, len(instances), seedSuffix(&inst.opts), inst.describeFailure())
+		}
+	}
+
+	opts, exec := &failed[0].opts, failed[0].exec
+	tcs := testjson.FilterFailedUnique(exec.Failed())
+	if len(tcs) == 0 {
+		fmt.Fprintf(out, "To reproduce the failure run:\n    %s\n",
+			formatCommand(goTestCmdArgs(opts, rerunOpts{})))
+		return
+	}
+	fmt.Fprintln(out, "To reproduce the failures run:")
+	for _, tc := range tcs {
+		fmt.Fprintf(out, "    %s\n", formatCommand(goTestCmdArgs(opts, newRerunOptsFromTestCase(tc))))
+	}
+}
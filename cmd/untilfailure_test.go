@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"gotest.tools/v3/assert"
+)
+
+func TestRunUntilFailure(t *testing.T) {
+	jsonPassed := `{"Package": "example.com/pkg", "Test": "TestFlaky", "Action": "run"}
+{"Package": "example.com/pkg", "Test": "TestFlaky", "Action": "pass"}
+{"Package": "example.com/pkg", "Action": "pass"}
+`
+	jsonFailed := `{"Package": "example.com/pkg", "Test": "TestFlaky", "Action": "run"}
+{"Package": "example.com/pkg", "Test": "TestFlaky", "Action": "fail"}
+{"Package": "example.com/pkg", "Action": "fail"}
+`
+	outputs := []string{jsonPassed, jsonPassed, jsonFailed}
+	clock := clockwork.NewFakeClock()
+	origClock := untilFailureClock
+	untilFailureClock = clock
+	defer func() { untilFailureClock = origClock }()
+
+	var started [][]string
+	fn := func(args []string) *proc {
+		started = append(started, args)
+		clock.Advance(time.Second)
+		out := outputs[0]
+		outputs = outputs[1:]
+		var err error
+		if out == jsonFailed {
+			err = newExitCode("failed", 1)
+		}
+		return &proc{
+			cmd:    fakeWaiter{result: err},
+			stdout: strings.NewReader(out),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	defer patchStartGoTestFn(fn)()
+
+	seed := int64(41)
+	origSeed := newShuffleSeed
+	newShuffleSeed = func() int64 {
+		seed++
+		return seed
+	}
+	defer func() { newShuffleSeed = origSeed }()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		format:              "testname",
+		untilFailure:        true,
+		untilFailureShuffle: true,
+		stdout:              out,
+		stderr:              os.Stderr,
+		hideSummary:         newHideSummaryValue(),
+	}
+	err := runUntilFailure(opts)
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+
+	assert.Equal(t, len(started), 3)
+	assert.DeepEqual(t, started[2], []string{"go", "test", "-json", "-shuffle=44", "./..."})
+	assert.Assert(t, strings.Contains(out.String(), "run 2 passed (shuffle seed 43)"), out.String())
+	expected := `Failed on run 3 after 3s (shuffle seed 44)
+To reproduce the failures run:
+    go test -json -shuffle=44 '-test.run=^TestFlaky$' example.com/pkg
+`
+	assert.Assert(t, strings.HasSuffix(out.String(), expected), out.String())
+}
+
+func TestRunUntilFailure_MaxRuns(t *testing.T) {
+	fn := func(args []string) *proc {
+		return &proc{
+			cmd:    fakeWaiter{},
+			stdout: strings.NewReader(`{"Package": "example.com/pkg", "Action": "pass"}` + "\n"),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	defer patchStartGoTestFn(fn)()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		format:              "testname",
+		untilFailure:        true,
+		untilFailureMaxRuns: 3,
+		stdout:              out,
+		stderr:              os.Stderr,
+		hideSummary:         newHideSummaryValue(),
+	}
+	assert.NilError(t, runUntilFailure(opts))
+	assert.Assert(t, strings.Contains(out.String(), "\nNo failures in 3 runs"), out.String())
+}
+
+func TestOptions_Validate_UntilFailure(t *testing.T) {
+	opts := &options{untilFailureShuffle: true}
+	assert.ErrorContains(t, opts.Validate(), "--until-failure-shuffle requires --until-failure")
+
+	opts = &options{untilFailure: true, rerunFailsMaxAttempts: 2}
+	assert.ErrorContains(t, opts.Validate(), "--until-failure can not be used with --rerun-fails")
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gotest.tools/gotestsum/internal/upload"
+	"gotest.tools/gotestsum/log"
+)
+
+type fileUploader interface {
+	UploadFile(ctx context.Context, filename string) (string, error)
+}
+
+// newUploader is a shim for testing.
+var newUploader = func(target upload.Target, metadata map[string]string) (fileUploader, error) {
+	return upload.New(target, metadata)
+}
+
+func validateUpload(opts *options) error {
+	if opts.upload == "" {
+		return nil
+	}
+	_, err := upload.ParseTarget(opts.upload)
+	return err
+}
+
+// uploadFiles uploads the files written by gotestsum to opts.upload.
+func uploadFiles(opts *options) error {
+	if opts.upload == "" {
+		return nil
+	}
+	var files []string
+	for _, path := range outputFilePaths(opts) {
+		if *path == "" {
+			continue
+		}
+		if _, err := os.Stat(*path); err != nil {
+			log.Debugf("not uploading %v: %v", *path, err)
+			continue
+		}
+		files = append(files, *path)
+	}
+	if len(files) == 0 {
+		log.Warnf("--upload is set, but there are no files to upload, " +
+			"use --jsonfile or --junitfile to write files")
+		return nil
+	}
+
+	target, err := upload.ParseTarget(opts.upload)
+	if err != nil {
+		return err
+	}
+	var metadata map[string]string
+	if opts.runID != "" {
+		metadata = map[string]string{uploadRunIDMetadataKey: opts.runID}
+	}
+	uploader, err := newUploader(target, metadata)
+	if err != nil {
+		return fmt.Errorf("failed to create uploader for %v: %w", target, err)
+	}
+	for _, file := range files {
+		uploaded, err := uploader.UploadFile(context.Background(), file)
+		if err != nil {
+			return err
+		}
+		log.Debugf("uploaded %v to %v", file, uploaded)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/internal/upload"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+type fakeUploader struct {
+	target   upload.Target
+	metadata map[string]string
+	files    []string
+	err      error
+}
+
+func (f *fakeUploader) UploadFile(_ context.Context, filename string) (string, error) {
+	f.files = append(f.files, filepath.Base(filename))
+	return "", f.err
+}
+
+func patchNewUploader(t *testing.T, fake *fakeUploader) {
+	orig := newUploader
+	newUploader = func(target upload.Target, metadata map[string]string) (fileUploader, error) {
+		fake.target = target
+		fake.metadata = metadata
+		return fake, nil
+	}
+	t.Cleanup(func() {
+		newUploader = orig
+	})
+}
+
+func TestUploadFiles(t *testing.T) {
+	fake := &fakeUploader{}
+	patchNewUploader(t, fake)
+	dir := fs.NewDir(t, "upload",
+		fs.WithFile("events.json", "{}"),
+		fs.WithFile("junit.xml", "<testsuites/>"))
+
+	opts := &options{
+		jsonFile:             dir.Join("events.json"),
+		junitFile:            dir.Join("junit.xml"),
+		rerunFailsReportFile: dir.Join("missing.txt"),
+		upload:               "gs://bucket/ci/{{.Timestamp}}",
+		runID:                "run-1",
+	}
+	now := time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+	assert.NilError(t, resolveOutputPaths(opts, now))
+	assert.NilError(t, uploadFiles(opts))
+
+	expected := upload.Target{Scheme: "gs", Bucket: "bucket", Prefix: "ci/20210203T040506"}
+	assert.Equal(t, fake.target, expected)
+	assert.DeepEqual(t, fake.files, []string{"events.json", "junit.xml"})
+	assert.DeepEqual(t, fake.metadata, map[string]string{"gotestsum-run-id": "run-1"})
+}
+
+func TestUploadFiles_Error(t *testing.T) {
+	fake := &fakeUploader{err: errors.New("access denied")}
+	patchNewUploader(t, fake)
+	dir := fs.NewDir(t, "upload", fs.WithFile("junit.xml", "<testsuites/>"))
+
+	opts := &options{junitFile: dir.Join("junit.xml"), upload: "s3://bucket"}
+	assert.Error(t, uploadFiles(opts), "access denied")
+}
+
+func TestOptions_Validate_Upload(t *testing.T) {
+	opts := &options{upload: "ftp://bucket/prefix"}
+	assert.ErrorContains(t, opts.Validate(), "must start with s3:// or gs://")
+}
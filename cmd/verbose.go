@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"strings"
+
+	"gotest.tools/gotestsum/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// formatNeedsVerbose returns true if the format prints events for each test.
+// test2json only sends events for each test when the test binary is run with
+// -test.v. All formats except standard-quiet use those events.
+func formatNeedsVerbose(format string) bool {
+	return format != "standard-quiet"
+}
+
+// verbosityArg returns the value of the -v or -test.v flag in args, and true
+// if the flag was found. The value of a flag with no value is "true".
+func verbosityArg(args []string) (string, bool) {
+	for _, arg := range args {
+		if arg == "-args" || arg == "--args" {
+			break
+		}
+		name, value := cutString(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || (name != "v" && name != "test.v") {
+			continue
+		}
+		if !strings.Contains(arg, "=") {
+			value = "true"
+		}
+		return value, true
+	}
+	return "", false
+}
+
+// isTest2JSONCommand returns true if the command runs a test binary with
+// 'go tool test2json'.
+func isTest2JSONCommand(args []string) bool {
+	for _, arg := range args {
+		if arg == "test2json" || strings.HasSuffix(arg, "/test2json") {
+			return true
+		}
+	}
+	return false
+}
+
+// rawCommandVerbosityArgs returns the -test.v flag to add to a raw command
+// which runs a test binary with test2json. test2json requires the test binary
+// to run with -test.v to send events for each test, and -test.v=test2json
+// preserves the framing of subtest output.
+func rawCommandVerbosityArgs(opts *options) []string {
+	if !isTest2JSONCommand(opts.args) || !formatNeedsVerbose(opts.format) {
+		return nil
+	}
+	if _, ok := verbosityArg(opts.args); ok {
+		return nil
+	}
+	return []string{"-test.v=test2json"}
+}
+
+// warnVerbosityConflicts prints a warning when the -v flag in args would
+// prevent the format from receiving the events it requires.
+func warnVerbosityConflicts(opts *options) {
+	if !formatNeedsVerbose(opts.format) {
+		return
+	}
+	value, ok := verbosityArg(opts.args)
+	if !ok || (value != "false" && value != "0") {
+		return
+	}
+	log.Warnf("-v=%s conflicts with --format %s, which requires the events "+
+		"sent when tests run with -v. Remove the flag, or use -v=test2json.",
+		value, opts.format)
+}
+
+// setupFormatVerbosity sets the verbosity level of the format from the
+// --verbose and --quiet flags.
+func setupFormatVerbosity(opts *options) {
+	switch {
+	case opts.quiet:
+		opts.formatOptions.Verbosity = testjson.VerbosityQuiet
+	case opts.verbose > testjson.VerbosityVeryVerbose:
+		opts.formatOptions.Verbosity = testjson.VerbosityVeryVerbose
+	default:
+		opts.formatOptions.Verbosity = opts.verbose
+	}
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestVerbosityArg(t *testing.T) {
+	type testCase struct {
+		args          []string
+		expected      string
+		expectedFound bool
+	}
+	fn := func(t *testing.T, tc testCase) {
+		value, found := verbosityArg(tc.args)
+		assert.Equal(t, value, tc.expected)
+		assert.Equal(t, found, tc.expectedFound)
+	}
+
+	var testCases = map[string]testCase{
+		"no args":           {},
+		"no verbose flag":   {args: []string{"-count=1", "./..."}},
+		"short flag":        {args: []string{"-v"}, expected: "true", expectedFound: true},
+		"test flag":         {args: []string{"-test.v=false"}, expected: "false", expectedFound: true},
+		"test2json":         {args: []string{"--v=test2json"}, expected: "test2json", expectedFound: true},
+		"after args":        {args: []string{"./pkg", "-args", "-v"}},
+		"similar flag name": {args: []string{"-vet=off"}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			fn(t, tc)
+		})
+	}
+}
+
+func TestSetupFormatVerbosity(t *testing.T) {
+	var testCases = map[string]struct {
+		args     []string
+		expected int
+	}{
+		"no flags":   {expected: testjson.VerbosityDefault},
+		"quiet":      {args: []string{"-q"}, expected: testjson.VerbosityQuiet},
+		"verbose":    {args: []string{"-v"}, expected: testjson.VerbosityVerbose},
+		"very":       {args: []string{"-vv"}, expected: testjson.VerbosityVeryVerbose},
+		"long flags": {args: []string{"--verbose", "--verbose"}, expected: testjson.VerbosityVeryVerbose},
+		"too many":   {args: []string{"-vvvv"}, expected: testjson.VerbosityVeryVerbose},
+		"go test -v": {args: []string{"--", "-v"}, expected: testjson.VerbosityDefault},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			flags, opts := setupFlags("gotestsum")
+			assert.NilError(t, flags.Parse(tc.args))
+			setupFormatVerbosity(opts)
+			assert.Equal(t, opts.formatOptions.Verbosity, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/log"
+)
+
+var vetModeValues = "test, separate"
+
+// vetModeValue is the flag.Value for --vet-mode, which sets how go vet is run.
+type vetModeValue string
+
+const (
+	// vetModeTest runs the go vet checks selected by go test, as part of
+	// go test.
+	vetModeTest vetModeValue = "test"
+	// vetModeSeparate runs go vet before go test, and runs go test with
+	// -vet=off.
+	vetModeSeparate vetModeValue = "separate"
+)
+
+func (v *vetModeValue) Set(val string) error {
+	switch vetModeValue(val) {
+	case vetModeTest, vetModeSeparate:
+		*v = vetModeValue(val)
+		return nil
+	}
+	return errors.Errorf("invalid value: %v, must be one of: "+vetModeValues, val)
+}
+
+func (v *vetModeValue) Type() string {
+	return "mode"
+}
+
+func (v *vetModeValue) String() string {
+	if *v == "" {
+		return string(vetModeTest)
+	}
+	return string(*v)
+}
+
+// vetTestName is the name of the test used to report the problems found by
+// go vet in a package, when go vet runs as a separate stage.
+const vetTestName = "GoVet"
+
+// vetArgs returns -vet=off when go vet runs as a separate stage, unless the
+// go test args already set -vet.
+func vetArgs(opts *options, args []string) []string {
+	if opts.vetMode != vetModeSeparate {
+		return nil
+	}
+	if start, _ := argIndex("vet", args); start >= 0 {
+		return nil
+	}
+	return []string{"-vet=off"}
+}
+
+// vetCmdArgs returns the go vet command for --vet-mode=separate. The build
+// flags from the go test args are used so that go vet checks the same files.
+func vetCmdArgs(opts *options) []string {
+	args := []string{"go", "vet", "-json"}
+	args = append(args, goListBuildFlags(opts.args)...)
+	return append(args, cmdArgPackageList(opts, rerunOpts{}, "./...")...)
+}
+
+// runVet runs go vet, and returns the problems found in each package, and the
+// lines of output which are not from a package.
+func runVet(ctx context.Context, opts *options) (analysisResult, []string, error) {
+	result := analysisResult{test: vetTestName}
+	out, err := runAnalysisFn(ctx, vetCmdArgs(opts))
+	if err != nil && !IsExitCoder(err) {
+		return result, nil, fmt.Errorf("failed to run go vet: %w", err)
+	}
+	problems, other := parseVetOutput(out)
+	if err != nil && len(problems) == 0 && len(other) == 0 {
+		other = append(other, "go vet: "+err.Error())
+	}
+	result.problems = problems
+	return result, other, nil
+}
+
+// vetDiagnostic is a problem reported by an analyzer in the output of
+// go vet -json.
+type vetDiagnostic struct {
+	Posn    string `json:"posn"`
+	Message string `json:"message"`
+}
+
+// parseVetOutput returns the problems found in each package, and the lines of
+// output which are not from a package. The output of go vet -json has a JSON
+// object for each package with diagnostics, which maps the package to the
+// diagnostics from each analyzer. Errors, like a package that does not
+// compile, are printed as text after a '# package' line.
+func parseVetOutput(out []byte) ([]analysisProblem, []string) {
+	var problems []analysisProblem
+	var other []string
+	var pkg string
+	var object []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case len(object) > 0 || line == "{":
+			object = append(object, line)
+			if line != "}" {
+				continue
+			}
+			diags, err := parseVetObject(strings.Join(object, "\n"))
+			if err != nil {
+				log.Debugf("failed to parse go vet output: %v", err)
+				other = append(other, object...)
+			}
+			problems = append(problems, diags...)
+			object = nil
+		case strings.HasPrefix(line, "# "):
+			pkg = vetPackageName(strings.TrimPrefix(line, "# "))
+		case strings.TrimSpace(line) == "":
+		case pkg != "":
+			problems = append(problems, analysisProblem{pkg: pkg, line: line})
+		default:
+			other = append(other, line)
+		}
+	}
+	if len(object) > 0 {
+		other = append(other, object...)
+	}
+	return problems, other
+}
+
+func parseVetObject(raw string) ([]analysisProblem, error) {
+	var packages map[string]map[string][]vetDiagnostic
+	if err := json.Unmarshal([]byte(raw), &packages); err != nil {
+		return nil, err
+	}
+	var problems []analysisProblem
+	for _, pkg := range sortedStringKeys(packages) {
+		analyzers := packages[pkg]
+		names := make([]string, 0, len(analyzers))
+		for name := range analyzers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, diag := range analyzers[name] {
+				problems = append(problems, analysisProblem{
+					pkg:  vetPackageName(pkg),
+					line: fmt.Sprintf("%s: %s (%s)", diag.Posn, diag.Message, name),
+				})
+			}
+		}
+	}
+	return problems, nil
+}
+
+func sortedStringKeys(m map[string]map[string][]vetDiagnostic) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// vetPackageName removes the name of the test variant of a package, ex:
+// example.com/pkg [example.com/pkg.test], and the brackets go vet prints
+// around the name of a package that does not compile.
+func vetPackageName(name string) string {
+	if i := strings.Index(name, " ["); i > 0 {
+		name = name[:i]
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(name, "["), "]")
+}
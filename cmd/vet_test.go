@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestParseVetOutput(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NilError(t, err)
+	out := `go: downloading example.com/dep v1.0.0
+# example.com/pkg/one
+{
+	"example.com/pkg/one": {
+		"printf": [
+			{
+				"posn": "` + filepath.Join(cwd, "one.go") + `:12:3",
+				"end": "` + filepath.Join(cwd, "one.go") + `:12:30",
+				"message": "fmt.Sprintf format %d has arg s of wrong type string"
+			}
+		]
+	}
+}
+# example.com/pkg/two
+{
+	"example.com/pkg/two [example.com/pkg/two.test]": {
+		"copylocks": [
+			{
+				"posn": "/other/two_test.go:5:7",
+				"message": "assignment copies lock value"
+			}
+		],
+		"assign": [
+			{
+				"posn": "/other/two_test.go:9:2",
+				"message": "self-assignment of x to x"
+			}
+		]
+	}
+}
+# [example.com/pkg/three]
+vet: ./three.go:3:12: undefined: missing
+`
+	problems, other := parseVetOutput([]byte(out))
+	expected := []analysisProblem{
+		{
+			pkg:  "example.com/pkg/one",
+			line: filepath.Join(cwd, "one.go") + ":12:3: fmt.Sprintf format %d has arg s of wrong type string (printf)",
+		},
+		{pkg: "example.com/pkg/two", line: "/other/two_test.go:9:2: self-assignment of x to x (assign)"},
+		{pkg: "example.com/pkg/two", line: "/other/two_test.go:5:7: assignment copies lock value (copylocks)"},
+		{pkg: "example.com/pkg/three", line: "vet: ./three.go:3:12: undefined: missing"},
+	}
+	assert.DeepEqual(t, problems, expected, cmpAnalysisProblem)
+	assert.DeepEqual(t, other, []string{"go: downloading example.com/dep v1.0.0"})
+}
+
+var cmpAnalysisProblem = gocmp.AllowUnexported(analysisProblem{})
+
+func TestRunVetStage(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NilError(t, err)
+	var args []string
+	defer patchRunAnalysisFn(func(a []string) ([]byte, error) {
+		args = a
+		return []byte(`{
+	"example.com/pkg": {
+		"printf": [{"posn": "` + filepath.Join(cwd, "pkg", "a.go") + `:4:2", "message": "bad format"}]
+	}
+}
+`), nil
+	})()
+
+	opts := &options{
+		vetMode:  vetModeSeparate,
+		packages: []string{"./pkg/..."},
+		args:     []string{"-tags=integration", "-race"},
+	}
+	exec, err := runAnalysisStage(context.Background(), opts, noopHandler{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, args, []string{"go", "vet", "-json", "-tags=integration", "./pkg/..."})
+
+	assert.Equal(t, analysisFailed(opts, exec), 1)
+	tc := exec.Failed()[0]
+	assert.Equal(t, tc.Package, "example.com/pkg")
+	assert.Equal(t, tc.Test.Name(), vetTestName)
+	output := strings.Join(exec.OutputLines(tc), "")
+	assert.Assert(t, is.Contains(output, "    "+filepath.Join("pkg", "a.go")+":4:2: bad format (printf)\n"))
+	assert.Equal(t, len(exec.Errors()), 0)
+	assert.Equal(t, ExitCodeWithDefault(analysisExitErr(opts, exec, nil)), 1)
+}
+
+func TestRun_VetModeSeparate(t *testing.T) {
+	defer patchRunAnalysisFn(func([]string) ([]byte, error) {
+		return []byte(`{
+	"example.com/pkg": {
+		"printf": [{"posn": "a.go:4:2", "message": "bad format"}]
+	}
+}
+`), nil
+	})()
+	jsonPassed := `{"Package": "example.com/pkg", "Action": "run"}
+{"Package": "example.com/pkg", "Test": "TestOne", "Action": "run"}
+{"Package": "example.com/pkg", "Test": "TestOne", "Action": "pass"}
+{"Package": "example.com/pkg", "Action": "pass"}
+`
+	var goTestArgs []string
+	defer patchStartGoTestFn(func(args []string) *proc {
+		goTestArgs = args
+		return &proc{
+			cmd:    fakeWaiter{},
+			stdout: strings.NewReader(jsonPassed),
+			stderr: bytes.NewReader(nil),
+		}
+	})()
+
+	newOpts := func(out *bytes.Buffer) *options {
+		return &options{
+			format:      "testname",
+			vetMode:     vetModeSeparate,
+			stdout:      out,
+			stderr:      out,
+			hideSummary: newHideSummaryValue(),
+		}
+	}
+
+	t.Run("vet fails", func(t *testing.T) {
+		goTestArgs = nil
+		out := new(bytes.Buffer)
+		err := run(newOpts(out))
+		assert.Equal(t, ExitCodeWithDefault(err), 1)
+		assert.Assert(t, goTestArgs == nil, "go test should not run")
+		assert.Assert(t, is.Contains(out.String(), "FAIL example.com/pkg.GoVet"))
+		assert.Assert(t, is.Contains(out.String(), "a.go:4:2: bad format (printf)"))
+	})
+
+	t.Run("vet continue", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		opts := newOpts(out)
+		opts.vetContinue = true
+		err := run(opts)
+		assert.Equal(t, ExitCodeWithDefault(err), 1)
+		assert.DeepEqual(t, goTestArgs, []string{"go", "test", "-json", "-vet=off", "./..."})
+		assert.Assert(t, is.Contains(out.String(), "PASS example.com/pkg.TestOne"))
+		assert.Assert(t, is.Contains(out.String(), "DONE 2 tests, 1 failure"))
+	})
+}
+
+func TestGoTestCmdArgs_VetModeSeparate(t *testing.T) {
+	opts := &options{vetMode: vetModeSeparate}
+	assert.DeepEqual(t, goTestCmdArgs(opts, rerunOpts{}),
+		[]string{"go", "test", "-json", "-vet=off", "./..."})
+
+	opts = &options{
+		vetMode:  vetModeSeparate,
+		packages: []string{"./pkg"},
+		args:     []string{"-vet=atomic"},
+	}
+	assert.DeepEqual(t, goTestCmdArgs(opts, rerunOpts{}),
+		[]string{"go", "test", "-json", "-vet=atomic", "./pkg"})
+}
+
+func patchRunAnalysisFn(f func(args []string) ([]byte, error)) func() {
+	orig := runAnalysisFn
+	runAnalysisFn = func(_ context.Context, args []string) ([]byte, error) {
+		return f(args)
+	}
+	return func() {
+		runAnalysisFn = orig
+	}
+}
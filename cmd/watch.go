@@ -56,7 +56,7 @@ func runSingle(opts *options) (*testjson.Execution, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	if err := opts.Validate(); err != nil {
+	if ok, err := setupRun(ctx, opts); err != nil || !ok {
 		return nil, err
 	}
 
@@ -71,12 +71,18 @@ func runSingle(opts *options) (*testjson.Execution, error) {
 	}
 	defer handler.Close() // nolint: errcheck
 	cfg := testjson.ScanConfig{
-		Stdout:  goTestProc.stdout,
-		Stderr:  goTestProc.stderr,
-		Handler: handler,
-		Stop:    cancel,
+		Stdout:                 goTestProc.stdout,
+		Stderr:                 goTestProc.stderr,
+		Handler:                handler,
+		Stop:                   cancel,
+		DemoteTeardownFailures: opts.teardownFailures.demote(),
+		KeepPassedOutput:       opts.formatOptions.ShowOutput.Passed(),
+		RewriteTestName:        newTestNameRewriter(opts),
+		RewriteOutput:          newOutputRewriter(opts),
+		Clock:                  opts.clock.newClock(),
+		StderrMerge:            opts.stderrMerge.merge(),
 	}
-	exec, err := testjson.ScanTestOutput(cfg)
+	exec, err := testjson.ScanTestOutputContext(ctx, cfg)
 	if err != nil {
 		return exec, finishRun(opts, exec, err)
 	}
@@ -95,7 +101,7 @@ func delveInitFile(exec *testjson.Execution) (string, func(), error) {
 
 	buf := bufio.NewWriter(fh)
 	for _, tc := range exec.Failed() {
-		fmt.Fprintf(buf, "break %s\n", tc.Test.Name())
+		fmt.Fprintf(buf, "break %s\n", tc.OriginalName().Name())
 	}
 	buf.WriteString("continue\n")
 	if err := buf.Flush(); err != nil {
@@ -14,14 +14,26 @@ import (
 // If there are multiple runs of a TestCase, all of them will be represented
 // by a single TestCase with the median elapsed time in the returned slice.
 func Slowest(exec *testjson.Execution, threshold time.Duration) []testjson.TestCase {
+	pkgs := exec.Packages()
+	cases := make([]testjson.TestCase, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		cases = append(cases, exec.Package(pkg).TestCases()...)
+	}
+	return SlowestTestCases(cases, threshold)
+}
+
+// SlowestTestCases is Slowest for test cases from any number of packages.
+func SlowestTestCases(cases []testjson.TestCase, threshold time.Duration) []testjson.TestCase {
 	if threshold == 0 {
 		return nil
 	}
-	pkgs := exec.Packages()
-	tests := make([]testjson.TestCase, 0, len(pkgs))
-	for _, pkg := range pkgs {
-		pkgTests := ByElapsed(exec.Package(pkg).TestCases(), median)
-		tests = append(tests, pkgTests...)
+	byPkg := make(map[string][]testjson.TestCase)
+	for _, tc := range cases {
+		byPkg[tc.Package] = append(byPkg[tc.Package], tc)
+	}
+	tests := make([]testjson.TestCase, 0, len(cases))
+	for _, pkgCases := range byPkg {
+		tests = append(tests, ByElapsed(pkgCases, Median)...)
 	}
 	sort.Slice(tests, func(i, j int) bool {
 		return tests[i].Elapsed > tests[j].Elapsed
@@ -32,6 +44,36 @@ func Slowest(exec *testjson.Execution, threshold time.Duration) []testjson.TestC
 	return tests[:end]
 }
 
+// ExcludeWarmUp removes the test cases from the first run of each package,
+// when the package has more than one run. The first run is often slower than
+// the following runs, because it runs with a cold build cache, and the time to
+// load the test data and warm up other caches is added to the tests. Runs are
+// ordered by the RunID of the test cases.
+func ExcludeWarmUp(cases []testjson.TestCase) []testjson.TestCase {
+	first := make(map[string]int)
+	multiple := make(map[string]bool)
+	for _, tc := range cases {
+		runID, ok := first[tc.Package]
+		switch {
+		case !ok:
+			first[tc.Package] = tc.RunID
+		case tc.RunID != runID:
+			multiple[tc.Package] = true
+			if tc.RunID < runID {
+				first[tc.Package] = tc.RunID
+			}
+		}
+	}
+	result := make([]testjson.TestCase, 0, len(cases))
+	for _, tc := range cases {
+		if multiple[tc.Package] && tc.RunID == first[tc.Package] {
+			continue
+		}
+		result = append(result, tc)
+	}
+	return result
+}
+
 // ByElapsed maps all test cases by name, and if there is more than one
 // instance of a TestCase, uses fn to select the elapsed time for the group.
 //
@@ -57,7 +99,8 @@ func ByElapsed(cases []testjson.TestCase, fn func(times []time.Duration) time.Du
 	return result
 }
 
-func median(times []time.Duration) time.Duration {
+// Median returns the median of times. It sorts times in place.
+func Median(times []time.Duration) time.Duration {
 	switch len(times) {
 	case 0:
 		return 0
@@ -19,7 +19,7 @@ func TestByElapsed_WithMedian(t *testing.T) {
 		{Test: "TestOne", Package: "pkg", Elapsed: 5 * time.Second},
 		{Test: "TestTwo", Package: "pkg", Elapsed: 6 * time.Second},
 	}
-	actual := ByElapsed(cases, median)
+	actual := ByElapsed(cases, Median)
 	expected := []testjson.TestCase{
 		{Test: "TestOne", Package: "pkg", Elapsed: 3 * time.Second},
 		{Test: "TestTwo", Package: "pkg", Elapsed: 4 * time.Second},
@@ -31,6 +31,23 @@ func TestByElapsed_WithMedian(t *testing.T) {
 		cmpopts.IgnoreUnexported(testjson.TestCase{}))
 }
 
+func TestExcludeWarmUp(t *testing.T) {
+	cases := []testjson.TestCase{
+		{Test: "TestOne", Package: "pkg", RunID: 2, Elapsed: time.Second},
+		{Test: "TestOne", Package: "pkg", RunID: 1, Elapsed: 9 * time.Second},
+		{Test: "TestTwo", Package: "pkg", RunID: 1, Elapsed: 8 * time.Second},
+		{Test: "TestTwo", Package: "pkg", RunID: 3, Elapsed: 2 * time.Second},
+		{Test: "TestOne", Package: "other", RunID: 1, Elapsed: 3 * time.Second},
+	}
+	actual := ExcludeWarmUp(cases)
+	expected := []testjson.TestCase{
+		{Test: "TestOne", Package: "pkg", RunID: 2, Elapsed: time.Second},
+		{Test: "TestTwo", Package: "pkg", RunID: 3, Elapsed: 2 * time.Second},
+		{Test: "TestOne", Package: "other", RunID: 1, Elapsed: 3 * time.Second},
+	}
+	assert.DeepEqual(t, actual, expected, cmpopts.IgnoreUnexported(testjson.TestCase{}))
+}
+
 func TestMedian(t *testing.T) {
 	var testcases = []struct {
 		name     string
@@ -56,7 +73,7 @@ func TestMedian(t *testing.T) {
 
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			actual := median(tc.times)
+			actual := Median(tc.times)
 			assert.Equal(t, actual, tc.expected)
 		})
 	}
@@ -0,0 +1,213 @@
+/*Package analytics uploads the results of a test run to a test analytics
+service, like a flaky test detection or CI visibility service.
+
+The results are sent as a single JSON document, so that a service can read
+the results of a run without parsing the output of go test.
+*/
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the version of the format of Results.
+const Version = 1
+
+// Results are the results of a test run.
+type Results struct {
+	Version int       `json:"version"`
+	RunID   string    `json:"runID,omitempty"`
+	Git     Git       `json:"git"`
+	CI      CI        `json:"ci"`
+	Started time.Time `json:"started"`
+	// Elapsed is the elapsed time of the run in seconds.
+	Elapsed float64  `json:"elapsed"`
+	Summary Summary  `json:"summary"`
+	Tests   []Test   `json:"tests"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// Git is the commit that was tested.
+type Git struct {
+	SHA        string `json:"sha,omitempty"`
+	Branch     string `json:"branch,omitempty"`
+	Repository string `json:"repository,omitempty"`
+}
+
+// CI is the CI job that ran the tests. It is empty when the tests did not run
+// in a known CI system.
+type CI struct {
+	Provider string `json:"provider,omitempty"`
+	BuildID  string `json:"buildID,omitempty"`
+	BuildURL string `json:"buildURL,omitempty"`
+	Job      string `json:"job,omitempty"`
+}
+
+// Summary is the number of tests with each result. Tests which were run
+// again are counted once for each run.
+type Summary struct {
+	Total   int `json:"total"`
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+	Errors  int `json:"errors"`
+}
+
+// Test is the result of a single run of a test.
+type Test struct {
+	Package string `json:"package"`
+	Name    string `json:"name"`
+	// Result is one of pass, fail, or skip.
+	Result string `json:"result"`
+	// Elapsed is the elapsed time of the test in seconds.
+	Elapsed float64 `json:"elapsed"`
+	// Attempt is 0 for the first run of the test, and is incremented each
+	// time the test is run again.
+	Attempt int `json:"attempt"`
+	// Output is the output of a failed test.
+	Output string `json:"output,omitempty"`
+}
+
+// Uploader uploads the results of a run to a service.
+type Uploader interface {
+	Upload(ctx context.Context, results Results) error
+}
+
+// New returns an Uploader for the URL. The scheme of the URL selects the
+// Uploader. The headers, like an Authorization header, are sent with every
+// request, and a failed upload is attempted again up to retries times.
+func New(rawURL string, headers map[string]string, retries int) (Uploader, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upload URL %v: %w", rawURL, err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return &HTTPUploader{
+			client:  &http.Client{Timeout: 30 * time.Second},
+			url:     u.String(),
+			headers: headers,
+			retries: retries,
+			backoff: time.Second,
+			sleep:   sleep,
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid upload URL %v, must start with http:// or https://", rawURL)
+}
+
+// HTTPUploader sends the results as JSON in the body of a POST request.
+type HTTPUploader struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+	retries int
+	// backoff is the time to wait before the first retry. It doubles after
+	// each retry.
+	backoff time.Duration
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+// maxRetryAfter is the longest time to wait for a Retry-After header.
+const maxRetryAfter = time.Minute
+
+// Upload sends the results. The request is sent again when it fails with a
+// network error, a 429 response, or a 5xx response.
+func (u *HTTPUploader) Upload(ctx context.Context, results Results) error {
+	body, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	wait := u.backoff
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := u.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if attempt >= u.retries || !isRetryable(err) {
+			return err
+		}
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		if err := u.sleep(ctx, wait); err != nil {
+			return err
+		}
+		wait *= 2
+	}
+}
+
+type responseError struct {
+	status  int
+	message string
+}
+
+func (e responseError) Error() string {
+	return e.message
+}
+
+func isRetryable(err error) bool {
+	if err, ok := err.(responseError); ok {
+		return err.status == http.StatusTooManyRequests || err.status >= 500
+	}
+	return true
+}
+
+// post sends the request, and returns the time from the Retry-After header of
+// a failed response.
+func (u *HTTPUploader) post(ctx context.Context, body []byte) (time.Duration, error) {
+	req, err := http.NewRequest(http.MethodPost, u.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range u.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, nil
+	}
+	msg, _ := ioutil.ReadAll(resp.Body)
+	return parseRetryAfter(resp.Header.Get("Retry-After")), responseError{
+		status:  resp.StatusCode,
+		message: fmt.Sprintf("unexpected response %v: %s", resp.Status, strings.TrimSpace(string(msg))),
+	}
+}
+
+// parseRetryAfter returns the delay from a Retry-After header in seconds. The
+// HTTP date format is not supported.
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	if d := time.Duration(seconds) * time.Second; d < maxRetryAfter {
+		return d
+	}
+	return maxRetryAfter
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,99 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestHTTPUploader_Upload(t *testing.T) {
+	var got Results
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Check(t, r.Method == http.MethodPost)
+		assert.Check(t, r.Header.Get("Content-Type") == "application/json")
+		assert.Check(t, r.Header.Get("Authorization") == "Bearer token")
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Check(t, json.Unmarshal(body, &got))
+	}))
+	defer srv.Close()
+
+	uploader, err := New(srv.URL, map[string]string{"Authorization": "Bearer token"}, 0)
+	assert.NilError(t, err)
+	results := Results{
+		Version: Version,
+		Git:     Git{SHA: "abc123", Branch: "main"},
+		Summary: Summary{Total: 1, Passed: 1},
+		Tests:   []Test{{Package: "example.com/pkg", Name: "TestOne", Result: "pass", Elapsed: 0.5}},
+	}
+	assert.NilError(t, uploader.Upload(context.Background(), results))
+	assert.DeepEqual(t, got, results)
+}
+
+func TestHTTPUploader_Upload_Retries(t *testing.T) {
+	statuses := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[requests]
+		requests++
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "7")
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	uploader, err := New(srv.URL, nil, 3)
+	assert.NilError(t, err)
+	var waits []time.Duration
+	uploader.(*HTTPUploader).sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	assert.NilError(t, uploader.Upload(context.Background(), Results{}))
+	assert.Equal(t, requests, 3)
+	assert.DeepEqual(t, waits, []time.Duration{time.Second, 7 * time.Second})
+}
+
+func TestHTTPUploader_Upload_NotRetryable(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	uploader, err := New(srv.URL, nil, 3)
+	assert.NilError(t, err)
+	err = uploader.Upload(context.Background(), Results{})
+	assert.Error(t, err, "unexpected response 401 Unauthorized: invalid token")
+	assert.Equal(t, requests, 1)
+}
+
+func TestHTTPUploader_Upload_RetriesExhausted(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	uploader, err := New(srv.URL, nil, 2)
+	assert.NilError(t, err)
+	uploader.(*HTTPUploader).sleep = func(context.Context, time.Duration) error {
+		return nil
+	}
+	err = uploader.Upload(context.Background(), Results{})
+	assert.ErrorContains(t, err, "unexpected response 502 Bad Gateway")
+	assert.Equal(t, requests, 3)
+}
+
+func TestNew_InvalidURL(t *testing.T) {
+	_, err := New("ftp://example.com/results", nil, 0)
+	assert.Error(t, err, "invalid upload URL ftp://example.com/results, must start with http:// or https://")
+}
@@ -0,0 +1,107 @@
+/*Package compress reads and writes compressed files of test2json events.
+
+Files are decompressed based on their content, so that compressed input may be
+read from stdin. Files are compressed based on their extension.
+*/
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// NewReader returns a reader of the decompressed content of r when r is
+// compressed with gzip, otherwise it returns a reader of r. The caller must
+// close the returned reader, which does not close r.
+//
+// zstd is detected, but not supported, because it is not in the standard
+// library. NewReader returns an error for zstd content.
+func NewReader(r io.Reader) (io.ReadCloser, error) {
+	buf := bufio.NewReader(r)
+	// Peek returns an error when r is shorter than the magic bytes, which is
+	// not an error here, because the content is shorter than any magic bytes.
+	header, _ := buf.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		gz, err := gzip.NewReader(buf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip header: %w", err)
+		}
+		return gz, nil
+	case bytes.HasPrefix(header, zstdMagic):
+		return nil, fmt.Errorf("zstd compressed input is not supported, decompress it with zstd -d, or use gzip")
+	}
+	return ioutil.NopCloser(buf), nil
+}
+
+// Open opens the file at path for reading, and decompresses it when it is
+// compressed.
+func Open(path string) (io.ReadCloser, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(fh)
+	if err != nil {
+		_ = fh.Close()
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return readCloser{Reader: r, closers: []io.Closer{r, fh}}, nil
+}
+
+// IsCompressedPath returns true if the extension of path is one that is
+// compressed by NewWriter.
+func IsCompressedPath(path string) bool {
+	return strings.HasSuffix(path, ".gz")
+}
+
+// NewWriter returns a writer which compresses the content written to w with
+// gzip, when the extension of path is .gz. Otherwise it returns w. Closing the
+// returned writer flushes the compressed content, and closes w.
+func NewWriter(path string, w io.WriteCloser) io.WriteCloser {
+	if !IsCompressedPath(path) {
+		return w
+	}
+	gz := gzip.NewWriter(w)
+	return writeCloser{Writer: gz, closers: []io.Closer{gz, w}}
+}
+
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r readCloser) Close() error {
+	return closeAll(r.closers)
+}
+
+type writeCloser struct {
+	io.Writer
+	closers []io.Closer
+}
+
+func (w writeCloser) Close() error {
+	return closeAll(w.closers)
+}
+
+// closeAll closes all of closers in order, and returns the first error.
+func closeAll(closers []io.Closer) error {
+	var first error
+	for _, c := range closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
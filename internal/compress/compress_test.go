@@ -0,0 +1,83 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+const events = `{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestOne","Elapsed":0.1}
+`
+
+func TestNewReader(t *testing.T) {
+	t.Run("gzip", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		gz := gzip.NewWriter(buf)
+		_, err := gz.Write([]byte(events))
+		assert.NilError(t, err)
+		assert.NilError(t, gz.Close())
+
+		r, err := NewReader(buf)
+		assert.NilError(t, err)
+		out, err := ioutil.ReadAll(r)
+		assert.NilError(t, err)
+		assert.Equal(t, string(out), events)
+	})
+
+	t.Run("not compressed", func(t *testing.T) {
+		r, err := NewReader(strings.NewReader(events))
+		assert.NilError(t, err)
+		out, err := ioutil.ReadAll(r)
+		assert.NilError(t, err)
+		assert.Equal(t, string(out), events)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		r, err := NewReader(strings.NewReader(""))
+		assert.NilError(t, err)
+		out, err := ioutil.ReadAll(r)
+		assert.NilError(t, err)
+		assert.Equal(t, string(out), "")
+	})
+
+	t.Run("zstd", func(t *testing.T) {
+		_, err := NewReader(bytes.NewReader([]byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}))
+		assert.ErrorContains(t, err, "zstd compressed input is not supported")
+	})
+}
+
+func TestNewWriter_RoundTrip(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+
+	for _, name := range []string{"events.json", "events.json.gz"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir.Path(), name)
+			fh, err := os.Create(path)
+			assert.NilError(t, err)
+			w := NewWriter(path, fh)
+			_, err = w.Write([]byte(events))
+			assert.NilError(t, err)
+			assert.NilError(t, w.Close())
+
+			raw, err := ioutil.ReadFile(path)
+			assert.NilError(t, err)
+			assert.Equal(t, bytes.HasPrefix(raw, gzipMagic), IsCompressedPath(name))
+
+			r, err := Open(path)
+			assert.NilError(t, err)
+			out, err := ioutil.ReadAll(r)
+			assert.NilError(t, err)
+			assert.NilError(t, r.Close())
+			assert.Equal(t, string(out), events)
+		})
+	}
+}
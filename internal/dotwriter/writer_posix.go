@@ -14,3 +14,9 @@ var clear = fmt.Sprintf("%c[%dA%c[2K", ESC, 1, ESC)
 func (w *Writer) clearLines(count int) {
 	_, _ = fmt.Fprint(w.out, strings.Repeat(clear, count))
 }
+
+// EnableVirtualTerminal is a no-op on platforms other than Windows, where
+// terminals always process ANSI escape sequences.
+func EnableVirtualTerminal(fd uintptr) bool {
+	return true
+}
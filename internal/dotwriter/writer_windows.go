@@ -34,9 +34,25 @@ type fdWriter interface {
 	Fd() uintptr
 }
 
+// EnableVirtualTerminal enables processing of ANSI escape sequences, used for
+// color and moving the cursor, by the console attached to fd. Returns false if
+// fd is not a console, or the console does not support virtual terminal
+// processing.
+func EnableVirtualTerminal(fd uintptr) bool {
+	var mode uint32
+	if err := windows.GetConsoleMode(windows.Handle(fd), &mode); err != nil {
+		return false
+	}
+	if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 {
+		return true
+	}
+	mode |= windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING
+	return windows.SetConsoleMode(windows.Handle(fd), mode) == nil
+}
+
 func (w *Writer) clearLines(count int) {
 	f, ok := w.out.(fdWriter)
-	if ok && !isConsole(f.Fd()) {
+	if ok && (!isConsole(f.Fd()) || isVirtualTerminal(f.Fd())) {
 		ok = false
 	}
 	if !ok {
@@ -70,3 +86,12 @@ func isConsole(fd uintptr) bool {
 	err := windows.GetConsoleMode(windows.Handle(fd), &mode)
 	return err == nil
 }
+
+// isVirtualTerminal returns true if the console attached to fd processes ANSI
+// escape sequences, so the lines can be cleared the same way as other
+// terminals.
+func isVirtualTerminal(fd uintptr) bool {
+	var mode uint32
+	err := windows.GetConsoleMode(windows.Handle(fd), &mode)
+	return err == nil && mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0
+}
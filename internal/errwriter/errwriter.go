@@ -0,0 +1,34 @@
+/*Package errwriter provides a Writer for writing many parts of a report
+without checking the error of each write.
+*/
+package errwriter
+
+import (
+	"fmt"
+	"io"
+)
+
+// Writer stores the first error returned by out, and ignores all writes
+// after an error.
+type Writer struct {
+	out io.Writer
+	err error
+}
+
+// New returns a Writer that writes to out.
+func New(out io.Writer) *Writer {
+	return &Writer{out: out}
+}
+
+// Printf writes the formatted text to out, unless a previous write failed.
+func (w *Writer) Printf(format string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.out, format, args...)
+}
+
+// Err returns the first error returned by out, or nil.
+func (w *Writer) Err() error {
+	return w.err
+}
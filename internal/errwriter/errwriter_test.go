@@ -0,0 +1,34 @@
+package errwriter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+type failAfter struct {
+	n   int
+	out strings.Builder
+}
+
+func (f *failAfter) Write(p []byte) (int, error) {
+	if f.n == 0 {
+		return 0, errors.New("write failed")
+	}
+	f.n--
+	return f.out.Write(p)
+}
+
+func TestWriter(t *testing.T) {
+	out := &failAfter{n: 1}
+	w := New(out)
+	w.Printf("one %d\n", 1)
+	assert.NilError(t, w.Err())
+	w.Printf("two %d\n", 2)
+	w.Printf("three %d\n", 3)
+	assert.Error(t, w.Err(), "write failed")
+	assert.Equal(t, out.out.String(), "one 1\n")
+	assert.Equal(t, out.n, 0, "writes after the error are ignored")
+}
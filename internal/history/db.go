@@ -0,0 +1,243 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// DBMaxResults is the number of the most recent results of each test kept in a
+// DB. Older results are removed when a run is added.
+const DBMaxResults = 50
+
+// DBMaxOutputLines is the number of lines at the end of the output of a failed
+// test that are kept in a DB.
+const DBMaxOutputLines = 50
+
+// DB is a database of the results of each test from many runs, stored in a
+// single local file, so that the history of a test is available without
+// keeping the output of every run.
+type DB struct {
+	path string
+	// Packages maps the name of a package to the results of each test in the
+	// package, by test name.
+	Packages map[string]map[string][]Result `json:"packages"`
+	// Impact maps the name of a package to the source files run by each test
+	// in the package, by test name. The paths of the files are relative to
+	// the directory of the run that recorded them, and use forward slashes.
+	Impact map[string]map[string][]string `json:"impact,omitempty"`
+}
+
+// Result is the result of one run of a test.
+type Result struct {
+	// Run is the ID of the run which ran the test.
+	Run string `json:"run"`
+	// Time is when the run started.
+	Time time.Time `json:"time"`
+	// Action is pass, fail, or skip.
+	Action testjson.Action `json:"action"`
+	// Elapsed is the time the test took to run, or -1 when the test never
+	// finished.
+	Elapsed time.Duration `json:"elapsed"`
+	// Attempt is the --rerun-fails attempt which ran the test. The first
+	// attempt is 0.
+	Attempt int `json:"attempt,omitempty"`
+	// Output is the end of the output of a failed test, up to
+	// DBMaxOutputLines lines.
+	Output string `json:"output,omitempty"`
+}
+
+// OpenDB reads the DB from the file at path. When the file does not exist, an
+// empty DB is returned, and the file is created by Save.
+func OpenDB(path string) (*DB, error) {
+	db := &DB{path: path, Packages: make(map[string]map[string][]Result)}
+	raw, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return db, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, db); err != nil {
+		return nil, fmt.Errorf("failed to read history database %v: %w", path, err)
+	}
+	if db.Packages == nil {
+		db.Packages = make(map[string]map[string][]Result)
+	}
+	return db, nil
+}
+
+// Add the result of every test in exec to the DB, as the run with the ID run.
+func (db *DB) Add(run string, exec *testjson.Execution) {
+	add := func(tcs []testjson.TestCase, action testjson.Action) {
+		for _, tc := range tcs {
+			tests, ok := db.Packages[tc.Package]
+			if !ok {
+				tests = make(map[string][]Result)
+				db.Packages[tc.Package] = tests
+			}
+			result := Result{
+				Run:     run,
+				Time:    exec.Started(),
+				Action:  action,
+				Elapsed: tc.Elapsed,
+				Attempt: tc.RunID,
+			}
+			if action == testjson.ActionFail {
+				lines := exec.OutputLines(tc)
+				if len(lines) > DBMaxOutputLines {
+					lines = lines[len(lines)-DBMaxOutputLines:]
+				}
+				result.Output = strings.Join(lines, "")
+			}
+			results := append(tests[tc.Test.Name()], result)
+			if len(results) > DBMaxResults {
+				results = results[len(results)-DBMaxResults:]
+			}
+			tests[tc.Test.Name()] = results
+		}
+	}
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		add(pkg.Passed, testjson.ActionPass)
+		add(pkg.Failed, testjson.ActionFail)
+		add(pkg.Skipped, testjson.ActionSkip)
+	}
+}
+
+// Results returns the results of the test in the package, from the oldest to
+// the most recent.
+func (db *DB) Results(pkg, test string) []Result {
+	return db.Packages[pkg][test]
+}
+
+// SetImpact replaces the source files run by the test in pkg.
+func (db *DB) SetImpact(pkg, test string, files []string) {
+	if db.Impact == nil {
+		db.Impact = make(map[string]map[string][]string)
+	}
+	tests, ok := db.Impact[pkg]
+	if !ok {
+		tests = make(map[string][]string)
+		db.Impact[pkg] = tests
+	}
+	files = append([]string(nil), files...)
+	sort.Strings(files)
+	tests[test] = files
+}
+
+// ImpactedTests returns the names of the tests that run any of the source
+// files, by package. The names of the tests are sorted.
+func (db *DB) ImpactedTests(files []string) map[string][]string {
+	changed := make(map[string]bool, len(files))
+	for _, file := range files {
+		changed[file] = true
+	}
+	result := make(map[string][]string)
+	for pkg, tests := range db.Impact {
+		for name, testFiles := range tests {
+			for _, file := range testFiles {
+				if changed[file] {
+					result[pkg] = append(result[pkg], name)
+					break
+				}
+			}
+		}
+		sort.Strings(result[pkg])
+	}
+	return result
+}
+
+// TestCases returns a TestCase for each result of a test that passed or
+// failed, sorted by package and test name. Skipped tests, and tests which
+// never finished, are not included because their elapsed time is not the time
+// it takes to run the test. The RunID of each TestCase is the position of its
+// run in the DB, ordered by the time the run started, so that the runs can be
+// compared.
+func (db *DB) TestCases() []testjson.TestCase {
+	runIDs := db.runIDs()
+	var tcs []testjson.TestCase
+	for pkg, tests := range db.Packages {
+		for name, results := range tests {
+			for _, result := range results {
+				if result.Action == testjson.ActionSkip || result.Elapsed < 0 {
+					continue
+				}
+				tcs = append(tcs, testjson.TestCase{
+					Package: pkg,
+					Test:    testjson.TestName(name),
+					Elapsed: result.Elapsed,
+					RunID:   runIDs[result.Time.UnixNano()],
+				})
+			}
+		}
+	}
+	sort.SliceStable(tcs, func(i, j int) bool {
+		if tcs[i].Package != tcs[j].Package {
+			return tcs[i].Package < tcs[j].Package
+		}
+		return tcs[i].Test < tcs[j].Test
+	})
+	return tcs
+}
+
+// runIDs returns the position of each run in the DB, ordered by the time the
+// run started, keyed by the start time of the run.
+func (db *DB) runIDs() map[int64]int {
+	var starts []int64
+	seen := make(map[int64]bool)
+	for _, tests := range db.Packages {
+		for _, results := range tests {
+			for _, result := range results {
+				if start := result.Time.UnixNano(); !seen[start] {
+					seen[start] = true
+					starts = append(starts, start)
+				}
+			}
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool {
+		return starts[i] < starts[j]
+	})
+	ids := make(map[int64]int, len(starts))
+	for i, start := range starts {
+		ids[start] = i
+	}
+	return ids
+}
+
+// Save writes the DB to its file. The file is replaced by renaming a new file,
+// so that a DB read at the same time is never partially written.
+func (db *DB) Save() error {
+	raw, err := json.Marshal(db)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(db.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %v: %w", dir, err)
+	}
+	tmp, err := ioutil.TempFile(dir, filepath.Base(db.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // nolint: errcheck
+	if _, err := tmp.Write(raw); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), db.path)
+}
@@ -0,0 +1,99 @@
+package history
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func scanExecution(t *testing.T, events string) *testjson.Execution {
+	t.Helper()
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(events)})
+	assert.NilError(t, err)
+	return exec
+}
+
+const dbTestEvents = `{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestOne","Elapsed":0.5}
+{"Action":"run","Package":"example.com/pkg","Test":"TestTwo"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestTwo","Output":"two_test.go:12: broken\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestTwo","Elapsed":1.5}
+{"Action":"run","Package":"example.com/pkg","Test":"TestThree"}
+{"Action":"skip","Package":"example.com/pkg","Test":"TestThree"}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":2}
+`
+
+func TestDB_AddAndSave(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	path := filepath.Join(dir.Path(), "sub", "history.json")
+
+	db, err := OpenDB(path)
+	assert.NilError(t, err)
+	db.Add("run-1", scanExecution(t, dbTestEvents))
+	assert.NilError(t, db.Save())
+
+	db, err = OpenDB(path)
+	assert.NilError(t, err)
+	db.Add("run-2", scanExecution(t, dbTestEvents))
+	assert.NilError(t, db.Save())
+
+	db, err = OpenDB(path)
+	assert.NilError(t, err)
+	results := db.Results("example.com/pkg", "TestTwo")
+	assert.Equal(t, len(results), 2)
+	assert.Equal(t, results[0].Run, "run-1")
+	assert.Equal(t, results[1].Run, "run-2")
+	assert.Equal(t, results[1].Action, testjson.ActionFail)
+	assert.Equal(t, results[1].Elapsed, 1500*time.Millisecond)
+	assert.Equal(t, results[1].Output, "two_test.go:12: broken\n")
+	assert.Equal(t, db.Results("example.com/pkg", "TestThree")[0].Action, testjson.ActionSkip)
+
+	tcs := db.TestCases()
+	assert.Equal(t, len(tcs), 4)
+	assert.Equal(t, tcs[0].Test, testjson.TestName("TestOne"))
+	assert.Equal(t, tcs[0].RunID, 0)
+	assert.Equal(t, tcs[1].RunID, 1)
+	assert.Equal(t, tcs[2].Test, testjson.TestName("TestTwo"))
+}
+
+func TestDB_AddKeepsMostRecentResults(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	db, err := OpenDB(filepath.Join(dir.Path(), "history.json"))
+	assert.NilError(t, err)
+	exec := scanExecution(t, dbTestEvents)
+	for i := 0; i < DBMaxResults+5; i++ {
+		db.Add("run", exec)
+	}
+	assert.Equal(t, len(db.Results("example.com/pkg", "TestOne")), DBMaxResults)
+}
+
+func TestDB_ImpactedTests(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	path := filepath.Join(dir.Path(), "history.json")
+
+	db, err := OpenDB(path)
+	assert.NilError(t, err)
+	db.SetImpact("example.com/api", "TestList", []string{"api/api.go"})
+	db.SetImpact("example.com/api", "TestCreate", []string{"store/db.go", "api/api.go"})
+	db.SetImpact("example.com/store", "TestGet", []string{"store/db.go"})
+	db.SetImpact("example.com/store", "TestGet", []string{"store/get.go"})
+	assert.NilError(t, db.Save())
+
+	db, err = OpenDB(path)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, db.ImpactedTests([]string{"store/db.go"}), map[string][]string{
+		"example.com/api": {"TestCreate"},
+	})
+	assert.DeepEqual(t, db.ImpactedTests([]string{"api/api.go", "store/get.go"}), map[string][]string{
+		"example.com/api":   {"TestCreate", "TestList"},
+		"example.com/store": {"TestGet"},
+	})
+}
@@ -0,0 +1,108 @@
+/*Package history stores the history of test runs in a local directory, a bucket
+in Amazon S3, or an HTTP API, so that runners on different machines can share
+the same history.
+
+Each run in the history has a name, and a set of files.
+
+A DB is a local file with the results of each test from many runs, which is
+smaller than the files of every run.
+*/
+package history
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store is the storage for the history of test runs.
+type Store interface {
+	// Runs returns the names of all the runs in the history, in no particular
+	// order.
+	Runs(ctx context.Context) ([]string, error)
+	// ReadFile returns the contents of the file name in run. The error wraps
+	// os.ErrNotExist when the file does not exist.
+	ReadFile(ctx context.Context, run, name string) ([]byte, error)
+	// WriteFile writes data to the file name in run, and creates the run if it
+	// does not exist.
+	WriteFile(ctx context.Context, run, name string, data []byte) error
+	// RemoveRun removes run, and all of its files, from the history.
+	RemoveRun(ctx context.Context, run string) error
+}
+
+// IsRemote returns true if location is the URL of a Store which is not a
+// local directory.
+func IsRemote(location string) bool {
+	u, err := url.Parse(location)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "s3", "http", "https":
+		return true
+	default:
+		return false
+	}
+}
+
+// Open returns the Store at location. The location may be the path to a local
+// directory, an s3://bucket/prefix URL, or the http:// or https:// URL of an
+// HTTP API. See NewHTTP for the API.
+func Open(location string) (Store, error) {
+	if !IsRemote(location) {
+		return Dir(location), nil
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid history URL %v, missing bucket name", location)
+		}
+		return NewS3(u.Host, strings.Trim(u.Path, "/"))
+	default:
+		return NewHTTP(location), nil
+	}
+}
+
+// Dir is a Store in a local directory. Each run is a sub-directory.
+type Dir string
+
+// Runs returns the names of the sub-directories of the directory.
+func (d Dir) Runs(_ context.Context) ([]string, error) {
+	entries, err := ioutil.ReadDir(string(d))
+	if err != nil {
+		return nil, err
+	}
+	var runs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			runs = append(runs, entry.Name())
+		}
+	}
+	return runs, nil
+}
+
+// ReadFile reads the file from the directory of run.
+func (d Dir) ReadFile(_ context.Context, run, name string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(string(d), run, name))
+}
+
+// WriteFile writes the file to the directory of run.
+func (d Dir) WriteFile(_ context.Context, run, name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Join(string(d), run), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(string(d), run, name), data, 0644)
+}
+
+// RemoveRun removes the directory of run.
+func (d Dir) RemoveRun(_ context.Context, run string) error {
+	return os.RemoveAll(filepath.Join(string(d), run))
+}
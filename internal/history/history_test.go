@@ -0,0 +1,161 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/upload"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+)
+
+// testStore writes, reads, lists, and removes runs in store.
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+
+	assert.NilError(t, store.WriteFile(ctx, "20210601T120000", "summary.json", []byte(`{"Total":1}`)))
+	assert.NilError(t, store.WriteFile(ctx, "20210601T120000", "events.json", []byte("{}\n")))
+	assert.NilError(t, store.WriteFile(ctx, "20210602T120000", "summary.json", []byte(`{"Total":2}`)))
+
+	runs, err := store.Runs(ctx)
+	assert.NilError(t, err)
+	sort.Strings(runs)
+	assert.DeepEqual(t, runs, []string{"20210601T120000", "20210602T120000"})
+
+	raw, err := store.ReadFile(ctx, "20210602T120000", "summary.json")
+	assert.NilError(t, err)
+	assert.Equal(t, string(raw), `{"Total":2}`)
+
+	_, err = store.ReadFile(ctx, "20210602T120000", "events.json")
+	assert.Assert(t, errors.Is(err, os.ErrNotExist), err)
+
+	assert.NilError(t, store.RemoveRun(ctx, "20210601T120000"))
+	runs, err = store.Runs(ctx)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, runs, []string{"20210602T120000"})
+}
+
+func TestDir(t *testing.T) {
+	dir := fs.NewDir(t, "history")
+	testStore(t, Dir(dir.Path()))
+}
+
+func TestHTTP(t *testing.T) {
+	srv := newFakeAPI(t, "secret-token")
+	defer env.Patch(t, "GOTESTSUM_HISTORY_TOKEN", "secret-token")()
+
+	store, err := Open(srv.URL + "/history/")
+	assert.NilError(t, err)
+	testStore(t, store)
+}
+
+func TestHTTP_Unauthorized(t *testing.T) {
+	srv := newFakeAPI(t, "secret-token")
+	defer env.Patch(t, "GOTESTSUM_HISTORY_TOKEN", "wrong")()
+
+	_, err := NewHTTP(srv.URL + "/history").Runs(context.Background())
+	assert.ErrorContains(t, err, "GET /runs: unexpected response 401 Unauthorized: bad token")
+}
+
+// newFakeAPI returns a server which implements the API used by HTTP, and
+// stores files in memory.
+func newFakeAPI(t *testing.T, token string) *httptest.Server {
+	var mu sync.Mutex
+	files := make(map[string]map[string][]byte)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "bad token", http.StatusUnauthorized)
+			return
+		}
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/history/runs"), "/")
+		switch {
+		case r.Method == http.MethodGet && len(parts) == 1:
+			runs := []string{}
+			for run := range files {
+				runs = append(runs, run)
+			}
+			_ = json.NewEncoder(w).Encode(runs)
+		case r.Method == http.MethodGet && len(parts) == 3:
+			body, ok := files[parts[1]][parts[2]]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(body)
+		case r.Method == http.MethodPut && len(parts) == 3:
+			if files[parts[1]] == nil {
+				files[parts[1]] = make(map[string][]byte)
+			}
+			files[parts[1]][parts[2]], _ = ioutil.ReadAll(r.Body)
+		case r.Method == http.MethodDelete && len(parts) == 2:
+			delete(files, parts[1])
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestS3(t *testing.T) {
+	store := &S3{store: &fakeObjectStore{objects: map[string][]byte{}}, bucket: "results", prefix: "ci/history"}
+	testStore(t, store)
+}
+
+type fakeObjectStore struct {
+	objects map[string][]byte
+}
+
+func (f *fakeObjectStore) Put(_ context.Context, bucket string, obj upload.Object) error {
+	f.objects[bucket+"/"+obj.Key] = obj.Body
+	return nil
+}
+
+func (f *fakeObjectStore) Get(_ context.Context, bucket string, key string) ([]byte, error) {
+	body, ok := f.objects[bucket+"/"+key]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return body, nil
+}
+
+func (f *fakeObjectStore) Delete(_ context.Context, bucket string, key string) error {
+	delete(f.objects, bucket+"/"+key)
+	return nil
+}
+
+func (f *fakeObjectStore) List(_ context.Context, bucket string, prefix string) ([]string, error) {
+	var keys []string
+	for key := range f.objects {
+		if key = strings.TrimPrefix(key, bucket+"/"); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func TestOpen(t *testing.T) {
+	store, err := Open("./test-runs")
+	assert.NilError(t, err)
+	assert.Equal(t, store, Dir("./test-runs"))
+
+	_, err = Open("s3:///prefix")
+	assert.Error(t, err, "invalid history URL s3:///prefix, missing bucket name")
+
+	assert.Assert(t, IsRemote("https://ci.example.com/history"))
+	assert.Assert(t, !IsRemote(`C:\test-runs`))
+}
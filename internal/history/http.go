@@ -0,0 +1,99 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// HTTP is a Store which uses an HTTP API. The API has the following
+// endpoints, relative to the base URL:
+//
+//	GET    /runs              returns a JSON array of the names of the runs
+//	GET    /runs/{run}/{name} returns the contents of a file, or 404
+//	PUT    /runs/{run}/{name} writes the contents of a file
+//	DELETE /runs/{run}        removes the run, and all of its files
+//
+// When the GOTESTSUM_HISTORY_TOKEN environment variable is set, its value is
+// sent as a bearer token in the Authorization header of every request.
+type HTTP struct {
+	client  *http.Client
+	baseURL string
+	token   string
+}
+
+// NewHTTP returns a Store which uses the HTTP API at baseURL.
+func NewHTTP(baseURL string) *HTTP {
+	return &HTTP{
+		client:  http.DefaultClient,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   os.Getenv("GOTESTSUM_HISTORY_TOKEN"),
+	}
+}
+
+// Runs returns the names of the runs from the API.
+func (h *HTTP) Runs(ctx context.Context) ([]string, error) {
+	body, err := h.do(ctx, http.MethodGet, "/runs", nil)
+	if err != nil {
+		return nil, err
+	}
+	var runs []string
+	if err := json.Unmarshal(body, &runs); err != nil {
+		return nil, fmt.Errorf("failed to parse the list of runs: %w", err)
+	}
+	return runs, nil
+}
+
+// ReadFile returns the contents of the file from the API.
+func (h *HTTP) ReadFile(ctx context.Context, run, name string) ([]byte, error) {
+	return h.do(ctx, http.MethodGet, filePath(run, name), nil)
+}
+
+// WriteFile sends the contents of the file to the API.
+func (h *HTTP) WriteFile(ctx context.Context, run, name string, data []byte) error {
+	_, err := h.do(ctx, http.MethodPut, filePath(run, name), data)
+	return err
+}
+
+// RemoveRun removes the run using the API.
+func (h *HTTP) RemoveRun(ctx context.Context, run string) error {
+	_, err := h.do(ctx, http.MethodDelete, "/runs/"+url.PathEscape(run), nil)
+	return err
+}
+
+func filePath(run, name string) string {
+	return "/runs/" + url.PathEscape(run) + "/" + url.PathEscape(name)
+}
+
+func (h *HTTP) do(ctx context.Context, method, path string, data []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, h.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(resp.Body)
+	switch {
+	case err != nil:
+		return nil, err
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%v %v: %w", method, path, os.ErrNotExist)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, fmt.Errorf("%v %v: unexpected response %v: %s",
+			method, path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
@@ -0,0 +1,106 @@
+package history
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/upload"
+)
+
+// objectStore is the subset of upload.S3 used by S3.
+type objectStore interface {
+	Put(ctx context.Context, bucket string, obj upload.Object) error
+	Get(ctx context.Context, bucket string, key string) ([]byte, error)
+	Delete(ctx context.Context, bucket string, key string) error
+	List(ctx context.Context, bucket string, prefix string) ([]string, error)
+}
+
+// S3 is a Store in a bucket in Amazon S3, or any service that supports the S3
+// API. Each file is stored with the key prefix/run/name.
+type S3 struct {
+	store  objectStore
+	bucket string
+	prefix string
+}
+
+// NewS3 returns a Store in bucket, using the credentials from the
+// environment. See upload.NewS3 for the details.
+func NewS3(bucket, prefix string) (*S3, error) {
+	store, err := upload.NewS3(http.DefaultClient)
+	if err != nil {
+		return nil, err
+	}
+	return &S3{store: store, bucket: bucket, prefix: prefix}, nil
+}
+
+func (s *S3) key(parts ...string) string {
+	return path.Join(append([]string{s.prefix}, parts...)...)
+}
+
+// listPrefix returns the prefix of the keys in the directory of run, or of
+// all runs when run is empty.
+func (s *S3) listPrefix(run string) string {
+	if p := s.key(run); p != "" {
+		return p + "/"
+	}
+	return ""
+}
+
+// Runs returns the names of the runs with at least one file in the bucket.
+func (s *S3) Runs(ctx context.Context) ([]string, error) {
+	prefix := s.listPrefix("")
+	keys, err := s.store.List(ctx, s.bucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var runs []string
+	for _, key := range keys {
+		run, _ := cut(strings.TrimPrefix(key, prefix), "/")
+		if run == "" || seen[run] {
+			continue
+		}
+		seen[run] = true
+		runs = append(runs, run)
+	}
+	sort.Strings(runs)
+	return runs, nil
+}
+
+// ReadFile returns the contents of the object for the file.
+func (s *S3) ReadFile(ctx context.Context, run, name string) ([]byte, error) {
+	return s.store.Get(ctx, s.bucket, s.key(run, name))
+}
+
+// WriteFile uploads the file as an object.
+func (s *S3) WriteFile(ctx context.Context, run, name string, data []byte) error {
+	return s.store.Put(ctx, s.bucket, upload.Object{
+		Key:         s.key(run, name),
+		Body:        data,
+		ContentType: "application/octet-stream",
+	})
+}
+
+// RemoveRun removes all the objects in the directory of run.
+func (s *S3) RemoveRun(ctx context.Context, run string) error {
+	keys, err := s.store.List(ctx, s.bucket, s.listPrefix(run))
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, s.bucket, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cut(s, sep string) (string, string) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):]
+	}
+	return s, ""
+}
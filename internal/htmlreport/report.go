@@ -0,0 +1,258 @@
+/*Package htmlreport creates a self-contained HTML report from a
+testjson.Execution.
+*/
+package htmlreport
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/errwriter"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Config used to write an HTML report.
+type Config struct {
+	// KnownIssues adds a link to the known issue of each failed test that
+	// matches a known issue.
+	KnownIssues *testjson.KnownIssues
+	// PassedOutput adds the output of passed tests to the report. The
+	// Execution must keep the output of passed tests, see
+	// testjson.ScanConfig.KeepPassedOutput.
+	PassedOutput bool
+	// This is used for tests to have a consistent timestamp
+	customTimestamp string
+}
+
+// status is the result of a package or test, used as the class of the HTML
+// element, so that the results can be hidden by the filters in the report.
+type status string
+
+const (
+	statusPass status = "pass"
+	statusFail status = "fail"
+	statusSkip status = "skip"
+)
+
+func (s status) label() string {
+	switch s {
+	case statusPass:
+		return "PASS"
+	case statusFail:
+		return "FAIL"
+	}
+	return "SKIP"
+}
+
+// testResult is a test case with its result.
+type testResult struct {
+	testjson.TestCase
+	status status
+}
+
+// Write creates an HTML document and writes it to out.
+func Write(out io.Writer, exec *testjson.Execution, cfg Config) error {
+	w := errwriter.New(out)
+	writeHeader(w, exec, cfg)
+	for _, name := range exec.Packages() {
+		writePackage(w, exec.Package(name), name, cfg)
+	}
+	if entries := exec.ErrorEntries(); len(entries) > 0 {
+		w.Printf("<h2>Errors</h2>\n")
+		for _, entry := range entries {
+			w.Printf("<p>%s</p>\n", html.EscapeString(errorTitle(entry)))
+			w.Printf("<pre class=\"output\">%s</pre>\n", html.EscapeString(entry.String()))
+		}
+	}
+	w.Printf("%s</body>\n</html>\n", filterScript)
+	if err := w.Err(); err != nil {
+		return fmt.Errorf("failed to write HTML report: %v", err)
+	}
+	return nil
+}
+
+// errorTitle returns a description of the kind of error, and the package
+// which caused it.
+func errorTitle(entry testjson.ErrorEntry) string {
+	var title string
+	switch entry.Kind {
+	case testjson.ErrorBuild:
+		title = "Build error"
+	case testjson.ErrorVet:
+		title = "Vet error"
+	case testjson.ErrorParse:
+		title = "Invalid test2json output"
+	case testjson.ErrorExit:
+		title = "Exited unexpectedly"
+	default:
+		title = "Error output"
+	}
+	if entry.Package != "" {
+		title += " in " + entry.Package
+	}
+	return title
+}
+
+const style = `<style>
+body { font-family: sans-serif; margin: 2em; }
+details { margin: 4px 0; }
+summary { cursor: pointer; }
+.package { border: 1px solid #d0d7de; border-radius: 4px; padding: 4px 8px; }
+.test { margin-left: 2em; }
+.label { display: inline-block; width: 4em; font-family: monospace; font-weight: bold; }
+.pass > summary .label, .pass > .label { color: #1a7f37; }
+.fail > summary .label, .fail > .label { color: #cf222e; }
+.skip > summary .label, .skip > .label { color: #9a6700; }
+.elapsed { color: #57606a; }
+.output { background: #f6f8fa; padding: 8px; overflow-x: auto; }
+body.hide-pass .pass, body.hide-fail .fail, body.hide-skip .skip { display: none; }
+</style>
+`
+
+// filterScript hides the packages and tests with a status when the checkbox
+// of the status is not checked.
+const filterScript = `<script>
+document.querySelectorAll("input[data-status]").forEach(function(input) {
+  input.addEventListener("change", function() {
+    document.body.classList.toggle("hide-" + input.dataset.status, !input.checked);
+  });
+});
+</script>
+`
+
+func writeHeader(w *errwriter.Writer, exec *testjson.Execution, cfg Config) {
+	w.Printf("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	w.Printf("<title>gotestsum test report</title>\n%s</head>\n<body>\n", style)
+	w.Printf("<h1>Test report</h1>\n")
+
+	timestamp := cfg.customTimestamp
+	if timestamp == "" {
+		timestamp = exec.Started().Format(time.RFC3339)
+	}
+	failed, skipped := len(exec.Failed()), len(exec.Skipped())
+	w.Printf("<p>%d tests, %d failed, %d skipped, in %s, started at %s.</p>\n",
+		exec.Total(), failed, skipped, formatDuration(exec.Elapsed()), timestamp)
+
+	w.Printf("<p>Show:")
+	for _, filter := range []struct {
+		status status
+		name   string
+		count  int
+	}{
+		{status: statusPass, name: "Passed", count: exec.Total() - failed - skipped},
+		{status: statusFail, name: "Failed", count: failed},
+		{status: statusSkip, name: "Skipped", count: skipped},
+	} {
+		w.Printf(` <label><input type="checkbox" data-status="%s" checked> %s (%d)</label>`,
+			filter.status, filter.name, filter.count)
+	}
+	w.Printf("</p>\n")
+}
+
+func packageStatus(pkg *testjson.Package) status {
+	switch {
+	case pkg.Result() == testjson.ActionFail:
+		return statusFail
+	case pkg.Result() == testjson.ActionSkip, pkg.Total == 0:
+		return statusSkip
+	}
+	return statusPass
+}
+
+// writePackage writes a collapsible section for the package. The sections of
+// failed packages are open, so that the failures are visible without
+// expanding every package.
+func writePackage(w *errwriter.Writer, pkg *testjson.Package, name string, cfg Config) {
+	status := packageStatus(pkg)
+	var open string
+	if status == statusFail {
+		open = " open"
+	}
+	w.Printf("<details class=\"package %s\"%s>\n<summary><span class=\"label\">%s</span> %s",
+		status, open, status.label(), html.EscapeString(name))
+	w.Printf(" <span class=\"elapsed\">(%d tests, %d failed, %s", pkg.Total, len(pkg.Failed),
+		formatDuration(pkg.Elapsed()))
+	if coverage := pkg.Coverage(); coverage != "" {
+		w.Printf(", %s", html.EscapeString(coverage))
+	}
+	w.Printf(")</span></summary>\n")
+
+	// The output of the package is only useful when it failed, for example with
+	// a build error, or a panic in TestMain.
+	if status == statusFail {
+		if output := pkg.Output(0); output != "" {
+			w.Printf("<pre class=\"output\">%s</pre>\n", html.EscapeString(output))
+		}
+	}
+	for _, tc := range testResults(pkg) {
+		writeTest(w, pkg, tc, cfg)
+	}
+	w.Printf("</details>\n")
+}
+
+// testResults returns the test cases of the package in the order they were
+// started.
+func testResults(pkg *testjson.Package) []testResult {
+	var results []testResult
+	add := func(tcs []testjson.TestCase, status status) {
+		for _, tc := range tcs {
+			results = append(results, testResult{TestCase: tc, status: status})
+		}
+	}
+	add(pkg.Passed, statusPass)
+	add(pkg.Failed, statusFail)
+	add(pkg.Skipped, statusSkip)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ID < results[j].ID
+	})
+	return results
+}
+
+// writeTest writes a line for the test. Failed and skipped tests are
+// collapsible, with the output of the test. Passed tests only include the
+// output with Config.PassedOutput.
+func writeTest(w *errwriter.Writer, pkg *testjson.Package, tc testResult, cfg Config) {
+	name := html.EscapeString(tc.Test.Name())
+	if tc.RunID > 0 {
+		name += fmt.Sprintf(" (re-run %d)", tc.RunID)
+	}
+	elapsed := fmt.Sprintf("<span class=\"elapsed\">(%s)</span>", formatDuration(tc.Elapsed))
+	output := strings.Join(pkg.OutputLines(tc.TestCase), "")
+	if tc.status == statusPass {
+		output = ""
+		if cfg.PassedOutput {
+			output = pkg.Output(tc.ID)
+		}
+	}
+	if tc.status == statusPass && output == "" {
+		w.Printf("<div class=\"test %s\"><span class=\"label\">%s</span> %s %s</div>\n",
+			tc.status, tc.status.label(), name, elapsed)
+		return
+	}
+
+	w.Printf("<details class=\"test %s\">\n<summary><span class=\"label\">%s</span> %s %s",
+		tc.status, tc.status.label(), name, elapsed)
+	if tc.status == statusFail {
+		if url, ok := cfg.KnownIssues.Lookup(pkg.Fingerprint(tc.TestCase)); ok {
+			w.Printf(" known issue <a href=\"%s\">%s</a>", html.EscapeString(url), html.EscapeString(url))
+		}
+	}
+	w.Printf("</summary>\n")
+	if output != "" {
+		w.Printf("<pre class=\"output\">%s</pre>\n", html.EscapeString(output))
+	}
+	w.Printf("</details>\n")
+}
+
+// formatDuration formats d as seconds. Tests which never finished have an
+// elapsed time of -1.
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%.2fs", d.Seconds())
+}
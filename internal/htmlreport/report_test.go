@@ -0,0 +1,70 @@
+package htmlreport
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestWrite(t *testing.T) {
+	out := new(bytes.Buffer)
+	exec := createExecution(t)
+
+	err := Write(out, exec, Config{customTimestamp: new(time.Time).Format(time.RFC3339)})
+	assert.NilError(t, err)
+	golden.Assert(t, withoutElapsedRun(out.String()), "htmlreport.golden")
+}
+
+// withoutElapsedRun replaces the elapsed time of the run, which is the time
+// spent reading the test data.
+func withoutElapsedRun(report string) string {
+	return regexp.MustCompile(`skipped, in [0-9.]+s,`).ReplaceAllString(report, "skipped, in 0.00s,")
+}
+
+func createExecution(t *testing.T) *testjson.Execution {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: readTestData(t, "out"),
+		Stderr: readTestData(t, "err"),
+	})
+	assert.NilError(t, err)
+	return exec
+}
+
+func readTestData(t *testing.T, stream string) io.Reader {
+	raw, err := ioutil.ReadFile("../../testjson/testdata/go-test-json." + stream)
+	assert.NilError(t, err)
+	return bytes.NewReader(raw)
+}
+
+func TestWrite_KnownIssueAndEscaping(t *testing.T) {
+	input := `{"Action":"run","Package":"example.com/pkg","Test":"TestKnown"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestKnown","Output":"    known_test.go:9: got <nil>, want \"a & b\"\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestKnown","Elapsed":0.25}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.5}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+	fingerprint := exec.Fingerprint(exec.Package("example.com/pkg").Failed[0])
+
+	out := new(bytes.Buffer)
+	err = Write(out, exec, Config{
+		KnownIssues: testjson.NewKnownIssues(map[string]string{fingerprint: "https://example.com/issues/1?a=b&c=d"}),
+	})
+	assert.NilError(t, err)
+
+	expected := `<details class="test fail">
+<summary><span class="label">FAIL</span> TestKnown <span class="elapsed">(0.25s)</span> known issue <a href="https://example.com/issues/1?a=b&amp;c=d">https://example.com/issues/1?a=b&amp;c=d</a></summary>
+<pre class="output">    known_test.go:9: got &lt;nil&gt;, want &#34;a &amp; b&#34;
+</pre>
+</details>
+`
+	assert.Assert(t, strings.Contains(out.String(), expected), out.String())
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 
@@ -40,8 +41,19 @@ type JUnitTestCase struct {
 	Classname   string            `xml:"classname,attr"`
 	Name        string            `xml:"name,attr"`
 	Time        string            `xml:"time,attr"`
+	Properties  *JUnitProperties  `xml:"properties,omitempty"`
 	SkipMessage *JUnitSkipMessage `xml:"skipped,omitempty"`
 	Failure     *JUnitFailure     `xml:"failure,omitempty"`
+	// FlakyFailures are the failures of the test before it passed when it
+	// was rerun. They are only set when Config.FlakyFailures is true.
+	FlakyFailures []JUnitFlakyFailure
+	SystemOut     string `xml:"system-out,omitempty"`
+	// SystemErr is the snapshot of the environment taken when the test
+	// failed. See testjson.Package.SetSnapshot.
+	SystemErr string `xml:"system-err,omitempty"`
+	// File is the path of the first file in the output of a failed test. It
+	// is only set when Config.FileAttribute is true.
+	File string `xml:"file,attr,omitempty"`
 }
 
 // JUnitSkipMessage contains the reason why a testcase was skipped.
@@ -55,6 +67,12 @@ type JUnitProperty struct {
 	Value string `xml:"value,attr"`
 }
 
+// JUnitProperties is a list of properties. It is a pointer in JUnitTestCase so
+// that the element is omitted when there are no properties.
+type JUnitProperties struct {
+	Properties []JUnitProperty `xml:"property"`
+}
+
 // JUnitFailure contains data related to a failed test.
 type JUnitFailure struct {
 	Message  string `xml:"message,attr"`
@@ -62,10 +80,50 @@ type JUnitFailure struct {
 	Contents string `xml:",chardata"`
 }
 
+// JUnitFlakyFailure is a failure of a test that passed when it was rerun. It
+// uses the flakyFailure element of the Maven Surefire report format, which is
+// recognized by tools that track flaky tests.
+type JUnitFlakyFailure struct {
+	XMLName    xml.Name `xml:"flakyFailure"`
+	Message    string   `xml:"message,attr"`
+	Type       string   `xml:"type,attr"`
+	StackTrace string   `xml:"stackTrace"`
+}
+
 // Config used to write a junit XML document.
 type Config struct {
 	FormatTestSuiteName     FormatFunc
 	FormatTestCaseClassname FormatFunc
+	// Properties are added to the properties of every testsuite, after the
+	// properties added by default.
+	Properties []JUnitProperty
+	// Labels limits the testcases to the tests with at least one of the
+	// labels. See testjson.LabelMarker.
+	Labels []string
+	// KnownIssues adds the fingerprint of each failure, and the URL of the
+	// known issue that matches the fingerprint, to the properties of the
+	// failed testcases.
+	KnownIssues *testjson.KnownIssues
+	// Quarantine reports the failures of quarantined tests as skipped
+	// testcases, with the output of the failure in system-out, and a
+	// quarantined property.
+	Quarantine *testjson.Quarantine
+	// FileAttribute sets the file attribute of failed testcases to the first
+	// file referenced in the output of the test. See testjson.FirstFile.
+	FileAttribute bool
+	// PassedOutput adds the output of passed tests to the system-out of the
+	// testcases. The Execution must keep the output of passed tests, see
+	// testjson.ScanConfig.KeepPassedOutput.
+	PassedOutput bool
+	// FlakyFailures reports the failures of a test that passed when it was
+	// rerun as flakyFailure elements of the passed testcase, instead of as
+	// failed testcases.
+	FlakyFailures bool
+	// TestSuitePerTest writes a testsuite for each top-level test, with the
+	// testcases of the test and all of its subtests, instead of a testsuite
+	// for each package. The testsuite is named by the package and the test,
+	// ex: example.com/pkg.TestTable.
+	TestSuitePerTest bool
 	// This is used for tests to have a consistent timestamp
 	customTimestamp string
 }
@@ -81,10 +139,39 @@ func Write(out io.Writer, exec *testjson.Execution, cfg Config) error {
 	return nil
 }
 
+// LabeledExecution is an Execution with a label. The label is used as a prefix
+// for the name of every testsuite created from the Execution.
+type LabeledExecution struct {
+	Label     string
+	Execution *testjson.Execution
+}
+
+// WriteLabeled creates an XML document with the testsuites from all of execs,
+// and writes it to out.
+func WriteLabeled(out io.Writer, execs []LabeledExecution, cfg Config) error {
+	suites := JUnitTestSuites{}
+	for _, labeled := range execs {
+		labelCfg := configWithDefaults(cfg)
+		formatName, label := labelCfg.FormatTestSuiteName, labeled.Label
+		labelCfg.FormatTestSuiteName = func(name string) string {
+			return label + "/" + formatName(name)
+		}
+		suites.Suites = append(suites.Suites, generate(labeled.Execution, labelCfg).Suites...)
+	}
+	if err := write(out, suites); err != nil {
+		return fmt.Errorf("failed to write JUnit XML: %v", err)
+	}
+	return nil
+}
+
 func generate(exec *testjson.Execution, cfg Config) JUnitTestSuites {
 	cfg = configWithDefaults(cfg)
 	version := goVersion()
 	suites := JUnitTestSuites{}
+	teardownFailed := make(map[string]bool)
+	for _, tc := range exec.TeardownFailed() {
+		teardownFailed[tc.Package] = true
+	}
 
 	for _, pkgname := range exec.Packages() {
 		pkg := exec.Package(pkgname)
@@ -92,19 +179,69 @@ func generate(exec *testjson.Execution, cfg Config) JUnitTestSuites {
 			Name:       cfg.FormatTestSuiteName(pkgname),
 			Tests:      pkg.Total,
 			Time:       formatDurationAsSeconds(pkg.Elapsed()),
-			Properties: packageProperties(version),
-			TestCases:  packageTestCases(pkg, cfg.FormatTestCaseClassname),
+			Properties: append(packageProperties(version), cfg.Properties...),
+			TestCases:  packageTestCases(pkg, teardownFailed[pkgname], cfg),
 			Failures:   len(pkg.Failed),
 			Timestamp:  cfg.customTimestamp,
 		}
+		if len(cfg.Labels) > 0 || cfg.FlakyFailures || cfg.Quarantine != nil {
+			junitpkg.Tests, junitpkg.Failures = countTestCases(junitpkg.TestCases)
+		}
 		if cfg.customTimestamp == "" {
 			junitpkg.Timestamp = exec.Started().Format(time.RFC3339)
 		}
+		if cfg.TestSuitePerTest {
+			suites.Suites = append(suites.Suites, testSuitePerTest(junitpkg)...)
+			continue
+		}
 		suites.Suites = append(suites.Suites, junitpkg)
 	}
 	return suites
 }
 
+// testSuitePerTest splits the testsuite of a package into a testsuite for each
+// top-level test, in the order of the first testcase of each test. The time of
+// each testsuite is the time of the top-level test, or the sum of the time of
+// its testcases when the top-level test did not finish.
+func testSuitePerTest(pkg JUnitTestSuite) []JUnitTestSuite {
+	var roots []string
+	byRoot := make(map[string][]JUnitTestCase)
+	for _, jtc := range pkg.TestCases {
+		root, _ := testjson.TestName(jtc.Name).Split()
+		if _, ok := byRoot[root]; !ok {
+			roots = append(roots, root)
+		}
+		byRoot[root] = append(byRoot[root], jtc)
+	}
+
+	suites := make([]JUnitTestSuite, 0, len(roots))
+	for _, root := range roots {
+		cases := byRoot[root]
+		suite := JUnitTestSuite{
+			Name:       pkg.Name + "." + root,
+			Time:       rootTestTime(root, cases),
+			Properties: pkg.Properties,
+			TestCases:  cases,
+			Timestamp:  pkg.Timestamp,
+		}
+		suite.Tests, suite.Failures = countTestCases(cases)
+		suites = append(suites, suite)
+	}
+	return suites
+}
+
+func rootTestTime(root string, cases []JUnitTestCase) string {
+	var total float64
+	for _, jtc := range cases {
+		if jtc.Name == root {
+			return jtc.Time
+		}
+		seconds, _ := strconv.ParseFloat(jtc.Time, 64)
+		total += seconds
+	}
+	return fmt.Sprintf("%f", total)
+}
+
 func configWithDefaults(cfg Config) Config {
 	noop := func(v string) string {
 		return v
@@ -148,10 +285,30 @@ func goVersion() string {
 	return strings.TrimPrefix(strings.TrimSpace(string(out)), "go version ")
 }
 
-func packageTestCases(pkg *testjson.Package, formatClassname FormatFunc) []JUnitTestCase {
+// countTestCases returns the number of testcases, and the number of failed
+// testcases.
+func countTestCases(cases []JUnitTestCase) (int, int) {
+	var failures int
+	for _, jtc := range cases {
+		if jtc.Failure != nil {
+			failures++
+		}
+	}
+	return len(cases), failures
+}
+
+func packageTestCases(pkg *testjson.Package, teardownFailed bool, cfg Config) []JUnitTestCase {
 	cases := []JUnitTestCase{}
+	formatClassname := cfg.FormatTestCaseClassname
 
-	if pkg.TestMainFailed() {
+	switch {
+	case teardownFailed:
+		// Report the package output without a failure, because all the tests
+		// passed and the package was demoted to passed with a teardown error.
+		jtc := newJUnitTestCase(testjson.TestCase{Test: "TestMain"}, formatClassname)
+		jtc.SystemOut = pkg.Output(0)
+		cases = append(cases, jtc)
+	case pkg.TestMainFailed():
 		jtc := newJUnitTestCase(testjson.TestCase{Test: "TestMain"}, formatClassname)
 		jtc.Failure = &JUnitFailure{
 			Message:  "Failed",
@@ -160,16 +317,32 @@ func packageTestCases(pkg *testjson.Package, formatClassname FormatFunc) []JUnit
 		cases = append(cases, jtc)
 	}
 
-	for _, tc := range pkg.Failed {
+	flaky := flakyFailures(pkg, cfg)
+	for _, tc := range testjson.FilterByLabel(pkg.Failed, cfg.Labels) {
+		if _, ok := flaky[tc.Test]; ok {
+			continue
+		}
 		jtc := newJUnitTestCase(tc, formatClassname)
+		if rule, ok := cfg.Quarantine.Lookup(tc); ok {
+			setQuarantined(&jtc, rule, strings.Join(pkg.OutputLines(tc), ""))
+			cases = append(cases, jtc)
+			continue
+		}
 		jtc.Failure = &JUnitFailure{
 			Message:  "Failed",
 			Contents: strings.Join(pkg.OutputLines(tc), ""),
 		}
+		jtc.SystemErr = pkg.Snapshot(tc)
+		if cfg.FileAttribute {
+			jtc.File = testjson.FirstFile(pkg.OutputLines(tc))
+		}
+		if cfg.KnownIssues != nil {
+			addKnownIssueProperties(&jtc, cfg.KnownIssues, pkg.Fingerprint(tc))
+		}
 		cases = append(cases, jtc)
 	}
 
-	for _, tc := range pkg.Skipped {
+	for _, tc := range testjson.FilterByLabel(pkg.Skipped, cfg.Labels) {
 		jtc := newJUnitTestCase(tc, formatClassname)
 		jtc.SkipMessage = &JUnitSkipMessage{
 			Message: strings.Join(pkg.OutputLines(tc), ""),
@@ -177,19 +350,126 @@ func packageTestCases(pkg *testjson.Package, formatClassname FormatFunc) []JUnit
 		cases = append(cases, jtc)
 	}
 
-	for _, tc := range pkg.Passed {
+	for _, tc := range testjson.FilterByLabel(pkg.Passed, cfg.Labels) {
 		jtc := newJUnitTestCase(tc, formatClassname)
+		if cfg.PassedOutput {
+			jtc.SystemOut = pkg.Output(tc.ID)
+		}
+		for _, failed := range flaky[tc.Test] {
+			jtc.FlakyFailures = append(jtc.FlakyFailures, JUnitFlakyFailure{
+				Message:    "Failed",
+				StackTrace: strings.Join(pkg.OutputLines(failed), ""),
+			})
+		}
+		delete(flaky, tc.Test)
 		cases = append(cases, jtc)
 	}
 	return cases
 }
 
+// flakyFailures returns the failures of each test that passed in a later
+// attempt, by the name of the test. Returns nil when Config.FlakyFailures is
+// false.
+func flakyFailures(pkg *testjson.Package, cfg Config) map[testjson.TestName][]testjson.TestCase {
+	if !cfg.FlakyFailures {
+		return nil
+	}
+	passed := make(map[testjson.TestName]int)
+	for _, tc := range pkg.Passed {
+		if runID, ok := passed[tc.Test]; !ok || tc.RunID > runID {
+			passed[tc.Test] = tc.RunID
+		}
+	}
+	flaky := make(map[testjson.TestName][]testjson.TestCase)
+	for _, tc := range pkg.Failed {
+		if runID, ok := passed[tc.Test]; ok && tc.RunID < runID {
+			flaky[tc.Test] = append(flaky[tc.Test], tc)
+		}
+	}
+	return flaky
+}
+
 func newJUnitTestCase(tc testjson.TestCase, formatClassname FormatFunc) JUnitTestCase {
-	return JUnitTestCase{
+	jtc := JUnitTestCase{
 		Classname: formatClassname(tc.Package),
 		Name:      tc.Test.Name(),
 		Time:      formatDurationAsSeconds(tc.Elapsed),
 	}
+	if len(tc.Labels) > 0 || len(tc.Leaks) > 0 || tc.Benchmark != nil || tc.Fuzz != nil {
+		jtc.Properties = &JUnitProperties{}
+	}
+	for _, label := range tc.Labels {
+		jtc.Properties.Properties = append(jtc.Properties.Properties,
+			JUnitProperty{Name: "label", Value: label})
+	}
+	for _, leak := range tc.Leaks {
+		jtc.Properties.Properties = append(jtc.Properties.Properties,
+			JUnitProperty{Name: "leak", Value: leak.String()})
+	}
+	if tc.Benchmark != nil {
+		jtc.Properties.Properties = append(jtc.Properties.Properties,
+			benchmarkProperties(*tc.Benchmark)...)
+	}
+	if tc.Fuzz != nil {
+		jtc.Properties.Properties = append(jtc.Properties.Properties,
+			fuzzProperties(*tc.Fuzz)...)
+	}
+	return jtc
+}
+
+// benchmarkProperties returns a property for the number of iterations of a
+// benchmark, and one for each of its metrics, named by the unit of the metric.
+func benchmarkProperties(result testjson.BenchmarkResult) []JUnitProperty {
+	props := []JUnitProperty{
+		{Name: "benchmark.iterations", Value: strconv.Itoa(result.Iterations)},
+	}
+	for _, metric := range result.Metrics {
+		props = append(props, JUnitProperty{
+			Name:  "benchmark." + metric.Unit,
+			Value: strconv.FormatFloat(metric.Value, 'f', -1, 64),
+		})
+	}
+	return props
+}
+
+// fuzzProperties returns the properties for the progress of a fuzz test.
+func fuzzProperties(result testjson.FuzzResult) []JUnitProperty {
+	props := []JUnitProperty{
+		{Name: "fuzz.execs", Value: strconv.FormatInt(result.Execs, 10)},
+		{Name: "fuzz.new-interesting", Value: strconv.Itoa(result.NewInteresting)},
+		{Name: "fuzz.corpus", Value: strconv.Itoa(result.Corpus)},
+	}
+	if result.FailingInput != "" {
+		props = append(props, JUnitProperty{Name: "fuzz.failing-input", Value: result.FailingInput})
+	}
+	return props
+}
+
+// setQuarantined reports the failure of a quarantined test as a skipped
+// testcase, so that it does not fail the report.
+func setQuarantined(jtc *JUnitTestCase, rule testjson.QuarantineRule, output string) {
+	msg := "Quarantined"
+	if rule.Reason != "" {
+		msg += ": " + rule.Reason
+	}
+	jtc.SkipMessage = &JUnitSkipMessage{Message: msg}
+	jtc.SystemOut = output
+	if jtc.Properties == nil {
+		jtc.Properties = &JUnitProperties{}
+	}
+	jtc.Properties.Properties = append(jtc.Properties.Properties,
+		JUnitProperty{Name: "quarantined", Value: "true"})
+}
+
+func addKnownIssueProperties(jtc *JUnitTestCase, issues *testjson.KnownIssues, fingerprint string) {
+	if jtc.Properties == nil {
+		jtc.Properties = &JUnitProperties{}
+	}
+	props := []JUnitProperty{{Name: "fingerprint", Value: fingerprint}}
+	if url, ok := issues.Lookup(fingerprint); ok {
+		props = append(props, JUnitProperty{Name: "known-issue", Value: url})
+	}
+	jtc.Properties.Properties = append(jtc.Properties.Properties, props...)
 }
 
 func write(out io.Writer, suites JUnitTestSuites) error {
@@ -6,11 +6,13 @@ import (
 	"io"
 	"io/ioutil"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
 	"gotest.tools/gotestsum/testjson"
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
 	"gotest.tools/v3/env"
 	"gotest.tools/v3/golden"
 )
@@ -40,6 +42,234 @@ func readTestData(t *testing.T, stream string) io.Reader {
 	return bytes.NewReader(raw)
 }
 
+func TestWrite_WithProperties(t *testing.T) {
+	out := new(bytes.Buffer)
+	exec := createExecution(t)
+
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+	err := Write(out, exec, Config{
+		Properties:      []JUnitProperty{{Name: "gotestsum.run.id", Value: "run-1"}},
+		customTimestamp: new(time.Time).Format(time.RFC3339),
+	})
+	assert.NilError(t, err)
+
+	expected := `<properties>
+			<property name="go.version" value="go7.7.7"></property>
+			<property name="gotestsum.run.id" value="run-1"></property>
+		</properties>`
+	assert.Equal(t, strings.Count(out.String(), expected), len(exec.Packages()))
+}
+
+func TestWrite_WithLabels(t *testing.T) {
+	in := `{"Action":"run","Package":"example.com/pkg","Test":"TestSlow"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestSlow","Output":"gotestsum-label: slow,db\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestSlow","Elapsed":1}
+{"Action":"run","Package":"example.com/pkg","Test":"TestFast"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestFast","Elapsed":0}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":1}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(in)})
+	assert.NilError(t, err)
+
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+	out := new(bytes.Buffer)
+	err = Write(out, exec, Config{
+		Labels:          []string{"db"},
+		customTimestamp: new(time.Time).Format(time.RFC3339),
+	})
+	assert.NilError(t, err)
+
+	assert.Assert(t, cmp.Contains(out.String(), `<testsuite tests="1" failures="1"`))
+	assert.Assert(t, cmp.Contains(out.String(), `<testcase classname="example.com/pkg" name="TestSlow" time="1.000000">
+			<properties>
+				<property name="label" value="slow"></property>
+				<property name="label" value="db"></property>
+			</properties>`))
+	assert.Assert(t, !strings.Contains(out.String(), "TestFast"))
+}
+
+func TestWrite_WithLeaks(t *testing.T) {
+	in := `{"Action":"run","Package":"example.com/pkg","Test":"TestLeaks"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestLeaks","Output":"gotestsum-label: db\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestLeaks","Output":"gotestsum-leak: goroutines=2,fds=1\n"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestLeaks","Elapsed":1}
+{"Action":"pass","Package":"example.com/pkg","Elapsed":1}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(in)})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	err = Write(out, exec, Config{customTimestamp: new(time.Time).Format(time.RFC3339)})
+	assert.NilError(t, err)
+
+	assert.Assert(t, cmp.Contains(out.String(), `<testcase classname="example.com/pkg" name="TestLeaks" time="1.000000">
+			<properties>
+				<property name="label" value="db"></property>
+				<property name="leak" value="goroutines=2"></property>
+				<property name="leak" value="fds=1"></property>
+			</properties>`))
+}
+
+func TestWrite_WithBenchmarksAndFuzzTests(t *testing.T) {
+	in := `{"Action":"run","Package":"example.com/pkg","Test":"BenchmarkEncode"}
+{"Action":"output","Package":"example.com/pkg","Test":"BenchmarkEncode","Output":"BenchmarkEncode-8 \t 300\t 4120 ns/op\t 1024 B/op\n"}
+{"Action":"run","Package":"example.com/pkg","Test":"FuzzParse"}
+{"Action":"output","Package":"example.com/pkg","Test":"FuzzParse","Output":"fuzz: elapsed: 1s, execs: 20 (20/sec), new interesting: 1 (total: 3)\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"FuzzParse","Output":"    Failing input written to testdata/fuzz/FuzzParse/abc\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"FuzzParse","Elapsed":1}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":1}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(in)})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	err = Write(out, exec, Config{customTimestamp: new(time.Time).Format(time.RFC3339)})
+	assert.NilError(t, err)
+
+	assert.Assert(t, cmp.Contains(out.String(), `<testcase classname="example.com/pkg" name="BenchmarkEncode" time="0.000000">
+			<properties>
+				<property name="benchmark.iterations" value="300"></property>
+				<property name="benchmark.ns/op" value="4120"></property>
+				<property name="benchmark.B/op" value="1024"></property>
+			</properties>
+		</testcase>`))
+	assert.Assert(t, cmp.Contains(out.String(), `<testcase classname="example.com/pkg" name="FuzzParse" time="1.000000">
+			<properties>
+				<property name="fuzz.execs" value="20"></property>
+				<property name="fuzz.new-interesting" value="1"></property>
+				<property name="fuzz.corpus" value="3"></property>
+				<property name="fuzz.failing-input" value="testdata/fuzz/FuzzParse/abc"></property>
+			</properties>`))
+}
+
+func TestWrite_WithQuarantine(t *testing.T) {
+	in := `{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestFlaky","Output":"    pkg_test.go:10: connection reset\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFlaky","Elapsed":1}
+{"Action":"run","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestBroken","Elapsed":1}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":2}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(in)})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	err = Write(out, exec, Config{
+		customTimestamp: new(time.Time).Format(time.RFC3339),
+		Quarantine: testjson.NewQuarantine([]testjson.QuarantineRule{
+			{Package: "example.com/pkg", Test: "TestFlaky", Reason: "https://issues.example.com/7"},
+		}),
+	})
+	assert.NilError(t, err)
+
+	assert.Assert(t, cmp.Contains(out.String(), `tests="2" failures="1"`))
+	assert.Assert(t, cmp.Contains(out.String(), `<testcase classname="example.com/pkg" name="TestFlaky" time="1.000000">
+			<properties>
+				<property name="quarantined" value="true"></property>
+			</properties>
+			<skipped message="Quarantined: https://issues.example.com/7"></skipped>
+			<system-out>    pkg_test.go:10: connection reset&#xA;</system-out>
+		</testcase>`))
+}
+
+func TestWrite_WithTestSuitePerTest(t *testing.T) {
+	in := `{"Action":"run","Package":"example.com/pkg","Test":"TestTable"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestTable/one"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestTable/one","Elapsed":1}
+{"Action":"run","Package":"example.com/pkg","Test":"TestTable/two"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestTable/two","Elapsed":2}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestTable","Elapsed":3}
+{"Action":"run","Package":"example.com/pkg","Test":"TestSingle"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestSingle","Elapsed":0.5}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":4}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(in)})
+	assert.NilError(t, err)
+
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+	suites := generate(exec, Config{TestSuitePerTest: true, customTimestamp: "now"})
+	assert.Equal(t, len(suites.Suites), 2)
+
+	table := suites.Suites[0]
+	assert.Equal(t, table.Name, "example.com/pkg.TestTable")
+	assert.Equal(t, table.Tests, 3)
+	assert.Equal(t, table.Failures, 2)
+	assert.Equal(t, table.Time, "3.000000")
+	assert.Equal(t, table.Timestamp, "now")
+	var names []string
+	for _, jtc := range table.TestCases {
+		names = append(names, jtc.Name)
+	}
+	assert.DeepEqual(t, names, []string{"TestTable/two", "TestTable", "TestTable/one"})
+
+	single := suites.Suites[1]
+	assert.Equal(t, single.Name, "example.com/pkg.TestSingle")
+	assert.Equal(t, single.Tests, 1)
+	assert.Equal(t, single.Failures, 0)
+	assert.Equal(t, single.Time, "0.500000")
+}
+
+func TestWrite_WithSnapshot(t *testing.T) {
+	in := `{"Action":"run","Package":"example.com/pkg","Test":"TestFails"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFails","Elapsed":1}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":1}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(in)})
+	assert.NilError(t, err)
+	pkg := exec.Package("example.com/pkg")
+	pkg.SetSnapshot(pkg.Failed[0], "TMPDIR=/tmp\n")
+
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+	out := new(bytes.Buffer)
+	err = Write(out, exec, Config{customTimestamp: new(time.Time).Format(time.RFC3339)})
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(out.String(), "<system-err>TMPDIR=/tmp&#xA;</system-err>"))
+}
+
+func TestWrite_WithKnownIssues(t *testing.T) {
+	in := `{"Action":"run","Package":"example.com/pkg","Test":"TestFails"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestFails","Output":"    pkg_test.go:10: connection reset\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFails","Elapsed":1}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":1}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(in)})
+	assert.NilError(t, err)
+	fingerprint := exec.Fingerprint(exec.Failed()[0])
+
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+	out := new(bytes.Buffer)
+	err = Write(out, exec, Config{
+		KnownIssues:     testjson.NewKnownIssues(map[string]string{fingerprint: "https://issues.example.com/1"}),
+		customTimestamp: new(time.Time).Format(time.RFC3339),
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(out.String(), `<properties>
+				<property name="fingerprint" value="`+fingerprint+`"></property>
+				<property name="known-issue" value="https://issues.example.com/1"></property>
+			</properties>`))
+}
+
+func TestWrite_WithFileAttribute(t *testing.T) {
+	in := `{"Action":"run","Package":"example.com/pkg","Test":"TestFails"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestFails","Output":"=== RUN   TestFails\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestFails","Output":"    pkg/store/store_test.go:10: connection reset\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFails","Elapsed":1}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":1}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader(in)})
+	assert.NilError(t, err)
+
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+	out := new(bytes.Buffer)
+	err = Write(out, exec, Config{
+		FileAttribute:   true,
+		customTimestamp: new(time.Time).Format(time.RFC3339),
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(out.String(),
+		`<testcase classname="example.com/pkg" name="TestFails" time="1.000000" file="pkg/store/store_test.go">`))
+}
+
 func TestGoVersion(t *testing.T) {
 	t.Run("unknown", func(t *testing.T) {
 		defer env.Patch(t, "PATH", "/bogus")()
@@ -51,3 +281,84 @@ func TestGoVersion(t *testing.T) {
 		assert.Equal(t, goVersion(), expected)
 	})
 }
+
+func TestWriteLabeled(t *testing.T) {
+	out := new(bytes.Buffer)
+	exec := createExecution(t)
+
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+	execs := []LabeledExecution{
+		{Label: "unit", Execution: exec},
+		{Label: "integration", Execution: exec},
+	}
+	err := WriteLabeled(out, execs, Config{customTimestamp: new(time.Time).Format(time.RFC3339)})
+	assert.NilError(t, err)
+
+	for _, pkg := range exec.Packages() {
+		assert.Assert(t, cmp.Contains(out.String(), `name="unit/`+pkg+`"`))
+		assert.Assert(t, cmp.Contains(out.String(), `name="integration/`+pkg+`"`))
+	}
+}
+
+func TestWrite_PassedOutput(t *testing.T) {
+	input := `{"Action":"run","Package":"example.com/pkg","Test":"TestPass"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestPass","Output":"pass output\n"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestPass"}
+{"Action":"pass","Package":"example.com/pkg"}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:           strings.NewReader(input),
+		KeepPassedOutput: true,
+	})
+	assert.NilError(t, err)
+
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+	out := new(bytes.Buffer)
+	assert.NilError(t, Write(out, exec, Config{PassedOutput: true}))
+	assert.Assert(t, cmp.Contains(out.String(), "<system-out>pass output&#xA;</system-out>"))
+
+	out.Reset()
+	assert.NilError(t, Write(out, exec, Config{}))
+	assert.Assert(t, !strings.Contains(out.String(), "<system-out>"), out.String())
+}
+
+func TestWrite_WithFlakyFailures(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestFlaky","Output":"    pkg_test.go:10: connection reset\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFlaky","Elapsed":1}
+{"Action":"run","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestBroken","Elapsed":1}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":2}
+`),
+	})
+	assert.NilError(t, err)
+	_, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		RunID:     1,
+		Execution: exec,
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestFlaky","Elapsed":1}
+{"Action":"pass","Package":"example.com/pkg","Elapsed":1}
+{"Action":"run","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestBroken","Elapsed":1}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":1}
+`),
+	})
+	assert.NilError(t, err)
+
+	defer env.Patch(t, "GOVERSION", "go7.7.7")()
+	out := new(bytes.Buffer)
+	err = Write(out, exec, Config{
+		FlakyFailures:   true,
+		customTimestamp: new(time.Time).Format(time.RFC3339),
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(out.String(), `<testsuite tests="3" failures="2"`))
+	assert.Assert(t, cmp.Contains(out.String(), `<testcase classname="example.com/pkg" name="TestFlaky" time="1.000000">
+			<flakyFailure message="Failed" type="">
+				<stackTrace>    pkg_test.go:10: connection reset&#xA;</stackTrace>
+			</flakyFailure>
+		</testcase>`))
+	assert.Equal(t, strings.Count(out.String(), `name="TestFlaky"`), 1)
+	assert.Equal(t, strings.Count(out.String(), `name="TestBroken"`), 2)
+}
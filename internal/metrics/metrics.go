@@ -0,0 +1,204 @@
+/*Package metrics encodes metrics in the Prometheus text exposition format, or
+the OpenMetrics text format, and pushes them to a Prometheus Pushgateway.
+
+See https://prometheus.io/docs/instrumenting/exposition_formats/ and
+https://github.com/prometheus/pushgateway.
+*/
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type is the type of a metric family.
+type Type string
+
+// The types of metric families.
+const (
+	Counter   Type = "counter"
+	Gauge     Type = "gauge"
+	Histogram Type = "histogram"
+)
+
+// Label is the name and value of a label of a sample.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a single value of a counter or gauge, or all the observations of
+// a histogram, with the same labels.
+type Sample struct {
+	Labels []Label
+	Value  float64
+	// Observations are the values observed by a histogram.
+	Observations []float64
+}
+
+// Family is a metric with a name, and a sample for each set of labels. The
+// name of a Counter must end with _total.
+type Family struct {
+	Name    string
+	Help    string
+	Type    Type
+	Samples []Sample
+	// Buckets are the upper bounds of the buckets of a Histogram, in
+	// increasing order. The +Inf bucket is always added.
+	Buckets []float64
+}
+
+// Format is the text format used to encode metric families.
+type Format int
+
+const (
+	// FormatText is the Prometheus text exposition format, version 0.0.4,
+	// which is accepted by the Pushgateway.
+	FormatText Format = iota
+	// FormatOpenMetrics is the OpenMetrics text format.
+	FormatOpenMetrics
+)
+
+// Encode writes the families to out in format.
+func Encode(out io.Writer, families []Family, format Format) error {
+	buf := new(bytes.Buffer)
+	for _, family := range families {
+		name := family.Name
+		if family.Type == Counter && format == FormatOpenMetrics {
+			name = strings.TrimSuffix(name, "_total")
+		}
+		fmt.Fprintf(buf, "# HELP %s %s\n", name, escapeHelp(family.Help))
+		fmt.Fprintf(buf, "# TYPE %s %s\n", name, family.Type)
+		for _, sample := range family.Samples {
+			if family.Type == Histogram {
+				writeHistogram(buf, family, sample)
+				continue
+			}
+			writeSample(buf, family.Name, sample.Labels, sample.Value)
+		}
+	}
+	if format == FormatOpenMetrics {
+		buf.WriteString("# EOF\n")
+	}
+	_, err := out.Write(buf.Bytes())
+	return err
+}
+
+func writeHistogram(buf *bytes.Buffer, family Family, sample Sample) {
+	values := append([]float64(nil), sample.Observations...)
+	sort.Float64s(values)
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	bounds := append(append([]float64(nil), family.Buckets...), math.Inf(1))
+	var count int
+	for _, bound := range bounds {
+		for count < len(values) && values[count] <= bound {
+			count++
+		}
+		labels := append(append([]Label(nil), sample.Labels...), Label{Name: "le", Value: formatFloat(bound)})
+		writeSample(buf, family.Name+"_bucket", labels, float64(count))
+	}
+	writeSample(buf, family.Name+"_sum", sample.Labels, sum)
+	writeSample(buf, family.Name+"_count", sample.Labels, float64(len(values)))
+}
+
+func writeSample(buf *bytes.Buffer, name string, labels []Label, value float64) {
+	buf.WriteString(name)
+	if len(labels) > 0 {
+		buf.WriteString("{")
+		for i, label := range labels {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			fmt.Fprintf(buf, "%s=\"%s\"", label.Name, escapeLabelValue(label.Value))
+		}
+		buf.WriteString("}")
+	}
+	buf.WriteString(" " + formatFloat(value) + "\n")
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
+
+// Pusher pushes metrics to a group of a Prometheus Pushgateway.
+type Pusher struct {
+	client *http.Client
+	url    string
+}
+
+// NewPusher returns a Pusher for the group at rawURL, which is the URL of the
+// Pushgateway followed by the job and any grouping labels, ex:
+// http://localhost:9091/metrics/job/gotestsum/branch/main. When the URL has no
+// path the group is the gotestsum job. Credentials in the URL are sent with
+// basic authentication.
+func NewPusher(rawURL string) (*Pusher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Pushgateway URL %v: %w", rawURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid Pushgateway URL %v, must start with http:// or https://", rawURL)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/metrics/job/gotestsum"
+	}
+	if !strings.Contains(u.Path, "/metrics/job/") {
+		return nil, fmt.Errorf("invalid Pushgateway URL %v, the path must be /metrics/job/JOB", rawURL)
+	}
+	return &Pusher{client: &http.Client{Timeout: 30 * time.Second}, url: u.String()}, nil
+}
+
+// Push replaces all the metrics in the group with families.
+func (p *Pusher) Push(ctx context.Context, families []Family) error {
+	body := new(bytes.Buffer)
+	if err := Encode(body, families, FormatText); err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, p.url, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected response %v: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+/*Package otlp exports log records to an OpenTelemetry collector using the
+OTLP/HTTP protocol with JSON encoding.
+
+See https://opentelemetry.io/docs/specs/otlp/.
+*/
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Severity is the severity number of a log record.
+type Severity int
+
+// The severity numbers used by gotestsum, from the OpenTelemetry log data
+// model.
+const (
+	SeverityInfo  Severity = 9
+	SeverityWarn  Severity = 13
+	SeverityError Severity = 17
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "INFO"
+	case SeverityWarn:
+		return "WARN"
+	case SeverityError:
+		return "ERROR"
+	default:
+		return strconv.Itoa(int(s))
+	}
+}
+
+// Attribute is a key and string value added to a log record or resource.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// LogRecord is a single log record.
+type LogRecord struct {
+	Time       time.Time
+	Severity   Severity
+	Body       string
+	Attributes []Attribute
+	// TraceID and SpanID correlate the record with a trace. They are hex
+	// encoded, and may be empty.
+	TraceID string
+	SpanID  string
+}
+
+// Exporter sends log records to the logs endpoint of a collector.
+type Exporter struct {
+	client   *http.Client
+	endpoint string
+	headers  map[string]string
+	resource []Attribute
+}
+
+// NewExporter returns an Exporter which sends records to endpoint. When the
+// endpoint has no path /v1/logs is used. The headers are sent with every
+// request, and the resource attributes describe the source of the records.
+func NewExporter(endpoint string, headers map[string]string, resource []Attribute) (*Exporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint %v: %w", endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %v, must start with http:// or https://", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/logs"
+	}
+	return &Exporter{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		endpoint: u.String(),
+		headers:  headers,
+		resource: resource,
+	}, nil
+}
+
+// Export sends records to the collector in a single request.
+func (e *Exporter) Export(ctx context.Context, records []LogRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(newExportRequest(e.resource, records))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected response %v: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// ParseHeaders parses headers in the format of the OTEL_EXPORTER_OTLP_HEADERS
+// environment variable: a comma separated list of key=value pairs, with
+// URL encoded values.
+func ParseHeaders(raw string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		i := strings.Index(pair, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid header %q, must be key=value", pair)
+		}
+		value, err := url.QueryUnescape(strings.TrimSpace(pair[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid header %q: %w", pair, err)
+		}
+		headers[strings.TrimSpace(pair[:i])] = value
+	}
+	return headers, nil
+}
+
+// The types below are the JSON encoding of the OTLP ExportLogsServiceRequest.
+
+type exportRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeLogs struct {
+	Scope      scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type logRecord struct {
+	TimeUnixNano   string     `json:"timeUnixNano"`
+	SeverityNumber int        `json:"severityNumber"`
+	SeverityText   string     `json:"severityText"`
+	Body           anyValue   `json:"body"`
+	Attributes     []keyValue `json:"attributes,omitempty"`
+	TraceID        string     `json:"traceId,omitempty"`
+	SpanID         string     `json:"spanId,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+func newKeyValues(attrs []Attribute) []keyValue {
+	result := make([]keyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		result = append(result, keyValue{Key: attr.Key, Value: anyValue{StringValue: attr.Value}})
+	}
+	return result
+}
+
+func newExportRequest(res []Attribute, records []LogRecord) exportRequest {
+	logs := make([]logRecord, 0, len(records))
+	for _, r := range records {
+		logs = append(logs, logRecord{
+			TimeUnixNano:   strconv.FormatInt(r.Time.UnixNano(), 10),
+			SeverityNumber: int(r.Severity),
+			SeverityText:   r.Severity.String(),
+			Body:           anyValue{StringValue: r.Body},
+			Attributes:     newKeyValues(r.Attributes),
+			TraceID:        r.TraceID,
+			SpanID:         r.SpanID,
+		})
+	}
+	return exportRequest{ResourceLogs: []resourceLogs{{
+		Resource: resource{Attributes: newKeyValues(res)},
+		ScopeLogs: []scopeLogs{{
+			Scope:      scope{Name: "gotest.tools/gotestsum"},
+			LogRecords: logs,
+		}},
+	}}}
+}
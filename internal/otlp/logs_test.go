@@ -0,0 +1,153 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestExporter_Export(t *testing.T) {
+	var body []byte
+	var path, contentType, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, contentType, auth = r.URL.Path, r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	exporter, err := NewExporter(srv.URL, map[string]string{"Authorization": "Bearer token"},
+		[]Attribute{{Key: "service.name", Value: "gotestsum"}})
+	assert.NilError(t, err)
+
+	err = exporter.Export(context.Background(), []LogRecord{{
+		Time:       time.Unix(1700000000, 5),
+		Severity:   SeverityError,
+		Body:       "--- FAIL: TestOne\n",
+		Attributes: []Attribute{{Key: "test.name", Value: "TestOne"}},
+		TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:     "00f067aa0ba902b7",
+	}})
+	assert.NilError(t, err)
+	assert.Equal(t, path, "/v1/logs")
+	assert.Equal(t, contentType, "application/json")
+	assert.Equal(t, auth, "Bearer token")
+
+	var got interface{}
+	assert.NilError(t, json.Unmarshal(body, &got))
+	var expected interface{}
+	assert.NilError(t, json.Unmarshal([]byte(`{"resourceLogs": [{
+		"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "gotestsum"}}]},
+		"scopeLogs": [{
+			"scope": {"name": "gotest.tools/gotestsum"},
+			"logRecords": [{
+				"timeUnixNano": "1700000000000000005",
+				"severityNumber": 17,
+				"severityText": "ERROR",
+				"body": {"stringValue": "--- FAIL: TestOne\n"},
+				"attributes": [{"key": "test.name", "value": {"stringValue": "TestOne"}}],
+				"traceId": "4bf92f3577b34da6a3ce929d0e0e4736",
+				"spanId": "00f067aa0ba902b7"
+			}]
+		}]
+	}]}`), &expected))
+	assert.DeepEqual(t, got, expected)
+}
+
+func TestExporter_Export_ErrorResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	exporter, err := NewExporter(srv.URL+"/custom/logs", nil, nil)
+	assert.NilError(t, err)
+	err = exporter.Export(context.Background(), []LogRecord{{Body: "output"}})
+	assert.ErrorContains(t, err, "429 Too Many Requests: quota exceeded")
+}
+
+func TestNewExporter_InvalidEndpoint(t *testing.T) {
+	_, err := NewExporter("localhost:4318", nil, nil)
+	assert.ErrorContains(t, err, "must start with http:// or https://")
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := ParseHeaders("api-key=abc%3D, x-tenant = team ,")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, headers, map[string]string{"api-key": "abc=", "x-tenant": "team"})
+
+	_, err = ParseHeaders("novalue")
+	assert.ErrorContains(t, err, `invalid header "novalue"`)
+}
+
+func TestFindTraceparent(t *testing.T) {
+	var testcases = []struct {
+		line    string
+		traceID string
+		spanID  string
+	}{
+		{
+			line:    "traceparent: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01\n",
+			traceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			spanID:  "00f067aa0ba902b7",
+		},
+		{
+			line:    `    client.go:12: request failed {"traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"}`,
+			traceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			spanID:  "00f067aa0ba902b7",
+		},
+		{line: "traceparent=00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{line: "no trace here"},
+	}
+	for _, tc := range testcases {
+		traceID, spanID, ok := FindTraceparent(tc.line)
+		assert.Equal(t, traceID, tc.traceID, tc.line)
+		assert.Equal(t, spanID, tc.spanID, tc.line)
+		assert.Equal(t, ok, tc.traceID != "", tc.line)
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	span, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01\n")
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, span, SpanContext{
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "00f067aa0ba902b7",
+		Sampled: true,
+	})
+	assert.Equal(t, span.Traceparent(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	span, ok = ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	assert.Assert(t, ok)
+	assert.Assert(t, !span.Sampled)
+
+	for _, value := range []string{
+		"",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+	} {
+		_, ok := ParseTraceparent(value)
+		assert.Assert(t, !ok, value)
+	}
+}
+
+func TestSpanContext_NewSpan(t *testing.T) {
+	root := SpanContext{}.NewSpan()
+	assert.Equal(t, len(root.TraceID), 32)
+	assert.Equal(t, len(root.SpanID), 16)
+	assert.Assert(t, root.Sampled)
+
+	child := root.NewSpan()
+	assert.Equal(t, child.TraceID, root.TraceID)
+	assert.Assert(t, child.SpanID != root.SpanID)
+
+	_, ok := ParseTraceparent(child.Traceparent())
+	assert.Assert(t, ok)
+}
@@ -0,0 +1,83 @@
+package otlp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// traceparentPattern matches a W3C traceparent value, optionally preceded by
+// the name of the header. See https://www.w3.org/TR/trace-context/.
+var traceparentPattern = regexp.MustCompile(
+	`(?i)traceparent[=:"\s]+([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})\b`)
+
+// FindTraceparent returns the trace ID and span ID from the first traceparent
+// in line. Lines like the following are matched:
+//
+//	traceparent: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+//	request failed traceparent=00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func FindTraceparent(line string) (traceID string, spanID string, ok bool) {
+	match := traceparentPattern.FindStringSubmatch(line)
+	if match == nil || match[2] == zeroTraceID || match[3] == zeroSpanID {
+		return "", "", false
+	}
+	return match[2], match[3], true
+}
+
+const (
+	zeroTraceID = "00000000000000000000000000000000"
+	zeroSpanID  = "0000000000000000"
+)
+
+// SpanContext identifies a span in a trace. The IDs are hex encoded.
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+	Sampled bool
+}
+
+// valuePattern matches the value of a version 00 traceparent.
+var valuePattern = regexp.MustCompile(`^00-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$`)
+
+// ParseTraceparent parses the value of a traceparent header, or the
+// TRACEPARENT environment variable.
+func ParseTraceparent(value string) (SpanContext, bool) {
+	match := valuePattern.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil || match[1] == zeroTraceID || match[2] == zeroSpanID {
+		return SpanContext{}, false
+	}
+	flags, err := hex.DecodeString(match[3])
+	if err != nil {
+		return SpanContext{}, false
+	}
+	return SpanContext{TraceID: match[1], SpanID: match[2], Sampled: flags[0]&1 == 1}, true
+}
+
+// Traceparent returns the value of the traceparent header for the span.
+func (s SpanContext) Traceparent() string {
+	flags := "00"
+	if s.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", s.TraceID, s.SpanID, flags)
+}
+
+// NewSpan returns a new span in the trace of s. If s is not part of a trace
+// the new span starts a new sampled trace.
+func (s SpanContext) NewSpan() SpanContext {
+	if s.TraceID == "" {
+		return SpanContext{TraceID: randomID(16), SpanID: randomID(8), Sampled: true}
+	}
+	return SpanContext{TraceID: s.TraceID, SpanID: randomID(8), Sampled: s.Sampled}
+}
+
+// randomID returns a random hex encoded ID of size bytes.
+func randomID(size int) string {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
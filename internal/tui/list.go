@@ -0,0 +1,143 @@
+package tui
+
+import (
+	"strings"
+
+	"gotest.tools/gotestsum/internal/width"
+)
+
+// List is the position of the cursor in a scrolling list of lines.
+type List struct {
+	// Len is the number of lines in the list.
+	Len int
+	// Cursor is the index of the selected line.
+	Cursor int
+	// offset is the index of the first visible line.
+	offset int
+}
+
+// Update moves the cursor for the movement keys. height is the number of lines
+// that are visible, and is used to move by a page. Returns false if the key is
+// not a movement key.
+func (l *List) Update(key Key, height int) bool {
+	switch key {
+	case KeyUp, "k":
+		l.Cursor--
+	case KeyDown, "j":
+		l.Cursor++
+	case KeyPageUp:
+		l.Cursor -= height
+	case KeyPageDown, " ":
+		l.Cursor += height
+	case KeyHome, "g":
+		l.Cursor = 0
+	case KeyEnd, "G":
+		l.Cursor = l.Len - 1
+	default:
+		return false
+	}
+	l.clamp()
+	return true
+}
+
+// SetLen sets the number of lines in the list, and moves the cursor if it is
+// past the end of the list.
+func (l *List) SetLen(n int) {
+	l.Len = n
+	l.clamp()
+}
+
+func (l *List) clamp() {
+	if l.Cursor >= l.Len {
+		l.Cursor = l.Len - 1
+	}
+	if l.Cursor < 0 {
+		l.Cursor = 0
+	}
+}
+
+// Visible returns the range of lines to display in height lines, scrolling
+// the list so that the cursor is visible.
+func (l *List) Visible(height int) (start, end int) {
+	if height < 1 {
+		height = 1
+	}
+	if l.Cursor < l.offset {
+		l.offset = l.Cursor
+	}
+	if l.Cursor >= l.offset+height {
+		l.offset = l.Cursor - height + 1
+	}
+	if max := l.Len - height; l.offset > max {
+		l.offset = max
+	}
+	if l.offset < 0 {
+		l.offset = 0
+	}
+	end = l.offset + height
+	if end > l.Len {
+		end = l.Len
+	}
+	return l.offset, end
+}
+
+// Line truncates or pads text to fill a line with the width. If selected is
+// true the line is highlighted.
+func Line(text string, lineWidth int, selected bool) string {
+	text = width.Truncate(text, lineWidth)
+	if !selected {
+		return text
+	}
+	return Reverse + text + strings.Repeat(" ", lineWidth-width.String(text)) + Reset
+}
+
+// Pager is the position of a scrolling view of lines that has no cursor, like
+// the output of a test.
+type Pager struct {
+	// Len is the number of lines.
+	Len int
+	// Offset is the index of the first visible line.
+	Offset int
+}
+
+// Update scrolls the view for the movement keys. height is the number of lines
+// that are visible. Returns false if the key is not a movement key.
+func (p *Pager) Update(key Key, height int) bool {
+	switch key {
+	case KeyUp, "k":
+		p.Offset--
+	case KeyDown, "j":
+		p.Offset++
+	case KeyPageUp:
+		p.Offset -= height
+	case KeyPageDown, " ":
+		p.Offset += height
+	case KeyHome, "g":
+		p.Offset = 0
+	case KeyEnd, "G":
+		p.Offset = p.Len
+	default:
+		return false
+	}
+	p.Visible(height)
+	return true
+}
+
+// Visible returns the range of lines to display in height lines. The view is
+// never scrolled past the last line.
+func (p *Pager) Visible(height int) (start, end int) {
+	if height < 1 {
+		height = 1
+	}
+	if max := p.Len - height; p.Offset > max {
+		p.Offset = max
+	}
+	if p.Offset < 0 {
+		p.Offset = 0
+	}
+	end = p.Offset + height
+	if end > p.Len {
+		end = p.Len
+	}
+	return p.Offset, end
+}
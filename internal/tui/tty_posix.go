@@ -0,0 +1,10 @@
+//go:build !windows
+// +build !windows
+
+package tui
+
+import "os"
+
+func openTTY() (*os.File, error) {
+	return os.Open("/dev/tty")
+}
@@ -0,0 +1,9 @@
+// +build windows
+
+package tui
+
+import "os"
+
+func openTTY() (*os.File, error) {
+	return os.OpenFile("CONIN$", os.O_RDWR, 0)
+}
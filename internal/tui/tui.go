@@ -0,0 +1,179 @@
+/*Package tui implements a minimal full screen terminal user interface, used by
+the interactive commands to browse the results of a test run.
+*/
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// Key is a key pressed by the user. Printable keys are the character, special
+// keys use one of the Key constants.
+type Key string
+
+// Special keys.
+const (
+	KeyUp        Key = "up"
+	KeyDown      Key = "down"
+	KeyLeft      Key = "left"
+	KeyRight     Key = "right"
+	KeyPageUp    Key = "pgup"
+	KeyPageDown  Key = "pgdown"
+	KeyHome      Key = "home"
+	KeyEnd       Key = "end"
+	KeyEnter     Key = "enter"
+	KeyEscape    Key = "esc"
+	KeyBackspace Key = "backspace"
+	KeyTab       Key = "tab"
+	KeyCtrlC     Key = "ctrl+c"
+)
+
+// Model is the state of a user interface.
+type Model interface {
+	// Update the model after a key was pressed. Returns true to exit.
+	Update(key Key) bool
+	// View returns the lines to display on a screen with the width and height.
+	View(width, height int) []string
+}
+
+// Escape sequences used to draw the screen.
+const (
+	enterAltScreen = "\x1b[?1049h\x1b[?25l"
+	exitAltScreen  = "\x1b[?25h\x1b[?1049l"
+	clearScreen    = "\x1b[H\x1b[2J"
+	// Reverse is used to highlight the selected line.
+	Reverse = "\x1b[7m"
+	// Bold is used for headers.
+	Bold = "\x1b[1m"
+	// Reset ends a Reverse or Bold sequence.
+	Reset = "\x1b[0m"
+)
+
+// Run the model until Update returns true. Keys are read from the terminal,
+// even when stdin is a file or pipe, and the screen is written to out.
+func Run(out *os.File, model Model) error {
+	tty, err := openTTY()
+	if err != nil {
+		return fmt.Errorf("interactive mode requires a terminal: %w", err)
+	}
+	defer tty.Close() // nolint: errcheck
+
+	fd := int(tty.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("interactive mode requires a terminal: %w", err)
+	}
+	defer term.Restore(fd, state) // nolint: errcheck
+
+	if _, err := io.WriteString(out, enterAltScreen); err != nil {
+		return err
+	}
+	defer io.WriteString(out, exitAltScreen) // nolint: errcheck
+
+	reader := bufio.NewReader(tty)
+	for {
+		width, height, err := term.GetSize(int(out.Fd()))
+		if err != nil {
+			width, height = 80, 24
+		}
+		if err := draw(out, model.View(width, height)); err != nil {
+			return err
+		}
+		key, err := ReadKey(reader)
+		if err != nil {
+			return err
+		}
+		if model.Update(key) {
+			return nil
+		}
+	}
+}
+
+func draw(out io.Writer, lines []string) error {
+	buf := new(strings.Builder)
+	buf.WriteString(clearScreen)
+	for i, line := range lines {
+		if i > 0 {
+			buf.WriteString("\r\n")
+		}
+		buf.WriteString(line)
+	}
+	_, err := io.WriteString(out, buf.String())
+	return err
+}
+
+// ReadKey reads a single key press from a terminal in raw mode.
+func ReadKey(in *bufio.Reader) (Key, error) {
+	b, err := in.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	switch b {
+	case 0x1b:
+		if in.Buffered() == 0 {
+			return KeyEscape, nil
+		}
+		return readEscapeSequence(in)
+	case '\r', '\n':
+		return KeyEnter, nil
+	case 0x7f, 0x08:
+		return KeyBackspace, nil
+	case '\t':
+		return KeyTab, nil
+	case 0x03:
+		return KeyCtrlC, nil
+	}
+	if b < utf8.RuneSelf {
+		return Key(b), nil
+	}
+	if err := in.UnreadByte(); err != nil {
+		return "", err
+	}
+	r, _, err := in.ReadRune()
+	return Key(r), err
+}
+
+var escapeSequences = map[string]Key{
+	"[A":  KeyUp,
+	"[B":  KeyDown,
+	"[C":  KeyRight,
+	"[D":  KeyLeft,
+	"[H":  KeyHome,
+	"[F":  KeyEnd,
+	"[1~": KeyHome,
+	"[4~": KeyEnd,
+	"[5~": KeyPageUp,
+	"[6~": KeyPageDown,
+	"OA":  KeyUp,
+	"OB":  KeyDown,
+	"OH":  KeyHome,
+	"OF":  KeyEnd,
+}
+
+// readEscapeSequence reads the rest of a sequence that started with ESC.
+// Unknown sequences are returned as KeyEscape.
+func readEscapeSequence(in *bufio.Reader) (Key, error) {
+	seq := new(strings.Builder)
+	for in.Buffered() > 0 {
+		b, err := in.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		seq.WriteByte(b)
+		// A sequence ends with a letter or ~, except for the first byte.
+		if seq.Len() > 1 && (b == '~' || (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z')) {
+			break
+		}
+	}
+	if key, ok := escapeSequences[seq.String()]; ok {
+		return key, nil
+	}
+	return KeyEscape, nil
+}
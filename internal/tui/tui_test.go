@@ -0,0 +1,84 @@
+package tui
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestReadKey(t *testing.T) {
+	in := bufio.NewReader(strings.NewReader("q\x1b[A\x1b[B\x1b[5~\r\x7fテ\x1b[Z\t\x03"))
+	var keys []Key
+	for {
+		key, err := ReadKey(in)
+		if err != nil {
+			break
+		}
+		keys = append(keys, key)
+	}
+	expected := []Key{"q", KeyUp, KeyDown, KeyPageUp, KeyEnter, KeyBackspace, "テ", KeyEscape, KeyTab, KeyCtrlC}
+	assert.DeepEqual(t, keys, expected)
+}
+
+func TestList(t *testing.T) {
+	l := &List{}
+	l.SetLen(10)
+
+	start, end := l.Visible(4)
+	assert.Equal(t, [2]int{start, end}, [2]int{0, 4})
+
+	for i := 0; i < 5; i++ {
+		assert.Assert(t, l.Update(KeyDown, 4))
+	}
+	assert.Equal(t, l.Cursor, 5)
+	start, end = l.Visible(4)
+	assert.Equal(t, [2]int{start, end}, [2]int{2, 6})
+
+	l.Update(KeyPageDown, 4)
+	assert.Equal(t, l.Cursor, 9)
+	start, end = l.Visible(4)
+	assert.Equal(t, [2]int{start, end}, [2]int{6, 10})
+
+	l.Update(KeyHome, 4)
+	assert.Equal(t, l.Cursor, 0)
+	start, end = l.Visible(4)
+	assert.Equal(t, [2]int{start, end}, [2]int{0, 4})
+
+	assert.Assert(t, !l.Update("x", 4))
+
+	l.Update(KeyEnd, 4)
+	l.SetLen(3)
+	assert.Equal(t, l.Cursor, 2)
+	start, end = l.Visible(4)
+	assert.Equal(t, [2]int{start, end}, [2]int{0, 3})
+}
+
+func TestLine(t *testing.T) {
+	assert.Equal(t, Line("example.com/pkg", 7, false), "example")
+	assert.Equal(t, Line("pkg", 6, true), Reverse+"pkg   "+Reset)
+}
+
+func TestPager(t *testing.T) {
+	p := &Pager{Len: 10}
+
+	assert.Assert(t, p.Update(KeyDown, 4))
+	start, end := p.Visible(4)
+	assert.Equal(t, [2]int{start, end}, [2]int{1, 5})
+
+	p.Update(KeyEnd, 4)
+	start, end = p.Visible(4)
+	assert.Equal(t, [2]int{start, end}, [2]int{6, 10})
+
+	p.Update(KeyPageUp, 4)
+	p.Update(KeyPageUp, 4)
+	start, end = p.Visible(4)
+	assert.Equal(t, [2]int{start, end}, [2]int{0, 4})
+
+	assert.Assert(t, !p.Update("x", 4))
+
+	p = &Pager{Len: 2, Offset: 5}
+	start, end = p.Visible(4)
+	assert.Equal(t, [2]int{start, end}, [2]int{0, 2})
+}
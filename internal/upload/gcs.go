@@ -0,0 +1,263 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// GCS stores objects in Google Cloud Storage.
+type GCS struct {
+	client *http.Client
+	// endpoint is the URL of the Cloud Storage API.
+	endpoint string
+	// token returns the OAuth2 access token used to authenticate requests.
+	token func(ctx context.Context) (string, error)
+}
+
+const gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+// NewGCS returns a Google Cloud Storage ObjectStore. The access token is read
+// from the GOOGLE_OAUTH_ACCESS_TOKEN environment variable, or created from the
+// service account key file in GOOGLE_APPLICATION_CREDENTIALS, or requested
+// from the metadata server when running on Google Cloud. When
+// STORAGE_EMULATOR_HOST is set, requests are sent to the emulator without
+// credentials.
+func NewGCS(client *http.Client) (*GCS, error) {
+	g := &GCS{client: client, endpoint: "https://storage.googleapis.com"}
+	switch {
+	case os.Getenv("STORAGE_EMULATOR_HOST") != "":
+		g.endpoint = emulatorEndpoint(os.Getenv("STORAGE_EMULATOR_HOST"))
+		g.token = func(context.Context) (string, error) { return "", nil }
+	case os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN") != "":
+		token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+		g.token = func(context.Context) (string, error) { return token, nil }
+	case os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") != "":
+		key, err := readServiceAccountKey(os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"))
+		if err != nil {
+			return nil, err
+		}
+		g.token = func(ctx context.Context) (string, error) {
+			return key.token(ctx, client, time.Now())
+		}
+	default:
+		g.token = func(ctx context.Context) (string, error) {
+			return metadataToken(ctx, client)
+		}
+	}
+	return g, nil
+}
+
+func emulatorEndpoint(host string) string {
+	if strings.HasPrefix(host, "http://") || strings.HasPrefix(host, "https://") {
+		return strings.TrimSuffix(host, "/")
+	}
+	return "http://" + host
+}
+
+// Put uploads obj to bucket. When the object has metadata, a multipart
+// upload is used to send the metadata with the body.
+func (g *GCS) Put(ctx context.Context, bucket string, obj Object) error {
+	token, err := g.token(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get access token: %w", err)
+	}
+	target := g.endpoint + "/upload/storage/v1/b/" + url.PathEscape(bucket) + "/o"
+	body, contentType := obj.Body, obj.ContentType
+	if len(obj.Metadata) == 0 {
+		target += "?uploadType=media&name=" + url.QueryEscape(obj.Key)
+	} else {
+		target += "?uploadType=multipart"
+		body, contentType, err = gcsMultipartBody(obj)
+		if err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", contentType)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	return checkResponse(resp)
+}
+
+// gcsMultipartBody returns the body and content type of a multipart upload
+// request for obj. The first part is the JSON resource of the object, and the
+// second part is the content of the object.
+// See https://cloud.google.com/storage/docs/uploading-objects#uploading-an-object.
+func gcsMultipartBody(obj Object) ([]byte, string, error) {
+	resource, err := json.Marshal(map[string]interface{}{
+		"name":        obj.Key,
+		"contentType": obj.ContentType,
+		"metadata":    obj.Metadata,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	buf := new(bytes.Buffer)
+	w := multipart.NewWriter(buf)
+	parts := []struct {
+		contentType string
+		body        []byte
+	}{
+		{contentType: "application/json; charset=UTF-8", body: resource},
+		{contentType: obj.ContentType, body: obj.Body},
+	}
+	for _, p := range parts {
+		part, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {p.contentType}})
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := part.Write(p.body); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "multipart/related; boundary=" + w.Boundary(), nil
+}
+
+// serviceAccountKey is the subset of the fields in a service account key file
+// used to request an access token.
+type serviceAccountKey struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+func readServiceAccountKey(filename string) (serviceAccountKey, error) {
+	var key serviceAccountKey
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return key, fmt.Errorf("failed to read GOOGLE_APPLICATION_CREDENTIALS: %w", err)
+	}
+	if err := json.Unmarshal(raw, &key); err != nil {
+		return key, fmt.Errorf("failed to parse GOOGLE_APPLICATION_CREDENTIALS: %w", err)
+	}
+	if key.ClientEmail == "" || key.PrivateKey == "" {
+		return key, fmt.Errorf("GOOGLE_APPLICATION_CREDENTIALS is not a service account key")
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	return key, nil
+}
+
+// token exchanges a JWT signed by the service account key for an access token.
+func (k serviceAccountKey) token(ctx context.Context, client *http.Client, now time.Time) (string, error) {
+	assertion, err := k.jwt(now)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequest(http.MethodPost, k.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return doTokenRequest(client, req)
+}
+
+func (k serviceAccountKey) jwt(now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(k.PrivateKey))
+	if block == nil {
+		return "", fmt.Errorf("invalid private key in service account key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid private key in service account key: %w", err)
+	}
+	privateKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", fmt.Errorf("service account private key must be an RSA key")
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   k.ClientEmail,
+		"scope": gcsScope,
+		"aud":   k.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	encoding := base64.RawURLEncoding
+	unsigned := encoding.EncodeToString(header) + "." + encoding.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + encoding.EncodeToString(signature), nil
+}
+
+// metadataToken requests an access token for the default service account from
+// the metadata server.
+func metadataToken(ctx context.Context, client *http.Client) (string, error) {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	target := "http://" + host + "/computeMetadata/v1/instance/service-accounts/default/token"
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Metadata-Flavor", "Google")
+	token, err := doTokenRequest(client, req)
+	if err != nil {
+		return "", fmt.Errorf("no Google Cloud credentials found: %w", err)
+	}
+	return token, nil
+}
+
+func doTokenRequest(client *http.Client, req *http.Request) (string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if err := checkResponse(resp); err != nil {
+		return "", err
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode access token: %w", err)
+	}
+	return token.AccessToken, nil
+}
@@ -0,0 +1,376 @@
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3 stores objects in Amazon S3, or any service that supports the S3 API.
+type S3 struct {
+	client      *http.Client
+	credentials awsCredentials
+	region      string
+	// endpoint is the URL of an S3 compatible service. When it is set the
+	// bucket is part of the path of the object URL instead of the host.
+	endpoint string
+	now      func() time.Time
+}
+
+type awsCredentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// NewS3 returns an S3 ObjectStore. Credentials are read from the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN environment
+// variables, or from the shared credentials file (~/.aws/credentials, or
+// AWS_SHARED_CREDENTIALS_FILE) using the profile from AWS_PROFILE. The region
+// is read from AWS_REGION or AWS_DEFAULT_REGION. AWS_ENDPOINT_URL may be used
+// to upload to an S3 compatible service.
+func NewS3(client *http.Client) (*S3, error) {
+	creds, err := lookupAWSCredentials()
+	if err != nil {
+		return nil, err
+	}
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3{
+		client:      client,
+		credentials: creds,
+		region:      region,
+		endpoint:    strings.TrimSuffix(os.Getenv("AWS_ENDPOINT_URL"), "/"),
+		now:         time.Now,
+	}, nil
+}
+
+func lookupAWSCredentials() (awsCredentials, error) {
+	creds := awsCredentials{
+		accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.accessKeyID != "" && creds.secretAccessKey != "" {
+		return creds, nil
+	}
+
+	filename := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if filename == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return creds, fmt.Errorf("no AWS credentials in the environment: %w", err)
+		}
+		filename = filepath.Join(home, ".aws", "credentials")
+	}
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+	creds, err := readAWSCredentialsFile(filename, profile)
+	if err != nil {
+		return creds, fmt.Errorf("no AWS credentials in the environment, "+
+			"and failed to read the credentials file: %w", err)
+	}
+	return creds, nil
+}
+
+// readAWSCredentialsFile reads the credentials for profile from the shared
+// credentials file, which uses the INI format.
+func readAWSCredentialsFile(filename, profile string) (awsCredentials, error) {
+	var creds awsCredentials
+	fh, err := os.Open(filename)
+	if err != nil {
+		return creds, err
+	}
+	defer fh.Close() // nolint: errcheck
+
+	var section string
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		case section != profile:
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			continue
+		}
+		value := strings.TrimSpace(line[i+1:])
+		switch strings.TrimSpace(line[:i]) {
+		case "aws_access_key_id":
+			creds.accessKeyID = value
+		case "aws_secret_access_key":
+			creds.secretAccessKey = value
+		case "aws_session_token":
+			creds.sessionToken = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return creds, err
+	}
+	if creds.accessKeyID == "" || creds.secretAccessKey == "" {
+		return creds, fmt.Errorf("profile %v not found in %v", profile, filename)
+	}
+	return creds, nil
+}
+
+// Put uploads obj to bucket. The metadata of the object is sent as
+// x-amz-meta- headers.
+func (s *S3) Put(ctx context.Context, bucket string, obj Object) error {
+	req, err := s.newRequest(ctx, http.MethodPut, bucket, obj.Key, nil, obj.Body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", obj.ContentType)
+	for k, v := range obj.Metadata {
+		req.Header.Set("X-Amz-Meta-"+k, v)
+	}
+	return s.do(req, obj.Body, nil)
+}
+
+// Get returns the body of the object with key in bucket. The error wraps
+// os.ErrNotExist when the object does not exist.
+func (s *S3) Get(ctx context.Context, bucket string, key string) ([]byte, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, bucket, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var body []byte
+	err = s.do(req, nil, func(resp *http.Response) error {
+		body, err = ioutil.ReadAll(resp.Body)
+		return err
+	})
+	return body, err
+}
+
+// Delete removes the object with key from bucket.
+func (s *S3) Delete(ctx context.Context, bucket string, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, bucket, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	return s.do(req, nil, nil)
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key string
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// List returns the keys of all the objects in bucket that start with prefix.
+func (s *S3) List(ctx context.Context, bucket string, prefix string) ([]string, error) {
+	var keys []string
+	var token string
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := s.newRequest(ctx, http.MethodGet, bucket, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		err = s.do(req, nil, func(resp *http.Response) error {
+			return xml.NewDecoder(resp.Body).Decode(&result)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range result.Contents {
+			keys = append(keys, c.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (s *S3) newRequest(
+	ctx context.Context, method, bucket, key string, query url.Values, body []byte,
+) (*http.Request, error) {
+	target := "https://" + bucket + ".s3." + s.region + ".amazonaws.com/" + escapePath(key)
+	if s.endpoint != "" {
+		target = s.endpoint + "/" + bucket + "/" + escapePath(key)
+	}
+	if len(query) > 0 {
+		// the AWS signature requires the query to be sorted, and encoded with
+		// %20 for spaces.
+		target += "?" + strings.Replace(query.Encode(), "+", "%20", -1)
+	}
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	return req.WithContext(ctx), nil
+}
+
+// do signs and sends req, and calls handle with a successful response.
+func (s *S3) do(req *http.Request, body []byte, handle func(*http.Response) error) error {
+	if s.credentials.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.credentials.sessionToken)
+	}
+	signS3(req, body, s.credentials, s.region, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+	if handle == nil {
+		return nil
+	}
+	return handle(resp)
+}
+
+// escapePath escapes each segment of the path using the rules of the AWS
+// signature.
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = escapeAWS(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// escapeAWS escapes s using the rules of the AWS signature, which use %20 for
+// spaces.
+func escapeAWS(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+const (
+	amzDateFormat  = "20060102T150405Z"
+	amzScopeFormat = "20060102"
+)
+
+// signS3 signs req for the s3 service. S3 requires the hash of the payload in
+// the X-Amz-Content-Sha256 header.
+func signS3(req *http.Request, body []byte, creds awsCredentials, region string, now time.Time) {
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signV4(req, payloadHash, creds, region, "s3", now)
+}
+
+// signV4 signs req using AWS signature version 4, and sets the Authorization
+// header. See https://docs.aws.amazon.com/general/latest/gr/sigv4_signing.html.
+//
+// The path of req is not normalized, or escaped a second time, because S3 does
+// not use either of those steps.
+func signV4(req *http.Request, payloadHash string, creds awsCredentials, region, service string, now time.Time) {
+	now = now.UTC()
+	req.Header.Set("X-Amz-Date", now.Format(amzDateFormat))
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		trimmed := make([]string, 0, len(values))
+		for _, value := range values {
+			trimmed = append(trimmed, strings.Join(strings.Fields(value), " "))
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.RawQuery),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := now.Format(amzScopeFormat) + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format(amzDateFormat),
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.secretAccessKey), now.Format(amzScopeFormat))
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s,SignedHeaders=%s,Signature=%s",
+		creds.accessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery returns the query sorted by name and then by value, with each
+// name and value escaped using the rules of the AWS signature.
+func canonicalQuery(rawQuery string) string {
+	query, _ := url.ParseQuery(rawQuery)
+	type param struct{ name, value string }
+	params := make([]param, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			params = append(params, param{name: escapeAWS(name), value: escapeAWS(value)})
+		}
+	}
+	sort.Slice(params, func(i, j int) bool {
+		if params[i].name != params[j].name {
+			return params[i].name < params[j].name
+		}
+		return params[i].value < params[j].value
+	})
+	pairs := make([]string, 0, len(params))
+	for _, p := range params {
+		pairs = append(pairs, p.name+"="+p.value)
+	}
+	return strings.Join(pairs, "&")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data)) // nolint: errcheck
+	return h.Sum(nil)
+}
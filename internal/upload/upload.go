@@ -0,0 +1,164 @@
+/*Package upload uploads files to a bucket in Amazon S3 or Google Cloud Storage.
+
+Credentials are read from the same places as the cloud provider tools. See
+the documentation of NewS3 and NewGCS for the details.
+*/
+package upload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Target is the location where files are uploaded.
+type Target struct {
+	// Scheme is either s3 or gs.
+	Scheme string
+	Bucket string
+	// Prefix is prepended to the name of each file to create the key of the
+	// object in the bucket. It never has a leading or trailing slash.
+	Prefix string
+}
+
+// String returns the URL of the target.
+func (t Target) String() string {
+	return t.Scheme + "://" + path.Join(t.Bucket, t.Prefix)
+}
+
+// ParseTarget parses a URL in the form s3://bucket/prefix or gs://bucket/prefix.
+func ParseTarget(raw string) (Target, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Target{}, fmt.Errorf("invalid upload URL %v: %w", raw, err)
+	}
+	switch u.Scheme {
+	case "s3", "gs":
+	default:
+		return Target{}, fmt.Errorf("invalid upload URL %v, must start with s3:// or gs://", raw)
+	}
+	if u.Host == "" {
+		return Target{}, fmt.Errorf("invalid upload URL %v, missing bucket name", raw)
+	}
+	return Target{
+		Scheme: u.Scheme,
+		Bucket: u.Host,
+		Prefix: strings.Trim(u.Path, "/"),
+	}, nil
+}
+
+// Object is an object stored in a bucket.
+type Object struct {
+	Key         string
+	Body        []byte
+	ContentType string
+	// Metadata is stored with the object as user-defined metadata.
+	Metadata map[string]string
+}
+
+// ObjectStore stores objects in a bucket.
+type ObjectStore interface {
+	Put(ctx context.Context, bucket string, obj Object) error
+}
+
+// Uploader uploads files to a Target.
+type Uploader struct {
+	target   Target
+	store    ObjectStore
+	metadata map[string]string
+}
+
+// New returns an Uploader for the target, using the credentials from the
+// environment for the scheme of the target. The metadata is stored with each
+// uploaded object.
+func New(target Target, metadata map[string]string) (*Uploader, error) {
+	var store ObjectStore
+	var err error
+	switch target.Scheme {
+	case "s3":
+		store, err = NewS3(http.DefaultClient)
+	case "gs":
+		store, err = NewGCS(http.DefaultClient)
+	default:
+		err = fmt.Errorf("unsupported upload scheme %v", target.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Uploader{target: target, store: store, metadata: metadata}, nil
+}
+
+// UploadFile uploads the file at filename to the target. The key of the object
+// is the target prefix joined with the base name of the file. Returns the URL
+// of the uploaded object.
+func (u *Uploader) UploadFile(ctx context.Context, filename string) (string, error) {
+	body, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	key := path.Join(u.target.Prefix, filepath.Base(filename))
+	obj := Object{
+		Key:         key,
+		Body:        body,
+		ContentType: contentType(filename),
+		Metadata:    u.metadata,
+	}
+	if err := u.store.Put(ctx, u.target.Bucket, obj); err != nil {
+		return "", fmt.Errorf("failed to upload %v: %w", filename, err)
+	}
+	return u.target.Scheme + "://" + u.target.Bucket + "/" + key, nil
+}
+
+// contentTypes are the content types of the files written by gotestsum. The
+// types from the mime package depend on the system, so these are used first.
+var contentTypes = map[string]string{
+	".json": "application/json",
+	".xml":  "application/xml",
+	".html": "text/html; charset=utf-8",
+	".txt":  "text/plain; charset=utf-8",
+	".log":  "text/plain; charset=utf-8",
+}
+
+func contentType(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if t, ok := contentTypes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// maxErrorBodySize is the maximum number of bytes of the body of an error
+// response that are included in the error.
+const maxErrorBodySize = 4096
+
+// checkResponse returns an error if the status code of resp is not 2xx. The
+// error includes the body of the response, which describes the reason for the
+// error, and wraps os.ErrNotExist when the status code is 404.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	raw, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize+1))
+	body := strings.TrimSpace(string(raw))
+	if len(raw) > maxErrorBodySize {
+		body = strings.TrimSpace(string(raw[:maxErrorBodySize])) + "..."
+	}
+	if body == "" {
+		body = "empty response body"
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("unexpected response %v: %s: %w", resp.Status, body, os.ErrNotExist)
+	}
+	return fmt.Errorf("unexpected response %v: %s", resp.Status, body)
+}
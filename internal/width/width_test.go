@@ -0,0 +1,45 @@
+package width
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestString(t *testing.T) {
+	var testCases = []struct {
+		input    string
+		expected int
+	}{
+		{input: "", expected: 0},
+		{input: "example.com/pkg", expected: 15},
+		{input: "·✖↷", expected: 3},
+		{input: "テスト", expected: 6},
+		{input: "⏳ ", expected: 3},
+		{input: "🔥", expected: 2},
+		{input: "é", expected: 1},
+		{input: "❤️", expected: 1},
+		{input: "👍🏽", expected: 2},
+		{input: "\x1b", expected: 0},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, String(tc.input), tc.expected, "input: %q", tc.input)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	var testCases = []struct {
+		input    string
+		n        int
+		expected string
+	}{
+		{input: "example.com/pkg", n: 20, expected: "example.com/pkg"},
+		{input: "example.com/pkg", n: 7, expected: "example"},
+		{input: "テスト", n: 5, expected: "テス"},
+		{input: "テスト", n: 1, expected: ""},
+		{input: "abc", n: 0, expected: ""},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, Truncate(tc.input, tc.n), tc.expected, "input: %q", tc.input)
+	}
+}
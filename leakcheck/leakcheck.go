@@ -0,0 +1,107 @@
+/*Package leakcheck reports the goroutines and file descriptors leaked by a
+test, so that gotestsum can list the tests which leak in the summary, and in
+the JUnit XML.
+
+The check is only enabled when the tests are run by gotestsum with
+--leak-check, which sets the GOTESTSUM_LEAK_CHECK environment variable. When
+the check is not enabled Check does nothing.
+
+    func TestServer(t *testing.T) {
+        defer leakcheck.Check(t)()
+        ...
+    }
+
+A leak is reported with a line of test output that starts with
+testjson.LeakMarker. The number of goroutines and file descriptors are counted
+for the whole process, so the check may report the wrong test when it is used
+by tests which run in parallel. File descriptors are only counted on Linux.
+*/
+package leakcheck // import "gotest.tools/gotestsum/leakcheck"
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// EnvVar is the environment variable which enables the check.
+const EnvVar = "GOTESTSUM_LEAK_CHECK"
+
+// marker must match testjson.LeakMarker. It is not imported, so that tests do
+// not depend on the testjson package.
+const marker = "gotestsum-leak:"
+
+// Timeout is the longest time Check waits for the goroutines and file
+// descriptors started by the test to end, before it reports them as leaked.
+var Timeout = 5 * time.Second
+
+// Check counts the goroutines and file descriptors when the test starts. The
+// returned function must be called when the test ends, usually with defer. It
+// logs the resources that were not released by the test.
+func Check(t testing.TB) func() {
+	if os.Getenv(EnvVar) == "" {
+		return func() {}
+	}
+	before := count()
+	return func() {
+		t.Helper()
+		if leaks := waitForRelease(before, Timeout); leaks != "" {
+			t.Log(marker + " " + leaks)
+		}
+	}
+}
+
+type resources struct {
+	goroutines int
+	// fds is the number of open file descriptors, or -1 when they can not be
+	// counted.
+	fds int
+}
+
+func count() resources {
+	return resources{goroutines: runtime.NumGoroutine(), fds: countFDs()}
+}
+
+// countFDs returns the number of open file descriptors of the process, or -1
+// when they can not be counted.
+func countFDs() int {
+	dir, err := os.Open("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	defer dir.Close() // nolint: errcheck
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return -1
+	}
+	return len(names)
+}
+
+// waitForRelease returns the leaks, formatted as kind=count pairs, or an empty
+// string when no resources were leaked before the timeout.
+func waitForRelease(before resources, timeout time.Duration) string {
+	deadline := time.Now().Add(timeout)
+	for {
+		leaks := leaked(before, count())
+		if leaks == "" || time.Now().After(deadline) {
+			return leaks
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func leaked(before, after resources) string {
+	var leaks []string
+	if n := after.goroutines - before.goroutines; n > 0 {
+		leaks = append(leaks, fmt.Sprintf("goroutines=%d", n))
+	}
+	if before.fds >= 0 && after.fds >= 0 {
+		if n := after.fds - before.fds; n > 0 {
+			leaks = append(leaks, fmt.Sprintf("fds=%d", n))
+		}
+	}
+	return strings.Join(leaks, ",")
+}
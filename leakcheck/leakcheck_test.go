@@ -0,0 +1,67 @@
+package leakcheck
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestMarker(t *testing.T) {
+	assert.Equal(t, marker, testjson.LeakMarker)
+}
+
+func TestLeaked(t *testing.T) {
+	before := resources{goroutines: 3, fds: 10}
+	assert.Equal(t, leaked(before, resources{goroutines: 2, fds: 10}), "")
+	assert.Equal(t, leaked(before, resources{goroutines: 5, fds: 11}), "goroutines=2,fds=1")
+	assert.Equal(t, leaked(resources{goroutines: 1, fds: -1}, resources{goroutines: 1, fds: 3}), "")
+}
+
+func TestWaitForRelease(t *testing.T) {
+	stop := make(chan struct{})
+	before := count()
+	go func() {
+		<-stop
+	}()
+	assert.Equal(t, waitForRelease(before, 0), "goroutines=1")
+
+	close(stop)
+	assert.Equal(t, waitForRelease(before, 5*time.Second), "")
+}
+
+func TestCheck_Disabled(t *testing.T) {
+	defer env.Patch(t, EnvVar, "")()
+	fake := &fakeT{}
+	Check(fake)()
+	assert.Equal(t, len(fake.logs), 0)
+}
+
+type fakeT struct {
+	testing.TB
+	logs []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Log(args ...interface{}) {
+	t.logs = append(t.logs, args[0].(string))
+}
+
+func TestCheck(t *testing.T) {
+	defer env.Patch(t, EnvVar, "1")()
+	defer func(timeout time.Duration) { Timeout = timeout }(Timeout)
+	Timeout = 0
+
+	fake := &fakeT{}
+	done := Check(fake)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		<-stop
+	}()
+	done()
+	assert.DeepEqual(t, fake.logs, []string{"gotestsum-leak: goroutines=1"})
+}
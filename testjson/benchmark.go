@@ -0,0 +1,190 @@
+package testjson
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BenchmarkResult is the result line printed by 'go test -bench' when a
+// benchmark finished.
+type BenchmarkResult struct {
+	// Iterations is the number of times the benchmark function ran, b.N.
+	Iterations int
+	// Metrics are the measurements of the benchmark, in the order they were
+	// printed. Ex: ns/op, B/op, and metrics added with b.ReportMetric.
+	Metrics []BenchmarkMetric
+}
+
+// BenchmarkMetric is one measurement of a benchmark.
+type BenchmarkMetric struct {
+	Value float64
+	Unit  string
+}
+
+// FuzzResult is the progress of a fuzz test run with 'go test -fuzz'.
+type FuzzResult struct {
+	// Execs is the number of inputs run by the fuzzer.
+	Execs int64
+	// NewInteresting is the number of inputs added to the corpus because
+	// they expanded the code coverage.
+	NewInteresting int
+	// Corpus is the total number of inputs in the corpus.
+	Corpus int
+	// FailingInput is the file the fuzzer wrote the failing input to, when
+	// the fuzz test failed.
+	FailingInput string
+}
+
+// benchmarkProcsSuffix matches the -GOMAXPROCS suffix that 'go test' adds to
+// the name of a benchmark in its result line.
+var benchmarkProcsSuffix = regexp.MustCompile(`-\d+$`)
+
+// parseBenchmarkResult returns the name and the result from a benchmark result
+// line, or false if the line is not a result line. A result line is the name
+// of the benchmark, followed by the number of iterations, followed by pairs of
+// value and unit:
+//
+//	BenchmarkEncode-8   	  300000	      4120 ns/op	    1024 B/op
+func parseBenchmarkResult(line string) (string, BenchmarkResult, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || len(fields)%2 != 0 || !strings.HasPrefix(fields[0], "Benchmark") {
+		return "", BenchmarkResult{}, false
+	}
+	iterations, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", BenchmarkResult{}, false
+	}
+	result := BenchmarkResult{Iterations: iterations}
+	for i := 2; i < len(fields); i += 2 {
+		value, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return "", BenchmarkResult{}, false
+		}
+		result.Metrics = append(result.Metrics, BenchmarkMetric{Value: value, Unit: fields[i+1]})
+	}
+	return fields[0], result, true
+}
+
+// isBenchmarkResultFor returns true when name, from a benchmark result line,
+// is the name of test, with or without the -GOMAXPROCS suffix.
+func isBenchmarkResultFor(name string, test string) bool {
+	return name == test || benchmarkProcsSuffix.ReplaceAllString(name, "") == test
+}
+
+// fuzzProgressPattern matches the progress line printed by the fuzzer.
+var fuzzProgressPattern = regexp.MustCompile(
+	`^fuzz: elapsed: \S+, execs: (\d+) \(\S+\), new interesting: (\d+) \(total: (\d+)\)`)
+
+const fuzzFailingInputPrefix = "Failing input written to "
+
+// parseFuzzOutput returns the result updated by a line of output from a fuzz
+// test, or false if the line is not a progress line or the path of the failing
+// input.
+func parseFuzzOutput(result *FuzzResult, line string) (*FuzzResult, bool) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, fuzzFailingInputPrefix) {
+		updated := FuzzResult{}
+		if result != nil {
+			updated = *result
+		}
+		updated.FailingInput = strings.TrimPrefix(line, fuzzFailingInputPrefix)
+		return &updated, true
+	}
+	match := fuzzProgressPattern.FindStringSubmatch(line)
+	if match == nil {
+		return result, false
+	}
+	updated := FuzzResult{}
+	if result != nil {
+		updated = *result
+	}
+	updated.Execs, _ = strconv.ParseInt(match[1], 10, 64)
+	updated.NewInteresting, _ = strconv.Atoi(match[2])
+	updated.Corpus, _ = strconv.Atoi(match[3])
+	return &updated, true
+}
+
+// IsBenchmark returns true if the name is the name of a benchmark, or of a
+// sub-benchmark.
+func (n TestName) IsBenchmark() bool {
+	return strings.HasPrefix(string(n), "Benchmark")
+}
+
+// addPackageBenchmarkResult adds a passed TestCase for a benchmark result line
+// in the output of the package. Versions of 'go test' before go1.20 do not
+// send events for benchmarks, and print the result line as package output.
+func (p *Package) addPackageBenchmarkResult(event TestEvent) {
+	name, result, ok := parseBenchmarkResult(event.Output)
+	if !ok {
+		return
+	}
+	tc := p.newTestCaseFromEvent(TestEvent{
+		Package: event.Package,
+		Test:    benchmarkProcsSuffix.ReplaceAllString(name, ""),
+		RunID:   event.RunID,
+		Time:    event.Time,
+	})
+	tc.Benchmark = &result
+	p.Passed = append(p.Passed, tc)
+}
+
+// endBenchmarks moves the benchmarks which are still running when the package
+// ends to Passed. 'go test' does not send a pass event for a benchmark, so a
+// benchmark passed when it printed a result, or when it has sub-benchmarks
+// which passed, and none which failed. The elapsed time of a benchmark is the
+// time from its run event until its result line, or until the result line of
+// its last sub-benchmark.
+func (p *Package) endBenchmarks() {
+	var running []TestCase
+	for _, tc := range p.running {
+		if tc.Test.IsBenchmark() {
+			running = append(running, tc)
+		}
+	}
+	// Sub-benchmarks start after their parent, so they are ended first.
+	sort.Slice(running, func(i, j int) bool {
+		return running[i].ID > running[j].ID
+	})
+
+	subEnd := make(map[string]time.Time)
+	var ended []TestCase // nolint: prealloc
+	for _, tc := range running {
+		end, hasSubs := subEnd[tc.Test.Name()]
+		switch {
+		case tc.Benchmark != nil:
+			end = tc.Time.Add(tc.Elapsed)
+		case !hasSubs || tc.hasSubTestFailed:
+			continue
+		case !tc.Time.IsZero() && !end.IsZero():
+			tc.Elapsed = end.Sub(tc.Time)
+		}
+		if i := strings.LastIndex(tc.Test.Name(), "/"); i > 0 {
+			parent := tc.Test.Name()[:i]
+			if last, ok := subEnd[parent]; !ok || end.After(last) {
+				subEnd[parent] = end
+			}
+		}
+		delete(p.running, tc.Test.Name())
+		ended = append(ended, tc)
+	}
+
+	for i := len(ended) - 1; i >= 0; i-- {
+		tc := ended[i]
+		p.Passed = append(p.Passed, tc)
+		if !tc.Test.IsSubTest() && !p.keepPassedOutput {
+			p.removeOutput(tc.ID)
+		}
+	}
+}
+
+// elapsedBetween returns the time from start to end, or 0 if either time is
+// not set.
+func elapsedBetween(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}
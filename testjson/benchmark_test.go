@@ -0,0 +1,120 @@
+package testjson
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParseBenchmarkResult(t *testing.T) {
+	type testCase struct {
+		line     string
+		name     string
+		expected BenchmarkResult
+		ok       bool
+	}
+	for _, tc := range []testCase{
+		{
+			line: "BenchmarkEncode-8   \t  300000\t      4120 ns/op\t    1024 B/op\n",
+			name: "BenchmarkEncode-8",
+			expected: BenchmarkResult{
+				Iterations: 300000,
+				Metrics: []BenchmarkMetric{
+					{Value: 4120, Unit: "ns/op"},
+					{Value: 1024, Unit: "B/op"},
+				},
+			},
+			ok: true,
+		},
+		{
+			line: "BenchmarkParent/sub \t       5\t        44.40 ns/op\n",
+			name: "BenchmarkParent/sub",
+			expected: BenchmarkResult{
+				Iterations: 5,
+				Metrics:    []BenchmarkMetric{{Value: 44.4, Unit: "ns/op"}},
+			},
+			ok: true,
+		},
+		{line: "BenchmarkEncode\n"},
+		{line: "BenchmarkEncode   \t  many\t      4120 ns/op\n"},
+		{line: "BenchmarkEncode   \t  300000\t      4120\n"},
+		{line: "TestEncode   \t  300000\t      4120 ns/op\n"},
+	} {
+		t.Run(tc.line, func(t *testing.T) {
+			name, result, ok := parseBenchmarkResult(tc.line)
+			assert.Equal(t, ok, tc.ok)
+			assert.Equal(t, name, tc.name)
+			assert.DeepEqual(t, result, tc.expected)
+		})
+	}
+}
+
+func TestParseFuzzOutput(t *testing.T) {
+	result, ok := parseFuzzOutput(nil, "fuzz: elapsed: 0s, gathering baseline coverage: 0/1 completed\n")
+	assert.Assert(t, !ok)
+	assert.Assert(t, result == nil)
+
+	result, ok = parseFuzzOutput(nil, "fuzz: elapsed: 3s, execs: 1234 (411/sec), new interesting: 2 (total: 10)\n")
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, result, &FuzzResult{Execs: 1234, NewInteresting: 2, Corpus: 10})
+
+	result, ok = parseFuzzOutput(result, "    Failing input written to testdata/fuzz/FuzzBad/81476e3145e0ed8c\n")
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, result, &FuzzResult{
+		Execs:          1234,
+		NewInteresting: 2,
+		Corpus:         10,
+		FailingInput:   "testdata/fuzz/FuzzBad/81476e3145e0ed8c",
+	})
+}
+
+func TestExecution_Benchmarks(t *testing.T) {
+	in := `{"Time":"2022-01-01T00:00:00Z","Action":"run","Package":"example.com/a","Test":"BenchmarkFast"}
+{"Time":"2022-01-01T00:00:00Z","Action":"output","Package":"example.com/a","Test":"BenchmarkFast","Output":"BenchmarkFast\n"}
+{"Time":"2022-01-01T00:00:02Z","Action":"output","Package":"example.com/a","Test":"BenchmarkFast","Output":"BenchmarkFast-8 \t 10\t 11.20 ns/op\n"}
+{"Time":"2022-01-01T00:00:02Z","Action":"run","Package":"example.com/a","Test":"BenchmarkParent"}
+{"Time":"2022-01-01T00:00:02Z","Action":"run","Package":"example.com/a","Test":"BenchmarkParent/sub"}
+{"Time":"2022-01-01T00:00:05Z","Action":"output","Package":"example.com/a","Test":"BenchmarkParent/sub","Output":"BenchmarkParent/sub-8 \t 5\t 44.40 ns/op\t 3.000 widgets/op\n"}
+{"Time":"2022-01-01T00:00:05Z","Action":"run","Package":"example.com/a","Test":"BenchmarkFail"}
+{"Time":"2022-01-01T00:00:05Z","Action":"output","Package":"example.com/a","Test":"BenchmarkFail","Output":"    a_test.go:8: bad\n"}
+{"Time":"2022-01-01T00:00:05Z","Action":"fail","Package":"example.com/a","Test":"BenchmarkFail"}
+{"Time":"2022-01-01T00:00:05Z","Action":"run","Package":"example.com/a","Test":"FuzzParse"}
+{"Time":"2022-01-01T00:00:06Z","Action":"output","Package":"example.com/a","Test":"FuzzParse","Output":"fuzz: elapsed: 1s, execs: 20 (20/sec), new interesting: 1 (total: 3)\n"}
+{"Time":"2022-01-01T00:00:06Z","Action":"pass","Package":"example.com/a","Test":"FuzzParse","Elapsed":1}
+{"Time":"2022-01-01T00:00:06Z","Action":"fail","Package":"example.com/a","Elapsed":6}
+{"Time":"2022-01-01T00:00:00Z","Action":"output","Package":"example.com/old","Output":"BenchmarkOld-4   \t 100\t 120 ns/op\n"}
+{"Time":"2022-01-01T00:00:00Z","Action":"pass","Package":"example.com/old","Elapsed":1}
+`
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(in)})
+	assert.NilError(t, err)
+	assert.Equal(t, len(exec.Errors()), 0)
+
+	pkg := exec.Package("example.com/a")
+	assert.Equal(t, len(pkg.Failed), 1)
+	assert.Equal(t, pkg.Failed[0].Test, TestName("BenchmarkFail"))
+
+	var names []TestName
+	for _, tc := range pkg.Passed {
+		names = append(names, tc.Test)
+	}
+	expected := []TestName{"FuzzParse", "BenchmarkFast", "BenchmarkParent", "BenchmarkParent/sub"}
+	assert.DeepEqual(t, names, expected)
+
+	assert.DeepEqual(t, pkg.Passed[0].Fuzz, &FuzzResult{Execs: 20, NewInteresting: 1, Corpus: 3})
+	assert.DeepEqual(t, pkg.Passed[1].Benchmark, &BenchmarkResult{
+		Iterations: 10,
+		Metrics:    []BenchmarkMetric{{Value: 11.2, Unit: "ns/op"}},
+	})
+	assert.Equal(t, pkg.Passed[1].Elapsed, 2*time.Second)
+	assert.Assert(t, pkg.Passed[2].Benchmark == nil)
+	assert.Equal(t, pkg.Passed[2].Elapsed, 3*time.Second)
+	assert.Equal(t, pkg.Passed[3].Benchmark.Iterations, 5)
+	assert.Equal(t, pkg.Passed[3].Elapsed, 3*time.Second)
+
+	old := exec.Package("example.com/old")
+	assert.Equal(t, len(old.Passed), 1)
+	assert.Equal(t, old.Passed[0].Test, TestName("BenchmarkOld"))
+	assert.Equal(t, old.Passed[0].Benchmark.Metrics[0].Value, 120.0)
+}
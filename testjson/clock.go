@@ -0,0 +1,48 @@
+package testjson
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of the current time used by an Execution, for the time
+// of events without a Time, and for Execution.Elapsed.
+//
+// The default is the wall clock. Programs which embed gotestsum may use a fake
+// Clock in their tests, so that the summary and the formats print the same
+// durations on every run.
+type Clock interface {
+	Now() time.Time
+}
+
+// EventClock is a Clock which returns the Time of the most recent event added
+// to the Execution. When events are read from a file, instead of from a
+// running 'go test', an EventClock makes Execution.Elapsed the time between
+// the first and the last event, instead of the time spent reading the file.
+type EventClock struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+// NewEventClock returns an EventClock. Now returns the zero time until the
+// first event with a Time is added.
+func NewEventClock() *EventClock {
+	return &EventClock{}
+}
+
+// Now returns the Time of the most recent event.
+func (c *EventClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// observe an event time. Events from parallel packages are not always in
+// order, so the time never moves backwards.
+func (c *EventClock) observe(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.After(c.last) {
+		c.last = t
+	}
+}
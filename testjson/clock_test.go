@@ -0,0 +1,37 @@
+package testjson
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"gotest.tools/v3/assert"
+)
+
+const clockTestEvents = `{"Time":"2021-06-01T10:00:00Z","Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Time":"2021-06-01T10:00:05Z","Action":"pass","Package":"example.com/pkg","Test":"TestOne","Elapsed":5}
+{"Time":"2021-06-01T10:00:03Z","Action":"output","Package":"example.com/other","Output":"ok\n"}
+{"Time":"2021-06-01T10:00:06Z","Action":"pass","Package":"example.com/pkg","Elapsed":6}
+`
+
+func TestScanTestOutput_WithEventClock(t *testing.T) {
+	exec, err := ScanTestOutput(NewScanConfig(strings.NewReader(clockTestEvents),
+		WithClock(NewEventClock())))
+	assert.NilError(t, err)
+
+	started := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, exec.Started(), started)
+	assert.Equal(t, exec.Elapsed(), 6*time.Second)
+}
+
+func TestScanTestOutput_WithFakeClock(t *testing.T) {
+	fake := clockwork.NewFakeClock()
+	exec, err := ScanTestOutput(NewScanConfig(strings.NewReader(clockTestEvents),
+		WithClock(fake)))
+	assert.NilError(t, err)
+
+	assert.Equal(t, exec.Started(), fake.Now())
+	fake.Advance(2 * time.Second)
+	assert.Equal(t, exec.Elapsed(), 2*time.Second)
+}
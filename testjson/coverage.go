@@ -0,0 +1,138 @@
+package testjson
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var coveragePercentPattern = regexp.MustCompile(`^coverage: ([0-9.]+)% of statements`)
+
+// CoveragePercent returns the percent from the Coverage of the package, or
+// false if the package did not print its coverage.
+func (p *Package) CoveragePercent() (float64, bool) {
+	match := coveragePercentPattern.FindStringSubmatch(p.coverage)
+	if match == nil {
+		return 0, false
+	}
+	percent, err := strconv.ParseFloat(match[1], 64)
+	return percent, err == nil
+}
+
+// TotalCoverage is the coverage of all the packages in an Execution.
+type TotalCoverage struct {
+	// Percent of the statements that were covered.
+	Percent float64
+	// Packages is the number of packages which printed their coverage.
+	Packages int
+	// Statements and Covered are the number of statements, and the number of
+	// covered statements, in the cover profile. They are zero when there is no
+	// cover profile, and Percent is the average of the package percents.
+	Statements int
+	Covered    int
+}
+
+func (c TotalCoverage) String() string {
+	packages := "packages"
+	if c.Packages == 1 {
+		packages = "package"
+	}
+	if c.Statements == 0 {
+		return fmt.Sprintf("total coverage: %.1f%% average of %d %s", c.Percent, c.Packages, packages)
+	}
+	return fmt.Sprintf("total coverage: %.1f%% of %d statements in %d %s",
+		c.Percent, c.Statements, c.Packages, packages)
+}
+
+// TotalCoverage returns the coverage of all the packages which printed their
+// coverage, or false when no package printed its coverage, because the tests
+// did not run with -cover.
+//
+// When coverProfile is the path of the file written by go test -coverprofile,
+// the total is weighted by the number of statements in each package. Otherwise,
+// or when the file can not be read, the total is the average of the coverage
+// of each package.
+func (e *Execution) TotalCoverage(coverProfile string) (TotalCoverage, bool) {
+	var total TotalCoverage
+	var sum float64
+	for _, name := range e.Packages() {
+		percent, ok := e.Package(name).CoveragePercent()
+		if !ok {
+			continue
+		}
+		total.Packages++
+		sum += percent
+	}
+	if total.Packages == 0 {
+		return total, false
+	}
+	total.Percent = sum / float64(total.Packages)
+
+	if coverProfile == "" {
+		return total, true
+	}
+	statements, covered, err := readCoverProfile(coverProfile)
+	if err != nil || statements == 0 {
+		return total, true
+	}
+	total.Statements, total.Covered = statements, covered
+	total.Percent = float64(covered) * 100 / float64(statements)
+	return total, true
+}
+
+// readCoverProfile returns the number of statements, and the number of covered
+// statements, in the cover profile. Each line of the profile, after the mode
+// line, is a block of statements with the format:
+//
+//	name.go:line.column,line.column numberOfStatements count
+//
+// A block may be listed more than once, for example when -coverpkg is used, so
+// the statements of each block are counted once, and are covered when any of
+// the counts is more than zero.
+func readCoverProfile(path string) (statements int, covered int, err error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer fh.Close() // nolint: errcheck
+
+	type block struct {
+		statements int
+		covered    bool
+	}
+	blocks := make(map[string]*block)
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || strings.HasPrefix(fields[0], "mode:") {
+			continue
+		}
+		numStmts, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid cover profile line %q: %w", scanner.Text(), err)
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid cover profile line %q: %w", scanner.Text(), err)
+		}
+		b, ok := blocks[fields[0]]
+		if !ok {
+			b = &block{statements: numStmts}
+			blocks[fields[0]] = b
+		}
+		b.covered = b.covered || count > 0
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+	for _, b := range blocks {
+		statements += b.statements
+		if b.covered {
+			covered += b.statements
+		}
+	}
+	return statements, covered, nil
+}
@@ -0,0 +1,79 @@
+package testjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+const coverageEvents = `{"Action":"run","Package":"example.com/app/api","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/app/api","Test":"TestOne","Elapsed":0.1}
+{"Action":"output","Package":"example.com/app/api","Output":"coverage: 50.0% of statements\n"}
+{"Action":"pass","Package":"example.com/app/api","Elapsed":0.1}
+{"Action":"run","Package":"example.com/app/store","Test":"TestTwo"}
+{"Action":"pass","Package":"example.com/app/store","Test":"TestTwo","Elapsed":0.1}
+{"Action":"output","Package":"example.com/app/store","Output":"coverage: 100.0% of statements\n"}
+{"Action":"pass","Package":"example.com/app/store","Elapsed":0.1}
+{"Action":"output","Package":"example.com/app/tools","Output":"?   \texample.com/app/tools\t[no test files]\n"}
+{"Action":"skip","Package":"example.com/app/tools","Elapsed":0}
+`
+
+// coverProfile has 6 statements in api, of which 3 are covered, and 2
+// statements in store, which are covered. The first block of api is listed
+// twice, as it is with -coverpkg.
+const coverProfile = `mode: set
+example.com/app/api/api.go:3.20,5.2 2 1
+example.com/app/api/api.go:7.20,9.2 3 0
+example.com/app/api/api.go:11.20,13.2 1 1
+example.com/app/store/store.go:3.20,5.2 2 1
+example.com/app/api/api.go:3.20,5.2 2 0
+`
+
+func TestExecution_TotalCoverage(t *testing.T) {
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(coverageEvents)})
+	assert.NilError(t, err)
+
+	t.Run("average without a cover profile", func(t *testing.T) {
+		total, ok := exec.TotalCoverage("")
+		assert.Assert(t, ok)
+		assert.Equal(t, total, TotalCoverage{Percent: 75, Packages: 2})
+		assert.Equal(t, total.String(), "total coverage: 75.0% average of 2 packages")
+	})
+
+	t.Run("weighted by the cover profile", func(t *testing.T) {
+		file := fs.NewFile(t, t.Name(), fs.WithContent(coverProfile))
+		defer file.Remove()
+
+		total, ok := exec.TotalCoverage(file.Path())
+		assert.Assert(t, ok)
+		assert.Equal(t, total, TotalCoverage{Percent: 62.5, Packages: 2, Statements: 8, Covered: 5})
+		assert.Equal(t, total.String(), "total coverage: 62.5% of 8 statements in 2 packages")
+	})
+
+	t.Run("missing cover profile", func(t *testing.T) {
+		total, ok := exec.TotalCoverage("does-not-exist.out")
+		assert.Assert(t, ok)
+		assert.Equal(t, total, TotalCoverage{Percent: 75, Packages: 2})
+	})
+
+	t.Run("without coverage", func(t *testing.T) {
+		exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(
+			`{"Action":"pass","Package":"example.com/app/api","Elapsed":0.1}` + "\n")})
+		assert.NilError(t, err)
+		_, ok := exec.TotalCoverage("")
+		assert.Assert(t, !ok)
+	})
+}
+
+func TestPrintSummary_TotalCoverage(t *testing.T) {
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(coverageEvents)})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	PrintSummary(out, exec, SummarizeNone)
+	assert.Assert(t, strings.HasSuffix(out.String(), " in 0.000s\ntotal coverage: 75.0% average of 2 packages\n"),
+		out.String())
+}
@@ -0,0 +1,93 @@
+package testjson
+
+import (
+	"bytes"
+	"io"
+	"sort"
+)
+
+// Flusher is implemented by an EventHandler or EventFormatter which buffers
+// output until Flush is called.
+type Flusher interface {
+	Flush() error
+}
+
+// sortedFormatter buffers the output of a formatter for each package, and
+// writes the output of every package, sorted by package name, when Flush is
+// called. It is used by FormatOptions.Deterministic so that the order of the
+// output does not depend on the order in which packages finished.
+type sortedFormatter struct {
+	out       io.Writer
+	formatter EventFormatter
+	// current is the buffer that formatter writes to. It is set to the buffer
+	// for the package of each event before the event is formatted.
+	current *switchWriter
+	pkgs    map[string]*bytes.Buffer
+}
+
+func newSortedFormatter(out io.Writer, format string, opts FormatOptions) EventFormatter {
+	if format == "dots-v2" {
+		// dots-v2 redraws the terminal for each event, which can not be sorted
+		format = "dots-v1"
+	}
+	current := &switchWriter{}
+	formatter := newFoldableEventFormatter(current, format, opts)
+	if formatter == nil {
+		return nil
+	}
+	return &sortedFormatter{
+		out:       out,
+		formatter: formatter,
+		current:   current,
+		pkgs:      make(map[string]*bytes.Buffer),
+	}
+}
+
+func (f *sortedFormatter) Format(event TestEvent, exec *Execution) error {
+	buf, ok := f.pkgs[event.Package]
+	if !ok {
+		buf = new(bytes.Buffer)
+		f.pkgs[event.Package] = buf
+	}
+	f.current.out = buf
+	return f.formatter.Format(event, exec)
+}
+
+// Flush writes the buffered output for all packages to out, sorted by package
+// name.
+func (f *sortedFormatter) Flush() error {
+	names := make([]string, 0, len(f.pkgs))
+	for name := range f.pkgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := f.out.Write(f.pkgs[name].Bytes()); err != nil {
+			return err
+		}
+	}
+	f.pkgs = make(map[string]*bytes.Buffer)
+	return nil
+}
+
+type switchWriter struct {
+	out io.Writer
+}
+
+func (w *switchWriter) Write(p []byte) (int, error) {
+	return w.out.Write(p)
+}
+
+// sortTestCases sorts tcs by package, then test name, then run ID.
+func sortTestCases(tcs []TestCase) {
+	sort.SliceStable(tcs, func(i, j int) bool {
+		a, b := tcs[i], tcs[j]
+		switch {
+		case a.Package != b.Package:
+			return a.Package < b.Package
+		case a.Test != b.Test:
+			return a.Test < b.Test
+		}
+		return a.RunID < b.RunID
+	})
+}
@@ -0,0 +1,67 @@
+package testjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+type flushingHandler struct {
+	formatter EventFormatter
+}
+
+func (h *flushingHandler) Event(event TestEvent, exec *Execution) error {
+	return h.formatter.Format(event, exec)
+}
+
+func (h *flushingHandler) Err(string) error {
+	return nil
+}
+
+func (h *flushingHandler) Flush() error {
+	return h.formatter.(Flusher).Flush()
+}
+
+func TestNewEventFormatter_Deterministic(t *testing.T) {
+	_, reset := patchClock()
+	defer reset()
+
+	stdout := `{"Package":"example.com/b","Test":"TestTwo","Action":"run"}
+{"Package":"example.com/a","Test":"TestOne","Action":"run"}
+{"Package":"example.com/b","Test":"TestTwo","Action":"fail","Elapsed":0.3}
+{"Package":"example.com/b","Action":"fail","Elapsed":0.31}
+{"Package":"example.com/a","Test":"TestZed","Action":"run"}
+{"Package":"example.com/a","Test":"TestZed","Action":"fail","Elapsed":0.1}
+{"Package":"example.com/a","Test":"TestOne","Action":"fail","Elapsed":0.2}
+{"Package":"example.com/a","Action":"fail","Elapsed":0.21}
+`
+	opts := FormatOptions{Deterministic: true, ShowBuildTime: true}
+	out := new(bytes.Buffer)
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  strings.NewReader(stdout),
+		Handler: &flushingHandler{formatter: NewEventFormatterWithOptions(out, "testname", opts)},
+	})
+	assert.NilError(t, err)
+
+	expected := `FAIL example.com/a.TestZed (0.00s)
+FAIL example.com/a.TestOne (0.00s)
+FAIL example.com/a
+FAIL example.com/b.TestTwo (0.00s)
+FAIL example.com/b
+`
+	assert.Equal(t, out.String(), expected)
+
+	out.Reset()
+	PrintSummaryWithOptions(out, exec, SummarizeFailed, opts)
+	expected = `
+=== Failed
+=== FAIL: example.com/a TestOne (0.00s)
+=== FAIL: example.com/a TestZed (0.00s)
+=== FAIL: example.com/b TestTwo (0.00s)
+
+DONE 3 tests, 3 failures in 0.000s
+`
+	assert.Equal(t, out.String(), expected)
+}
@@ -0,0 +1,112 @@
+package testjson
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DurationFormat is the format used to print elapsed times.
+type DurationFormat int
+
+const (
+	// DurationDefault uses the format that each output has always used. The
+	// summary and the testname format use seconds, and the pkgname formats
+	// use units.
+	DurationDefault DurationFormat = iota
+	// DurationSeconds prints elapsed times as a number of seconds, ex: 83.40s.
+	DurationSeconds
+	// DurationUnits prints elapsed times with units, ex: 1m23.4s.
+	DurationUnits
+)
+
+var durationFormatValues = map[string]DurationFormat{
+	"default": DurationDefault,
+	"seconds": DurationSeconds,
+	"units":   DurationUnits,
+}
+
+// NewDurationFormat returns a DurationFormat from a string value. If the
+// string does not match any known values returns false for the second value.
+func NewDurationFormat(value string) (DurationFormat, bool) {
+	f, ok := durationFormatValues[value]
+	return f, ok
+}
+
+func (f DurationFormat) String() string {
+	for name, value := range durationFormatValues {
+		if value == f {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// alignedDurationWidth is the width that elapsed times are padded to when
+// FormatOptions.AlignDurations is set.
+const alignedDurationWidth = 8
+
+// formatDuration formats d using the DurationFormat from the options, or
+// defaultFormat if the options use DurationDefault. precision is the number of
+// digits after the decimal point of the seconds.
+func (o FormatOptions) formatDuration(d time.Duration, precision int, defaultFormat DurationFormat) string {
+	if d == neverFinished {
+		return o.align("unknown")
+	}
+	if o.Deterministic {
+		d = 0
+	}
+	format := o.DurationFormat
+	if format == DurationDefault {
+		format = defaultFormat
+	}
+
+	var result string
+	switch format {
+	case DurationUnits:
+		result = d.Round(time.Duration(pow10(9 - precision))).String()
+	default:
+		result = fmt.Sprintf("%.[2]*[1]fs", d.Seconds(), precision)
+	}
+	if o.DecimalSeparator != "" {
+		result = strings.Replace(result, ".", o.DecimalSeparator, 1)
+	}
+	return o.align(result)
+}
+
+func (o FormatOptions) align(v string) string {
+	if !o.AlignDurations || len(v) >= alignedDurationWidth {
+		return v
+	}
+	return strings.Repeat(" ", alignedDurationWidth-len(v)) + v
+}
+
+func pow10(n int) int64 {
+	result := int64(1)
+	for i := 0; i < n; i++ {
+		result *= 10
+	}
+	return result
+}
+
+// formatCount formats n with FormatOptions.ThousandsSeparator between each
+// group of three digits.
+func (o FormatOptions) formatCount(n int) string {
+	digits := strconv.Itoa(n)
+	if o.ThousandsSeparator == "" {
+		return digits
+	}
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(o.ThousandsSeparator)
+		}
+		b.WriteRune(d)
+	}
+	return sign + b.String()
+}
@@ -0,0 +1,111 @@
+package testjson
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestFormatOptions_FormatDuration(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		opts          FormatOptions
+		duration      time.Duration
+		precision     int
+		defaultFormat DurationFormat
+		expected      string
+	}{
+		{
+			name:          "default seconds",
+			duration:      83400 * time.Millisecond,
+			precision:     2,
+			defaultFormat: DurationSeconds,
+			expected:      "83.40s",
+		},
+		{
+			name:          "default units",
+			duration:      83400 * time.Millisecond,
+			precision:     3,
+			defaultFormat: DurationUnits,
+			expected:      "1m23.4s",
+		},
+		{
+			name:          "units",
+			opts:          FormatOptions{DurationFormat: DurationUnits},
+			duration:      83456 * time.Millisecond,
+			precision:     2,
+			defaultFormat: DurationSeconds,
+			expected:      "1m23.46s",
+		},
+		{
+			name:          "seconds",
+			opts:          FormatOptions{DurationFormat: DurationSeconds},
+			duration:      83456 * time.Millisecond,
+			precision:     3,
+			defaultFormat: DurationUnits,
+			expected:      "83.456s",
+		},
+		{
+			name:          "decimal separator",
+			opts:          FormatOptions{DecimalSeparator: ","},
+			duration:      1500 * time.Millisecond,
+			precision:     2,
+			defaultFormat: DurationSeconds,
+			expected:      "1,50s",
+		},
+		{
+			name:          "aligned",
+			opts:          FormatOptions{AlignDurations: true},
+			duration:      1500 * time.Millisecond,
+			precision:     2,
+			defaultFormat: DurationSeconds,
+			expected:      "   1.50s",
+		},
+		{
+			name:          "never finished",
+			duration:      neverFinished,
+			precision:     2,
+			defaultFormat: DurationSeconds,
+			expected:      "unknown",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := tc.opts.formatDuration(tc.duration, tc.precision, tc.defaultFormat)
+			assert.Equal(t, actual, tc.expected)
+		})
+	}
+}
+
+func TestFormatOptions_FormatCount(t *testing.T) {
+	opts := FormatOptions{ThousandsSeparator: ","}
+	assert.Equal(t, opts.formatCount(7), "7")
+	assert.Equal(t, opts.formatCount(123), "123")
+	assert.Equal(t, opts.formatCount(1234), "1,234")
+	assert.Equal(t, opts.formatCount(1234567), "1,234,567")
+	assert.Equal(t, opts.formatCount(-1234), "-1,234")
+	assert.Equal(t, FormatOptions{}.formatCount(1234), "1234")
+}
+
+func TestPrintSummaryWithOptions(t *testing.T) {
+	_, reset := patchClock()
+	defer reset()
+
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout: bytes.NewReader(golden.Get(t, "go-test-json.out")),
+	})
+	assert.NilError(t, err)
+
+	opts := FormatOptions{
+		DurationFormat:     DurationUnits,
+		DecimalSeparator:   ",",
+		ThousandsSeparator: ".",
+		AlignDurations:     true,
+	}
+	buf := new(bytes.Buffer)
+	PrintSummaryWithOptions(buf, exec, SummarizeFailed, opts)
+	golden.Assert(t, buf.String(), "summary-with-format-options.out")
+}
@@ -18,5 +18,42 @@ prints the number of tests run.
     }
     fmt.Println("Ran %d tests", exec.Total())
 
+Use ScanTestOutputContext to end the scan when a context is cancelled, or its
+deadline is exceeded. A Handler which implements ContextEventHandler receives
+the context with each event.
+
+Embedding
+
+Programs which run go test themselves can use the same formats and summary as
+the gotestsum command. NewEventFormatterWithOptions creates any of the formats
+listed by Formats, NewFormatHandler sends the events to the formatter, and
+PrintSummaryWithOptions prints the summary of the Execution.
+
+    formatter := testjson.NewEventFormatterWithOptions(os.Stdout, "pkgname", testjson.FormatOptions{})
+    exec, err := testjson.ScanTestOutput(testjson.NewScanConfig(goTestStdout,
+        testjson.WithStderr(goTestStderr),
+        testjson.WithHandler(testjson.NewFormatHandler(formatter, os.Stderr))))
+    if err != nil {
+        return err
+    }
+    testjson.PrintSummaryWithOptions(os.Stdout, exec, testjson.SummarizeAll, testjson.FormatOptions{})
+
+WithClock sets the Clock used for the elapsed time of the run. Tests of a
+program which embeds gotestsum may use a fake Clock to print the same summary
+on every run, and EventClock measures the time from the events, for output
+read from a file.
+
+The testjsontest package has utilities to test a custom format, or handler,
+with a canned test2json stream, the same way the formats of this package are
+tested.
+
+Compatibility
+
+The exported identifiers of this package follow semantic versioning. They are
+not removed or changed in an incompatible way within a major version. New fields
+may be added to structs like ScanConfig, FormatOptions, and TestEvent, so
+create them with keyed fields, or with NewScanConfig and its ScanOptions. The
+text printed by each format, and the summary, may change in any version.
+
 */
 package testjson // import "gotest.tools/gotestsum/testjson"
@@ -10,34 +10,39 @@ import (
 
 	"golang.org/x/term"
 	"gotest.tools/gotestsum/internal/dotwriter"
+	"gotest.tools/gotestsum/internal/width"
 	"gotest.tools/gotestsum/log"
 )
 
-func dotsFormatV1(event TestEvent, exec *Execution) (string, error) {
-	pkg := exec.Package(event.Package)
-	switch {
-	case event.PackageEvent():
-		return "", nil
-	case event.Action == ActionRun && pkg.Total == 1:
-		return "[" + RelativePackagePath(event.Package) + "]", nil
+func dotsFormatV1(opts FormatOptions) func(event TestEvent, exec *Execution) (string, error) {
+	return func(event TestEvent, exec *Execution) (string, error) {
+		pkg := exec.Package(event.Package)
+		switch {
+		case event.PackageEvent():
+			return "", nil
+		case event.Action == ActionRun && pkg.Total == 1:
+			return "[" + RelativePackagePath(event.Package) + "]", nil
+		}
+		return fmtDot(opts, event), nil
 	}
-	return fmtDot(event), nil
 }
 
-func fmtDot(event TestEvent) string {
+func fmtDot(opts FormatOptions, event TestEvent) string {
 	withColor := colorEvent(event)
+	symbols := opts.symbols()
 	switch event.Action {
 	case ActionPass:
-		return withColor("·")
+		return withColor(symbols.dotPass)
 	case ActionFail:
-		return withColor("✖")
+		return withColor(symbols.dotFail)
 	case ActionSkip:
-		return withColor("↷")
+		return withColor(symbols.dotSkip)
 	}
 	return ""
 }
 
 type dotFormatter struct {
+	opts      FormatOptions
 	pkgs      map[string]*dotLine
 	order     []string
 	writer    *dotwriter.Writer
@@ -45,7 +50,8 @@ type dotFormatter struct {
 }
 
 type dotLine struct {
-	runes      int
+	// width is the number of columns used by the dots on the current line.
+	width      int
 	builder    *strings.Builder
 	lastUpdate time.Time
 }
@@ -55,25 +61,26 @@ func (l *dotLine) update(dot string) {
 		return
 	}
 	l.builder.WriteString(dot)
-	l.runes++
+	l.width += width.String(dot)
 }
 
 // checkWidth marks the line as full when the width of the line hits the
 // terminal width.
 func (l *dotLine) checkWidth(prefix, terminal int) {
-	if prefix+l.runes >= terminal {
+	if prefix+l.width >= terminal {
 		l.builder.WriteString("\n" + strings.Repeat(" ", prefix))
-		l.runes = 0
+		l.width = 0
 	}
 }
 
-func newDotFormatter(out io.Writer) EventFormatter {
+func newDotFormatter(out io.Writer, opts FormatOptions) EventFormatter {
 	w, _, err := term.GetSize(int(os.Stdout.Fd()))
 	if err != nil || w == 0 {
 		log.Warnf("Failed to detect terminal width for dots format, error: %v", err)
-		return &formatAdapter{format: dotsFormatV1, out: out}
+		return &formatAdapter{format: dotsFormatV1(opts), out: out}
 	}
 	return &dotFormatter{
+		opts:      opts,
 		pkgs:      make(map[string]*dotLine),
 		writer:    dotwriter.New(out),
 		termWidth: w,
@@ -89,7 +96,7 @@ func (d *dotFormatter) Format(event TestEvent, exec *Execution) error {
 	line.lastUpdate = event.Time
 
 	if !event.PackageEvent() {
-		line.update(fmtDot(event))
+		line.update(fmtDot(d.opts, event))
 	}
 	switch event.Action {
 	case ActionOutput, ActionBench:
@@ -104,10 +111,10 @@ func (d *dotFormatter) Format(event TestEvent, exec *Execution) error {
 		line := d.pkgs[pkg]
 		pkgname := RelativePackagePath(pkg) + " "
 		prefix := fmtDotElapsed(exec.Package(pkg))
-		line.checkWidth(len(prefix+pkgname), d.termWidth)
+		line.checkWidth(width.String(prefix+pkgname), d.termWidth)
 		fmt.Fprintf(d.writer, prefix+pkgname+line.builder.String()+"\n")
 	}
-	PrintSummary(d.writer, exec, SummarizeNone)
+	PrintSummaryWithOptions(d.writer, exec, SummarizeNone, d.opts)
 	return d.writer.Flush()
 }
 
@@ -119,7 +126,12 @@ func (d *dotFormatter) orderByLastUpdated(i, j int) bool {
 
 func fmtDotElapsed(p *Package) string {
 	f := func(v string) string {
-		return fmt.Sprintf(" %5s ", v)
+		// pad using the display width, because %5s pads by the number of runes
+		pad := 5 - width.String(v)
+		if pad < 0 {
+			pad = 0
+		}
+		return " " + strings.Repeat(" ", pad) + v + " "
 	}
 
 	elapsed := p.Elapsed()
@@ -147,7 +159,7 @@ func fmtDotElapsed(p *Package) string {
 
 	for _, trunc := range steps {
 		r := f(elapsed.Truncate(trunc).String())
-		if len(r) <= maxWidth {
+		if width.String(r) <= maxWidth {
 			return r
 		}
 	}
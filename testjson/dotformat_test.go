@@ -11,6 +11,7 @@ import (
 
 	"gotest.tools/gotestsum/internal/dotwriter"
 	"gotest.tools/gotestsum/internal/text"
+	"gotest.tools/gotestsum/internal/width"
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/assert/cmp"
 	"gotest.tools/v3/golden"
@@ -66,7 +67,7 @@ func TestFmtDotElapsed(t *testing.T) {
 		},
 		{
 			elapsed:  3 * time.Hour,
-			expected: "    ⏳  ",
+			expected: "   ⏳  ",
 		},
 		{
 			elapsed:  14 * time.Millisecond,
@@ -109,7 +110,7 @@ func TestFmtDotElapsed(t *testing.T) {
 				elapsed: tc.elapsed,
 			}
 			actual := fmtDotElapsed(pkg)
-			assert.Check(t, cmp.Equal(utf8.RuneCountInString(actual), 7))
+			assert.Check(t, cmp.Equal(width.String(actual), 7))
 			assert.Equal(t, actual, tc.expected)
 		})
 	}
@@ -139,7 +140,7 @@ func TestFmtDotElapsed_RuneCountProperty(t *testing.T) {
 
 func TestNewDotFormatter(t *testing.T) {
 	buf := new(bytes.Buffer)
-	ef := newDotFormatter(buf)
+	ef := newDotFormatter(buf, FormatOptions{})
 
 	d, ok := ef.(*dotFormatter)
 	skip.If(t, !ok, "no terminal width")
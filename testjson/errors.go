@@ -0,0 +1,57 @@
+package testjson
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrMalformedEvent is the error matched by errors.Is for any
+// MalformedEventError.
+var ErrMalformedEvent = errors.New("malformed test event")
+
+// MalformedEventError is returned by ScanTestOutput when a line read from
+// ScanConfig.Stdout is not a valid TestEvent.
+type MalformedEventError struct {
+	// Line is the line number, starting from 1, of the malformed line.
+	Line int
+	// Raw is the text of the malformed line.
+	Raw string
+	// Err is the error returned when parsing the line.
+	Err error
+}
+
+func (e *MalformedEventError) Error() string {
+	return fmt.Sprintf("failed to parse test output: %s: %v", e.Raw, e.Err)
+}
+
+func (e *MalformedEventError) Unwrap() error {
+	return e.Err
+}
+
+// Is returns true if target is ErrMalformedEvent.
+func (e *MalformedEventError) Is(target error) bool {
+	return target == ErrMalformedEvent
+}
+
+// HandlerError is returned by ScanTestOutput when the EventHandler in
+// ScanConfig returns an error.
+type HandlerError struct {
+	// Handler is the EventHandler which returned the error.
+	Handler EventHandler
+	// Event is the TestEvent passed to Handler.Event, or nil if the error was
+	// returned by Handler.Err.
+	Event *TestEvent
+	// Err is the error returned by the Handler.
+	Err error
+}
+
+func (e *HandlerError) Error() string {
+	if e.Event == nil {
+		return fmt.Sprintf("failed to handle stderr: %v", e.Err)
+	}
+	return e.Err.Error()
+}
+
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,57 @@
+package testjson
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestScanTestOutput_MalformedEventError(t *testing.T) {
+	cfg := ScanConfig{
+		Stdout: bytes.NewReader(golden.Get(t, "go-test-json-with-nonjson-stdout.out")),
+	}
+	_, err := ScanTestOutput(cfg)
+	assert.Assert(t, errors.Is(err, ErrMalformedEvent), err)
+
+	var malformed *MalformedEventError
+	assert.Assert(t, errors.As(err, &malformed))
+	assert.Equal(t, malformed.Line, 2)
+	assert.Equal(t, malformed.Raw, "|||This line is not valid test2json output.|||")
+}
+
+func TestScanTestOutput_HandlerError(t *testing.T) {
+	t.Run("event", func(t *testing.T) {
+		handler := &handlerFails{}
+		cfg := ScanConfig{
+			Stdout:  bytes.NewReader(golden.Get(t, "go-test-json.out")),
+			Handler: handler,
+		}
+		_, err := ScanTestOutput(cfg)
+		assert.Error(t, err, "something failed")
+
+		var handlerErr *HandlerError
+		assert.Assert(t, errors.As(err, &handlerErr))
+		assert.Equal(t, handlerErr.Handler, EventHandler(handler))
+		assert.Assert(t, handlerErr.Event != nil)
+		assert.Assert(t, !errors.Is(err, ErrMalformedEvent))
+	})
+
+	t.Run("stderr", func(t *testing.T) {
+		handler := &captureHandler{}
+		cfg := ScanConfig{
+			Stdout:  strings.NewReader(""),
+			Stderr:  strings.NewReader("build failed\n"),
+			Handler: handler,
+		}
+		_, err := ScanTestOutput(cfg)
+		assert.Error(t, err, "failed to handle stderr: build failed")
+
+		var handlerErr *HandlerError
+		assert.Assert(t, errors.As(err, &handlerErr))
+		assert.Assert(t, handlerErr.Event == nil)
+	})
+}
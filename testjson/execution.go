@@ -3,6 +3,7 @@ package testjson
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -30,8 +31,36 @@ const (
 	ActionFail   Action = "fail"
 	ActionOutput Action = "output"
 	ActionSkip   Action = "skip"
+	// ActionStart is sent by test2json from Go 1.20 or later when the test
+	// binary of a package starts.
+	ActionStart Action = "start"
+	// ActionBuildOutput and ActionBuildFail are sent by 'go test' from Go 1.24
+	// or later for the output of a package that failed to build, before the
+	// events of the package. The events have an ImportPath instead of a
+	// Package.
+	ActionBuildOutput Action = "build-output"
+	ActionBuildFail   Action = "build-fail"
 )
 
+// ActionRunStart is not output by test2json. gotestsum writes an event with
+// this action as the first line of a --jsonfile, with the ID of the run as the
+// Output of the event, and before the events of each --rerun-fails attempt,
+// with the number of the attempt as the RunID of the event. See RunStartEvent.
+const ActionRunStart Action = "run-start"
+
+// RunStartEvent returns the JSON encoding of the ActionRunStart event for
+// attempt of the run with runID, without a trailing newline. The first
+// attempt of a run is 0.
+func RunStartEvent(runID string, attempt int, now time.Time) []byte {
+	raw, _ := json.Marshal(struct {
+		Time   time.Time
+		Action Action
+		Output string
+		RunID  int `json:",omitempty"`
+	}{Time: now, Action: ActionRunStart, Output: runID, RunID: attempt})
+	return raw
+}
+
 // IsTerminal returns true if the Action is one of: pass, fail, skip.
 func (a Action) IsTerminal() bool {
 	switch a {
@@ -53,10 +82,17 @@ type TestEvent struct {
 	Elapsed float64
 	// Output of test or benchmark
 	Output string
+	// ImportPath of the package that is built by an ActionBuildOutput or
+	// ActionBuildFail event. Package is set from ImportPath when the event is
+	// parsed.
+	ImportPath string
 	// raw is the raw JSON bytes of the event
 	raw []byte
 	// RunID from the ScanConfig which produced this test event.
 	RunID int
+	// originalTest is the name of the test before it was rewritten by
+	// ScanConfig.RewriteTestName. It is empty if the name was not rewritten.
+	originalTest string
 }
 
 // PackageEvent returns true if the event is a package start or end event
@@ -70,6 +106,8 @@ func (e TestEvent) ElapsedFormatted() string {
 }
 
 // Bytes returns the serialized JSON bytes that were parsed to create the event.
+// If the name of the test was rewritten by ScanConfig.RewriteTestName the bytes
+// are the event re-encoded with the new name.
 func (e TestEvent) Bytes() []byte {
 	return e.raw
 }
@@ -84,6 +122,13 @@ type Package struct {
 
 	// elapsed time reported by the pass or fail event for the package.
 	elapsed time.Duration
+	// firstEvent is the time of the first TestEvent received for the package.
+	// The first event is sent once the test binary has started, so the time
+	// before it is spent building the package.
+	firstEvent time.Time
+	// lastEvent is the time of the most recent TestEvent received for the
+	// package.
+	lastEvent time.Time
 
 	// mapping of root TestCase ID to all sub test IDs. Used to mitigate
 	// github.com/golang/go/issues/29755, and github.com/golang/go/issues/40771.
@@ -93,6 +138,19 @@ type Package struct {
 	// output printed by test cases, indexed by TestCase.ID. Package output is
 	// saved with key 0.
 	output map[int][]string
+	// spill is the file used to store output after Execution.SpillOutput.
+	spill *outputSpill
+	// spilled is the output stored in spill, indexed by TestCase.ID. Lines in
+	// spilled are always older than the lines in output.
+	spilled map[int][]lineRef
+	// maxOutputLines is set by Execution.TruncateOutput.
+	maxOutputLines int
+	// truncated is the number of lines of output removed by
+	// Execution.TruncateOutput, indexed by TestCase.ID.
+	truncated map[int]int
+	// snapshots of the environment taken when a test failed, indexed by
+	// TestCase.ID. See SetSnapshot.
+	snapshots map[int]string
 	// coverage stores the code coverage output for the package without the
 	// trailing newline (ex: coverage: 91.1% of statements).
 	coverage string
@@ -107,6 +165,17 @@ type Package struct {
 	// github.com/golang/go/issues/45508. This field may be removed in the future
 	// if the issue is fixed in Go.
 	panicked bool
+	// exitedUnexpectedly is true if the events for the package ended without
+	// a pass or fail event for the package, or for a running test. This
+	// happens when the test binary exits early, for example when a test calls
+	// os.Exit, or the process crashes.
+	exitedUnexpectedly bool
+	// noTestFiles is true if the package received a skip event, which 'go test'
+	// sends for a package with no test files. The package did not exit
+	// unexpectedly, but its Result is not changed by the event.
+	noTestFiles bool
+	// keepPassedOutput is set from ScanConfig.KeepPassedOutput.
+	keepPassedOutput bool
 }
 
 // Result returns if the package passed, failed, or was skipped because there
@@ -121,6 +190,36 @@ func (p *Package) Elapsed() time.Duration {
 	return p.elapsed
 }
 
+// StartTime returns the time of the first TestEvent received for the package.
+// When events are read from 'go test' the first event is received once the
+// test binary for the package has started, so StartTime is approximately the
+// time the package finished building.
+func (p *Package) StartTime() time.Time {
+	return p.firstEvent
+}
+
+// EndTime returns the time of the last TestEvent received for the package.
+// Once the package has finished this is the time of the pass or fail event
+// for the package.
+func (p *Package) EndTime() time.Time {
+	return p.lastEvent
+}
+
+// Coverage returns the code coverage output for the package, without the
+// trailing newline (ex: coverage: 91.1% of statements). Coverage returns an
+// empty string when the tests were not run with -cover.
+func (p *Package) Coverage() string {
+	return p.coverage
+}
+
+// ExitedUnexpectedly returns true if the test binary for the package exited
+// before sending the events for all of its tests. This may happen when a test
+// calls os.Exit, or the process crashes. Tests which were running when the
+// process exited are reported as failed.
+func (p *Package) ExitedUnexpectedly() bool {
+	return p.exitedUnexpectedly
+}
+
 // TestCases returns all the test cases.
 func (p *Package) TestCases() []TestCase {
 	tc := append([]TestCase{}, p.Passed...)
@@ -148,7 +247,7 @@ func (p *Package) LastFailedByName(name string) TestCase {
 //
 // Unlike OutputLines() it does not return lines from subtests in some cases.
 func (p *Package) Output(id int) string {
-	return strings.Join(p.output[id], "")
+	return strings.Join(p.lines(id), "")
 }
 
 // OutputLines returns the full test output for a test as a slice of strings.
@@ -159,7 +258,7 @@ func (p *Package) Output(id int) string {
 // then all output for every subtest under the root test is returned.
 // See https://github.com/golang/go/issues/29755.
 func (p *Package) OutputLines(tc TestCase) []string {
-	lines := p.output[tc.ID]
+	lines := p.lines(tc.ID)
 
 	// If this is a subtest, or a root test case with subtest failures the
 	// subtest failure output should contain the relevant lines, so we don't need
@@ -171,7 +270,7 @@ func (p *Package) OutputLines(tc TestCase) []string {
 	result := make([]string, 0, len(lines)+1)
 	result = append(result, lines...)
 	for _, sub := range p.subTests[tc.ID] {
-		result = append(result, p.output[sub]...)
+		result = append(result, p.lines(sub)...)
 	}
 	return result
 }
@@ -180,8 +279,14 @@ func (p *Package) addOutput(id int, output string) {
 	if strings.HasPrefix(output, "panic: ") {
 		p.panicked = true
 	}
-	// TODO: limit size of buffered test output
-	p.output[id] = append(p.output[id], output)
+	if p.spill != nil {
+		p.output[id] = p.spillLines(id, append(p.output[id], output))
+	} else {
+		p.output[id] = append(p.output[id], output)
+	}
+	if max := p.maxOutputLines; max > 0 && len(p.spilled[id])+len(p.output[id]) > 2*max {
+		p.truncateOutput(id, max)
+	}
 }
 
 type TestName string
@@ -205,16 +310,22 @@ func (n TestName) Name() string {
 }
 
 func (p *Package) removeOutput(id int) {
-	delete(p.output, id)
+	p.deleteOutput(id)
 
 	skipped := tcIDSet(p.Skipped)
 	for _, sub := range p.subTests[id] {
 		if _, isSkipped := skipped[sub]; !isSkipped {
-			delete(p.output, sub)
+			p.deleteOutput(sub)
 		}
 	}
 }
 
+func (p *Package) deleteOutput(id int) {
+	delete(p.output, id)
+	delete(p.spilled, id)
+	delete(p.truncated, id)
+}
+
 func tcIDSet(skipped []TestCase) map[int]struct{} {
 	result := make(map[int]struct{})
 	for _, tc := range skipped {
@@ -229,6 +340,13 @@ func (p *Package) TestMainFailed() bool {
 	return p.action == ActionFail && len(p.Failed) == 0
 }
 
+// TeardownFailed returns true if the package failed after all of its tests
+// passed. This may occur if the cleanup in TestMain exited non-zero, or the
+// test binary failed after the last test finished.
+func (p *Package) TeardownFailed() bool {
+	return p.TestMainFailed() && len(p.Passed) > 0
+}
+
 const neverFinished time.Duration = -1
 
 // end adds any tests that were missing an ActionFail TestEvent to the list of
@@ -236,8 +354,13 @@ const neverFinished time.Duration = -1
 //
 // This is done to work around 'go test' not sending the ActionFail TestEvents
 // in some cases, when a test panics.
-func (p *Package) end() []TestEvent {
-	result := make([]TestEvent, 0, len(p.running))
+//
+// If detectExit is true, and the package did not receive a pass or fail event,
+// or had running tests that did not panic, the package is marked as exited
+// unexpectedly, and an artificial ActionFail TestEvent is returned for the
+// package.
+func (p *Package) end(name string, detectExit bool) []TestEvent {
+	result := make([]TestEvent, 0, len(p.running)+1)
 	for k, tc := range p.running {
 		if tc.Test.IsSubTest() && rootTestPassed(p, tc) {
 			// mitigate github.com/golang/go/issues/40771 (gotestsum/issues/141)
@@ -256,9 +379,48 @@ func (p *Package) end() []TestEvent {
 		})
 		delete(p.running, k)
 	}
+
+	if !detectExit {
+		return result
+	}
+	switch {
+	case p.action == "" && !p.noTestFiles:
+		p.action = ActionFail
+		p.exitedUnexpectedly = true
+		result = append(result, TestEvent{Action: ActionFail, Package: name})
+	case len(result) > 0 && !p.panicked:
+		p.exitedUnexpectedly = true
+	}
 	return result
 }
 
+// exitOutputLines is the number of lines of output from a package which exited
+// unexpectedly to include in the error for the package.
+const exitOutputLines = 5
+
+// lastRunningOutput returns the output of the running test which started
+// most recently, or the package output if there are no running tests.
+func (p *Package) lastRunningOutput() []string {
+	var last *TestCase
+	for _, tc := range p.running {
+		tc := tc
+		if last == nil || tc.ID > last.ID {
+			last = &tc
+		}
+	}
+	if last == nil {
+		return p.lines(0)
+	}
+	return p.lines(last.ID)
+}
+
+func lastLines(lines []string, n int) []string {
+	if len(lines) > n {
+		return lines[len(lines)-n:]
+	}
+	return lines
+}
+
 // rootTestPassed looks for the root test associated with subtest and returns
 // true if the root test passed. This is used to mitigate
 // github.com/golang/go/issues/40771 (gotestsum/issues/141) and may be removed
@@ -302,6 +464,31 @@ type TestCase struct {
 	hasSubTestFailed bool
 	// Time when the test was run.
 	Time time.Time
+	// originalName is the name of the test before it was rewritten by
+	// ScanConfig.RewriteTestName.
+	originalName TestName
+	// Labels added by the test with a line of output that starts with
+	// LabelMarker.
+	Labels []string
+	// Leaks reported by the test with a line of output that starts with
+	// LeakMarker.
+	Leaks []Leak
+	// Benchmark is the result printed by a benchmark, or nil if the test is
+	// not a benchmark, or did not finish.
+	Benchmark *BenchmarkResult
+	// Fuzz is the progress printed by a fuzz test run with -fuzz, or nil if
+	// the test did not run the fuzzer.
+	Fuzz *FuzzResult
+}
+
+// OriginalName returns the name of the test as it was reported by 'go test',
+// before it was rewritten by ScanConfig.RewriteTestName. The original name must
+// be used to select the test with 'go test -run'.
+func (tc TestCase) OriginalName() TestName {
+	if tc.originalName != "" {
+		return tc.originalName
+	}
+	return tc.Test
 }
 
 func newPackage() *Package {
@@ -320,14 +507,54 @@ type Execution struct {
 	errors     []string
 	done       bool
 	lastRunID  int
+	// demoteTeardownFailures is set from ScanConfig.DemoteTeardownFailures.
+	demoteTeardownFailures bool
+	// spill is set by SpillOutput.
+	spill *outputSpill
+	// maxOutputLines is set by TruncateOutput.
+	maxOutputLines int
+	// keepPassedOutput is set from ScanConfig.KeepPassedOutput.
+	keepPassedOutput bool
+	// firstFailure and firstFailureTime are set by the first fail event. See
+	// FirstFailure.
+	firstFailure     FirstFailure
+	firstFailureTime time.Time
+	// clock is set from ScanConfig.Clock. See Execution.now.
+	clock Clock
+	// errorEntries are the errors returned by ErrorEntries. They are guarded
+	// by errorsLock.
+	errorEntries []ErrorEntry
+	// buildErrors groups the lines of ActionBuildOutput events into
+	// errorEntries.
+	buildErrors *stderrErrors
 }
 
 func (e *Execution) add(event TestEvent) {
+	eventTime := event.Time
+	if eventClock, ok := e.clock.(*EventClock); ok && !eventTime.IsZero() {
+		eventClock.observe(eventTime)
+		if e.started.IsZero() {
+			e.started = eventTime
+		}
+	}
+	if eventTime.IsZero() {
+		eventTime = e.now()
+	}
+	if isBuildEvent(event) {
+		e.addBuildEvent(event)
+		return
+	}
 	pkg, ok := e.packages[event.Package]
 	if !ok {
 		pkg = newPackage()
+		pkg.firstEvent = eventTime
+		pkg.spill = e.spill
+		pkg.maxOutputLines = e.maxOutputLines
+		pkg.keepPassedOutput = e.keepPassedOutput
 		e.packages[event.Package] = pkg
 	}
+	pkg.lastEvent = eventTime
+	e.recordFailure(event, eventTime)
 	if event.PackageEvent() {
 		pkg.addEvent(event)
 		return
@@ -335,12 +562,36 @@ func (e *Execution) add(event TestEvent) {
 	pkg.addTestEvent(event)
 }
 
+func isBuildEvent(event TestEvent) bool {
+	return event.Action == ActionBuildOutput || event.Action == ActionBuildFail
+}
+
+// addBuildEvent adds the output of a package that failed to build to the
+// errors, the same as the output of the build on stderr from earlier versions
+// of Go. The package is not added to the Execution, because the events of the
+// package that follow include its result.
+func (e *Execution) addBuildEvent(event TestEvent) {
+	if event.Action != ActionBuildOutput {
+		return
+	}
+	if e.buildErrors == nil {
+		e.buildErrors = newStderrErrors()
+	}
+	line := strings.TrimRight(event.Output, "\n")
+	e.addError(line)
+	e.buildErrors.add(e, line)
+}
+
 func (p *Package) addEvent(event TestEvent) {
 	switch event.Action {
 	case ActionPass, ActionFail:
 		p.action = event.Action
 		p.elapsed = elapsedDuration(event.Elapsed)
+		p.endBenchmarks()
+	case ActionSkip:
+		p.noTestFiles = true
 	case ActionOutput:
+		p.addPackageBenchmarkResult(event)
 		if isCoverageOutput(event.Output) {
 			p.coverage = strings.TrimRight(event.Output, "\n")
 		}
@@ -361,6 +612,8 @@ func (p *Package) newTestCaseFromEvent(event TestEvent) TestCase {
 		ID:      p.Total,
 		RunID:   event.RunID,
 		Time:    event.Time,
+
+		originalName: TestName(event.originalTest),
 	}
 }
 
@@ -389,6 +642,28 @@ func (p *Package) addTestEvent(event TestEvent) {
 	case ActionOutput, ActionBench:
 		tc := p.running[event.Test]
 		p.addOutput(tc.ID, event.Output)
+		if labels := parseLabels(event.Output); len(labels) > 0 {
+			tc.Labels = addLabels(tc.Labels, labels)
+			p.running[event.Test] = tc
+		}
+		if leaks := parseLeaks(event.Output); len(leaks) > 0 {
+			tc.Leaks = addLeaks(tc.Leaks, leaks)
+			p.running[event.Test] = tc
+		}
+		if tc.Test.IsBenchmark() {
+			name, result, ok := parseBenchmarkResult(event.Output)
+			if ok && isBenchmarkResultFor(name, event.Test) {
+				tc.Benchmark = &result
+				tc.Elapsed = elapsedBetween(tc.Time, event.Time)
+				p.running[event.Test] = tc
+			}
+		}
+		if strings.HasPrefix(event.Test, "Fuzz") {
+			if fuzz, ok := parseFuzzOutput(tc.Fuzz, event.Output); ok {
+				tc.Fuzz = fuzz
+				p.running[event.Test] = tc
+			}
+		}
 		return
 	case ActionPause, ActionCont:
 		return
@@ -418,7 +693,7 @@ func (p *Package) addTestEvent(event TestEvent) {
 		// Do not immediately remove output for subtests, to work around a bug
 		// in 'go test' where output is attributed to the wrong sub test.
 		// github.com/golang/go/issues/29755.
-		if tc.Test.IsSubTest() {
+		if tc.Test.IsSubTest() || p.keepPassedOutput {
 			return
 		}
 
@@ -464,11 +739,21 @@ func (e *Execution) Packages() []string {
 	return sortedKeys(e.packages)
 }
 
-var clock = clockwork.NewRealClock()
+// clock is the Clock of an Execution created without ScanConfig.Clock.
+var clock Clock = clockwork.NewRealClock()
 
-// Elapsed returns the time elapsed since the execution started.
+// now returns the current time from the Clock of the Execution.
+func (e *Execution) now() time.Time {
+	if e.clock != nil {
+		return e.clock.Now()
+	}
+	return clock.Now()
+}
+
+// Elapsed returns the time elapsed since the execution started, measured by
+// the Clock from ScanConfig.Clock.
 func (e *Execution) Elapsed() time.Duration {
-	return clock.Now().Sub(e.started)
+	return e.now().Sub(e.started)
 }
 
 // Failed returns a list of all the failed test cases.
@@ -481,7 +766,7 @@ func (e *Execution) Failed() []TestCase {
 		pkg := e.packages[name]
 
 		// Add package-level failure output if there were no failed tests.
-		if pkg.TestMainFailed() {
+		if pkg.TestMainFailed() && !e.isTeardownFailure(pkg) {
 			failed = append(failed, TestCase{Package: name})
 		}
 		failed = append(failed, pkg.Failed...)
@@ -489,6 +774,27 @@ func (e *Execution) Failed() []TestCase {
 	return failed
 }
 
+// TeardownFailed returns a TestCase for each package that passed with a
+// teardown error. A package passed with a teardown error when all of its tests
+// passed but the package failed, and the Execution was scanned with
+// ScanConfig.DemoteTeardownFailures. These packages are not included in Failed.
+func (e *Execution) TeardownFailed() []TestCase {
+	if e == nil {
+		return nil
+	}
+	var result []TestCase
+	for _, name := range sortedKeys(e.packages) {
+		if e.isTeardownFailure(e.packages[name]) {
+			result = append(result, TestCase{Package: name})
+		}
+	}
+	return result
+}
+
+func (e *Execution) isTeardownFailure(pkg *Package) bool {
+	return e.demoteTeardownFailures && pkg.TeardownFailed()
+}
+
 // FilterFailedUnique filters a slice of failed TestCases by removing root test
 // case that have failed subtests.
 func FilterFailedUnique(tcs []TestCase) []TestCase {
@@ -538,7 +844,8 @@ func (e *Execution) addError(err string) {
 	e.errorsLock.Unlock()
 }
 
-// Errors returns a list of all the errors.
+// Errors returns a list of all the errors. Each line of stderr is a separate
+// error. See ErrorEntries for the errors with their ErrorKind and package.
 func (e *Execution) Errors() []string {
 	e.errorsLock.RLock()
 	defer e.errorsLock.RUnlock()
@@ -556,15 +863,55 @@ func (e *Execution) HasPanic() bool {
 	return false
 }
 
-func (e *Execution) end() []TestEvent {
+// end is called once all the events have been scanned. detectExit is false when
+// the scan was stopped early, because the packages that did not finish were
+// stopped, and did not exit unexpectedly.
+func (e *Execution) end(detectExit bool) []TestEvent {
 	e.done = true
 	var result []TestEvent // nolint: prealloc
-	for _, pkg := range e.packages {
-		result = append(result, pkg.end()...)
+	for _, name := range sortedKeys(e.packages) {
+		pkg := e.packages[name]
+		exited, lastOutput := pkg.exitedUnexpectedly, pkg.lastRunningOutput()
+		// Events without a Package, like the build events of a package that
+		// does not set ImportPath, are not a test binary that could exit.
+		result = append(result, pkg.end(name, detectExit && name != "")...)
+		if pkg.exitedUnexpectedly && !exited {
+			msg := exitedUnexpectedlyError(name, lastLines(lastOutput, exitOutputLines))
+			e.addError(msg)
+			e.addErrorEntry(ErrorEntry{Kind: ErrorExit, Package: name, Lines: strings.Split(msg, "\n")})
+		}
 	}
 	return result
 }
 
+// exitedUnexpectedlyError returns the error message for a package which exited
+// unexpectedly. The lines of output are indented so that the message counts
+// as a single error.
+func exitedUnexpectedlyError(name string, output []string) string {
+	msg := name + ": process exited unexpectedly (os.Exit?/crash)"
+	if len(output) == 0 {
+		return msg
+	}
+	msg += ", last output:"
+	for _, line := range output {
+		msg += "\n    " + strings.TrimRight(line, "\n")
+	}
+	return msg
+}
+
+// buildElapsed returns the time from the start of the execution until the
+// first event for the package was received. This approximates the time spent
+// building and linking the test binary for the package, including any time
+// spent waiting for other packages to build. Returns 0 if the first event
+// happened before the execution started, which happens when the events are
+// read from a file.
+func (e *Execution) buildElapsed(pkg *Package) time.Duration {
+	if pkg.firstEvent.Before(e.started) {
+		return 0
+	}
+	return pkg.firstEvent.Sub(e.started)
+}
+
 func (e *Execution) Started() time.Time {
 	return e.started
 }
@@ -572,10 +919,18 @@ func (e *Execution) Started() time.Time {
 // newExecution returns a new Execution and records the current time as the
 // time the test execution started.
 func newExecution() *Execution {
-	return &Execution{
-		started:  clock.Now(),
-		packages: make(map[string]*Package),
+	return newExecutionWithClock(nil)
+}
+
+// newExecutionWithClock returns a new Execution which uses c as its Clock.
+// When c is nil the Execution uses the default clock. The start time of an
+// Execution with an EventClock is the time of its first event.
+func newExecutionWithClock(c Clock) *Execution {
+	e := &Execution{clock: c, packages: make(map[string]*Package)}
+	if _, ok := c.(*EventClock); !ok {
+		e.started = e.now()
 	}
+	return e
 }
 
 // ScanConfig used by ScanTestOutput.
@@ -598,8 +953,74 @@ type ScanConfig struct {
 	// IgnoreNonJSONOutputLines causes ScanTestOutput to ignore non-JSON lines received from
 	// the Stdout reader. Instead of causing an error, the lines will be sent to Handler.Err.
 	IgnoreNonJSONOutputLines bool
+	// StderrMerge is the strategy used to combine lines from Stderr with the
+	// TestEvents from Stdout. Defaults to StderrMergeConcurrent.
+	StderrMerge StderrMerge
+	// DemoteTeardownFailures reports packages which failed after all of their
+	// tests passed as passed with a teardown error, instead of as failed. See
+	// Execution.TeardownFailed.
+	DemoteTeardownFailures bool
+	// RewriteTestName is called with the package and name of every test. The
+	// name it returns replaces the name of the test in the Execution, in the
+	// events sent to Handler, and in the framing lines of the test output
+	// (ex: --- FAIL: TestName). Returning the name unchanged, or an empty
+	// string, leaves the test name as is. TestCase.OriginalName returns the
+	// name from before the rewrite.
+	RewriteTestName func(pkg, name string) string
+	// RewriteOutput is called with the output of every output event. The
+	// output it returns replaces the output in the Execution, and in the
+	// events sent to Handler. See NewPathRewriter.
+	RewriteOutput func(output string) string
+	// SplitRuns detects when Stdout contains the output of more than one run of
+	// 'go test', for example a file that was appended to by several runs, or
+	// that includes the reruns from --rerun-fails. The events of each run after
+	// the first are assigned the next RunID. When Stdout contains the
+	// ActionRunStart events written by gotestsum, a new run starts at each of
+	// those events, and the reruns from --rerun-fails have the RunID of their
+	// attempt. Otherwise a new run starts when an event is received for a
+	// package that already finished, or when the time between two events is
+	// more than RunGap.
+	SplitRuns bool
+	// RunGap is the time between two events that starts a new run when
+	// SplitRuns is true. A zero value only starts a new run when a package
+	// starts again.
+	RunGap time.Duration
+	// KeepPassedOutput keeps the output of passed tests in the Execution, so
+	// that it can be included in reports. By default the output of a test is
+	// removed when the test passes.
+	KeepPassedOutput bool
+	// Clock is the source of the current time for a new Execution. It is
+	// ignored when Execution is set. Defaults to the wall clock. See
+	// EventClock for events read from a file.
+	Clock Clock
+	// InputAdapter converts each line read from Stdout into TestEvents. It is
+	// used to read the output of test runners for other languages. Defaults
+	// to reading the test2json output of 'go test -json'.
+	InputAdapter InputAdapter
 }
 
+// StderrMerge is a strategy for combining the lines read from ScanConfig.Stderr
+// with the TestEvents read from ScanConfig.Stdout.
+type StderrMerge int
+
+const (
+	// StderrMergeConcurrent sends stderr lines to EventHandler.Err as soon as
+	// they are read, concurrently with calls to EventHandler.Event.
+	StderrMergeConcurrent StderrMerge = iota
+	// StderrMergeSeparate sends all stderr lines to EventHandler.Err after all
+	// the TestEvents have been handled.
+	StderrMergeSeparate
+	// StderrMergePackage sends the lines of a build or vet error on stderr,
+	// which start with a '# package' header, to EventHandler.Err just before
+	// the pass or fail TestEvent of the package, so that the error is printed
+	// next to the result of the package. The lines of a package that does not
+	// have a pass or fail TestEvent are sent after all the TestEvents have been
+	// handled. Stdout and stderr are read concurrently, so lines that are read
+	// after the TestEvent of their package, and all other lines, are sent as
+	// soon as they are read.
+	StderrMergePackage
+)
+
 // EventHandler is called by ScanTestOutput for each event and write to stderr.
 type EventHandler interface {
 	// Event is called for every TestEvent, with the current value of Execution.
@@ -610,11 +1031,31 @@ type EventHandler interface {
 	Err(text string) error
 }
 
+// ContextEventHandler is an EventHandler which receives the context passed to
+// ScanTestOutputContext. If the Handler in ScanConfig implements
+// ContextEventHandler, EventContext is called instead of Event.
+type ContextEventHandler interface {
+	EventHandler
+	// EventContext is called for every TestEvent, with the current value of
+	// Execution. It may return an error to stop scanning.
+	EventContext(ctx context.Context, event TestEvent, execution *Execution) error
+}
+
 // ScanTestOutput reads lines from config.Stdout and config.Stderr, populates an
 // Execution, calls the Handler for each event, and returns the Execution.
 //
-// If config.Handler is nil, a default no-op handler will be used.
+// If config.Handler is nil, a default no-op handler will be used. If
+// config.Handler implements Flusher, Flush is called once all the events and
+// stderr lines have been handled.
 func ScanTestOutput(config ScanConfig) (*Execution, error) {
+	return ScanTestOutputContext(context.Background(), config)
+}
+
+// ScanTestOutputContext is ScanTestOutput with a context. When ctx is done
+// config.Stop is called, and scanning ends with the error from ctx once the
+// line being read from Stdout or Stderr is received. The Stop function should
+// end the process which is writing to the readers.
+func ScanTestOutputContext(ctx context.Context, config ScanConfig) (_ *Execution, err error) {
 	if config.Stdout == nil {
 		return nil, fmt.Errorf("stdout reader must be non-nil")
 	}
@@ -629,28 +1070,223 @@ func ScanTestOutput(config ScanConfig) (*Execution, error) {
 	}
 	execution := config.Execution
 	if execution == nil {
-		execution = newExecution()
+		execution = newExecutionWithClock(config.Clock)
 	}
 	execution.done = false
 	execution.lastRunID = config.RunID
+	if config.DemoteTeardownFailures {
+		execution.demoteTeardownFailures = true
+	}
+	if config.KeepPassedOutput {
+		execution.keepPassedOutput = true
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			config.Stop()
+		case <-done:
+		}
+	}()
+	if flusher, ok := config.Handler.(Flusher); ok {
+		defer func() {
+			if flushErr := flusher.Flush(); err == nil {
+				err = flushErr
+			}
+		}()
+	}
+	handler := &contextHandler{ctx: ctx, handler: config.Handler}
+	config.Handler = handler
+
+	stderrConfig := config
+	var stderrLines *bufferedErrHandler
+	switch config.StderrMerge {
+	case StderrMergeSeparate:
+		stderrLines = &bufferedErrHandler{}
+		stderrConfig.Handler = stderrLines
+	case StderrMergePackage:
+		merge := newPackageErrHandler(handler)
+		config.Handler = merge
+		stderrConfig.Handler = merge
+		defer func() {
+			if flushErr := merge.flush(); err == nil {
+				err = flushErr
+			}
+		}()
+	}
 
 	var group errgroup.Group
 	group.Go(func() error {
 		return stopOnError(config.Stop, readStdout(config, execution))
 	})
 	group.Go(func() error {
-		return stopOnError(config.Stop, readStderr(config, execution))
+		return stopOnError(config.Stop, readStderr(stderrConfig, execution))
 	})
 
-	err := group.Wait()
-	for _, event := range execution.end() {
-		if err := config.Handler.Event(event, execution); err != nil {
-			return execution, err
+	err = group.Wait()
+	// The events from end are handled even when ctx is done, so that the tests
+	// which were still running are reported, and the error which stopped the
+	// scan is returned instead of the error from ctx.
+	for _, event := range execution.end(err == nil && ctx.Err() == nil) {
+		if handlerErr := handler.handleEvent(event, execution); err == nil {
+			err = handlerErr
 		}
 	}
+	if flushErr := stderrLines.flush(handler.handleErr); err == nil {
+		err = flushErr
+	}
 	return execution, err
 }
 
+// contextHandler ends scanning when ctx is done, and passes ctx to the
+// wrapped handler if it implements ContextEventHandler.
+type contextHandler struct {
+	ctx     context.Context
+	handler EventHandler
+}
+
+func (h *contextHandler) Event(event TestEvent, execution *Execution) error {
+	if err := h.ctx.Err(); err != nil {
+		return err
+	}
+	return h.handleEvent(event, execution)
+}
+
+// handleEvent calls the wrapped handler without checking if ctx is done.
+func (h *contextHandler) handleEvent(event TestEvent, execution *Execution) error {
+	var err error
+	if handler, ok := h.handler.(ContextEventHandler); ok {
+		err = handler.EventContext(h.ctx, event, execution)
+	} else {
+		err = h.handler.Event(event, execution)
+	}
+	if err != nil {
+		return &HandlerError{Handler: h.handler, Event: &event, Err: err}
+	}
+	return nil
+}
+
+func (h *contextHandler) Err(text string) error {
+	if err := h.ctx.Err(); err != nil {
+		return err
+	}
+	return h.handleErr(text)
+}
+
+// handleErr calls the wrapped handler without checking if ctx is done.
+func (h *contextHandler) handleErr(text string) error {
+	if err := h.handler.Err(text); err != nil {
+		return &HandlerError{Handler: h.handler, Err: err}
+	}
+	return nil
+}
+
+// bufferedErrHandler stores stderr lines until they are sent to an
+// EventHandler by flush.
+type bufferedErrHandler struct {
+	noopHandler
+	lines []string
+}
+
+func (h *bufferedErrHandler) Err(text string) error {
+	h.lines = append(h.lines, text)
+	return nil
+}
+
+func (h *bufferedErrHandler) flush(handleErr func(text string) error) error {
+	if h == nil {
+		return nil
+	}
+	for _, line := range h.lines {
+		if err := handleErr(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// packageErrHandler holds the lines of a build or vet error on stderr until
+// the pass or fail TestEvent of the package is handled. See
+// StderrMergePackage.
+type packageErrHandler struct {
+	handler *contextHandler
+	// current is the package of the error that stderr lines are added to, or
+	// an empty string when the lines are not part of a build or vet error.
+	// It is only used by the stderr goroutine.
+	current string
+
+	mu sync.Mutex
+	// pending are the lines of each package that have not been sent.
+	pending map[string][]string
+	// ended are the packages that had a pass or fail TestEvent.
+	ended map[string]bool
+}
+
+func newPackageErrHandler(handler *contextHandler) *packageErrHandler {
+	return &packageErrHandler{
+		handler: handler,
+		pending: make(map[string][]string),
+		ended:   make(map[string]bool),
+	}
+}
+
+func (h *packageErrHandler) Event(event TestEvent, execution *Execution) error {
+	if event.PackageEvent() && (event.Action == ActionPass || event.Action == ActionFail) {
+		h.mu.Lock()
+		lines := h.pending[event.Package]
+		delete(h.pending, event.Package)
+		h.ended[event.Package] = true
+		h.mu.Unlock()
+
+		for _, line := range lines {
+			if err := h.handler.Err(line); err != nil {
+				return err
+			}
+		}
+	}
+	return h.handler.Event(event, execution)
+}
+
+func (h *packageErrHandler) Err(text string) error {
+	switch {
+	case strings.HasPrefix(text, "# ["):
+		h.current = strings.TrimSuffix(strings.TrimPrefix(text, "# ["), "]")
+	case strings.HasPrefix(text, "# "):
+		h.current = importPathPackage(strings.TrimPrefix(text, "# "))
+	case strings.HasPrefix(text, "go: "):
+		h.current = ""
+	}
+
+	h.mu.Lock()
+	if h.current == "" || h.ended[h.current] {
+		h.mu.Unlock()
+		return h.handler.Err(text)
+	}
+	h.pending[h.current] = append(h.pending[h.current], text)
+	h.mu.Unlock()
+	return nil
+}
+
+// flush sends the lines of the packages that did not have a pass or fail
+// TestEvent, sorted by package.
+func (h *packageErrHandler) flush() error {
+	pkgs := make([]string, 0, len(h.pending))
+	for pkg := range h.pending {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	for _, pkg := range pkgs {
+		for _, line := range h.pending[pkg] {
+			if err := h.handler.handleErr(line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func stopOnError(stop func(), err error) error {
 	if err != nil {
 		stop()
@@ -661,43 +1297,95 @@ func stopOnError(stop func(), err error) error {
 
 func readStdout(config ScanConfig, execution *Execution) error {
 	scanner := bufio.NewScanner(config.Stdout)
+	adapter := config.InputAdapter
+	if adapter == nil {
+		adapter = goTestJSONAdapter{}
+	}
+	var splitter *runSplitter
+	if config.SplitRuns {
+		splitter = newRunSplitter(config.RunID, config.RunGap)
+	}
+	var line int
 	for scanner.Scan() {
+		line++
 		raw := scanner.Bytes()
-		event, err := parseEvent(raw)
+		events, err := adapter.Events(raw)
 		switch {
 		case err == errBadEvent:
 			// nolint: errcheck
 			config.Handler.Err(errBadEvent.Error() + ": " + scanner.Text())
+			execution.addErrorEntry(ErrorEntry{Kind: ErrorParse, Lines: []string{scanner.Text()}})
 			continue
 		case err != nil:
 			if config.IgnoreNonJSONOutputLines {
 				// nolint: errcheck
 				config.Handler.Err(string(raw))
+				execution.addErrorEntry(ErrorEntry{Kind: ErrorParse, Lines: []string{string(raw)}})
 				continue
 			}
-			return errors.Wrapf(err, "failed to parse test output: %s", string(raw))
+			return &MalformedEventError{Line: line, Raw: string(raw), Err: err}
 		}
-
-		event.RunID = config.RunID
-		execution.add(event)
-		if err := config.Handler.Event(event, execution); err != nil {
+		for _, event := range events {
+			if err := handleEvent(config, execution, splitter, event); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "failed to scan test output")
+	}
+	for _, event := range adapter.End() {
+		if err := handleEvent(config, execution, splitter, event); err != nil {
 			return err
 		}
 	}
-	return errors.Wrap(scanner.Err(), "failed to scan test output")
+	return nil
+}
+
+// handleEvent adds an event read from ScanConfig.Stdout to the execution, and
+// sends it to the handler.
+func handleEvent(config ScanConfig, execution *Execution, splitter *runSplitter, event TestEvent) error {
+	// The start of a run is only metadata, it is not sent to the handler.
+	if event.Action == ActionRunStart {
+		if splitter != nil {
+			splitter.startRun(event.RunID)
+		}
+		return nil
+	}
+	if event.raw == nil {
+		encodeEvent(&event)
+	}
+
+	event.RunID = config.RunID
+	if splitter != nil {
+		event.RunID = splitter.next(event)
+		if event.RunID > execution.lastRunID {
+			execution.lastRunID = event.RunID
+		}
+	}
+	if config.RewriteTestName != nil && !event.PackageEvent() {
+		rewriteTestName(&event, config.RewriteTestName(event.Package, event.Test))
+	}
+	if config.RewriteOutput != nil && event.Action == ActionOutput {
+		rewriteOutput(&event, config.RewriteOutput(event.Output))
+	}
+	execution.add(event)
+	return config.Handler.Event(event, execution)
 }
 
 func readStderr(config ScanConfig, execution *Execution) error {
 	scanner := bufio.NewScanner(config.Stderr)
+	entries := newStderrErrors()
 	for scanner.Scan() {
 		line := scanner.Text()
 		if err := config.Handler.Err(line); err != nil {
-			return fmt.Errorf("failed to handle stderr: %v", err)
+			return err
 		}
 		if isGoModuleOutput(line) {
 			continue
 		}
 		execution.addError(line)
+		entries.add(execution, line)
 	}
 	if err := scanner.Err(); err != nil {
 		return fmt.Errorf("failed to scan stderr: %v", err)
@@ -732,11 +1420,105 @@ func parseEvent(raw []byte) (TestEvent, error) {
 	event := TestEvent{}
 	err := json.Unmarshal(raw, &event)
 	event.raw = raw
+	// Output from test binaries on Windows may use CRLF line endings. Use LF so
+	// that output is handled the same way on every platform.
+	event.Output = strings.Replace(event.Output, "\r\n", "\n", -1)
+	if event.Package == "" && event.ImportPath != "" {
+		event.Package = importPathPackage(event.ImportPath)
+	}
 	return event, err
 }
 
+// importPathPackage returns the package from the ImportPath of a build event.
+// The ImportPath of a package built for its tests is followed by the name of
+// the test binary, ex: 'example.com/pkg [example.com/pkg.test]'.
+func importPathPackage(importPath string) string {
+	if i := strings.Index(importPath, " ["); i >= 0 {
+		return importPath[:i]
+	}
+	return importPath
+}
+
 var errBadEvent = errors.New("bad output from test2json")
 
+// rewriteTestName replaces the name of the test in event with name. The raw
+// bytes of the event are re-encoded so that anything which writes
+// TestEvent.Bytes, like the jsonfile, uses the new name.
+func rewriteTestName(event *TestEvent, name string) {
+	if name == "" || name == event.Test {
+		return
+	}
+	event.Output = rewriteFramingLine(event.Output, event.Test, name)
+	event.originalTest = event.Test
+	event.Test = name
+	encodeEvent(event)
+}
+
+// encodeEvent sets the raw bytes of event to its test2json encoding.
+func encodeEvent(event *TestEvent) {
+	raw, err := json.Marshal(newTest2JSONEvent(*event))
+	if err != nil {
+		log.Warnf("failed to encode TestEvent for %v: %v", event.Test, err)
+		return
+	}
+	event.raw = raw
+}
+
+// framingPrefixes are the prefixes of the lines printed by 'go test' before
+// the name of a test.
+var framingPrefixes = []string{
+	"=== RUN   ",
+	"=== PAUSE ",
+	"=== CONT  ",
+	"=== NAME  ",
+	"--- PASS: ",
+	"--- FAIL: ",
+	"--- SKIP: ",
+}
+
+// rewriteFramingLine replaces name with newName in output if output is one of
+// the framing lines printed by 'go test' for the test.
+func rewriteFramingLine(output, name, newName string) string {
+	trimmed := strings.TrimLeft(output, " ")
+	indent := output[:len(output)-len(trimmed)]
+	for _, prefix := range framingPrefixes {
+		rest := strings.TrimPrefix(trimmed, prefix)
+		if rest == trimmed || !strings.HasPrefix(rest, name) {
+			continue
+		}
+		suffix := rest[len(name):]
+		if suffix != "" && suffix[0] != ' ' && suffix[0] != '\n' {
+			continue
+		}
+		return indent + prefix + newName + suffix
+	}
+	return output
+}
+
+// test2JSONEvent is the format of the events written by test2json.
+type test2JSONEvent struct {
+	Time    *time.Time `json:",omitempty"`
+	Action  Action
+	Package string  `json:",omitempty"`
+	Test    string  `json:",omitempty"`
+	Elapsed float64 `json:",omitempty"`
+	Output  string  `json:",omitempty"`
+}
+
+func newTest2JSONEvent(event TestEvent) test2JSONEvent {
+	e := test2JSONEvent{
+		Action:  event.Action,
+		Package: event.Package,
+		Test:    event.Test,
+		Elapsed: event.Elapsed,
+		Output:  event.Output,
+	}
+	if !event.Time.IsZero() {
+		e.Time = &event.Time
+	}
+	return e
+}
+
 type noopHandler struct{}
 
 func (s noopHandler) Event(TestEvent, *Execution) error {
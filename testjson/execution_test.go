@@ -2,7 +2,12 @@ package testjson
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -14,7 +19,9 @@ import (
 
 func TestExecution_Add_PackageCoverage(t *testing.T) {
 	exec := newExecution()
+	now := time.Now()
 	exec.add(TestEvent{
+		Time:    now,
 		Package: "mytestpkg",
 		Action:  ActionOutput,
 		Output:  "coverage: 33.1% of statements\n",
@@ -22,7 +29,9 @@ func TestExecution_Add_PackageCoverage(t *testing.T) {
 
 	pkg := exec.Package("mytestpkg")
 	expected := &Package{
-		coverage: "coverage: 33.1% of statements",
+		firstEvent: now,
+		lastEvent:  now,
+		coverage:   "coverage: 33.1% of statements",
 		output: map[int][]string{
 			0: {"coverage: 33.1% of statements\n"},
 		},
@@ -31,6 +40,123 @@ func TestExecution_Add_PackageCoverage(t *testing.T) {
 	assert.DeepEqual(t, pkg, expected, cmpPackage)
 }
 
+func TestPackage_StartTime_EndTime(t *testing.T) {
+	exec := newExecution()
+	start := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	events := []TestEvent{
+		{Time: start, Package: "mytestpkg", Action: ActionRun, Test: "TestOne"},
+		{Time: start.Add(time.Second), Package: "mytestpkg", Action: ActionPass, Test: "TestOne"},
+		{Time: start.Add(2 * time.Second), Package: "other", Action: ActionRun, Test: "TestTwo"},
+		{Time: start.Add(3 * time.Second), Package: "mytestpkg", Action: ActionPass},
+	}
+	for _, event := range events {
+		exec.add(event)
+	}
+
+	pkg := exec.Package("mytestpkg")
+	assert.Equal(t, pkg.StartTime(), start)
+	assert.Equal(t, pkg.EndTime(), start.Add(3*time.Second))
+
+	other := exec.Package("other")
+	assert.Equal(t, other.StartTime(), start.Add(2*time.Second))
+	assert.Equal(t, other.EndTime(), start.Add(2*time.Second))
+}
+
+func TestExecution_TeardownFailed(t *testing.T) {
+	scan := func(t *testing.T, demote bool) *Execution {
+		exec, err := ScanTestOutput(ScanConfig{
+			Stdout:                 bytes.NewReader(golden.Get(t, "go-test-json-teardown-failure.out")),
+			DemoteTeardownFailures: demote,
+		})
+		assert.NilError(t, err)
+		return exec
+	}
+
+	t.Run("default", func(t *testing.T) {
+		exec := scan(t, false)
+		assert.Assert(t, exec.Package("example.com/teardown").TeardownFailed())
+		assert.DeepEqual(t, exec.Failed(), []TestCase{{Package: "example.com/teardown"}},
+			cmpopts.IgnoreUnexported(TestCase{}))
+		assert.Equal(t, len(exec.TeardownFailed()), 0)
+	})
+
+	t.Run("demoted", func(t *testing.T) {
+		exec := scan(t, true)
+		assert.Equal(t, len(exec.Failed()), 0)
+		assert.DeepEqual(t, exec.TeardownFailed(), []TestCase{{Package: "example.com/teardown"}},
+			cmpopts.IgnoreUnexported(TestCase{}))
+	})
+}
+
+func TestScanTestOutput_ExitedUnexpectedly(t *testing.T) {
+	handler := &captureHandler{}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  bytes.NewReader(golden.Get(t, "go-test-json-exited-unexpectedly.out")),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+
+	crash := exec.Package("example.com/crash")
+	assert.Assert(t, crash.ExitedUnexpectedly())
+	assert.Equal(t, crash.Result(), ActionFail)
+	assert.Equal(t, len(crash.Failed), 1)
+
+	exit := exec.Package("example.com/exit")
+	assert.Assert(t, exit.ExitedUnexpectedly())
+	assert.Equal(t, len(exit.Failed), 1)
+	assert.Equal(t, len(exit.Passed), 1)
+
+	var pkgEvents []TestEvent
+	for _, event := range handler.events {
+		if event.PackageEvent() && event.Package == "example.com/crash" {
+			pkgEvents = append(pkgEvents, event)
+		}
+	}
+	expected := []TestEvent{{Action: ActionFail, Package: "example.com/crash"}}
+	assert.DeepEqual(t, pkgEvents, expected, cmpopts.IgnoreUnexported(TestEvent{}))
+	assert.Equal(t, len(exec.Errors()), 2)
+}
+
+func TestScanTestOutput_BuildFail(t *testing.T) {
+	handler := &captureHandler{}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  bytes.NewReader(golden.Get(t, "go-test-json-build-fail.out")),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, exec.Packages(), []string{"example.com/broken", "example.com/good"})
+	broken := exec.Package("example.com/broken")
+	assert.Assert(t, !broken.ExitedUnexpectedly())
+	assert.Equal(t, broken.Result(), ActionFail)
+	assert.Equal(t, exec.Package("example.com/good").Result(), ActionPass)
+
+	assert.DeepEqual(t, exec.Errors(), []string{"./broken_test.go:5:21: undefined: somepackage"})
+	expected := []ErrorEntry{{
+		Kind:    ErrorBuild,
+		Package: "example.com/broken [example.com/broken.test]",
+		Lines:   []string{"./broken_test.go:5:21: undefined: somepackage"},
+	}}
+	assert.DeepEqual(t, exec.ErrorEntries(), expected)
+
+	for _, event := range handler.events[:3] {
+		assert.Equal(t, event.Package, "example.com/broken")
+	}
+}
+
+func TestScanTestOutput_NoTestFiles(t *testing.T) {
+	in := `{"Action":"output","Package":"example.com/empty","Output":"?   \texample.com/empty\t[no test files]\n"}
+{"Action":"skip","Package":"example.com/empty","Elapsed":0}
+`
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(in)})
+	assert.NilError(t, err)
+
+	pkg := exec.Package("example.com/empty")
+	assert.Assert(t, !pkg.ExitedUnexpectedly())
+	assert.Equal(t, pkg.Result(), Action(""), "the result is not changed by the skip event")
+	assert.Equal(t, len(exec.Errors()), 0)
+}
+
 var cmpPackage = cmp.Options{
 	cmp.AllowUnexported(Package{}),
 	cmpopts.EquateEmpty(),
@@ -231,3 +357,276 @@ func (s *captureHandler) Err(text string) error {
 	s.errs = append(s.errs, text)
 	return fmt.Errorf(text)
 }
+
+func TestScanTestOutput_StderrMerge(t *testing.T) {
+	type testCase struct {
+		merge    StderrMerge
+		expected []string
+	}
+	stdout := `{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+{"Package": "pkg", "Test": "TestOne", "Action": "pass"}
+{"Package": "pkg", "Action": "pass"}
+`
+	fn := func(t *testing.T, tc testCase) {
+		handler := &sequenceHandler{}
+		cfg := ScanConfig{
+			Stdout:      strings.NewReader(stdout),
+			Stderr:      strings.NewReader("build failed\n  detail\n"),
+			Handler:     handler,
+			StderrMerge: tc.merge,
+		}
+		_, err := ScanTestOutput(cfg)
+		assert.NilError(t, err)
+		if tc.expected != nil {
+			assert.DeepEqual(t, handler.calls, tc.expected)
+			return
+		}
+		assert.Equal(t, len(handler.calls), 5)
+	}
+
+	t.Run("concurrent", func(t *testing.T) {
+		fn(t, testCase{merge: StderrMergeConcurrent})
+	})
+	t.Run("separate", func(t *testing.T) {
+		fn(t, testCase{
+			merge: StderrMergeSeparate,
+			expected: []string{
+				"event: run TestOne",
+				"event: pass TestOne",
+				"event: pass ",
+				"err: build failed",
+				"err:   detail",
+			},
+		})
+	})
+}
+
+func TestScanTestOutput_StderrMergePackage(t *testing.T) {
+	stderrDone := make(chan struct{})
+	stderr := &notifyEOFReader{
+		Reader: strings.NewReader("go: downloading example.com/dep v1.0.0\n" +
+			"# example.com/b\nb.go:1:2: undefined: x\n" +
+			"# example.com/c [example.com/c.test]\nc_test.go:3:4: undefined: y\n"),
+		eof: stderrDone,
+	}
+	// stdout is written once all of stderr has been read, so that the order of
+	// the calls does not depend on the scheduling of the readers.
+	stdout, writer := io.Pipe()
+	go func() {
+		<-stderrDone
+		_, _ = io.WriteString(writer, `{"Package": "example.com/a", "Test": "TestOne", "Action": "run"}
+{"Package": "example.com/a", "Test": "TestOne", "Action": "pass"}
+{"Package": "example.com/a", "Action": "pass"}
+{"Package": "example.com/b", "Action": "output", "Output": "FAIL\texample.com/b [build failed]\n"}
+{"Package": "example.com/b", "Action": "fail"}
+`)
+		_ = writer.Close()
+	}()
+
+	handler := &sequenceHandler{}
+	_, err := ScanTestOutput(ScanConfig{
+		Stdout:      stdout,
+		Stderr:      stderr,
+		Handler:     handler,
+		StderrMerge: StderrMergePackage,
+	})
+	assert.NilError(t, err)
+	expected := []string{
+		"err: go: downloading example.com/dep v1.0.0",
+		"event: run TestOne",
+		"event: pass TestOne",
+		"event: pass ",
+		"event: output ",
+		"err: # example.com/b",
+		"err: b.go:1:2: undefined: x",
+		"event: fail ",
+		"err: # example.com/c [example.com/c.test]",
+		"err: c_test.go:3:4: undefined: y",
+	}
+	assert.DeepEqual(t, handler.calls, expected)
+}
+
+// notifyEOFReader closes eof when the wrapped Reader returns io.EOF.
+type notifyEOFReader struct {
+	io.Reader
+	eof  chan struct{}
+	once sync.Once
+}
+
+func (r *notifyEOFReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == io.EOF {
+		r.once.Do(func() { close(r.eof) })
+	}
+	return n, err
+}
+
+type sequenceHandler struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (s *sequenceHandler) Event(event TestEvent, _ *Execution) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, fmt.Sprintf("event: %s %s", event.Action, event.Test))
+	return nil
+}
+
+func (s *sequenceHandler) Err(text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, "err: "+text)
+	return nil
+}
+
+func TestScanTestOutputContext_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	stopped := make(chan struct{})
+	var once sync.Once
+	cfg := ScanConfig{
+		Stdout: bytes.NewReader(golden.Get(t, "go-test-json.out")),
+		Stop:   func() { once.Do(func() { close(stopped) }) },
+	}
+	_, err := ScanTestOutputContext(ctx, cfg)
+	assert.Assert(t, errors.Is(err, context.Canceled), err)
+	<-stopped
+}
+
+func TestScanTestOutputContext_WithContextEventHandler(t *testing.T) {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	handler := &contextCaptureHandler{key: ctxKey{}}
+	cfg := ScanConfig{
+		Stdout:  bytes.NewReader(golden.Get(t, "go-test-json.out")),
+		Handler: handler,
+	}
+	_, err := ScanTestOutputContext(ctx, cfg)
+	assert.NilError(t, err)
+	assert.Assert(t, len(handler.values) > 0)
+	for _, value := range handler.values {
+		assert.Equal(t, value, "value")
+	}
+}
+
+func TestScanTestOutputContext_StopCancelsContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := `{"Action":"run","Package":"pkg","Test":"TestRunning"}
+{"Action":"run","Package":"pkg","Test":"TestFails"}
+{"Action":"fail","Package":"pkg","Test":"TestFails","Elapsed":0.1}
+{"Action":"run","Package":"pkg","Test":"TestNeverRead"}
+`
+	handler := &maxFailsHandler{}
+	cfg := ScanConfig{
+		Stdout:  strings.NewReader(source),
+		Handler: handler,
+		Stop:    cancel,
+	}
+	exec, err := ScanTestOutputContext(ctx, cfg)
+	assert.Error(t, err, "max failures was reached")
+	assert.Assert(t, errors.Is(ctx.Err(), context.Canceled))
+
+	expected := []string{
+		"event: run TestRunning",
+		"event: run TestFails",
+		"event: fail TestFails",
+		"event: fail TestRunning",
+	}
+	assert.DeepEqual(t, handler.calls, expected)
+	assert.Equal(t, len(exec.Failed()), 2)
+}
+
+// maxFailsHandler returns an error for every event after the first test
+// failed, like the --max-fails handler in cmd.
+type maxFailsHandler struct {
+	sequenceHandler
+}
+
+func (h *maxFailsHandler) Event(event TestEvent, execution *Execution) error {
+	_ = h.sequenceHandler.Event(event, execution)
+	if len(execution.Failed()) > 0 {
+		return fmt.Errorf("max failures was reached")
+	}
+	return nil
+}
+
+type contextCaptureHandler struct {
+	noopHandler
+	key    interface{}
+	values []interface{}
+}
+
+func (h *contextCaptureHandler) EventContext(ctx context.Context, _ TestEvent, _ *Execution) error {
+	h.values = append(h.values, ctx.Value(h.key))
+	return nil
+}
+
+func TestParseEvent_CRLFOutput(t *testing.T) {
+	raw := []byte(`{"Action":"output","Package":"pkg","Test":"TestOne","Output":"--- FAIL: TestOne (0.00s)\r\n"}`)
+	event, err := parseEvent(raw)
+	assert.NilError(t, err)
+	assert.Equal(t, event.Output, "--- FAIL: TestOne (0.00s)\n")
+	assert.DeepEqual(t, event.Bytes(), raw)
+}
+
+func TestScanTestOutput_RewriteTestName(t *testing.T) {
+	source := `{"Action":"run","Package":"pkg","Test":"TestOne_gen123"}
+{"Action":"output","Package":"pkg","Test":"TestOne_gen123","Output":"=== RUN   TestOne_gen123\n"}
+{"Action":"output","Package":"pkg","Test":"TestOne_gen123","Output":"    one_test.go:12: TestOne_gen123 failed\n"}
+{"Action":"output","Package":"pkg","Test":"TestOne_gen123","Output":"--- FAIL: TestOne_gen123 (0.00s)\n"}
+{"Action":"fail","Package":"pkg","Test":"TestOne_gen123","Elapsed":0.01}
+{"Action":"run","Package":"pkg","Test":"TestTwo"}
+{"Action":"pass","Package":"pkg","Test":"TestTwo"}
+{"Action":"fail","Package":"pkg","Elapsed":0.02}
+`
+	handler := &captureHandler{}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  strings.NewReader(source),
+		Handler: handler,
+		RewriteTestName: func(pkg, name string) string {
+			return strings.TrimSuffix(name, "_gen123")
+		},
+	})
+	assert.NilError(t, err)
+
+	failed := exec.Failed()
+	assert.Equal(t, len(failed), 1)
+	assert.Equal(t, failed[0].Test, TestName("TestOne"))
+	assert.Equal(t, failed[0].OriginalName(), TestName("TestOne_gen123"))
+	assert.Equal(t, exec.Package("pkg").Passed[0].OriginalName(), TestName("TestTwo"))
+
+	expected := []string{
+		"=== RUN   TestOne\n",
+		"    one_test.go:12: TestOne_gen123 failed\n",
+		"--- FAIL: TestOne (0.00s)\n",
+	}
+	assert.DeepEqual(t, exec.OutputLines(failed[0]), expected)
+
+	assert.Equal(t, string(handler.events[3].Bytes()),
+		`{"Action":"output","Package":"pkg","Test":"TestOne","Output":"--- FAIL: TestOne (0.00s)\n"}`)
+	// events that are not rewritten keep the original bytes
+	assert.Equal(t, string(handler.events[5].Bytes()),
+		`{"Action":"run","Package":"pkg","Test":"TestTwo"}`)
+}
+
+func TestScanTestOutput_KeepPassedOutput(t *testing.T) {
+	input := `{"Action":"run","Package":"example.com/pkg","Test":"TestPass"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestPass","Output":"pass output\n"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestPass"}
+{"Action":"pass","Package":"example.com/pkg"}
+`
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+	pkg := exec.Package("example.com/pkg")
+	assert.Equal(t, pkg.Output(pkg.Passed[0].ID), "")
+
+	exec, err = ScanTestOutput(ScanConfig{Stdout: strings.NewReader(input), KeepPassedOutput: true})
+	assert.NilError(t, err)
+	pkg = exec.Package("example.com/pkg")
+	assert.Equal(t, pkg.Output(pkg.Passed[0].ID), "pass output\n")
+}
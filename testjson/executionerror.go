@@ -0,0 +1,134 @@
+package testjson
+
+import "strings"
+
+// ErrorKind is the category of an ErrorEntry.
+type ErrorKind int
+
+const (
+	// ErrorStderr is a line of stderr from 'go test' which is not part of a
+	// build or vet error, like a warning from the go command.
+	ErrorStderr ErrorKind = iota
+	// ErrorBuild is the output of the compiler for a package which failed to
+	// build.
+	ErrorBuild
+	// ErrorVet is the output of the go vet checks run by 'go test' for a
+	// package.
+	ErrorVet
+	// ErrorParse is a line of the test2json output which is not a valid
+	// TestEvent.
+	ErrorParse
+	// ErrorExit is a package which exited before all of its tests finished.
+	ErrorExit
+)
+
+var errorKindNames = map[ErrorKind]string{
+	ErrorStderr: "stderr",
+	ErrorBuild:  "build",
+	ErrorVet:    "vet",
+	ErrorParse:  "parse",
+	ErrorExit:   "exit",
+}
+
+func (k ErrorKind) String() string {
+	if name, ok := errorKindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ErrorEntry is an error from a run which is not the failure of a test.
+type ErrorEntry struct {
+	Kind ErrorKind
+	// Package is the package which caused the error, or an empty string when
+	// the package is not known.
+	Package string
+	// Lines of the error, without the trailing newline. The '# package'
+	// header of a build or vet error is not included.
+	Lines []string
+}
+
+// String returns the lines of the error.
+func (e ErrorEntry) String() string {
+	return strings.Join(e.Lines, "\n")
+}
+
+// ErrorEntries returns all the errors, in the order they were received. Unlike
+// Errors, which returns each line of stderr as a separate error, each entry
+// has a Kind, and the lines of a build error are a single entry. Entries of
+// ErrorParse are not included in Errors.
+func (e *Execution) ErrorEntries() []ErrorEntry {
+	e.errorsLock.RLock()
+	defer e.errorsLock.RUnlock()
+	var entries []ErrorEntry
+	for _, entry := range e.errorEntries {
+		if len(entry.Lines) == 0 {
+			continue
+		}
+		entry.Lines = append([]string(nil), entry.Lines...)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func (e *Execution) addErrorEntry(entry ErrorEntry) int {
+	e.errorsLock.Lock()
+	defer e.errorsLock.Unlock()
+	e.errorEntries = append(e.errorEntries, entry)
+	return len(e.errorEntries) - 1
+}
+
+// stderrErrors groups the lines of stderr into an ErrorEntry for each error.
+type stderrErrors struct {
+	// current is the index of the build or vet error that lines are added to,
+	// or -1 when lines are not part of a build or vet error.
+	current int
+}
+
+func newStderrErrors() *stderrErrors {
+	return &stderrErrors{current: -1}
+}
+
+// add a line of stderr to the ErrorEntries of exec. A '# package' header
+// starts a build error, and a '# [package]' header starts a vet error. The
+// lines after a header are part of the error until the next header, or a line
+// from the go command. Every other line is an ErrorStderr.
+func (s *stderrErrors) add(exec *Execution, line string) {
+	switch {
+	case strings.HasPrefix(line, "# ["):
+		pkg := strings.TrimSuffix(strings.TrimPrefix(line, "# ["), "]")
+		s.start(exec, ErrorEntry{Kind: ErrorVet, Package: pkg})
+		return
+	case strings.HasPrefix(line, "# "):
+		s.start(exec, ErrorEntry{Kind: ErrorBuild, Package: strings.TrimPrefix(line, "# ")})
+		return
+	case strings.HasPrefix(line, "go: "):
+		s.current = -1
+	}
+
+	if s.current < 0 {
+		exec.addErrorEntry(ErrorEntry{Kind: ErrorStderr, Lines: []string{line}})
+		return
+	}
+	exec.errorsLock.Lock()
+	defer exec.errorsLock.Unlock()
+	entry := &exec.errorEntries[s.current]
+	entry.Lines = append(entry.Lines, line)
+}
+
+// start a new build or vet error. The vet header of a package follows the
+// build header of the same package, so a build error without any lines becomes
+// the vet error.
+func (s *stderrErrors) start(exec *Execution, entry ErrorEntry) {
+	if s.current >= 0 {
+		exec.errorsLock.Lock()
+		prev := &exec.errorEntries[s.current]
+		if len(prev.Lines) == 0 && prev.Package == entry.Package {
+			prev.Kind = entry.Kind
+			exec.errorsLock.Unlock()
+			return
+		}
+		exec.errorsLock.Unlock()
+	}
+	s.current = exec.addErrorEntry(entry)
+}
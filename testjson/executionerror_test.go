@@ -0,0 +1,87 @@
+package testjson
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestExecution_ErrorEntries(t *testing.T) {
+	stdout := `{"Action":"run","Package":"example.com/ok","Test":"TestOne"}
+not json
+{"Action":"pass","Package":"example.com/ok","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/ok"}
+`
+	stderr := `go: warning: "./..." matched no packages
+# example.com/broken
+broken/broken.go:5:2: undefined: missing
+broken/broken.go:6:2: undefined: other
+# example.com/vetted
+# [example.com/vetted]
+vetted/vetted_test.go:9:2: fmt.Printf format %d has arg s of wrong type string
+go: some go command error
+`
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:                   strings.NewReader(stdout),
+		Stderr:                   strings.NewReader(stderr),
+		StderrMerge:              StderrMergeSeparate,
+		IgnoreNonJSONOutputLines: true,
+	})
+	assert.NilError(t, err)
+
+	var entries, parseErrors []ErrorEntry
+	for _, entry := range exec.ErrorEntries() {
+		// stdout and stderr are read at the same time, so the order of the
+		// parse errors and the stderr errors is not known.
+		if entry.Kind == ErrorParse {
+			parseErrors = append(parseErrors, entry)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	assert.DeepEqual(t, parseErrors, []ErrorEntry{{Kind: ErrorParse, Lines: []string{"not json"}}})
+
+	expected := []ErrorEntry{
+		{Kind: ErrorStderr, Lines: []string{`go: warning: "./..." matched no packages`}},
+		{
+			Kind:    ErrorBuild,
+			Package: "example.com/broken",
+			Lines: []string{
+				"broken/broken.go:5:2: undefined: missing",
+				"broken/broken.go:6:2: undefined: other",
+			},
+		},
+		{
+			Kind:    ErrorVet,
+			Package: "example.com/vetted",
+			Lines:   []string{"vetted/vetted_test.go:9:2: fmt.Printf format %d has arg s of wrong type string"},
+		},
+		{Kind: ErrorStderr, Lines: []string{"go: some go command error"}},
+	}
+	assert.DeepEqual(t, entries, expected)
+
+	// Errors is unchanged, every line of stderr except the headers.
+	assert.DeepEqual(t, exec.Errors(), []string{
+		`go: warning: "./..." matched no packages`,
+		"broken/broken.go:5:2: undefined: missing",
+		"broken/broken.go:6:2: undefined: other",
+		"vetted/vetted_test.go:9:2: fmt.Printf format %d has arg s of wrong type string",
+		"go: some go command error",
+	})
+}
+
+func TestExecution_ErrorEntries_ExitedUnexpectedly(t *testing.T) {
+	stdout := `{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestOne","Output":"about to exit\n"}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.1}
+`
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(stdout)})
+	assert.NilError(t, err)
+
+	entries := exec.ErrorEntries()
+	assert.Equal(t, len(entries), 1)
+	assert.Equal(t, entries[0].Kind, ErrorExit)
+	assert.Equal(t, entries[0].Package, "example.com/pkg")
+	assert.Equal(t, entries[0].String(), exec.Errors()[0])
+}
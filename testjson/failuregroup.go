@@ -0,0 +1,101 @@
+package testjson
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// panicCausePattern matches the first line of a panic.
+	panicCausePattern = regexp.MustCompile(`^panic: (.*?)( \[recovered\])?$`)
+	// addressPattern matches a host and port, which change when a test uses a
+	// random port.
+	addressPattern = regexp.MustCompile(`\b(\d{1,3}(\.\d{1,3}){3}|\[[0-9a-fA-F:]+\]|localhost):\d+\b`)
+)
+
+// FailureCause returns the normalized message of the failure of tc, or an
+// empty string when the output of the test has no message. The message is the
+// first line of a panic, or otherwise the first message logged by t.Error or
+// t.Fatal, or by an assertion library. The message is normalized the same way
+// as the output used by Fingerprint, and addresses are replaced by <addr>, so
+// that the same failure in different tests has the same cause.
+func (e *Execution) FailureCause(tc TestCase) string {
+	return failureCause(e.OutputLines(tc))
+}
+
+func failureCause(lines []string) string {
+	var message string
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if isFramingLine(line) || isTestFramingLine(line) {
+			continue
+		}
+		if match := panicCausePattern.FindStringSubmatch(trimmed); match != nil {
+			return normalizeCause("panic: " + match[1])
+		}
+		if message != "" {
+			continue
+		}
+		if loc := logPrefixPattern.FindStringIndex(line); loc != nil {
+			message = strings.TrimSpace(line[loc[1]:])
+			if message == "" || strings.HasPrefix(message, "Error Trace:") {
+				message = assertionMessage(lines[i+1:])
+			}
+		}
+	}
+	return normalizeCause(message)
+}
+
+// assertionMessage returns the message from the multi-line output of an
+// assertion library, which prints the message on an Error: line, or on the
+// line after it.
+func assertionMessage(lines []string) string {
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "Error:") {
+			continue
+		}
+		message := strings.TrimSpace(strings.TrimPrefix(trimmed, "Error:"))
+		if strings.HasSuffix(message, ":") && i+1 < len(lines) {
+			message += " " + strings.TrimSpace(lines[i+1])
+		}
+		return message
+	}
+	return ""
+}
+
+func normalizeCause(message string) string {
+	if message == "" {
+		return ""
+	}
+	return addressPattern.ReplaceAllString(normalizeLine(message), "<addr>")
+}
+
+// FailureGroup is a group of failed tests with the same FailureCause.
+type FailureGroup struct {
+	Cause string
+	Tests []TestCase
+}
+
+// GroupFailures groups the failed tests in tcs by their FailureCause. The
+// groups are in the order of the first test of each group, and the tests in
+// a group are in the order of tcs. Tests without a cause are each in a group
+// of their own.
+func (e *Execution) GroupFailures(tcs []TestCase) []FailureGroup {
+	return groupFailures(tcs, e.FailureCause)
+}
+
+func groupFailures(tcs []TestCase, causeOf func(TestCase) string) []FailureGroup {
+	var groups []FailureGroup // nolint: prealloc
+	index := make(map[string]int)
+	for _, tc := range tcs {
+		cause := causeOf(tc)
+		if i, ok := index[cause]; ok && cause != "" {
+			groups[i].Tests = append(groups[i].Tests, tc)
+			continue
+		}
+		index[cause] = len(groups)
+		groups = append(groups, FailureGroup{Cause: cause, Tests: []TestCase{tc}})
+	}
+	return groups
+}
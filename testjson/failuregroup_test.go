@@ -0,0 +1,127 @@
+package testjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"gotest.tools/v3/assert"
+)
+
+func TestFailureCause(t *testing.T) {
+	type testCase struct {
+		name     string
+		lines    []string
+		expected string
+	}
+	for _, tc := range []testCase{
+		{
+			name: "t.Fatal",
+			lines: []string{
+				"=== RUN   TestOne\n",
+				"    one_test.go:12: dial tcp 127.0.0.1:49152: connect: connection refused\n",
+				"--- FAIL: TestOne (0.00s)\n",
+			},
+			expected: "dial tcp <addr>: connect: connection refused",
+		},
+		{
+			name: "first message",
+			lines: []string{
+				"    one_test.go:12: first\n",
+				"    one_test.go:13: second\n",
+			},
+			expected: "first",
+		},
+		{
+			name: "panic",
+			lines: []string{
+				"    one_test.go:12: before the panic\n",
+				"panic: runtime error: invalid memory address or nil pointer dereference [recovered]\n",
+				"\tpanic: runtime error: invalid memory address or nil pointer dereference\n",
+				"[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x4f7e8a]\n",
+			},
+			expected: "panic: runtime error: invalid memory address or nil pointer dereference",
+		},
+		{
+			name: "assertion",
+			lines: []string{
+				"    one_test.go:12: \n",
+				"        \tError Trace:\tone_test.go:12\n",
+				"        \tError:      \tReceived unexpected error:\n",
+				"        \t            \tcontext deadline exceeded\n",
+				"        \tTest:       \tTestOne\n",
+			},
+			expected: "Received unexpected error: context deadline exceeded",
+		},
+		{
+			name: "normalized",
+			lines: []string{
+				"    one_test.go:12: timed out after 1.5s waiting for /tmp/TestOne123/sock\n",
+			},
+			expected: "timed out after <duration> waiting for <tmp>",
+		},
+		{
+			name:  "no message",
+			lines: []string{"--- FAIL: TestOne (0.00s)\n"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, failureCause(tc.lines), tc.expected)
+		})
+	}
+}
+
+func TestGroupFailures(t *testing.T) {
+	causes := map[TestName]string{
+		"TestOne":   "connection refused",
+		"TestTwo":   "",
+		"TestThree": "connection refused",
+		"TestFour":  "",
+		"TestFive":  "timeout",
+	}
+	var tcs []TestCase
+	for _, name := range []TestName{"TestOne", "TestTwo", "TestThree", "TestFour", "TestFive"} {
+		tcs = append(tcs, TestCase{Package: "example.com/pkg", Test: name})
+	}
+
+	groups := groupFailures(tcs, func(tc TestCase) string {
+		return causes[tc.Test]
+	})
+	expected := []FailureGroup{
+		{Cause: "connection refused", Tests: []TestCase{tcs[0], tcs[2]}},
+		{Tests: []TestCase{tcs[1]}},
+		{Tests: []TestCase{tcs[3]}},
+		{Cause: "timeout", Tests: []TestCase{tcs[4]}},
+	}
+	assert.DeepEqual(t, groups, expected, cmpopts.IgnoreUnexported(TestCase{}))
+}
+
+func TestPrintSummary_WithGroupFailures(t *testing.T) {
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestOne","Output":"    pkg_test.go:10: dial tcp 127.0.0.1:40123: connect: connection refused\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestOne","Elapsed":0.1}
+{"Action":"run","Package":"example.com/pkg","Test":"TestTwo"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestTwo","Output":"    pkg_test.go:20: expected 2, got 3\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestTwo","Elapsed":0.2}
+{"Action":"run","Package":"example.com/pkg","Test":"TestThree"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestThree","Output":"    pkg_test.go:30: dial tcp 127.0.0.1:40456: connect: connection refused\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestThree","Elapsed":0.3}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.6}
+`)})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	PrintSummaryWithOptions(out, exec, SummarizeFailed|SummarizeOutput, FormatOptions{GroupFailures: true})
+	expected := `
+=== Failed
+=== 2 tests failed with: dial tcp <addr>: connect: connection refused
+=== FAIL: example.com/pkg TestOne (0.10s)
+    pkg_test.go:10: dial tcp 127.0.0.1:40123: connect: connection refused
+=== FAIL: example.com/pkg TestThree (0.30s)
+
+=== FAIL: example.com/pkg TestTwo (0.20s)
+    pkg_test.go:20: expected 2, got 3
+`
+	assert.Assert(t, strings.HasPrefix(out.String(), expected), out.String())
+}
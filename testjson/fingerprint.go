@@ -0,0 +1,95 @@
+package testjson
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// fingerprintLength is the number of hex characters in a fingerprint.
+const fingerprintLength = 12
+
+var (
+	timestampPattern = regexp.MustCompile(
+		`\d{4}[-/]\d{2}[-/]\d{2}([T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?)?|` +
+			`\b\d{2}:\d{2}:\d{2}(\.\d+)?\b`)
+	pointerPattern   = regexp.MustCompile(`0x[0-9a-fA-F]+`)
+	durationPattern  = regexp.MustCompile(`\b\d+(\.\d+)?(ns|µs|us|ms|s|m|h)\b`)
+	goroutinePattern = regexp.MustCompile(`goroutine \d+`)
+	tempPathPattern  = newTempPathPattern()
+)
+
+// newTempPathPattern returns a pattern that matches paths in the temporary
+// directory, including the directories created by testing.T.TempDir.
+func newTempPathPattern() *regexp.Regexp {
+	dirs := []string{regexp.QuoteMeta(filepath.ToSlash(os.TempDir())), `/tmp`, `/var/folders`}
+	return regexp.MustCompile(`(` + strings.Join(dirs, "|") + `)[/\\][^\s:'"]*`)
+}
+
+// normalizeLine removes the parts of a line of output that change each time a
+// test runs, like timestamps, pointers, elapsed times, and temporary paths.
+func normalizeLine(line string) string {
+	line = filepath.ToSlash(strings.TrimSpace(line))
+	line = tempPathPattern.ReplaceAllString(line, "<tmp>")
+	line = timestampPattern.ReplaceAllString(line, "<time>")
+	line = pointerPattern.ReplaceAllString(line, "<ptr>")
+	line = durationPattern.ReplaceAllString(line, "<duration>")
+	return goroutinePattern.ReplaceAllString(line, "goroutine <n>")
+}
+
+// Fingerprint returns a short hash of the package, name, and normalized output
+// of tc. Two failures of the same test with the same cause have the same
+// fingerprint, even when the output includes timestamps, pointers, elapsed
+// times, or temporary paths.
+func (p *Package) Fingerprint(tc TestCase) string {
+	h := sha256.New()
+	h.Write([]byte(tc.Package + "\n" + tc.Test.Name() + "\n"))
+	for _, line := range p.OutputLines(tc) {
+		if isFramingLine(line) {
+			continue
+		}
+		if line = normalizeLine(line); line != "" {
+			h.Write([]byte(line + "\n"))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:fingerprintLength]
+}
+
+// Fingerprint returns the fingerprint of tc. See Package.Fingerprint.
+func (e *Execution) Fingerprint(tc TestCase) string {
+	return e.packages[tc.Package].Fingerprint(tc)
+}
+
+// KnownIssues maps the fingerprint of a failure to the URL of the issue that
+// tracks the failure.
+type KnownIssues struct {
+	issues map[string]string
+}
+
+// NewKnownIssues returns KnownIssues from a map of fingerprint to issue URL.
+func NewKnownIssues(issues map[string]string) *KnownIssues {
+	return &KnownIssues{issues: issues}
+}
+
+// Lookup returns the URL of the issue for the failure with fingerprint.
+func (k *KnownIssues) Lookup(fingerprint string) (string, bool) {
+	if k == nil {
+		return "", false
+	}
+	url, ok := k.issues[fingerprint]
+	return url, ok
+}
+
+// IsKnown returns true if the failure of every test case in tcs matches a
+// known issue.
+func (k *KnownIssues) IsKnown(exec *Execution, tcs []TestCase) bool {
+	for _, tc := range tcs {
+		if _, ok := k.Lookup(exec.Fingerprint(tc)); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package testjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestNormalizeLine(t *testing.T) {
+	type testCase struct {
+		line     string
+		expected string
+	}
+	for _, tc := range []testCase{
+		{
+			line:     "    db_test.go:12: 2021-06-01T12:00:00.123Z connection refused\n",
+			expected: "db_test.go:12: <time> connection refused",
+		},
+		{
+			line:     "    cache_test.go:40: 12:00:01 lookup of 0xc000123abc failed\n",
+			expected: "cache_test.go:40: <time> lookup of <ptr> failed",
+		},
+		{
+			line:     "    fs_test.go:8: open /tmp/TestOpen123456/001/file: no such file\n",
+			expected: "fs_test.go:8: open <tmp>: no such file",
+		},
+		{
+			line:     "goroutine 42 [running]:\n",
+			expected: "goroutine <n> [running]:",
+		},
+		{
+			line:     "    http_test.go:20: timeout after 1.5s\n",
+			expected: "http_test.go:20: timeout after <duration>",
+		},
+	} {
+		t.Run(tc.line, func(t *testing.T) {
+			assert.Equal(t, normalizeLine(tc.line), tc.expected)
+		})
+	}
+}
+
+const fingerprintInput = `{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestOne","Output":"=== RUN   TestOne\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestOne","Output":"    pkg_test.go:10: 2021-06-01T12:00:00Z lookup 0xc000010000 failed\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestOne","Elapsed":0.1}
+{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestOne","Output":"=== RUN   TestOne\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestOne","Output":"    pkg_test.go:10: 2021-06-02T08:30:00Z lookup 0xc000020000 failed\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestOne","Elapsed":0.1}
+{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestOne","Output":"    pkg_test.go:14: unexpected status 500\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestOne","Elapsed":0.1}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.3}
+`
+
+func TestExecution_Fingerprint(t *testing.T) {
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(fingerprintInput)})
+	assert.NilError(t, err)
+
+	failed := exec.Failed()
+	assert.Equal(t, len(failed), 3)
+	first := exec.Fingerprint(failed[0])
+	assert.Equal(t, len(first), fingerprintLength)
+	assert.Equal(t, exec.Fingerprint(failed[1]), first)
+	assert.Assert(t, exec.Fingerprint(failed[2]) != first)
+}
+
+func TestPrintSummary_WithKnownIssues(t *testing.T) {
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(fingerprintInput)})
+	assert.NilError(t, err)
+
+	failed := exec.Failed()
+	known := exec.Fingerprint(failed[0])
+	unknown := exec.Fingerprint(failed[2])
+	issues := NewKnownIssues(map[string]string{known: "https://issues.example.com/1"})
+	assert.Assert(t, issues.IsKnown(exec, failed[:2]))
+	assert.Assert(t, !issues.IsKnown(exec, failed))
+
+	out := new(bytes.Buffer)
+	PrintSummaryWithOptions(out, exec, SummarizeFailed, FormatOptions{KnownIssues: issues})
+	expected := `
+=== Failed
+=== FAIL: example.com/pkg TestOne (0.10s)
+    known issue: https://issues.example.com/1 (fingerprint ` + known + `)
+=== FAIL: example.com/pkg TestOne (0.10s)
+    known issue: https://issues.example.com/1 (fingerprint ` + known + `)
+=== FAIL: example.com/pkg TestOne (0.10s)
+    fingerprint: ` + unknown + `
+`
+	assert.Assert(t, strings.HasPrefix(out.String(), expected), out.String())
+}
@@ -0,0 +1,70 @@
+package testjson
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// FirstFailure is the first failure observed by an Execution.
+type FirstFailure struct {
+	Package string
+	// Test is the name of the test that failed, or empty when the package
+	// failed without a failed test, for example when it failed to build.
+	Test TestName
+	// Elapsed is the time from the start of the Execution until the failure.
+	Elapsed time.Duration
+}
+
+// recordFailure stores the time of the first fail event.
+func (e *Execution) recordFailure(event TestEvent, eventTime time.Time) {
+	if event.Action != ActionFail || !e.firstFailureTime.IsZero() {
+		return
+	}
+	e.firstFailure = FirstFailure{Package: event.Package, Test: TestName(event.Test)}
+	e.firstFailureTime = eventTime
+}
+
+// FirstFailure returns the first test, or package, that failed. Returns nil
+// if nothing failed.
+func (e *Execution) FirstFailure() *FirstFailure {
+	if e == nil || e.firstFailureTime.IsZero() {
+		return nil
+	}
+	start := e.started
+	// Events read from a file have a time from before the Execution started,
+	// so use the time of the first event instead.
+	if e.firstFailureTime.Before(start) {
+		for _, pkg := range e.packages {
+			if pkg.firstEvent.Before(start) {
+				start = pkg.firstEvent
+			}
+		}
+	}
+	result := e.firstFailure
+	result.Elapsed = e.firstFailureTime.Sub(start)
+	return &result
+}
+
+// writeFirstFailure prints the time until the first failure, and an estimate
+// of the time that would have been saved by stopping the run at the first
+// failure.
+func writeFirstFailure(out io.Writer, execution *Execution, opts FormatOptions) {
+	first := execution.FirstFailure()
+	if first == nil {
+		return
+	}
+	name := RelativePackagePath(first.Package)
+	if first.Test != "" {
+		name += " " + first.Test.Name()
+	}
+	saved := execution.Elapsed() - first.Elapsed
+	if saved < 0 {
+		saved = 0
+	}
+	fmt.Fprintf(out, "First failure after %s in %s, failing fast would have saved %s\n",
+		strings.TrimLeft(opts.formatDuration(first.Elapsed, 3, DurationSeconds), " "),
+		name,
+		strings.TrimLeft(opts.formatDuration(saved, 3, DurationSeconds), " "))
+}
@@ -0,0 +1,54 @@
+package testjson
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestExecution_FirstFailure(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+	fake, reset := patchClock()
+	defer reset()
+
+	exec := newExecution()
+	assert.Assert(t, exec.FirstFailure() == nil)
+
+	events := []TestEvent{
+		{Action: ActionRun, Package: "example.com/pkg", Test: "TestOne"},
+		{Action: ActionPass, Package: "example.com/pkg", Test: "TestOne"},
+		{Action: ActionRun, Package: "example.com/pkg", Test: "TestTwo"},
+		{Action: ActionFail, Package: "example.com/pkg", Test: "TestTwo"},
+		{Action: ActionFail, Package: "example.com/other"},
+		{Action: ActionFail, Package: "example.com/pkg"},
+	}
+	for _, event := range events {
+		fake.Advance(2 * time.Second)
+		exec.add(event)
+	}
+	fake.Advance(10 * time.Second)
+
+	expected := &FirstFailure{Package: "example.com/pkg", Test: "TestTwo", Elapsed: 8 * time.Second}
+	assert.DeepEqual(t, exec.FirstFailure(), expected)
+
+	out := new(bytes.Buffer)
+	exec.done = true
+	PrintSummaryWithOptions(out, exec, SummarizeNone, FormatOptions{ShowFirstFailure: true})
+	assert.Equal(t, out.String(), `
+DONE 2 tests, 2 failures in 22.000s
+First failure after 8.000s in pkg TestTwo, failing fast would have saved 14.000s
+`)
+}
+
+func TestExecution_FirstFailure_FromFile(t *testing.T) {
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	exec := newExecution()
+	exec.add(TestEvent{Time: start, Action: ActionRun, Package: "example.com/pkg", Test: "TestOne"})
+	exec.add(TestEvent{Time: start.Add(3 * time.Second), Action: ActionFail, Package: "example.com/pkg", Test: "TestOne"})
+
+	first := exec.FirstFailure()
+	assert.Assert(t, first != nil)
+	assert.Equal(t, first.Elapsed, 3*time.Second)
+}
@@ -0,0 +1,195 @@
+package testjson
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LogFolding is the kind of markers printed around groups of lines, so that a
+// CI log viewer can collapse each group.
+type LogFolding int
+
+const (
+	// LogFoldingNone does not print any markers.
+	LogFoldingNone LogFolding = iota
+	// LogFoldingGitHub prints ::group:: and ::endgroup:: workflow commands.
+	LogFoldingGitHub
+	// LogFoldingGitLab prints section_start and section_end markers.
+	LogFoldingGitLab
+	// LogFoldingBuildkite prints --- and +++ group headers.
+	LogFoldingBuildkite
+)
+
+var logFoldingValues = map[string]LogFolding{
+	"none":      LogFoldingNone,
+	"github":    LogFoldingGitHub,
+	"gitlab":    LogFoldingGitLab,
+	"buildkite": LogFoldingBuildkite,
+}
+
+// NewLogFolding returns a LogFolding from a string value. If the string does
+// not match any known values returns false for the second value.
+func NewLogFolding(value string) (LogFolding, bool) {
+	f, ok := logFoldingValues[value]
+	return f, ok
+}
+
+func (f LogFolding) String() string {
+	for name, value := range logFoldingValues {
+		if value == f {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// Start writes the marker which starts a group of lines with the title. name
+// identifies the group, and must be passed to End. Groups which are not
+// collapsed are expanded by the log viewers which support it.
+func (f LogFolding) Start(out io.Writer, name string, title string, collapsed bool) {
+	switch f {
+	case LogFoldingGitHub:
+		fmt.Fprintf(out, "::group::%s\n", escapeWorkflowData(title))
+	case LogFoldingGitLab:
+		var option string
+		if collapsed {
+			option = "[collapsed=true]"
+		}
+		fmt.Fprintf(out, "\x1b[0Ksection_start:%d:%s%s\r\x1b[0K%s\n",
+			clock.Now().Unix(), gitLabSectionName(name), option, title)
+	case LogFoldingBuildkite:
+		header := "---"
+		if !collapsed {
+			header = "+++"
+		}
+		fmt.Fprintf(out, "%s %s\n", header, title)
+	}
+}
+
+// End writes the marker which ends the group started with name. Buildkite does
+// not have an end marker, a group ends when the next group starts.
+func (f LogFolding) End(out io.Writer, name string) {
+	switch f {
+	case LogFoldingGitHub:
+		fmt.Fprintln(out, "::endgroup::")
+	case LogFoldingGitLab:
+		fmt.Fprintf(out, "\x1b[0Ksection_end:%d:%s\r\x1b[0K\n",
+			clock.Now().Unix(), gitLabSectionName(name))
+	}
+}
+
+var gitLabSectionNameReplacer = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// gitLabSectionName returns name with all the characters which are not allowed
+// in a GitLab section name replaced by '_'.
+func gitLabSectionName(name string) string {
+	return gitLabSectionNameReplacer.ReplaceAllString(name, "_")
+}
+
+// foldingFormatter buffers the output of a formatter for each package, and
+// writes it in a group of FormatOptions.LogFolding when the package ends, so
+// that the output of each package can be collapsed in a CI log viewer. The
+// output of a package which is a single line, like the package line of the
+// pkgname format, is written without a group. Failed packages are in an
+// expanded group.
+type foldingFormatter struct {
+	out       io.Writer
+	opts      FormatOptions
+	formatter EventFormatter
+	current   *switchWriter
+	pkgs      map[string]*bytes.Buffer
+}
+
+// foldedFormats are the formats which print the output of a package in
+// groups with FormatOptions.LogFolding. The dots formats print the tests of
+// many packages on each line, and the other formats have their own markup.
+func foldedFormat(format string) bool {
+	switch format {
+	case "dots", "dots-v1", "dots-v2", "github-actions", "teamcity", "tap":
+		return false
+	}
+	return true
+}
+
+// newFoldableEventFormatter returns the formatter for format, wrapped by a
+// foldingFormatter when the options and the format use LogFolding.
+func newFoldableEventFormatter(out io.Writer, format string, opts FormatOptions) EventFormatter {
+	if opts.LogFolding == LogFoldingNone || !foldedFormat(format) {
+		return newEventFormatter(out, format, opts)
+	}
+	current := &switchWriter{out: out}
+	formatter := newEventFormatter(current, format, opts)
+	if formatter == nil {
+		return nil
+	}
+	return &foldingFormatter{
+		out:       out,
+		opts:      opts,
+		formatter: formatter,
+		current:   current,
+		pkgs:      make(map[string]*bytes.Buffer),
+	}
+}
+
+func (f *foldingFormatter) Format(event TestEvent, exec *Execution) error {
+	if event.Package == "" {
+		f.current.out = f.out
+		return f.formatter.Format(event, exec)
+	}
+	buf, ok := f.pkgs[event.Package]
+	if !ok {
+		buf = new(bytes.Buffer)
+		f.pkgs[event.Package] = buf
+	}
+	f.current.out = buf
+	if err := f.formatter.Format(event, exec); err != nil {
+		return err
+	}
+	if !event.PackageEvent() || !event.Action.IsTerminal() {
+		return nil
+	}
+
+	delete(f.pkgs, event.Package)
+	output := buf.String()
+	if strings.Count(strings.TrimSuffix(output, "\n"), "\n") == 0 {
+		_, err := io.WriteString(f.out, output)
+		return err
+	}
+	title, _ := shortFormatPackageEvent(f.opts, event, exec)
+	group := new(strings.Builder)
+	f.opts.LogFolding.Start(group, event.Package, strings.TrimSpace(title), event.Action != ActionFail)
+	group.WriteString(output)
+	if !strings.HasSuffix(output, "\n") {
+		group.WriteString("\n")
+	}
+	f.opts.LogFolding.End(group, event.Package)
+	_, err := io.WriteString(f.out, group.String())
+	return err
+}
+
+// Flush writes the output of the packages which did not end, sorted by
+// package name, without a group.
+func (f *foldingFormatter) Flush() error {
+	f.current.out = f.out
+	if flusher, ok := f.formatter.(Flusher); ok {
+		if err := flusher.Flush(); err != nil {
+			return err
+		}
+	}
+	names := make([]string, 0, len(f.pkgs))
+	for name := range f.pkgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := f.out.Write(f.pkgs[name].Bytes()); err != nil {
+			return err
+		}
+	}
+	f.pkgs = make(map[string]*bytes.Buffer)
+	return nil
+}
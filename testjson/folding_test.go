@@ -0,0 +1,83 @@
+package testjson
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestFoldingFormatter(t *testing.T) {
+	fake, reset := patchClock()
+	defer reset()
+
+	events := []TestEvent{
+		{Package: "example.com/pass", Action: ActionRun, Test: "TestOne"},
+		{Package: "example.com/fail", Action: ActionRun, Test: "TestOne"},
+		{Package: "example.com/pass", Action: ActionPass, Test: "TestOne"},
+		{Package: "example.com/fail", Action: ActionFail, Test: "TestOne"},
+		{Package: "example.com/pass", Action: ActionRun, Test: "TestTwo"},
+		{Package: "example.com/pass", Action: ActionPass, Test: "TestTwo"},
+		{Package: "example.com/pass", Action: ActionPass},
+		{Package: "example.com/fail", Action: ActionFail},
+		{Package: "example.com/empty", Action: ActionSkip},
+	}
+	format := func(format string, folding LogFolding) string {
+		exec := newExecution()
+		buf := new(bytes.Buffer)
+		formatter := NewEventFormatterWithOptions(buf, format, FormatOptions{LogFolding: folding})
+		for _, event := range events {
+			exec.add(event)
+			assert.NilError(t, formatter.Format(event, exec))
+		}
+		if flusher, ok := formatter.(Flusher); ok {
+			assert.NilError(t, flusher.Flush())
+		}
+		return buf.String()
+	}
+
+	t.Run("github", func(t *testing.T) {
+		expected := `::group::✓  example.com/pass
+PASS example.com/pass.TestOne (0.00s)
+PASS example.com/pass.TestTwo (0.00s)
+PASS example.com/pass
+::endgroup::
+::group::✖  example.com/fail
+FAIL example.com/fail.TestOne (0.00s)
+FAIL example.com/fail
+::endgroup::
+EMPTY example.com/empty
+`
+		assert.Equal(t, format("testname", LogFoldingGitHub), expected)
+	})
+
+	t.Run("gitlab", func(t *testing.T) {
+		ts := fake.Now().Unix()
+		expected := fmt.Sprintf("\x1b[0Ksection_start:%d:example.com_pass[collapsed=true]\r\x1b[0K✓  example.com/pass\n", ts) +
+			"PASS example.com/pass.TestOne (0.00s)\nPASS example.com/pass.TestTwo (0.00s)\nPASS example.com/pass\n" +
+			fmt.Sprintf("\x1b[0Ksection_end:%d:example.com_pass\r\x1b[0K\n", ts)
+		out := format("testname", LogFoldingGitLab)
+		assert.Assert(t, bytes.HasPrefix([]byte(out), []byte(expected)), out)
+	})
+
+	t.Run("buildkite", func(t *testing.T) {
+		out := format("testname", LogFoldingBuildkite)
+		assert.Assert(t, bytes.Contains([]byte(out), []byte("--- ✓  example.com/pass\n")), out)
+		assert.Assert(t, bytes.Contains([]byte(out), []byte("+++ ✖  example.com/fail\n")), out)
+	})
+
+	t.Run("dots are not folded", func(t *testing.T) {
+		assert.Equal(t, format("dots", LogFoldingGitHub), format("dots", LogFoldingNone))
+	})
+}
+
+func TestNewLogFolding(t *testing.T) {
+	for _, name := range []string{"none", "github", "gitlab", "buildkite"} {
+		folding, ok := NewLogFolding(name)
+		assert.Assert(t, ok, name)
+		assert.Equal(t, folding.String(), name)
+	}
+	_, ok := NewLogFolding("jenkins")
+	assert.Assert(t, !ok)
+}
@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/fatih/color"
+	"gotest.tools/gotestsum/log"
 )
 
 func debugFormat(event TestEvent, _ *Execution) (string, error) {
@@ -18,12 +21,13 @@ func debugFormat(event TestEvent, _ *Execution) (string, error) {
 		event.Output), nil
 }
 
-// go test -v
-func standardVerboseFormat(event TestEvent, _ *Execution) (string, error) {
-	if event.Action == ActionOutput {
-		return event.Output, nil
+// go test -v. With FormatOptions.BufferTestOutput the output of each test is
+// printed as a single block when the test ends.
+func standardVerboseFormat(opts FormatOptions) func(event TestEvent, _ *Execution) (string, error) {
+	blocks := newTestOutputBlocks(opts)
+	return func(event TestEvent, _ *Execution) (string, error) {
+		return blocks.next(event), nil
 	}
-	return "", nil
 }
 
 // go test
@@ -41,16 +45,28 @@ func standardQuietFormat(event TestEvent, _ *Execution) (string, error) {
 	return event.Output, nil
 }
 
-func testNameFormat(event TestEvent, exec *Execution) (string, error) {
+// testNameFormat prints a line for each test and package result. At
+// VerbosityQuiet only failures are printed, at VerbosityVerbose skipped tests
+// are also printed, and at VerbosityVeryVerbose the output of every test is
+// printed before its result.
+func testNameFormat(opts FormatOptions) func(event TestEvent, exec *Execution) (string, error) {
+	buf := newTestOutputBuffer(opts)
+	return func(event TestEvent, exec *Execution) (string, error) {
+		buf.add(event)
+		return testNameFormatEvent(opts, buf, event, exec)
+	}
+}
+
+func testNameFormatEvent(opts FormatOptions, buf *testOutputBuffer, event TestEvent, exec *Execution) (string, error) {
 	result := colorEvent(event)(strings.ToUpper(string(event.Action)))
 	formatTest := func() string {
 		pkgPath := RelativePackagePath(event.Package)
 
-		return fmt.Sprintf("%s %s%s %s\n",
+		return fmt.Sprintf("%s %s%s (%s)\n",
 			result,
 			joinPkgToTestName(pkgPath, event.Test),
 			formatRunID(event.RunID),
-			event.ElapsedFormatted())
+			opts.formatDuration(time.Duration(event.Elapsed*float64(time.Second)), 2, DurationSeconds))
 	}
 
 	switch {
@@ -61,6 +77,9 @@ func testNameFormat(event TestEvent, exec *Execution) (string, error) {
 		if !event.Action.IsTerminal() {
 			return "", nil
 		}
+		if opts.Verbosity <= VerbosityQuiet && event.Action != ActionFail {
+			return "", nil
+		}
 		pkg := exec.Package(event.Package)
 		if event.Action == ActionSkip || (event.Action == ActionPass && pkg.Total == 0) {
 			result = colorEvent(event)("EMPTY")
@@ -78,10 +97,27 @@ func testNameFormat(event TestEvent, exec *Execution) (string, error) {
 	case event.Action == ActionFail:
 		pkg := exec.Package(event.Package)
 		tc := pkg.LastFailedByName(event.Test)
+		buf.take(event)
+		if !opts.ShowOutput.Failed() {
+			return formatTest(), nil
+		}
 		return pkg.Output(tc.ID) + formatTest(), nil
 
 	case event.Action == ActionPass:
-		return formatTest(), nil
+		if opts.Verbosity <= VerbosityQuiet {
+			return "", nil
+		}
+		return buf.take(event) + formatTest(), nil
+
+	case event.Action == ActionSkip:
+		output := buf.take(event)
+		if opts.Verbosity < VerbosityVerbose {
+			return "", nil
+		}
+		if opts.Verbosity < VerbosityVeryVerbose {
+			output = ""
+		}
+		return output + formatTest(), nil
 	}
 	return "", nil
 }
@@ -135,14 +171,32 @@ func all(cond ...bool) bool {
 
 const cachedMessage = " (cached)"
 
-func pkgNameFormat(event TestEvent, exec *Execution) (string, error) {
-	if !event.PackageEvent() {
+// pkgNameFormat prints a line for each package. At VerbosityQuiet only the
+// packages which failed are printed. At VerbosityVerbose or higher, or when
+// the output of passed tests is shown, the format is the same as
+// pkgNameWithFailuresFormat.
+func pkgNameFormat(opts FormatOptions) func(event TestEvent, exec *Execution) (string, error) {
+	if opts.Verbosity >= VerbosityVerbose || opts.ShowOutput.Passed() {
+		return pkgNameWithFailuresFormat(opts)
+	}
+	return func(event TestEvent, exec *Execution) (string, error) {
+		if !event.PackageEvent() {
+			return "", nil
+		}
+		return quietFormatPackageEvent(opts, event, exec)
+	}
+}
+
+// quietFormatPackageEvent is shortFormatPackageEvent, except that at
+// VerbosityQuiet only the packages which failed are printed.
+func quietFormatPackageEvent(opts FormatOptions, event TestEvent, exec *Execution) (string, error) {
+	if opts.Verbosity <= VerbosityQuiet && event.Action != ActionFail {
 		return "", nil
 	}
-	return shortFormatPackageEvent(event, exec)
+	return shortFormatPackageEvent(opts, event, exec)
 }
 
-func shortFormatPackageEvent(event TestEvent, exec *Execution) (string, error) {
+func shortFormatPackageEvent(opts FormatOptions, event TestEvent, exec *Execution) (string, error) {
 	pkg := exec.Package(event.Package)
 
 	fmtElapsed := func() string {
@@ -150,10 +204,17 @@ func shortFormatPackageEvent(event TestEvent, exec *Execution) (string, error) {
 			return cachedMessage
 		}
 		d := elapsedDuration(event.Elapsed)
+		if opts.ShowBuildTime && !opts.Deterministic {
+			if build := exec.buildElapsed(pkg); build > 0 {
+				return fmt.Sprintf(" (build %s, test %s)",
+					opts.formatDuration(build, 3, DurationUnits),
+					opts.formatDuration(d, 3, DurationUnits))
+			}
+		}
 		if d == 0 {
 			return ""
 		}
-		return fmt.Sprintf(" (%s)", d)
+		return fmt.Sprintf(" (%s)", opts.formatDuration(d, 3, DurationUnits))
 	}
 	fmtCoverage := func() string {
 		if pkg.coverage == "" {
@@ -161,39 +222,76 @@ func shortFormatPackageEvent(event TestEvent, exec *Execution) (string, error) {
 		}
 		return " (" + pkg.coverage + ")"
 	}
+	fmtStatus := func() string {
+		switch {
+		case pkg.exitedUnexpectedly:
+			return " (exited unexpectedly)"
+		case exec.isTeardownFailure(pkg):
+			return " (teardown error)"
+		}
+		return ""
+	}
+	fmtSlow := func() string {
+		if pkg.cached || pkg.Total == 0 {
+			return ""
+		}
+		return opts.slowPackageMarker(event.Package, elapsedDuration(event.Elapsed))
+	}
 	fmtEvent := func(action string) (string, error) {
-		return fmt.Sprintf("%s  %s%s%s\n",
+		return fmt.Sprintf("%s  %s%s%s%s%s\n",
 			action,
 			RelativePackagePath(event.Package),
 			fmtElapsed(),
 			fmtCoverage(),
+			fmtStatus(),
+			fmtSlow(),
 		), nil
 	}
 	withColor := colorEvent(event)
+	symbols := opts.symbols()
 	switch event.Action {
 	case ActionSkip:
-		return fmtEvent(withColor("∅"))
+		return fmtEvent(withColor(symbols.empty))
 	case ActionPass:
 		if pkg.Total == 0 {
-			return fmtEvent(withColor("∅"))
+			return fmtEvent(withColor(symbols.empty))
 		}
-		return fmtEvent(withColor("✓"))
+		return fmtEvent(withColor(symbols.pass))
 	case ActionFail:
-		return fmtEvent(withColor("✖"))
+		if exec.isTeardownFailure(pkg) {
+			return fmtEvent(color.YellowString(symbols.teardown))
+		}
+		return fmtEvent(withColor(symbols.fail))
 	}
 	return "", nil
 }
 
-func pkgNameWithFailuresFormat(event TestEvent, exec *Execution) (string, error) {
-	if !event.PackageEvent() {
-		if event.Action == ActionFail {
-			pkg := exec.Package(event.Package)
-			tc := pkg.LastFailedByName(event.Test)
-			return pkg.Output(tc.ID), nil
+// pkgNameWithFailuresFormat prints a line for each package, and the output of
+// each failed test. At VerbosityQuiet only the packages which failed are
+// printed, and at VerbosityVeryVerbose the output of every test is printed.
+func pkgNameWithFailuresFormat(opts FormatOptions) func(event TestEvent, exec *Execution) (string, error) {
+	buf := newTestOutputBuffer(opts)
+	return func(event TestEvent, exec *Execution) (string, error) {
+		buf.add(event)
+		if !event.PackageEvent() {
+			if !event.Action.IsTerminal() {
+				return "", nil
+			}
+			output := buf.take(event)
+			switch {
+			case event.Action == ActionFail && opts.ShowOutput.Failed():
+				pkg := exec.Package(event.Package)
+				tc := pkg.LastFailedByName(event.Test)
+				return pkg.Output(tc.ID), nil
+			case event.Action == ActionPass:
+				return output, nil
+			case event.Action == ActionSkip && opts.Verbosity >= VerbosityVeryVerbose:
+				return output, nil
+			}
+			return "", nil
 		}
-		return "", nil
+		return quietFormatPackageEvent(opts, event, exec)
 	}
-	return shortFormatPackageEvent(event, exec)
 }
 
 func colorEvent(event TestEvent) func(format string, a ...interface{}) string {
@@ -214,26 +312,217 @@ type EventFormatter interface {
 	Format(event TestEvent, output *Execution) error
 }
 
+// FormatOptions used to customize the output of an EventFormatter.
+type FormatOptions struct {
+	// ShowBuildTime adds the time spent building each package, separate from
+	// the time spent running the tests, to the package lines of the pkgname
+	// formats.
+	ShowBuildTime bool
+	// DurationFormat is the format used to print elapsed times in the testname
+	// and pkgname formats, and in the summary.
+	DurationFormat DurationFormat
+	// DecimalSeparator replaces the '.' in elapsed times. Defaults to '.'.
+	DecimalSeparator string
+	// ThousandsSeparator is printed between each group of three digits in
+	// counts of tests in the summary. Defaults to no separator.
+	ThousandsSeparator string
+	// AlignDurations pads elapsed times to a fixed width, so that they line up
+	// when they are printed on consecutive lines.
+	AlignDurations bool
+	// Deterministic makes the output the same for every run of the same tests.
+	// Elapsed times are printed as zero, build times are omitted, and test
+	// cases in the summary are sorted by name. The output of the formatter is
+	// buffered, and written sorted by package when the formatter is flushed.
+	// See Flusher.
+	Deterministic bool
+	// HighlightLogLevels colors the lines of test output in the summary which
+	// look like error or warning log lines, panics, or data race reports.
+	HighlightLogLevels bool
+	// FirstErrorFirst prints the first line of test output which looks like an
+	// error at the top of the output of each test in the summary.
+	FirstErrorFirst bool
+	// GroupFailures groups the failed tests in the summary which failed with
+	// the same FailureCause. The output is printed only for the first test of
+	// each group.
+	GroupFailures bool
+	// FailureLink is executed with the TestCase of each failed test to create
+	// a link which is printed with the failure in the summary. The link is
+	// usually the URL of the logs or traces of the test in another system.
+	FailureLink *template.Template
+	// Labels is a comma separated list of labels. When it is set, only the
+	// tests with at least one of the labels are listed in the summary. See
+	// LabelMarker.
+	Labels string
+	// KnownIssues are used to annotate failed tests in the summary with the
+	// URL of the issue that tracks the failure, or the fingerprint of the
+	// failure when it is not a known issue.
+	KnownIssues *KnownIssues
+	// Quarantine is used to annotate the failures of quarantined tests in the
+	// summary.
+	Quarantine *Quarantine
+	// ShowFirstFailure prints the time until the first failure after the
+	// summary.
+	ShowFirstFailure bool
+	// Accessible uses a different symbol, or word, for each result in every
+	// format, so that the result is never shown only by color.
+	Accessible bool
+	// Verbosity is the level of detail printed by the testname and pkgname
+	// formats. See VerbosityQuiet, VerbosityVerbose, and VerbosityVeryVerbose.
+	// Other formats ignore it.
+	Verbosity int
+	// ShowOutput selects the results of the tests whose output is printed by
+	// the testname and pkgname formats.
+	ShowOutput ShowOutput
+	// SlowPackageThreshold marks the packages in the pkgname formats which
+	// took longer than the threshold to run. Zero disables the marker.
+	SlowPackageThreshold time.Duration
+	// SlowPackageBaseline is the usual elapsed time of each package, for
+	// example the average of previous runs. Packages which took more than
+	// SlowPackagePercent longer than their baseline are marked as slow in the
+	// pkgname formats.
+	SlowPackageBaseline *PackageBaseline
+	// SlowPackagePercent is the increase over SlowPackageBaseline, as a
+	// percentage, at which a package is marked as slow. Zero disables the
+	// marker.
+	SlowPackagePercent int
+	// BufferTestOutput prints the output of each test as a single block when
+	// the test ends, instead of as it is received, so that the output of
+	// parallel tests is not interleaved. It is used by the standard-verbose
+	// and github-actions formats.
+	BufferTestOutput bool
+	// ShowPackageStart prints a line when each package starts in the testname
+	// and pkgname formats.
+	ShowPackageStart bool
+	// PackageTestCounts adds the number of tests in each package to the lines
+	// printed by ShowPackageStart.
+	PackageTestCounts *PackageTestCounts
+	// Template is executed with a TemplateData for each event by the template
+	// format. It must be parsed with TemplateFuncs.
+	Template *template.Template
+	// LogFolding prints the output of each package in a group which can be
+	// collapsed by a CI log viewer. It is ignored by the dots formats, and by
+	// the formats for a CI system.
+	LogFolding LogFolding
+	// CoverProfile is the path of the file written by go test -coverprofile.
+	// It is used to weight the total coverage printed in the summary by the
+	// number of statements in each package. See Execution.TotalCoverage.
+	CoverProfile string
+}
+
+// Link returns the link for the failed test case from the FailureLink
+// template, or an empty string if there is no template.
+func (o FormatOptions) Link(tc TestCase) string {
+	if o.FailureLink == nil {
+		return ""
+	}
+	buf := new(strings.Builder)
+	if err := o.FailureLink.Execute(buf, tc); err != nil {
+		log.Warnf("failed to execute link template for %v %v: %v", tc.Package, tc.Test, err)
+		return ""
+	}
+	return buf.String()
+}
+
+// resultSymbols are the symbols used to print the result of a test or package.
+type resultSymbols struct {
+	// pass, fail, empty, and teardown are used by the pkgname formats.
+	pass     string
+	fail     string
+	empty    string
+	teardown string
+	// start is used by FormatOptions.ShowPackageStart.
+	start string
+	// dotPass, dotFail, and dotSkip are used by the dots formats.
+	dotPass string
+	dotFail string
+	dotSkip string
+}
+
+var defaultSymbols = resultSymbols{
+	pass:     "✓",
+	fail:     "✖",
+	empty:    "∅",
+	teardown: "✓",
+	start:    "▶",
+	dotPass:  "·",
+	dotFail:  "✖",
+	dotSkip:  "↷",
+}
+
+// accessibleSymbols are used by FormatOptions.Accessible. The symbols for
+// a package are the same words used by the testname format and the summary,
+// and the dots are the characters used by many xUnit test runners.
+var accessibleSymbols = resultSymbols{
+	pass:     "PASS",
+	fail:     "FAIL",
+	empty:    "EMPTY",
+	teardown: "TEARDOWN",
+	start:    "START",
+	dotPass:  ".",
+	dotFail:  "F",
+	dotSkip:  "S",
+}
+
+func (o FormatOptions) symbols() resultSymbols {
+	if o.Accessible {
+		return accessibleSymbols
+	}
+	return defaultSymbols
+}
+
 // NewEventFormatter returns a formatter for printing events.
+//
+// Deprecated: use NewEventFormatterWithOptions, which accepts FormatOptions.
 func NewEventFormatter(out io.Writer, format string) EventFormatter {
+	return NewEventFormatterWithOptions(out, format, FormatOptions{})
+}
+
+// NewEventFormatterWithOptions returns a formatter for printing events. The
+// format may be the name of a built-in format, or of a format added with
+// RegisterFormat. Returns nil if there is no format with that name.
+func NewEventFormatterWithOptions(out io.Writer, format string, formatOpts FormatOptions) EventFormatter {
+	// The tap format numbers the packages in the order they are printed, so
+	// the output can not be sorted.
+	if formatOpts.Deterministic && format != "tap" {
+		return newSortedFormatter(out, format, formatOpts)
+	}
+	return newFoldableEventFormatter(out, format, formatOpts)
+}
+
+func newEventFormatter(out io.Writer, format string, formatOpts FormatOptions) EventFormatter {
 	switch format {
 	case "debug":
 		return &formatAdapter{out, debugFormat}
 	case "standard-verbose":
-		return &formatAdapter{out, standardVerboseFormat}
+		return &formatAdapter{out, standardVerboseFormat(formatOpts)}
 	case "standard-quiet":
 		return &formatAdapter{out, standardQuietFormat}
 	case "dots", "dots-v1":
-		return &formatAdapter{out, dotsFormatV1}
+		return &formatAdapter{out, dotsFormatV1(formatOpts)}
 	case "dots-v2":
-		return newDotFormatter(out)
+		return newDotFormatter(out, formatOpts)
 	case "testname", "short-verbose":
-		return &formatAdapter{out, testNameFormat}
+		return &formatAdapter{out, withPackageStart(formatOpts, testNameFormat(formatOpts))}
 	case "pkgname", "short":
-		return &formatAdapter{out, pkgNameFormat}
+		return &formatAdapter{out, withPackageStart(formatOpts, pkgNameFormat(formatOpts))}
 	case "pkgname-and-test-fails", "short-with-failures":
-		return &formatAdapter{out, pkgNameWithFailuresFormat}
+		return &formatAdapter{out, withPackageStart(formatOpts, pkgNameWithFailuresFormat(formatOpts))}
+	case "tap":
+		return newTAPFormatter(out, formatOpts)
+	case "github-actions":
+		return newGitHubActionsFormatter(out, formatOpts)
+	case "teamcity":
+		return newTeamCityFormatter(out, formatOpts)
+	case "template":
+		return &formatAdapter{out, templateFormat(formatOpts)}
+	case "screen-reader":
+		return &formatAdapter{out, screenReaderFormat(formatOpts, false)}
+	case "screen-reader-failures":
+		return &formatAdapter{out, screenReaderFormat(formatOpts, true)}
 	default:
+		if factory := lookupFormat(format); factory != nil {
+			return factory(out, formatOpts)
+		}
 		return nil
 	}
 }
@@ -69,7 +69,7 @@ func patchPkgPathPrefix(val string) func() {
 func TestScanTestOutput_WithTestNameFormat(t *testing.T) {
 	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
 
-	shim := newFakeHandlerWithAdapter(testNameFormat, "go-test-json")
+	shim := newFakeHandlerWithAdapter(testNameFormat(FormatOptions{}), "go-test-json")
 	exec, err := ScanTestOutput(shim.Config(t))
 
 	assert.NilError(t, err)
@@ -82,6 +82,15 @@ var expectedExecution = &Execution{
 	done:    true,
 	started: time.Now(),
 	errors:  []string{"internal/broken/broken.go:5:21: undefined: somepackage"},
+	errorEntries: []ErrorEntry{{
+		Kind:    ErrorBuild,
+		Package: "github.com/gotestyourself/gotestyourself/testjson/internal/broken",
+		Lines:   []string{"internal/broken/broken.go:5:21: undefined: somepackage"},
+	}},
+	firstFailure: FirstFailure{
+		Package: "github.com/gotestyourself/gotestyourself/testjson/internal/badmain",
+	},
+	firstFailureTime: time.Date(2018, 3, 22, 22, 33, 35, 157410331, time.UTC),
 	packages: map[string]*Package{
 		"github.com/gotestyourself/gotestyourself/testjson/internal/good": {
 			Total: 18,
@@ -133,6 +142,8 @@ var cmpPackageShallow = gocmp.Options{
 	gocmp.FilterPath(opt.PathField(Package{}, "output"), gocmp.Ignore()),
 	gocmp.FilterPath(opt.PathField(Package{}, "Passed"), gocmp.Ignore()),
 	gocmp.FilterPath(opt.PathField(Package{}, "subTests"), gocmp.Ignore()),
+	gocmp.FilterPath(opt.PathField(Package{}, "firstEvent"), gocmp.Ignore()),
+	gocmp.FilterPath(opt.PathField(Package{}, "lastEvent"), gocmp.Ignore()),
 	gocmp.Comparer(func(x, y TestCase) bool {
 		return x.Test == y.Test
 	}),
@@ -147,7 +158,7 @@ func stringPath(spec string) func(gocmp.Path) bool {
 func TestScanTestOutputWithDotsFormatV1(t *testing.T) {
 	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
 
-	shim := newFakeHandlerWithAdapter(dotsFormatV1, "go-test-json")
+	shim := newFakeHandlerWithAdapter(dotsFormatV1(FormatOptions{}), "go-test-json")
 	exec, err := ScanTestOutput(shim.Config(t))
 
 	assert.NilError(t, err)
@@ -159,7 +170,7 @@ func TestScanTestOutputWithDotsFormatV1(t *testing.T) {
 func TestScanTestOutput_WithPkgNameFormat(t *testing.T) {
 	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
 
-	shim := newFakeHandlerWithAdapter(pkgNameFormat, "go-test-json")
+	shim := newFakeHandlerWithAdapter(pkgNameFormat(FormatOptions{}), "go-test-json")
 	exec, err := ScanTestOutput(shim.Config(t))
 
 	assert.NilError(t, err)
@@ -171,7 +182,7 @@ func TestScanTestOutput_WithPkgNameFormat(t *testing.T) {
 func TestScanTestOutput_WithPkgNameFormat_WithCoverage(t *testing.T) {
 	defer patchPkgPathPrefix("gotest.tools")()
 
-	shim := newFakeHandlerWithAdapter(pkgNameFormat, "go-test-json-with-cover")
+	shim := newFakeHandlerWithAdapter(pkgNameFormat(FormatOptions{}), "go-test-json-with-cover")
 	exec, err := ScanTestOutput(shim.Config(t))
 
 	assert.NilError(t, err)
@@ -183,7 +194,7 @@ func TestScanTestOutput_WithPkgNameFormat_WithCoverage(t *testing.T) {
 func TestScanTestOutputWithStandardVerboseFormat(t *testing.T) {
 	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
 
-	shim := newFakeHandlerWithAdapter(standardVerboseFormat, "go-test-json")
+	shim := newFakeHandlerWithAdapter(standardVerboseFormat(FormatOptions{}), "go-test-json")
 	exec, err := ScanTestOutput(shim.Config(t))
 
 	assert.NilError(t, err)
@@ -192,6 +203,18 @@ func TestScanTestOutputWithStandardVerboseFormat(t *testing.T) {
 	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
 }
 
+func TestScanTestOutputWithStandardVerboseFormat_BufferTestOutput(t *testing.T) {
+	shim := newFakeHandlerWithAdapter(
+		standardVerboseFormat(FormatOptions{BufferTestOutput: true}), "go-test-json-with-parallel-fails")
+	_, err := ScanTestOutput(ScanConfig{
+		Stdout:  bytes.NewReader(golden.Get(t, "go-test-json-with-parallel-fails.out")),
+		Handler: shim,
+	})
+
+	assert.NilError(t, err)
+	golden.Assert(t, shim.out.String(), "go-test-verbose-buffered.out")
+}
+
 func TestScanTestOutputWithStandardQuietFormat(t *testing.T) {
 	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
 
@@ -220,6 +243,15 @@ var expectedCoverageExecution = &Execution{
 	done:    true,
 	started: time.Now(),
 	errors:  []string{"internal/broken/broken.go:5:21: undefined: somepackage"},
+	errorEntries: []ErrorEntry{{
+		Kind:    ErrorBuild,
+		Package: "gotest.tools/gotestsum/testjson/internal/broken",
+		Lines:   []string{"internal/broken/broken.go:5:21: undefined: somepackage"},
+	}},
+	firstFailure: FirstFailure{
+		Package: "gotest.tools/gotestsum/testjson/internal/badmain",
+	},
+	firstFailureTime: time.Date(2019, 6, 26, 23, 28, 47, 238295101, time.UTC),
 	packages: map[string]*Package{
 		"gotest.tools/gotestsum/testjson/internal/good": {
 			Total: 18,
@@ -256,3 +288,318 @@ var expectedCoverageExecution = &Execution{
 		},
 	},
 }
+
+func TestPkgNameFormat_ShowBuildTime(t *testing.T) {
+	start := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	exec := newExecution()
+	exec.started = start
+
+	events := []TestEvent{
+		{Package: "example.com/pkg", Action: ActionRun, Test: "TestOne", Time: start.Add(1500 * time.Millisecond)},
+		{Package: "example.com/pkg", Action: ActionPass, Test: "TestOne", Time: start.Add(2 * time.Second)},
+		{Package: "example.com/pkg", Action: ActionPass, Time: start.Add(2 * time.Second), Elapsed: 0.52},
+	}
+	buf := new(bytes.Buffer)
+	formatter := NewEventFormatterWithOptions(buf, "pkgname", FormatOptions{ShowBuildTime: true})
+	for _, event := range events {
+		exec.add(event)
+		assert.NilError(t, formatter.Format(event, exec))
+	}
+	assert.Equal(t, buf.String(), "✓  example.com/pkg (build 1.5s, test 520ms)\n")
+}
+
+func TestNewEventFormatter_DefaultOptions(t *testing.T) {
+	events := []TestEvent{
+		{Package: "example.com/pkg", Action: ActionRun, Test: "TestOne"},
+		{Package: "example.com/pkg", Action: ActionPass, Test: "TestOne"},
+		{Package: "example.com/pkg", Action: ActionPass, Elapsed: 0.52},
+	}
+	format := func(formatter EventFormatter, buf *bytes.Buffer) string {
+		exec := newExecution()
+		for _, event := range events {
+			exec.add(event)
+			assert.NilError(t, formatter.Format(event, exec))
+		}
+		return buf.String()
+	}
+
+	buf := new(bytes.Buffer)
+	// nolint: staticcheck
+	got := format(NewEventFormatter(buf, "pkgname"), buf)
+	expectedBuf := new(bytes.Buffer)
+	expected := format(NewEventFormatterWithOptions(expectedBuf, "pkgname", FormatOptions{}), expectedBuf)
+	assert.Equal(t, got, expected)
+	// nolint: staticcheck
+	assert.Assert(t, NewEventFormatter(buf, "unknown") == nil)
+}
+
+func TestPkgNameFormat_SlowPackage(t *testing.T) {
+	events := []TestEvent{
+		{Package: "example.com/fast", Action: ActionRun, Test: "TestOne"},
+		{Package: "example.com/fast", Action: ActionPass, Test: "TestOne"},
+		{Package: "example.com/fast", Action: ActionPass, Elapsed: 0.5},
+		{Package: "example.com/slow", Action: ActionRun, Test: "TestOne"},
+		{Package: "example.com/slow", Action: ActionPass, Test: "TestOne"},
+		{Package: "example.com/slow", Action: ActionPass, Elapsed: 3},
+		{Package: "example.com/regressed", Action: ActionRun, Test: "TestOne"},
+		{Package: "example.com/regressed", Action: ActionFail, Test: "TestOne"},
+		{Package: "example.com/regressed", Action: ActionFail, Elapsed: 1.5},
+		{Package: "example.com/empty", Action: ActionPass, Elapsed: 5},
+	}
+	format := func(opts FormatOptions) string {
+		exec := newExecution()
+		buf := new(bytes.Buffer)
+		formatter := NewEventFormatterWithOptions(buf, "pkgname", opts)
+		for _, event := range events {
+			exec.add(event)
+			assert.NilError(t, formatter.Format(event, exec))
+		}
+		return buf.String()
+	}
+	opts := FormatOptions{
+		SlowPackageThreshold: 2 * time.Second,
+		SlowPackageBaseline: NewPackageBaseline(map[string]time.Duration{
+			"example.com/fast":      450 * time.Millisecond,
+			"example.com/regressed": time.Second,
+		}),
+		SlowPackagePercent: 20,
+	}
+
+	expected := `✓  example.com/fast (500ms)
+✓  example.com/slow (3s) (slow)
+✖  example.com/regressed (1.5s) (slow, +50% vs 1s avg)
+∅  example.com/empty (5s)
+`
+	assert.Equal(t, format(opts), expected)
+
+	opts.Deterministic = true
+	assert.Equal(t, opts.slowPackageMarker("example.com/slow", 3*time.Second), "")
+}
+
+func TestFormats_PackageStart(t *testing.T) {
+	events := []TestEvent{
+		{Package: "example.com/one", Action: ActionStart},
+		{Package: "example.com/two", Action: ActionStart},
+		{Package: "example.com/one", Action: ActionRun, Test: "TestOne"},
+		{Package: "example.com/one", Action: ActionPass, Test: "TestOne"},
+		{Package: "example.com/one", Action: ActionPass},
+		// a package from go test before Go 1.20 has no start event
+		{Package: "example.com/old", Action: ActionRun, Test: "TestOld"},
+		{Package: "example.com/old", Action: ActionFail, Test: "TestOld"},
+		{Package: "example.com/old", Action: ActionFail},
+		{Package: "example.com/two", Action: ActionSkip},
+	}
+	format := func(name string, opts FormatOptions) string {
+		exec := newExecution()
+		buf := new(bytes.Buffer)
+		formatter := NewEventFormatterWithOptions(buf, name, opts)
+		for _, event := range events {
+			exec.add(event)
+			assert.NilError(t, formatter.Format(event, exec))
+		}
+		return buf.String()
+	}
+	opts := FormatOptions{
+		ShowPackageStart: true,
+		PackageTestCounts: NewPackageTestCounts(map[string]int{
+			"example.com/one": 1,
+			"example.com/two": 0,
+			"example.com/old": 12,
+		}),
+	}
+
+	expected := `▶  example.com/one started (1 test)
+▶  example.com/two started (0 tests)
+✓  example.com/one
+▶  example.com/old started (12 tests)
+✖  example.com/old
+∅  example.com/two
+`
+	assert.Equal(t, format("pkgname", opts), expected)
+
+	opts.PackageTestCounts = nil
+	expected = `▶  example.com/one started
+▶  example.com/two started
+PASS example.com/one.TestOne (0.00s)
+PASS example.com/one
+▶  example.com/old started
+FAIL example.com/old.TestOld (0.00s)
+FAIL example.com/old
+EMPTY example.com/two
+`
+	assert.Equal(t, format("testname", opts), expected)
+
+	opts.Verbosity = VerbosityQuiet
+	expected = `FAIL example.com/old.TestOld (0.00s)
+FAIL example.com/old
+`
+	assert.Equal(t, format("testname", opts), expected)
+}
+
+func TestFormats_Accessible(t *testing.T) {
+	events := []TestEvent{
+		{Package: "example.com/pkg", Action: ActionRun, Test: "TestPass"},
+		{Package: "example.com/pkg", Action: ActionPass, Test: "TestPass"},
+		{Package: "example.com/pkg", Action: ActionRun, Test: "TestFail"},
+		{Package: "example.com/pkg", Action: ActionFail, Test: "TestFail"},
+		{Package: "example.com/pkg", Action: ActionRun, Test: "TestSkip"},
+		{Package: "example.com/pkg", Action: ActionSkip, Test: "TestSkip"},
+		{Package: "example.com/pkg", Action: ActionFail},
+		{Package: "example.com/ok", Action: ActionRun, Test: "TestPass"},
+		{Package: "example.com/ok", Action: ActionPass, Test: "TestPass"},
+		{Package: "example.com/ok", Action: ActionPass},
+		{Package: "example.com/empty", Action: ActionSkip},
+	}
+	format := func(name string, opts FormatOptions) string {
+		exec := newExecution()
+		buf := new(bytes.Buffer)
+		formatter := NewEventFormatterWithOptions(buf, name, opts)
+		for _, event := range events {
+			exec.add(event)
+			assert.NilError(t, formatter.Format(event, exec))
+		}
+		return buf.String()
+	}
+
+	opts := FormatOptions{Accessible: true}
+	assert.Equal(t, format("pkgname", opts), `FAIL  example.com/pkg
+PASS  example.com/ok
+EMPTY  example.com/empty
+`)
+	assert.Equal(t, format("dots-v1", opts), "[example.com/pkg].FS[example.com/ok].")
+
+	assert.Equal(t, format("pkgname", FormatOptions{}), `✖  example.com/pkg
+✓  example.com/ok
+∅  example.com/empty
+`)
+	assert.Equal(t, format("dots-v1", FormatOptions{}), "[example.com/pkg]·✖↷[example.com/ok]·")
+}
+
+func TestFormats_Verbosity(t *testing.T) {
+	events := []TestEvent{
+		{Package: "example.com/pkg", Action: ActionRun, Test: "TestPass"},
+		{Package: "example.com/pkg", Action: ActionOutput, Test: "TestPass", Output: "pass output\n"},
+		{Package: "example.com/pkg", Action: ActionPass, Test: "TestPass"},
+		{Package: "example.com/pkg", Action: ActionRun, Test: "TestFail"},
+		{Package: "example.com/pkg", Action: ActionOutput, Test: "TestFail", Output: "fail output\n"},
+		{Package: "example.com/pkg", Action: ActionFail, Test: "TestFail"},
+		{Package: "example.com/pkg", Action: ActionRun, Test: "TestSkip"},
+		{Package: "example.com/pkg", Action: ActionOutput, Test: "TestSkip", Output: "skip output\n"},
+		{Package: "example.com/pkg", Action: ActionSkip, Test: "TestSkip"},
+		{Package: "example.com/pkg", Action: ActionFail},
+		{Package: "example.com/ok", Action: ActionRun, Test: "TestPass"},
+		{Package: "example.com/ok", Action: ActionOutput, Test: "TestPass", Output: "ok output\n"},
+		{Package: "example.com/ok", Action: ActionPass, Test: "TestPass"},
+		{Package: "example.com/ok", Action: ActionPass},
+	}
+	format := func(name string, verbosity int) string {
+		exec := newExecution()
+		buf := new(bytes.Buffer)
+		formatter := NewEventFormatterWithOptions(buf, name, FormatOptions{Verbosity: verbosity})
+		for _, event := range events {
+			exec.add(event)
+			assert.NilError(t, formatter.Format(event, exec))
+		}
+		return buf.String()
+	}
+
+	t.Run("testname", func(t *testing.T) {
+		assert.Equal(t, format("testname", VerbosityQuiet), `fail output
+FAIL example.com/pkg.TestFail (0.00s)
+FAIL example.com/pkg
+`)
+		assert.Equal(t, format("testname", VerbosityDefault), `PASS example.com/pkg.TestPass (0.00s)
+fail output
+FAIL example.com/pkg.TestFail (0.00s)
+FAIL example.com/pkg
+PASS example.com/ok.TestPass (0.00s)
+PASS example.com/ok
+`)
+		assert.Equal(t, format("testname", VerbosityVerbose), `PASS example.com/pkg.TestPass (0.00s)
+fail output
+FAIL example.com/pkg.TestFail (0.00s)
+SKIP example.com/pkg.TestSkip (0.00s)
+FAIL example.com/pkg
+PASS example.com/ok.TestPass (0.00s)
+PASS example.com/ok
+`)
+		assert.Equal(t, format("testname", VerbosityVeryVerbose), `pass output
+PASS example.com/pkg.TestPass (0.00s)
+fail output
+FAIL example.com/pkg.TestFail (0.00s)
+skip output
+SKIP example.com/pkg.TestSkip (0.00s)
+FAIL example.com/pkg
+ok output
+PASS example.com/ok.TestPass (0.00s)
+PASS example.com/ok
+`)
+	})
+
+	t.Run("pkgname", func(t *testing.T) {
+		assert.Equal(t, format("pkgname", VerbosityQuiet), "✖  example.com/pkg\n")
+		assert.Equal(t, format("pkgname", VerbosityDefault), `✖  example.com/pkg
+✓  example.com/ok
+`)
+		assert.Equal(t, format("pkgname", VerbosityVerbose), `fail output
+✖  example.com/pkg
+✓  example.com/ok
+`)
+		assert.Equal(t, format("pkgname", VerbosityVeryVerbose), `pass output
+fail output
+skip output
+✖  example.com/pkg
+ok output
+✓  example.com/ok
+`)
+	})
+
+	t.Run("pkgname-and-test-fails", func(t *testing.T) {
+		assert.Equal(t, format("pkgname-and-test-fails", VerbosityQuiet), `fail output
+✖  example.com/pkg
+`)
+	})
+}
+
+func TestFormats_ShowOutput(t *testing.T) {
+	events := []TestEvent{
+		{Package: "example.com/pkg", Action: ActionRun, Test: "TestPass"},
+		{Package: "example.com/pkg", Action: ActionOutput, Test: "TestPass", Output: "pass output\n"},
+		{Package: "example.com/pkg", Action: ActionPass, Test: "TestPass"},
+		{Package: "example.com/pkg", Action: ActionRun, Test: "TestFail"},
+		{Package: "example.com/pkg", Action: ActionOutput, Test: "TestFail", Output: "fail output\n"},
+		{Package: "example.com/pkg", Action: ActionFail, Test: "TestFail"},
+		{Package: "example.com/pkg", Action: ActionFail},
+	}
+	format := func(name string, show ShowOutput) string {
+		exec := newExecution()
+		buf := new(bytes.Buffer)
+		formatter := NewEventFormatterWithOptions(buf, name, FormatOptions{ShowOutput: show})
+		for _, event := range events {
+			exec.add(event)
+			assert.NilError(t, formatter.Format(event, exec))
+		}
+		return buf.String()
+	}
+
+	assert.Equal(t, format("testname", ShowOutputNone), `PASS example.com/pkg.TestPass (0.00s)
+FAIL example.com/pkg.TestFail (0.00s)
+FAIL example.com/pkg
+`)
+	assert.Equal(t, format("testname", ShowOutputPass), `pass output
+PASS example.com/pkg.TestPass (0.00s)
+FAIL example.com/pkg.TestFail (0.00s)
+FAIL example.com/pkg
+`)
+	assert.Equal(t, format("testname", ShowOutputAll), `pass output
+PASS example.com/pkg.TestPass (0.00s)
+fail output
+FAIL example.com/pkg.TestFail (0.00s)
+FAIL example.com/pkg
+`)
+	assert.Equal(t, format("pkgname", ShowOutputAll), `pass output
+fail output
+✖  example.com/pkg
+`)
+	assert.Equal(t, format("pkgname-and-test-fails", ShowOutputNone), "✖  example.com/pkg\n")
+}
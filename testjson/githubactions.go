@@ -0,0 +1,131 @@
+package testjson
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// githubActionsFormatter prints the output of each package in a collapsible
+// group, and an annotation for each failed test, using GitHub Actions workflow
+// commands. See
+// https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions.
+//
+// The output of a package is buffered until the package ends, because the
+// output of packages that run in parallel would otherwise be mixed in the
+// groups.
+type githubActionsFormatter struct {
+	out    io.Writer
+	opts   FormatOptions
+	pkgs   map[string]*githubActionsPackage
+	blocks *testOutputBlocks
+}
+
+type githubActionsPackage struct {
+	output strings.Builder
+	failed []TestCase
+}
+
+func newGitHubActionsFormatter(out io.Writer, opts FormatOptions) EventFormatter {
+	return &githubActionsFormatter{
+		out:    out,
+		opts:   opts,
+		pkgs:   make(map[string]*githubActionsPackage),
+		blocks: newTestOutputBlocks(opts),
+	}
+}
+
+func (f *githubActionsFormatter) Format(event TestEvent, exec *Execution) error {
+	p, ok := f.pkgs[event.Package]
+	if !ok {
+		p = &githubActionsPackage{}
+		f.pkgs[event.Package] = p
+	}
+
+	p.output.WriteString(f.blocks.next(event))
+	switch {
+	case event.Action == ActionOutput:
+		return nil
+	case event.Action == ActionFail && !event.PackageEvent():
+		p.failed = append(p.failed, exec.Package(event.Package).LastFailedByName(event.Test))
+		return nil
+	case !event.PackageEvent() || !event.Action.IsTerminal():
+		return nil
+	}
+	delete(f.pkgs, event.Package)
+
+	buf := new(strings.Builder)
+	title, _ := shortFormatPackageEvent(f.opts, event, exec)
+	fmt.Fprintf(buf, "::group::%s\n", escapeWorkflowData(strings.TrimSpace(title)))
+	buf.WriteString(p.output.String())
+	if p.output.Len() > 0 && !strings.HasSuffix(p.output.String(), "\n") {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("::endgroup::\n")
+
+	pkg := exec.Package(event.Package)
+	var annotated bool
+	for _, tc := range p.failed {
+		// The failure is reported by the annotation of the subtest.
+		if tc.hasSubTestFailed {
+			continue
+		}
+		annotated = true
+		f.writeAnnotation(buf, pkg, tc)
+	}
+	if event.Action == ActionFail && !annotated {
+		fmt.Fprintf(buf, "::error title=%s::%s\n",
+			escapeWorkflowProperty(RelativePackagePath(event.Package)+" failed"),
+			escapeWorkflowData(outputMessage(pkg.Output(0))))
+	}
+	_, err := io.WriteString(f.out, buf.String())
+	return err
+}
+
+// writeAnnotation prints an error annotation for tc, at the file and line of
+// the first line of output from t.Error or t.Fatal. A failure that matches a
+// known issue is printed as a warning.
+func (f *githubActionsFormatter) writeAnnotation(out io.Writer, pkg *Package, tc TestCase) {
+	lines := pkg.OutputLines(tc)
+	command := "error"
+	if _, ok := f.opts.KnownIssues.Lookup(pkg.Fingerprint(tc)); ok {
+		command = "warning"
+	}
+	props := []string{"title=" + escapeWorkflowProperty(
+		joinPkgToTestName(RelativePackagePath(tc.Package), tc.Test.Name()))}
+	if file, line := firstFileLine(lines); file != "" {
+		props = append(props,
+			"file="+escapeWorkflowProperty(annotationPath(tc.Package, file)),
+			fmt.Sprintf("line=%d", line))
+	}
+	fmt.Fprintf(out, "::%s %s::%s\n", command, strings.Join(props, ","),
+		escapeWorkflowData(outputMessage(strings.Join(lines, ""))))
+}
+
+// annotationPath returns the path of file relative to the root of the module.
+// The testing package prints only the base name of the file, which is joined
+// with the directory of the package. A path with a directory is from
+// --fullpath, and is used as it is.
+func annotationPath(pkg string, file string) string {
+	if strings.Contains(file, "/") {
+		return file
+	}
+	dir := RelativePackagePath(pkg)
+	if dir == "." {
+		return file
+	}
+	return path.Join(dir, file)
+}
+
+// escapeWorkflowData escapes the message of a workflow command.
+func escapeWorkflowData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeWorkflowProperty escapes the value of a property of a workflow
+// command.
+func escapeWorkflowProperty(s string) string {
+	return strings.NewReplacer(
+		"%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,63 @@
+package testjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestScanTestOutput_WithGitHubActionsFormat(t *testing.T) {
+	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
+
+	shim := newFakeHandler(nil, "go-test-json")
+	shim.formatter = newGitHubActionsFormatter(shim.out, FormatOptions{})
+	exec, err := ScanTestOutput(shim.Config(t))
+	assert.NilError(t, err)
+
+	golden.Assert(t, shim.out.String(), "github-actions-format.out")
+	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
+}
+
+func TestGitHubActionsFormat_KnownIssueAndFullpath(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+
+	input := `{"Action":"run","Package":"example.com/pkg","Test":"TestKnown"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestKnown","Output":"=== RUN   TestKnown\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestKnown","Output":"    pkg/known_test.go:9: 100% broken, again\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestKnown","Output":"--- FAIL: TestKnown (0.00s)\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestKnown","Elapsed":0}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0}
+`
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(input)})
+	assert.NilError(t, err)
+	fingerprint := exec.Fingerprint(exec.Package("example.com/pkg").Failed[0])
+
+	out := new(bytes.Buffer)
+	_, err = ScanTestOutput(ScanConfig{
+		Stdout: strings.NewReader(input),
+		Handler: &fakeHandler{
+			formatter: newGitHubActionsFormatter(out, FormatOptions{
+				KnownIssues: NewKnownIssues(map[string]string{fingerprint: "https://example.com/1"}),
+			}),
+			err: new(bytes.Buffer),
+		},
+	})
+	assert.NilError(t, err)
+
+	expected := `::group::✖  pkg
+=== RUN   TestKnown
+    pkg/known_test.go:9: 100% broken, again
+--- FAIL: TestKnown (0.00s)
+::endgroup::
+::warning title=pkg.TestKnown,file=pkg/known_test.go,line=9::pkg/known_test.go:9: 100%25 broken, again
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestEscapeWorkflowProperty(t *testing.T) {
+	assert.Equal(t, escapeWorkflowProperty("a:b,c%d\ne"), "a%3Ab%2Cc%25d%0Ae")
+	assert.Equal(t, escapeWorkflowData("a:b,c%d\r\ne"), "a:b,c%25d%0D%0Ae")
+}
@@ -0,0 +1,51 @@
+package testjson
+
+// InputAdapter converts the output of a test runner into TestEvents, so that
+// the results of tests written in other languages can be scanned into the
+// same Execution, and included in the same summary and reports, as the
+// results of 'go test'. An InputAdapter is used for a single scan, and may
+// keep the state of the tests which are running.
+//
+// The events returned by an InputAdapter are encoded as test2json events by
+// TestEvent.Bytes, so that a --jsonfile can be read back without the adapter.
+type InputAdapter interface {
+	// Events returns the TestEvents for one line read from ScanConfig.Stdout.
+	// An error stops the scan, unless ScanConfig.IgnoreNonJSONOutputLines is
+	// set.
+	Events(line []byte) ([]TestEvent, error)
+	// End is called once Stdout has been read, and returns the end events
+	// for the tests and packages which are still running.
+	End() []TestEvent
+}
+
+// goTestJSONAdapter reads the test2json output of 'go test -json'. It is the
+// default InputAdapter.
+type goTestJSONAdapter struct{}
+
+func (goTestJSONAdapter) Events(line []byte) ([]TestEvent, error) {
+	event, err := parseEvent(line)
+	if err != nil {
+		return nil, err
+	}
+	return []TestEvent{event}, nil
+}
+
+func (goTestJSONAdapter) End() []TestEvent {
+	return nil
+}
+
+// InputFormats are the names of the formats accepted by NewInputAdapter.
+var InputFormats = []string{"go", "pytest-reportlog"}
+
+// NewInputAdapter returns the InputAdapter for the format, one of
+// InputFormats. The "go" format returns nil, which is the default of
+// ScanConfig.InputAdapter.
+func NewInputAdapter(format string) (InputAdapter, bool) {
+	switch format {
+	case "", "go":
+		return nil, true
+	case "pytest-reportlog":
+		return NewPytestReportLogAdapter(), true
+	}
+	return nil, false
+}
@@ -0,0 +1,85 @@
+package testjson
+
+import (
+	"regexp"
+	"strings"
+)
+
+// LabelMarker is the prefix of a line of test output which adds labels to the
+// test. The labels are a comma separated list which follow the marker. The line
+// may be printed with t.Log, or directly to stdout:
+//
+//	t.Log("gotestsum-label: slow,db")
+//
+// The labels are added to TestCase.Labels.
+const LabelMarker = "gotestsum-label:"
+
+// labelLinePattern matches a line with LabelMarker, optionally prefixed by the
+// file and line number added by t.Log.
+var labelLinePattern = regexp.MustCompile(`^\s*(?:[^\s:]+\.go:\d+: )?` + LabelMarker + `(.*)$`)
+
+// parseLabels returns the labels from a line of test output, or nil if the line
+// does not contain LabelMarker.
+func parseLabels(line string) []string {
+	match := labelLinePattern.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+	if match == nil {
+		return nil
+	}
+	return SplitLabels(match[1])
+}
+
+// SplitLabels splits a comma separated list of labels. Empty labels are
+// removed.
+func SplitLabels(value string) []string {
+	var labels []string
+	for _, label := range strings.Split(value, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// addLabels appends the labels which are not already in existing.
+func addLabels(existing []string, labels []string) []string {
+	for _, label := range labels {
+		if !containsLabel(existing, label) {
+			existing = append(existing, label)
+		}
+	}
+	return existing
+}
+
+func containsLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAnyLabel returns true if the test case has at least one of labels.
+func (tc TestCase) HasAnyLabel(labels []string) bool {
+	for _, label := range labels {
+		if containsLabel(tc.Labels, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterByLabel returns the TestCases which have at least one of labels. If
+// labels is empty all of tcs are returned.
+func FilterByLabel(tcs []TestCase, labels []string) []TestCase {
+	if len(labels) == 0 {
+		return tcs
+	}
+	var result []TestCase
+	for _, tc := range tcs {
+		if tc.HasAnyLabel(labels) {
+			result = append(result, tc)
+		}
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+package testjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParseLabels(t *testing.T) {
+	type testCase struct {
+		line     string
+		expected []string
+	}
+	for _, tc := range []testCase{
+		{line: "gotestsum-label: slow,db\n", expected: []string{"slow", "db"}},
+		{line: "    labels_test.go:12: gotestsum-label: slow\n", expected: []string{"slow"}},
+		{line: "        labels_test.go:12: gotestsum-label:  a , ,b\r\n", expected: []string{"a", "b"}},
+		{line: "gotestsum-label:\n"},
+		{line: "=== RUN   TestLabels\n"},
+		{line: "    labels_test.go:12: the gotestsum-label: marker\n"},
+	} {
+		t.Run(tc.line, func(t *testing.T) {
+			assert.DeepEqual(t, parseLabels(tc.line), tc.expected)
+		})
+	}
+}
+
+const labelsInput = `{"Action":"run","Package":"example.com/pkg","Test":"TestSlow"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestSlow","Output":"    pkg_test.go:10: gotestsum-label: slow,db\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestSlow","Output":"gotestsum-label: db,network\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestSlow","Output":"    pkg_test.go:12: too slow\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestSlow","Elapsed":1}
+{"Action":"run","Package":"example.com/pkg","Test":"TestFast"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestFast","Output":"    pkg_test.go:20: broken\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFast","Elapsed":0}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":1}
+`
+
+func TestScanTestOutput_Labels(t *testing.T) {
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(labelsInput)})
+	assert.NilError(t, err)
+
+	failed := exec.Failed()
+	assert.Equal(t, len(failed), 2)
+	assert.DeepEqual(t, failed[0].Labels, []string{"slow", "db", "network"})
+	assert.Assert(t, failed[1].Labels == nil)
+
+	filtered := FilterByLabel(failed, []string{"network", "other"})
+	assert.Equal(t, len(filtered), 1)
+	assert.Equal(t, filtered[0].Test, TestName("TestSlow"))
+	assert.Equal(t, len(FilterByLabel(failed, nil)), 2)
+}
+
+func TestPrintSummary_FilteredByLabel(t *testing.T) {
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(labelsInput)})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	PrintSummaryWithOptions(out, exec, SummarizeAll, FormatOptions{Labels: "slow"})
+	assert.Assert(t, strings.Contains(out.String(), "TestSlow"), out.String())
+	assert.Assert(t, !strings.Contains(out.String(), "TestFast"), out.String())
+	assert.Assert(t, strings.Contains(out.String(), "2 tests, 2 failures"), out.String())
+}
@@ -0,0 +1,99 @@
+package testjson
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LeakMarker is the prefix of a line of test output which reports resources
+// leaked by the test. The marker is followed by a comma separated list of
+// kind=count pairs. The line may be printed with t.Log, or directly to stdout:
+//
+//	t.Log("gotestsum-leak: goroutines=2,fds=1")
+//
+// The gotest.tools/gotestsum/leakcheck package prints this line for goroutines
+// and file descriptors. The leaks are added to TestCase.Leaks.
+const LeakMarker = "gotestsum-leak:"
+
+// leakLinePattern matches a line with LeakMarker, optionally prefixed by the
+// file and line number added by t.Log.
+var leakLinePattern = regexp.MustCompile(`^\s*(?:[^\s:]+\.go:\d+: )?` + LeakMarker + `(.*)$`)
+
+// Leak is a count of one kind of resource, like goroutines, which was leaked
+// by a test.
+type Leak struct {
+	Kind  string
+	Count int
+}
+
+func (l Leak) String() string {
+	return l.Kind + "=" + strconv.Itoa(l.Count)
+}
+
+// parseLeaks returns the leaks from a line of test output, or nil if the line
+// does not contain LeakMarker. Pairs which are not kind=count, or which have a
+// count less than one, are ignored.
+func parseLeaks(line string) []Leak {
+	match := leakLinePattern.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+	if match == nil {
+		return nil
+	}
+	var leaks []Leak
+	for _, pair := range strings.Split(match[1], ",") {
+		i := strings.Index(pair, "=")
+		if i < 0 {
+			continue
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(pair[i+1:]))
+		if err != nil || count < 1 {
+			continue
+		}
+		leaks = append(leaks, Leak{Kind: strings.TrimSpace(pair[:i]), Count: count})
+	}
+	return leaks
+}
+
+// addLeaks adds leaks to existing. When a kind is reported more than once the
+// largest count is kept.
+func addLeaks(existing []Leak, leaks []Leak) []Leak {
+next:
+	for _, leak := range leaks {
+		for i := range existing {
+			if existing[i].Kind == leak.Kind {
+				if leak.Count > existing[i].Count {
+					existing[i].Count = leak.Count
+				}
+				continue next
+			}
+		}
+		existing = append(existing, leak)
+	}
+	return existing
+}
+
+// Leaked returns the test cases which reported a leak with LeakMarker, sorted
+// by package and the order the tests finished.
+func (e *Execution) Leaked() []TestCase {
+	if e == nil {
+		return nil
+	}
+	var result []TestCase
+	for _, name := range sortedKeys(e.packages) {
+		pkg := e.packages[name]
+		var tcs []TestCase
+		for _, group := range [][]TestCase{pkg.Failed, pkg.Skipped, pkg.Passed} {
+			for _, tc := range group {
+				if len(tc.Leaks) > 0 {
+					tcs = append(tcs, tc)
+				}
+			}
+		}
+		sort.Slice(tcs, func(i, j int) bool {
+			return tcs[i].ID < tcs[j].ID
+		})
+		result = append(result, tcs...)
+	}
+	return result
+}
@@ -0,0 +1,57 @@
+package testjson
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParseLeaks(t *testing.T) {
+	type testCase struct {
+		line     string
+		expected []Leak
+	}
+	for _, tc := range []testCase{
+		{line: "gotestsum-leak: goroutines=2\n", expected: []Leak{{Kind: "goroutines", Count: 2}}},
+		{
+			line:     "    leaks_test.go:12: gotestsum-leak: goroutines=1, fds = 3\r\n",
+			expected: []Leak{{Kind: "goroutines", Count: 1}, {Kind: "fds", Count: 3}},
+		},
+		{line: "gotestsum-leak: goroutines=none,fds,sockets=0\n"},
+		{line: "=== RUN   TestLeaks\n"},
+		{line: "    leaks_test.go:12: the gotestsum-leak: fds=1 marker\n"},
+	} {
+		t.Run(tc.line, func(t *testing.T) {
+			assert.DeepEqual(t, parseLeaks(tc.line), tc.expected)
+		})
+	}
+}
+
+func TestExecution_Leaked(t *testing.T) {
+	in := `{"Action":"run","Package":"example.com/b","Test":"TestOne"}
+{"Action":"output","Package":"example.com/b","Test":"TestOne","Output":"    b_test.go:10: gotestsum-leak: fds=1\n"}
+{"Action":"pass","Package":"example.com/b","Test":"TestOne","Elapsed":1}
+{"Action":"run","Package":"example.com/a","Test":"TestFirst"}
+{"Action":"output","Package":"example.com/a","Test":"TestFirst","Output":"gotestsum-leak: goroutines=2\n"}
+{"Action":"output","Package":"example.com/a","Test":"TestFirst","Output":"gotestsum-leak: goroutines=1,fds=2\n"}
+{"Action":"pass","Package":"example.com/a","Test":"TestFirst","Elapsed":1}
+{"Action":"run","Package":"example.com/a","Test":"TestClean"}
+{"Action":"pass","Package":"example.com/a","Test":"TestClean","Elapsed":1}
+{"Action":"run","Package":"example.com/a","Test":"TestSecond"}
+{"Action":"output","Package":"example.com/a","Test":"TestSecond","Output":"gotestsum-leak: goroutines=4\n"}
+{"Action":"fail","Package":"example.com/a","Test":"TestSecond","Elapsed":1}
+{"Action":"fail","Package":"example.com/a","Elapsed":3}
+{"Action":"pass","Package":"example.com/b","Elapsed":1}
+`
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(in)})
+	assert.NilError(t, err)
+
+	leaked := exec.Leaked()
+	assert.Equal(t, len(leaked), 3)
+	assert.Equal(t, leaked[0].Test, TestName("TestFirst"))
+	assert.DeepEqual(t, leaked[0].Leaks, []Leak{{Kind: "goroutines", Count: 2}, {Kind: "fds", Count: 2}})
+	assert.Equal(t, leaked[1].Test, TestName("TestSecond"))
+	assert.Equal(t, leaked[2].Package, "example.com/b")
+	assert.DeepEqual(t, leaked[2].Leaks, []Leak{{Kind: "fds", Count: 1}})
+}
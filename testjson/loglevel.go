@@ -0,0 +1,70 @@
+package testjson
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// logLevel is the severity of a line of test output.
+type logLevel int
+
+const (
+	logLevelNone logLevel = iota
+	logLevelWarn
+	logLevelError
+)
+
+var (
+	// errorLinePattern matches the common formats of error and fatal log
+	// lines, panics, and the data race report from the race detector.
+	errorLinePattern = regexp.MustCompile(
+		`\b(ERROR|ERRO|FATAL|FATA|CRITICAL)\b|` +
+			`(?i:\blevel=(error|fatal)\b|"level":\s*"(error|fatal)")|` +
+			`^\s*panic: |` +
+			`WARNING: DATA RACE`)
+	warnLinePattern = regexp.MustCompile(
+		`\b(WARN|WARNING)\b|` +
+			`(?i:\blevel=warn(ing)?\b|"level":\s*"warn(ing)?")`)
+)
+
+// lineLogLevel returns the severity of a line of test output.
+func lineLogLevel(line string) logLevel {
+	switch {
+	case errorLinePattern.MatchString(line):
+		return logLevelError
+	case warnLinePattern.MatchString(line):
+		return logLevelWarn
+	default:
+		return logLevelNone
+	}
+}
+
+// highlightLogLevel returns line with color for the severity of the line.
+func highlightLogLevel(line string) string {
+	var colorize func(format string, a ...interface{}) string
+	switch lineLogLevel(line) {
+	case logLevelError:
+		colorize = color.RedString
+	case logLevelWarn:
+		colorize = color.YellowString
+	default:
+		return line
+	}
+	// Color the line without the trailing newline, so that the color does not
+	// continue on the next line.
+	trimmed := strings.TrimSuffix(line, "\n")
+	return colorize("%s", trimmed) + line[len(trimmed):]
+}
+
+// firstErrorLine returns the first line with logLevelError, or an empty string
+// if no lines have that severity.
+func firstErrorLine(lines []string) string {
+	for _, line := range lines {
+		if lineLogLevel(line) == logLevelError {
+			return line
+		}
+	}
+	return ""
+}
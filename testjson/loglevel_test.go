@@ -0,0 +1,73 @@
+package testjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"gotest.tools/v3/assert"
+)
+
+func TestLineLogLevel(t *testing.T) {
+	type testCase struct {
+		line     string
+		expected logLevel
+	}
+	for _, tc := range []testCase{
+		{line: "    db_test.go:12: 2021/06/01 12:00:00 ERROR connection refused\n", expected: logLevelError},
+		{line: `time="2021-06-01" level=error msg="connection refused"` + "\n", expected: logLevelError},
+		{line: `{"level":"fatal","msg":"exiting"}` + "\n", expected: logLevelError},
+		{line: "panic: runtime error: index out of range [recovered]\n", expected: logLevelError},
+		{line: "WARNING: DATA RACE\n", expected: logLevelError},
+		{line: "    db_test.go:12: WARN slow query\n", expected: logLevelWarn},
+		{line: `level=warning msg="slow query"` + "\n", expected: logLevelWarn},
+		{line: "    db_test.go:12: expected no errors\n"},
+		{line: "    db_test.go:12: TestWarningMessage\n"},
+	} {
+		t.Run(tc.line, func(t *testing.T) {
+			assert.Equal(t, lineLogLevel(tc.line), tc.expected)
+		})
+	}
+}
+
+func TestHighlightLogLevel(t *testing.T) {
+	defer patchNoColor(false)()
+	assert.Equal(t, highlightLogLevel("ERROR broken\n"), color.RedString("ERROR broken")+"\n")
+	assert.Equal(t, highlightLogLevel("WARN slow"), color.YellowString("WARN slow"))
+	assert.Equal(t, highlightLogLevel("ok\n"), "ok\n")
+}
+
+func patchNoColor(value bool) func() {
+	orig := color.NoColor
+	color.NoColor = value
+	return func() {
+		color.NoColor = orig
+	}
+}
+
+func TestPrintSummary_FirstErrorFirst(t *testing.T) {
+	in := `{"Action":"run","Package":"example.com/pkg","Test":"TestQuery"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestQuery","Output":"=== RUN   TestQuery\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestQuery","Output":"    db_test.go:10: INFO connecting\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestQuery","Output":"    db_test.go:11: ERROR connection refused\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestQuery","Output":"    db_test.go:12: ERROR retry failed\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestQuery","Output":"--- FAIL: TestQuery (0.10s)\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestQuery","Elapsed":0.1}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.1}
+`
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(in)})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	PrintSummaryWithOptions(out, exec, SummarizeFailed|SummarizeOutput, FormatOptions{FirstErrorFirst: true})
+	expected := `
+=== Failed
+=== FAIL: example.com/pkg TestQuery (0.10s)
+    first error: db_test.go:11: ERROR connection refused
+    db_test.go:10: INFO connecting
+    db_test.go:11: ERROR connection refused
+    db_test.go:12: ERROR retry failed
+`
+	assert.Assert(t, strings.HasPrefix(out.String(), expected), out.String())
+}
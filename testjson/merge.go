@@ -0,0 +1,125 @@
+package testjson
+
+// MergeExecutions returns an Execution with the packages and errors of all the
+// executions, so that the results of more than one 'go test' command can be
+// reported as a single run. When a package is in more than one execution its
+// test cases are combined, and the package fails if it failed in any of the
+// executions. The executions must be done before they are merged.
+func MergeExecutions(execs ...*Execution) *Execution {
+	result := &Execution{packages: make(map[string]*Package), done: true}
+	for _, exec := range execs {
+		if exec == nil {
+			continue
+		}
+		if result.clock == nil {
+			result.clock = exec.clock
+		}
+		if result.started.IsZero() || exec.started.Before(result.started) {
+			result.started = exec.started
+		}
+		if exec.lastRunID > result.lastRunID {
+			result.lastRunID = exec.lastRunID
+		}
+		if !exec.firstFailureTime.IsZero() &&
+			(result.firstFailureTime.IsZero() || exec.firstFailureTime.Before(result.firstFailureTime)) {
+			result.firstFailure = exec.firstFailure
+			result.firstFailureTime = exec.firstFailureTime
+		}
+		result.demoteTeardownFailures = result.demoteTeardownFailures || exec.demoteTeardownFailures
+		result.keepPassedOutput = result.keepPassedOutput || exec.keepPassedOutput
+
+		exec.errorsLock.RLock()
+		result.errors = append(result.errors, exec.errors...)
+		result.errorEntries = append(result.errorEntries, exec.errorEntries...)
+		exec.errorsLock.RUnlock()
+
+		for name, pkg := range exec.packages {
+			if existing, ok := result.packages[name]; ok {
+				result.packages[name] = mergePackages(existing, pkg)
+				continue
+			}
+			result.packages[name] = pkg
+		}
+	}
+	return result
+}
+
+// mergePackages returns a new Package with the test cases of a followed by
+// the test cases of b. The IDs of the test cases from b are offset by a.Total
+// so that the IDs remain unique. The output of both packages is read from any
+// spill file, so that the new Package does not depend on the spill file of
+// either package.
+func mergePackages(a, b *Package) *Package {
+	result := newPackage()
+	result.Total = a.Total + b.Total
+	result.maxOutputLines = a.maxOutputLines
+	result.keepPassedOutput = a.keepPassedOutput || b.keepPassedOutput
+	result.elapsed = a.elapsed + b.elapsed
+	result.firstEvent = a.firstEvent
+	if result.firstEvent.IsZero() || (!b.firstEvent.IsZero() && b.firstEvent.Before(a.firstEvent)) {
+		result.firstEvent = b.firstEvent
+	}
+	result.lastEvent = a.lastEvent
+	if b.lastEvent.After(a.lastEvent) {
+		result.lastEvent = b.lastEvent
+	}
+	result.coverage = a.coverage
+	if result.coverage == "" {
+		result.coverage = b.coverage
+	}
+	result.action = mergeAction(a.action, b.action)
+	result.cached = a.cached && b.cached
+	result.panicked = a.panicked || b.panicked
+	result.exitedUnexpectedly = a.exitedUnexpectedly || b.exitedUnexpectedly
+
+	result.addTestCases(a, 0)
+	result.addTestCases(b, a.Total)
+	return result
+}
+
+// addTestCases adds the test cases, output, and snapshots from pkg to p, with
+// the ID of each test case increased by offset. The package output, with ID 0,
+// is appended to the package output of p.
+func (p *Package) addTestCases(pkg *Package, offset int) {
+	shift := func(tcs []TestCase) []TestCase {
+		result := make([]TestCase, 0, len(tcs))
+		for _, tc := range tcs {
+			tc.ID += offset
+			result = append(result, tc)
+		}
+		return result
+	}
+	p.Failed = append(p.Failed, shift(pkg.Failed)...)
+	p.Skipped = append(p.Skipped, shift(pkg.Skipped)...)
+	p.Passed = append(p.Passed, shift(pkg.Passed)...)
+
+	p.output[0] = append(p.output[0], pkg.lines(0)...)
+	for id := 1; id <= pkg.Total; id++ {
+		if lines := pkg.lines(id); len(lines) > 0 {
+			p.output[id+offset] = lines
+		}
+	}
+	for id, snapshot := range pkg.snapshots {
+		p.SetSnapshot(TestCase{ID: id + offset}, snapshot)
+	}
+	for id, subs := range pkg.subTests {
+		for _, sub := range subs {
+			p.subTests[id+offset] = append(p.subTests[id+offset], sub+offset)
+		}
+	}
+}
+
+// mergeAction returns the result of a package which was run twice, with the
+// results a and b.
+func mergeAction(a, b Action) Action {
+	switch {
+	case a == ActionFail || b == ActionFail:
+		return ActionFail
+	case a == ActionPass || b == ActionPass:
+		return ActionPass
+	case a == "":
+		return b
+	default:
+		return a
+	}
+}
@@ -0,0 +1,71 @@
+package testjson
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestMergeExecutions(t *testing.T) {
+	first := scanTestEvents(t, `{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestOne","Output":"    one_test.go:10: first run\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestOne","Elapsed":0.1}
+{"Action":"output","Package":"example.com/pkg","Output":"coverage: 50.0% of statements\n"}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.2}
+{"Action":"run","Package":"example.com/other","Test":"TestTwo"}
+{"Action":"pass","Package":"example.com/other","Test":"TestTwo","Elapsed":0.1}
+{"Action":"pass","Package":"example.com/other","Elapsed":0.1}
+`)
+	second := scanTestEvents(t, `{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestOne/sub"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestOne/sub","Output":"    one_test.go:20: second run\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestOne/sub","Elapsed":0.1}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestOne","Elapsed":0.1}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.3}
+`)
+	second.addError("example.com/pkg: build failed")
+
+	merged := MergeExecutions(first, second)
+	assert.DeepEqual(t, merged.Packages(), []string{"example.com/other", "example.com/pkg"})
+	assert.Equal(t, merged.Total(), 4)
+	assert.DeepEqual(t, merged.Errors(), []string{"example.com/pkg: build failed"})
+	assert.Assert(t, merged.Package("example.com/other") == first.Package("example.com/other"))
+
+	pkg := merged.Package("example.com/pkg")
+	assert.Equal(t, pkg.Result(), ActionFail)
+	assert.Equal(t, pkg.Coverage(), "coverage: 50.0% of statements")
+	assert.Equal(t, pkg.Elapsed().String(), "500ms")
+
+	failed := merged.Failed()
+	assert.Equal(t, len(failed), 3)
+	ids := map[int]bool{}
+	for _, tc := range failed {
+		ids[tc.ID] = true
+	}
+	assert.Equal(t, len(ids), 3, "test case IDs must be unique")
+
+	var output []string
+	for _, tc := range failed {
+		output = append(output, strings.Join(merged.OutputLines(tc), ""))
+	}
+	assert.DeepEqual(t, output, []string{
+		"    one_test.go:10: first run\n",
+		"    one_test.go:20: second run\n",
+		"",
+	})
+}
+
+func TestMergeAction(t *testing.T) {
+	assert.Equal(t, mergeAction(ActionPass, ActionFail), ActionFail)
+	assert.Equal(t, mergeAction(ActionSkip, ActionPass), ActionPass)
+	assert.Equal(t, mergeAction(ActionSkip, ActionSkip), ActionSkip)
+	assert.Equal(t, mergeAction("", ActionSkip), ActionSkip)
+}
+
+func scanTestEvents(t *testing.T, events string) *Execution {
+	t.Helper()
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(events)})
+	assert.NilError(t, err)
+	return exec
+}
@@ -0,0 +1,60 @@
+package testjson
+
+import "fmt"
+
+// PackageTestCounts is the number of tests in each package, for example from
+// 'go test -list'.
+type PackageTestCounts struct {
+	counts map[string]int
+}
+
+// NewPackageTestCounts returns PackageTestCounts from a map of package name to
+// the number of tests in the package.
+func NewPackageTestCounts(counts map[string]int) *PackageTestCounts {
+	return &PackageTestCounts{counts: counts}
+}
+
+// Lookup returns the number of tests in the package.
+func (c *PackageTestCounts) Lookup(pkg string) (int, bool) {
+	if c == nil {
+		return 0, false
+	}
+	count, ok := c.counts[pkg]
+	return count, ok
+}
+
+// withPackageStart prints a line before the output of the first event of each
+// package when FormatOptions.ShowPackageStart is set, so that the packages
+// which are running are visible before they print a result. With 'go test'
+// from Go 1.20 or later the first event of a package is an ActionStart event.
+// At VerbosityQuiet no lines are printed.
+func withPackageStart(
+	opts FormatOptions,
+	format func(event TestEvent, exec *Execution) (string, error),
+) func(event TestEvent, exec *Execution) (string, error) {
+	if !opts.ShowPackageStart || opts.Verbosity <= VerbosityQuiet {
+		return format
+	}
+	started := make(map[string]bool)
+	return func(event TestEvent, exec *Execution) (string, error) {
+		var line string
+		if !started[event.Package] {
+			started[event.Package] = true
+			line = formatPackageStart(opts, event.Package)
+		}
+		output, err := format(event, exec)
+		return line + output, err
+	}
+}
+
+func formatPackageStart(opts FormatOptions, pkg string) string {
+	var tests string
+	if count, ok := opts.PackageTestCounts.Lookup(pkg); ok {
+		tests = fmt.Sprintf(" (%s test", opts.formatCount(count))
+		if count != 1 {
+			tests += "s"
+		}
+		tests += ")"
+	}
+	return fmt.Sprintf("%s  %s started%s\n", opts.symbols().start, RelativePackagePath(pkg), tests)
+}
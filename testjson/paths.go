@@ -0,0 +1,106 @@
+package testjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gotest.tools/gotestsum/log"
+)
+
+// PathMapping replaces the directory From at the start of a file path with To.
+// When To is empty the path is made relative to From.
+type PathMapping struct {
+	From string
+	To   string
+}
+
+// ParsePathMapping returns the PathMapping from a value with the format
+// FROM=>TO.
+func ParsePathMapping(raw string) (PathMapping, error) {
+	i := strings.Index(raw, "=>")
+	if i < 0 || strings.TrimSpace(raw[:i]) == "" {
+		return PathMapping{}, fmt.Errorf("invalid value %q, must be FROM=>TO", raw)
+	}
+	return PathMapping{From: strings.TrimSpace(raw[:i]), To: strings.TrimSpace(raw[i+2:])}, nil
+}
+
+type pathRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// NewPathRewriter returns a function which rewrites the file paths in a line
+// of test output using mappings, for use as ScanConfig.RewriteOutput. A path
+// is only rewritten when it starts at the beginning of the line, or after a
+// space, quote, parenthesis, or equal sign. The first mapping that matches a
+// path is used.
+func NewPathRewriter(mappings []PathMapping) func(output string) string {
+	var rules []pathRule // nolint: prealloc
+	for _, m := range mappings {
+		from := strings.TrimRight(filepath.ToSlash(m.From), "/")
+		if from == "" {
+			continue
+		}
+		to := strings.TrimRight(filepath.ToSlash(m.To), "/")
+		if to != "" {
+			to += "/"
+		}
+		rules = append(rules, pathRule{
+			pattern:     regexp.MustCompile(`(^|[\s"'(=])` + regexp.QuoteMeta(from) + `/`),
+			replacement: "${1}" + strings.ReplaceAll(to, "$", "$$"),
+		})
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	return func(output string) string {
+		for _, rule := range rules {
+			if rule.pattern.MatchString(output) {
+				return rule.pattern.ReplaceAllString(output, rule.replacement)
+			}
+		}
+		return output
+	}
+}
+
+// rewriteOutput replaces the output of event, and re-encodes the event so that
+// TestEvent.Bytes includes the new output.
+func rewriteOutput(event *TestEvent, output string) {
+	if output == event.Output {
+		return
+	}
+	event.Output = output
+
+	raw, err := json.Marshal(newTest2JSONEvent(*event))
+	if err != nil {
+		log.Warnf("failed to encode TestEvent for %v: %v", event.Test, err)
+		return
+	}
+	event.raw = raw
+}
+
+var fileLinePattern = regexp.MustCompile(`^\s*([^\s:]+\.go):(\d+):`)
+
+// FirstFile returns the path of the first file referenced in lines, using the
+// file:line: prefix that the testing package adds to the output of t.Error,
+// t.Fatal, and t.Log.
+func FirstFile(lines []string) string {
+	file, _ := firstFileLine(lines)
+	return file
+}
+
+// firstFileLine returns the path and line number of the first file referenced
+// in lines. See FirstFile.
+func firstFileLine(lines []string) (string, int) {
+	for _, line := range lines {
+		if match := fileLinePattern.FindStringSubmatch(line); match != nil {
+			n, _ := strconv.Atoi(match[2])
+			return match[1], n
+		}
+	}
+	return "", 0
+}
@@ -0,0 +1,85 @@
+package testjson
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestNewPathRewriter(t *testing.T) {
+	rewrite := NewPathRewriter([]PathMapping{
+		{From: "/home/user/repo/"},
+		{From: "/src", To: "/builds/repo"},
+	})
+
+	var testCases = []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    "    /home/user/repo/pkg/store_test.go:12: failed\n",
+			expected: "    pkg/store_test.go:12: failed\n",
+		},
+		{
+			input:    "/home/user/repo/main.go:3:2: undefined: x\n",
+			expected: "main.go:3:2: undefined: x\n",
+		},
+		{
+			input:    "\t/src/pkg/store.go:40 +0x1d\n",
+			expected: "\t/builds/repo/pkg/store.go:40 +0x1d\n",
+		},
+		{
+			input:    `open "/src/testdata/input.json": no such file` + "\n",
+			expected: `open "/builds/repo/testdata/input.json": no such file` + "\n",
+		},
+		{
+			input:    "    /other/src/pkg/store_test.go:12: failed\n",
+			expected: "    /other/src/pkg/store_test.go:12: failed\n",
+		},
+		{
+			input:    "    /home/user/repository/x_test.go:1: failed\n",
+			expected: "    /home/user/repository/x_test.go:1: failed\n",
+		},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, rewrite(tc.input), tc.expected)
+	}
+}
+
+func TestNewPathRewriter_NoMappings(t *testing.T) {
+	assert.Assert(t, NewPathRewriter(nil) == nil)
+	assert.Assert(t, NewPathRewriter([]PathMapping{{From: "", To: "/src"}}) == nil)
+}
+
+func TestScanTestOutput_WithRewriteOutput(t *testing.T) {
+	in := `{"Action":"run","Package":"example.com/pkg","Test":"TestFails"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestFails","Output":"    /src/pkg/pkg_test.go:10: failed\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFails","Elapsed":1}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":1}
+`
+	handler := &captureHandler{}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:        strings.NewReader(in),
+		Handler:       handler,
+		RewriteOutput: NewPathRewriter([]PathMapping{{From: "/src"}}),
+	})
+	assert.NilError(t, err)
+
+	tc := exec.Failed()[0]
+	assert.DeepEqual(t, exec.OutputLines(tc), []string{"    pkg/pkg_test.go:10: failed\n"})
+	assert.Equal(t, FirstFile(exec.OutputLines(tc)), "pkg/pkg_test.go")
+	assert.Assert(t, cmp.Contains(string(handler.events[1].Bytes()), `"Output":"    pkg/pkg_test.go:10: failed\n"`))
+}
+
+func TestFirstFile(t *testing.T) {
+	lines := []string{
+		"=== RUN   TestFails\n",
+		"    failed without a file\n",
+		"    pkg/pkg_test.go:10: failed\n",
+		"    pkg/other_test.go:20: failed again\n",
+	}
+	assert.Equal(t, FirstFile(lines), "pkg/pkg_test.go")
+	assert.Equal(t, FirstFile(lines[:2]), "")
+}
@@ -0,0 +1,263 @@
+package testjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pytestReport is a line of the file written by the pytest-reportlog plugin,
+// with pytest --report-log=FILE.
+type pytestReport struct {
+	ReportType string          `json:"$report_type"`
+	NodeID     string          `json:"nodeid"`
+	When       string          `json:"when"`
+	Outcome    string          `json:"outcome"`
+	Duration   float64         `json:"duration"`
+	Start      float64         `json:"start"`
+	LongRepr   json.RawMessage `json:"longrepr"`
+	// Sections are the output captured from the test, as pairs of a title and
+	// the output, ex: ["Captured stdout call", "text\n"].
+	Sections [][]string `json:"sections"`
+}
+
+// pytestReportLogAdapter is an InputAdapter for the output of the
+// pytest-reportlog plugin. Each test file is reported as a package, and each
+// test as a test in that package, using the part of the node ID after the
+// file as the name of the test.
+type pytestReportLogAdapter struct {
+	tests map[string]*pytestTest
+	// packages are the packages which have started, in the order they started.
+	packages []*pytestPackage
+}
+
+type pytestTest struct {
+	pkg     *pytestPackage
+	name    string
+	elapsed float64
+	action  Action
+}
+
+type pytestPackage struct {
+	name    string
+	elapsed float64
+	failed  bool
+	ended   bool
+}
+
+// NewPytestReportLogAdapter returns an InputAdapter which reads the JSON lines
+// written by the pytest-reportlog plugin, ex: pytest --report-log=/dev/stdout.
+func NewPytestReportLogAdapter() InputAdapter {
+	return &pytestReportLogAdapter{tests: make(map[string]*pytestTest)}
+}
+
+func (a *pytestReportLogAdapter) Events(line []byte) ([]TestEvent, error) {
+	var report pytestReport
+	if err := json.Unmarshal(line, &report); err != nil {
+		return nil, err
+	}
+	switch report.ReportType {
+	case "CollectReport":
+		return a.collectReport(report), nil
+	case "TestReport":
+		return a.testReport(report), nil
+	case "SessionFinish":
+		return a.End(), nil
+	}
+	return nil, nil
+}
+
+// collectReport reports the errors from importing a test file as the output of
+// the package, and fails the package.
+func (a *pytestReportLogAdapter) collectReport(report pytestReport) []TestEvent {
+	if report.Outcome != "failed" {
+		return nil
+	}
+	pkgName, _ := splitPytestNodeID(report.NodeID)
+	pkg, events := a.startPackage(pkgName, report)
+	pkg.failed = true
+	for _, line := range outputLines(formatPytestLongRepr(report.LongRepr)) {
+		events = append(events, TestEvent{Action: ActionOutput, Package: pkg.name, Output: line})
+	}
+	return events
+}
+
+func (a *pytestReportLogAdapter) testReport(report pytestReport) []TestEvent {
+	var events []TestEvent
+	test, ok := a.tests[report.NodeID]
+	if !ok {
+		pkgName, name := splitPytestNodeID(report.NodeID)
+		var pkg *pytestPackage
+		pkg, events = a.startPackage(pkgName, report)
+		test = &pytestTest{pkg: pkg, name: name, action: ActionPass}
+		a.tests[report.NodeID] = test
+		events = append(events, pytestEvent(report, test, ActionRun, ""))
+	}
+
+	test.elapsed += report.Duration
+	switch report.Outcome {
+	case "failed":
+		test.action = ActionFail
+	case "skipped":
+		if test.action != ActionFail {
+			test.action = ActionSkip
+		}
+	}
+	if report.Outcome != "passed" {
+		for _, line := range outputLines(formatPytestLongRepr(report.LongRepr)) {
+			events = append(events, pytestEvent(report, test, ActionOutput, line))
+		}
+	}
+	if report.When != "teardown" {
+		return events
+	}
+
+	// The sections of each report include the output captured by the earlier
+	// phases of the test, so they are only added from the last report.
+	for _, section := range report.Sections {
+		if len(section) != 2 {
+			continue
+		}
+		for _, line := range outputLines(indentOutput(section[1])) {
+			events = append(events, pytestEvent(report, test, ActionOutput, line))
+		}
+	}
+	return append(events, a.endTest(report.NodeID, test)...)
+}
+
+func (a *pytestReportLogAdapter) startPackage(name string, report pytestReport) (*pytestPackage, []TestEvent) {
+	for _, pkg := range a.packages {
+		if pkg.name == name && !pkg.ended {
+			return pkg, nil
+		}
+	}
+	pkg := &pytestPackage{name: name}
+	a.packages = append(a.packages, pkg)
+	return pkg, []TestEvent{{Time: pytestTime(report.Start), Action: ActionStart, Package: name}}
+}
+
+func pytestEvent(report pytestReport, test *pytestTest, action Action, output string) TestEvent {
+	return TestEvent{
+		Time:    pytestTime(report.Start),
+		Action:  action,
+		Package: test.pkg.name,
+		Test:    test.name,
+		Output:  output,
+	}
+}
+
+func (a *pytestReportLogAdapter) endTest(nodeID string, test *pytestTest) []TestEvent {
+	delete(a.tests, nodeID)
+	test.pkg.elapsed += test.elapsed
+	if test.action == ActionFail {
+		test.pkg.failed = true
+	}
+	elapsed := math.Round(test.elapsed*1000) / 1000
+	framing := fmt.Sprintf("--- %s: %s (%.2fs)\n", strings.ToUpper(string(test.action)), test.name, elapsed)
+	return []TestEvent{
+		{Action: ActionOutput, Package: test.pkg.name, Test: test.name, Output: framing},
+		{Action: test.action, Package: test.pkg.name, Test: test.name, Elapsed: elapsed},
+	}
+}
+
+// End fails the tests which did not report a teardown, and ends every package.
+func (a *pytestReportLogAdapter) End() []TestEvent {
+	nodeIDs := make([]string, 0, len(a.tests))
+	for nodeID := range a.tests {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+
+	var events []TestEvent
+	for _, nodeID := range nodeIDs {
+		test := a.tests[nodeID]
+		test.action = ActionFail
+		events = append(events, a.endTest(nodeID, test)...)
+	}
+	for _, pkg := range a.packages {
+		if pkg.ended {
+			continue
+		}
+		pkg.ended = true
+		action := ActionPass
+		if pkg.failed {
+			action = ActionFail
+		}
+		elapsed := math.Round(pkg.elapsed*1000) / 1000
+		events = append(events, TestEvent{Action: action, Package: pkg.name, Elapsed: elapsed})
+	}
+	return events
+}
+
+// splitPytestNodeID returns the file and the name of the test from a pytest
+// node ID, ex: tests/test_api.py::TestAPI::test_get.
+func splitPytestNodeID(nodeID string) (string, string) {
+	i := strings.Index(nodeID, "::")
+	if i < 0 {
+		return nodeID, nodeID
+	}
+	return nodeID[:i], nodeID[i+2:]
+}
+
+func pytestTime(start float64) time.Time {
+	if start <= 0 {
+		return time.Time{}
+	}
+	sec, frac := math.Modf(start)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e6))*int64(time.Microsecond)).UTC()
+}
+
+// formatPytestLongRepr returns the output for the longrepr of a report. The
+// longrepr is a string, a [path, line, reason] list for a skipped test, or an
+// object with the location and message of a failure.
+func formatPytestLongRepr(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return indentOutput(text)
+	}
+	var skip []interface{}
+	if err := json.Unmarshal(raw, &skip); err == nil && len(skip) == 3 {
+		return fmt.Sprintf("    %v:%v: %v\n", skip[0], skip[1], skip[2])
+	}
+	var failure struct {
+		ReprCrash struct {
+			Path    string `json:"path"`
+			LineNo  int    `json:"lineno"`
+			Message string `json:"message"`
+		} `json:"reprcrash"`
+	}
+	if err := json.Unmarshal(raw, &failure); err == nil && failure.ReprCrash.Message != "" {
+		crash := failure.ReprCrash
+		return indentOutput(fmt.Sprintf("%s:%d: %s", crash.Path, crash.LineNo, crash.Message))
+	}
+	return indentOutput(string(raw))
+}
+
+// outputLines splits text into lines like the output events from test2json.
+// The text must end with a newline.
+func outputLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// indentOutput indents each line of text, in the same way as the output of
+// t.Log from a go test.
+func indentOutput(text string) string {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return ""
+	}
+	return "    " + strings.Replace(text, "\n", "\n    ", -1) + "\n"
+}
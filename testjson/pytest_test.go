@@ -0,0 +1,54 @@
+package testjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestScanTestOutput_WithPytestReportLogAdapter(t *testing.T) {
+	_, reset := patchClock()
+	defer reset()
+
+	handler := &captureHandler{}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:       bytes.NewReader(golden.Get(t, "pytest-reportlog.jsonl")),
+		Handler:      handler,
+		InputAdapter: NewPytestReportLogAdapter(),
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, exec.Total(), 5)
+	assert.DeepEqual(t, exec.Packages(),
+		[]string{"tests/test_api.py", "tests/test_broken.py", "tests/test_store.py"})
+
+	pkg := exec.Package("tests/test_api.py")
+	assert.Equal(t, len(pkg.Passed), 1)
+	assert.Equal(t, pkg.Failed[0].Test, TestName("TestAPI::test_post[json]"))
+	assert.Equal(t, pkg.Skipped[0].Test, TestName("test_slow"))
+	assert.Equal(t, pkg.Result(), ActionFail)
+
+	// the session ended before the teardown of test_save and test_load
+	pkg = exec.Package("tests/test_store.py")
+	assert.Equal(t, len(pkg.Failed), 2)
+
+	// The events are encoded as test2json for the --jsonfile
+	var raw []string
+	for _, event := range handler.events {
+		raw = append(raw, string(event.Bytes()))
+	}
+	golden.Assert(t, strings.Join(raw, "\n")+"\n", "pytest-reportlog-events.out")
+
+	buf := new(bytes.Buffer)
+	PrintSummary(buf, exec, SummarizeAll)
+	golden.Assert(t, buf.String(), "pytest-reportlog-summary.out")
+}
+
+func TestFormatPytestLongRepr(t *testing.T) {
+	assert.Equal(t, formatPytestLongRepr(nil), "")
+	assert.Equal(t, formatPytestLongRepr([]byte("null")), "")
+	assert.Equal(t, formatPytestLongRepr([]byte(`"line one\nline two\n"`)), "    line one\n    line two\n")
+	assert.Equal(t, formatPytestLongRepr([]byte(`["a.py", 3, "Skipped: slow"]`)), "    a.py:3: Skipped: slow\n")
+}
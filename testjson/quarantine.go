@@ -0,0 +1,50 @@
+package testjson
+
+import "strings"
+
+// QuarantineRule quarantines a test, and all of its subtests.
+type QuarantineRule struct {
+	Package string
+	Test    string
+	// Reason is the reason the test is quarantined, usually the URL of the
+	// issue that tracks the flaky test. It may be empty.
+	Reason string
+}
+
+// Quarantine is a list of known-flaky tests. The failures of a quarantined test
+// are reported, but do not fail the run.
+type Quarantine struct {
+	rules []QuarantineRule
+}
+
+// NewQuarantine returns a Quarantine of the tests in rules.
+func NewQuarantine(rules []QuarantineRule) *Quarantine {
+	return &Quarantine{rules: rules}
+}
+
+// Lookup returns the rule that quarantines tc. A rule for a test also
+// quarantines its subtests.
+func (q *Quarantine) Lookup(tc TestCase) (QuarantineRule, bool) {
+	if q == nil {
+		return QuarantineRule{}, false
+	}
+	for _, rule := range q.rules {
+		if rule.Package != tc.Package {
+			continue
+		}
+		if name := tc.Test.Name(); name == rule.Test || strings.HasPrefix(name, rule.Test+"/") {
+			return rule, true
+		}
+	}
+	return QuarantineRule{}, false
+}
+
+// IsQuarantined returns true if every test case in tcs is quarantined.
+func (q *Quarantine) IsQuarantined(tcs []TestCase) bool {
+	for _, tc := range tcs {
+		if _, ok := q.Lookup(tc); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package testjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestQuarantine_Lookup(t *testing.T) {
+	quarantine := NewQuarantine([]QuarantineRule{
+		{Package: "example.com/pkg", Test: "TestFlaky", Reason: "https://issues.example.com/7"},
+	})
+	type testCase struct {
+		tc       TestCase
+		expected bool
+	}
+	for _, tc := range []testCase{
+		{tc: TestCase{Package: "example.com/pkg", Test: "TestFlaky"}, expected: true},
+		{tc: TestCase{Package: "example.com/pkg", Test: "TestFlaky/sub"}, expected: true},
+		{tc: TestCase{Package: "example.com/pkg", Test: "TestFlakyToo"}},
+		{tc: TestCase{Package: "example.com/other", Test: "TestFlaky"}},
+	} {
+		t.Run(tc.tc.Package+" "+tc.tc.Test.Name(), func(t *testing.T) {
+			rule, ok := quarantine.Lookup(tc.tc)
+			assert.Equal(t, ok, tc.expected)
+			if ok {
+				assert.Equal(t, rule.Reason, "https://issues.example.com/7")
+			}
+		})
+	}
+
+	var nilQuarantine *Quarantine
+	_, ok := nilQuarantine.Lookup(TestCase{Package: "example.com/pkg", Test: "TestFlaky"})
+	assert.Assert(t, !ok)
+}
+
+func TestPrintSummary_WithQuarantine(t *testing.T) {
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFlaky","Elapsed":0.1}
+{"Action":"run","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestBroken","Elapsed":0.1}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.2}
+`)})
+	assert.NilError(t, err)
+	quarantine := NewQuarantine([]QuarantineRule{
+		{Package: "example.com/pkg", Test: "TestFlaky", Reason: "https://issues.example.com/7"},
+	})
+	assert.Assert(t, quarantine.IsQuarantined(exec.Failed()[:1]))
+	assert.Assert(t, !quarantine.IsQuarantined(exec.Failed()))
+
+	out := new(bytes.Buffer)
+	PrintSummaryWithOptions(out, exec, SummarizeFailed, FormatOptions{Quarantine: quarantine})
+	expected := `
+=== Failed
+=== FAIL: example.com/pkg TestFlaky (0.10s)
+    quarantined: https://issues.example.com/7
+=== FAIL: example.com/pkg TestBroken (0.10s)
+`
+	assert.Assert(t, strings.HasPrefix(out.String(), expected), out.String())
+}
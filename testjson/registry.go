@@ -0,0 +1,88 @@
+package testjson
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// FormatFactory returns a new EventFormatter which writes to out.
+type FormatFactory func(out io.Writer, opts FormatOptions) EventFormatter
+
+var (
+	registryLock sync.RWMutex
+	registry     = map[string]FormatFactory{}
+)
+
+// builtinFormats are the names of the formats created by
+// NewEventFormatterWithOptions which can not be replaced by RegisterFormat.
+var builtinFormats = map[string]bool{
+	"debug":                  true,
+	"standard-verbose":       true,
+	"standard-quiet":         true,
+	"dots":                   true,
+	"dots-v1":                true,
+	"dots-v2":                true,
+	"testname":               true,
+	"short-verbose":          true,
+	"pkgname":                true,
+	"short":                  true,
+	"pkgname-and-test-fails": true,
+	"short-with-failures":    true,
+	"tap":                    true,
+	"github-actions":         true,
+	"teamcity":               true,
+	"template":               true,
+	"screen-reader":          true,
+	"screen-reader-failures": true,
+}
+
+// RegisterFormat adds a named format which is created by
+// NewEventFormatterWithOptions, so that programs which embed gotestsum can add
+// their own formats and select them with the --format flag.
+//
+// RegisterFormat panics if name is empty, factory is nil, or name is already
+// used by a built-in format or a registered format. It is intended to be called
+// from an init function.
+func RegisterFormat(name string, factory FormatFactory) {
+	if name == "" || factory == nil {
+		panic("testjson: RegisterFormat requires a name and a factory")
+	}
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if _, exists := registry[name]; exists || builtinFormats[name] {
+		panic(fmt.Sprintf("testjson: RegisterFormat called twice for format %v", name))
+	}
+	registry[name] = factory
+}
+
+// RegisteredFormats returns the sorted names of all the formats added by
+// RegisterFormat.
+func RegisteredFormats() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupFormat(name string) FormatFactory {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	return registry[name]
+}
+
+// Formats returns the sorted names of all the formats that can be created by
+// NewEventFormatterWithOptions, both built-in and added by RegisterFormat.
+func Formats() []string {
+	names := RegisteredFormats()
+	for name := range builtinFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,58 @@
+package testjson
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRegisterFormat(t *testing.T) {
+	defer func() {
+		registryLock.Lock()
+		delete(registry, "test-custom")
+		registryLock.Unlock()
+	}()
+
+	var gotOpts FormatOptions
+	RegisterFormat("test-custom", func(out io.Writer, opts FormatOptions) EventFormatter {
+		gotOpts = opts
+		return &formatAdapter{out: out, format: func(event TestEvent, _ *Execution) (string, error) {
+			return "custom " + string(event.Action) + "\n", nil
+		}}
+	})
+	assert.DeepEqual(t, RegisteredFormats(), []string{"test-custom"})
+
+	buf := new(bytes.Buffer)
+	formatter := NewEventFormatterWithOptions(buf, "test-custom", FormatOptions{ShowBuildTime: true})
+	assert.Assert(t, formatter != nil)
+	assert.Equal(t, gotOpts, FormatOptions{ShowBuildTime: true})
+
+	assert.NilError(t, formatter.Format(TestEvent{Action: ActionPass}, newExecution()))
+	assert.Equal(t, buf.String(), "custom pass\n")
+}
+
+func TestRegisterFormat_Panics(t *testing.T) {
+	factory := func(out io.Writer, opts FormatOptions) EventFormatter { return nil }
+	assertPanics := func(t *testing.T, name string, factory FormatFactory) {
+		t.Helper()
+		defer func() {
+			assert.Assert(t, recover() != nil, "expected a panic")
+		}()
+		RegisterFormat(name, factory)
+	}
+
+	assertPanics(t, "pkgname", factory)
+	assertPanics(t, "", factory)
+	assertPanics(t, "test-nil-factory", nil)
+}
+
+func TestFormats(t *testing.T) {
+	formats := Formats()
+	assert.Equal(t, len(formats), len(builtinFormats))
+	for _, name := range formats {
+		assert.Assert(t, NewEventFormatterWithOptions(ioutil.Discard, name, FormatOptions{}) != nil, name)
+	}
+}
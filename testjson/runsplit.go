@@ -0,0 +1,79 @@
+package testjson
+
+import "time"
+
+// runSplitter assigns a RunID to each event when ScanConfig.SplitRuns is true.
+type runSplitter struct {
+	runID int
+	gap   time.Duration
+	// last is the time of the most recent event with a time.
+	last time.Time
+	// ended is the set of packages which finished in the current run.
+	ended map[string]bool
+	// started is true once an event has been received in the current run.
+	started bool
+	// recorded is true once an ActionRunStart event has been received. From
+	// then on the runs are only started by ActionRunStart events.
+	recorded bool
+	// base is the RunID of the first attempt of the current run.
+	base int
+	// max is the largest RunID assigned to a run.
+	max int
+}
+
+func newRunSplitter(runID int, gap time.Duration) *runSplitter {
+	return &runSplitter{
+		runID: runID,
+		gap:   gap,
+		ended: make(map[string]bool),
+		base:  runID,
+		max:   runID,
+	}
+}
+
+// next returns the RunID for event. Unless the runs are recorded by
+// ActionRunStart events, a new run starts if the event is for a package that
+// already finished, or if it happened more than gap after the previous event.
+func (s *runSplitter) next(event TestEvent) int {
+	if !s.recorded && (s.ended[event.Package] || s.isAfterGap(event.Time)) {
+		s.setRunID(s.max + 1)
+		s.base = s.runID
+	}
+	if !event.Time.IsZero() {
+		s.last = event.Time
+	}
+	if event.PackageEvent() && event.Action.IsTerminal() {
+		s.ended[event.Package] = true
+	}
+	s.started = true
+	return s.runID
+}
+
+// startRun is called for each ActionRunStart event. The attempt is the RunID
+// of the event. An attempt greater than 0 is a --rerun-fails attempt of the
+// current run. Otherwise a new run starts, unless no events have been received
+// in the current run.
+func (s *runSplitter) startRun(attempt int) {
+	s.recorded = true
+	switch {
+	case attempt > 0:
+		s.setRunID(s.base + attempt)
+	case s.started:
+		s.setRunID(s.max + 1)
+		s.base = s.runID
+		s.last = time.Time{}
+		s.started = false
+	}
+}
+
+func (s *runSplitter) setRunID(runID int) {
+	s.runID = runID
+	if runID > s.max {
+		s.max = runID
+	}
+	s.ended = make(map[string]bool)
+}
+
+func (s *runSplitter) isAfterGap(t time.Time) bool {
+	return s.gap > 0 && !t.IsZero() && !s.last.IsZero() && t.Sub(s.last) > s.gap
+}
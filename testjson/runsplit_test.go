@@ -0,0 +1,116 @@
+package testjson
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestScanTestOutput_SplitRuns(t *testing.T) {
+	run := func(start, result string) string {
+		return `{"Time":"` + start + `","Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Time":"` + start + `","Action":"` + result + `","Package":"example.com/pkg","Test":"TestFlaky","Elapsed":0.1}
+{"Time":"` + start + `","Action":"` + result + `","Package":"example.com/pkg","Elapsed":0.2}
+`
+	}
+	in := run("2021-01-02T03:04:00Z", "fail") +
+		run("2021-01-02T03:04:01Z", "pass") +
+		`{"Time":"2021-01-02T03:04:02Z","Action":"run","Package":"example.com/other","Test":"TestOther"}
+{"Time":"2021-01-02T03:04:02Z","Action":"pass","Package":"example.com/other","Test":"TestOther","Elapsed":0.1}
+{"Time":"2021-01-02T03:04:02Z","Action":"pass","Package":"example.com/other","Elapsed":0.2}
+` + run("2021-01-02T05:00:00Z", "fail")
+
+	runIDs := func(tcs []TestCase) []int {
+		var result []int
+		for _, tc := range tcs {
+			result = append(result, tc.RunID)
+		}
+		return result
+	}
+
+	t.Run("package starts again", func(t *testing.T) {
+		exec, err := ScanTestOutput(ScanConfig{
+			Stdout:    strings.NewReader(in),
+			SplitRuns: true,
+		})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, runIDs(exec.Failed()), []int{0, 2})
+		assert.DeepEqual(t, runIDs(exec.Package("example.com/pkg").Passed), []int{1})
+		assert.DeepEqual(t, runIDs(exec.Package("example.com/other").Passed), []int{1})
+		assert.Equal(t, exec.lastRunID, 2)
+	})
+
+	t.Run("time gap", func(t *testing.T) {
+		exec, err := ScanTestOutput(ScanConfig{
+			Stdout:    strings.NewReader(in),
+			SplitRuns: true,
+			RunGap:    time.Minute,
+		})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, runIDs(exec.Failed()), []int{0, 2})
+		assert.DeepEqual(t, runIDs(exec.Package("example.com/other").Passed), []int{1})
+	})
+
+	t.Run("time gap without package restart", func(t *testing.T) {
+		exec, err := ScanTestOutput(ScanConfig{
+			Stdout: strings.NewReader(
+				run("2021-01-02T03:04:00Z", "fail") +
+					strings.ReplaceAll(run("2021-01-02T05:00:00Z", "pass"), "example.com/pkg", "example.com/other")),
+			SplitRuns: true,
+			RunGap:    time.Minute,
+		})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, runIDs(exec.Package("example.com/other").Passed), []int{1})
+	})
+
+	t.Run("run start events", func(t *testing.T) {
+		start := func(id string) string {
+			return string(RunStartEvent(id, 0, time.Date(2021, 1, 2, 3, 4, 0, 0, time.UTC))) + "\n"
+		}
+		other := strings.ReplaceAll(run("2021-01-02T03:04:01Z", "pass"), "example.com/pkg", "example.com/other")
+		handler := &captureHandler{}
+		exec, err := ScanTestOutput(ScanConfig{
+			Stdout: strings.NewReader(
+				start("one") + run("2021-01-02T03:04:00Z", "fail") +
+					start("two") + other),
+			Handler:   handler,
+			SplitRuns: true,
+		})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, runIDs(exec.Failed()), []int{0})
+		assert.DeepEqual(t, runIDs(exec.Package("example.com/other").Passed), []int{1})
+		assert.DeepEqual(t, exec.Packages(), []string{"example.com/other", "example.com/pkg"})
+		assert.Equal(t, len(handler.events), 6, "run start events are not sent to the handler")
+	})
+
+	t.Run("run start events with rerun attempts", func(t *testing.T) {
+		start := func(id string, attempt int) string {
+			return string(RunStartEvent(id, attempt, time.Date(2021, 1, 2, 3, 4, 0, 0, time.UTC))) + "\n"
+		}
+		exec, err := ScanTestOutput(ScanConfig{
+			Stdout: strings.NewReader(
+				start("one", 0) + run("2021-01-02T03:04:00Z", "fail") +
+					// the first rerun attempt runs the package twice
+					start("one", 1) + run("2021-01-02T03:04:01Z", "fail") +
+					run("2021-01-02T03:04:02Z", "fail") +
+					start("one", 2) + run("2021-01-02T03:04:03Z", "pass") +
+					start("two", 0) + run("2021-01-02T03:05:00Z", "fail") +
+					start("two", 1) + run("2021-01-02T03:05:01Z", "pass")),
+			SplitRuns: true,
+			RunGap:    time.Nanosecond,
+		})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, runIDs(exec.Failed()), []int{0, 1, 1, 3})
+		assert.DeepEqual(t, runIDs(exec.Package("example.com/pkg").Passed), []int{2, 4})
+		assert.Equal(t, exec.lastRunID, 4)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(in)})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, runIDs(exec.Failed()), []int{0, 0})
+		assert.Equal(t, exec.lastRunID, 0)
+	})
+}
@@ -0,0 +1,144 @@
+package testjson
+
+import (
+	"io"
+	"time"
+)
+
+// ScanOption sets a field of a ScanConfig created by NewScanConfig.
+type ScanOption func(config *ScanConfig)
+
+// NewScanConfig returns a ScanConfig which reads the test2json output stream
+// from stdout, with the options applied in order. It is equivalent to creating
+// a ScanConfig literal, and is provided so that programs which embed gotestsum
+// are not affected by fields added to ScanConfig in later versions.
+func NewScanConfig(stdout io.Reader, options ...ScanOption) ScanConfig {
+	config := ScanConfig{Stdout: stdout}
+	for _, option := range options {
+		option(&config)
+	}
+	return config
+}
+
+// WithStderr sets ScanConfig.Stderr.
+func WithStderr(stderr io.Reader) ScanOption {
+	return func(config *ScanConfig) {
+		config.Stderr = stderr
+	}
+}
+
+// WithHandler sets ScanConfig.Handler.
+func WithHandler(handler EventHandler) ScanOption {
+	return func(config *ScanConfig) {
+		config.Handler = handler
+	}
+}
+
+// WithRunID sets ScanConfig.RunID.
+func WithRunID(runID int) ScanOption {
+	return func(config *ScanConfig) {
+		config.RunID = runID
+	}
+}
+
+// WithExecution sets ScanConfig.Execution, to add the events to an Execution
+// from a previous scan.
+func WithExecution(exec *Execution) ScanOption {
+	return func(config *ScanConfig) {
+		config.Execution = exec
+	}
+}
+
+// WithStop sets ScanConfig.Stop.
+func WithStop(stop func()) ScanOption {
+	return func(config *ScanConfig) {
+		config.Stop = stop
+	}
+}
+
+// WithIgnoreNonJSONOutputLines sets ScanConfig.IgnoreNonJSONOutputLines.
+func WithIgnoreNonJSONOutputLines() ScanOption {
+	return func(config *ScanConfig) {
+		config.IgnoreNonJSONOutputLines = true
+	}
+}
+
+// WithStderrMerge sets ScanConfig.StderrMerge.
+func WithStderrMerge(merge StderrMerge) ScanOption {
+	return func(config *ScanConfig) {
+		config.StderrMerge = merge
+	}
+}
+
+// WithDemoteTeardownFailures sets ScanConfig.DemoteTeardownFailures.
+func WithDemoteTeardownFailures() ScanOption {
+	return func(config *ScanConfig) {
+		config.DemoteTeardownFailures = true
+	}
+}
+
+// WithRewriteTestName sets ScanConfig.RewriteTestName.
+func WithRewriteTestName(rewrite func(pkg, name string) string) ScanOption {
+	return func(config *ScanConfig) {
+		config.RewriteTestName = rewrite
+	}
+}
+
+// WithRewriteOutput sets ScanConfig.RewriteOutput.
+func WithRewriteOutput(rewrite func(output string) string) ScanOption {
+	return func(config *ScanConfig) {
+		config.RewriteOutput = rewrite
+	}
+}
+
+// WithSplitRuns sets ScanConfig.SplitRuns, and ScanConfig.RunGap to gap.
+func WithSplitRuns(gap time.Duration) ScanOption {
+	return func(config *ScanConfig) {
+		config.SplitRuns = true
+		config.RunGap = gap
+	}
+}
+
+// WithKeepPassedOutput sets ScanConfig.KeepPassedOutput.
+func WithKeepPassedOutput() ScanOption {
+	return func(config *ScanConfig) {
+		config.KeepPassedOutput = true
+	}
+}
+
+// WithClock sets ScanConfig.Clock.
+func WithClock(c Clock) ScanOption {
+	return func(config *ScanConfig) {
+		config.Clock = c
+	}
+}
+
+// NewFormatHandler returns an EventHandler which sends every TestEvent to the
+// formatter, and writes every line of stderr to errOut. It is the handler used
+// by gotestsum when no other output is written.
+func NewFormatHandler(formatter EventFormatter, errOut io.Writer) EventHandler {
+	return &formatHandler{formatter: formatter, errOut: errOut}
+}
+
+type formatHandler struct {
+	formatter EventFormatter
+	errOut    io.Writer
+}
+
+func (h *formatHandler) Event(event TestEvent, exec *Execution) error {
+	return h.formatter.Format(event, exec)
+}
+
+func (h *formatHandler) Err(text string) error {
+	_, _ = io.WriteString(h.errOut, text+"\n")
+	// like the Err of gotestsum, a failed write to stderr does not stop the scan
+	return nil
+}
+
+// Flush flushes the formatter when it implements Flusher.
+func (h *formatHandler) Flush() error {
+	if flusher, ok := h.formatter.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package testjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestNewScanConfig(t *testing.T) {
+	stdout := strings.NewReader("")
+	stderr := strings.NewReader("")
+	exec := newExecution()
+	handler := &captureHandler{}
+	eventClock := NewEventClock()
+
+	config := NewScanConfig(stdout,
+		WithStderr(stderr),
+		WithHandler(handler),
+		WithRunID(2),
+		WithExecution(exec),
+		WithIgnoreNonJSONOutputLines(),
+		WithStderrMerge(StderrMergeSeparate),
+		WithDemoteTeardownFailures(),
+		WithSplitRuns(time.Second),
+		WithKeepPassedOutput(),
+		WithClock(eventClock))
+
+	assert.Equal(t, config.Stdout, stdout)
+	assert.Equal(t, config.Stderr, stderr)
+	assert.Equal(t, config.Handler, handler)
+	assert.Equal(t, config.RunID, 2)
+	assert.Equal(t, config.Execution, exec)
+	assert.Assert(t, config.IgnoreNonJSONOutputLines)
+	assert.Equal(t, config.StderrMerge, StderrMergeSeparate)
+	assert.Assert(t, config.DemoteTeardownFailures)
+	assert.Assert(t, config.SplitRuns)
+	assert.Equal(t, config.RunGap, time.Second)
+	assert.Assert(t, config.KeepPassedOutput)
+	assert.Equal(t, config.Clock, Clock(eventClock))
+}
+
+func TestNewFormatHandler(t *testing.T) {
+	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
+
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	handler := NewFormatHandler(NewEventFormatterWithOptions(out, "pkgname", FormatOptions{}), errOut)
+	_, err := ScanTestOutput(NewScanConfig(
+		bytes.NewReader(golden.Get(t, "go-test-json.out")),
+		WithStderr(bytes.NewReader(golden.Get(t, "go-test-json.err"))),
+		WithHandler(handler)))
+
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "short-format.out")
+	golden.Assert(t, errOut.String(), "short-format.err")
+}
@@ -0,0 +1,109 @@
+package testjson
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// screenReaderFormat prints a short sentence for each test and package result,
+// for use with a screen reader. Every sentence starts with the result, and the
+// output is never redrawn or colored. When failuresOnly is true, only the
+// failed tests and packages are printed.
+func screenReaderFormat(opts FormatOptions, failuresOnly bool) func(event TestEvent, exec *Execution) (string, error) {
+	return func(event TestEvent, exec *Execution) (string, error) {
+		if !event.Action.IsTerminal() {
+			return "", nil
+		}
+		if failuresOnly && event.Action != ActionFail {
+			return "", nil
+		}
+		pkg := exec.Package(event.Package)
+		if event.PackageEvent() {
+			return screenReaderPackage(opts, event, pkg), nil
+		}
+		return screenReaderTest(opts, event, pkg), nil
+	}
+}
+
+func screenReaderTest(opts FormatOptions, event TestEvent, pkg *Package) string {
+	name := event.Test
+	if event.RunID > 0 {
+		name += fmt.Sprintf(", rerun %d", event.RunID)
+	}
+	where := fmt.Sprintf("%s in %s", name, RelativePackagePath(event.Package))
+	elapsed := spokenDuration(opts, elapsedDuration(event.Elapsed))
+
+	switch event.Action {
+	case ActionPass:
+		return fmt.Sprintf("Passed %s, %s.\n", where, elapsed)
+	case ActionSkip:
+		tc := lastTestCaseByName(pkg.Skipped, event.Test)
+		if reason := SkipReason(pkg.OutputLines(tc)); reason != "" {
+			return fmt.Sprintf("Skipped %s, %s.\n", where, reason)
+		}
+		return fmt.Sprintf("Skipped %s.\n", where)
+	}
+
+	tc := pkg.LastFailedByName(event.Test)
+	output := outputMessage(strings.Join(pkg.OutputLines(tc), ""))
+	if output == "" {
+		return fmt.Sprintf("Failed %s, %s.\n", where, elapsed)
+	}
+	return fmt.Sprintf("Failed %s, %s. Output:\n%s\nEnd of output for %s.\n",
+		where, elapsed, output, event.Test)
+}
+
+func screenReaderPackage(opts FormatOptions, event TestEvent, pkg *Package) string {
+	name := RelativePackagePath(event.Package)
+	elapsed := spokenDuration(opts, elapsedDuration(event.Elapsed))
+	if pkg.cached {
+		elapsed = "cached"
+	}
+
+	switch {
+	case event.Action == ActionSkip, event.Action == ActionPass && pkg.Total == 0:
+		return fmt.Sprintf("Package %s has no tests.\n", name)
+	case event.Action == ActionPass:
+		return fmt.Sprintf("Package %s passed, %s, %s.\n",
+			name, spokenCount(opts, pkg.Total, "test"), elapsed)
+	case len(pkg.Failed) > 0:
+		return fmt.Sprintf("Package %s failed, %s of %s failed, %s.\n",
+			name, opts.formatCount(len(pkg.Failed)), spokenCount(opts, pkg.Total, "test"), elapsed)
+	}
+
+	output := outputMessage(pkg.Output(0))
+	if output == "" {
+		return fmt.Sprintf("Package %s failed, %s.\n", name, elapsed)
+	}
+	return fmt.Sprintf("Package %s failed, %s. Output:\n%s\nEnd of output for %s.\n",
+		name, elapsed, output, name)
+}
+
+func lastTestCaseByName(tcs []TestCase, name string) TestCase {
+	for i := len(tcs) - 1; i >= 0; i-- {
+		if tcs[i].Test.Name() == name {
+			return tcs[i]
+		}
+	}
+	return TestCase{}
+}
+
+// spokenDuration formats d as a number of seconds, with the unit as a word.
+func spokenDuration(opts FormatOptions, d time.Duration) string {
+	if opts.Deterministic {
+		d = 0
+	}
+	seconds := fmt.Sprintf("%.2f", d.Seconds())
+	if opts.DecimalSeparator != "" {
+		seconds = strings.Replace(seconds, ".", opts.DecimalSeparator, 1)
+	}
+	return seconds + " seconds"
+}
+
+func spokenCount(opts FormatOptions, n int, noun string) string {
+	if n != 1 {
+		noun += "s"
+	}
+	return opts.formatCount(n) + " " + noun
+}
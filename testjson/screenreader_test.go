@@ -0,0 +1,27 @@
+package testjson
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestScanTestOutput_WithScreenReaderFormat(t *testing.T) {
+	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
+
+	shim := newFakeHandlerWithAdapter(screenReaderFormat(FormatOptions{}, false), "go-test-json")
+	exec, err := ScanTestOutput(shim.Config(t))
+	assert.NilError(t, err)
+	golden.Assert(t, shim.out.String(), "screen-reader-format.out")
+	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
+}
+
+func TestScanTestOutput_WithScreenReaderFailuresFormat(t *testing.T) {
+	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
+
+	shim := newFakeHandlerWithAdapter(screenReaderFormat(FormatOptions{}, true), "go-test-json")
+	_, err := ScanTestOutput(shim.Config(t))
+	assert.NilError(t, err)
+	golden.Assert(t, shim.out.String(), "screen-reader-failures-format.out")
+}
@@ -0,0 +1,51 @@
+package testjson
+
+import (
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// PackageBaseline is the usual elapsed time of each package.
+type PackageBaseline struct {
+	elapsed map[string]time.Duration
+}
+
+// NewPackageBaseline returns a PackageBaseline from a map of package name to
+// elapsed time.
+func NewPackageBaseline(elapsed map[string]time.Duration) *PackageBaseline {
+	return &PackageBaseline{elapsed: elapsed}
+}
+
+// Lookup returns the baseline elapsed time of the package.
+func (b *PackageBaseline) Lookup(pkg string) (time.Duration, bool) {
+	if b == nil {
+		return 0, false
+	}
+	elapsed, ok := b.elapsed[pkg]
+	return elapsed, ok
+}
+
+// slowPackageMarker returns the marker printed at the end of the line for a
+// package in the pkgname formats when the package was slow, or an empty string
+// when it was not. A package is slow when elapsed is more than
+// SlowPackagePercent longer than the baseline of the package, or longer than
+// SlowPackageThreshold. The marker is never printed with Deterministic,
+// because it depends on the elapsed time.
+func (o FormatOptions) slowPackageMarker(pkg string, elapsed time.Duration) string {
+	if o.Deterministic || elapsed <= 0 {
+		return ""
+	}
+	if baseline, ok := o.SlowPackageBaseline.Lookup(pkg); ok && o.SlowPackagePercent > 0 && baseline > 0 {
+		increase := int64((elapsed - baseline) * 100 / baseline)
+		if increase > int64(o.SlowPackagePercent) {
+			return color.YellowString(" (slow, +%d%% vs %s avg)", increase,
+				strings.TrimLeft(o.formatDuration(baseline, 3, DurationUnits), " "))
+		}
+	}
+	if o.SlowPackageThreshold > 0 && elapsed > o.SlowPackageThreshold {
+		return color.YellowString(" (slow)")
+	}
+	return ""
+}
@@ -0,0 +1,26 @@
+package testjson
+
+// SetSnapshot stores a snapshot of the environment, taken when tc failed. The
+// snapshot is printed with the test in the summary.
+func (p *Package) SetSnapshot(tc TestCase, snapshot string) {
+	if p.snapshots == nil {
+		p.snapshots = make(map[int]string)
+	}
+	p.snapshots[tc.ID] = snapshot
+}
+
+// Snapshot returns the snapshot of the environment stored by SetSnapshot for
+// tc, or an empty string if there is no snapshot.
+func (p *Package) Snapshot(tc TestCase) string {
+	return p.snapshots[tc.ID]
+}
+
+// Snapshot returns the snapshot of the environment stored by
+// Package.SetSnapshot for tc, or an empty string if there is no snapshot.
+func (e *Execution) Snapshot(tc TestCase) string {
+	pkg := e.packages[tc.Package]
+	if pkg == nil {
+		return ""
+	}
+	return pkg.Snapshot(tc)
+}
@@ -0,0 +1,153 @@
+package testjson
+
+import (
+	"fmt"
+	"io"
+)
+
+// SpillFile is a file used to store test output outside of memory. See
+// Execution.SpillOutput.
+type SpillFile interface {
+	io.Writer
+	io.ReaderAt
+}
+
+// outputSpill stores lines of test output in a SpillFile.
+type outputSpill struct {
+	file   SpillFile
+	offset int64
+	// err is the first error returned by file.Write. Once a write fails all
+	// output is stored in memory, so that the lines of a test stay in order.
+	err error
+}
+
+// lineRef is the position of a line of output in the SpillFile.
+type lineRef struct {
+	offset int64
+	length int
+}
+
+func (s *outputSpill) write(line string) (lineRef, bool) {
+	if s.err != nil {
+		return lineRef{}, false
+	}
+	n, err := io.WriteString(s.file, line)
+	ref := lineRef{offset: s.offset, length: n}
+	s.offset += int64(n)
+	if err != nil {
+		s.err = err
+		return lineRef{}, false
+	}
+	return ref, true
+}
+
+func (s *outputSpill) read(ref lineRef) string {
+	buf := make([]byte, ref.length)
+	if _, err := s.file.ReadAt(buf, ref.offset); err != nil {
+		return fmt.Sprintf("failed to read test output from disk: %v\n", err)
+	}
+	return string(buf)
+}
+
+// SpillOutput moves all of the test output stored in memory to file, and stores
+// any output received after the call in file instead of in memory. The output
+// is read back from file when it is needed, so file must not be closed until
+// the Execution is no longer used. Each Execution must use a different file.
+//
+// SpillOutput must not be called concurrently with the scan that populates the
+// Execution. It is safe to call from EventHandler.Event.
+func (e *Execution) SpillOutput(file SpillFile) error {
+	if e.spill != nil {
+		return fmt.Errorf("test output is already stored in a file")
+	}
+	e.spill = &outputSpill{file: file}
+	for _, name := range sortedKeys(e.packages) {
+		pkg := e.packages[name]
+		pkg.spill = e.spill
+		for id, lines := range pkg.output {
+			pkg.output[id] = pkg.spillLines(id, lines)
+		}
+	}
+	return e.spill.err
+}
+
+// spillLines writes lines to the SpillFile, and returns any lines that could
+// not be written.
+func (p *Package) spillLines(id int, lines []string) []string {
+	for i, line := range lines {
+		ref, ok := p.spill.write(line)
+		if !ok {
+			return lines[i:]
+		}
+		if p.spilled == nil {
+			p.spilled = make(map[int][]lineRef)
+		}
+		p.spilled[id] = append(p.spilled[id], ref)
+	}
+	return nil
+}
+
+// TruncateOutput limits the test output stored for each test to approximately
+// the last maxLines lines. Output that is removed is replaced by a line with
+// the number of lines that were removed. The limit applies to the output that
+// is already stored, and to all output received after the call.
+//
+// TruncateOutput must not be called concurrently with the scan that populates
+// the Execution. It is safe to call from EventHandler.Event.
+func (e *Execution) TruncateOutput(maxLines int) {
+	e.maxOutputLines = maxLines
+	for _, pkg := range e.packages {
+		pkg.maxOutputLines = maxLines
+		for id := range pkg.output {
+			pkg.truncateOutput(id, maxLines)
+		}
+		for id := range pkg.spilled {
+			pkg.truncateOutput(id, maxLines)
+		}
+	}
+}
+
+// truncateOutput removes the oldest lines of output for the test with id, so
+// that at most max lines are stored.
+func (p *Package) truncateOutput(id int, max int) {
+	spilled, lines := p.spilled[id], p.output[id]
+	drop := len(spilled) + len(lines) - max
+	if max <= 0 || drop <= 0 {
+		return
+	}
+	if p.truncated == nil {
+		p.truncated = make(map[int]int)
+	}
+	p.truncated[id] += drop
+
+	n := drop
+	if n > len(spilled) {
+		n = len(spilled)
+	}
+	if len(spilled) > 0 {
+		// copy the slice so that the memory used by the old lines is released
+		p.spilled[id] = append([]lineRef(nil), spilled[n:]...)
+	}
+	if drop -= n; drop > 0 {
+		p.output[id] = append([]string(nil), lines[drop:]...)
+	}
+}
+
+// lines returns all of the output stored for the test with id.
+func (p *Package) lines(id int) []string {
+	spilled, lines := p.spilled[id], p.output[id]
+	truncated := p.truncated[id]
+	if len(spilled) == 0 && truncated == 0 {
+		return lines
+	}
+
+	result := make([]string, 0, len(spilled)+len(lines)+1)
+	if truncated > 0 {
+		result = append(result,
+			fmt.Sprintf("... %d lines of output were truncated to reduce memory use\n", truncated))
+	}
+	for _, ref := range spilled {
+		result = append(result, p.spill.read(ref))
+	}
+	return append(result, lines...)
+}
@@ -0,0 +1,103 @@
+package testjson
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+// spillHandler calls SpillOutput after the first n events.
+type spillHandler struct {
+	noopHandler
+	n    int
+	file SpillFile
+	err  error
+}
+
+func (h *spillHandler) Event(_ TestEvent, exec *Execution) error {
+	h.n--
+	if h.n == 0 {
+		h.err = exec.SpillOutput(h.file)
+	}
+	return nil
+}
+
+func TestExecution_SpillOutput(t *testing.T) {
+	_, reset := patchClock()
+	defer reset()
+
+	scan := func(handler EventHandler) *Execution {
+		exec, err := ScanTestOutput(ScanConfig{
+			Stdout:  bytes.NewReader(golden.Get(t, "go-test-json.out")),
+			Handler: handler,
+		})
+		assert.NilError(t, err)
+		return exec
+	}
+	expected := new(bytes.Buffer)
+	PrintSummary(expected, scan(noopHandler{}), SummarizeAll)
+
+	dir := fs.NewDir(t, "spill")
+	file, err := os.Create(dir.Join("output"))
+	assert.NilError(t, err)
+	defer file.Close()
+
+	handler := &spillHandler{n: 50, file: file}
+	exec := scan(handler)
+	assert.NilError(t, handler.err)
+
+	actual := new(bytes.Buffer)
+	PrintSummary(actual, exec, SummarizeAll)
+	assert.Equal(t, actual.String(), expected.String())
+
+	info, err := file.Stat()
+	assert.NilError(t, err)
+	assert.Assert(t, info.Size() > 0)
+	for _, pkg := range exec.packages {
+		for id, lines := range pkg.output {
+			assert.Equal(t, len(lines), 0, "output for %d is in memory", id)
+		}
+	}
+
+	assert.ErrorContains(t, exec.SpillOutput(file), "already stored in a file")
+}
+
+func TestExecution_TruncateOutput(t *testing.T) {
+	exec := newExecution()
+	add := func(output string) {
+		exec.add(TestEvent{Package: "pkg", Test: "TestOne", Action: ActionOutput, Output: output})
+	}
+	exec.add(TestEvent{Package: "pkg", Test: "TestOne", Action: ActionRun})
+	for i := 0; i < 10; i++ {
+		add(strings.Repeat("x", i) + "\n")
+	}
+	exec.TruncateOutput(3)
+
+	tc := exec.Package("pkg").running["TestOne"]
+	expected := []string{
+		"... 7 lines of output were truncated to reduce memory use\n",
+		"xxxxxxx\n",
+		"xxxxxxxx\n",
+		"xxxxxxxxx\n",
+	}
+	assert.DeepEqual(t, exec.Package("pkg").OutputLines(tc), expected)
+
+	// output after the call is truncated once it is twice the limit
+	for i := 0; i < 3; i++ {
+		add("y\n")
+	}
+	assert.Equal(t, len(exec.Package("pkg").OutputLines(tc)), 7)
+	add("z\n")
+	expected = []string{
+		"... 11 lines of output were truncated to reduce memory use\n",
+		"y\n",
+		"y\n",
+		"z\n",
+	}
+	assert.DeepEqual(t, exec.Package("pkg").OutputLines(tc), expected)
+}
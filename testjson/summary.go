@@ -3,6 +3,7 @@ package testjson
 import (
 	"fmt"
 	"io"
+	"regexp"
 	"strings"
 	"time"
 	"unicode"
@@ -68,12 +69,19 @@ func NewSummary(value string) (Summary, bool) {
 // PrintSummary of a test Execution. Prints a section for each summary type
 // followed by a DONE line to out.
 func PrintSummary(out io.Writer, execution *Execution, opts Summary) {
+	PrintSummaryWithOptions(out, execution, opts, FormatOptions{})
+}
+
+// PrintSummaryWithOptions prints the summary of a test Execution, the same as
+// PrintSummary, using formatOpts to format elapsed times and counts.
+func PrintSummaryWithOptions(out io.Writer, execution *Execution, opts Summary, formatOpts FormatOptions) {
 	execSummary := newExecSummary(execution, opts)
 	if opts.Includes(SummarizeSkipped) {
-		writeTestCaseSummary(out, execSummary, formatSkipped())
+		writeTestCaseSummary(out, execSummary, formatSkipped(), formatOpts)
 	}
 	if opts.Includes(SummarizeFailed) {
-		writeTestCaseSummary(out, execSummary, formatFailed())
+		writeTestCaseSummary(out, execSummary, formatFailed(), formatOpts)
+		writeTestCaseSummary(out, execSummary, formatTeardownFailed(), formatOpts)
 	}
 
 	errors := execution.Errors()
@@ -81,16 +89,24 @@ func PrintSummary(out io.Writer, execution *Execution, opts Summary) {
 		writeErrorSummary(out, errors)
 	}
 
-	fmt.Fprintf(out, "\n%s %d tests%s%s%s in %s\n",
+	fmt.Fprintf(out, "\n%s %s tests%s%s%s%s in %s\n",
 		formatExecStatus(execution),
-		execution.Total(),
-		formatTestCount(len(execution.Skipped()), "skipped", ""),
-		formatTestCount(len(execution.Failed()), "failure", "s"),
-		formatTestCount(countErrors(errors), "error", "s"),
-		FormatDurationAsSeconds(execution.Elapsed(), 3))
+		formatOpts.formatCount(execution.Total()),
+		formatTestCount(formatOpts, len(execution.Skipped()), "skipped", ""),
+		formatTestCount(formatOpts, len(execution.Failed()), "failure", "s"),
+		formatTestCount(formatOpts, len(execution.TeardownFailed()), "teardown error", "s"),
+		formatTestCount(formatOpts, countErrors(errors), "error", "s"),
+		strings.TrimLeft(formatOpts.formatDuration(execution.Elapsed(), 3, DurationSeconds), " "))
+	if total, ok := execution.TotalCoverage(formatOpts.CoverProfile); ok {
+		fmt.Fprintln(out, total.String())
+	}
+
+	if formatOpts.ShowFirstFailure {
+		writeFirstFailure(out, execution, formatOpts)
+	}
 }
 
-func formatTestCount(count int, category string, pluralize string) string {
+func formatTestCount(opts FormatOptions, count int, category string, pluralize string) string {
 	switch count {
 	case 0:
 		return ""
@@ -98,7 +114,7 @@ func formatTestCount(count int, category string, pluralize string) string {
 	default:
 		category += pluralize
 	}
-	return fmt.Sprintf(", %d %s", count, category)
+	return fmt.Sprintf(", %s %s", opts.formatCount(count), category)
 }
 
 func formatExecStatus(exec *Execution) string {
@@ -145,8 +161,12 @@ func countErrors(errors []string) int {
 
 type executionSummary interface {
 	Failed() []TestCase
+	TeardownFailed() []TestCase
 	Skipped() []TestCase
 	OutputLines(TestCase) []string
+	Snapshot(TestCase) string
+	Fingerprint(TestCase) string
+	FailureCause(TestCase) string
 }
 
 type noOutputSummary struct {
@@ -157,6 +177,10 @@ func (s *noOutputSummary) OutputLines(_ TestCase) []string {
 	return nil
 }
 
+func (s *noOutputSummary) Snapshot(_ TestCase) string {
+	return ""
+}
+
 func newExecSummary(execution *Execution, opts Summary) executionSummary {
 	if opts.Includes(SummarizeOutput) {
 		return execution
@@ -164,43 +188,144 @@ func newExecSummary(execution *Execution, opts Summary) executionSummary {
 	return &noOutputSummary{Execution: execution}
 }
 
-func writeTestCaseSummary(out io.Writer, execution executionSummary, conf testCaseFormatConfig) {
-	testCases := conf.getter(execution)
+func writeTestCaseSummary(out io.Writer, execution executionSummary, conf testCaseFormatConfig, opts FormatOptions) {
+	testCases := FilterByLabel(conf.getter(execution), SplitLabels(opts.Labels))
 	if len(testCases) == 0 {
 		return
 	}
+	if opts.Deterministic {
+		sortTestCases(testCases)
+	}
 	fmt.Fprintln(out, "\n=== "+conf.header)
+	if conf.groupFailures && opts.GroupFailures {
+		writeFailureGroups(out, execution, conf, opts, testCases)
+		return
+	}
 	for idx, tc := range testCases {
-		fmt.Fprintf(out, "=== %s: %s %s%s (%s)\n",
-			conf.prefix,
-			RelativePackagePath(tc.Package),
-			tc.Test,
-			formatRunID(tc.RunID),
-			FormatDurationAsSeconds(tc.Elapsed, 2))
-		for _, line := range execution.OutputLines(tc) {
-			if isFramingLine(line) || conf.filter(tc.Test.Name(), line) {
-				continue
-			}
-			fmt.Fprint(out, line)
-		}
+		writeTestCase(out, execution, conf, opts, tc)
 		if _, isNoOutput := execution.(*noOutputSummary); !isNoOutput && idx+1 != len(testCases) {
 			fmt.Fprintln(out)
 		}
 	}
 }
 
+// writeFailureGroups prints the tests which failed with the same cause as a
+// group. The first test of a group is printed with its output, followed by
+// the names of the other tests in the group.
+func writeFailureGroups(
+	out io.Writer,
+	execution executionSummary,
+	conf testCaseFormatConfig,
+	opts FormatOptions,
+	testCases []TestCase,
+) {
+	groups := groupFailures(testCases, execution.FailureCause)
+	for idx, group := range groups {
+		if len(group.Tests) > 1 {
+			fmt.Fprintf(out, "=== %d tests failed with: %s\n", len(group.Tests), group.Cause)
+		}
+		writeTestCase(out, execution, conf, opts, group.Tests[0])
+		for _, tc := range group.Tests[1:] {
+			fmt.Fprintf(out, "=== %s: %s %s%s (%s)\n",
+				conf.prefix,
+				RelativePackagePath(tc.Package),
+				tc.Test,
+				formatRunID(tc.RunID),
+				opts.formatDuration(tc.Elapsed, 2, DurationSeconds))
+		}
+		if _, isNoOutput := execution.(*noOutputSummary); !isNoOutput && idx+1 != len(groups) {
+			fmt.Fprintln(out)
+		}
+	}
+}
+
+func writeTestCase(out io.Writer, execution executionSummary, conf testCaseFormatConfig, opts FormatOptions, tc TestCase) {
+	fmt.Fprintf(out, "=== %s: %s %s%s (%s)\n",
+		conf.prefix,
+		RelativePackagePath(tc.Package),
+		tc.Test,
+		formatRunID(tc.RunID),
+		opts.formatDuration(tc.Elapsed, 2, DurationSeconds))
+	var lines []string
+	for _, line := range execution.OutputLines(tc) {
+		if isFramingLine(line) || conf.filter(tc.Test.Name(), line) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if conf.withLink {
+		if link := opts.Link(tc); link != "" {
+			fmt.Fprintln(out, "    link: "+link)
+		}
+		if opts.KnownIssues != nil {
+			writeKnownIssue(out, opts.KnownIssues, execution.Fingerprint(tc))
+		}
+		writeQuarantined(out, opts.Quarantine, tc)
+	}
+	if opts.FirstErrorFirst {
+		if line := firstErrorLine(lines); line != "" {
+			fmt.Fprintln(out, "    first error: "+strings.TrimSpace(line))
+		}
+	}
+	for _, line := range lines {
+		if opts.HighlightLogLevels {
+			line = highlightLogLevel(line)
+		}
+		fmt.Fprint(out, line)
+	}
+	if snapshot := execution.Snapshot(tc); snapshot != "" {
+		writeSnapshot(out, snapshot)
+	}
+}
+
+func writeKnownIssue(out io.Writer, issues *KnownIssues, fingerprint string) {
+	if url, ok := issues.Lookup(fingerprint); ok {
+		fmt.Fprintf(out, "    known issue: %s (fingerprint %s)\n", url, fingerprint)
+		return
+	}
+	fmt.Fprintln(out, "    fingerprint: "+fingerprint)
+}
+
+func writeQuarantined(out io.Writer, quarantine *Quarantine, tc TestCase) {
+	rule, ok := quarantine.Lookup(tc)
+	switch {
+	case !ok:
+	case rule.Reason == "":
+		fmt.Fprintln(out, "    quarantined")
+	default:
+		fmt.Fprintln(out, "    quarantined: "+rule.Reason)
+	}
+}
+
+// writeSnapshot prints the snapshot of the environment taken when a test
+// failed, indented below the output of the test.
+func writeSnapshot(out io.Writer, snapshot string) {
+	fmt.Fprintln(out, "    environment:")
+	for _, line := range strings.Split(strings.TrimRight(snapshot, "\n"), "\n") {
+		fmt.Fprintln(out, "        "+line)
+	}
+}
+
 type testCaseFormatConfig struct {
 	header string
 	prefix string
-	filter func(testName string, line string) bool
-	getter func(executionSummary) []TestCase
+	// withLink prints the FormatOptions.FailureLink, and the known issue, for
+	// each test case.
+	withLink bool
+	// groupFailures groups the test cases by FailureCause when
+	// FormatOptions.GroupFailures is enabled.
+	groupFailures bool
+	filter        func(testName string, line string) bool
+	getter        func(executionSummary) []TestCase
 }
 
 func formatFailed() testCaseFormatConfig {
 	withColor := color.RedString
 	return testCaseFormatConfig{
-		header: withColor("Failed"),
-		prefix: withColor("FAIL"),
+		header:        withColor("Failed"),
+		prefix:        withColor("FAIL"),
+		withLink:      true,
+		groupFailures: true,
 		filter: func(testName string, line string) bool {
 			return strings.HasPrefix(line, "--- FAIL: "+testName+" ")
 		},
@@ -210,6 +335,20 @@ func formatFailed() testCaseFormatConfig {
 	}
 }
 
+func formatTeardownFailed() testCaseFormatConfig {
+	withColor := color.YellowString
+	return testCaseFormatConfig{
+		header: withColor("Passed with teardown error"),
+		prefix: withColor("TEARDOWN"),
+		filter: func(testName string, line string) bool {
+			return false
+		},
+		getter: func(execution executionSummary) []TestCase {
+			return execution.TeardownFailed()
+		},
+	}
+}
+
 func formatSkipped() testCaseFormatConfig {
 	withColor := color.YellowString
 	return testCaseFormatConfig{
@@ -229,3 +368,54 @@ func isFramingLine(line string) bool {
 		strings.HasPrefix(line, "=== PAUSE Test") ||
 		strings.HasPrefix(line, "=== CONT  Test")
 }
+
+// outputWithoutFraming returns the lines of output, without the framing lines
+// printed by go test, and without blank lines.
+func outputWithoutFraming(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if strings.TrimSpace(line) == "" || isTestFramingLine(line) {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t"))
+	}
+	return lines
+}
+
+// outputMessage returns the output without the framing lines, and without
+// the indentation of each line.
+func outputMessage(output string) string {
+	lines := outputWithoutFraming(output)
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+var logPrefixPattern = regexp.MustCompile(`^\s*[^\s:]+\.go:\d+: ?`)
+
+// SkipReason returns the message passed to t.Skip, from the output of a
+// skipped test. It returns an empty string when there is no message.
+func SkipReason(lines []string) string {
+	for _, line := range lines {
+		if isTestFramingLine(line) {
+			continue
+		}
+		if reason := strings.TrimSpace(logPrefixPattern.ReplaceAllString(line, "")); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// isTestFramingLine returns true if the line is one of the lines printed by
+// go test for the start or end of a test or subtest.
+func isTestFramingLine(line string) bool {
+	line = strings.TrimSpace(line)
+	for _, prefix := range framingPrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
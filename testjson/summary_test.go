@@ -5,6 +5,7 @@ import (
 	"io"
 	"strings"
 	"testing"
+	"text/template"
 	"time"
 
 	"github.com/jonboulle/clockwork"
@@ -328,3 +329,73 @@ func TestPrintSummary_WithRerunID(t *testing.T) {
 	PrintSummary(buf, exec, SummarizeAll)
 	golden.Assert(t, buf.String(), "summary-with-run-id.out")
 }
+
+func TestPrintSummary_WithTeardownFailure(t *testing.T) {
+	_, reset := patchClock()
+	defer reset()
+
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:                 bytes.NewReader(golden.Get(t, "go-test-json-teardown-failure.out")),
+		DemoteTeardownFailures: true,
+	})
+	assert.NilError(t, err)
+
+	buf := new(bytes.Buffer)
+	PrintSummary(buf, exec, SummarizeAll)
+	golden.Assert(t, buf.String(), "summary-teardown-failure.out")
+}
+
+func TestPrintSummary_WithExitedUnexpectedly(t *testing.T) {
+	_, reset := patchClock()
+	defer reset()
+
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout: bytes.NewReader(golden.Get(t, "go-test-json-exited-unexpectedly.out")),
+	})
+	assert.NilError(t, err)
+
+	buf := new(bytes.Buffer)
+	PrintSummary(buf, exec, SummarizeAll)
+	golden.Assert(t, buf.String(), "summary-exited-unexpectedly.out")
+}
+
+func TestPrintSummary_WithBuildFail(t *testing.T) {
+	_, reset := patchClock()
+	defer reset()
+
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout: bytes.NewReader(golden.Get(t, "go-test-json-build-fail.out")),
+	})
+	assert.NilError(t, err)
+
+	buf := new(bytes.Buffer)
+	PrintSummary(buf, exec, SummarizeAll)
+	golden.Assert(t, buf.String(), "summary-build-fail.out")
+}
+
+func TestPrintSummary_WithFailureLink(t *testing.T) {
+	in := `{"Action":"run","Package":"example.com/pkg","Test":"TestFails"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestFails","Output":"    pkg_test.go:10: broken\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFails","Elapsed":0.1}
+{"Action":"run","Package":"example.com/pkg","Test":"TestSkips"}
+{"Action":"skip","Package":"example.com/pkg","Test":"TestSkips","Elapsed":0}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.1}
+`
+	exec, err := ScanTestOutput(ScanConfig{Stdout: strings.NewReader(in)})
+	assert.NilError(t, err)
+
+	link := template.Must(template.New("link").Parse("https://ci.example.com/{{.Package}}/{{.Test}}"))
+	out := new(bytes.Buffer)
+	PrintSummaryWithOptions(out, exec, SummarizeSkipped|SummarizeFailed|SummarizeOutput,
+		FormatOptions{FailureLink: link})
+	expected := `
+=== Skipped
+=== SKIP: example.com/pkg TestSkips (0.00s)
+
+=== Failed
+=== FAIL: example.com/pkg TestFails (0.10s)
+    link: https://ci.example.com/example.com/pkg/TestFails
+    pkg_test.go:10: broken
+`
+	assert.Assert(t, strings.HasPrefix(out.String(), expected), out.String())
+}
@@ -0,0 +1,201 @@
+package testjson
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tapFormatter prints the results in the format of the Test Anything Protocol
+// version 13. See https://testanything.org/tap-version-13-specification.html.
+//
+// Each package is a test point, and the tests in the package are printed as a
+// nested subtest, indented by 4 spaces, once the package ends. Subtests of a
+// test are nested in the same way. The plan is printed when Flush is called,
+// so when the output is from more than one run of go test, for example with
+// --rerun-fails, each run is a separate TAP document.
+type tapFormatter struct {
+	out  *bufio.Writer
+	opts FormatOptions
+	// count is the number of packages printed since the last plan.
+	count int
+	// printed is the IDs of the test cases that have already been printed,
+	// for each package.
+	printed map[string]map[int]bool
+}
+
+func newTAPFormatter(out io.Writer, opts FormatOptions) EventFormatter {
+	return &tapFormatter{
+		out:     bufio.NewWriter(out),
+		opts:    opts,
+		printed: make(map[string]map[int]bool),
+	}
+}
+
+func (f *tapFormatter) Format(event TestEvent, exec *Execution) error {
+	if !event.PackageEvent() {
+		return nil
+	}
+	switch event.Action {
+	case ActionPass, ActionFail, ActionSkip:
+	default:
+		return nil
+	}
+	if f.count == 0 {
+		f.out.WriteString("TAP version 13\n")
+	}
+	f.count++
+	f.writePackage(event, exec.Package(event.Package))
+	return f.out.Flush()
+}
+
+// Flush prints the plan for the packages printed since the last call to Flush.
+func (f *tapFormatter) Flush() error {
+	if f.count == 0 {
+		f.out.WriteString("TAP version 13\n")
+	}
+	fmt.Fprintf(f.out, "1..%d\n", f.count)
+	f.count = 0
+	return f.out.Flush()
+}
+
+// tapNode is a test point in the TAP output.
+type tapNode struct {
+	tc       TestCase
+	action   Action
+	children []*tapNode
+}
+
+func (f *tapFormatter) writePackage(event TestEvent, pkg *Package) {
+	printed, ok := f.printed[event.Package]
+	if !ok {
+		printed = make(map[int]bool)
+		f.printed[event.Package] = printed
+	}
+
+	var roots []*tapNode
+	nodes := make(map[TestName]*tapNode)
+	for _, node := range newTAPNodes(pkg, printed) {
+		printed[node.tc.ID] = true
+		nodes[node.tc.Test] = node
+		if parent := nodes[tapParentName(node.tc.Test)]; parent != nil {
+			parent.children = append(parent.children, node)
+			continue
+		}
+		roots = append(roots, node)
+	}
+
+	name := RelativePackagePath(event.Package)
+	if len(roots) > 0 {
+		fmt.Fprintf(f.out, "# Subtest: %s\n", name)
+		f.writeNodes(pkg, roots, "    ")
+	}
+
+	switch {
+	case event.Action == ActionSkip:
+		fmt.Fprintf(f.out, "ok %d - %s # SKIP no test files\n", f.count, tapEscape(name))
+	case event.Action == ActionPass:
+		fmt.Fprintf(f.out, "ok %d - %s\n", f.count, tapEscape(name))
+	default:
+		fmt.Fprintf(f.out, "not ok %d - %s\n", f.count, tapEscape(name))
+		if !hasFailedNode(roots) {
+			f.writeDiagnostic("", pkg.Elapsed(), pkg.Output(0))
+		}
+	}
+}
+
+// newTAPNodes returns a node for each test case in pkg that has not been
+// printed, in the order the tests were started.
+func newTAPNodes(pkg *Package, printed map[int]bool) []*tapNode {
+	var nodes []*tapNode
+	add := func(tcs []TestCase, action Action) {
+		for _, tc := range tcs {
+			if !printed[tc.ID] {
+				nodes = append(nodes, &tapNode{tc: tc, action: action})
+			}
+		}
+	}
+	add(pkg.Passed, ActionPass)
+	add(pkg.Failed, ActionFail)
+	add(pkg.Skipped, ActionSkip)
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].tc.ID < nodes[j].tc.ID
+	})
+	return nodes
+}
+
+func tapParentName(name TestName) TestName {
+	i := strings.LastIndex(string(name), "/")
+	if i < 0 {
+		return ""
+	}
+	return name[:i]
+}
+
+func hasFailedNode(nodes []*tapNode) bool {
+	for _, node := range nodes {
+		if node.action == ActionFail {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *tapFormatter) writeNodes(pkg *Package, nodes []*tapNode, indent string) {
+	for i, node := range nodes {
+		if len(node.children) > 0 {
+			fmt.Fprintf(f.out, "%s# Subtest: %s\n", indent, node.tc.Test)
+			f.writeNodes(pkg, node.children, indent+"    ")
+		}
+		f.writeTestPoint(pkg, node, i+1, indent)
+	}
+	fmt.Fprintf(f.out, "%s1..%d\n", indent, len(nodes))
+}
+
+func (f *tapFormatter) writeTestPoint(pkg *Package, node *tapNode, num int, indent string) {
+	desc := tapEscape(node.tc.Test.Name())
+	switch node.action {
+	case ActionPass:
+		fmt.Fprintf(f.out, "%sok %d - %s\n", indent, num, desc)
+	case ActionSkip:
+		fmt.Fprintf(f.out, "%sok %d - %s # SKIP", indent, num, desc)
+		if reason := SkipReason(pkg.OutputLines(node.tc)); reason != "" {
+			fmt.Fprint(f.out, " "+tapEscape(reason))
+		}
+		fmt.Fprintln(f.out)
+	case ActionFail:
+		fmt.Fprintf(f.out, "%snot ok %d - %s", indent, num, desc)
+		if url, ok := f.opts.KnownIssues.Lookup(pkg.Fingerprint(node.tc)); ok {
+			fmt.Fprint(f.out, " # TODO known issue "+tapEscape(url))
+		}
+		fmt.Fprintln(f.out)
+		if len(node.children) == 0 || !hasFailedNode(node.children) {
+			f.writeDiagnostic(indent, node.tc.Elapsed, strings.Join(pkg.OutputLines(node.tc), ""))
+		}
+	}
+}
+
+// writeDiagnostic prints a YAML block with the elapsed time and the output of
+// a failed test point.
+func (f *tapFormatter) writeDiagnostic(indent string, elapsed time.Duration, output string) {
+	indent += "  "
+	fmt.Fprintf(f.out, "%s---\n", indent)
+	fmt.Fprintf(f.out, "%sduration_ms: %d\n", indent, elapsed.Milliseconds())
+	if lines := outputWithoutFraming(output); len(lines) > 0 {
+		fmt.Fprintf(f.out, "%smessage: |\n", indent)
+		for _, line := range lines {
+			fmt.Fprintf(f.out, "%s  %s\n", indent, line)
+		}
+	}
+	fmt.Fprintf(f.out, "%s...\n", indent)
+}
+
+// tapEscape escapes the characters which have a special meaning in the
+// description of a test point.
+func tapEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, "#", `\#`)
+}
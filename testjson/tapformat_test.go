@@ -0,0 +1,65 @@
+package testjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestScanTestOutput_WithTAPFormat(t *testing.T) {
+	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
+
+	shim := newFakeHandler(nil, "go-test-json")
+	shim.formatter = newTAPFormatter(shim.out, FormatOptions{})
+	exec, err := ScanTestOutput(shim.Config(t))
+	assert.NilError(t, err)
+	assert.NilError(t, shim.formatter.(Flusher).Flush())
+
+	golden.Assert(t, shim.out.String(), "tap-format.out")
+	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
+}
+
+func TestTAPFormat_Directives(t *testing.T) {
+	out := new(bytes.Buffer)
+	formatter := newTAPFormatter(out, FormatOptions{})
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestSkip"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestSkip","Output":"=== RUN   TestSkip\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestSkip","Output":"    skip_test.go:12: needs #docker\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestSkip","Output":"--- SKIP: TestSkip (0.00s)\n"}
+{"Action":"skip","Package":"example.com/pkg","Test":"TestSkip","Elapsed":0}
+{"Action":"run","Package":"example.com/pkg","Test":"TestKnown"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestKnown","Output":"    known_test.go:9: timeout\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestKnown","Elapsed":1.5}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":1.6}
+`),
+		Handler: &fakeHandler{formatter: formatter, out: out, err: new(bytes.Buffer)},
+	})
+	assert.NilError(t, err)
+
+	out.Reset()
+	fingerprint := exec.Fingerprint(exec.Package("example.com/pkg").Failed[0])
+	formatter = newTAPFormatter(out, FormatOptions{
+		KnownIssues: NewKnownIssues(map[string]string{fingerprint: "https://example.com/issue/1"}),
+	})
+	assert.NilError(t, formatter.Format(TestEvent{Package: "example.com/pkg", Action: ActionFail}, exec))
+	assert.NilError(t, formatter.(Flusher).Flush())
+
+	expected := `TAP version 13
+# Subtest: example.com/pkg
+    ok 1 - TestSkip # SKIP needs \#docker
+    not ok 2 - TestKnown # TODO known issue https://example.com/issue/1
+      ---
+      duration_ms: 1500
+      message: |
+            known_test.go:9: timeout
+      ...
+    1..2
+not ok 1 - example.com/pkg
+1..1
+`
+	assert.Equal(t, out.String(), expected)
+}
@@ -0,0 +1,185 @@
+package testjson
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// teamCityFormatter prints TeamCity service messages, so that TeamCity reports
+// each test as it runs. See
+// https://www.jetbrains.com/help/teamcity/service-messages.html#Reporting+Tests.
+//
+// Each package is a test suite. The messages of each package, and of each test,
+// use a different flowId, because packages and parallel tests run at the same
+// time, and TeamCity requires the tests in a flow to run one at a time.
+type teamCityFormatter struct {
+	out  io.Writer
+	opts FormatOptions
+	// suites is the set of packages which have a started test suite.
+	suites map[string]bool
+	// running is the output of each started test.
+	running map[testOutputKey]*strings.Builder
+}
+
+func newTeamCityFormatter(out io.Writer, opts FormatOptions) EventFormatter {
+	return &teamCityFormatter{
+		out:     out,
+		opts:    opts,
+		suites:  make(map[string]bool),
+		running: make(map[testOutputKey]*strings.Builder),
+	}
+}
+
+func (f *teamCityFormatter) Format(event TestEvent, exec *Execution) error {
+	// Tests which never finished were already reported as failed when their
+	// package ended, before the Execution sends a fail event for them.
+	if event.Elapsed == float64(neverFinished) && !f.suites[event.Package] {
+		return nil
+	}
+
+	buf := new(strings.Builder)
+	if !f.suites[event.Package] {
+		f.suites[event.Package] = true
+		teamCityMessage(buf, "testSuiteStarted", "name", event.Package, "flowId", event.Package)
+	}
+
+	switch {
+	case event.PackageEvent():
+		if event.Action.IsTerminal() {
+			f.endSuite(buf, event, exec.Package(event.Package))
+		}
+	case event.Action == ActionRun:
+		f.startTest(buf, event.Package, event.Test)
+	case event.Action == ActionOutput:
+		f.testOutput(buf, event.Package, event.Test).WriteString(event.Output)
+	case event.Action.IsTerminal():
+		f.endTest(buf, event, exec.Package(event.Package))
+	}
+	_, err := io.WriteString(f.out, buf.String())
+	return err
+}
+
+func (f *teamCityFormatter) startTest(buf *strings.Builder, pkg string, test string) {
+	key := testOutputKey{pkg: pkg, test: test}
+	f.running[key] = new(strings.Builder)
+	teamCityMessage(buf, "testStarted",
+		"name", test, "flowId", teamCityTestFlowID(pkg, test), "captureStandardOutput", "false")
+}
+
+// testOutput returns the output of a started test. A test is started when the
+// first event for the test is not a run event, which happens when the output
+// of go test is missing events.
+func (f *teamCityFormatter) testOutput(buf *strings.Builder, pkg string, test string) *strings.Builder {
+	output, ok := f.running[testOutputKey{pkg: pkg, test: test}]
+	if !ok {
+		f.startTest(buf, pkg, test)
+		output = f.running[testOutputKey{pkg: pkg, test: test}]
+	}
+	return output
+}
+
+func (f *teamCityFormatter) endTest(buf *strings.Builder, event TestEvent, pkg *Package) {
+	output := f.testOutput(buf, event.Package, event.Test).String()
+	delete(f.running, testOutputKey{pkg: event.Package, test: event.Test})
+
+	flowID := teamCityTestFlowID(event.Package, event.Test)
+	message := outputMessage(output)
+	switch event.Action {
+	case ActionPass:
+		if message != "" {
+			teamCityMessage(buf, "testStdOut", "name", event.Test, "flowId", flowID, "out", message)
+		}
+	case ActionSkip:
+		teamCityMessage(buf, "testIgnored", "name", event.Test, "flowId", flowID,
+			"message", SkipReason(strings.SplitAfter(output, "\n")))
+	case ActionFail:
+		tc := pkg.LastFailedByName(event.Test)
+		summary := "Test failed"
+		if tc.hasSubTestFailed {
+			summary = "Subtest failed"
+		}
+		if url, ok := f.opts.KnownIssues.Lookup(pkg.Fingerprint(tc)); ok {
+			summary += ", known issue " + url
+		}
+		teamCityMessage(buf, "testFailed", "name", event.Test, "flowId", flowID,
+			"message", summary, "details", message)
+	}
+
+	duration := elapsedDuration(event.Elapsed)
+	switch {
+	case event.Elapsed == float64(neverFinished):
+		teamCityMessage(buf, "testFinished", "name", event.Test, "flowId", flowID)
+		return
+	case f.opts.Deterministic:
+		duration = 0
+	}
+	teamCityMessage(buf, "testFinished", "name", event.Test, "flowId", flowID,
+		"duration", fmt.Sprint(duration.Milliseconds()))
+}
+
+// endSuite ends the test suite of a package. Tests which were still running
+// when the package ended, for example because of a panic or a timeout, are
+// reported as failed. A package that failed without a failed test, for
+// example because of a build error, is reported as a build problem.
+func (f *teamCityFormatter) endSuite(buf *strings.Builder, event TestEvent, pkg *Package) {
+	var running []string
+	for key := range f.running {
+		if key.pkg == event.Package {
+			running = append(running, key.test)
+		}
+	}
+	sort.Strings(running)
+	for _, test := range running {
+		key := testOutputKey{pkg: event.Package, test: test}
+		flowID := teamCityTestFlowID(event.Package, test)
+		teamCityMessage(buf, "testFailed", "name", test, "flowId", flowID,
+			"message", "Test did not finish", "details", outputMessage(f.running[key].String()))
+		teamCityMessage(buf, "testFinished", "name", test, "flowId", flowID)
+		delete(f.running, key)
+	}
+
+	if event.Action == ActionFail && len(pkg.Failed) == 0 && len(running) == 0 {
+		description := event.Package + " failed"
+		teamCityMessage(buf, "message", "text", description, "flowId", event.Package,
+			"errorDetails", outputMessage(pkg.Output(0)), "status", "ERROR")
+		teamCityMessage(buf, "buildProblem", "description", description)
+	}
+
+	delete(f.suites, event.Package)
+	teamCityMessage(buf, "testSuiteFinished", "name", event.Package, "flowId", event.Package)
+}
+
+func teamCityTestFlowID(pkg string, test string) string {
+	return pkg + "." + test
+}
+
+// teamCityMessage writes a service message with the attributes, which are
+// pairs of names and values.
+func teamCityMessage(out io.Writer, name string, attrs ...string) {
+	var b strings.Builder
+	b.WriteString("##teamcity[" + name)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		fmt.Fprintf(&b, " %s='%s'", attrs[i], teamCityEscape(attrs[i+1]))
+	}
+	b.WriteString("]\n")
+	_, _ = io.WriteString(out, b.String())
+}
+
+var teamCityReplacer = strings.NewReplacer(
+	"|", "||",
+	"'", "|'",
+	"\n", "|n",
+	"\r", "|r",
+	"[", "|[",
+	"]", "|]",
+	"\u0085", "|x",
+	"\u2028", "|l",
+	"\u2029", "|p",
+)
+
+// teamCityEscape escapes the value of an attribute of a service message.
+func teamCityEscape(s string) string {
+	return teamCityReplacer.Replace(s)
+}
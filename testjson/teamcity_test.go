@@ -0,0 +1,48 @@
+package testjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestScanTestOutput_WithTeamCityFormat(t *testing.T) {
+	shim := newFakeHandler(nil, "go-test-json")
+	shim.formatter = newTeamCityFormatter(shim.out, FormatOptions{})
+	exec, err := ScanTestOutput(shim.Config(t))
+	assert.NilError(t, err)
+
+	golden.Assert(t, shim.out.String(), "teamcity-format.out")
+	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
+}
+
+func TestTeamCityFormat_PackageFailures(t *testing.T) {
+	input := `{"Action":"output","Package":"example.com/build","Output":"# example.com/build\n"}
+{"Action":"output","Package":"example.com/build","Output":"./a.go:3:1: syntax error\n"}
+{"Action":"fail","Package":"example.com/build","Elapsed":0}
+{"Action":"run","Package":"example.com/panic","Test":"TestPanic"}
+{"Action":"output","Package":"example.com/panic","Test":"TestPanic","Output":"panic: it's [broken]\n"}
+{"Action":"fail","Package":"example.com/panic","Elapsed":0.5}
+`
+	out := new(bytes.Buffer)
+	_, err := ScanTestOutput(ScanConfig{
+		Stdout:  strings.NewReader(input),
+		Handler: &fakeHandler{formatter: newTeamCityFormatter(out, FormatOptions{}), err: new(bytes.Buffer)},
+	})
+	assert.NilError(t, err)
+
+	expected := `##teamcity[testSuiteStarted name='example.com/build' flowId='example.com/build']
+##teamcity[message text='example.com/build failed' flowId='example.com/build' errorDetails='# example.com/build|n./a.go:3:1: syntax error' status='ERROR']
+##teamcity[buildProblem description='example.com/build failed']
+##teamcity[testSuiteFinished name='example.com/build' flowId='example.com/build']
+##teamcity[testSuiteStarted name='example.com/panic' flowId='example.com/panic']
+##teamcity[testStarted name='TestPanic' flowId='example.com/panic.TestPanic' captureStandardOutput='false']
+##teamcity[testFailed name='TestPanic' flowId='example.com/panic.TestPanic' message='Test did not finish' details='panic: it|'s |[broken|]']
+##teamcity[testFinished name='TestPanic' flowId='example.com/panic.TestPanic']
+##teamcity[testSuiteFinished name='example.com/panic' flowId='example.com/panic']
+`
+	assert.Equal(t, out.String(), expected)
+}
@@ -0,0 +1,98 @@
+package testjson
+
+import (
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// TemplateData is the value used to execute FormatOptions.Template for each
+// event in the template format.
+type TemplateData struct {
+	// Event is the event being formatted.
+	Event TestEvent
+	// Execution is the state of the run, including the event.
+	Execution *Execution
+	// Package is the state of the package of the event.
+	Package *Package
+	// RelativePackage is the package of the event, relative to the module. See
+	// RelativePackagePath.
+	RelativePackage string
+	// Elapsed is the elapsed time of the event, formatted with the
+	// DurationFormat of the options. It is empty when the event has no elapsed
+	// time.
+	Elapsed string
+	// Link is the link from FormatOptions.FailureLink, for an event of a
+	// failed test.
+	Link string
+}
+
+// TemplateFuncs returns the functions which may be used by the template of the
+// template format, in addition to the functions of text/template. The template
+// must be parsed with these functions.
+//
+// The color function prints text in a color: red, green, yellow, blue,
+// magenta, cyan, or white. The colorAction function prints text in the color
+// used by the other formats for an Action.
+//
+//	{{ color "cyan" .RelativePackage }}
+//	{{ colorAction .Event.Action .Event.Test }}
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"color": func(name string, text string) string {
+			if attr, ok := templateColors[name]; ok {
+				return color.New(attr).Sprint(text)
+			}
+			return text
+		},
+		"colorAction": func(action Action, text string) string {
+			return colorEvent(TestEvent{Action: action})("%s", text)
+		},
+	}
+}
+
+var templateColors = map[string]color.Attribute{
+	"red":     color.FgRed,
+	"green":   color.FgGreen,
+	"yellow":  color.FgYellow,
+	"blue":    color.FgBlue,
+	"magenta": color.FgMagenta,
+	"cyan":    color.FgCyan,
+	"white":   color.FgWhite,
+}
+
+// templateFormat executes FormatOptions.Template for each event. The output of
+// the template is printed when it contains more than whitespace, so that a
+// template can use a separate line for each action, and print nothing for the
+// events it does not handle.
+func templateFormat(opts FormatOptions) func(event TestEvent, exec *Execution) (string, error) {
+	return func(event TestEvent, exec *Execution) (string, error) {
+		if opts.Template == nil {
+			return "", nil
+		}
+		data := TemplateData{
+			Event:           event,
+			Execution:       exec,
+			Package:         exec.Package(event.Package),
+			RelativePackage: RelativePackagePath(event.Package),
+		}
+		if event.Action.IsTerminal() {
+			data.Elapsed = strings.TrimLeft(
+				opts.formatDuration(time.Duration(event.Elapsed*float64(time.Second)), 2, DurationSeconds), " ")
+		}
+		if event.Action == ActionFail && !event.PackageEvent() {
+			data.Link = opts.Link(data.Package.LastFailedByName(event.Test))
+		}
+
+		buf := new(strings.Builder)
+		if err := opts.Template.Execute(buf, data); err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(buf.String()) == "" {
+			return "", nil
+		}
+		return buf.String(), nil
+	}
+}
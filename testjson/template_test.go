@@ -0,0 +1,44 @@
+package testjson
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestTemplateFormat(t *testing.T) {
+	events := []TestEvent{
+		{Package: "example.com/pkg", Action: ActionRun, Test: "TestPass"},
+		{Package: "example.com/pkg", Action: ActionOutput, Test: "TestPass", Output: "--- PASS: TestPass\n"},
+		{Package: "example.com/pkg", Action: ActionPass, Test: "TestPass", Elapsed: 0.5},
+		{Package: "example.com/pkg", Action: ActionRun, Test: "TestFail"},
+		{Package: "example.com/pkg", Action: ActionFail, Test: "TestFail", Elapsed: 1.25},
+		{Package: "example.com/pkg", Action: ActionOutput, Output: "coverage: 50.0% of statements\n"},
+		{Package: "example.com/pkg", Action: ActionFail, Elapsed: 2},
+	}
+	tmpl := template.Must(template.New("test").Funcs(TemplateFuncs()).Parse(`
+{{- if .Event.Action.IsTerminal -}}
+{{ colorAction .Event.Action (print .Event.Action) }} {{ .RelativePackage }}
+{{- with .Event.Test }} {{ . }}{{ end }} ({{ .Elapsed }})
+{{- if .Event.PackageEvent }} {{ .Package.Coverage }}{{ end }}
+{{- with .Link }} {{ . }}{{ end }}
+{{ end -}}
+`))
+	link := template.Must(template.New("link").Parse(`https://ci.example.com/{{ .Test }}`))
+
+	buf := new(bytes.Buffer)
+	exec := newExecution()
+	formatter := NewEventFormatterWithOptions(buf, "template", FormatOptions{Template: tmpl, FailureLink: link})
+	for _, event := range events {
+		exec.add(event)
+		assert.NilError(t, formatter.Format(event, exec))
+	}
+
+	expected := `pass example.com/pkg TestPass (0.50s)
+fail example.com/pkg TestFail (1.25s) https://ci.example.com/TestFail
+fail example.com/pkg (2.00s) coverage: 50.0% of statements
+`
+	assert.Equal(t, buf.String(), expected)
+}
@@ -0,0 +1,98 @@
+package testjson
+
+import (
+	"sort"
+	"strings"
+)
+
+// testOutputBlocks buffers the output of each test until the test ends, so that
+// the output of tests which run in parallel is printed as one contiguous block
+// for each test, instead of interleaved. The output of a subtest is added to
+// the block of its parent when the parent is still running, so that a root
+// test and all of its subtests are printed together.
+type testOutputBlocks struct {
+	running map[testOutputKey]*strings.Builder
+}
+
+// newTestOutputBlocks returns a testOutputBlocks when opts.BufferTestOutput is
+// set, otherwise it returns nil. A nil testOutputBlocks does not buffer any
+// output.
+func newTestOutputBlocks(opts FormatOptions) *testOutputBlocks {
+	if !opts.BufferTestOutput {
+		return nil
+	}
+	return &testOutputBlocks{running: make(map[testOutputKey]*strings.Builder)}
+}
+
+// next returns the output to print for the event. The output of a test is
+// returned when the test ends. The output of tests which were still running
+// when the package ended, for example because of a panic or timeout, is
+// returned, sorted by test name, when the package ends.
+func (b *testOutputBlocks) next(event TestEvent) string {
+	var output string
+	if event.Action == ActionOutput {
+		output = event.Output
+	}
+	if b == nil {
+		return output
+	}
+
+	if event.PackageEvent() {
+		if event.Action.IsTerminal() {
+			return b.endPackage(event.Package) + output
+		}
+		return output
+	}
+
+	key := testOutputKey{pkg: event.Package, test: event.Test}
+	switch {
+	case event.Action == ActionRun:
+		b.block(key)
+	case event.Action == ActionOutput:
+		b.block(key).WriteString(output)
+	case event.Action.IsTerminal():
+		block, ok := b.running[key]
+		if !ok {
+			return ""
+		}
+		delete(b.running, key)
+		if i := strings.LastIndex(event.Test, "/"); i > 0 {
+			parent, ok := b.running[testOutputKey{pkg: event.Package, test: event.Test[:i]}]
+			if ok {
+				parent.WriteString(block.String())
+				return ""
+			}
+		}
+		return block.String()
+	}
+	return ""
+}
+
+// block returns the buffered output of the test, starting a new block if the
+// test was not already running.
+func (b *testOutputBlocks) block(key testOutputKey) *strings.Builder {
+	block, ok := b.running[key]
+	if !ok {
+		block = new(strings.Builder)
+		b.running[key] = block
+	}
+	return block
+}
+
+func (b *testOutputBlocks) endPackage(pkg string) string {
+	var tests []string
+	for key := range b.running {
+		if key.pkg == pkg {
+			tests = append(tests, key.test)
+		}
+	}
+	sort.Strings(tests)
+
+	var output strings.Builder
+	for _, test := range tests {
+		key := testOutputKey{pkg: pkg, test: test}
+		output.WriteString(b.running[key].String())
+		delete(b.running, key)
+	}
+	return output.String()
+}
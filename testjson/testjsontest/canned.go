@@ -0,0 +1,49 @@
+package testjsontest
+
+// MixedStdout is a test2json stream of a run of four packages, with passed,
+// failed, and skipped tests, subtests, a package without tests, and a package
+// which failed to build. MixedStderr is the stderr of the same run.
+const MixedStdout = `{"Time":"2021-06-01T10:00:00.000Z","Action":"start","Package":"example.com/pkg/one"}
+{"Time":"2021-06-01T10:00:00.010Z","Action":"run","Package":"example.com/pkg/one","Test":"TestPassed"}
+{"Time":"2021-06-01T10:00:00.010Z","Action":"output","Package":"example.com/pkg/one","Test":"TestPassed","Output":"=== RUN   TestPassed\n"}
+{"Time":"2021-06-01T10:00:00.020Z","Action":"output","Package":"example.com/pkg/one","Test":"TestPassed","Output":"--- PASS: TestPassed (0.01s)\n"}
+{"Time":"2021-06-01T10:00:00.020Z","Action":"pass","Package":"example.com/pkg/one","Test":"TestPassed","Elapsed":0.01}
+{"Time":"2021-06-01T10:00:00.020Z","Action":"run","Package":"example.com/pkg/one","Test":"TestSkipped"}
+{"Time":"2021-06-01T10:00:00.020Z","Action":"output","Package":"example.com/pkg/one","Test":"TestSkipped","Output":"=== RUN   TestSkipped\n"}
+{"Time":"2021-06-01T10:00:00.020Z","Action":"output","Package":"example.com/pkg/one","Test":"TestSkipped","Output":"    one_test.go:20: requires a database\n"}
+{"Time":"2021-06-01T10:00:00.020Z","Action":"output","Package":"example.com/pkg/one","Test":"TestSkipped","Output":"--- SKIP: TestSkipped (0.00s)\n"}
+{"Time":"2021-06-01T10:00:00.020Z","Action":"skip","Package":"example.com/pkg/one","Test":"TestSkipped","Elapsed":0}
+{"Time":"2021-06-01T10:00:00.020Z","Action":"run","Package":"example.com/pkg/one","Test":"TestFailed"}
+{"Time":"2021-06-01T10:00:00.020Z","Action":"output","Package":"example.com/pkg/one","Test":"TestFailed","Output":"=== RUN   TestFailed\n"}
+{"Time":"2021-06-01T10:00:00.020Z","Action":"run","Package":"example.com/pkg/one","Test":"TestFailed/sub_passed"}
+{"Time":"2021-06-01T10:00:00.020Z","Action":"output","Package":"example.com/pkg/one","Test":"TestFailed/sub_passed","Output":"=== RUN   TestFailed/sub_passed\n"}
+{"Time":"2021-06-01T10:00:00.030Z","Action":"run","Package":"example.com/pkg/one","Test":"TestFailed/sub_failed"}
+{"Time":"2021-06-01T10:00:00.030Z","Action":"output","Package":"example.com/pkg/one","Test":"TestFailed/sub_failed","Output":"=== RUN   TestFailed/sub_failed\n"}
+{"Time":"2021-06-01T10:00:00.040Z","Action":"output","Package":"example.com/pkg/one","Test":"TestFailed/sub_failed","Output":"    one_test.go:42: expected 2, got 3\n"}
+{"Time":"2021-06-01T10:00:00.040Z","Action":"output","Package":"example.com/pkg/one","Test":"TestFailed","Output":"--- FAIL: TestFailed (0.02s)\n"}
+{"Time":"2021-06-01T10:00:00.040Z","Action":"output","Package":"example.com/pkg/one","Test":"TestFailed/sub_passed","Output":"    --- PASS: TestFailed/sub_passed (0.01s)\n"}
+{"Time":"2021-06-01T10:00:00.040Z","Action":"pass","Package":"example.com/pkg/one","Test":"TestFailed/sub_passed","Elapsed":0.01}
+{"Time":"2021-06-01T10:00:00.040Z","Action":"output","Package":"example.com/pkg/one","Test":"TestFailed/sub_failed","Output":"    --- FAIL: TestFailed/sub_failed (0.01s)\n"}
+{"Time":"2021-06-01T10:00:00.040Z","Action":"fail","Package":"example.com/pkg/one","Test":"TestFailed/sub_failed","Elapsed":0.01}
+{"Time":"2021-06-01T10:00:00.040Z","Action":"fail","Package":"example.com/pkg/one","Test":"TestFailed","Elapsed":0.02}
+{"Time":"2021-06-01T10:00:00.040Z","Action":"output","Package":"example.com/pkg/one","Output":"FAIL\n"}
+{"Time":"2021-06-01T10:00:00.050Z","Action":"output","Package":"example.com/pkg/one","Output":"FAIL\texample.com/pkg/one\t0.050s\n"}
+{"Time":"2021-06-01T10:00:00.050Z","Action":"fail","Package":"example.com/pkg/one","Elapsed":0.05}
+{"Time":"2021-06-01T10:00:00.100Z","Action":"start","Package":"example.com/pkg/two"}
+{"Time":"2021-06-01T10:00:00.110Z","Action":"run","Package":"example.com/pkg/two","Test":"TestPassed"}
+{"Time":"2021-06-01T10:00:00.110Z","Action":"output","Package":"example.com/pkg/two","Test":"TestPassed","Output":"=== RUN   TestPassed\n"}
+{"Time":"2021-06-01T10:00:00.210Z","Action":"output","Package":"example.com/pkg/two","Test":"TestPassed","Output":"--- PASS: TestPassed (0.10s)\n"}
+{"Time":"2021-06-01T10:00:00.210Z","Action":"pass","Package":"example.com/pkg/two","Test":"TestPassed","Elapsed":0.1}
+{"Time":"2021-06-01T10:00:00.210Z","Action":"output","Package":"example.com/pkg/two","Output":"PASS\n"}
+{"Time":"2021-06-01T10:00:00.220Z","Action":"output","Package":"example.com/pkg/two","Output":"ok  \texample.com/pkg/two\t0.120s\n"}
+{"Time":"2021-06-01T10:00:00.220Z","Action":"pass","Package":"example.com/pkg/two","Elapsed":0.12}
+{"Time":"2021-06-01T10:00:00.230Z","Action":"output","Package":"example.com/pkg/empty","Output":"?   \texample.com/pkg/empty\t[no test files]\n"}
+{"Time":"2021-06-01T10:00:00.230Z","Action":"skip","Package":"example.com/pkg/empty","Elapsed":0}
+{"Time":"2021-06-01T10:00:00.240Z","Action":"output","Package":"example.com/pkg/broken","Output":"FAIL\texample.com/pkg/broken [build failed]\n"}
+{"Time":"2021-06-01T10:00:00.240Z","Action":"fail","Package":"example.com/pkg/broken","Elapsed":0}
+`
+
+// MixedStderr is the stderr of the run of MixedStdout.
+const MixedStderr = `# example.com/pkg/broken
+broken/broken.go:5:2: undefined: missing
+`
@@ -0,0 +1,127 @@
+/*Package testjsontest provides utilities for testing formats and handlers
+which use the testjson package.
+
+Format sends each event from a test2json stream to a formatter, and returns
+what the formatter printed, so that a custom format can be compared to a golden
+file, the same way the formats of gotestsum are tested.
+
+    func TestMyFormat(t *testing.T) {
+        result := testjsontest.Format(t,
+            strings.NewReader(testjsontest.MixedStdout),
+            strings.NewReader(testjsontest.MixedStderr),
+            newMyFormatter)
+        golden.Assert(t, result.Stdout, "my-format.out")
+    }
+*/
+package testjsontest // import "gotest.tools/gotestsum/testjson/testjsontest"
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// TestingT is the subset of testing.TB used by this package.
+type TestingT interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+}
+
+// Result is the output of a formatter from Format.
+type Result struct {
+	// Stdout is the output of the formatter.
+	Stdout string
+	// Stderr is the lines of stderr from the stream, which are not sent to the
+	// formatter.
+	Stderr string
+	// Execution built from the stream.
+	Execution *testjson.Execution
+}
+
+// Format scans the test2json stream from stdout, and the output of 'go test'
+// from stderr, and sends each event to the formatter created by newFormatter.
+// stderr may be nil. The formatter is flushed at the end of the stream when it
+// implements testjson.Flusher.
+//
+// The Execution uses a testjson.EventClock, so that the elapsed time of the
+// run, and the summary, are the same on every run when the events in the
+// stream have a Time.
+func Format(
+	t TestingT,
+	stdout io.Reader,
+	stderr io.Reader,
+	newFormatter func(out io.Writer) testjson.EventFormatter,
+) Result {
+	t.Helper()
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	formatter := newFormatter(out)
+	if formatter == nil {
+		t.Fatalf("formatter must be non-nil")
+		return Result{}
+	}
+	handler := testjson.NewFormatHandler(formatter, errOut)
+
+	options := []testjson.ScanOption{
+		testjson.WithHandler(handler),
+		testjson.WithClock(testjson.NewEventClock()),
+		testjson.WithStderrMerge(testjson.StderrMergeSeparate),
+	}
+	if stderr != nil {
+		options = append(options, testjson.WithStderr(stderr))
+	}
+	exec, err := testjson.ScanTestOutput(testjson.NewScanConfig(stdout, options...))
+	if err != nil {
+		t.Fatalf("failed to scan test output: %v", err)
+		return Result{}
+	}
+	if flusher, ok := handler.(testjson.Flusher); ok {
+		if err := flusher.Flush(); err != nil {
+			t.Fatalf("failed to flush formatter: %v", err)
+			return Result{}
+		}
+	}
+	return Result{Stdout: out.String(), Stderr: errOut.String(), Execution: exec}
+}
+
+// Builtin returns a function for Format which creates one of the formats
+// listed by testjson.Formats.
+func Builtin(format string, opts testjson.FormatOptions) func(out io.Writer) testjson.EventFormatter {
+	return func(out io.Writer) testjson.EventFormatter {
+		return testjson.NewEventFormatterWithOptions(out, format, opts)
+	}
+}
+
+// Recorder is a testjson.EventHandler which records every event, and every
+// line of stderr, so that a test can check the events a handler receives.
+type Recorder struct {
+	Events []testjson.TestEvent
+	Stderr []string
+}
+
+// Event records the event.
+func (r *Recorder) Event(event testjson.TestEvent, _ *testjson.Execution) error {
+	r.Events = append(r.Events, event)
+	return nil
+}
+
+// Err records the line of stderr.
+func (r *Recorder) Err(text string) error {
+	r.Stderr = append(r.Stderr, text)
+	return nil
+}
+
+// Stream returns the events as a test2json stream, with one JSON object on each
+// line, which may be the stdout of Format, or of testjson.ScanTestOutput.
+func Stream(events ...testjson.TestEvent) io.Reader {
+	buf := new(strings.Builder)
+	enc := json.NewEncoder(buf)
+	for _, event := range events {
+		// Encode only writes to buf, so it only fails for values which can not
+		// be encoded, which a TestEvent never has.
+		_ = enc.Encode(event)
+	}
+	return strings.NewReader(buf.String())
+}
@@ -0,0 +1,52 @@
+package testjsontest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestFormat_Builtin(t *testing.T) {
+	for _, format := range []string{"testname", "pkgname", "standard-verbose"} {
+		t.Run(format, func(t *testing.T) {
+			result := Format(t,
+				strings.NewReader(MixedStdout),
+				strings.NewReader(MixedStderr),
+				Builtin(format, testjson.FormatOptions{}))
+			golden.Assert(t, result.Stdout, "mixed-"+format+".out")
+			assert.Equal(t, result.Stderr, MixedStderr)
+			assert.Equal(t, result.Execution.Total(), 6)
+			assert.Equal(t, result.Execution.Elapsed(), 240*time.Millisecond)
+		})
+	}
+}
+
+func TestFormat_Summary(t *testing.T) {
+	result := Format(t, strings.NewReader(MixedStdout), strings.NewReader(MixedStderr),
+		Builtin("dots-v1", testjson.FormatOptions{}))
+	out := new(strings.Builder)
+	testjson.PrintSummaryWithOptions(out, result.Execution, testjson.SummarizeAll,
+		testjson.FormatOptions{})
+	golden.Assert(t, out.String(), "mixed-summary.out")
+}
+
+func TestRecorder(t *testing.T) {
+	recorder := &Recorder{}
+	stream := Stream(
+		testjson.TestEvent{Action: testjson.ActionRun, Package: "example.com/pkg", Test: "TestOne"},
+		testjson.TestEvent{Action: testjson.ActionPass, Package: "example.com/pkg", Test: "TestOne", Elapsed: 0.5},
+		testjson.TestEvent{Action: testjson.ActionPass, Package: "example.com/pkg", Elapsed: 1})
+	exec, err := testjson.ScanTestOutput(testjson.NewScanConfig(stream,
+		testjson.WithHandler(recorder)))
+	assert.NilError(t, err)
+
+	assert.Equal(t, exec.Total(), 1)
+	assert.Equal(t, len(recorder.Events), 3)
+	assert.Equal(t, recorder.Events[1].Action, testjson.ActionPass)
+	assert.Equal(t, recorder.Events[1].Elapsed, 0.5)
+	assert.Equal(t, len(recorder.Stderr), 0)
+}
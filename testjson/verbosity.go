@@ -0,0 +1,122 @@
+package testjson
+
+import "strings"
+
+// Verbosity levels for FormatOptions.Verbosity. Each format decides what to
+// print at each level. Formats which do not support a level print the same
+// output as the closest level they do support.
+const (
+	// VerbosityQuiet prints only failures.
+	VerbosityQuiet = -1
+	// VerbosityDefault is the output of the format without any flags.
+	VerbosityDefault = 0
+	// VerbosityVerbose adds more detail, like the skipped tests.
+	VerbosityVerbose = 1
+	// VerbosityVeryVerbose adds the output of the tests that passed.
+	VerbosityVeryVerbose = 2
+)
+
+// testOutputBuffer collects the output of each test as it is received. The
+// output of a passed test is removed from the Execution when the test ends,
+// before the event is formatted, so formats which print the output of passed
+// tests must keep a copy. A nil testOutputBuffer ignores all output.
+type testOutputBuffer struct {
+	output map[testOutputKey]*strings.Builder
+}
+
+type testOutputKey struct {
+	pkg  string
+	test string
+}
+
+// newTestOutputBuffer returns a testOutputBuffer when the output of passed
+// tests is printed with opts, otherwise it returns nil.
+func newTestOutputBuffer(opts FormatOptions) *testOutputBuffer {
+	if !opts.showPassedOutput() {
+		return nil
+	}
+	return &testOutputBuffer{output: make(map[testOutputKey]*strings.Builder)}
+}
+
+// add the output of a test event to the buffer.
+func (b *testOutputBuffer) add(event TestEvent) {
+	if b == nil || event.PackageEvent() || event.Action != ActionOutput {
+		return
+	}
+	key := testOutputKey{pkg: event.Package, test: event.Test}
+	buf, ok := b.output[key]
+	if !ok {
+		buf = new(strings.Builder)
+		b.output[key] = buf
+	}
+	buf.WriteString(event.Output)
+}
+
+// take returns the output of the test from event, and removes it from the
+// buffer.
+func (b *testOutputBuffer) take(event TestEvent) string {
+	if b == nil {
+		return ""
+	}
+	key := testOutputKey{pkg: event.Package, test: event.Test}
+	buf, ok := b.output[key]
+	if !ok {
+		return ""
+	}
+	delete(b.output, key)
+	return buf.String()
+}
+
+// ShowOutput selects the results of the tests whose output is printed by the
+// formats which print test output.
+type ShowOutput int
+
+const (
+	// ShowOutputFail prints the output of failed tests.
+	ShowOutputFail ShowOutput = iota
+	// ShowOutputPass prints the output of passed tests.
+	ShowOutputPass
+	// ShowOutputAll prints the output of passed and failed tests.
+	ShowOutputAll
+	// ShowOutputNone does not print the output of any test.
+	ShowOutputNone
+)
+
+var showOutputValues = map[string]ShowOutput{
+	"fail": ShowOutputFail,
+	"pass": ShowOutputPass,
+	"all":  ShowOutputAll,
+	"none": ShowOutputNone,
+}
+
+// NewShowOutput returns a ShowOutput from a string value. If the string does
+// not match any known values returns false for the second value.
+func NewShowOutput(value string) (ShowOutput, bool) {
+	s, ok := showOutputValues[value]
+	return s, ok
+}
+
+func (s ShowOutput) String() string {
+	for name, value := range showOutputValues {
+		if value == s {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// Passed returns true if the output of passed tests is shown.
+func (s ShowOutput) Passed() bool {
+	return s == ShowOutputPass || s == ShowOutputAll
+}
+
+// Failed returns true if the output of failed tests is shown.
+func (s ShowOutput) Failed() bool {
+	return s == ShowOutputFail || s == ShowOutputAll
+}
+
+// showPassedOutput returns true if the output of passed tests is printed,
+// either because of the verbosity level, or because of ShowOutput.
+func (o FormatOptions) showPassedOutput() bool {
+	return o.Verbosity >= VerbosityVeryVerbose || o.ShowOutput.Passed()
+}